@@ -21,6 +21,18 @@ const (
 	// health assessment result.
 	HealthyCondition string = "Healthy"
 
+	// ChildrenReadyCondition represents the combined readiness of the
+	// Kustomizations applied by the Kustomization.
+	ChildrenReadyCondition string = "ChildrenReady"
+
+	// TestSuccessCondition represents the result of the last run
+	// of the smoke tests.
+	TestSuccessCondition string = "TestSuccess"
+
+	// TestFailedReason represents the fact that
+	// one of the smoke tests failed.
+	TestFailedReason string = "TestFailed"
+
 	// PruneFailedReason represents the fact that the
 	// pruning of the Kustomization failed.
 	PruneFailedReason string = "PruneFailed"
@@ -29,6 +41,10 @@ const (
 	// source artifact download failed.
 	ArtifactFailedReason string = "ArtifactFailed"
 
+	// ArtifactIntegrityErrorReason represents the fact that the extracted
+	// source artifact files don't match their published checksums.
+	ArtifactIntegrityErrorReason string = "ArtifactIntegrityError"
+
 	// BuildFailedReason represents the fact that the
 	// kustomize build failed.
 	BuildFailedReason string = "BuildFailed"
@@ -41,6 +57,19 @@ const (
 	// one of the dependencies is not ready.
 	DependencyNotReadyReason string = "DependencyNotReady"
 
+	// ChildrenNotReadyReason represents the fact that some of the
+	// Kustomizations applied by the Kustomization are not ready.
+	ChildrenNotReadyReason string = "ChildrenNotReady"
+
+	// CircularDependencyReason represents the fact that the Kustomization
+	// depends on itself through its dependencies.
+	CircularDependencyReason string = "CircularDependency"
+
+	// InvalidSpecReason represents the fact that the spec of the
+	// Kustomization matches a human error pattern, e.g. a dependency
+	// on itself.
+	InvalidSpecReason string = "InvalidSpec"
+
 	// ReconciliationSucceededReason represents the fact that
 	// the reconciliation succeeded.
 	ReconciliationSucceededReason string = "ReconciliationSucceeded"
@@ -48,4 +77,63 @@ const (
 	// ReconciliationFailedReason represents the fact that
 	// the reconciliation failed.
 	ReconciliationFailedReason string = "ReconciliationFailed"
+
+	// TenancyViolationReason represents the fact that
+	// the Kustomization violates the tenancy lockdown policy,
+	// or applies cluster-scoped objects denied to the tenants.
+	TenancyViolationReason string = "TenancyViolation"
+
+	// DependentsNotDeletedReason represents the fact that the deletion
+	// of the Kustomization waits for its dependents to be deleted.
+	DependentsNotDeletedReason string = "DependentsNotDeleted"
+
+	// ImagePolicyViolationReason represents the fact that the container
+	// images of the Kustomization violate its image policy.
+	ImagePolicyViolationReason string = "ImagePolicyViolation"
+
+	// ImageVerificationFailedReason represents the fact that the signature
+	// of container images of the Kustomization couldn't be verified.
+	ImageVerificationFailedReason string = "ImageVerificationFailed"
+
+	// ObservationSucceededReason represents the fact that the objects
+	// have been compared with the cluster, in the 'Observe' mode.
+	ObservationSucceededReason string = "ObservationSucceeded"
+
+	// QuotaExceededReason represents the fact that the new objects of the
+	// Kustomization would exceed the resource quotas of their namespaces.
+	QuotaExceededReason string = "QuotaExceeded"
+
+	// OwnershipConflictReason represents the fact that objects of the
+	// Kustomization are in the inventory of other Kustomizations.
+	OwnershipConflictReason string = "OwnershipConflict"
+
+	// APIServerPinMismatchReason represents the fact that the certificates
+	// presented by the API server of the remote cluster don't match the
+	// pins of the Kustomization.
+	APIServerPinMismatchReason string = "APIServerPinMismatch"
+
+	// ReconciliationGatedReason represents the fact that the apply is
+	// paused, as a closed ReconciliationGate selects the Kustomization.
+	ReconciliationGatedReason string = "ReconciliationGated"
+
+	// RevisionRateLimitedReason represents the fact that the apply of a new
+	// revision is delayed, as the revision rate limit is reached.
+	RevisionRateLimitedReason string = "RevisionRateLimited"
+
+	// DecryptionVerifiedReason represents the fact that all the encrypted
+	// files have been decrypted, in the 'VerifyDecryption' mode.
+	DecryptionVerifiedReason string = "DecryptionVerified"
+
+	// DecryptionFailedReason represents the fact that some of the encrypted
+	// files couldn't be decrypted, in the 'VerifyDecryption' mode.
+	DecryptionFailedReason string = "DecryptionFailed"
+
+	// WebhookCircuitOpenReason represents the fact that the apply is
+	// delayed, as an admission webhook intercepting the objects is flapping.
+	WebhookCircuitOpenReason string = "WebhookCircuitOpen"
+
+	// CredentialsExpiredReason represents the fact that the reconciliation
+	// failed because the credentials of a key management service or of the
+	// remote cluster are expired.
+	CredentialsExpiredReason string = "CredentialsExpired"
 )
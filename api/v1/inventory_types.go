@@ -31,4 +31,9 @@ type ResourceRef struct {
 
 	// Version is the API version of the Kubernetes resource object's kind.
 	Version string `json:"v"`
+
+	// Digest is the checksum of the object's rendered content, recorded
+	// when the differential apply is enabled.
+	// +optional
+	Digest string `json:"digest,omitempty"`
 }
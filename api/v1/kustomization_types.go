@@ -21,6 +21,7 @@ import (
 
 	"github.com/fluxcd/pkg/apis/kustomize"
 	"github.com/fluxcd/pkg/apis/meta"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -33,11 +34,16 @@ const (
 	MergeValue                = "Merge"
 	IfNotPresentValue         = "IfNotPresent"
 	IgnoreValue               = "Ignore"
+	PreserveValue             = "Preserve"
 )
 
 // KustomizationSpec defines the configuration to calculate the desired state
 // from a Source using Kustomize.
 type KustomizationSpec struct {
+	// BuildOptions configures how the kustomize overlay is built.
+	// +optional
+	BuildOptions *BuildOptions `json:"buildOptions,omitempty"`
+
 	// CommonMetadata specifies the common labels and annotations that are
 	// applied to all resources. Any existing label or annotation will be
 	// overridden if its key matches a common one.
@@ -50,6 +56,22 @@ type KustomizationSpec struct {
 	// +optional
 	DependsOn []meta.NamespacedObjectReference `json:"dependsOn,omitempty"`
 
+	// DeleteAfterDependents blocks the finalization of the Kustomization,
+	// when it's deleted, until the Kustomizations depending on it are
+	// deleted, so that the objects are pruned in the reverse order of
+	// the dependencies. Defaults to false.
+	// +optional
+	DeleteAfterDependents bool `json:"deleteAfterDependents,omitempty"`
+
+	// Priority of the Kustomization in the reconciliation queue, when the
+	// controller starts and when a source change triggers the reconciliation
+	// of many Kustomizations. The Kustomizations with a higher priority are
+	// enqueued first. Defaults to 0.
+	// +kubebuilder:validation:Minimum=-1000
+	// +kubebuilder:validation:Maximum=1000
+	// +optional
+	Priority int32 `json:"priority,omitempty"`
+
 	// Decrypt Kubernetes secrets before applying them on the cluster.
 	// +optional
 	Decryption *Decryption `json:"decryption,omitempty"`
@@ -80,12 +102,47 @@ type KustomizationSpec struct {
 	// +optional
 	KubeConfig *meta.KubeConfigReference `json:"kubeConfig,omitempty"`
 
+	// RemoteCluster assembles the connection to a remote cluster from the
+	// URL of its API server, its CA bundle and a bearer token, instead of a
+	// kubeconfig. It behaves like KubeConfig in combination with
+	// ServiceAccountName, and can't be set together with KubeConfig.
+	// +optional
+	RemoteCluster *RemoteClusterReference `json:"remoteCluster,omitempty"`
+
+	// APIServerPins lists the pins of the certificates the API server of
+	// the remote cluster must present, either 'sha256/<base64>' for the
+	// SHA-256 digest of a Subject Public Key Info, or 'sha256:<hex>' for the
+	// SHA-256 fingerprint of a certificate. The connections fail unless a
+	// certificate of the chain matches a pin.
+	// +kubebuilder:validation:items:Pattern="^sha256[/:].+$"
+	// +optional
+	APIServerPins []string `json:"apiServerPins,omitempty"`
+
 	// Path to the directory containing the kustomization.yaml file, or the
 	// set of plain YAMLs a kustomization.yaml should be generated for.
 	// Defaults to 'None', which translates to the root path of the SourceRef.
 	// +optional
 	Path string `json:"path,omitempty"`
 
+	// Ignore excludes the files matching the patterns, in the .gitignore
+	// format and evaluated relative to the Path, from the decryption and
+	// the build.
+	// +optional
+	Ignore *string `json:"ignore,omitempty"`
+
+	// SourceChangeFilter restricts the source revision changes triggering a
+	// reconciliation to the ones changing files under the Path or the filter
+	// paths, when the source artifact lists the changed files in its metadata.
+	// The reconciliations at the specified interval are not affected.
+	// +optional
+	SourceChangeFilter *SourceChangeFilter `json:"sourceChangeFilter,omitempty"`
+
+	// RevisionRateLimit limits the number of distinct source revisions
+	// applied in a time window. The revisions exceeding the limit are
+	// coalesced, and the latest one is applied once the window allows it.
+	// +optional
+	RevisionRateLimit *RevisionRateLimit `json:"revisionRateLimit,omitempty"`
+
 	// PostBuild describes which actions to perform on the YAML manifest
 	// generated by building the kustomize overlay.
 	// +optional
@@ -95,6 +152,16 @@ type KustomizationSpec struct {
 	// +required
 	Prune bool `json:"prune"`
 
+	// RetainedGenerations is the number of previous generations of the
+	// ConfigMaps and Secrets created by kustomize generators with a name
+	// suffix hash, which are kept in-cluster when pruning. This allows
+	// workloads which are rolling out or rolled back to refer to them.
+	// Defaults to 0.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=10
+	// +optional
+	RetainedGenerations int `json:"retainedGenerations,omitempty"`
+
 	// A list of resources to be included in the health assessment.
 	// +optional
 	HealthChecks []meta.NamespacedObjectKindReference `json:"healthChecks,omitempty"`
@@ -119,6 +186,12 @@ type KustomizationSpec struct {
 	// +required
 	SourceRef CrossNamespaceSourceReference `json:"sourceRef"`
 
+	// OverlaySources are the sources whose artifacts are extracted at the
+	// given paths of the source artifact before the build, e.g. to combine
+	// a shared base repository with a thin environment repository.
+	// +optional
+	OverlaySources []OverlaySource `json:"overlaySources,omitempty"`
+
 	// This flag tells the controller to suspend subsequent kustomize executions,
 	// it does not apply to already started executions. Defaults to false.
 	// +optional
@@ -132,6 +205,12 @@ type KustomizationSpec struct {
 	// +optional
 	TargetNamespace string `json:"targetNamespace,omitempty"`
 
+	// InheritNamespace sets the namespace of the Kustomization on the
+	// namespaced objects which have no namespace, instead of failing the
+	// apply. It has no effect when TargetNamespace is set.
+	// +optional
+	InheritNamespace bool `json:"inheritNamespace,omitempty"`
+
 	// Timeout for validation, apply and health checking operations.
 	// Defaults to 'Interval' duration.
 	// +kubebuilder:validation:Type=string
@@ -139,12 +218,76 @@ type KustomizationSpec struct {
 	// +optional
 	Timeout *metav1.Duration `json:"timeout,omitempty"`
 
+	// ApplyTimeout bounds the duration of the requests made for each
+	// object during the apply, so that an unresponsive API fails the apply
+	// without consuming the whole Timeout.
+	// +kubebuilder:validation:Type=string
+	// +kubebuilder:validation:Pattern="^([0-9]+(\\.[0-9]+)?(ms|s|m|h))+$"
+	// +optional
+	ApplyTimeout *metav1.Duration `json:"applyTimeout,omitempty"`
+
 	// Force instructs the controller to recreate resources
 	// when patching fails due to an immutable field change.
 	// +kubebuilder:default:=false
 	// +optional
 	Force bool `json:"force,omitempty"`
 
+	// Mode defines how the objects are reconciled. 'Apply' applies the
+	// objects to the cluster. 'Observe' compares the objects with the
+	// cluster without modifying them, records the existing objects in the
+	// inventory and reports the differences in status, e.g. to import an
+	// existing cluster. 'VerifyDecryption' decrypts the encrypted files under
+	// the path without building nor applying them, and reports the outcome
+	// for each file in status, e.g. to validate the distribution of the
+	// decryption keys to a new cluster. Defaults to 'Apply'.
+	// +kubebuilder:validation:Enum=Apply;Observe;VerifyDecryption
+	// +optional
+	Mode string `json:"mode,omitempty"`
+
+	// AdmissionDryRunPolicy defines how the errors returned by the admission
+	// webhooks and ValidatingAdmissionPolicies for the server-side dry-run
+	// are handled. 'Fail' fails the apply. 'Warn' reports them as warnings
+	// and applies the objects, in which case the errors returned for the
+	// actual writes still fail the apply. Defaults to 'Fail'.
+	// +kubebuilder:validation:Enum=Fail;Warn
+	// +optional
+	AdmissionDryRunPolicy string `json:"admissionDryRunPolicy,omitempty"`
+
+	// ImagePolicy defines the vulnerability scan gate checking the
+	// container images of the manifests before they are applied.
+	// +optional
+	ImagePolicy *ImagePolicy `json:"imagePolicy,omitempty"`
+
+	// ImageVerification verifies the signatures of the container images
+	// of the manifests before they are applied.
+	// +optional
+	ImageVerification *ImageVerification `json:"imageVerification,omitempty"`
+
+	// ValidationRules are CEL expressions evaluated against the objects of
+	// the manifests before they are applied, e.g. to detect workloads which
+	// can't be scheduled on the nodes of the cluster. The violations are
+	// reported as warnings and don't block the apply.
+	// +optional
+	ValidationRules []ValidationRule `json:"validationRules,omitempty"`
+
+	// SkipMutationRules lists the names of the mutation rules of the
+	// controller which are not applied to the objects of the manifests,
+	// '*' skipping all of them.
+	// +optional
+	SkipMutationRules []string `json:"skipMutationRules,omitempty"`
+
+	// InventoryExport records the inventory into a ConfigMap on the target
+	// cluster, in the format of the cli-utils inventory objects and kubectl
+	// ApplySets, so that these tools can operate on the applied objects.
+	// +optional
+	InventoryExport *InventoryExport `json:"inventoryExport,omitempty"`
+
+	// ArgoCDMigration adopts the objects managed by an Argo CD Application
+	// while both tools are running, by preserving the Argo CD tracking
+	// metadata of the applied objects until the grace period expires.
+	// +optional
+	ArgoCDMigration *ArgoCDMigration `json:"argoCDMigration,omitempty"`
+
 	// Wait instructs the controller to check the health of all the reconciled
 	// resources. When enabled, the HealthChecks are ignored. Defaults to false.
 	// +optional
@@ -153,8 +296,567 @@ type KustomizationSpec struct {
 	// Components specifies relative paths to specifications of other Components.
 	// +optional
 	Components []string `json:"components,omitempty"`
+
+	// ComponentToggles specifies relative paths to Components which are
+	// appended to the Components when enabled, e.g. by a post build variable,
+	// so that the features of an environment can be toggled without one
+	// overlay per combination.
+	// +optional
+	ComponentToggles []ComponentToggle `json:"componentToggles,omitempty"`
+
+	// Environment selects the path, components and post build variables
+	// of the Kustomization from the labels describing the cluster.
+	// +optional
+	Environment *Environment `json:"environment,omitempty"`
+
+	// RolloutOnConfigChange instructs the controller to annotate the pod
+	// templates of Deployments, StatefulSets, DaemonSets and CronJobs with
+	// a checksum of the ConfigMaps and Secrets they refer to, which are part
+	// of the Kustomization. Changes to their data trigger a rollout.
+	// Defaults to false.
+	// +optional
+	RolloutOnConfigChange bool `json:"rolloutOnConfigChange,omitempty"`
+
+	// ImmutableConfig instructs the controller to mark the ConfigMaps and
+	// Secrets of the Kustomization as immutable, and to rotate them when
+	// their data changes.
+	// +optional
+	ImmutableConfig *ImmutableConfig `json:"immutableConfig,omitempty"`
+
+	// DifferentialApply instructs the controller to skip the server-side
+	// apply of the objects whose rendered content is unchanged since the
+	// last reconciliation, except for the periodic drift detection.
+	// +optional
+	DifferentialApply *DifferentialApply `json:"differentialApply,omitempty"`
+
+	// DriftDetection excludes fields from the apply, so that the values set
+	// in-cluster by mutating webhooks, defaulting or other controllers are
+	// not reverted on every reconciliation.
+	// +optional
+	DriftDetection *DriftDetection `json:"driftDetection,omitempty"`
+
+	// ApplyStrategy overrides the server-side apply of the objects of
+	// specific kinds, e.g. for the aggregated or legacy API servers which
+	// mishandle server-side apply.
+	// +optional
+	ApplyStrategy *ApplyStrategy `json:"applyStrategy,omitempty"`
+
+	// Validation defines how the API server validates the fields of the
+	// applied objects.
+	// +optional
+	Validation *Validation `json:"validation,omitempty"`
+
+	// AggregateChildren rolls up the readiness of the Kustomizations applied
+	// by this Kustomization, e.g. in the app-of-apps pattern, into the
+	// ChildrenReady condition. The Kustomization is ready only once all its
+	// children are ready.
+	// +optional
+	AggregateChildren bool `json:"aggregateChildren,omitempty"`
+
+	// Tests are the smoke tests run in-cluster, in order, once the health
+	// checks pass. The Kustomization is ready only once all the tests
+	// succeed, their result being reported in the TestSuccess condition.
+	// +optional
+	Tests []Test `json:"tests,omitempty"`
+}
+
+// Validation defines the server-side validation of the applied objects.
+type Validation struct {
+	// FieldValidation instructs the API server how to handle the unknown
+	// and duplicate fields of the objects. 'Strict' rejects the objects,
+	// 'Warn' applies them and returns a warning, 'Ignore' silently drops
+	// the fields. Defaults to the API server default, 'Warn' since
+	// Kubernetes 1.25.
+	// +kubebuilder:validation:Enum=Strict;Warn;Ignore
+	// +optional
+	FieldValidation string `json:"fieldValidation,omitempty"`
+}
+
+// ApplyStrategy defines how the objects are applied.
+type ApplyStrategy struct {
+	// Overrides sets the apply strategy of the objects of the given kinds.
+	// The first matching override applies.
+	// +optional
+	Overrides []ApplyStrategyOverride `json:"overrides,omitempty"`
+
+	// PreserveAutoscaledReplicas keeps the in-cluster replicas of the
+	// workloads scaled by a HorizontalPodAutoscaler, detected among the
+	// objects of the manifests and in the cluster, instead of applying
+	// the replicas of the manifests.
+	// +optional
+	PreserveAutoscaledReplicas bool `json:"preserveAutoscaledReplicas,omitempty"`
+
+	// SingleStage applies the objects one by one, in the order of the
+	// kustomize build, instead of applying the CRDs and Namespaces, then the
+	// class types, then the other objects sorted by kind. The cluster
+	// definitions are waited for before applying the next objects.
+	// +optional
+	SingleStage bool `json:"singleStage,omitempty"`
+}
+
+// ApplyStrategyOverride sets the apply strategy of the objects of a kind.
+type ApplyStrategyOverride struct {
+	// Group restricts the override to the kind of this API group.
+	// When not specified, the kind is matched in all the API groups.
+	// +optional
+	Group string `json:"group,omitempty"`
+
+	// Kind of the objects.
+	// +kubebuilder:validation:MinLength=1
+	// +required
+	Kind string `json:"kind"`
+
+	// Strategy is the apply strategy of the objects. 'ServerSide' applies
+	// them with server-side apply. 'ClientSide' applies them with a
+	// three-way merge patch computed from their last applied configuration,
+	// recorded in the 'kubectl.kubernetes.io/last-applied-configuration'
+	// annotation.
+	// +kubebuilder:validation:Enum=ServerSide;ClientSide
+	// +required
+	Strategy string `json:"strategy"`
+}
+
+// ImmutableConfig defines how the immutable ConfigMaps and Secrets
+// are rotated.
+type ImmutableConfig struct {
+	// Rotation is how the changes to the data of the immutable objects are
+	// applied. 'Rename' suffixes the name of the objects referred to by the
+	// pod templates of the Kustomization with the hash of their data, and
+	// updates the references. 'Recreate' deletes and recreates the objects,
+	// which is also how the objects not referred to are rotated.
+	// +kubebuilder:validation:Enum=Rename;Recreate
+	// +kubebuilder:default:=Rename
+	// +optional
+	Rotation string `json:"rotation,omitempty"`
 }
 
+// DifferentialApply defines the drift detection settings of the
+// differential apply.
+type DifferentialApply struct {
+	// DriftDetectionInterval is the interval at which all the objects are
+	// applied, regardless of their rendered content, to detect and correct
+	// drift in-cluster. Defaults to one hour.
+	// +kubebuilder:validation:Type=string
+	// +kubebuilder:validation:Pattern="^([0-9]+(\\.[0-9]+)?(ms|s|m|h))+$"
+	// +optional
+	DriftDetectionInterval *metav1.Duration `json:"driftDetectionInterval,omitempty"`
+
+	// RevisionDiff skips the fetch, the build and the apply of the new
+	// source revisions which don't change files under the Path or the
+	// SourceChangeFilter paths, when the source artifact lists the files
+	// changed since the last applied revision in its metadata. All the
+	// objects are still applied at the DriftDetectionInterval.
+	// +optional
+	RevisionDiff bool `json:"revisionDiff,omitempty"`
+}
+
+// Test is a smoke test run in-cluster after the apply. Exactly one of
+// ConfigMap and HTTP must be set.
+type Test struct {
+	// Name of the test, reported when it fails.
+	// +kubebuilder:validation:Pattern="^[a-z0-9]([-a-z0-9]*[a-z0-9])?$"
+	// +kubebuilder:validation:MaxLength=63
+	// +required
+	Name string `json:"name"`
+
+	// ConfigMap checks the value of a key of a ConfigMap.
+	// +optional
+	ConfigMap *ConfigMapTest `json:"configMap,omitempty"`
+
+	// HTTP runs a Job requesting a URL from inside the cluster.
+	// +optional
+	HTTP *HTTPTest `json:"http,omitempty"`
+}
+
+// ConfigMapTest checks the value of a key of a ConfigMap.
+type ConfigMapTest struct {
+	// Name of the ConfigMap.
+	// +required
+	Name string `json:"name"`
+
+	// Namespace of the ConfigMap, defaults to the target namespace or
+	// the namespace of the Kustomization.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// Key of the ConfigMap data.
+	// +required
+	Key string `json:"key"`
+
+	// Value expected for the key, the key only having to be set if empty.
+	// +optional
+	Value string `json:"value,omitempty"`
+}
+
+// HTTPTest runs a Job requesting a URL with curl, which succeeds if the
+// response status is below 400.
+type HTTPTest struct {
+	// URL requested from inside the cluster, e.g. the URL of a Service.
+	// +kubebuilder:validation:Pattern="^https?://"
+	// +required
+	URL string `json:"url"`
+
+	// Image of the Job container, which must provide curl.
+	// +kubebuilder:default:="curlimages/curl:8.7.1"
+	// +optional
+	Image string `json:"image,omitempty"`
+}
+
+// OverlaySource is a source whose artifact is extracted at a path of the
+// source artifact.
+type OverlaySource struct {
+	// SourceRef of the overlaid artifact.
+	// +required
+	SourceRef CrossNamespaceSourceReference `json:"sourceRef"`
+
+	// Path relative to the root of the source artifact where the artifact
+	// is extracted, replacing the files under it, e.g. './base'.
+	// The paths of the overlay sources must not overlap.
+	// +kubebuilder:validation:MinLength=1
+	// +required
+	Path string `json:"path"`
+}
+
+// OverlayRevision is the revision of an overlay source artifact.
+type OverlayRevision struct {
+	// Source of the artifact in the 'Kind/namespace/name' format.
+	// +required
+	Source string `json:"source"`
+
+	// Revision of the artifact.
+	// +required
+	Revision string `json:"revision"`
+}
+
+// DriftDetection defines the fields excluded from the correction of the
+// drift in-cluster.
+type DriftDetection struct {
+	// Ignore contains the rules of the fields removed from the objects
+	// before they're applied, the controller no longer owning them.
+	// +optional
+	Ignore []IgnoreRule `json:"ignore,omitempty"`
+}
+
+// IgnoreRule removes the fields at the paths from the objects matching
+// the target.
+type IgnoreRule struct {
+	// Paths are the JSON pointers of the ignored fields, as per RFC 6901,
+	// e.g. '/spec/template/metadata/annotations/sidecar.istio.io~1status'.
+	// +kubebuilder:validation:MinItems=1
+	// +required
+	Paths []string `json:"paths"`
+
+	// Target selects the objects the fields are ignored for, all the
+	// objects if not set.
+	// +optional
+	Target *kustomize.Selector `json:"target,omitempty"`
+}
+
+// BuildOptions defines the kustomize build settings.
+type BuildOptions struct {
+	// LoadRestrictions restricts the files kustomize is allowed to load.
+	// 'RootOnly' only allows loading files from the directory of each
+	// kustomization file and its subdirectories, 'None' allows loading files
+	// from anywhere in the source artifact. Setting 'None' requires the
+	// controller to run with '--allow-load-restrictions-none'.
+	// Defaults to 'RootOnly'.
+	// +kubebuilder:validation:Enum=None;RootOnly
+	// +optional
+	LoadRestrictions string `json:"loadRestrictions,omitempty"`
+
+	// Reorder sets the order of the resources in the build output.
+	// 'none' keeps the order in which the resources are declared,
+	// 'legacy' sorts them by kind in the order used by 'kustomize build'.
+	// Defaults to 'none'.
+	// +kubebuilder:validation:Enum=none;legacy
+	// +optional
+	Reorder string `json:"reorder,omitempty"`
+
+	// AddManagedByLabel adds the 'app.kubernetes.io/managed-by' label,
+	// set to the version of kustomize, to all the resources in the build output.
+	// When set to false, the label is not added even if the kustomization
+	// file requests it with 'buildMetadata: [managedByLabel]'.
+	// When not set, the kustomization file decides.
+	// +optional
+	AddManagedByLabel *bool `json:"addManagedByLabel,omitempty"`
+
+	// ManagedByLabelValue overrides the value of the 'app.kubernetes.io/managed-by'
+	// label added by kustomize, which defaults to 'kustomize-<version>'.
+	// It can't be set when AddManagedByLabel is false.
+	// +kubebuilder:validation:MaxLength=63
+	// +optional
+	ManagedByLabelValue string `json:"managedByLabelValue,omitempty"`
+
+	// OpenAPIPath is the path, relative to Path, of an OpenAPI schema file
+	// used by kustomize to patch custom resources with strategic merge patches.
+	// It takes precedence over the 'openapi' field of the kustomization file.
+	// +optional
+	OpenAPIPath string `json:"openAPIPath,omitempty"`
+
+	// OpenAPISchemaFrom specifies an OpenAPI schema, stored in a ConfigMap
+	// or served by the target cluster, used by kustomize to patch custom
+	// resources with strategic merge patches.
+	// It can't be set together with OpenAPIPath.
+	// +optional
+	OpenAPISchemaFrom *OpenAPISchemaSource `json:"openAPISchemaFrom,omitempty"`
+}
+
+// OpenAPISchemaSource defines where to get an OpenAPI schema from.
+type OpenAPISchemaSource struct {
+	// Kind of the schema source, 'ConfigMap' reads the schema from a ConfigMap
+	// in the same namespace as the Kustomization, 'Cluster' fetches the OpenAPI
+	// v2 schema from the cluster the Kustomization is applied to.
+	// +kubebuilder:validation:Enum=ConfigMap;Cluster
+	// +required
+	Kind string `json:"kind"`
+
+	// Name of the ConfigMap, required when Kind is 'ConfigMap'.
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// Key of the ConfigMap data holding the schema in JSON or YAML format.
+	// Defaults to 'schema.json'.
+	// +optional
+	Key string `json:"key,omitempty"`
+}
+
+const (
+	// LoadRestrictionsNone allows kustomize to load files from anywhere
+	// in the source artifact.
+	LoadRestrictionsNone = "None"
+
+	// LoadRestrictionsRootOnly allows kustomize to load files only from
+	// the directory of each kustomization file and its subdirectories.
+	LoadRestrictionsRootOnly = "RootOnly"
+
+	// OpenAPISchemaFromConfigMap reads the OpenAPI schema from a ConfigMap.
+	OpenAPISchemaFromConfigMap = "ConfigMap"
+
+	// OpenAPISchemaFromCluster fetches the OpenAPI schema from the cluster.
+	OpenAPISchemaFromCluster = "Cluster"
+
+	// AdmissionDryRunFail fails the apply when the server-side dry-run
+	// is denied by an admission webhook or policy.
+	AdmissionDryRunFail = "Fail"
+
+	// AdmissionDryRunWarn reports the server-side dry-run denials of the
+	// admission webhooks and policies as warnings.
+	AdmissionDryRunWarn = "Warn"
+
+	// ImagePolicyBlock fails the reconciliation when the images
+	// violate the image policy.
+	ImagePolicyBlock = "Block"
+
+	// ImagePolicyWarn reports the images violating the image policy
+	// in an event, and applies the manifests.
+	ImagePolicyWarn = "Warn"
+
+	// ApplyMode applies the objects to the cluster.
+	ApplyMode = "Apply"
+
+	// ObserveMode compares the objects with the cluster without
+	// modifying them.
+	ObserveMode = "Observe"
+
+	// VerifyDecryptionMode decrypts the encrypted files without building
+	// nor applying them.
+	VerifyDecryptionMode = "VerifyDecryption"
+
+	// ServerSideApply applies the objects with server-side apply.
+	ServerSideApply = "ServerSide"
+
+	// ClientSideApply applies the objects with a client-side three-way merge.
+	ClientSideApply = "ClientSide"
+
+	// RenameRotation rotates the immutable ConfigMaps and Secrets by
+	// suffixing their name with the hash of their data.
+	RenameRotation = "Rename"
+
+	// RecreateRotation rotates the immutable ConfigMaps and Secrets by
+	// deleting and recreating them.
+	RecreateRotation = "Recreate"
+)
+
+// ImageVerification defines the verification of the signatures of the
+// container images.
+type ImageVerification struct {
+	// Provider specifies the technology used to sign the images.
+	// +kubebuilder:validation:Enum=cosign
+	// +kubebuilder:default:=cosign
+	// +optional
+	Provider string `json:"provider,omitempty"`
+
+	// SecretRef specifies the Secret containing the public keys of trusted
+	// authors, in data keys ending with '.pub'. When not specified, the
+	// keyless verification is used.
+	// +optional
+	SecretRef *meta.LocalObjectReference `json:"secretRef,omitempty"`
+
+	// MatchOIDCIdentity specifies the identity matching criteria to use
+	// while verifying the keyless signatures. The verification succeeds if
+	// the identity matches at least one of the criteria.
+	// +optional
+	MatchOIDCIdentity []OIDCIdentityMatch `json:"matchOIDCIdentity,omitempty"`
+}
+
+// InventoryExport defines the ConfigMap recording the inventory.
+type InventoryExport struct {
+	// Name of the ConfigMap.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=253
+	// +required
+	Name string `json:"name"`
+
+	// Namespace of the ConfigMap, defaults to the namespace
+	// of the Kustomization.
+	// +kubebuilder:validation:MaxLength=63
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// ArgoCDMigration defines the adoption of the objects of an Argo CD
+// Application.
+type ArgoCDMigration struct {
+	// Application is the name of the Argo CD Application, matched against
+	// the 'argocd.argoproj.io/tracking-id' annotation and the
+	// 'app.kubernetes.io/instance' label of the objects.
+	// +kubebuilder:validation:MinLength=1
+	// +required
+	Application string `json:"application"`
+
+	// StripTrackingAfter is the grace period, from the first apply of the
+	// objects tracked by the Application, after which the Argo CD tracking
+	// metadata is removed from the objects. The tracking metadata is kept
+	// when not set.
+	// +kubebuilder:validation:Type=string
+	// +kubebuilder:validation:Pattern="^([0-9]+(\\.[0-9]+)?(ms|s|m|h))+$"
+	// +optional
+	StripTrackingAfter *metav1.Duration `json:"stripTrackingAfter,omitempty"`
+}
+
+// ValidationRule defines a CEL expression which the objects of the
+// manifests are expected to satisfy.
+type ValidationRule struct {
+	// Name identifies the rule in the warnings.
+	// +required
+	Name string `json:"name"`
+
+	// Kinds restricts the rule to the objects of these kinds.
+	// When not specified, the rule is evaluated for all the objects.
+	// +optional
+	Kinds []string `json:"kinds,omitempty"`
+
+	// Expression is a CEL expression evaluated with the object in the
+	// 'object' variable, which must return true when the object is valid.
+	// +required
+	Expression string `json:"expression"`
+
+	// Message is reported for the objects violating the rule.
+	// Defaults to the expression.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// OIDCIdentityMatch specifies the options for verifying the certificate
+// identity, i.e. the issuer and the subject of the certificate.
+type OIDCIdentityMatch struct {
+	// Issuer specifies the regex pattern to match against to verify
+	// the OIDC issuer in the Fulcio certificate.
+	// +required
+	Issuer string `json:"issuer"`
+
+	// Subject specifies the regex pattern to match against to verify
+	// the identity subject in the Fulcio certificate.
+	// +required
+	Subject string `json:"subject"`
+}
+
+// ImagePolicy defines the vulnerabilities allowed in the container images.
+type ImagePolicy struct {
+	// Severity is the lowest severity of the vulnerabilities violating
+	// the policy. Defaults to 'CRITICAL'.
+	// +kubebuilder:validation:Enum=LOW;MEDIUM;HIGH;CRITICAL
+	// +kubebuilder:default:=CRITICAL
+	// +optional
+	Severity string `json:"severity,omitempty"`
+
+	// Action defines how violations are handled. 'Block' fails the
+	// reconciliation before applying. 'Warn' emits an event and applies
+	// the manifests. Defaults to 'Block'.
+	// +kubebuilder:validation:Enum=Block;Warn
+	// +optional
+	Action string `json:"action,omitempty"`
+
+	// IgnoreVulnerabilities lists the identifiers of the vulnerabilities
+	// which don't violate the policy, e.g. CVE-2023-44487.
+	// +optional
+	IgnoreVulnerabilities []string `json:"ignoreVulnerabilities,omitempty"`
+}
+
+// SourceChangeFilter defines the paths whose changes trigger a reconciliation.
+type SourceChangeFilter struct {
+	// Paths is a list of paths relative to the source root, in addition to
+	// the Kustomization Path, e.g. the paths of the bases and components
+	// referred to by the overlay.
+	// +optional
+	Paths []string `json:"paths,omitempty"`
+}
+
+// RevisionRateLimit defines the maximum number of distinct source revisions
+// applied in a sliding time window.
+type RevisionRateLimit struct {
+	// MaxRevisions is the maximum number of distinct revisions applied in
+	// the window.
+	// +kubebuilder:validation:Minimum=1
+	// +required
+	MaxRevisions int `json:"maxRevisions"`
+
+	// Window is the duration of the sliding window. Defaults to one hour.
+	// +kubebuilder:validation:Type=string
+	// +kubebuilder:validation:Pattern="^([0-9]+(\\.[0-9]+)?(ms|s|m|h))+$"
+	// +optional
+	Window *metav1.Duration `json:"window,omitempty"`
+}
+
+// ChangedPathsMetadataKey is the source artifact metadata key listing the
+// paths, relative to the source root and separated by commas or new lines,
+// of the files changed since the previous revision.
+const ChangedPathsMetadataKey = "kustomize.toolkit.fluxcd.io/changed-paths"
+
+// PreviousRevisionMetadataKey is the source artifact metadata key holding
+// the revision the changed paths are listed against.
+const PreviousRevisionMetadataKey = "kustomize.toolkit.fluxcd.io/previous-revision"
+
+// CommitSubjectMetadataKey is the source artifact metadata key holding the
+// subject of the commit of the revision, i.e. the first line of the commit
+// message.
+const CommitSubjectMetadataKey = "kustomize.toolkit.fluxcd.io/commit-subject"
+
+// CommitAuthorMetadataKey is the source artifact metadata key holding the
+// author of the commit of the revision, e.g. 'Jane Doe <jane@example.com>'.
+const CommitAuthorMetadataKey = "kustomize.toolkit.fluxcd.io/commit-author"
+
+// FileChecksumsMetadataKey is the Bucket artifact metadata key listing the
+// SHA-256 checksums of the artifact files, one per line in the sha256sum
+// format, with the paths relative to the source root.
+const FileChecksumsMetadataKey = "kustomize.toolkit.fluxcd.io/file-checksums"
+
+// LogLevelAnnotation is the annotation which sets the log level of the
+// reconciliation of a Kustomization, one of trace, debug, info or error.
+// It only makes the logs more verbose than the controller log level.
+const LogLevelAnnotation = "kustomize.toolkit.fluxcd.io/log-level"
+
+// ReplicasAnnotation is the annotation which, when set to 'Preserve' on a
+// workload, keeps its in-cluster replicas instead of applying the replicas
+// of the manifest, e.g. for the workloads scaled by an autoscaler.
+const ReplicasAnnotation = "kustomize.toolkit.fluxcd.io/replicas"
+
+// SubresourcesAnnotation is the annotation which lists the subresources
+// applied when the object is created, separated by commas: 'status' applies
+// the status of the manifest, 'scale=<replicas>' sets the replicas through
+// the scale subresource.
+const SubresourcesAnnotation = "kustomize.toolkit.fluxcd.io/subresources"
+
 // CommonMetadata defines the common labels and annotations.
 type CommonMetadata struct {
 	// Annotations to be added to the object's metadata.
@@ -166,6 +868,69 @@ type CommonMetadata struct {
 	Labels map[string]string `json:"labels,omitempty"`
 }
 
+// ComponentToggle appends a Component to the Kustomization when enabled.
+type ComponentToggle struct {
+	// Path is the relative path to the Component specification.
+	// +required
+	Path string `json:"path"`
+
+	// Enabled appends the Component when true. Defaults to true.
+	// +optional
+	Enabled *bool `json:"enabled,omitempty"`
+
+	// EnabledBy is the name of a variable of the post build substitutions
+	// whose boolean value enables the Component. It takes precedence over
+	// Enabled when the variable is set.
+	// +optional
+	EnabledBy string `json:"enabledBy,omitempty"`
+}
+
+// Environment defines the labels describing the cluster, and the presets
+// selected by them.
+type Environment struct {
+	// NamespaceName is the name of a Namespace whose labels describe
+	// the cluster, e.g. kube-system.
+	// +optional
+	NamespaceName string `json:"namespaceName,omitempty"`
+
+	// ConfigMapRef refers to a ConfigMap whose data describes the cluster.
+	// The data keys take precedence over the labels of the Namespace.
+	// +optional
+	ConfigMapRef *meta.NamespacedObjectReference `json:"configMapRef,omitempty"`
+
+	// Presets is the list of presets matched against the labels of the
+	// cluster. The matching presets are merged in order, the later ones
+	// taking precedence.
+	// +required
+	Presets []EnvironmentPreset `json:"presets"`
+}
+
+// EnvironmentPreset defines the path, components and post build
+// variables used on the clusters matching its selector.
+type EnvironmentPreset struct {
+	// Name of the preset.
+	// +required
+	Name string `json:"name"`
+
+	// Selector is matched against the labels of the cluster.
+	// +required
+	Selector metav1.LabelSelector `json:"selector"`
+
+	// Path overrides the path of the Kustomization.
+	// +optional
+	Path string `json:"path,omitempty"`
+
+	// Components are appended to the components of the Kustomization.
+	// +optional
+	Components []string `json:"components,omitempty"`
+
+	// Substitute holds the post build variables set by the preset. They
+	// take precedence over the variables from the post build substituteFrom,
+	// and are overridden by the post build substitute.
+	// +optional
+	Substitute map[string]string `json:"substitute,omitempty"`
+}
+
 // Decryption defines how decryption is handled for Kubernetes manifests.
 type Decryption struct {
 	// Provider is the name of the decryption engine.
@@ -176,6 +941,108 @@ type Decryption struct {
 	// The secret name containing the private OpenPGP keys used for decryption.
 	// +optional
 	SecretRef *meta.LocalObjectReference `json:"secretRef,omitempty"`
+
+	// AgeKeyFile is the name of the file holding the age identities, in the
+	// subdirectory named after the namespace of the Kustomization of the
+	// directory mounted into the controller and configured with the
+	// '--sops-age-key-dir' flag, e.g. by the Secrets Store CSI driver.
+	// When not specified, no identity of the directory is used.
+	// +kubebuilder:validation:Pattern="^[a-zA-Z0-9_.-]+$"
+	// +optional
+	AgeKeyFile string `json:"ageKeyFile,omitempty"`
+
+	// ExternalStore configures the materialization of decrypted Secrets in an
+	// external secret manager. Secrets annotated with
+	// 'kustomize.toolkit.fluxcd.io/external-store: enabled' are written to the
+	// store, and replaced in-cluster with an ExternalSecret referencing them.
+	// +optional
+	ExternalStore *ExternalSecretStore `json:"externalStore,omitempty"`
+}
+
+// ExternalSecretStore defines where decrypted Secret values are written to,
+// and how the applied objects refer to them.
+type ExternalSecretStore struct {
+	// Provider is the name of the external secret manager.
+	// +kubebuilder:validation:Enum=vault
+	// +required
+	Provider string `json:"provider"`
+
+	// Address of the external secret manager API,
+	// e.g. 'https://vault.example.com:8200'.
+	// +required
+	Address string `json:"address"`
+
+	// Path is the location of the KV version 2 secrets engine the values are
+	// written to, in the format '<mount>[/<prefix>]'. The values of each Secret
+	// are stored at '<mount>/<prefix>/<namespace>/<name>'.
+	// +kubebuilder:validation:MinLength=1
+	// +required
+	Path string `json:"path"`
+
+	// The secret name containing the 'token' used to authenticate with the
+	// external secret manager.
+	// +required
+	SecretRef meta.LocalObjectReference `json:"secretRef"`
+
+	// StoreRef refers to the External Secrets Operator store the generated
+	// ExternalSecret objects use to retrieve the values.
+	// +required
+	StoreRef ExternalSecretStoreReference `json:"storeRef"`
+}
+
+// RemoteClusterReference holds the connection details of a remote cluster.
+type RemoteClusterReference struct {
+	// Server is the URL of the API server of the remote cluster.
+	// +kubebuilder:validation:Pattern="^https://"
+	// +required
+	Server string `json:"server"`
+
+	// TLSServerName is the name used to verify the certificate of the API
+	// server, when it differs from the host of the Server URL.
+	// +optional
+	TLSServerName string `json:"tlsServerName,omitempty"`
+
+	// CA is the PEM-encoded CA bundle of the API server. When neither CA
+	// nor CAConfigMapRef are set, the CA bundle of the controller is used.
+	// +optional
+	CA string `json:"ca,omitempty"`
+
+	// CAConfigMapRef references the ConfigMap holding the PEM-encoded CA
+	// bundle of the API server, under the 'ca.crt' key if no key is set.
+	// +optional
+	CAConfigMapRef *ConfigMapKeyReference `json:"caConfigMapRef,omitempty"`
+
+	// TokenSecretRef references the Secret holding the bearer token used
+	// to authenticate with the API server, under the 'token' key if no key
+	// is set.
+	// +required
+	TokenSecretRef meta.SecretKeyReference `json:"tokenSecretRef"`
+}
+
+// ConfigMapKeyReference references a key of a ConfigMap in the namespace
+// of the Kustomization.
+type ConfigMapKeyReference struct {
+	// Name of the ConfigMap.
+	// +required
+	Name string `json:"name"`
+
+	// Key in the ConfigMap.
+	// +optional
+	Key string `json:"key,omitempty"`
+}
+
+// ExternalSecretStoreReference contains a reference to an External Secrets
+// Operator SecretStore or ClusterSecretStore.
+type ExternalSecretStoreReference struct {
+	// Kind of the referent.
+	// +kubebuilder:validation:Enum=SecretStore;ClusterSecretStore
+	// +kubebuilder:default:=SecretStore
+	// +optional
+	Kind string `json:"kind,omitempty"`
+
+	// Name of the referent.
+	// +required
+	Name string `json:"name"`
 }
 
 // PostBuild describes which actions to perform on the YAML manifest
@@ -196,6 +1063,13 @@ type PostBuild struct {
 	// happen.
 	// +optional
 	SubstituteFrom []SubstituteReference `json:"substituteFrom,omitempty"`
+
+	// SubstituteSchema is a JSON Schema the variables are validated against
+	// before substitution. The variables are passed to the validation as the
+	// properties of an object, with the values converted to the type declared
+	// by the matching property schema (integer, number or boolean).
+	// +optional
+	SubstituteSchema *apiextensionsv1.JSON `json:"substituteSchema,omitempty"`
 }
 
 // SubstituteReference contains a reference to a resource containing
@@ -221,6 +1095,81 @@ type SubstituteReference struct {
 	Optional bool `json:"optional,omitempty"`
 }
 
+// ApplyProgress records the number of objects applied for a revision,
+// when the objects are applied in chunks.
+type ApplyProgress struct {
+	// Revision of the applied objects.
+	// +required
+	Revision string `json:"revision"`
+
+	// Digest of the rendered manifests.
+	// +required
+	Digest string `json:"digest"`
+
+	// Applied is the number of objects applied so far.
+	// +required
+	Applied int `json:"applied"`
+
+	// Total is the number of objects of the revision.
+	// +required
+	Total int `json:"total"`
+}
+
+// FlappingResource describes an object changed by the apply while its
+// manifest is unchanged, because of a mutating webhook, a defaulting
+// mismatch or another controller fighting over its fields.
+type FlappingResource struct {
+	// Object in the 'Kind/namespace/name' format.
+	// +required
+	Object string `json:"object"`
+
+	// Reconciliations is the number of consecutive reconciliations which
+	// changed the object. The object is reported as flapping from two.
+	// +required
+	Reconciliations int `json:"reconciliations"`
+
+	// FieldPaths are the JSON pointers of the fields of the manifest whose
+	// value differs in-cluster before the apply.
+	// +optional
+	FieldPaths []string `json:"fieldPaths,omitempty"`
+}
+
+// CommitMetadata describes the commit of a source revision.
+type CommitMetadata struct {
+	// Subject is the first line of the commit message.
+	// +optional
+	Subject string `json:"subject,omitempty"`
+
+	// Author is the author of the commit.
+	// +optional
+	Author string `json:"author,omitempty"`
+}
+
+// AppliedRevision records the time at which a revision was applied.
+type AppliedRevision struct {
+	// Revision is the applied source revision.
+	// +required
+	Revision string `json:"revision"`
+
+	// AppliedAt is the time at which the revision was applied.
+	// +required
+	AppliedAt metav1.Time `json:"appliedAt"`
+}
+
+// ArgoCDMigrationStatus reports the adoption of the objects of an Argo CD
+// Application.
+type ArgoCDMigrationStatus struct {
+	// AdoptedAt is the time at which objects tracked by the Application
+	// were first applied.
+	// +required
+	AdoptedAt metav1.Time `json:"adoptedAt"`
+
+	// Tracked is the number of applied objects which still carry the
+	// Argo CD tracking metadata.
+	// +optional
+	Tracked int `json:"tracked,omitempty"`
+}
+
 // KustomizationStatus defines the observed state of a kustomization.
 type KustomizationStatus struct {
 	meta.ReconcileRequestStatus `json:",inline"`
@@ -241,10 +1190,238 @@ type KustomizationStatus struct {
 	// +optional
 	LastAttemptedRevision string `json:"lastAttemptedRevision,omitempty"`
 
+	// LastAttemptedOverlayRevisions are the revisions of the overlay source
+	// artifacts of the last reconciliation attempt.
+	// +optional
+	LastAttemptedOverlayRevisions []OverlayRevision `json:"lastAttemptedOverlayRevisions,omitempty"`
+
+	// LastAppliedCommit describes the commit of the last applied revision,
+	// when the source artifact provides it in its metadata.
+	// +optional
+	LastAppliedCommit *CommitMetadata `json:"lastAppliedCommit,omitempty"`
+
+	// LastTimeToReady is the duration from the availability of the last
+	// applied revision in the source, or from the creation of the
+	// Kustomization if later, to the Kustomization being ready for it.
+	// +optional
+	LastTimeToReady *metav1.Duration `json:"lastTimeToReady,omitempty"`
+
 	// Inventory contains the list of Kubernetes resource object references that
 	// have been successfully applied.
 	// +optional
 	Inventory *ResourceInventory `json:"inventory,omitempty"`
+
+	// BuildWarnings contains the warnings emitted by kustomize for the last
+	// attempted revision, e.g. the use of deprecated fields, followed by the
+	// violations of the validation rules.
+	// +optional
+	BuildWarnings []string `json:"buildWarnings,omitempty"`
+
+	// UnmanagedOverrides contains the objects, in the 'Kind/namespace/name'
+	// format, for which the reconciliation has been disabled in-cluster
+	// with the 'kustomize.toolkit.fluxcd.io/reconcile: disabled' annotation
+	// or label. These objects are neither applied nor pruned.
+	// +optional
+	UnmanagedOverrides []string `json:"unmanagedOverrides,omitempty"`
+
+	// PendingRecreations contains the objects, in the 'Kind/namespace/name'
+	// format, deleted by a forced apply to replace their immutable fields,
+	// which could not be created again. They are applied before the other
+	// objects by the next reconciliations, until they are created.
+	// +optional
+	PendingRecreations []string `json:"pendingRecreations,omitempty"`
+
+	// FlappingResources contains the objects changed by consecutive
+	// reconciliations while their manifests are unchanged, with the fields
+	// which differ in-cluster before the apply.
+	// +optional
+	FlappingResources []FlappingResource `json:"flappingResources,omitempty"`
+
+	// SuggestedIgnoreRules contains the rules ignoring the fields of the
+	// flapping objects, to be added to the DriftDetection ignore rules if
+	// the values set in-cluster are expected.
+	// +optional
+	SuggestedIgnoreRules []IgnoreRule `json:"suggestedIgnoreRules,omitempty"`
+
+	// ClusterScopedResources contains the cluster-scoped objects, in the
+	// 'Kind/name' format, of the Kustomizations of the tenants, which
+	// impersonate a service account outside the namespaces exempted from
+	// the tenant lockdown.
+	// +optional
+	ClusterScopedResources []string `json:"clusterScopedResources,omitempty"`
+
+	// Images contains the container images referenced in the manifests
+	// of the last applied revision, sorted and deduplicated.
+	// +optional
+	Images []string `json:"images,omitempty"`
+
+	// ManifestDigest is the digest of the normalized manifest stream of
+	// the last applied revision, in the 'sha256:<hex>' format.
+	// +optional
+	ManifestDigest string `json:"manifestDigest,omitempty"`
+
+	// Differences contains the objects, in the 'Kind/namespace/name: state'
+	// format, which are either 'missing' from the cluster or have 'drifted'
+	// from the manifests of the last observed revision, when the mode is
+	// 'Observe'.
+	// +optional
+	Differences []string `json:"differences,omitempty"`
+
+	// DecryptionResults contains the encrypted files under the path, in the
+	// 'path: result' format, where the result is either 'decrypted' or the
+	// decryption error, when the mode is 'VerifyDecryption'.
+	// +optional
+	DecryptionResults []string `json:"decryptionResults,omitempty"`
+
+	// ApplyProgress contains the progress of the apply of the objects in
+	// chunks across reconciliations, when their number is above the chunk
+	// size of the controller.
+	// +optional
+	ApplyProgress *ApplyProgress `json:"applyProgress,omitempty"`
+
+	// ArgoCDMigration contains the progress of the adoption of the objects
+	// of the Argo CD Application, when ArgoCDMigration is set.
+	// +optional
+	ArgoCDMigration *ArgoCDMigrationStatus `json:"argoCDMigration,omitempty"`
+
+	// LastFullApplyAt is the time at which all the objects were last
+	// applied, when the differential apply is enabled.
+	// +optional
+	LastFullApplyAt *metav1.Time `json:"lastFullApplyAt,omitempty"`
+
+	// AppliedRevisions lists the distinct revisions applied in the window
+	// of the revision rate limit, in the order they were applied.
+	// +optional
+	AppliedRevisions []AppliedRevision `json:"appliedRevisions,omitempty"`
+
+	// Dependencies contains the state of the Kustomizations referenced in
+	// DependsOn, the last time they were checked.
+	// +optional
+	Dependencies []DependencyStatus `json:"dependencies,omitempty"`
+
+	// Details references the ConfigMap holding the sections of the status
+	// moved out of the object because of its size.
+	// +optional
+	Details *StatusDetailsReference `json:"details,omitempty"`
+
+	// ClusterCapabilities contains the capabilities detected on the cluster
+	// the objects are applied to.
+	// +optional
+	ClusterCapabilities *ClusterCapabilities `json:"clusterCapabilities,omitempty"`
+
+	// EffectiveTimeout is the timeout of the last reconciliation, from the
+	// spec or defaulted by the controller.
+	// +optional
+	EffectiveTimeout *metav1.Duration `json:"effectiveTimeout,omitempty"`
+
+	// EffectiveRetryInterval is the interval at which a failed
+	// reconciliation is retried, from the spec or defaulted by the
+	// controller.
+	// +optional
+	EffectiveRetryInterval *metav1.Duration `json:"effectiveRetryInterval,omitempty"`
+
+	// Children contains the state of the Kustomizations applied by this
+	// Kustomization, when AggregateChildren is set.
+	// +optional
+	Children []ChildStatus `json:"children,omitempty"`
+}
+
+// ChildStatus contains the state of a Kustomization applied by another.
+type ChildStatus struct {
+	// Name of the child Kustomization.
+	// +required
+	Name string `json:"name"`
+
+	// Namespace of the child Kustomization.
+	// +required
+	Namespace string `json:"namespace"`
+
+	// Ready is true if the child Kustomization is ready and up to date
+	// with its generation.
+	// +required
+	Ready bool `json:"ready"`
+
+	// Revision is the last applied revision of the child Kustomization.
+	// +optional
+	Revision string `json:"revision,omitempty"`
+
+	// Message explains why the child Kustomization is not ready.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// ClusterCapabilities contains the capabilities of a Kubernetes cluster,
+// from which the controller adapts how the objects are applied.
+type ClusterCapabilities struct {
+	// Version is the Kubernetes version of the cluster.
+	// +required
+	Version string `json:"version"`
+
+	// ServerSideApply is true when the cluster supports server-side apply,
+	// generally available since Kubernetes 1.22. Otherwise the objects are
+	// applied with client-side apply.
+	// +optional
+	ServerSideApply bool `json:"serverSideApply"`
+
+	// ValidatingAdmissionPolicy is true when the cluster serves the CEL
+	// ValidatingAdmissionPolicies.
+	// +optional
+	ValidatingAdmissionPolicy bool `json:"validatingAdmissionPolicy"`
+
+	// ApplySet is true when the cluster supports the pruning of the kubectl
+	// ApplySets, available since Kubernetes 1.27.
+	// +optional
+	ApplySet bool `json:"applySet"`
+
+	// StrictFieldValidation is true when the API server rejects the unknown
+	// and duplicate fields of the requests with strict field validation,
+	// enabled by default since Kubernetes 1.25.
+	// +optional
+	StrictFieldValidation bool `json:"strictFieldValidation"`
+}
+
+// StatusDetailsReference references the ConfigMap holding the sections of
+// the status which are too large to be stored in the object.
+type StatusDetailsReference struct {
+	// Name of the ConfigMap, in the namespace of the Kustomization.
+	// +required
+	Name string `json:"name"`
+
+	// Sections lists the status fields stored in the ConfigMap, one of
+	// 'buildWarnings', 'unmanagedOverrides', 'images', 'differences',
+	// 'inventory' and 'conditions'.
+	// The 'conditions' section holds the conditions whose message is
+	// truncated in the object.
+	// +required
+	Sections []string `json:"sections"`
+}
+
+// DependencyStatus contains the state of a dependency of the Kustomization.
+type DependencyStatus struct {
+	// Name of the dependency.
+	// +required
+	Name string `json:"name"`
+
+	// Namespace of the dependency.
+	// +required
+	Namespace string `json:"namespace"`
+
+	// Ready is true if the dependency is ready and up to date.
+	// +required
+	Ready bool `json:"ready"`
+
+	// Revision is the last applied revision of the dependency.
+	// +optional
+	Revision string `json:"revision,omitempty"`
+
+	// Message explains why the dependency is not ready.
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// WaitingSince is the time since which the Kustomization
+	// waits for the dependency to be ready.
+	// +optional
+	WaitingSince *metav1.Time `json:"waitingSince,omitempty"`
 }
 
 // GetTimeout returns the timeout with default.
@@ -259,6 +1436,12 @@ func (in Kustomization) GetTimeout() time.Duration {
 	return duration
 }
 
+// IsRemote returns true if the Kustomization is applied to a remote
+// cluster, with either a kubeconfig or the remote cluster connection.
+func (in Kustomization) IsRemote() bool {
+	return in.Spec.KubeConfig != nil || in.Spec.RemoteCluster != nil
+}
+
 // GetRetryInterval returns the retry interval
 func (in Kustomization) GetRetryInterval() time.Duration {
 	if in.Spec.RetryInterval != nil {
@@ -278,6 +1461,43 @@ func (in Kustomization) GetDependsOn() []meta.NamespacedObjectReference {
 	return in.Spec.DependsOn
 }
 
+// GetDriftDetectionInterval returns the interval at which all the objects
+// are applied when the differential apply is enabled, defaulting to one hour.
+func (in Kustomization) GetDriftDetectionInterval() time.Duration {
+	if in.Spec.DifferentialApply != nil && in.Spec.DifferentialApply.DriftDetectionInterval != nil {
+		return in.Spec.DifferentialApply.DriftDetectionInterval.Duration
+	}
+	return time.Hour
+}
+
+// GetRevisionRateLimitWindow returns the window of the revision rate limit,
+// defaulting to one hour.
+func (in Kustomization) GetRevisionRateLimitWindow() time.Duration {
+	if in.Spec.RevisionRateLimit != nil && in.Spec.RevisionRateLimit.Window != nil {
+		return in.Spec.RevisionRateLimit.Window.Duration
+	}
+	return time.Hour
+}
+
+// GetImmutableConfigRotation returns the rotation of the immutable
+// ConfigMaps and Secrets, defaulting to RenameRotation.
+func (in Kustomization) GetImmutableConfigRotation() string {
+	if in.Spec.ImmutableConfig == nil || in.Spec.ImmutableConfig.Rotation == "" {
+		return RenameRotation
+	}
+	return in.Spec.ImmutableConfig.Rotation
+}
+
+// GetLoadRestrictions returns the configured load restrictions,
+// defaulting to LoadRestrictionsRootOnly.
+
+func (in Kustomization) GetLoadRestrictions() string {
+	if in.Spec.BuildOptions == nil || in.Spec.BuildOptions.LoadRestrictions == "" {
+		return LoadRestrictionsRootOnly
+	}
+	return in.Spec.BuildOptions.LoadRestrictions
+}
+
 // GetConditions returns the status conditions of the object.
 func (in Kustomization) GetConditions() []metav1.Condition {
 	return in.Status.Conditions
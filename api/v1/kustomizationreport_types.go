@@ -0,0 +1,111 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	KustomizationReportKind = "KustomizationReport"
+
+	// KustomizationReportName is the name of the report maintained by the
+	// controller in each namespace with Kustomizations.
+	KustomizationReportName = "kustomizations"
+)
+
+// KustomizationReportStatus summarizes the Kustomizations of a namespace.
+type KustomizationReportStatus struct {
+	// Total is the number of Kustomizations in the namespace.
+	Total int `json:"total"`
+
+	// Ready is the number of Kustomizations whose Ready condition is true.
+	Ready int `json:"ready"`
+
+	// Failing is the number of Kustomizations whose Ready condition is
+	// false.
+	Failing int `json:"failing"`
+
+	// Suspended is the number of suspended Kustomizations.
+	Suspended int `json:"suspended"`
+
+	// FailingNames are the names of the Kustomizations whose Ready
+	// condition is false.
+	// +optional
+	FailingNames []string `json:"failingNames,omitempty"`
+
+	// Kustomizations are the status summaries of the Kustomizations,
+	// sorted by name.
+	// +optional
+	Kustomizations []KustomizationSummary `json:"kustomizations,omitempty"`
+}
+
+// KustomizationSummary is the status summary of a Kustomization.
+type KustomizationSummary struct {
+	// Name of the Kustomization.
+	// +required
+	Name string `json:"name"`
+
+	// Ready is the status of the Ready condition, 'True', 'False' or
+	// 'Unknown'.
+	// +required
+	Ready metav1.ConditionStatus `json:"ready"`
+
+	// Reason of the Ready condition.
+	// +optional
+	Reason string `json:"reason,omitempty"`
+
+	// Suspended is true when the Kustomization is suspended.
+	// +optional
+	Suspended bool `json:"suspended,omitempty"`
+
+	// LastAppliedRevision of the Kustomization.
+	// +optional
+	LastAppliedRevision string `json:"lastAppliedRevision,omitempty"`
+}
+
+// +genclient
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:shortName=ksreport
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp",description=""
+// +kubebuilder:printcolumn:name="Total",type="integer",JSONPath=".status.total",description=""
+// +kubebuilder:printcolumn:name="Ready",type="integer",JSONPath=".status.ready",description=""
+// +kubebuilder:printcolumn:name="Failing",type="integer",JSONPath=".status.failing",description=""
+// +kubebuilder:printcolumn:name="Suspended",type="integer",JSONPath=".status.suspended",description=""
+
+// KustomizationReport is the Schema for the kustomizationreports API, which
+// summarizes the status of the Kustomizations of its namespace. It's
+// maintained by the controller, and written as a whole.
+type KustomizationReport struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Status KustomizationReportStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// KustomizationReportList contains a list of Kustomization reports.
+type KustomizationReportList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []KustomizationReport `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&KustomizationReport{}, &KustomizationReportList{})
+}
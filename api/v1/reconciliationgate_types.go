@@ -0,0 +1,75 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	ReconciliationGateKind = "ReconciliationGate"
+)
+
+// ReconciliationGateSpec defines the Kustomizations paused by the gate.
+type ReconciliationGateSpec struct {
+	// Closed pauses the applies of the selected Kustomizations, which are
+	// still reconciled to report their status. Defaults to false.
+	// +optional
+	Closed bool `json:"closed,omitempty"`
+
+	// Selector selects the Kustomizations paused by the gate by their
+	// labels, in all namespaces. An empty selector selects all the
+	// Kustomizations.
+	// +required
+	Selector metav1.LabelSelector `json:"selector"`
+
+	// Message is reported in the Ready condition of the paused
+	// Kustomizations, e.g. the reason of the freeze or the reference
+	// of the incident.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// +genclient
+// +genclient:nonNamespaced
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster,shortName=rgate
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp",description=""
+// +kubebuilder:printcolumn:name="Closed",type="boolean",JSONPath=".spec.closed",description=""
+// +kubebuilder:printcolumn:name="Message",type="string",JSONPath=".spec.message",description=""
+
+// ReconciliationGate is the Schema for the reconciliationgates API, which
+// pauses the applies of the Kustomizations selected by labels while closed.
+type ReconciliationGate struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ReconciliationGateSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ReconciliationGateList contains a list of reconciliation gates.
+type ReconciliationGateList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ReconciliationGate `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ReconciliationGate{}, &ReconciliationGateList{})
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package applytimeout bounds the duration of the requests made for each
+// object, so that an unresponsive API, e.g. an aggregated API whose backend
+// hangs, fails fast instead of consuming the timeout of the whole
+// reconciliation.
+package applytimeout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	ssautil "github.com/fluxcd/pkg/ssa/utils"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Error is returned when a request made for an object exceeds the timeout.
+type Error struct {
+	// Object identifies the object, in the 'Kind/namespace/name' format.
+	Object string
+
+	// Timeout is the per-object timeout.
+	Timeout time.Duration
+
+	err error
+}
+
+// Error implements error.
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s apply timed out after %s: %s", e.Object, e.Timeout, e.err)
+}
+
+// Unwrap returns the error of the request.
+func (e *Error) Unwrap() error {
+	return e.err
+}
+
+// Client wraps a client to bound the duration of the get and write requests
+// of each object.
+type Client struct {
+	client.Client
+
+	timeout time.Duration
+}
+
+// NewClient returns a Client bounding the requests to the given timeout.
+func NewClient(c client.Client, timeout time.Duration) *Client {
+	return &Client{Client: c, timeout: timeout}
+}
+
+// Get gets the object within the timeout.
+func (c *Client) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	return c.do(ctx, obj, func(ctx context.Context) error {
+		return c.Client.Get(ctx, key, obj, opts...)
+	})
+}
+
+// Create creates the object within the timeout.
+func (c *Client) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	return c.do(ctx, obj, func(ctx context.Context) error {
+		return c.Client.Create(ctx, obj, opts...)
+	})
+}
+
+// Patch patches the object within the timeout.
+func (c *Client) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	return c.do(ctx, obj, func(ctx context.Context) error {
+		return c.Client.Patch(ctx, obj, patch, opts...)
+	})
+}
+
+// Update updates the object within the timeout.
+func (c *Client) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	return c.do(ctx, obj, func(ctx context.Context) error {
+		return c.Client.Update(ctx, obj, opts...)
+	})
+}
+
+// Delete deletes the object within the timeout.
+func (c *Client) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	return c.do(ctx, obj, func(ctx context.Context) error {
+		return c.Client.Delete(ctx, obj, opts...)
+	})
+}
+
+// do runs the request with the timeout, and returns an Error if the
+// request failed because the timeout expired, and not the parent context.
+func (c *Client) do(ctx context.Context, obj client.Object, request func(context.Context) error) error {
+	reqCtx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	err := request(reqCtx)
+	if err == nil || ctx.Err() != nil || !errors.Is(reqCtx.Err(), context.DeadlineExceeded) {
+		return err
+	}
+
+	name := fmt.Sprintf("%s/%s", obj.GetNamespace(), obj.GetName())
+	if u, ok := obj.(*unstructured.Unstructured); ok {
+		name = ssautil.FmtUnstructured(u)
+	}
+	return &Error{Object: name, Timeout: c.timeout, err: err}
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package applytimeout
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func configMap(name string) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{}
+	u.SetAPIVersion("v1")
+	u.SetKind("ConfigMap")
+	u.SetNamespace("apps")
+	u.SetName(name)
+	return u
+}
+
+func TestClient(t *testing.T) {
+	// The patches of the 'hung' object block until the context is done.
+	c := NewClient(fake.NewClientBuilder().WithInterceptorFuncs(interceptor.Funcs{
+		Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+			if obj.GetName() == "hung" {
+				<-ctx.Done()
+				return ctx.Err()
+			}
+			return c.Patch(ctx, obj, patch, opts...)
+		},
+	}).Build(), 50*time.Millisecond)
+
+	t.Run("times out the hung requests", func(t *testing.T) {
+		g := NewWithT(t)
+		err := c.Patch(context.Background(), configMap("hung"), client.Apply, client.FieldOwner("test"))
+		var timeoutErr *Error
+		g.Expect(errors.As(err, &timeoutErr)).To(BeTrue())
+		g.Expect(timeoutErr.Object).To(Equal("ConfigMap/apps/hung"))
+		g.Expect(err.Error()).To(HavePrefix("ConfigMap/apps/hung apply timed out after 50ms"))
+		g.Expect(errors.Is(err, context.DeadlineExceeded)).To(BeTrue())
+	})
+
+	t.Run("passes through the other requests", func(t *testing.T) {
+		g := NewWithT(t)
+		g.Expect(c.Create(context.Background(), configMap("ok"))).To(Succeed())
+		g.Expect(c.Get(context.Background(), client.ObjectKey{Namespace: "apps", Name: "ok"}, configMap(""))).To(Succeed())
+	})
+
+	t.Run("returns the error of the cancelled parent context", func(t *testing.T) {
+		g := NewWithT(t)
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		err := c.Patch(ctx, configMap("hung"), client.Apply, client.FieldOwner("test"))
+		var timeoutErr *Error
+		g.Expect(errors.As(err, &timeoutErr)).To(BeFalse())
+		g.Expect(errors.Is(err, context.DeadlineExceeded)).To(BeTrue())
+	})
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package argocd recognizes the objects tracked by an Argo CD Application,
+// and removes the tracking metadata from the objects adopted by a
+// Kustomization once the migration is over.
+package argocd
+
+import (
+	"encoding/json"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	// TrackingIDAnnotation is the annotation set by Argo CD with the
+	// annotation tracking method, in the format
+	// '<application>:<group>/<kind>:<namespace>/<name>'.
+	TrackingIDAnnotation = "argocd.argoproj.io/tracking-id"
+
+	// InstanceLabel is the label set by Argo CD with the label tracking
+	// method, the default one.
+	InstanceLabel = "app.kubernetes.io/instance"
+)
+
+// IsTracked returns whether the in-cluster object carries the tracking
+// metadata of the given application. The instance label is only considered
+// when the desired object doesn't set it, as it is commonly set by charts.
+// The application can be prefixed with the namespace of the Application,
+// in the '<namespace>_<name>' format used by Argo CD for the Applications
+// outside of its namespace.
+func IsTracked(existing metav1.Object, desired *unstructured.Unstructured, application string) bool {
+	if id, ok := existing.GetAnnotations()[TrackingIDAnnotation]; ok {
+		if matches(strings.SplitN(id, ":", 2)[0], application) {
+			return true
+		}
+	}
+	if _, ok := desired.GetLabels()[InstanceLabel]; ok {
+		return false
+	}
+	return matches(existing.GetLabels()[InstanceLabel], application)
+}
+
+func matches(value, application string) bool {
+	return value != "" && (value == application || strings.HasSuffix(value, "_"+application))
+}
+
+// StripPatch returns the JSON merge patch removing the tracking metadata,
+// and the last applied configuration used by Argo CD to compute its diffs,
+// from the in-cluster object. The metadata set by the desired object is
+// kept. It returns nil when there is nothing to remove.
+func StripPatch(existing metav1.Object, desired *unstructured.Unstructured) ([]byte, error) {
+	annotations := make(map[string]interface{})
+	for _, key := range []string{TrackingIDAnnotation, corev1.LastAppliedConfigAnnotation} {
+		if _, ok := existing.GetAnnotations()[key]; !ok {
+			continue
+		}
+		if _, ok := desired.GetAnnotations()[key]; !ok {
+			annotations[key] = nil
+		}
+	}
+	labels := make(map[string]interface{})
+	if _, ok := existing.GetLabels()[InstanceLabel]; ok {
+		if _, ok := desired.GetLabels()[InstanceLabel]; !ok {
+			labels[InstanceLabel] = nil
+		}
+	}
+	if len(annotations) == 0 && len(labels) == 0 {
+		return nil, nil
+	}
+
+	metadata := make(map[string]interface{})
+	if len(annotations) > 0 {
+		metadata["annotations"] = annotations
+	}
+	if len(labels) > 0 {
+		metadata["labels"] = labels
+	}
+	return json.Marshal(map[string]interface{}{"metadata": metadata})
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package argocd
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func newObject(labels, annotations map[string]string) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{}
+	u.SetLabels(labels)
+	u.SetAnnotations(annotations)
+	return u
+}
+
+func TestIsTracked(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing *unstructured.Unstructured
+		desired  *unstructured.Unstructured
+		want     bool
+	}{
+		{
+			name:     "tracking annotation",
+			existing: newObject(nil, map[string]string{TrackingIDAnnotation: "podinfo:apps/Deployment:apps/podinfo"}),
+			desired:  newObject(nil, nil),
+			want:     true,
+		},
+		{
+			name:     "tracking annotation of an Application in any namespace",
+			existing: newObject(nil, map[string]string{TrackingIDAnnotation: "team_podinfo:apps/Deployment:apps/podinfo"}),
+			desired:  newObject(nil, nil),
+			want:     true,
+		},
+		{
+			name:     "tracking annotation of another Application",
+			existing: newObject(nil, map[string]string{TrackingIDAnnotation: "podinfo-dev:apps/Deployment:apps/podinfo"}),
+			desired:  newObject(nil, nil),
+			want:     false,
+		},
+		{
+			name:     "instance label",
+			existing: newObject(map[string]string{InstanceLabel: "podinfo"}, nil),
+			desired:  newObject(nil, nil),
+			want:     true,
+		},
+		{
+			name:     "instance label of the manifests",
+			existing: newObject(map[string]string{InstanceLabel: "podinfo"}, nil),
+			desired:  newObject(map[string]string{InstanceLabel: "podinfo"}, nil),
+			want:     false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(IsTracked(tt.existing, tt.desired, "podinfo")).To(Equal(tt.want))
+		})
+	}
+}
+
+func TestStripPatch(t *testing.T) {
+	g := NewWithT(t)
+
+	existing := &metav1.ObjectMeta{
+		Labels: map[string]string{InstanceLabel: "podinfo", "app": "podinfo"},
+		Annotations: map[string]string{
+			TrackingIDAnnotation: "podinfo:apps/Deployment:apps/podinfo",
+			"kubectl.kubernetes.io/last-applied-configuration": "{}",
+		},
+	}
+	patch, err := StripPatch(existing, newObject(map[string]string{"app": "podinfo"}, nil))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(string(patch)).To(Equal(`{"metadata":{"annotations":{"argocd.argoproj.io/tracking-id":null,` +
+		`"kubectl.kubernetes.io/last-applied-configuration":null},"labels":{"app.kubernetes.io/instance":null}}}`))
+
+	patch, err = StripPatch(existing, newObject(map[string]string{InstanceLabel: "podinfo"},
+		map[string]string{TrackingIDAnnotation: "podinfo", "kubectl.kubernetes.io/last-applied-configuration": "{}"}))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(patch).To(BeNil())
+}
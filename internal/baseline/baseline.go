@@ -0,0 +1,65 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package baseline renders the objects defined by the cluster operators,
+// e.g. NetworkPolicies and ResourceQuotas, which are stamped into the target
+// namespaces created by the Kustomizations.
+package baseline
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	ssautil "github.com/fluxcd/pkg/ssa/utils"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// NamespaceVariable is replaced with the name of the target namespace
+// in the templates.
+const NamespaceVariable = "${namespace}"
+
+// Render returns the objects of the templates, in the order of their keys,
+// for the given namespace. The objects without namespace are set to it, and
+// an error is returned for the objects of other namespaces.
+func Render(templates map[string]string, namespace string) ([]*unstructured.Unstructured, error) {
+	keys := make([]string, 0, len(templates))
+	for key := range templates {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var objects []*unstructured.Unstructured
+	for _, key := range keys {
+		manifests := strings.ReplaceAll(templates[key], NamespaceVariable, namespace)
+		objs, err := ssautil.ReadObjects(strings.NewReader(manifests))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the baseline template '%s': %w", key, err)
+		}
+		for _, u := range objs {
+			switch u.GetNamespace() {
+			case "":
+				u.SetNamespace(namespace)
+			case namespace:
+			default:
+				return nil, fmt.Errorf("the baseline template '%s' contains %s outside of the target namespace",
+					key, ssautil.FmtUnstructured(u))
+			}
+		}
+		objects = append(objects, objs...)
+	}
+	return objects, nil
+}
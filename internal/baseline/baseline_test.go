@@ -0,0 +1,70 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package baseline
+
+import (
+	"testing"
+
+	ssautil "github.com/fluxcd/pkg/ssa/utils"
+	. "github.com/onsi/gomega"
+)
+
+func TestRender(t *testing.T) {
+	g := NewWithT(t)
+
+	objects, err := Render(map[string]string{
+		"quota.yaml": `apiVersion: v1
+kind: ResourceQuota
+metadata:
+  name: baseline
+  namespace: ${namespace}
+spec:
+  hard:
+    pods: "20"
+`,
+		"network.yaml": `apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: deny-ingress
+  labels:
+    tenant: ${namespace}
+spec:
+  podSelector: {}
+  policyTypes:
+  - Ingress
+`,
+	}, "apps")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(objects).To(HaveLen(2))
+	g.Expect(ssautil.FmtUnstructured(objects[0])).To(Equal("NetworkPolicy/apps/deny-ingress"))
+	g.Expect(objects[0].GetLabels()).To(HaveKeyWithValue("tenant", "apps"))
+	g.Expect(ssautil.FmtUnstructured(objects[1])).To(Equal("ResourceQuota/apps/baseline"))
+
+	t.Run("rejects the objects of other namespaces", func(t *testing.T) {
+		g := NewWithT(t)
+		_, err := Render(map[string]string{
+			"quota.yaml": `apiVersion: v1
+kind: ResourceQuota
+metadata:
+  name: baseline
+  namespace: kube-system
+`,
+		}, "apps")
+		g.Expect(err).To(HaveOccurred())
+		g.Expect(err.Error()).To(ContainSubstring("ResourceQuota/kube-system/baseline outside of the target namespace"))
+	})
+}
@@ -0,0 +1,245 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package build runs kustomize builds with the settings configured for a
+// v1.Kustomization.
+package build
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+
+	securefs "github.com/fluxcd/pkg/kustomize/filesys"
+	"sigs.k8s.io/kustomize/api/konfig"
+	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/api/provenance"
+	"sigs.k8s.io/kustomize/api/resmap"
+	kustypes "sigs.k8s.io/kustomize/api/types"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+	"sigs.k8s.io/kustomize/kyaml/openapi"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// OptionLoadRestrictions is the name of the load restrictions build option.
+	OptionLoadRestrictions = "loadRestrictions"
+	// OptionReorder is the name of the reorder build option.
+	OptionReorder = "reorder"
+	// OptionAddManagedByLabel is the name of the managed-by label build option.
+	OptionAddManagedByLabel = "addManagedByLabel"
+	// OptionManagedByLabelValue is the name of the managed-by label value build option.
+	OptionManagedByLabelValue = "managedByLabelValue"
+	// OptionOpenAPIPath is the name of the OpenAPI schema path build option.
+	OptionOpenAPIPath = "openAPIPath"
+	// OptionOpenAPISchemaFrom is the name of the OpenAPI schema source build option.
+	OptionOpenAPISchemaFrom = "openAPISchemaFrom"
+)
+
+// openAPISchemaFile is the name of the file the OpenAPI schema is written
+// to, when passed to the build as bytes.
+const openAPISchemaFile = ".kustomize-openapi-schema.json"
+
+// SupportedOptions lists the names of the build options which can be
+// set on a Kustomization.
+var SupportedOptions = []string{
+	OptionLoadRestrictions,
+	OptionReorder,
+	OptionAddManagedByLabel,
+	OptionManagedByLabelValue,
+	OptionOpenAPIPath,
+	OptionOpenAPISchemaFrom,
+}
+
+// buildMutex is a workaround for the concurrent map read and map write bug
+// in kustomize, https://github.com/kubernetes-sigs/kustomize/issues/3659
+var buildMutex sync.Mutex
+
+// Options holds the kustomize build settings.
+type Options struct {
+	// AllowRemoteBases allows the overlay to refer to remote bases.
+	AllowRemoteBases bool
+
+	// LocalOnly denies the references to remote bases and files, which
+	// kustomize otherwise fetches over the network. It takes precedence
+	// over AllowRemoteBases.
+	LocalOnly bool
+
+	// LoadRestrictions restricts the files kustomize is allowed to load,
+	// defaults to kustypes.LoadRestrictionsRootOnly. The secure filesystem
+	// denies any operation outside the root, regardless of this setting.
+	LoadRestrictions kustypes.LoadRestrictions
+
+	// Reorder sets the sort order of the resources in the build output,
+	// defaults to krusty.ReorderOptionNone which keeps the input order.
+	Reorder krusty.ReorderOption
+
+	// AddManagedByLabel adds the 'app.kubernetes.io/managed-by' label
+	// to all the resources in the build output.
+	AddManagedByLabel bool
+
+	// RemoveManagedByLabel removes the 'app.kubernetes.io/managed-by' label
+	// added by kustomize when requested by the kustomization file.
+	RemoveManagedByLabel bool
+
+	// ManagedByLabelValue overrides the value of the 'app.kubernetes.io/managed-by'
+	// label added by kustomize.
+	ManagedByLabelValue string
+
+	// OpenAPIPath is the path, relative to the overlay, of an OpenAPI schema
+	// file used by kustomize for strategic merge patches of custom resources.
+	// It overrides the 'openapi' field of the kustomization file.
+	OpenAPIPath string
+
+	// OpenAPISchema is an OpenAPI schema document, in JSON or YAML format,
+	// used by kustomize for strategic merge patches of custom resources.
+	// It can't be set together with OpenAPIPath.
+	OpenAPISchema []byte
+}
+
+// SecureBuild runs the kustomize build for the overlay at dirPath with the
+// given options, on a secure on-disk filesystem denying operations outside
+// root, and with all plugins disabled except for the builtin ones.
+func SecureBuild(root, dirPath string, opts Options) (res resmap.ResMap, err error) {
+	var fs filesys.FileSystem
+	if opts.AllowRemoteBases && !opts.LocalOnly {
+		fs, err = securefs.MakeFsOnDiskSecureBuild(root)
+	} else {
+		fs, err = securefs.MakeFsOnDiskSecure(root)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if opts.LocalOnly {
+		if err := checkLocalReferences(fs, dirPath, make(map[string]bool)); err != nil {
+			return nil, err
+		}
+	}
+
+	buildMutex.Lock()
+	defer buildMutex.Unlock()
+
+	// Kustomize tends to panic in unpredicted ways due to (accidental)
+	// invalid object data; recover when this happens to ensure continuity of
+	// operations.
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("recovered from kustomize build panic: %v", r)
+		}
+	}()
+
+	loadRestrictions := opts.LoadRestrictions
+	if loadRestrictions == kustypes.LoadRestrictionsUnknown {
+		loadRestrictions = kustypes.LoadRestrictionsRootOnly
+	}
+
+	reorder := opts.Reorder
+	if reorder == "" {
+		reorder = krusty.ReorderOptionNone
+	}
+
+	// Kustomize keeps the OpenAPI schema in a global variable,
+	// make sure it doesn't leak into the next build.
+	defer openapi.ResetOpenAPI()
+
+	openAPIPath := opts.OpenAPIPath
+	if len(opts.OpenAPISchema) > 0 {
+		if openAPIPath != "" {
+			return nil, fmt.Errorf("the OpenAPI schema path and the OpenAPI schema are mutually exclusive")
+		}
+		if err := fs.WriteFile(filepath.Join(dirPath, openAPISchemaFile), opts.OpenAPISchema); err != nil {
+			return nil, err
+		}
+		openAPIPath = openAPISchemaFile
+	}
+	if openAPIPath != "" {
+		if err := setOpenAPIPath(fs, dirPath, openAPIPath); err != nil {
+			return nil, err
+		}
+	}
+
+	// Exec and Go plugins are always disabled, only builtin plugins can run.
+	k := krusty.MakeKustomizer(&krusty.Options{
+		LoadRestrictions:  loadRestrictions,
+		Reorder:           reorder,
+		AddManagedbyLabel: opts.AddManagedByLabel,
+		PluginConfig:      kustypes.DisabledPluginConfig(),
+	})
+	m, err := k.Run(fs, dirPath)
+	if err != nil {
+		return nil, err
+	}
+	if opts.RemoveManagedByLabel || opts.ManagedByLabelValue != "" {
+		if err := setManagedByLabel(m, opts.RemoveManagedByLabel, opts.ManagedByLabelValue); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// setManagedByLabel removes or sets the value of the managed-by label added
+// by kustomize. The labels set in the manifests to another value are kept.
+func setManagedByLabel(m resmap.ResMap, remove bool, value string) error {
+	injected := fmt.Sprintf("kustomize-%s", provenance.GetProvenance().Semver())
+	for _, res := range m.Resources() {
+		labels := res.GetLabels()
+		if labels[konfig.ManagedbyLabelKey] != injected {
+			continue
+		}
+		if remove {
+			delete(labels, konfig.ManagedbyLabelKey)
+		} else {
+			labels[konfig.ManagedbyLabelKey] = value
+		}
+		if err := res.SetLabels(labels); err != nil {
+			return fmt.Errorf("failed to set the labels of %s: %w", res.CurId(), err)
+		}
+	}
+	return nil
+}
+
+// setOpenAPIPath sets the 'openapi.path' field of the kustomization file
+// in dirPath to the given path.
+func setOpenAPIPath(fs filesys.FileSystem, dirPath, openAPIPath string) error {
+	if filepath.IsAbs(openAPIPath) {
+		return fmt.Errorf("openapi path '%s' must be relative to the kustomization path", openAPIPath)
+	}
+
+	for _, fileName := range konfig.RecognizedKustomizationFileNames() {
+		kfile := filepath.Join(dirPath, fileName)
+		if !fs.Exists(kfile) {
+			continue
+		}
+
+		data, err := fs.ReadFile(kfile)
+		if err != nil {
+			return err
+		}
+		kus := make(map[string]interface{})
+		if err := yaml.Unmarshal(data, &kus); err != nil {
+			return fmt.Errorf("failed to decode %s: %w", fileName, err)
+		}
+		kus["openapi"] = map[string]string{"path": openAPIPath}
+
+		data, err = yaml.Marshal(kus)
+		if err != nil {
+			return err
+		}
+		return fs.WriteFile(kfile, data)
+	}
+
+	return fmt.Errorf("no kustomization file found in '%s'", dirPath)
+}
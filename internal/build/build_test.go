@@ -0,0 +1,302 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package build
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"sigs.k8s.io/kustomize/api/krusty"
+	kustypes "sigs.k8s.io/kustomize/api/types"
+)
+
+func Test_SecureBuild_LoadRestrictions(t *testing.T) {
+	root, err := filepath.Abs("testdata/restrictions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dirPath := filepath.Join(root, "overlay")
+
+	t.Run("loads files outside the kustomization root", func(t *testing.T) {
+		g := NewWithT(t)
+		m, err := SecureBuild(root, dirPath, Options{
+			LoadRestrictions: kustypes.LoadRestrictionsNone,
+		})
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(m.Resources()).To(HaveLen(1))
+	})
+
+	t.Run("defaults to root only", func(t *testing.T) {
+		g := NewWithT(t)
+		_, err := SecureBuild(root, dirPath, Options{})
+		g.Expect(err).To(HaveOccurred())
+	})
+
+	t.Run("restricts loading to the kustomization root", func(t *testing.T) {
+		g := NewWithT(t)
+		_, err := SecureBuild(root, dirPath, Options{
+			LoadRestrictions: kustypes.LoadRestrictionsRootOnly,
+		})
+		g.Expect(err).To(HaveOccurred())
+		g.Expect(err.Error()).To(ContainSubstring("security; file"))
+	})
+}
+
+func Test_SecureBuild_Options(t *testing.T) {
+	t.Run("keeps the input order by default", func(t *testing.T) {
+		g := NewWithT(t)
+		root := copyTestData(t, "testdata/options")
+
+		m, err := SecureBuild(root, root, Options{})
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(m.Resources()).To(HaveLen(2))
+		g.Expect(m.Resources()[0].GetKind()).To(Equal("Deployment"))
+		g.Expect(m.Resources()[0].GetLabels()).ToNot(HaveKey("app.kubernetes.io/managed-by"))
+	})
+
+	t.Run("reorders resources in legacy order", func(t *testing.T) {
+		g := NewWithT(t)
+		root := copyTestData(t, "testdata/options")
+
+		m, err := SecureBuild(root, root, Options{Reorder: krusty.ReorderOptionLegacy})
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(m.Resources()[0].GetKind()).To(Equal("Namespace"))
+	})
+
+	t.Run("adds the managed-by label", func(t *testing.T) {
+		g := NewWithT(t)
+		root := copyTestData(t, "testdata/options")
+
+		m, err := SecureBuild(root, root, Options{AddManagedByLabel: true})
+		g.Expect(err).ToNot(HaveOccurred())
+		for _, res := range m.Resources() {
+			g.Expect(res.GetLabels()).To(HaveKey("app.kubernetes.io/managed-by"))
+		}
+	})
+
+	t.Run("overrides the managed-by label value", func(t *testing.T) {
+		g := NewWithT(t)
+		root := copyTestData(t, "testdata/options")
+
+		m, err := SecureBuild(root, root, Options{AddManagedByLabel: true, ManagedByLabelValue: "flux"})
+		g.Expect(err).ToNot(HaveOccurred())
+		for _, res := range m.Resources() {
+			g.Expect(res.GetLabels()).To(HaveKeyWithValue("app.kubernetes.io/managed-by", "flux"))
+		}
+	})
+
+	t.Run("removes the managed-by label requested by the kustomization", func(t *testing.T) {
+		g := NewWithT(t)
+		root := copyTestData(t, "testdata/options")
+
+		kfile := filepath.Join(root, "kustomization.yaml")
+		data, err := os.ReadFile(kfile)
+		g.Expect(err).ToNot(HaveOccurred())
+		data = append(data, []byte("buildMetadata: [managedByLabel]\n")...)
+		g.Expect(os.WriteFile(kfile, data, 0o644)).To(Succeed())
+
+		m, err := SecureBuild(root, root, Options{})
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(m.Resources()[0].GetLabels()).To(HaveKey("app.kubernetes.io/managed-by"))
+
+		m, err = SecureBuild(root, root, Options{RemoveManagedByLabel: true})
+		g.Expect(err).ToNot(HaveOccurred())
+		for _, res := range m.Resources() {
+			g.Expect(res.GetLabels()).ToNot(HaveKey("app.kubernetes.io/managed-by"))
+		}
+	})
+
+	t.Run("sets the openapi path", func(t *testing.T) {
+		g := NewWithT(t)
+		root := copyTestData(t, "testdata/options")
+
+		_, err := SecureBuild(root, root, Options{OpenAPIPath: "schema.json"})
+		g.Expect(err).ToNot(HaveOccurred())
+
+		data, err := os.ReadFile(filepath.Join(root, "kustomization.yaml"))
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(string(data)).To(ContainSubstring("path: schema.json"))
+	})
+
+	t.Run("sets the openapi schema", func(t *testing.T) {
+		g := NewWithT(t)
+		root := copyTestData(t, "testdata/options")
+
+		schema, err := os.ReadFile(filepath.Join(root, "schema.json"))
+		g.Expect(err).ToNot(HaveOccurred())
+
+		_, err = SecureBuild(root, root, Options{OpenAPISchema: schema})
+		g.Expect(err).ToNot(HaveOccurred())
+
+		data, err := os.ReadFile(filepath.Join(root, "kustomization.yaml"))
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(string(data)).To(ContainSubstring("path: " + openAPISchemaFile))
+	})
+
+	t.Run("fails for both openapi path and schema", func(t *testing.T) {
+		g := NewWithT(t)
+		root := copyTestData(t, "testdata/options")
+
+		_, err := SecureBuild(root, root, Options{OpenAPIPath: "schema.json", OpenAPISchema: []byte("{}")})
+		g.Expect(err).To(HaveOccurred())
+		g.Expect(err.Error()).To(ContainSubstring("mutually exclusive"))
+	})
+
+	t.Run("fails for a missing openapi file", func(t *testing.T) {
+		g := NewWithT(t)
+		root := copyTestData(t, "testdata/options")
+
+		_, err := SecureBuild(root, root, Options{OpenAPIPath: "missing.json"})
+		g.Expect(err).To(HaveOccurred())
+	})
+
+	t.Run("fails for an absolute openapi path", func(t *testing.T) {
+		g := NewWithT(t)
+		root := copyTestData(t, "testdata/options")
+
+		_, err := SecureBuild(root, root, Options{OpenAPIPath: "/etc/schema.json"})
+		g.Expect(err).To(HaveOccurred())
+		g.Expect(err.Error()).To(ContainSubstring("must be relative"))
+	})
+}
+
+func Test_SecureBuild_OpenAPISchema(t *testing.T) {
+	schema, err := os.ReadFile("testdata/openapi/schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	items := func(g *WithT, opts Options) []interface{} {
+		root := copyTestData(t, "testdata/openapi")
+		m, err := SecureBuild(root, root, opts)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(m.Resources()).To(HaveLen(1))
+		obj, err := m.Resources()[0].Map()
+		g.Expect(err).ToNot(HaveOccurred())
+		return obj["spec"].(map[string]interface{})["items"].([]interface{})
+	}
+
+	t.Run("replaces lists without a schema", func(t *testing.T) {
+		g := NewWithT(t)
+		g.Expect(items(g, Options{})).To(HaveLen(1))
+	})
+
+	t.Run("merges lists with a schema", func(t *testing.T) {
+		g := NewWithT(t)
+		g.Expect(items(g, Options{OpenAPISchema: schema})).To(HaveLen(2))
+	})
+
+	t.Run("does not leak the schema into the next build", func(t *testing.T) {
+		g := NewWithT(t)
+		g.Expect(items(g, Options{})).To(HaveLen(1))
+	})
+}
+
+// copyTestData copies the files of the given directory to a temporary
+// directory, as the build may write to the kustomization file.
+func copyTestData(t *testing.T, dir string) string {
+	t.Helper()
+	tmpDir := t.TempDir()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(tmpDir, entry.Name()), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return tmpDir
+}
+
+func Test_SecureBuild_LocalOnly(t *testing.T) {
+	configMap := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+`
+	tests := []struct {
+		name    string
+		files   map[string]string
+		wantErr string
+	}{
+		{
+			name: "local resources and components",
+			files: map[string]string{
+				"overlay/kustomization.yaml":   "resources: [../base]\ncomponents: [../component]\nconfigMapGenerator:\n- name: env\n  envs: [app.env]\n",
+				"overlay/app.env":              "KEY=value\n",
+				"base/kustomization.yaml":      "resources: [config.yaml]\n",
+				"base/config.yaml":             configMap,
+				"component/kustomization.yaml": "apiVersion: kustomize.config.k8s.io/v1alpha1\nkind: Component\nnamePrefix: app-\n",
+			},
+		},
+		{
+			name: "remote resource",
+			files: map[string]string{
+				"overlay/kustomization.yaml": "resources: [https://example.invalid/deploy.yaml]\n",
+			},
+			wantErr: "'https://example.invalid/deploy.yaml' referenced in",
+		},
+		{
+			name: "remote base",
+			files: map[string]string{
+				"overlay/kustomization.yaml": "resources: [../base]\n",
+				"base/kustomization.yaml":    "resources:\n- github.com/example/repo//deploy?ref=main\n",
+			},
+			wantErr: "remote references are not allowed",
+		},
+		{
+			name: "remote patch",
+			files: map[string]string{
+				"overlay/kustomization.yaml": "patches:\n- path: http://169.254.169.254/latest/meta-data\n",
+			},
+			wantErr: "remote references are not allowed",
+		},
+		{
+			name: "remote generator file",
+			files: map[string]string{
+				"overlay/kustomization.yaml": "configMapGenerator:\n- name: config\n  files: [key=https://example.invalid/key]\n",
+			},
+			wantErr: "remote references are not allowed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			root := t.TempDir()
+			for name, data := range tt.files {
+				path := filepath.Join(root, name)
+				g.Expect(os.MkdirAll(filepath.Dir(path), 0o755)).To(Succeed())
+				g.Expect(os.WriteFile(path, []byte(data), 0o644)).To(Succeed())
+			}
+
+			_, err := SecureBuild(root, filepath.Join(root, "overlay"), Options{AllowRemoteBases: true, LocalOnly: true})
+			if tt.wantErr != "" {
+				g.Expect(err).To(HaveOccurred())
+				g.Expect(err.Error()).To(ContainSubstring(tt.wantErr))
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+		})
+	}
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package build
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"sigs.k8s.io/kustomize/api/konfig"
+	kustypes "sigs.k8s.io/kustomize/api/types"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+	"sigs.k8s.io/yaml"
+)
+
+// checkLocalReferences returns an error if the kustomization file of dir, or
+// of the directories it includes as resources or components, refers to a
+// file which is not on the filesystem, i.e. to a remote base or file which
+// kustomize would fetch over the network, regardless of the filesystem.
+func checkLocalReferences(fs filesys.FileSystem, dir string, visited map[string]bool) error {
+	if visited[dir] {
+		return nil
+	}
+	visited[dir] = true
+
+	var kus *kustypes.Kustomization
+	var kusPath string
+	for _, name := range konfig.RecognizedKustomizationFileNames() {
+		path := filepath.Join(dir, name)
+		if !fs.Exists(path) {
+			continue
+		}
+		data, err := fs.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		kus = &kustypes.Kustomization{}
+		if err := yaml.Unmarshal(data, kus); err != nil {
+			return fmt.Errorf("failed to unmarshal kustomization file from '%s': %w", path, err)
+		}
+		kus.FixKustomization()
+		kusPath = path
+		break
+	}
+	if kus == nil {
+		return nil
+	}
+
+	var refs []string
+	refs = append(refs, kus.Resources...)
+	refs = append(refs, kus.Components...)
+	refs = append(refs, kus.Crds...)
+	refs = append(refs, kus.Configurations...)
+	refs = append(refs, kus.Generators...)
+	refs = append(refs, kus.Transformers...)
+	refs = append(refs, kus.Validators...)
+	refs = append(refs, kus.OpenAPI["path"])
+	for _, p := range kus.PatchesStrategicMerge {
+		refs = append(refs, string(p))
+	}
+	for _, p := range append(kus.Patches, kus.PatchesJson6902...) {
+		refs = append(refs, p.Path)
+	}
+	for _, r := range kus.Replacements {
+		refs = append(refs, r.Path)
+	}
+	var sources []kustypes.KvPairSources
+	for _, g := range kus.ConfigMapGenerator {
+		sources = append(sources, g.KvPairSources)
+	}
+	for _, g := range kus.SecretGenerator {
+		sources = append(sources, g.KvPairSources)
+	}
+	for _, s := range sources {
+		for _, f := range s.FileSources {
+			if _, path, ok := strings.Cut(f, "="); ok {
+				f = path
+			}
+			refs = append(refs, f)
+		}
+		refs = append(refs, s.EnvSources...)
+		refs = append(refs, s.EnvSource)
+	}
+
+	for _, ref := range refs {
+		// Skip the unset fields and the inline patches and configurations.
+		if ref == "" || strings.Contains(ref, "\n") {
+			continue
+		}
+		if !fs.Exists(filepath.Join(dir, ref)) {
+			return fmt.Errorf("'%s' referenced in '%s' is not in the source, remote references are not allowed",
+				ref, kusPath)
+		}
+	}
+
+	for _, ref := range append(kus.Resources, kus.Components...) {
+		if path := filepath.Join(dir, ref); fs.IsDir(path) {
+			if err := checkLocalReferences(fs, path, visited); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
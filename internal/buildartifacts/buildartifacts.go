@@ -0,0 +1,342 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package buildartifacts retains the files of the failed builds, with the
+// secrets redacted, and serves them for a limited time behind unguessable
+// paths to the authorized callers, to inspect what kustomize saw without
+// reproducing the build.
+package buildartifacts
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	securejoin "github.com/cyphar/filepath-securejoin"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/types"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/kustomize/api/konfig"
+	kustypes "sigs.k8s.io/kustomize/api/types"
+	"sigs.k8s.io/yaml"
+
+	"github.com/fluxcd/kustomize-controller/internal/metricsauth"
+)
+
+const (
+	// PathPrefix is the path under which the archives are served.
+	PathPrefix = "/debug/build-errors/"
+
+	// MaxArchiveSize is the maximum size of the files of an archive,
+	// before compression.
+	MaxArchiveSize = 10 << 20
+
+	// redacted replaces the values of the Secrets.
+	redacted = "**redacted**"
+)
+
+// Store retains the archive of the last failed build of each
+// Kustomization until it expires.
+type Store struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu       sync.Mutex
+	archives map[string]archive
+	tokens   map[types.NamespacedName]string
+}
+
+type archive struct {
+	key     types.NamespacedName
+	data    []byte
+	expires time.Time
+}
+
+// NewStore returns a Store whose archives expire after the given duration.
+func NewStore(ttl time.Duration) *Store {
+	return &Store{
+		ttl:      ttl,
+		now:      time.Now,
+		archives: make(map[string]archive),
+		tokens:   make(map[types.NamespacedName]string),
+	}
+}
+
+// Add archives the files under root for the given Kustomization, replacing
+// its previous archive, and returns the path the archive is served at.
+func (s *Store) Add(key types.NamespacedName, root string) (string, error) {
+	data, err := Archive(root)
+	if err != nil {
+		return "", err
+	}
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(b)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.purge()
+	if previous, ok := s.tokens[key]; ok {
+		delete(s.archives, previous)
+	}
+	s.archives[token] = archive{key: key, data: data, expires: s.now().Add(s.ttl)}
+	s.tokens[key] = token
+	return PathPrefix + token, nil
+}
+
+// Remove deletes the archive of the given Kustomization.
+func (s *Store) Remove(key types.NamespacedName) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if token, ok := s.tokens[key]; ok {
+		delete(s.archives, token)
+		delete(s.tokens, key)
+	}
+}
+
+// get returns the archive of the given token, if not expired.
+func (s *Store) get(token string) (archive, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.purge()
+	a, ok := s.archives[token]
+	return a, ok
+}
+
+// purge deletes the expired archives. It must be called with the lock held.
+func (s *Store) purge() {
+	now := s.now()
+	for token, a := range s.archives {
+		if now.After(a.expires) {
+			delete(s.archives, token)
+			delete(s.tokens, a.key)
+		}
+	}
+}
+
+// Handler returns an HTTP handler serving the archives under PathPrefix.
+// The callers authenticate with a bearer token and must be allowed to get
+// the Secrets of the namespace of the Kustomization, as the files may hold
+// values which aren't redacted. The options are read on each request, which
+// allows to set the client once the manager is created.
+func (s *Store) Handler(auth *metricsauth.Options) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", "GET")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if auth.Client == nil {
+			http.Error(w, "the controller is not ready", http.StatusServiceUnavailable)
+			return
+		}
+		a, ok := s.get(strings.TrimPrefix(r.URL.Path, PathPrefix))
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		code, err := metricsauth.Authorize(r, auth.Client, authorizationv1.SubjectAccessReviewSpec{
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace: a.key.Namespace,
+				Verb:      "get",
+				Resource:  "secrets",
+			},
+		})
+		if err != nil {
+			http.Error(w, err.Error(), code)
+			return
+		}
+		w.Header().Set("Content-Type", "application/gzip")
+		w.Header().Set("Content-Disposition",
+			fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("%s-%s.tar.gz", a.key.Namespace, a.key.Name)))
+		_, _ = w.Write(a.data)
+	})
+}
+
+// Archive returns a gzipped tarball of the files under root, in which:
+//   - the values of the Secrets are replaced with '**redacted**';
+//   - the files of the secret generators, which are decrypted in place,
+//     and the files which aren't YAML or JSON are replaced with a note.
+func Archive(root string) ([]byte, error) {
+	secretSources := secretGeneratorSources(root)
+
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	var size int64
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+
+		var data []byte
+		switch ext := strings.ToLower(filepath.Ext(path)); {
+		case secretSources[path]:
+			data = []byte("# redacted: secret generator source\n")
+		case ext != ".yaml" && ext != ".yml" && ext != ".json":
+			data = []byte("# redacted: not a YAML or JSON file\n")
+		default:
+			if data, err = os.ReadFile(path); err != nil {
+				return err
+			}
+			data = redactSecrets(data)
+		}
+
+		if size += int64(len(data)); size > MaxArchiveSize {
+			return fmt.Errorf("the build files exceed the maximum size of %d bytes", MaxArchiveSize)
+		}
+		if err := tw.WriteHeader(&tar.Header{
+			Name:    filepath.ToSlash(rel),
+			Mode:    0o600,
+			Size:    int64(len(data)),
+			ModTime: time.Unix(0, 0),
+		}); err != nil {
+			return err
+		}
+		_, err = tw.Write(data)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// redactSecrets returns the YAML documents with the values of the Secrets
+// redacted. The files without Secrets are returned unchanged, and the ones
+// which can't be parsed are redacted entirely if they mention Secrets.
+func redactSecrets(data []byte) []byte {
+	var docs []map[string]interface{}
+	var found bool
+	decoder := utilyaml.NewYAMLOrJSONDecoder(bytes.NewReader(data), 4096)
+	for {
+		var doc map[string]interface{}
+		if err := decoder.Decode(&doc); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if bytes.Contains(data, []byte("Secret")) {
+				return []byte("# redacted: unparsable file mentioning Secrets\n")
+			}
+			return data
+		}
+		if doc == nil {
+			continue
+		}
+		if doc["kind"] == "Secret" {
+			found = true
+			for _, field := range []string{"data", "stringData"} {
+				if values, ok := doc[field].(map[string]interface{}); ok {
+					for k := range values {
+						values[k] = redacted
+					}
+				}
+			}
+		}
+		docs = append(docs, doc)
+	}
+	if !found {
+		return data
+	}
+
+	var out bytes.Buffer
+	for i, doc := range docs {
+		b, err := yaml.Marshal(doc)
+		if err != nil {
+			return []byte("# redacted: unparsable file mentioning Secrets\n")
+		}
+		if i > 0 {
+			out.WriteString("---\n")
+		}
+		out.Write(b)
+	}
+	return out.Bytes()
+}
+
+// secretGeneratorSources returns the absolute paths of the files and env
+// files of the secret generators of the Kustomization files under root.
+func secretGeneratorSources(root string) map[string]bool {
+	sources := make(map[string]bool)
+	names := konfig.RecognizedKustomizationFileNames()
+	_ = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		var isKustomization bool
+		for _, name := range names {
+			isKustomization = isKustomization || d.Name() == name
+		}
+		if !isKustomization {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+		var kus kustypes.Kustomization
+		if err := yaml.Unmarshal(data, &kus); err != nil {
+			return nil
+		}
+
+		dir := strings.TrimPrefix(filepath.Dir(path), root)
+		add := func(ref string) {
+			if p, err := securejoin.SecureJoin(root, filepath.Join(dir, ref)); err == nil {
+				sources[p] = true
+			}
+		}
+		for _, gen := range kus.SecretGenerator {
+			for _, src := range gen.FileSources {
+				parts := strings.SplitN(src, "=", 2)
+				add(parts[len(parts)-1])
+			}
+			for _, env := range append(gen.EnvSources, gen.EnvSource) {
+				if env != "" {
+					add(env)
+				}
+			}
+		}
+		return nil
+	})
+	return sources
+}
@@ -0,0 +1,210 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package buildartifacts
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	"github.com/fluxcd/kustomize-controller/internal/metricsauth"
+)
+
+// reviewClient returns a client authenticating the 'valid' token as the
+// 'dev' user, allowed to get the secrets of the 'flux-system' namespace only.
+func reviewClient() client.Client {
+	return fake.NewClientBuilder().WithScheme(scheme.Scheme).WithInterceptorFuncs(interceptor.Funcs{
+		Create: func(_ context.Context, _ client.WithWatch, obj client.Object, _ ...client.CreateOption) error {
+			switch review := obj.(type) {
+			case *authenticationv1.TokenReview:
+				if review.Spec.Token == "valid" {
+					review.Status.Authenticated = true
+					review.Status.User = authenticationv1.UserInfo{Username: "dev"}
+				}
+			case *authorizationv1.SubjectAccessReview:
+				attrs := review.Spec.ResourceAttributes
+				review.Status.Allowed = review.Spec.User == "dev" &&
+					attrs.Namespace == "flux-system" && attrs.Verb == "get" && attrs.Resource == "secrets"
+			default:
+				return errors.New("unexpected object")
+			}
+			return nil
+		},
+	}).Build()
+}
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, data := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func readArchive(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+	gr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string]string)
+	tr := tar.NewReader(gr)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[hdr.Name] = string(b)
+	}
+	return files
+}
+
+func TestArchive(t *testing.T) {
+	g := NewWithT(t)
+
+	deployment := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+`
+	root := writeFiles(t, map[string]string{
+		"apps/kustomization.yaml": `apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+resources:
+- deployment.yaml
+- secret.yaml
+secretGenerator:
+- name: creds
+  envs:
+  - creds.env
+  files:
+  - tls.yaml=certs/tls.yaml
+`,
+		"apps/deployment.yaml": deployment,
+		"apps/secret.yaml": `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: token
+stringData:
+  token: s3cr3t
+`,
+		"apps/creds.env":      "PASSWORD=s3cr3t\n",
+		"apps/certs/tls.yaml": "key: s3cr3t\n",
+		"apps/broken.yaml":    "kind: Secret\ndata: [\n",
+		"README.md":           "s3cr3t",
+	})
+
+	data, err := Archive(root)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	files := readArchive(t, data)
+	g.Expect(files).To(HaveLen(7))
+	g.Expect(files["apps/deployment.yaml"]).To(Equal(deployment))
+	g.Expect(files["apps/secret.yaml"]).To(ContainSubstring("name: config"))
+	g.Expect(files["apps/secret.yaml"]).To(ContainSubstring("token: '**redacted**'"))
+	for name, content := range files {
+		g.Expect(content).ToNot(ContainSubstring("s3cr3t"), name)
+	}
+}
+
+func TestStore(t *testing.T) {
+	g := NewWithT(t)
+
+	root := writeFiles(t, map[string]string{"kustomization.yaml": "resources: []\n"})
+	key := types.NamespacedName{Name: "apps", Namespace: "flux-system"}
+	now := time.Now()
+	s := NewStore(time.Minute)
+	s.now = func() time.Time { return now }
+
+	server := httptest.NewServer(s.Handler(&metricsauth.Options{Client: reviewClient()}))
+	defer server.Close()
+	getWithToken := func(path, token string) *http.Response {
+		req, err := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		g.Expect(err).ToNot(HaveOccurred())
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		g.Expect(err).ToNot(HaveOccurred())
+		resp.Body.Close()
+		return resp
+	}
+	get := func(path string) *http.Response {
+		return getWithToken(path, "valid")
+	}
+
+	first, err := s.Add(key, root)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(first).To(HavePrefix(PathPrefix))
+
+	// The path alone doesn't give access to the archive.
+	g.Expect(getWithToken(first, "").StatusCode).To(Equal(http.StatusUnauthorized))
+	g.Expect(getWithToken(first, "invalid").StatusCode).To(Equal(http.StatusUnauthorized))
+	other, err := s.Add(types.NamespacedName{Name: "apps", Namespace: "apps"}, root)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(get(other).StatusCode).To(Equal(http.StatusForbidden))
+
+	resp := get(first)
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	g.Expect(resp.Header.Get("Content-Disposition")).To(ContainSubstring("flux-system-apps.tar.gz"))
+
+	// The last archive replaces the previous one.
+	second, err := s.Add(key, root)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(get(first).StatusCode).To(Equal(http.StatusNotFound))
+	g.Expect(get(second).StatusCode).To(Equal(http.StatusOK))
+
+	now = now.Add(2 * time.Minute)
+	g.Expect(get(second).StatusCode).To(Equal(http.StatusNotFound))
+}
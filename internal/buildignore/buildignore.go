@@ -0,0 +1,128 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package buildignore removes the files excluded from the build by
+// gitignore-style patterns, before the files are decrypted and built.
+package buildignore
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fluxcd/pkg/sourceignore"
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+)
+
+// IgnoreFile is the name of the files holding the patterns of the files
+// excluded from the build, scoped to the directory they are in.
+const IgnoreFile = ".kustomizeignore"
+
+// ParsePatterns returns the patterns, one per line in the .gitignore
+// format, scoped to the slash-separated path of the domain directory
+// relative to the root the patterns are matched against.
+func ParsePatterns(patterns, domain string) []gitignore.Pattern {
+	return sourceignore.ReadPatterns(strings.NewReader(patterns), split(domain))
+}
+
+// LoadPatterns returns the patterns of the IgnoreFile files found under
+// root, each scoped to the directory it is in.
+func LoadPatterns(root string) ([]gitignore.Pattern, error) {
+	var ps []gitignore.Pattern
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if d.IsDir() || d.Name() != IgnoreFile {
+			return nil
+		}
+		rel, err := filepath.Rel(root, filepath.Dir(path))
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		ps = append(ps, ParsePatterns(string(data), filepath.ToSlash(rel))...)
+		return nil
+	})
+	return ps, err
+}
+
+// Excluded returns true if the slash-separated path, relative to the root
+// the patterns are matched against, or one of its parents is excluded by
+// the patterns.
+func Excluded(ps []gitignore.Pattern, path string, isDir bool) bool {
+	if len(ps) == 0 {
+		return false
+	}
+	matcher := gitignore.NewMatcher(ps)
+	parts := split(path)
+	for i := 1; i <= len(parts); i++ {
+		if matcher.Match(parts[:i], i < len(parts) || isDir) {
+			return true
+		}
+	}
+	return false
+}
+
+// Remove deletes the files and directories under root matching the
+// patterns. It returns the slash-separated paths of the removed entries,
+// relative to root, in lexical order.
+func Remove(root string, ps []gitignore.Pattern) ([]string, error) {
+	if len(ps) == 0 {
+		return nil, nil
+	}
+	matcher := gitignore.NewMatcher(ps)
+
+	var removed []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		if !matcher.Match(split(filepath.ToSlash(rel)), d.IsDir()) {
+			return nil
+		}
+		if err := os.RemoveAll(path); err != nil {
+			return err
+		}
+		removed = append(removed, filepath.ToSlash(rel))
+		if d.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	return removed, err
+}
+
+// split returns the components of the slash-separated path, nil for the
+// root.
+func split(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" || path == "." {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
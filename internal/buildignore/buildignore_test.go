@@ -0,0 +1,122 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package buildignore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for f, data := range files {
+		path := filepath.Join(dir, f)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestRemove(t *testing.T) {
+	files := map[string]string{
+		"apps/kustomization.yaml":               "---",
+		"apps/deployment.yaml":                  "---",
+		"apps/README.md":                        "---",
+		"apps/docs/index.md":                    "---",
+		"apps/docs/example.yaml":                "---",
+		"apps/tests/values.yaml":                "---",
+		"apps/overlays/prod/kustomization.yaml": "---",
+		"apps/overlays/prod/test-values.yaml":   "---",
+		"tests/values.yaml":                     "---",
+	}
+
+	tests := []struct {
+		name     string
+		patterns string
+		removed  []string
+	}{
+		{
+			name:     "no patterns",
+			patterns: "# comment only\n\n",
+		},
+		{
+			name:     "directories and extensions",
+			patterns: "docs/\n*.md\n",
+			removed:  []string{"apps/README.md", "apps/docs"},
+		},
+		{
+			name:     "anchored and nested patterns",
+			patterns: "/tests\n**/test-*.yaml\n",
+			removed:  []string{"apps/overlays/prod/test-values.yaml", "apps/tests"},
+		},
+		{
+			name:     "negation",
+			patterns: "*.yaml\n!kustomization.yaml\n!deployment.yaml\n",
+			removed:  []string{"apps/docs/example.yaml", "apps/overlays/prod/test-values.yaml", "apps/tests/values.yaml"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			dir := writeFiles(t, files)
+
+			removed, err := Remove(dir, ParsePatterns(tt.patterns, "apps"))
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(removed).To(Equal(tt.removed))
+			for _, f := range tt.removed {
+				g.Expect(filepath.Join(dir, f)).ToNot(BeAnExistingFile())
+			}
+			g.Expect(filepath.Join(dir, "apps/kustomization.yaml")).To(BeAnExistingFile())
+			g.Expect(filepath.Join(dir, "tests/values.yaml")).To(BeAnExistingFile())
+		})
+	}
+}
+
+func TestLoadPatterns(t *testing.T) {
+	g := NewWithT(t)
+	dir := writeFiles(t, map[string]string{
+		IgnoreFile:                  "/staging\n",
+		"apps/" + IgnoreFile:        "# test fixtures\ntestdata/\n",
+		"apps/deployment.yaml":      "---",
+		"apps/testdata/values.yaml": "---",
+		"staging/deployment.yaml":   "---",
+		"testdata/values.yaml":      "---",
+	})
+
+	ps, err := LoadPatterns(dir)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(ps).To(HaveLen(2))
+
+	g.Expect(Excluded(ps, "staging", true)).To(BeTrue())
+	g.Expect(Excluded(ps, "apps/testdata/values.yaml", false)).To(BeTrue())
+	g.Expect(Excluded(ps, "apps", true)).To(BeFalse())
+	g.Expect(Excluded(ps, "testdata", true)).To(BeFalse())
+	g.Expect(Excluded(ps, ".", true)).To(BeFalse())
+
+	removed, err := Remove(dir, ps)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(removed).To(Equal([]string{"apps/testdata", "staging"}))
+}
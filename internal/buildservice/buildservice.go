@@ -0,0 +1,139 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package buildservice serves the builds of local sources with the pipeline
+// of the controller, i.e. the generation, the kustomize build, the post-build
+// substitutions and the controller-wide mutations, so that the diffing tools
+// render the objects as the controller would apply them. The builds are
+// served over HTTP on the metrics address, and the sources are not decrypted.
+package buildservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/fluxcd/kustomize-controller/internal/manifeststream"
+	"github.com/fluxcd/kustomize-controller/internal/metricsauth"
+)
+
+const (
+	// PathPrefix is the path under which the builds are served, followed
+	// by the namespace and the name of the Kustomization.
+	PathPrefix = "/build/"
+
+	// MaxArchiveSize is the maximum size of the compressed source archive.
+	MaxArchiveSize = 50 << 20
+)
+
+// ErrNotFound is returned by the build function when the Kustomization
+// does not exist.
+var ErrNotFound = errors.New("kustomization not found")
+
+// Request is a build request.
+type Request struct {
+	// Kustomization is the key of the Kustomization whose spec and
+	// controller settings are used for the build.
+	Kustomization types.NamespacedName
+
+	// Path overrides the path of the Kustomization in the source when
+	// not empty.
+	Path string
+
+	// Archive is the gzip-compressed tarball of the source.
+	Archive io.Reader
+}
+
+// Options configures the builds.
+type Options struct {
+	// Client is used to authenticate and authorize the callers with
+	// token and subject access reviews.
+	Client client.Client
+
+	// Build returns the objects rendered for the request.
+	Build func(ctx context.Context, req Request) ([]*unstructured.Unstructured, error)
+}
+
+// Handler returns an HTTP handler building the gzip-compressed tarball
+// posted to '/build/<namespace>/<name>' and responding with the manifest
+// stream of the objects, in which the values of the Secrets are redacted.
+// The callers authenticate with a bearer token and must be allowed to get
+// the Secrets of the namespace, as the objects can contain values
+// substituted from these Secrets. The options are read on each request,
+// which allows to set the build function once the controller is set up.
+func Handler(opts *Options) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", "POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if opts.Build == nil || opts.Client == nil {
+			http.Error(w, "the controller is not ready", http.StatusServiceUnavailable)
+			return
+		}
+
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, PathPrefix), "/")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			http.Error(w, fmt.Sprintf("the path must be '%s<namespace>/<name>'", PathPrefix), http.StatusBadRequest)
+			return
+		}
+		key := types.NamespacedName{Namespace: parts[0], Name: parts[1]}
+
+		code, err := metricsauth.Authorize(r, opts.Client, authorizationv1.SubjectAccessReviewSpec{
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace: key.Namespace,
+				Verb:      "get",
+				Resource:  "secrets",
+			},
+		})
+		if err != nil {
+			http.Error(w, err.Error(), code)
+			return
+		}
+
+		objects, err := opts.Build(r.Context(), Request{
+			Kustomization: key,
+			Path:          r.URL.Query().Get("path"),
+			Archive:       http.MaxBytesReader(w, r.Body, MaxArchiveSize),
+		})
+		if err != nil {
+			code = http.StatusUnprocessableEntity
+			if errors.Is(err, ErrNotFound) {
+				code = http.StatusNotFound
+			}
+			http.Error(w, fmt.Sprintf("build failed: %s", err), code)
+			return
+		}
+
+		data, err := manifeststream.Format(objects)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to render the objects: %s", err), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/yaml")
+		w.Header().Set("X-Manifest-Stream-Version", manifeststream.Version)
+		_, _ = w.Write(data)
+	})
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package buildservice
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	"github.com/fluxcd/kustomize-controller/internal/manifeststream"
+)
+
+// reviewClient returns a client authenticating the 'valid' token as the
+// 'dev' user, allowed to get the secrets of the 'apps' namespace only.
+func reviewClient() client.Client {
+	return fake.NewClientBuilder().WithScheme(scheme.Scheme).WithInterceptorFuncs(interceptor.Funcs{
+		Create: func(_ context.Context, _ client.WithWatch, obj client.Object, _ ...client.CreateOption) error {
+			switch review := obj.(type) {
+			case *authenticationv1.TokenReview:
+				if review.Spec.Token == "valid" {
+					review.Status.Authenticated = true
+					review.Status.User = authenticationv1.UserInfo{Username: "dev"}
+				}
+			case *authorizationv1.SubjectAccessReview:
+				attrs := review.Spec.ResourceAttributes
+				review.Status.Allowed = review.Spec.User == "dev" &&
+					attrs.Namespace == "apps" && attrs.Verb == "get" && attrs.Resource == "secrets"
+			default:
+				return errors.New("unexpected object")
+			}
+			return nil
+		},
+	}).Build()
+}
+
+func TestHandler(t *testing.T) {
+	var got Request
+	var gotArchive string
+	opts := &Options{
+		Client: reviewClient(),
+		Build: func(_ context.Context, req Request) ([]*unstructured.Unstructured, error) {
+			got = req
+			data, err := io.ReadAll(req.Archive)
+			if err != nil {
+				return nil, err
+			}
+			gotArchive = string(data)
+			switch req.Kustomization.Name {
+			case "missing":
+				return nil, ErrNotFound
+			case "broken":
+				return nil, errors.New("kustomize build failed")
+			}
+			secret := &unstructured.Unstructured{}
+			secret.SetAPIVersion("v1")
+			secret.SetKind("Secret")
+			secret.SetName("token")
+			secret.SetNamespace("apps")
+			_ = unstructured.SetNestedField(secret.Object, "c2VjcmV0", "data", "token")
+			return []*unstructured.Unstructured{secret}, nil
+		},
+	}
+
+	tests := []struct {
+		name  string
+		path  string
+		token string
+		code  int
+	}{
+		{name: "build", path: "apps/podinfo?path=./deploy", token: "valid", code: http.StatusOK},
+		{name: "missing token", path: "apps/podinfo", code: http.StatusUnauthorized},
+		{name: "invalid token", path: "apps/podinfo", token: "invalid", code: http.StatusUnauthorized},
+		{name: "forbidden namespace", path: "flux-system/podinfo", token: "valid", code: http.StatusForbidden},
+		{name: "invalid path", path: "apps", token: "valid", code: http.StatusBadRequest},
+		{name: "not found", path: "apps/missing", token: "valid", code: http.StatusNotFound},
+		{name: "build failure", path: "apps/broken", token: "valid", code: http.StatusUnprocessableEntity},
+	}
+
+	handler := Handler(opts)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			req := httptest.NewRequest(http.MethodPost, PathPrefix+tt.path, strings.NewReader("archive"))
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			g.Expect(rec.Code).To(Equal(tt.code), rec.Body.String())
+			if tt.code != http.StatusOK {
+				return
+			}
+			g.Expect(got.Kustomization.String()).To(Equal("apps/podinfo"))
+			g.Expect(got.Path).To(Equal("./deploy"))
+			g.Expect(gotArchive).To(Equal("archive"))
+			g.Expect(rec.Header().Get("X-Manifest-Stream-Version")).ToNot(BeEmpty())
+			g.Expect(rec.Body.String()).To(ContainSubstring("token: " + manifeststream.Redacted))
+			g.Expect(rec.Body.String()).ToNot(ContainSubstring("c2VjcmV0"))
+		})
+	}
+
+	t.Run("method not allowed", func(t *testing.T) {
+		g := NewWithT(t)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, PathPrefix+"apps/podinfo", nil))
+		g.Expect(rec.Code).To(Equal(http.StatusMethodNotAllowed))
+	})
+
+	t.Run("controller not ready", func(t *testing.T) {
+		g := NewWithT(t)
+		rec := httptest.NewRecorder()
+		Handler(&Options{}).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, PathPrefix+"apps/podinfo", nil))
+		g.Expect(rec.Code).To(Equal(http.StatusServiceUnavailable))
+	})
+}
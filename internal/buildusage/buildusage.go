@@ -0,0 +1,112 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package buildusage measures the resources used by the build and
+// decryption of the Kustomizations, and reports them as metrics.
+//
+// The CPU time and peak RSS are measured for the whole process, the
+// values recorded while other reconciliations run concurrently include
+// their usage. They are meant to spot the overlays which are expensive
+// to build, not for accounting.
+package buildusage
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/fluxcd/kustomize-controller/internal/workdir"
+)
+
+var (
+	cpuSeconds = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "gotk_build_cpu_seconds",
+			Help: "CPU time used by the last build of the Kustomization, including the decryption.",
+		},
+		[]string{"name", "namespace"},
+	)
+
+	rssDeltaBytes = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "gotk_build_peak_rss_delta_bytes",
+			Help: "Increase of the controller peak resident memory during the last build of the Kustomization.",
+		},
+		[]string{"name", "namespace"},
+	)
+
+	diskBytes = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "gotk_build_disk_bytes",
+			Help: "Disk space used by the working directory after the last build of the Kustomization.",
+		},
+		[]string{"name", "namespace"},
+	)
+)
+
+// RegisterMetrics registers the build usage metrics with the registry.
+func RegisterMetrics(registry prometheus.Registerer) {
+	registry.MustRegister(cpuSeconds, rssDeltaBytes, diskBytes)
+}
+
+// Usage contains the resources used by a build.
+type Usage struct {
+	// CPU is the user and system CPU time.
+	CPU time.Duration
+
+	// PeakRSSDelta is the increase of the peak resident set size, in bytes.
+	PeakRSSDelta int64
+
+	// DiskBytes is the size of the files in the working directory.
+	DiskBytes int64
+}
+
+// Measurement is a build usage measurement in progress.
+type Measurement struct {
+	cpu     time.Duration
+	peakRSS int64
+}
+
+// Start returns a Measurement of the resources used from now on.
+func Start() *Measurement {
+	cpu, peakRSS := readUsage()
+	return &Measurement{cpu: cpu, peakRSS: peakRSS}
+}
+
+// Stop returns the resources used since the start of the Measurement,
+// with the disk space used by the given working directory.
+func (m *Measurement) Stop(workDir string) Usage {
+	cpu, peakRSS := readUsage()
+	return Usage{
+		CPU:          max(cpu-m.cpu, 0),
+		PeakRSSDelta: max(peakRSS-m.peakRSS, 0),
+		DiskBytes:    workdir.Size(workDir),
+	}
+}
+
+// Record sets the metrics of the Kustomization to the given usage.
+func Record(name, namespace string, u Usage) {
+	cpuSeconds.WithLabelValues(name, namespace).Set(u.CPU.Seconds())
+	rssDeltaBytes.WithLabelValues(name, namespace).Set(float64(u.PeakRSSDelta))
+	diskBytes.WithLabelValues(name, namespace).Set(float64(u.DiskBytes))
+}
+
+// Delete removes the metrics of the Kustomization.
+func Delete(name, namespace string) {
+	cpuSeconds.DeleteLabelValues(name, namespace)
+	rssDeltaBytes.DeleteLabelValues(name, namespace)
+	diskBytes.DeleteLabelValues(name, namespace)
+}
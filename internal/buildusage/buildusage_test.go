@@ -0,0 +1,60 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package buildusage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMeasurement(t *testing.T) {
+	g := NewWithT(t)
+
+	dir := t.TempDir()
+	g.Expect(os.MkdirAll(filepath.Join(dir, "apps"), 0o755)).To(Succeed())
+	g.Expect(os.WriteFile(filepath.Join(dir, "apps", "deploy.yaml"), make([]byte, 512), 0o644)).To(Succeed())
+	g.Expect(os.WriteFile(filepath.Join(dir, "kustomization.yaml"), make([]byte, 256), 0o644)).To(Succeed())
+
+	m := Start()
+	// Burn some CPU to get a non zero measurement.
+	deadline := time.Now().Add(20 * time.Millisecond)
+	for time.Now().Before(deadline) {
+	}
+	u := m.Stop(dir)
+
+	g.Expect(u.DiskBytes).To(Equal(int64(768)))
+	g.Expect(u.CPU).To(BeNumerically(">=", 0))
+	g.Expect(u.PeakRSSDelta).To(BeNumerically(">=", 0))
+}
+
+func TestRecord(t *testing.T) {
+	g := NewWithT(t)
+
+	Record("app", "default", Usage{CPU: 1500 * time.Millisecond, PeakRSSDelta: 1024, DiskBytes: 2048})
+	g.Expect(testutil.ToFloat64(cpuSeconds.WithLabelValues("app", "default"))).To(Equal(1.5))
+	g.Expect(testutil.ToFloat64(rssDeltaBytes.WithLabelValues("app", "default"))).To(Equal(float64(1024)))
+	g.Expect(testutil.ToFloat64(diskBytes.WithLabelValues("app", "default"))).To(Equal(float64(2048)))
+
+	Delete("app", "default")
+	g.Expect(testutil.CollectAndCount(cpuSeconds)).To(Equal(0))
+	g.Expect(testutil.CollectAndCount(diskBytes)).To(Equal(0))
+}
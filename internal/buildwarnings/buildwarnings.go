@@ -0,0 +1,109 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package buildwarnings collects the warnings kustomize emits for the
+// Kustomization files of an overlay, e.g. the use of deprecated fields.
+package buildwarnings
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	securejoin "github.com/cyphar/filepath-securejoin"
+	"sigs.k8s.io/kustomize/api/konfig"
+	kustypes "sigs.k8s.io/kustomize/api/types"
+	"sigs.k8s.io/yaml"
+)
+
+// MaxWarnings is the maximum number of warnings returned by Collect.
+const MaxWarnings = 50
+
+// Collect walks the Kustomization files of the overlay at dirPath, and the
+// local resources and components it refers to, and returns the warnings
+// kustomize would print for them during the build.
+// Files which can't be loaded are skipped, as the build reports these
+// with a proper error.
+func Collect(root, dirPath string) []string {
+	var warnings []string
+	visited := make(map[string]struct{})
+
+	var walk func(dir string)
+	walk = func(dir string) {
+		if len(warnings) >= MaxWarnings {
+			return
+		}
+		if _, ok := visited[dir]; ok {
+			return
+		}
+		visited[dir] = struct{}{}
+
+		kus, fileName := load(root, dir)
+		if kus == nil {
+			return
+		}
+
+		relPath, err := filepath.Rel(root, filepath.Join(dir, fileName))
+		if err != nil {
+			relPath = fileName
+		}
+		for _, msg := range *kus.CheckDeprecatedFields() {
+			msg = strings.TrimPrefix(msg, "# Warning: ")
+			warnings = append(warnings, fmt.Sprintf("%s: %s", relPath, msg))
+		}
+
+		for _, ref := range append(kus.Resources, append(kus.Bases, kus.Components...)...) {
+			if filepath.IsAbs(ref) || strings.Contains(ref, "://") {
+				continue
+			}
+			next, err := securejoin.SecureJoin(root, filepath.Join(strings.TrimPrefix(dir, root), ref))
+			if err != nil {
+				continue
+			}
+			if fi, err := os.Stat(next); err == nil && fi.IsDir() {
+				walk(next)
+			}
+		}
+	}
+	walk(dirPath)
+
+	if len(warnings) > MaxWarnings {
+		warnings = warnings[:MaxWarnings]
+	}
+	return warnings
+}
+
+// load returns the Kustomization file in the given directory and its name,
+// or nil if none can be loaded.
+func load(root, dir string) (*kustypes.Kustomization, string) {
+	for _, fileName := range konfig.RecognizedKustomizationFileNames() {
+		filePath, err := securejoin.SecureJoin(root, filepath.Join(strings.TrimPrefix(dir, root), fileName))
+		if err != nil {
+			continue
+		}
+		data, err := os.ReadFile(filePath)
+		if err != nil {
+			continue
+		}
+		var kus kustypes.Kustomization
+		if err := yaml.Unmarshal(data, &kus); err != nil {
+			return nil, ""
+		}
+		return &kus, fileName
+	}
+	return nil, ""
+}
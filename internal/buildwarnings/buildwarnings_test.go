@@ -0,0 +1,40 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package buildwarnings
+
+import (
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func Test_Collect(t *testing.T) {
+	g := NewWithT(t)
+
+	root, err := filepath.Abs("testdata")
+	g.Expect(err).ToNot(HaveOccurred())
+
+	warnings := Collect(root, filepath.Join(root, "overlay"))
+	g.Expect(warnings).To(HaveLen(3))
+	g.Expect(warnings[0]).To(HavePrefix("overlay/kustomization.yaml: 'patchesStrategicMerge' is deprecated."))
+	g.Expect(warnings[1]).To(HavePrefix("base/kustomization.yaml: 'vars' is deprecated."))
+	g.Expect(warnings[2]).To(HavePrefix("component/kustomization.yaml: 'commonLabels' is deprecated."))
+
+	g.Expect(Collect(root, filepath.Join(root, "component"))).To(HaveLen(1))
+	g.Expect(Collect(root, filepath.Join(root, "missing"))).To(BeEmpty())
+}
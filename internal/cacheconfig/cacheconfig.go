@@ -0,0 +1,119 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cacheconfig configures the controller-runtime cache, to limit the
+// memory used by the informers in large clusters.
+package cacheconfig
+
+import (
+	"fmt"
+	"time"
+
+	flag "github.com/spf13/pflag"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/labels"
+	ctrlcache "sigs.k8s.io/controller-runtime/pkg/cache"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	flagSyncPeriod         = "cache-sync-period"
+	flagSecretsSelector    = "cache-secrets-selector"
+	flagConfigMapsSelector = "cache-configmaps-selector"
+	flagStripManagedFields = "cache-strip-managed-fields"
+)
+
+// Options contains the cache settings.
+type Options struct {
+	// SyncPeriod is the minimum frequency at which the informers resync
+	// the watched objects. Defaults to the controller-runtime default.
+	SyncPeriod time.Duration
+
+	// SecretsSelector is the label selector of the cached Secrets.
+	SecretsSelector string
+
+	// ConfigMapsSelector is the label selector of the cached ConfigMaps.
+	ConfigMapsSelector string
+
+	// StripManagedFields removes the managed fields of the cached objects.
+	StripManagedFields bool
+}
+
+// BindFlags will parse the given pflag.FlagSet for the cache option flags
+// and set the Options accordingly.
+func (o *Options) BindFlags(fs *flag.FlagSet) {
+	fs.DurationVar(&o.SyncPeriod, flagSyncPeriod, 0,
+		"The minimum interval at which the cached objects are resynced. Defaults to 10 hours.")
+	fs.StringVar(&o.SecretsSelector, flagSecretsSelector, "",
+		"The label selector of the Secrets cached by the controller, requires the CacheSecretsAndConfigMaps feature gate. "+
+			"The Secrets not matching the selector can't be read by the controller.")
+	fs.StringVar(&o.ConfigMapsSelector, flagConfigMapsSelector, "",
+		"The label selector of the ConfigMaps cached by the controller, requires the CacheSecretsAndConfigMaps feature gate. "+
+			"The ConfigMaps not matching the selector can't be read by the controller.")
+	fs.BoolVar(&o.StripManagedFields, flagStripManagedFields, false,
+		"Remove the managed fields of the cached objects to reduce the memory usage.")
+}
+
+// Apply sets the cache options. The selectors are only valid when the
+// Secrets and ConfigMaps are cached.
+func (o Options) Apply(opts *ctrlcache.Options, cacheSecretsAndConfigMaps bool) error {
+	if o.SyncPeriod > 0 {
+		syncPeriod := o.SyncPeriod
+		opts.SyncPeriod = &syncPeriod
+	}
+
+	selectors := []struct {
+		flag     string
+		value    string
+		object   ctrlclient.Object
+		selector labels.Selector
+	}{
+		{flag: flagSecretsSelector, value: o.SecretsSelector, object: &corev1.Secret{}},
+		{flag: flagConfigMapsSelector, value: o.ConfigMapsSelector, object: &corev1.ConfigMap{}},
+	}
+	for _, s := range selectors {
+		if s.value == "" {
+			continue
+		}
+		if !cacheSecretsAndConfigMaps {
+			return fmt.Errorf("--%s requires the CacheSecretsAndConfigMaps feature gate", s.flag)
+		}
+		selector, err := labels.Parse(s.value)
+		if err != nil {
+			return fmt.Errorf("invalid --%s value: %w", s.flag, err)
+		}
+		if opts.ByObject == nil {
+			opts.ByObject = make(map[ctrlclient.Object]ctrlcache.ByObject)
+		}
+		opts.ByObject[s.object] = ctrlcache.ByObject{Label: selector}
+	}
+
+	if o.StripManagedFields {
+		opts.DefaultTransform = StripManagedFields
+	}
+
+	return nil
+}
+
+// StripManagedFields is a cache transform function removing the managed
+// fields of the objects.
+func StripManagedFields(in interface{}) (interface{}, error) {
+	if obj, err := meta.Accessor(in); err == nil && obj.GetManagedFields() != nil {
+		obj.SetManagedFields(nil)
+	}
+	return in, nil
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cacheconfig
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	flag "github.com/spf13/pflag"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrlcache "sigs.k8s.io/controller-runtime/pkg/cache"
+)
+
+func TestOptions_Apply(t *testing.T) {
+	t.Run("sets the cache options from flags", func(t *testing.T) {
+		g := NewWithT(t)
+
+		var o Options
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		o.BindFlags(fs)
+		g.Expect(fs.Parse([]string{
+			"--cache-sync-period=1h",
+			"--cache-secrets-selector=toolkit.fluxcd.io/cache=true",
+			"--cache-configmaps-selector=toolkit.fluxcd.io/cache in (true)",
+			"--cache-strip-managed-fields",
+		})).To(Succeed())
+
+		var opts ctrlcache.Options
+		g.Expect(o.Apply(&opts, true)).To(Succeed())
+		g.Expect(*opts.SyncPeriod).To(Equal(time.Hour))
+		g.Expect(opts.ByObject).To(HaveLen(2))
+		for obj, byObject := range opts.ByObject {
+			switch obj.(type) {
+			case *corev1.Secret:
+				g.Expect(byObject.Label.String()).To(Equal("toolkit.fluxcd.io/cache=true"))
+			case *corev1.ConfigMap:
+				g.Expect(byObject.Label.String()).To(Equal("toolkit.fluxcd.io/cache in (true)"))
+			default:
+				t.Errorf("unexpected object %T", obj)
+			}
+		}
+		g.Expect(opts.DefaultTransform).ToNot(BeNil())
+	})
+
+	t.Run("keeps the defaults", func(t *testing.T) {
+		g := NewWithT(t)
+
+		var opts ctrlcache.Options
+		g.Expect(Options{}.Apply(&opts, false)).To(Succeed())
+		g.Expect(opts.SyncPeriod).To(BeNil())
+		g.Expect(opts.ByObject).To(BeEmpty())
+		g.Expect(opts.DefaultTransform).To(BeNil())
+	})
+
+	t.Run("requires the Secrets to be cached", func(t *testing.T) {
+		g := NewWithT(t)
+
+		var opts ctrlcache.Options
+		err := Options{SecretsSelector: "app=test"}.Apply(&opts, false)
+		g.Expect(err).To(MatchError(ContainSubstring("CacheSecretsAndConfigMaps")))
+	})
+
+	t.Run("rejects invalid selectors", func(t *testing.T) {
+		g := NewWithT(t)
+
+		var opts ctrlcache.Options
+		err := Options{ConfigMapsSelector: "app in ("}.Apply(&opts, true)
+		g.Expect(err).To(MatchError(ContainSubstring("--cache-configmaps-selector")))
+	})
+}
+
+func TestStripManagedFields(t *testing.T) {
+	g := NewWithT(t)
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test",
+			ManagedFields: []metav1.ManagedFieldsEntry{
+				{Manager: "kubectl", Operation: metav1.ManagedFieldsOperationApply},
+			},
+		},
+	}
+
+	out, err := StripManagedFields(secret)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(out.(*corev1.Secret).ManagedFields).To(BeNil())
+	g.Expect(out.(*corev1.Secret).Name).To(Equal("test"))
+
+	_, err = StripManagedFields("not an object")
+	g.Expect(err).ToNot(HaveOccurred())
+}
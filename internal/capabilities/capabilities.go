@@ -0,0 +1,177 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package capabilities detects the capabilities of the Kubernetes clusters
+// the objects are applied to, from their version and the APIs they serve,
+// and reports them as metrics.
+package capabilities
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/rest"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+)
+
+const admissionGroup = "admissionregistration.k8s.io"
+
+var (
+	serverSideApplyVersion       = version.MajorMinor(1, 22)
+	strictFieldValidationVersion = version.MajorMinor(1, 25)
+	applySetVersion              = version.MajorMinor(1, 27)
+)
+
+var capability = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "gotk_cluster_capability",
+		Help: "Set to 1 when the cluster has the capability, 0 otherwise.",
+	},
+	[]string{"cluster", "capability"},
+)
+
+// RegisterMetrics registers the cluster capabilities metric with the registry.
+func RegisterMetrics(registry prometheus.Registerer) {
+	registry.MustRegister(capability)
+}
+
+// Detector detects the capabilities of the clusters, and caches them for a
+// period of time so that each cluster is probed once per period.
+type Detector struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	capabilities *kustomizev1.ClusterCapabilities
+	expiresAt    time.Time
+}
+
+// NewDetector returns a Detector which probes the clusters again after
+// the given duration, e.g. to detect the upgrades.
+func NewDetector(ttl time.Duration) *Detector {
+	return &Detector{
+		ttl:     ttl,
+		entries: make(map[string]cacheEntry),
+	}
+}
+
+// Get returns the capabilities of the cluster the config points at,
+// detecting them if not cached or expired.
+func (d *Detector) Get(ctx context.Context, cfg *rest.Config) (*kustomizev1.ClusterCapabilities, error) {
+	key := cfg.Host
+
+	d.mu.Lock()
+	entry, ok := d.entries[key]
+	d.mu.Unlock()
+	if ok && time.Now().Before(entry.expiresAt) {
+		return entry.capabilities.DeepCopy(), nil
+	}
+
+	dc, err := discovery.NewDiscoveryClientForConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	capabilities, err := Detect(dc)
+	if err != nil {
+		return nil, err
+	}
+	record(key, capabilities)
+
+	d.mu.Lock()
+	d.entries[key] = cacheEntry{capabilities: capabilities, expiresAt: time.Now().Add(d.ttl)}
+	d.mu.Unlock()
+	return capabilities.DeepCopy(), nil
+}
+
+// Detect probes the capabilities of the cluster with the discovery client.
+func Detect(dc discovery.DiscoveryInterface) (*kustomizev1.ClusterCapabilities, error) {
+	info, err := dc.ServerVersion()
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the cluster version: %w", err)
+	}
+	v, err := version.ParseGeneric(info.GitVersion)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse the cluster version '%s': %w", info.GitVersion, err)
+	}
+
+	vap, err := servesValidatingAdmissionPolicies(dc)
+	if err != nil {
+		return nil, err
+	}
+
+	return &kustomizev1.ClusterCapabilities{
+		Version:                   info.GitVersion,
+		ServerSideApply:           v.AtLeast(serverSideApplyVersion),
+		ValidatingAdmissionPolicy: vap,
+		ApplySet:                  v.AtLeast(applySetVersion),
+		StrictFieldValidation:     v.AtLeast(strictFieldValidationVersion),
+	}, nil
+}
+
+// servesValidatingAdmissionPolicies returns true if the cluster serves the
+// ValidatingAdmissionPolicies in any version, as they are disabled by default
+// before they graduate to GA.
+func servesValidatingAdmissionPolicies(dc discovery.DiscoveryInterface) (bool, error) {
+	groups, err := dc.ServerGroups()
+	if err != nil {
+		return false, fmt.Errorf("unable to read the cluster API groups: %w", err)
+	}
+	for _, g := range groups.Groups {
+		if g.Name != admissionGroup {
+			continue
+		}
+		for _, gv := range g.Versions {
+			resources, err := dc.ServerResourcesForGroupVersion(gv.GroupVersion)
+			if err != nil {
+				if apierrors.IsNotFound(err) {
+					continue
+				}
+				return false, fmt.Errorf("unable to read the %s resources: %w", gv.GroupVersion, err)
+			}
+			for _, r := range resources.APIResources {
+				if r.Name == "validatingadmissionpolicies" {
+					return true, nil
+				}
+			}
+		}
+	}
+	return false, nil
+}
+
+// record sets the metrics of the capabilities of the cluster.
+func record(cluster string, c *kustomizev1.ClusterCapabilities) {
+	for name, enabled := range map[string]bool{
+		"ServerSideApply":           c.ServerSideApply,
+		"ValidatingAdmissionPolicy": c.ValidatingAdmissionPolicy,
+		"ApplySet":                  c.ApplySet,
+		"StrictFieldValidation":     c.StrictFieldValidation,
+	} {
+		value := 0.0
+		if enabled {
+			value = 1
+		}
+		capability.WithLabelValues(cluster, name).Set(value)
+	}
+}
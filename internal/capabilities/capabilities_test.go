@@ -0,0 +1,89 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package capabilities
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+)
+
+func newDiscovery(gitVersion string, resources ...*metav1.APIResourceList) *fakediscovery.FakeDiscovery {
+	return &fakediscovery.FakeDiscovery{
+		Fake:               &clienttesting.Fake{Resources: resources},
+		FakedServerVersion: &version.Info{GitVersion: gitVersion},
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name      string
+		discovery *fakediscovery.FakeDiscovery
+		want      *kustomizev1.ClusterCapabilities
+	}{
+		{
+			name: "current cluster",
+			discovery: newDiscovery("v1.28.3", &metav1.APIResourceList{
+				GroupVersion: "admissionregistration.k8s.io/v1beta1",
+				APIResources: []metav1.APIResource{{Name: "validatingadmissionpolicies"}},
+			}),
+			want: &kustomizev1.ClusterCapabilities{
+				Version:                   "v1.28.3",
+				ServerSideApply:           true,
+				ValidatingAdmissionPolicy: true,
+				ApplySet:                  true,
+				StrictFieldValidation:     true,
+			},
+		},
+		{
+			name: "legacy cluster",
+			discovery: newDiscovery("v1.21.14-eks-18ef993", &metav1.APIResourceList{
+				GroupVersion: "admissionregistration.k8s.io/v1",
+				APIResources: []metav1.APIResource{{Name: "validatingwebhookconfigurations"}},
+			}),
+			want: &kustomizev1.ClusterCapabilities{
+				Version: "v1.21.14-eks-18ef993",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			got, err := Detect(tt.discovery)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(got).To(Equal(tt.want))
+		})
+	}
+}
+
+func TestRecord(t *testing.T) {
+	g := NewWithT(t)
+
+	record("https://cluster:6443", &kustomizev1.ClusterCapabilities{
+		Version:         "v1.24.0",
+		ServerSideApply: true,
+	})
+	g.Expect(testutil.ToFloat64(capability.WithLabelValues("https://cluster:6443", "ServerSideApply"))).To(Equal(float64(1)))
+	g.Expect(testutil.ToFloat64(capability.WithLabelValues("https://cluster:6443", "ApplySet"))).To(BeZero())
+}
@@ -0,0 +1,175 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package certpin verifies the certificate chain presented by the API server
+// of a remote cluster against pinned public keys or certificate fingerprints,
+// so that a tampered kubeconfig can't redirect the controller to another
+// server.
+package certpin
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	// SPKIPrefix is the prefix of the pins of the base64-encoded SHA-256
+	// digest of a Subject Public Key Info.
+	SPKIPrefix = "sha256/"
+
+	// FingerprintPrefix is the prefix of the pins of the hex-encoded
+	// SHA-256 fingerprint of a certificate.
+	FingerprintPrefix = "sha256:"
+)
+
+// MismatchError is returned when none of the certificates presented by the
+// API server matches the pins.
+type MismatchError struct {
+	// Server is the address of the API server.
+	Server string
+
+	// Presented lists the SPKI pins of the presented certificates.
+	Presented []string
+}
+
+// Error implements error.
+func (e *MismatchError) Error() string {
+	return fmt.Sprintf("the certificates of the API server %s don't match the pins, presented: %s",
+		e.Server, strings.Join(e.Presented, ", "))
+}
+
+// Pins is a set of pins.
+type Pins map[string]bool
+
+// Parse returns the set of pins, each either 'sha256/<base64>' for the
+// SPKI of a certificate, or 'sha256:<hex>' for the fingerprint of a
+// certificate.
+func Parse(pins []string) (Pins, error) {
+	set := make(Pins, len(pins))
+	for _, pin := range pins {
+		switch {
+		case strings.HasPrefix(pin, SPKIPrefix):
+			digest, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(pin, SPKIPrefix))
+			if err != nil || len(digest) != sha256.Size {
+				return nil, fmt.Errorf("invalid SPKI pin '%s': expected the base64-encoded SHA-256 digest", pin)
+			}
+		case strings.HasPrefix(pin, FingerprintPrefix):
+			digest, err := hex.DecodeString(strings.ReplaceAll(strings.TrimPrefix(pin, FingerprintPrefix), ":", ""))
+			if err != nil || len(digest) != sha256.Size {
+				return nil, fmt.Errorf("invalid certificate fingerprint pin '%s': expected the hex-encoded SHA-256 digest", pin)
+			}
+			pin = FingerprintPrefix + hex.EncodeToString(digest)
+		default:
+			return nil, fmt.Errorf("invalid pin '%s': expected the '%s' or '%s' prefix", pin, SPKIPrefix, FingerprintPrefix)
+		}
+		set[pin] = true
+	}
+	return set, nil
+}
+
+// SPKI returns the SPKI pin of the certificate.
+func SPKI(cert *x509.Certificate) string {
+	digest := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return SPKIPrefix + base64.StdEncoding.EncodeToString(digest[:])
+}
+
+// Fingerprint returns the fingerprint pin of the certificate.
+func Fingerprint(cert *x509.Certificate) string {
+	digest := sha256.Sum256(cert.Raw)
+	return FingerprintPrefix + hex.EncodeToString(digest[:])
+}
+
+// Verify returns nil if any certificate of the chain matches the pins.
+func (p Pins) Verify(server string, certs []*x509.Certificate) error {
+	presented := make([]string, 0, len(certs))
+	for _, cert := range certs {
+		if p[SPKI(cert)] || p[Fingerprint(cert)] {
+			return nil
+		}
+		presented = append(presented, SPKI(cert))
+	}
+	return &MismatchError{Server: server, Presented: presented}
+}
+
+// verifyConnection returns the callback verifying the connections, which
+// includes the CA certificates of the verified chains.
+func (p Pins) verifyConnection(server string) func(tls.ConnectionState) error {
+	return func(cs tls.ConnectionState) error {
+		certs := cs.PeerCertificates
+		for _, chain := range cs.VerifiedChains {
+			certs = append(certs, chain...)
+		}
+		return p.Verify(server, certs)
+	}
+}
+
+// Configure returns a copy of the REST config whose transport verifies the
+// certificates of the API server against the pins, on every connection.
+func Configure(cfg *rest.Config, pins Pins) (*rest.Config, error) {
+	tlsConfig, err := rest.TLSConfigFor(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig == nil {
+		return nil, fmt.Errorf("the API server %s is not served over TLS", cfg.Host)
+	}
+	tlsConfig.VerifyConnection = pins.verifyConnection(cfg.Host)
+
+	out := rest.CopyConfig(cfg)
+	out.TLSClientConfig = rest.TLSClientConfig{}
+	out.Transport = utilnet.SetTransportDefaults(&http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		TLSHandshakeTimeout: 10 * time.Second,
+		TLSClientConfig:     tlsConfig,
+	})
+	return out, nil
+}
+
+// Check requests the version of the API server with the REST config
+// returned by Configure, and returns a MismatchError if the certificates
+// of the API server don't match the pins. The other errors are ignored,
+// as they are reported by the actual requests.
+func Check(ctx context.Context, cfg *rest.Config) error {
+	client, err := rest.HTTPClientFor(cfg)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(cfg.Host, "/")+"/version", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		var mismatch *MismatchError
+		if errors.As(err, &mismatch) {
+			return mismatch
+		}
+		return nil
+	}
+	return resp.Body.Close()
+}
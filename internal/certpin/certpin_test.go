@@ -0,0 +1,93 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package certpin
+
+import (
+	"context"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"k8s.io/client-go/rest"
+)
+
+func TestParse(t *testing.T) {
+	g := NewWithT(t)
+
+	digest := strings.Repeat("ab", 32)
+	pins, err := Parse([]string{
+		"sha256/47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU=",
+		"sha256:" + strings.ToUpper(digest[:2]) + ":" + digest[2:],
+	})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(pins).To(HaveKey("sha256/47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU="))
+	g.Expect(pins).To(HaveKey("sha256:" + digest))
+
+	for _, pin := range []string{"sha256/short", "sha256:zz", "md5:abcd"} {
+		_, err := Parse([]string{pin})
+		g.Expect(err).To(HaveOccurred(), pin)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("{}"))
+	}))
+	defer server.Close()
+
+	cert := server.Certificate()
+	cfg := &rest.Config{
+		Host: server.URL,
+		TLSClientConfig: rest.TLSClientConfig{
+			CAData: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}),
+		},
+	}
+
+	tests := []struct {
+		name     string
+		pins     []string
+		mismatch bool
+	}{
+		{name: "SPKI pin", pins: []string{SPKI(cert)}},
+		{name: "fingerprint pin", pins: []string{"sha256/47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU=", Fingerprint(cert)}},
+		{name: "mismatch", pins: []string{"sha256/47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU="}, mismatch: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			pins, err := Parse(tt.pins)
+			g.Expect(err).ToNot(HaveOccurred())
+
+			pinned, err := Configure(cfg, pins)
+			g.Expect(err).ToNot(HaveOccurred())
+
+			err = Check(context.Background(), pinned)
+			if !tt.mismatch {
+				g.Expect(err).ToNot(HaveOccurred())
+				return
+			}
+			var mismatch *MismatchError
+			g.Expect(errors.As(err, &mismatch)).To(BeTrue())
+			g.Expect(mismatch.Presented).To(ContainElement(SPKI(cert)))
+		})
+	}
+}
@@ -0,0 +1,246 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cloudevents publishes the controller events to an HTTP sink
+// in the CloudEvents structured content mode.
+package cloudevents
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	eventv1 "github.com/fluxcd/pkg/apis/event/v1beta1"
+	"github.com/go-logr/logr"
+	"github.com/google/uuid"
+	"github.com/hashicorp/go-retryablehttp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kuberecorder "k8s.io/client-go/tools/record"
+	"k8s.io/client-go/tools/reference"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+)
+
+const (
+	// SpecVersion is the CloudEvents specification version.
+	SpecVersion = "1.0"
+
+	// ContentType is the content type of the structured events.
+	ContentType = "application/cloudevents+json"
+
+	// TypePrefix is the prefix of the event types, the type ends
+	// with the event reason.
+	TypePrefix = "io.fluxcd.kustomize."
+
+	// AddressKey is the Secret key holding the sink address.
+	AddressKey = "address"
+
+	// TokenKey is the Secret key holding the bearer token.
+	TokenKey = "token"
+)
+
+// Event is a CloudEvent in the structured content mode, the Flux event is
+// set as data, and the severity, revision and reconcile ID are extension
+// attributes.
+type Event struct {
+	SpecVersion     string        `json:"specversion"`
+	ID              string        `json:"id"`
+	Source          string        `json:"source"`
+	Type            string        `json:"type"`
+	Subject         string        `json:"subject"`
+	Time            time.Time     `json:"time"`
+	DataContentType string        `json:"datacontenttype"`
+	Severity        string        `json:"severity"`
+	Revision        string        `json:"revision,omitempty"`
+	ReconcileID     string        `json:"reconcileid,omitempty"`
+	Data            eventv1.Event `json:"data"`
+}
+
+// Recorder forwards the events to a Kubernetes event recorder, and
+// publishes them to the CloudEvents sink.
+type Recorder struct {
+	kuberecorder.EventRecorder
+
+	// Address is the URL of the CloudEvents sink.
+	Address string
+
+	// Token is the optional bearer token sent to the sink.
+	Token string
+
+	// ReportingController is the name of the controller.
+	ReportingController string
+
+	// Client is the retryable HTTP client.
+	Client *retryablehttp.Client
+
+	// Scheme is used to look up the recorded objects.
+	Scheme *runtime.Scheme
+
+	// Log is the recorder logger.
+	Log logr.Logger
+}
+
+var _ kuberecorder.EventRecorder = &Recorder{}
+
+// NewRecorder returns a Recorder which wraps the given event recorder and
+// publishes the events to the sink address.
+func NewRecorder(recorder kuberecorder.EventRecorder, scheme *runtime.Scheme, log logr.Logger,
+	address, token, reportingController string) (*Recorder, error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid CloudEvents sink address '%s': the scheme must be http or https", address)
+	}
+
+	httpClient := retryablehttp.NewClient()
+	httpClient.HTTPClient.Timeout = 5 * time.Second
+	httpClient.RetryMax = 3
+	httpClient.CheckRetry = retryablehttp.ErrorPropagatedRetryPolicy
+	httpClient.Logger = nil
+
+	return &Recorder{
+		EventRecorder:       recorder,
+		Address:             address,
+		Token:               token,
+		ReportingController: reportingController,
+		Client:              httpClient,
+		Scheme:              scheme,
+		Log:                 log,
+	}, nil
+}
+
+// SinkFromSecret returns the sink address and token stored in the given Secret.
+func SinkFromSecret(ctx context.Context, c client.Reader, key client.ObjectKey) (address, token string, err error) {
+	var secret corev1.Secret
+	if err := c.Get(ctx, key, &secret); err != nil {
+		return "", "", fmt.Errorf("failed to get CloudEvents secret '%s': %w", key, err)
+	}
+
+	address = strings.TrimSpace(string(secret.Data[AddressKey]))
+	if address == "" {
+		return "", "", fmt.Errorf("CloudEvents secret '%s' has no '%s' key", key, AddressKey)
+	}
+	return address, strings.TrimSpace(string(secret.Data[TokenKey])), nil
+}
+
+// Event records an event and publishes it to the sink.
+func (r *Recorder) Event(object runtime.Object, eventtype, reason, message string) {
+	r.AnnotatedEventf(object, nil, eventtype, reason, "%s", message)
+}
+
+// Eventf records an event and publishes it to the sink.
+func (r *Recorder) Eventf(object runtime.Object, eventtype, reason, messageFmt string, args ...interface{}) {
+	r.AnnotatedEventf(object, nil, eventtype, reason, messageFmt, args...)
+}
+
+// AnnotatedEventf records an event and publishes it to the sink in the
+// background, so that an unavailable sink doesn't delay the reconciliation.
+// Trace events are not published.
+func (r *Recorder) AnnotatedEventf(object runtime.Object, annotations map[string]string,
+	eventtype, reason, messageFmt string, args ...interface{}) {
+	r.EventRecorder.AnnotatedEventf(object, annotations, eventtype, reason, messageFmt, args...)
+
+	if eventtype == eventv1.EventTypeTrace {
+		return
+	}
+
+	ref, err := reference.GetReference(r.Scheme, object)
+	if err != nil {
+		r.Log.Error(err, "failed to get object reference")
+		return
+	}
+
+	event := r.newEvent(ref, annotations, eventtype, reason, fmt.Sprintf(messageFmt, args...))
+	go func() {
+		if err := r.publish(event); err != nil {
+			r.Log.Error(err, "unable to publish CloudEvent",
+				"name", ref.Name, "namespace", ref.Namespace, "reconciler kind", ref.Kind)
+		}
+	}()
+}
+
+func (r *Recorder) newEvent(ref *corev1.ObjectReference, annotations map[string]string,
+	eventtype, reason, message string) Event {
+	severity := eventv1.EventSeverityInfo
+	if eventtype == corev1.EventTypeWarning {
+		severity = eventv1.EventSeverityError
+	}
+
+	hostname, _ := os.Hostname()
+	now := metav1.Now()
+
+	return Event{
+		SpecVersion: SpecVersion,
+		ID:          uuid.NewString(),
+		Source: fmt.Sprintf("/apis/%s/namespaces/%s/%ss/%s",
+			ref.APIVersion, ref.Namespace, strings.ToLower(ref.Kind), ref.Name),
+		Type:            TypePrefix + strings.ToLower(ref.Kind) + "." + reason,
+		Subject:         fmt.Sprintf("%s/%s", ref.Namespace, ref.Name),
+		Time:            now.UTC(),
+		DataContentType: "application/json",
+		Severity:        severity,
+		Revision:        annotations[kustomizev1.GroupVersion.Group+"/revision"],
+		ReconcileID:     annotations[kustomizev1.GroupVersion.Group+"/reconcile-id"],
+		Data: eventv1.Event{
+			InvolvedObject:      *ref,
+			Severity:            severity,
+			Timestamp:           now,
+			Message:             message,
+			Reason:              reason,
+			Metadata:            annotations,
+			ReportingController: r.ReportingController,
+			ReportingInstance:   hostname,
+		},
+	}
+}
+
+func (r *Recorder) publish(event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := retryablehttp.NewRequest(http.MethodPost, r.Address, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", ContentType)
+	if r.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+r.Token)
+	}
+
+	res, err := r.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("CloudEvents sink responded with status %s", res.Status)
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudevents
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	eventv1 "github.com/fluxcd/pkg/apis/event/v1beta1"
+	"github.com/go-logr/logr"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+)
+
+func TestRecorder(t *testing.T) {
+	g := NewWithT(t)
+
+	type request struct {
+		contentType   string
+		authorization string
+		event         Event
+	}
+	requests := make(chan request, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var e Event
+		g.Expect(json.NewDecoder(r.Body).Decode(&e)).To(Succeed())
+		requests <- request{
+			contentType:   r.Header.Get("Content-Type"),
+			authorization: r.Header.Get("Authorization"),
+			event:         e,
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	scheme := runtime.NewScheme()
+	g.Expect(kustomizev1.AddToScheme(scheme)).To(Succeed())
+
+	kubeRecorder := record.NewFakeRecorder(10)
+	recorder, err := NewRecorder(kubeRecorder, scheme, logr.Discard(), server.URL, "secret-token", "kustomize-controller")
+	g.Expect(err).ToNot(HaveOccurred())
+
+	obj := &kustomizev1.Kustomization{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "app",
+			Namespace: "apps",
+		},
+	}
+
+	t.Run("publishes events", func(t *testing.T) {
+		recorder.AnnotatedEventf(obj, map[string]string{
+			kustomizev1.GroupVersion.Group + "/revision":     "main@sha1:abc",
+			kustomizev1.GroupVersion.Group + "/reconcile-id": "6f1c2a3e-8f6b-4d2c-9a3b-1f2e3d4c5b6a",
+		}, corev1.EventTypeWarning, kustomizev1.HealthCheckFailedReason, "health check failed after %s", "5m")
+
+		g.Expect(kubeRecorder.Events).To(Receive(HavePrefix("Warning HealthCheckFailed health check failed after 5m")))
+
+		var req request
+		g.Eventually(requests, time.Second).Should(Receive(&req))
+		g.Expect(req.contentType).To(Equal(ContentType))
+		g.Expect(req.authorization).To(Equal("Bearer secret-token"))
+
+		e := req.event
+		g.Expect(e.SpecVersion).To(Equal(SpecVersion))
+		g.Expect(e.ID).ToNot(BeEmpty())
+		g.Expect(e.Source).To(Equal("/apis/kustomize.toolkit.fluxcd.io/v1/namespaces/apps/kustomizations/app"))
+		g.Expect(e.Type).To(Equal("io.fluxcd.kustomize.kustomization.HealthCheckFailed"))
+		g.Expect(e.Subject).To(Equal("apps/app"))
+		g.Expect(e.Severity).To(Equal(eventv1.EventSeverityError))
+		g.Expect(e.Revision).To(Equal("main@sha1:abc"))
+		g.Expect(e.ReconcileID).To(Equal("6f1c2a3e-8f6b-4d2c-9a3b-1f2e3d4c5b6a"))
+		g.Expect(e.Data.Message).To(Equal("health check failed after 5m"))
+		g.Expect(e.Data.ReportingController).To(Equal("kustomize-controller"))
+	})
+
+	t.Run("skips trace events", func(t *testing.T) {
+		recorder.Event(obj, eventv1.EventTypeTrace, "Progressing", "building")
+		g.Consistently(requests, 200*time.Millisecond).ShouldNot(Receive())
+	})
+
+	t.Run("rejects invalid addresses", func(t *testing.T) {
+		_, err := NewRecorder(kubeRecorder, scheme, logr.Discard(), "tcp://events:9000", "", "kustomize-controller")
+		g.Expect(err).To(HaveOccurred())
+	})
+}
+
+func TestSinkFromSecret(t *testing.T) {
+	g := NewWithT(t)
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "cloudevents",
+			Namespace: "flux-system",
+		},
+		Data: map[string][]byte{
+			AddressKey: []byte("https://events.example.com\n"),
+			TokenKey:   []byte("token"),
+		},
+	}
+	c := fake.NewClientBuilder().WithObjects(secret).Build()
+
+	address, token, err := SinkFromSecret(context.Background(), c, client.ObjectKeyFromObject(secret))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(address).To(Equal("https://events.example.com"))
+	g.Expect(token).To(Equal("token"))
+
+	_, _, err = SinkFromSecret(context.Background(), c, client.ObjectKey{Namespace: "flux-system", Name: "missing"})
+	g.Expect(err).To(HaveOccurred())
+}
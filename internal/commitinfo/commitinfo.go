@@ -0,0 +1,109 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package commitinfo reads the commit metadata of the source revisions from
+// the artifact metadata, and carries it along the reconciliation so that it
+// annotates the events of the revision.
+package commitinfo
+
+import (
+	"context"
+	"strings"
+
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+)
+
+const (
+	// OCIAuthorsAnnotation is the OCI annotation listing the authors of the
+	// artifact, used when the artifact doesn't set the commit author.
+	OCIAuthorsAnnotation = "org.opencontainers.image.authors"
+
+	// maxSubjectLength bounds the length of the commit subject, as the
+	// metadata is set by the tools pushing the artifacts.
+	maxSubjectLength = 256
+)
+
+// Info is the commit metadata of a source revision.
+type Info struct {
+	// Revision is the source revision of the commit.
+	Revision string
+
+	// Subject is the first line of the commit message.
+	Subject string
+
+	// Author is the author of the commit.
+	Author string
+}
+
+// FromArtifact returns the commit metadata of the artifact, or nil if the
+// artifact doesn't provide any.
+func FromArtifact(artifact *sourcev1.Artifact) *Info {
+	if artifact == nil {
+		return nil
+	}
+
+	subject, _, _ := strings.Cut(strings.TrimSpace(artifact.Metadata[kustomizev1.CommitSubjectMetadataKey]), "\n")
+	subject = strings.TrimSpace(subject)
+	if r := []rune(subject); len(r) > maxSubjectLength {
+		subject = string(r[:maxSubjectLength]) + "..."
+	}
+	author := strings.TrimSpace(artifact.Metadata[kustomizev1.CommitAuthorMetadataKey])
+	if author == "" {
+		author = strings.TrimSpace(artifact.Metadata[OCIAuthorsAnnotation])
+	}
+	if subject == "" && author == "" {
+		return nil
+	}
+	return &Info{Revision: artifact.Revision, Subject: subject, Author: author}
+}
+
+// Status returns the commit metadata recorded in the status of the
+// Kustomizations.
+func (i *Info) Status() *kustomizev1.CommitMetadata {
+	if i == nil {
+		return nil
+	}
+	return &kustomizev1.CommitMetadata{Subject: i.Subject, Author: i.Author}
+}
+
+// Annotate adds the commit metadata to the metadata of an event of the
+// revision, with the keys prefixed with the API group.
+func (i *Info) Annotate(metadata map[string]string, revision string) {
+	if i == nil || revision != i.Revision {
+		return
+	}
+	if i.Subject != "" {
+		metadata[kustomizev1.GroupVersion.Group+"/commit-subject"] = i.Subject
+	}
+	if i.Author != "" {
+		metadata[kustomizev1.GroupVersion.Group+"/commit-author"] = i.Author
+	}
+}
+
+type contextKey struct{}
+
+// IntoContext returns a copy of the context carrying the commit metadata.
+func IntoContext(ctx context.Context, info *Info) context.Context {
+	return context.WithValue(ctx, contextKey{}, info)
+}
+
+// FromContext returns the commit metadata carried by the context, or nil.
+func FromContext(ctx context.Context) *Info {
+	info, _ := ctx.Value(contextKey{}).(*Info)
+	return info
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commitinfo
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	. "github.com/onsi/gomega"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+)
+
+func TestFromArtifact(t *testing.T) {
+	tests := []struct {
+		name     string
+		metadata map[string]string
+		want     *Info
+	}{
+		{
+			name: "without metadata",
+		},
+		{
+			name: "subject and author",
+			metadata: map[string]string{
+				kustomizev1.CommitSubjectMetadataKey: "Bump the frontend to v1.2.0",
+				kustomizev1.CommitAuthorMetadataKey:  "Jane Doe <jane@example.com>",
+			},
+			want: &Info{Revision: "main@sha1:1", Subject: "Bump the frontend to v1.2.0", Author: "Jane Doe <jane@example.com>"},
+		},
+		{
+			name: "full commit message",
+			metadata: map[string]string{
+				kustomizev1.CommitSubjectMetadataKey: "Bump the frontend to v1.2.0\n\nFixes the login page.",
+			},
+			want: &Info{Revision: "main@sha1:1", Subject: "Bump the frontend to v1.2.0"},
+		},
+		{
+			name: "OCI authors",
+			metadata: map[string]string{
+				OCIAuthorsAnnotation: "platform@example.com",
+			},
+			want: &Info{Revision: "main@sha1:1", Author: "platform@example.com"},
+		},
+		{
+			name: "long subject",
+			metadata: map[string]string{
+				kustomizev1.CommitSubjectMetadataKey: strings.Repeat("é", 300),
+			},
+			want: &Info{Revision: "main@sha1:1", Subject: strings.Repeat("é", maxSubjectLength) + "..."},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			got := FromArtifact(&sourcev1.Artifact{Revision: "main@sha1:1", Metadata: tt.metadata})
+			g.Expect(got).To(Equal(tt.want))
+		})
+	}
+}
+
+func TestAnnotate(t *testing.T) {
+	g := NewWithT(t)
+
+	info := &Info{Revision: "main@sha1:1", Subject: "Bump the frontend to v1.2.0", Author: "Jane Doe <jane@example.com>"}
+	ctx := IntoContext(context.Background(), info)
+
+	metadata := map[string]string{}
+	FromContext(ctx).Annotate(metadata, "main@sha1:1")
+	g.Expect(metadata).To(Equal(map[string]string{
+		"kustomize.toolkit.fluxcd.io/commit-subject": "Bump the frontend to v1.2.0",
+		"kustomize.toolkit.fluxcd.io/commit-author":  "Jane Doe <jane@example.com>",
+	}))
+
+	// The events of other revisions are not annotated.
+	metadata = map[string]string{}
+	FromContext(ctx).Annotate(metadata, "main@sha1:2")
+	g.Expect(metadata).To(BeEmpty())
+
+	// The contexts without commit metadata are supported.
+	FromContext(context.Background()).Annotate(metadata, "main@sha1:1")
+	g.Expect(metadata).To(BeEmpty())
+	g.Expect(FromContext(context.Background()).Status()).To(BeNil())
+}
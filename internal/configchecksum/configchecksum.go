@@ -0,0 +1,193 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package configchecksum annotates the pod templates of workloads with a
+// checksum of the ConfigMaps and Secrets they refer to, so that changes to
+// their data trigger a rollout.
+package configchecksum
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+)
+
+// Annotation is the pod template annotation holding the checksum.
+var Annotation = fmt.Sprintf("%s/config-checksum", kustomizev1.GroupVersion.Group)
+
+// templatePaths maps the workload kinds to the path of their pod template.
+var templatePaths = map[string][]string{
+	"apps/Deployment":  {"spec", "template"},
+	"apps/StatefulSet": {"spec", "template"},
+	"apps/DaemonSet":   {"spec", "template"},
+	"batch/CronJob":    {"spec", "jobTemplate", "spec", "template"},
+}
+
+// TemplatePath returns the path of the pod template of a workload,
+// or false if the object is not a supported workload.
+func TemplatePath(u *unstructured.Unstructured) ([]string, bool) {
+	path, ok := templatePaths[u.GroupVersionKind().Group+"/"+u.GetKind()]
+	return path, ok
+}
+
+// Set annotates the pod templates of the workloads with the checksum of the
+// ConfigMaps and Secrets they refer to. Only the ConfigMaps and Secrets
+// which are part of the objects are taken into account.
+func Set(objects []*unstructured.Unstructured) error {
+	checksums := make(map[string]string)
+	for _, u := range objects {
+		if u.GetAPIVersion() != "v1" || (u.GetKind() != "ConfigMap" && u.GetKind() != "Secret") {
+			continue
+		}
+		sum, err := dataChecksum(u)
+		if err != nil {
+			return fmt.Errorf("%s/%s/%s: %w", u.GetKind(), u.GetNamespace(), u.GetName(), err)
+		}
+		checksums[key(u.GetKind(), u.GetNamespace(), u.GetName())] = sum
+	}
+	if len(checksums) == 0 {
+		return nil
+	}
+
+	for _, u := range objects {
+		path, ok := TemplatePath(u)
+		if !ok {
+			continue
+		}
+
+		tmpl, found, err := unstructured.NestedMap(u.Object, path...)
+		if err != nil || !found {
+			continue
+		}
+		var podTemplate corev1.PodTemplateSpec
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(tmpl, &podTemplate); err != nil {
+			return fmt.Errorf("%s/%s/%s: %w", u.GetKind(), u.GetNamespace(), u.GetName(), err)
+		}
+
+		var refs []string
+		for _, ref := range References(podTemplate.Spec) {
+			k := key(ref.Kind, u.GetNamespace(), ref.Name)
+			if sum, ok := checksums[k]; ok {
+				refs = append(refs, fmt.Sprintf("%s:%s", k, sum))
+			}
+		}
+		if len(refs) == 0 {
+			continue
+		}
+		sort.Strings(refs)
+		refs = slices.Compact(refs)
+
+		annotationsPath := append(append([]string{}, path...), "metadata", "annotations")
+		if err := unstructured.SetNestedField(u.Object, checksum([]byte(strings.Join(refs, "\n"))),
+			append(annotationsPath, Annotation)...); err != nil {
+			return fmt.Errorf("%s/%s/%s: %w", u.GetKind(), u.GetNamespace(), u.GetName(), err)
+		}
+	}
+	return nil
+}
+
+// Reference is a ConfigMap or Secret referred to by a pod.
+type Reference struct {
+	Kind string
+	Name string
+}
+
+// References returns the ConfigMaps and Secrets used by the pod
+// in volumes and environment variables.
+func References(spec corev1.PodSpec) []Reference {
+	var refs []Reference
+	add := func(kind, name string) {
+		if name != "" {
+			refs = append(refs, Reference{Kind: kind, Name: name})
+		}
+	}
+
+	for _, v := range spec.Volumes {
+		if v.ConfigMap != nil {
+			add("ConfigMap", v.ConfigMap.Name)
+		}
+		if v.Secret != nil {
+			add("Secret", v.Secret.SecretName)
+		}
+		if v.Projected != nil {
+			for _, src := range v.Projected.Sources {
+				if src.ConfigMap != nil {
+					add("ConfigMap", src.ConfigMap.Name)
+				}
+				if src.Secret != nil {
+					add("Secret", src.Secret.Name)
+				}
+			}
+		}
+	}
+
+	containers := append(append([]corev1.Container{}, spec.InitContainers...), spec.Containers...)
+	for _, c := range containers {
+		for _, envFrom := range c.EnvFrom {
+			if envFrom.ConfigMapRef != nil {
+				add("ConfigMap", envFrom.ConfigMapRef.Name)
+			}
+			if envFrom.SecretRef != nil {
+				add("Secret", envFrom.SecretRef.Name)
+			}
+		}
+		for _, env := range c.Env {
+			if env.ValueFrom == nil {
+				continue
+			}
+			if env.ValueFrom.ConfigMapKeyRef != nil {
+				add("ConfigMap", env.ValueFrom.ConfigMapKeyRef.Name)
+			}
+			if env.ValueFrom.SecretKeyRef != nil {
+				add("Secret", env.ValueFrom.SecretKeyRef.Name)
+			}
+		}
+	}
+
+	return refs
+}
+
+// dataChecksum returns the checksum of the data of a ConfigMap or Secret.
+func dataChecksum(u *unstructured.Unstructured) (string, error) {
+	data := make(map[string]interface{})
+	for _, field := range []string{"data", "binaryData", "stringData"} {
+		if v, ok := u.Object[field]; ok {
+			data[field] = v
+		}
+	}
+	b, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+	return checksum(b), nil
+}
+
+func checksum(b []byte) string {
+	return fmt.Sprintf("%x", sha256.Sum256(b))
+}
+
+func key(kind, namespace, name string) string {
+	return fmt.Sprintf("%s/%s/%s", kind, namespace, name)
+}
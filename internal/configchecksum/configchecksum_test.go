@@ -0,0 +1,89 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package configchecksum
+
+import (
+	"os"
+	"testing"
+
+	ssautil "github.com/fluxcd/pkg/ssa/utils"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func readObjects(t *testing.T) []*unstructured.Unstructured {
+	t.Helper()
+	f, err := os.Open("testdata/objects.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	objects, err := ssautil.ReadObjects(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return objects
+}
+
+func find(objects []*unstructured.Unstructured, kind string) *unstructured.Unstructured {
+	for _, u := range objects {
+		if u.GetKind() == kind {
+			return u
+		}
+	}
+	return nil
+}
+
+func templateChecksum(g *WithT, u *unstructured.Unstructured, path ...string) string {
+	path = append(path, "metadata", "annotations", Annotation)
+	v, _, err := unstructured.NestedString(u.Object, path...)
+	g.Expect(err).ToNot(HaveOccurred())
+	return v
+}
+
+func Test_Set(t *testing.T) {
+	g := NewWithT(t)
+
+	objects := readObjects(t)
+	g.Expect(Set(objects)).To(Succeed())
+
+	deploySum := templateChecksum(g, find(objects, "Deployment"), "spec", "template")
+	g.Expect(deploySum).To(HaveLen(64))
+
+	cronSum := templateChecksum(g, find(objects, "CronJob"), "spec", "jobTemplate", "spec", "template")
+	g.Expect(cronSum).To(HaveLen(64))
+	g.Expect(cronSum).ToNot(Equal(deploySum))
+
+	// ConfigMaps which are not part of the objects are ignored.
+	g.Expect(templateChecksum(g, find(objects, "StatefulSet"), "spec", "template")).To(BeEmpty())
+
+	t.Run("is stable", func(t *testing.T) {
+		g := NewWithT(t)
+		objects := readObjects(t)
+		g.Expect(Set(objects)).To(Succeed())
+		g.Expect(templateChecksum(g, find(objects, "Deployment"), "spec", "template")).To(Equal(deploySum))
+	})
+
+	t.Run("changes with the secret data", func(t *testing.T) {
+		g := NewWithT(t)
+		objects := readObjects(t)
+		g.Expect(unstructured.SetNestedField(find(objects, "Secret").Object, "rotated", "stringData", "token")).To(Succeed())
+		g.Expect(Set(objects)).To(Succeed())
+		g.Expect(templateChecksum(g, find(objects, "Deployment"), "spec", "template")).ToNot(Equal(deploySum))
+		g.Expect(templateChecksum(g, find(objects, "CronJob"), "spec", "jobTemplate", "spec", "template")).To(Equal(cronSum))
+	})
+}
@@ -0,0 +1,49 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"reflect"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+// ConfigDataChangePredicate filters the updates of ConfigMaps and Secrets
+// which don't change their data, such as metadata only updates.
+type ConfigDataChangePredicate struct {
+	predicate.Funcs
+}
+
+func (ConfigDataChangePredicate) Update(e event.UpdateEvent) bool {
+	if e.ObjectOld == nil || e.ObjectNew == nil {
+		return false
+	}
+
+	switch oldObj := e.ObjectOld.(type) {
+	case *corev1.ConfigMap:
+		newObj, ok := e.ObjectNew.(*corev1.ConfigMap)
+		return ok && (!reflect.DeepEqual(oldObj.Data, newObj.Data) ||
+			!reflect.DeepEqual(oldObj.BinaryData, newObj.BinaryData))
+	case *corev1.Secret:
+		newObj, ok := e.ObjectNew.(*corev1.Secret)
+		return ok && !reflect.DeepEqual(oldObj.Data, newObj.Data)
+	}
+
+	return false
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+func TestConfigDataChangePredicate_Update(t *testing.T) {
+	configMap := func(labels, data map[string]string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "vars", Labels: labels},
+			Data:       data,
+		}
+	}
+	secret := func(labels map[string]string, data map[string][]byte) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "vars", Labels: labels},
+			Data:       data,
+		}
+	}
+
+	tests := []struct {
+		name   string
+		oldObj client.Object
+		newObj client.Object
+		want   bool
+	}{
+		{
+			name:   "ConfigMap data change",
+			oldObj: configMap(nil, map[string]string{"zone": "az-1a"}),
+			newObj: configMap(nil, map[string]string{"zone": "az-1b"}),
+			want:   true,
+		},
+		{
+			name:   "ConfigMap metadata change",
+			oldObj: configMap(nil, map[string]string{"zone": "az-1a"}),
+			newObj: configMap(map[string]string{"app": "test"}, map[string]string{"zone": "az-1a"}),
+			want:   false,
+		},
+		{
+			name:   "Secret data change",
+			oldObj: secret(nil, map[string][]byte{"token": []byte("old")}),
+			newObj: secret(nil, map[string][]byte{"token": []byte("new")}),
+			want:   true,
+		},
+		{
+			name:   "Secret metadata change",
+			oldObj: secret(nil, map[string][]byte{"token": []byte("old")}),
+			newObj: secret(map[string]string{"app": "test"}, map[string][]byte{"token": []byte("old")}),
+			want:   false,
+		},
+		{
+			name:   "mismatched kinds",
+			oldObj: configMap(nil, nil),
+			newObj: secret(nil, nil),
+			want:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			got := ConfigDataChangePredicate{}.Update(event.UpdateEvent{ObjectOld: tt.oldObj, ObjectNew: tt.newObj})
+			g.Expect(got).To(Equal(tt.want))
+		})
+	}
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+)
+
+// GateOpenedPredicate triggers an update event when a ReconciliationGate
+// is opened, and a delete event when a closed gate is deleted, so that the
+// paused Kustomizations are reconciled as soon as they are released.
+type GateOpenedPredicate struct {
+	predicate.Funcs
+}
+
+func (GateOpenedPredicate) Create(event.CreateEvent) bool {
+	return false
+}
+
+func (GateOpenedPredicate) Update(e event.UpdateEvent) bool {
+	if e.ObjectOld == nil || e.ObjectNew == nil {
+		return false
+	}
+
+	oldObj, ok := e.ObjectOld.(*kustomizev1.ReconciliationGate)
+	if !ok {
+		return false
+	}
+	newObj, ok := e.ObjectNew.(*kustomizev1.ReconciliationGate)
+	if !ok {
+		return false
+	}
+
+	return oldObj.Spec.Closed && !newObj.Spec.Closed
+}
+
+func (GateOpenedPredicate) Delete(e event.DeleteEvent) bool {
+	obj, ok := e.Object.(*kustomizev1.ReconciliationGate)
+	return ok && obj.Spec.Closed
+}
+
+func (GateOpenedPredicate) Generic(event.GenericEvent) bool {
+	return false
+}
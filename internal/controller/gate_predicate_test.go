@@ -0,0 +1,75 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+)
+
+func TestGateOpenedPredicate(t *testing.T) {
+	gate := func(closed bool) *kustomizev1.ReconciliationGate {
+		return &kustomizev1.ReconciliationGate{
+			ObjectMeta: metav1.ObjectMeta{Name: "freeze"},
+			Spec:       kustomizev1.ReconciliationGateSpec{Closed: closed},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		oldObj *kustomizev1.ReconciliationGate
+		newObj *kustomizev1.ReconciliationGate
+		want   bool
+	}{
+		{
+			name:   "opened",
+			oldObj: gate(true),
+			newObj: gate(false),
+			want:   true,
+		},
+		{
+			name:   "closed",
+			oldObj: gate(false),
+			newObj: gate(true),
+			want:   false,
+		},
+		{
+			name:   "still closed",
+			oldObj: gate(true),
+			newObj: gate(true),
+			want:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			got := GateOpenedPredicate{}.Update(event.UpdateEvent{ObjectOld: tt.oldObj, ObjectNew: tt.newObj})
+			g.Expect(got).To(Equal(tt.want))
+		})
+	}
+
+	g := NewWithT(t)
+	g.Expect(GateOpenedPredicate{}.Delete(event.DeleteEvent{Object: gate(true)})).To(BeTrue())
+	g.Expect(GateOpenedPredicate{}.Delete(event.DeleteEvent{Object: gate(false)})).To(BeFalse())
+	g.Expect(GateOpenedPredicate{}.Create(event.CreateEvent{Object: gate(true)})).To(BeFalse())
+}
@@ -0,0 +1,250 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/fluxcd/pkg/testserver"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+)
+
+func TestKustomizationReconciler_ApplyStrategy(t *testing.T) {
+	g := NewWithT(t)
+	id := "as-" + randStringRunes(5)
+	revision := "v1.0.0"
+	resultK := &kustomizev1.Kustomization{}
+
+	err := createNamespace(id)
+	g.Expect(err).NotTo(HaveOccurred(), "failed to create test namespace")
+
+	err = createKubeConfigSecret(id)
+	g.Expect(err).NotTo(HaveOccurred(), "failed to create kubeconfig secret")
+
+	manifests := func(data string) []testserver.File {
+		return []testserver.File{
+			{
+				Name: "config.yaml",
+				Body: fmt.Sprintf(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+data:
+%s
+`, data),
+			},
+			{
+				Name: "secret.yaml",
+				Body: `apiVersion: v1
+kind: Secret
+metadata:
+  name: secret
+stringData:
+  key: value
+`,
+			},
+		}
+	}
+
+	artifact, err := testServer.ArtifactFromFiles(manifests("  a: \"1\"\n  b: \"2\""))
+	g.Expect(err).NotTo(HaveOccurred())
+
+	repositoryName := types.NamespacedName{
+		Name:      fmt.Sprintf("as-%s", randStringRunes(5)),
+		Namespace: id,
+	}
+
+	err = applyGitRepository(repositoryName, artifact, revision)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	kustomization := &kustomizev1.Kustomization{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("as-%s", randStringRunes(5)),
+			Namespace: id,
+		},
+		Spec: kustomizev1.KustomizationSpec{
+			Interval:        metav1.Duration{Duration: reconciliationInterval},
+			Path:            "./",
+			TargetNamespace: id,
+			Prune:           true,
+			KubeConfig: &meta.KubeConfigReference{
+				SecretRef: meta.SecretKeyReference{
+					Name: "kubeconfig",
+				},
+			},
+			SourceRef: kustomizev1.CrossNamespaceSourceReference{
+				Name:      repositoryName.Name,
+				Namespace: repositoryName.Namespace,
+				Kind:      sourcev1.GitRepositoryKind,
+			},
+			ApplyStrategy: &kustomizev1.ApplyStrategy{
+				Overrides: []kustomizev1.ApplyStrategyOverride{
+					{Kind: "ConfigMap", Strategy: kustomizev1.ClientSideApply},
+				},
+			},
+		},
+	}
+
+	g.Expect(k8sClient.Create(context.Background(), kustomization)).To(Succeed())
+
+	configMap := &corev1.ConfigMap{}
+	configMapKey := client.ObjectKey{Name: "config", Namespace: id}
+
+	t.Run("applies the overridden kinds with client-side apply", func(t *testing.T) {
+		g := NewWithT(t)
+		g.Eventually(func() bool {
+			_ = k8sClient.Get(context.Background(), client.ObjectKeyFromObject(kustomization), resultK)
+			return resultK.Status.LastAppliedRevision == revision
+		}, timeout, time.Second).Should(BeTrue())
+
+		g.Expect(resultK.Status.Inventory.Entries).To(HaveLen(2))
+		g.Expect(resultK.Status.ClusterCapabilities).ToNot(BeNil())
+		g.Expect(resultK.Status.ClusterCapabilities.ServerSideApply).To(BeTrue())
+
+		g.Expect(k8sClient.Get(context.Background(), configMapKey, configMap)).To(Succeed())
+		g.Expect(configMap.Annotations).To(HaveKey(corev1.LastAppliedConfigAnnotation))
+		g.Expect(configMap.ManagedFields).To(ContainElement(
+			HaveField("Operation", metav1.ManagedFieldsOperationUpdate)))
+
+		secret := &corev1.Secret{}
+		g.Expect(k8sClient.Get(context.Background(), client.ObjectKey{Name: "secret", Namespace: id}, secret)).To(Succeed())
+		g.Expect(secret.Annotations).ToNot(HaveKey(corev1.LastAppliedConfigAnnotation))
+	})
+
+	t.Run("removes the fields removed from the manifests", func(t *testing.T) {
+		g := NewWithT(t)
+
+		g.Expect(k8sClient.Get(context.Background(), configMapKey, configMap)).To(Succeed())
+		configMap.Data["other"] = "3"
+		g.Expect(k8sClient.Update(context.Background(), configMap)).To(Succeed())
+
+		revision = "v2.0.0"
+		artifact, err := testServer.ArtifactFromFiles(manifests("  a: \"changed\""))
+		g.Expect(err).NotTo(HaveOccurred())
+		err = applyGitRepository(repositoryName, artifact, revision)
+		g.Expect(err).NotTo(HaveOccurred())
+
+		g.Eventually(func() bool {
+			_ = k8sClient.Get(context.Background(), client.ObjectKeyFromObject(kustomization), resultK)
+			return resultK.Status.LastAppliedRevision == revision
+		}, timeout, time.Second).Should(BeTrue())
+
+		g.Expect(k8sClient.Get(context.Background(), configMapKey, configMap)).To(Succeed())
+		g.Expect(configMap.Data).To(Equal(map[string]string{"a": "changed", "other": "3"}))
+	})
+}
+
+func TestKustomizationReconciler_ApplyStrategySingleStage(t *testing.T) {
+	g := NewWithT(t)
+	id := "as-" + randStringRunes(5)
+	revision := "v1.0.0"
+	resultK := &kustomizev1.Kustomization{}
+
+	err := createNamespace(id)
+	g.Expect(err).NotTo(HaveOccurred(), "failed to create test namespace")
+
+	// The objects are declared in the reverse order of the staged apply.
+	artifact, err := testServer.ArtifactFromFiles([]testserver.File{
+		{
+			Name: "objects.yaml",
+			Body: fmt.Sprintf(`---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: zeta
+  namespace: %[1]s
+data:
+  key: value
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: alpha
+  namespace: %[1]s
+data:
+  key: value
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: %[1]s-last
+`, id),
+		},
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	repositoryName := types.NamespacedName{
+		Name:      fmt.Sprintf("as-%s", randStringRunes(5)),
+		Namespace: id,
+	}
+	err = applyGitRepository(repositoryName, artifact, revision)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	kustomization := &kustomizev1.Kustomization{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("as-%s", randStringRunes(5)),
+			Namespace: id,
+		},
+		Spec: kustomizev1.KustomizationSpec{
+			Interval: metav1.Duration{Duration: reconciliationInterval},
+			Path:     "./",
+			SourceRef: kustomizev1.CrossNamespaceSourceReference{
+				Name:      repositoryName.Name,
+				Namespace: repositoryName.Namespace,
+				Kind:      sourcev1.GitRepositoryKind,
+			},
+			Prune: true,
+			ApplyStrategy: &kustomizev1.ApplyStrategy{
+				SingleStage: true,
+			},
+		},
+	}
+	g.Expect(k8sClient.Create(context.Background(), kustomization)).To(Succeed())
+
+	g.Eventually(func() bool {
+		_ = k8sClient.Get(context.Background(), client.ObjectKeyFromObject(kustomization), resultK)
+		return resultK.Status.LastAppliedRevision == revision
+	}, timeout, time.Second).Should(BeTrue())
+	logStatus(t, resultK)
+	g.Expect(resultK.Status.Inventory.Entries).To(HaveLen(3))
+
+	// The resource versions of the created objects follow the order of
+	// their creation.
+	resourceVersion := func(obj client.Object, name, namespace string) int {
+		g.Expect(k8sClient.Get(context.Background(), types.NamespacedName{Name: name, Namespace: namespace}, obj)).To(Succeed())
+		v, err := strconv.Atoi(obj.GetResourceVersion())
+		g.Expect(err).NotTo(HaveOccurred())
+		return v
+	}
+	zeta := resourceVersion(&corev1.ConfigMap{}, "zeta", id)
+	alpha := resourceVersion(&corev1.ConfigMap{}, "alpha", id)
+	last := resourceVersion(&corev1.Namespace{}, id+"-last", "")
+	g.Expect(zeta).To(BeNumerically("<", alpha))
+	g.Expect(alpha).To(BeNumerically("<", last))
+}
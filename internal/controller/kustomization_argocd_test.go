@@ -0,0 +1,148 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/fluxcd/pkg/testserver"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+	"github.com/fluxcd/kustomize-controller/internal/argocd"
+)
+
+func TestKustomizationReconciler_ArgoCDMigration(t *testing.T) {
+	g := NewWithT(t)
+	id := "argo-" + randStringRunes(5)
+	revision := "v1.0.0"
+	resultK := &kustomizev1.Kustomization{}
+
+	err := createNamespace(id)
+	g.Expect(err).NotTo(HaveOccurred(), "failed to create test namespace")
+
+	err = createKubeConfigSecret(id)
+	g.Expect(err).NotTo(HaveOccurred(), "failed to create kubeconfig secret")
+
+	// The object as applied by Argo CD.
+	tracked := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "config",
+			Namespace: id,
+			Labels:    map[string]string{argocd.InstanceLabel: "podinfo"},
+			Annotations: map[string]string{
+				argocd.TrackingIDAnnotation:        fmt.Sprintf("podinfo:/ConfigMap:%s/config", id),
+				corev1.LastAppliedConfigAnnotation: "{}",
+			},
+		},
+		Data: map[string]string{"key": "value"},
+	}
+	g.Expect(k8sClient.Create(context.Background(), tracked)).To(Succeed())
+
+	artifact, err := testServer.ArtifactFromFiles([]testserver.File{
+		{
+			Name: "config.yaml",
+			Body: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+data:
+  key: value
+`,
+		},
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	repositoryName := types.NamespacedName{
+		Name:      fmt.Sprintf("argo-%s", randStringRunes(5)),
+		Namespace: id,
+	}
+
+	err = applyGitRepository(repositoryName, artifact, revision)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	kustomization := &kustomizev1.Kustomization{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("argo-%s", randStringRunes(5)),
+			Namespace: id,
+		},
+		Spec: kustomizev1.KustomizationSpec{
+			Interval:        metav1.Duration{Duration: reconciliationInterval},
+			Path:            "./",
+			TargetNamespace: id,
+			Prune:           true,
+			KubeConfig: &meta.KubeConfigReference{
+				SecretRef: meta.SecretKeyReference{
+					Name: "kubeconfig",
+				},
+			},
+			SourceRef: kustomizev1.CrossNamespaceSourceReference{
+				Name:      repositoryName.Name,
+				Namespace: repositoryName.Namespace,
+				Kind:      sourcev1.GitRepositoryKind,
+			},
+			ArgoCDMigration: &kustomizev1.ArgoCDMigration{
+				Application: "podinfo",
+			},
+		},
+	}
+
+	g.Expect(k8sClient.Create(context.Background(), kustomization)).To(Succeed())
+
+	t.Run("adopts the tracked objects", func(t *testing.T) {
+		g := NewWithT(t)
+		g.Eventually(func() bool {
+			_ = k8sClient.Get(context.Background(), client.ObjectKeyFromObject(kustomization), resultK)
+			return isReconcileSuccess(resultK)
+		}, timeout, time.Second).Should(BeTrue())
+
+		g.Expect(resultK.Status.ArgoCDMigration).ToNot(BeNil())
+		g.Expect(resultK.Status.ArgoCDMigration.Tracked).To(Equal(1))
+		g.Expect(resultK.Status.Inventory.Entries).To(HaveLen(1))
+
+		g.Expect(k8sClient.Get(context.Background(), client.ObjectKeyFromObject(tracked), tracked)).To(Succeed())
+		g.Expect(tracked.GetAnnotations()).To(HaveKey(argocd.TrackingIDAnnotation))
+		g.Expect(tracked.GetAnnotations()).To(HaveKey(corev1.LastAppliedConfigAnnotation))
+		g.Expect(tracked.GetLabels()).To(HaveKey(argocd.InstanceLabel))
+	})
+
+	t.Run("strips the tracking after the grace period", func(t *testing.T) {
+		g := NewWithT(t)
+		resultK.Spec.ArgoCDMigration.StripTrackingAfter = &metav1.Duration{Duration: time.Millisecond}
+		g.Expect(k8sClient.Update(context.Background(), resultK)).To(Succeed())
+
+		g.Eventually(func() bool {
+			_ = k8sClient.Get(context.Background(), client.ObjectKeyFromObject(kustomization), resultK)
+			return isReconcileSuccess(resultK) && resultK.Status.ObservedGeneration == resultK.Generation
+		}, timeout, time.Second).Should(BeTrue())
+		g.Expect(resultK.Status.ArgoCDMigration.Tracked).To(BeZero())
+
+		g.Expect(k8sClient.Get(context.Background(), client.ObjectKeyFromObject(tracked), tracked)).To(Succeed())
+		g.Expect(tracked.GetAnnotations()).ToNot(HaveKey(argocd.TrackingIDAnnotation))
+		g.Expect(tracked.GetAnnotations()).ToNot(HaveKey(corev1.LastAppliedConfigAnnotation))
+		g.Expect(tracked.GetLabels()).ToNot(HaveKey(argocd.InstanceLabel))
+	})
+}
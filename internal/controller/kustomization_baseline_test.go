@@ -0,0 +1,156 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/fluxcd/pkg/testserver"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+)
+
+func TestKustomizationReconciler_NamespaceBaseline(t *testing.T) {
+	g := NewWithT(t)
+	id := "bl-" + randStringRunes(5)
+	tenant := "bl-tenant-" + randStringRunes(5)
+	revision := "v1.0.0"
+	resultK := &kustomizev1.Kustomization{}
+
+	err := createNamespace(id)
+	g.Expect(err).NotTo(HaveOccurred(), "failed to create test namespace")
+
+	err = createKubeConfigSecret(id)
+	g.Expect(err).NotTo(HaveOccurred(), "failed to create kubeconfig secret")
+
+	templates := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "baseline", Namespace: id},
+		Data: map[string]string{
+			"quota.yaml": `apiVersion: v1
+kind: ResourceQuota
+metadata:
+  name: baseline
+  labels:
+    tenant: ${namespace}
+spec:
+  hard:
+    pods: "20"
+`,
+		},
+	}
+	g.Expect(k8sClient.Create(context.Background(), templates)).To(Succeed())
+
+	reconciler.NamespaceBaseline = client.ObjectKeyFromObject(templates)
+	reconciler.baselineClient = k8sClient
+	defer func() {
+		reconciler.NamespaceBaseline = types.NamespacedName{}
+		reconciler.baselineClient = nil
+	}()
+
+	artifact, err := testServer.ArtifactFromFiles([]testserver.File{
+		{
+			Name: "namespace.yaml",
+			Body: `apiVersion: v1
+kind: Namespace
+metadata:
+  name: tenant
+`,
+		},
+		{
+			Name: "config.yaml",
+			Body: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+data:
+  key: value
+`,
+		},
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	repositoryName := types.NamespacedName{
+		Name:      fmt.Sprintf("bl-%s", randStringRunes(5)),
+		Namespace: id,
+	}
+
+	err = applyGitRepository(repositoryName, artifact, revision)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	kustomization := &kustomizev1.Kustomization{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("bl-%s", randStringRunes(5)),
+			Namespace: id,
+		},
+		Spec: kustomizev1.KustomizationSpec{
+			Interval:        metav1.Duration{Duration: reconciliationInterval},
+			Path:            "./",
+			TargetNamespace: tenant,
+			Prune:           true,
+			KubeConfig: &meta.KubeConfigReference{
+				SecretRef: meta.SecretKeyReference{
+					Name: "kubeconfig",
+				},
+			},
+			SourceRef: kustomizev1.CrossNamespaceSourceReference{
+				Name:      repositoryName.Name,
+				Namespace: repositoryName.Namespace,
+				Kind:      sourcev1.GitRepositoryKind,
+			},
+		},
+	}
+
+	t.Run("skips the remote clusters", func(t *testing.T) {
+		g := NewWithT(t)
+		g.Expect(k8sClient.Create(context.Background(), kustomization)).To(Succeed())
+
+		g.Eventually(func() bool {
+			_ = k8sClient.Get(context.Background(), client.ObjectKeyFromObject(kustomization), resultK)
+			return isReconcileSuccess(resultK)
+		}, timeout, time.Second).Should(BeTrue())
+		g.Expect(k8sClient.Get(context.Background(), client.ObjectKey{Name: "baseline", Namespace: tenant},
+			&corev1.ResourceQuota{})).ToNot(Succeed())
+	})
+
+	t.Run("stamps the baseline into the target namespace", func(t *testing.T) {
+		g := NewWithT(t)
+		resultK.Spec.KubeConfig = nil
+		g.Expect(k8sClient.Update(context.Background(), resultK)).To(Succeed())
+
+		quota := &corev1.ResourceQuota{}
+		g.Eventually(func() error {
+			return k8sClient.Get(context.Background(), client.ObjectKey{Name: "baseline", Namespace: tenant}, quota)
+		}, timeout, time.Second).Should(Succeed())
+		g.Expect(quota.GetLabels()).To(HaveKeyWithValue("tenant", tenant))
+
+		g.Eventually(func() bool {
+			_ = k8sClient.Get(context.Background(), client.ObjectKeyFromObject(kustomization), resultK)
+			return isReconcileSuccess(resultK) && resultK.Status.ObservedGeneration == resultK.Generation
+		}, timeout, time.Second).Should(BeTrue())
+		g.Expect(resultK.Status.Inventory.Entries).To(HaveLen(2))
+	})
+}
@@ -0,0 +1,138 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/fluxcd/pkg/testserver"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	. "github.com/onsi/gomega"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+	"github.com/fluxcd/kustomize-controller/internal/buildartifacts"
+	"github.com/fluxcd/kustomize-controller/internal/metricsauth"
+)
+
+func TestKustomizationReconciler_BuildErrorArtifacts(t *testing.T) {
+	g := NewWithT(t)
+	id := "ba-" + randStringRunes(5)
+	revision := "v1.0.0"
+
+	store := buildartifacts.NewStore(time.Minute)
+	reconciler.BuildErrorArtifacts = store
+	defer func() { reconciler.BuildErrorArtifacts = nil }()
+
+	err := createNamespace(id)
+	g.Expect(err).NotTo(HaveOccurred(), "failed to create test namespace")
+
+	err = createKubeConfigSecret(id)
+	g.Expect(err).NotTo(HaveOccurred(), "failed to create kubeconfig secret")
+
+	artifact, err := testServer.ArtifactFromFiles([]testserver.File{
+		{
+			Name: "kustomization.yaml",
+			Body: `apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+resources:
+- missing.yaml
+`,
+		},
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	repositoryName := types.NamespacedName{
+		Name:      fmt.Sprintf("ba-%s", randStringRunes(5)),
+		Namespace: id,
+	}
+
+	err = applyGitRepository(repositoryName, artifact, revision)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	kustomization := &kustomizev1.Kustomization{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("ba-%s", randStringRunes(5)),
+			Namespace: id,
+		},
+		Spec: kustomizev1.KustomizationSpec{
+			Interval:        metav1.Duration{Duration: reconciliationInterval},
+			Path:            "./",
+			TargetNamespace: id,
+			KubeConfig: &meta.KubeConfigReference{
+				SecretRef: meta.SecretKeyReference{
+					Name: "kubeconfig",
+				},
+			},
+			SourceRef: kustomizev1.CrossNamespaceSourceReference{
+				Name:      repositoryName.Name,
+				Namespace: repositoryName.Namespace,
+				Kind:      sourcev1.GitRepositoryKind,
+			},
+		},
+	}
+
+	g.Expect(k8sClient.Create(context.Background(), kustomization)).To(Succeed())
+
+	annotation := kustomizev1.GroupVersion.Group + "/build-artifact"
+	var path string
+	g.Eventually(func() bool {
+		for _, event := range getEvents(kustomization.GetName(), nil) {
+			if p := event.GetAnnotations()[annotation]; p != "" {
+				g.Expect(event.Reason).To(Equal(kustomizev1.BuildFailedReason))
+				path = p
+				return true
+			}
+		}
+		return false
+	}, timeout, time.Second).Should(BeTrue())
+
+	// The reviews allow any token to get the Secrets of the namespace.
+	reviewClient := fake.NewClientBuilder().WithInterceptorFuncs(interceptor.Funcs{
+		Create: func(_ context.Context, _ client.WithWatch, obj client.Object, _ ...client.CreateOption) error {
+			switch review := obj.(type) {
+			case *authenticationv1.TokenReview:
+				review.Status.Authenticated = true
+			case *authorizationv1.SubjectAccessReview:
+				review.Status.Allowed = review.Spec.ResourceAttributes.Namespace == id
+			}
+			return nil
+		},
+	}).Build()
+	server := httptest.NewServer(store.Handler(&metricsauth.Options{Client: reviewClient}))
+	defer server.Close()
+	req, err := http.NewRequest(http.MethodGet, server.URL+path, nil)
+	g.Expect(err).ToNot(HaveOccurred())
+	req.Header.Set("Authorization", "Bearer token")
+	resp, err := http.DefaultClient.Do(req)
+	g.Expect(err).ToNot(HaveOccurred())
+	defer resp.Body.Close()
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	g.Expect(resp.Header.Get("Content-Type")).To(Equal("application/gzip"))
+}
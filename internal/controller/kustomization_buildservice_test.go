@@ -0,0 +1,169 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"testing"
+	"time"
+
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+	"github.com/fluxcd/kustomize-controller/internal/buildservice"
+)
+
+func TestKustomizationReconciler_BuildService(t *testing.T) {
+	g := NewWithT(t)
+	id := "bs-" + randStringRunes(5)
+
+	err := createNamespace(id)
+	g.Expect(err).NotTo(HaveOccurred(), "failed to create test namespace")
+
+	kustomization := &kustomizev1.Kustomization{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "app",
+			Namespace: id,
+		},
+		Spec: kustomizev1.KustomizationSpec{
+			Interval:        metav1.Duration{Duration: time.Hour},
+			Path:            "./deploy",
+			Suspend:         true,
+			TargetNamespace: id,
+			SourceRef: kustomizev1.CrossNamespaceSourceReference{
+				Name: "missing",
+				Kind: sourcev1.GitRepositoryKind,
+			},
+			PostBuild: &kustomizev1.PostBuild{
+				Substitute: map[string]string{"greeting": "hello"},
+			},
+		},
+	}
+	g.Expect(k8sClient.Create(context.Background(), kustomization)).To(Succeed())
+
+	archive := func(files map[string]string) *bytes.Buffer {
+		var buf bytes.Buffer
+		gw := gzip.NewWriter(&buf)
+		tw := tar.NewWriter(gw)
+		for name, data := range files {
+			g.Expect(tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(data))})).To(Succeed())
+			_, err := tw.Write([]byte(data))
+			g.Expect(err).NotTo(HaveOccurred())
+		}
+		g.Expect(tw.Close()).To(Succeed())
+		g.Expect(gw.Close()).To(Succeed())
+		return &buf
+	}
+	files := map[string]string{
+		"deploy/configmap.yaml": `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+data:
+  message: "${greeting}"
+`,
+	}
+
+	key := types.NamespacedName{Name: "app", Namespace: id}
+	g.Eventually(func() error {
+		_, err := reconciler.buildSource(context.Background(), buildservice.Request{
+			Kustomization: key,
+			Archive:       archive(files),
+		})
+		return err
+	}, timeout, time.Second).Should(Succeed())
+
+	objects, err := reconciler.buildSource(context.Background(), buildservice.Request{
+		Kustomization: key,
+		Archive:       archive(files),
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(objects).To(HaveLen(1))
+	g.Expect(objects[0].GetNamespace()).To(Equal(id))
+	g.Expect(objects[0].GetLabels()).To(HaveKeyWithValue("kustomize.toolkit.fluxcd.io/name", "app"))
+	g.Expect(objects[0].Object["data"]).To(HaveKeyWithValue("message", "hello"))
+
+	t.Run("path override", func(t *testing.T) {
+		g := NewWithT(t)
+		_, err := reconciler.buildSource(context.Background(), buildservice.Request{
+			Kustomization: key,
+			Path:          "./missing",
+			Archive:       archive(files),
+		})
+		g.Expect(err).To(HaveOccurred())
+		g.Expect(err.Error()).To(ContainSubstring("kustomization path not found"))
+	})
+
+	t.Run("without remote references", func(t *testing.T) {
+		g := NewWithT(t)
+		_, err := reconciler.buildSource(context.Background(), buildservice.Request{
+			Kustomization: key,
+			Archive: archive(map[string]string{
+				"deploy/kustomization.yaml": `apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+resources:
+- http://169.254.169.254/latest/meta-data/deploy.yaml
+`,
+			}),
+		})
+		g.Expect(err).To(HaveOccurred())
+		g.Expect(err.Error()).To(ContainSubstring("remote references are not allowed"))
+	})
+
+	t.Run("without decryption", func(t *testing.T) {
+		g := NewWithT(t)
+		encrypted := kustomization.DeepCopy()
+		encrypted.ObjectMeta = metav1.ObjectMeta{Name: "encrypted", Namespace: id}
+		encrypted.Spec.Decryption = &kustomizev1.Decryption{Provider: "sops"}
+		g.Expect(k8sClient.Create(context.Background(), encrypted)).To(Succeed())
+
+		ciphertext := "ENC[AES256_GCM,data:ZGF0YQ==,iv:aXY=,tag:dGFn,type:str]"
+		objects, err := reconciler.buildSource(context.Background(), buildservice.Request{
+			Kustomization: types.NamespacedName{Name: "encrypted", Namespace: id},
+			Archive: archive(map[string]string{
+				"deploy/secret.yaml": `apiVersion: v1
+kind: Secret
+metadata:
+  name: token
+stringData:
+  token: "` + ciphertext + `"
+sops:
+  mac: "` + ciphertext + `"
+  version: 3.8.1
+`,
+			}),
+		})
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(objects).To(HaveLen(1))
+		g.Expect(objects[0].Object["stringData"]).To(HaveKeyWithValue("token", ciphertext))
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		g := NewWithT(t)
+		_, err := reconciler.buildSource(context.Background(), buildservice.Request{
+			Kustomization: types.NamespacedName{Name: "missing", Namespace: id},
+			Archive:       archive(files),
+		})
+		g.Expect(err).To(MatchError(buildservice.ErrNotFound))
+	})
+}
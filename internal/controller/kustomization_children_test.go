@@ -0,0 +1,142 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/fluxcd/pkg/runtime/conditions"
+	"github.com/fluxcd/pkg/testserver"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+)
+
+func TestKustomizationReconciler_AggregateChildren(t *testing.T) {
+	g := NewWithT(t)
+	id := "children-" + randStringRunes(5)
+	revision := "v1.0.0"
+	resultK := &kustomizev1.Kustomization{}
+
+	err := createNamespace(id)
+	g.Expect(err).NotTo(HaveOccurred(), "failed to create test namespace")
+
+	childRepositoryName := types.NamespacedName{
+		Name:      fmt.Sprintf("child-%s", randStringRunes(5)),
+		Namespace: id,
+	}
+
+	artifact, err := testServer.ArtifactFromFiles([]testserver.File{
+		{
+			Name: "child.yaml",
+			Body: fmt.Sprintf(`apiVersion: kustomize.toolkit.fluxcd.io/v1
+kind: Kustomization
+metadata:
+  name: child
+spec:
+  interval: 1m
+  path: ./
+  prune: true
+  targetNamespace: %[1]s
+  sourceRef:
+    kind: GitRepository
+    name: %[2]s
+    namespace: %[1]s
+`, id, childRepositoryName.Name),
+		},
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	repositoryName := types.NamespacedName{
+		Name:      fmt.Sprintf("parent-%s", randStringRunes(5)),
+		Namespace: id,
+	}
+
+	err = applyGitRepository(repositoryName, artifact, revision)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	kustomization := &kustomizev1.Kustomization{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("parent-%s", randStringRunes(5)),
+			Namespace: id,
+		},
+		Spec: kustomizev1.KustomizationSpec{
+			Interval:        metav1.Duration{Duration: reconciliationInterval},
+			Path:            "./",
+			TargetNamespace: id,
+			Prune:           true,
+			SourceRef: kustomizev1.CrossNamespaceSourceReference{
+				Name:      repositoryName.Name,
+				Namespace: repositoryName.Namespace,
+				Kind:      sourcev1.GitRepositoryKind,
+			},
+			AggregateChildren: true,
+		},
+	}
+
+	g.Expect(k8sClient.Create(context.Background(), kustomization)).To(Succeed())
+
+	t.Run("reports the children which are not ready", func(t *testing.T) {
+		g := NewWithT(t)
+		g.Eventually(func() bool {
+			_ = k8sClient.Get(context.Background(), client.ObjectKeyFromObject(kustomization), resultK)
+			return conditions.GetReason(resultK, meta.ReadyCondition) == kustomizev1.ChildrenNotReadyReason
+		}, timeout, time.Second).Should(BeTrue())
+
+		g.Expect(conditions.IsFalse(resultK, kustomizev1.ChildrenReadyCondition)).To(BeTrue())
+		g.Expect(conditions.GetMessage(resultK, kustomizev1.ChildrenReadyCondition)).To(
+			ContainSubstring(fmt.Sprintf("%s/child", id)))
+		g.Expect(resultK.Status.Children).To(HaveLen(1))
+		g.Expect(resultK.Status.Children[0].Ready).To(BeFalse())
+	})
+
+	t.Run("becomes ready with its children", func(t *testing.T) {
+		g := NewWithT(t)
+		childArtifact, err := testServer.ArtifactFromFiles([]testserver.File{
+			{
+				Name: "config.yaml",
+				Body: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+data:
+  key: value
+`,
+			},
+		})
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(applyGitRepository(childRepositoryName, childArtifact, revision)).To(Succeed())
+
+		g.Eventually(func() bool {
+			_ = k8sClient.Get(context.Background(), client.ObjectKeyFromObject(kustomization), resultK)
+			return conditions.IsReady(resultK)
+		}, timeout, time.Second).Should(BeTrue())
+
+		g.Expect(conditions.IsTrue(resultK, kustomizev1.ChildrenReadyCondition)).To(BeTrue())
+		g.Expect(resultK.Status.Children).To(HaveLen(1))
+		g.Expect(resultK.Status.Children[0].Ready).To(BeTrue())
+		g.Expect(resultK.Status.Children[0].Revision).To(Equal(revision))
+	})
+}
@@ -0,0 +1,168 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/fluxcd/pkg/runtime/conditions"
+	"github.com/fluxcd/pkg/ssa"
+	"github.com/fluxcd/pkg/testserver"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+)
+
+func TestKustomizationReconciler_ApplyChunks(t *testing.T) {
+	g := NewWithT(t)
+	id := "chunk-" + randStringRunes(5)
+	revision := "v1.0.0"
+	resultK := &kustomizev1.Kustomization{}
+
+	reconciler.ApplyChunkSize = 2
+	defer func() { reconciler.ApplyChunkSize = 0 }()
+
+	err := createNamespace(id)
+	g.Expect(err).NotTo(HaveOccurred(), "failed to create test namespace")
+
+	err = createKubeConfigSecret(id)
+	g.Expect(err).NotTo(HaveOccurred(), "failed to create kubeconfig secret")
+
+	var manifests strings.Builder
+	for i := 0; i < 5; i++ {
+		fmt.Fprintf(&manifests, `---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config-%d
+data:
+  key: value
+`, i)
+	}
+	artifact, err := testServer.ArtifactFromFiles([]testserver.File{
+		{
+			Name: "configmaps.yaml",
+			Body: manifests.String(),
+		},
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	repositoryName := types.NamespacedName{
+		Name:      fmt.Sprintf("chunk-%s", randStringRunes(5)),
+		Namespace: id,
+	}
+
+	err = applyGitRepository(repositoryName, artifact, revision)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	kustomization := &kustomizev1.Kustomization{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("chunk-%s", randStringRunes(5)),
+			Namespace: id,
+		},
+		Spec: kustomizev1.KustomizationSpec{
+			Interval:        metav1.Duration{Duration: reconciliationInterval},
+			Path:            "./",
+			TargetNamespace: id,
+			Prune:           true,
+			KubeConfig: &meta.KubeConfigReference{
+				SecretRef: meta.SecretKeyReference{
+					Name: "kubeconfig",
+				},
+			},
+			SourceRef: kustomizev1.CrossNamespaceSourceReference{
+				Name:      repositoryName.Name,
+				Namespace: repositoryName.Namespace,
+				Kind:      sourcev1.GitRepositoryKind,
+			},
+		},
+	}
+
+	g.Expect(k8sClient.Create(context.Background(), kustomization)).To(Succeed())
+
+	g.Eventually(func() bool {
+		_ = k8sClient.Get(context.Background(), client.ObjectKeyFromObject(kustomization), resultK)
+		return isReconcileSuccess(resultK) && resultK.Status.LastAppliedRevision == revision
+	}, timeout, time.Second).Should(BeTrue())
+
+	g.Expect(resultK.Status.ApplyProgress).To(BeNil())
+	g.Expect(resultK.Status.Inventory.Entries).To(HaveLen(5))
+	for i := 0; i < 5; i++ {
+		key := types.NamespacedName{Name: fmt.Sprintf("config-%d", i), Namespace: id}
+		g.Expect(k8sClient.Get(context.Background(), key, &corev1.ConfigMap{})).To(Succeed())
+	}
+
+}
+
+func TestKustomizationReconciler_nextApplyChunk(t *testing.T) {
+	g := NewWithT(t)
+	r := &KustomizationReconciler{ApplyChunkSize: 2}
+	obj := &kustomizev1.Kustomization{}
+	resources := []byte("manifests")
+
+	var objects []*unstructured.Unstructured
+	for _, kind := range []string{"ConfigMap", "Namespace", "Secret", "ConfigMap", "Service"} {
+		u := &unstructured.Unstructured{}
+		u.SetAPIVersion("v1")
+		u.SetKind(kind)
+		u.SetName(fmt.Sprintf("%s-%d", strings.ToLower(kind), len(objects)))
+		objects = append(objects, u)
+	}
+
+	chunk, applied := r.nextApplyChunk(obj, "v1", resources, objects)
+	g.Expect(applied).To(Equal(0))
+	g.Expect(chunk).To(HaveLen(2))
+	g.Expect(chunk[0].GetKind()).To(Equal("Namespace"))
+
+	changeSet := ssa.NewChangeSet()
+	addUnchanged(changeSet, chunk)
+	err := r.recordApplyProgress(obj, "v1", resources, 2, len(objects), changeSet, nil)
+	g.Expect(err).To(MatchError(errApplyInProgress))
+	g.Expect(obj.Status.Inventory.Entries).To(HaveLen(2))
+	g.Expect(conditions.IsUnknown(obj, meta.ReadyCondition)).To(BeTrue())
+
+	chunk, applied = r.nextApplyChunk(obj, "v1", resources, objects)
+	g.Expect(applied).To(Equal(2))
+	g.Expect(chunk).To(HaveLen(2))
+
+	// The progress is discarded when the manifests change.
+	chunk, applied = r.nextApplyChunk(obj, "v1", []byte("changed"), objects)
+	g.Expect(applied).To(Equal(0))
+	g.Expect(chunk).To(HaveLen(2))
+
+	obj.Status.ApplyProgress.Applied = 4
+	chunk, applied = r.nextApplyChunk(obj, "v1", resources, objects)
+	g.Expect(applied).To(Equal(4))
+	g.Expect(chunk).To(HaveLen(1))
+
+	// The objects are applied at once below the chunk size.
+	chunk, applied = r.nextApplyChunk(obj, "v1", resources, objects[:2])
+	g.Expect(applied).To(Equal(0))
+	g.Expect(chunk).To(HaveLen(2))
+	g.Expect(obj.Status.ApplyProgress).To(BeNil())
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/fluxcd/pkg/runtime/conditions"
+	"github.com/fluxcd/pkg/testserver"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+)
+
+func TestKustomizationReconciler_TenantClusterScoped(t *testing.T) {
+	g := NewWithT(t)
+	id := "cs-" + randStringRunes(5)
+	revision := "v1.0.0"
+
+	err := createNamespace(id)
+	g.Expect(err).NotTo(HaveOccurred(), "failed to create test namespace")
+
+	reconciler.TenantDenyClusterScoped = true
+	reconciler.TenantClusterScopedKinds = []string{"Namespace"}
+	defer func() {
+		reconciler.TenantDenyClusterScoped = false
+		reconciler.TenantClusterScopedKinds = nil
+	}()
+
+	artifact, err := testServer.ArtifactFromFiles([]testserver.File{
+		{
+			Name: "manifests.yaml",
+			Body: fmt.Sprintf(`---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: %[1]s-apps
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: %[1]s-admin
+rules:
+- apiGroups: ["*"]
+  resources: ["*"]
+  verbs: ["*"]
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+`, id),
+		},
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	repositoryName := types.NamespacedName{
+		Name:      fmt.Sprintf("cs-%s", randStringRunes(5)),
+		Namespace: id,
+	}
+	err = applyGitRepository(repositoryName, artifact, revision)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	kustomization := &kustomizev1.Kustomization{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("cs-%s", randStringRunes(5)),
+			Namespace: id,
+		},
+		Spec: kustomizev1.KustomizationSpec{
+			Interval: metav1.Duration{Duration: reconciliationInterval},
+			Path:     "./",
+			SourceRef: kustomizev1.CrossNamespaceSourceReference{
+				Name:      repositoryName.Name,
+				Namespace: repositoryName.Namespace,
+				Kind:      sourcev1.GitRepositoryKind,
+			},
+			ServiceAccountName: "tenant",
+			TargetNamespace:    id,
+			Timeout:            &metav1.Duration{Duration: 10 * time.Second},
+		},
+	}
+	g.Expect(k8sClient.Create(context.Background(), kustomization)).To(Succeed())
+
+	resultK := &kustomizev1.Kustomization{}
+	g.Eventually(func() bool {
+		_ = k8sClient.Get(context.Background(), client.ObjectKeyFromObject(kustomization), resultK)
+		return conditions.GetReason(resultK, meta.ReadyCondition) == kustomizev1.TenancyViolationReason
+	}, timeout, time.Second).Should(BeTrue())
+	logStatus(t, resultK)
+
+	g.Expect(resultK.Status.ClusterScopedResources).To(Equal([]string{
+		fmt.Sprintf("ClusterRole/%s-admin", id),
+		fmt.Sprintf("Namespace/%s-apps", id),
+	}))
+	g.Expect(conditions.GetMessage(resultK, meta.ReadyCondition)).To(ContainSubstring(fmt.Sprintf("ClusterRole/%s-admin", id)))
+	g.Expect(conditions.GetMessage(resultK, meta.ReadyCondition)).NotTo(ContainSubstring("Namespace/"))
+	g.Expect(conditions.IsStalled(resultK)).To(BeFalse())
+}
@@ -0,0 +1,181 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/fluxcd/pkg/testserver"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+)
+
+func TestKustomizationReconciler_ComponentToggles(t *testing.T) {
+	g := NewWithT(t)
+	id := "ct-" + randStringRunes(5)
+	revision := "v1.0.0"
+	resultK := &kustomizev1.Kustomization{}
+
+	err := createNamespace(id)
+	g.Expect(err).NotTo(HaveOccurred(), "failed to create test namespace")
+
+	err = createKubeConfigSecret(id)
+	g.Expect(err).NotTo(HaveOccurred(), "failed to create kubeconfig secret")
+
+	component := func(name string) []testserver.File {
+		return []testserver.File{
+			{
+				Name: fmt.Sprintf("components/%s/kustomization.yaml", name),
+				Body: `apiVersion: kustomize.config.k8s.io/v1alpha1
+kind: Component
+resources:
+- config.yaml
+`,
+			},
+			{
+				Name: fmt.Sprintf("components/%s/config.yaml", name),
+				Body: fmt.Sprintf(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: %s
+data:
+  key: val
+`, name),
+			},
+		}
+	}
+	files := []testserver.File{
+		{
+			Name: "app/kustomization.yaml",
+			Body: `apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+resources:
+- config.yaml
+`,
+		},
+		{
+			Name: "app/config.yaml",
+			Body: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app
+data:
+  key: val
+`,
+		},
+	}
+	files = append(files, component("monitoring")...)
+	files = append(files, component("debug")...)
+
+	artifact, err := testServer.ArtifactFromFiles(files)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	repositoryName := types.NamespacedName{
+		Name:      fmt.Sprintf("ct-%s", randStringRunes(5)),
+		Namespace: id,
+	}
+
+	err = applyGitRepository(repositoryName, artifact, revision)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	disabled := false
+	kustomization := &kustomizev1.Kustomization{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("ct-%s", randStringRunes(5)),
+			Namespace: id,
+		},
+		Spec: kustomizev1.KustomizationSpec{
+			Interval:        metav1.Duration{Duration: reconciliationInterval},
+			Path:            "./app",
+			Prune:           true,
+			TargetNamespace: id,
+			KubeConfig: &meta.KubeConfigReference{
+				SecretRef: meta.SecretKeyReference{
+					Name: "kubeconfig",
+				},
+			},
+			SourceRef: kustomizev1.CrossNamespaceSourceReference{
+				Name:      repositoryName.Name,
+				Namespace: repositoryName.Namespace,
+				Kind:      sourcev1.GitRepositoryKind,
+			},
+			ComponentToggles: []kustomizev1.ComponentToggle{
+				{Path: "../components/monitoring", EnabledBy: "MONITORING"},
+				{Path: "../components/debug", Enabled: &disabled},
+			},
+			PostBuild: &kustomizev1.PostBuild{
+				Substitute: map[string]string{"MONITORING": "true"},
+			},
+		},
+	}
+
+	g.Expect(k8sClient.Create(context.Background(), kustomization)).To(Succeed())
+
+	isReconciled := func() bool {
+		_ = k8sClient.Get(context.Background(), client.ObjectKeyFromObject(kustomization), resultK)
+		return isReconcileSuccess(resultK) && resultK.Status.ObservedGeneration == resultK.Generation
+	}
+	configMapExists := func(name string) func() bool {
+		return func() bool {
+			err := k8sClient.Get(context.Background(), client.ObjectKey{Name: name, Namespace: id}, &corev1.ConfigMap{})
+			return !apierrors.IsNotFound(err)
+		}
+	}
+
+	t.Run("appends the enabled components", func(t *testing.T) {
+		g := NewWithT(t)
+		g.Eventually(isReconciled, timeout, time.Second).Should(BeTrue())
+
+		g.Expect(configMapExists("app")()).To(BeTrue())
+		g.Expect(configMapExists("monitoring")()).To(BeTrue())
+		g.Expect(configMapExists("debug")()).To(BeFalse())
+	})
+
+	t.Run("removes the components disabled by variable", func(t *testing.T) {
+		g := NewWithT(t)
+		resultK.Spec.PostBuild.Substitute["MONITORING"] = "false"
+		g.Expect(k8sClient.Update(context.Background(), resultK)).To(Succeed())
+
+		g.Eventually(isReconciled, timeout, time.Second).Should(BeTrue())
+		g.Eventually(configMapExists("monitoring"), timeout, time.Second).Should(BeFalse())
+		g.Expect(configMapExists("app")()).To(BeTrue())
+	})
+
+	t.Run("fails on a non-boolean variable", func(t *testing.T) {
+		g := NewWithT(t)
+		resultK.Spec.PostBuild.Substitute["MONITORING"] = "maybe"
+		g.Expect(k8sClient.Update(context.Background(), resultK)).To(Succeed())
+
+		g.Eventually(func() bool {
+			_ = k8sClient.Get(context.Background(), client.ObjectKeyFromObject(kustomization), resultK)
+			ready := apimeta.FindStatusCondition(resultK.Status.Conditions, meta.ReadyCondition)
+			return ready != nil && ready.Reason == kustomizev1.BuildFailedReason
+		}, timeout, time.Second).Should(BeTrue())
+	})
+}
@@ -19,34 +19,56 @@ package controller
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"errors"
 	"fmt"
+	"maps"
 	"os"
+	"path/filepath"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	securejoin "github.com/cyphar/filepath-securejoin"
 	ssautil "github.com/fluxcd/pkg/ssa/utils"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	apimeta "k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	kerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/metadata"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
 	kuberecorder "k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/retry"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/ratelimiter"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+	"sigs.k8s.io/kustomize/api/krusty"
+	kustypes "sigs.k8s.io/kustomize/api/types"
+	"sigs.k8s.io/yaml"
 
 	"github.com/fluxcd/cli-utils/pkg/kstatus/polling"
+	"github.com/fluxcd/cli-utils/pkg/kstatus/watcher"
 	"github.com/fluxcd/cli-utils/pkg/object"
 	apiacl "github.com/fluxcd/pkg/apis/acl"
 	eventv1 "github.com/fluxcd/pkg/apis/event/v1beta1"
@@ -66,17 +88,138 @@ import (
 	sourcev1b2 "github.com/fluxcd/source-controller/api/v1beta2"
 
 	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+	"github.com/fluxcd/kustomize-controller/internal/applytimeout"
+	"github.com/fluxcd/kustomize-controller/internal/argocd"
+	"github.com/fluxcd/kustomize-controller/internal/baseline"
+	"github.com/fluxcd/kustomize-controller/internal/build"
+	"github.com/fluxcd/kustomize-controller/internal/buildartifacts"
+	"github.com/fluxcd/kustomize-controller/internal/buildignore"
+	"github.com/fluxcd/kustomize-controller/internal/buildservice"
+	"github.com/fluxcd/kustomize-controller/internal/buildusage"
+	"github.com/fluxcd/kustomize-controller/internal/buildwarnings"
+	"github.com/fluxcd/kustomize-controller/internal/capabilities"
+	"github.com/fluxcd/kustomize-controller/internal/certpin"
+	"github.com/fluxcd/kustomize-controller/internal/commitinfo"
+	"github.com/fluxcd/kustomize-controller/internal/configchecksum"
+	"github.com/fluxcd/kustomize-controller/internal/crdconversion"
+	"github.com/fluxcd/kustomize-controller/internal/credexpiry"
+	"github.com/fluxcd/kustomize-controller/internal/csa"
 	"github.com/fluxcd/kustomize-controller/internal/decryptor"
+	"github.com/fluxcd/kustomize-controller/internal/diagnostics"
+	"github.com/fluxcd/kustomize-controller/internal/driftignore"
+	"github.com/fluxcd/kustomize-controller/internal/dryrun"
+	"github.com/fluxcd/kustomize-controller/internal/dryruncache"
+	"github.com/fluxcd/kustomize-controller/internal/effectiveconfig"
+	"github.com/fluxcd/kustomize-controller/internal/environment"
+	"github.com/fluxcd/kustomize-controller/internal/fanout"
+	"github.com/fluxcd/kustomize-controller/internal/faultinjection"
+	"github.com/fluxcd/kustomize-controller/internal/features"
+	"github.com/fluxcd/kustomize-controller/internal/fieldvalidation"
+	"github.com/fluxcd/kustomize-controller/internal/filediff"
+	"github.com/fluxcd/kustomize-controller/internal/flapping"
+	"github.com/fluxcd/kustomize-controller/internal/healthprogress"
+	"github.com/fluxcd/kustomize-controller/internal/healthwatch"
+	"github.com/fluxcd/kustomize-controller/internal/images"
+	"github.com/fluxcd/kustomize-controller/internal/imagescan"
+	"github.com/fluxcd/kustomize-controller/internal/imageverify"
+	"github.com/fluxcd/kustomize-controller/internal/immutableconfig"
+	"github.com/fluxcd/kustomize-controller/internal/integrity"
 	"github.com/fluxcd/kustomize-controller/internal/inventory"
+	"github.com/fluxcd/kustomize-controller/internal/leadtime"
+	"github.com/fluxcd/kustomize-controller/internal/loglevel"
+	"github.com/fluxcd/kustomize-controller/internal/manifeststream"
+	"github.com/fluxcd/kustomize-controller/internal/mutationrules"
+	"github.com/fluxcd/kustomize-controller/internal/nsreport"
+	"github.com/fluxcd/kustomize-controller/internal/openapi"
+	"github.com/fluxcd/kustomize-controller/internal/owners"
+	"github.com/fluxcd/kustomize-controller/internal/priority"
+	"github.com/fluxcd/kustomize-controller/internal/quota"
+	"github.com/fluxcd/kustomize-controller/internal/reconciliationgate"
+	"github.com/fluxcd/kustomize-controller/internal/refwatch"
+	"github.com/fluxcd/kustomize-controller/internal/remotecluster"
+	"github.com/fluxcd/kustomize-controller/internal/replicas"
+	"github.com/fluxcd/kustomize-controller/internal/revisionrate"
+	"github.com/fluxcd/kustomize-controller/internal/scope"
+	"github.com/fluxcd/kustomize-controller/internal/secretstore"
+	"github.com/fluxcd/kustomize-controller/internal/simulation"
+	"github.com/fluxcd/kustomize-controller/internal/smoketest"
+	intazkv "github.com/fluxcd/kustomize-controller/internal/sops/azkv"
+	"github.com/fluxcd/kustomize-controller/internal/speccheck"
+	"github.com/fluxcd/kustomize-controller/internal/statusdetails"
+	"github.com/fluxcd/kustomize-controller/internal/subresources"
+	"github.com/fluxcd/kustomize-controller/internal/substitution"
+	"github.com/fluxcd/kustomize-controller/internal/throttle"
+	"github.com/fluxcd/kustomize-controller/internal/validationrules"
+	"github.com/fluxcd/kustomize-controller/internal/webhookbreaker"
+	"github.com/fluxcd/kustomize-controller/internal/webhookwatch"
+	"github.com/fluxcd/kustomize-controller/internal/workdir"
 )
 
 // +kubebuilder:rbac:groups=kustomize.toolkit.fluxcd.io,resources=kustomizations,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=kustomize.toolkit.fluxcd.io,resources=kustomizations/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=kustomize.toolkit.fluxcd.io,resources=kustomizations/finalizers,verbs=get;create;update;patch;delete
+// +kubebuilder:rbac:groups=kustomize.toolkit.fluxcd.io,resources=kustomizationreports,verbs=get;list;watch;create;update;delete
+// +kubebuilder:rbac:groups=kustomize.toolkit.fluxcd.io,resources=reconciliationgates,verbs=get;list;watch
 // +kubebuilder:rbac:groups=source.toolkit.fluxcd.io,resources=buckets;ocirepositories;gitrepositories,verbs=get;list;watch
 // +kubebuilder:rbac:groups=source.toolkit.fluxcd.io,resources=buckets/status;ocirepositories/status;gitrepositories/status,verbs=get
 // +kubebuilder:rbac:groups="",resources=configmaps;secrets;serviceaccounts,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=create;update;delete
 // +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get
+// +kubebuilder:rbac:groups="",resources=resourcequotas,verbs=list
+// +kubebuilder:rbac:groups=discovery.k8s.io,resources=endpointslices,verbs=get;list;watch
+// +kubebuilder:rbac:groups=authentication.k8s.io,resources=tokenreviews,verbs=create
+// +kubebuilder:rbac:groups=authorization.k8s.io,resources=subjectaccessreviews,verbs=create
+// +kubebuilder:rbac:groups=admissionregistration.k8s.io,resources=mutatingwebhookconfigurations;validatingwebhookconfigurations;validatingadmissionpolicies;validatingadmissionpolicybindings,verbs=list
+
+// openAPISchemaCacheTTL is the duration for which the OpenAPI schemas
+// fetched from the clusters are reused across builds.
+const openAPISchemaCacheTTL = 5 * time.Minute
+
+// clusterCapabilitiesTTL is the duration after which the capabilities of
+// the clusters are detected again, e.g. after an upgrade.
+const clusterCapabilitiesTTL = time.Hour
+
+// healthCheckProgressInterval is the minimum duration between the updates
+// of the Reconciling condition with the progress of the health checks.
+const healthCheckProgressInterval = 10 * time.Second
+
+// dependsOnIndexKey is the index of the Kustomizations by the
+// Kustomizations they depend on.
+const dependsOnIndexKey = ".spec.dependsOn"
+
+// childrenIndexKey is the index of the Kustomizations by the child
+// Kustomizations whose readiness they aggregate.
+const childrenIndexKey = ".status.children"
+
+// applyChunkRequeueDelay is the delay after which the reconciliation is
+// requeued to apply the next chunk of objects.
+const applyChunkRequeueDelay = time.Second
+
+// errApplyInProgress is returned by the reconciliation when it applied a
+// chunk of the objects, and the next ones are applied by the next one.
+var errApplyInProgress = errors.New("apply in progress")
+
+// buildArtifactError is a build error whose files are retained for
+// download at the given path.
+type buildArtifactError struct {
+	err  error
+	path string
+}
+
+func (e *buildArtifactError) Error() string {
+	return e.err.Error()
+}
+
+func (e *buildArtifactError) Unwrap() error {
+	return e.err
+}
+
+// fileSnapshot is the snapshot of the files of an applied revision.
+type fileSnapshot struct {
+	revision string
+	files    filediff.Snapshot
+}
 
 // KustomizationReconciler reconciles a Kustomization object
 type KustomizationReconciler struct {
@@ -86,18 +229,72 @@ type KustomizationReconciler struct {
 
 	artifactFetchRetries int
 	requeueDependency    time.Duration
-
-	StatusPoller            *polling.StatusPoller
-	PollingOpts             polling.Options
-	ControllerName          string
-	statusManager           string
-	NoCrossNamespaceRefs    bool
-	NoRemoteBases           bool
-	FailFast                bool
-	DefaultServiceAccount   string
-	KubeConfigOpts          runtimeClient.KubeConfigOptions
-	ConcurrentSSA           int
-	DisallowedFieldManagers []string
+	restConfig           *rest.Config
+	apiReader            client.Reader
+	baselineClient       client.Client
+	openAPISchemas       *openapi.ClusterCache
+	dryRunCache          *dryruncache.Cache
+	clusterCapabilities  *capabilities.Detector
+	healthWatches        *healthwatch.Manager
+	referenceWatches     *refwatch.Manager
+	webhookWatches       *webhookwatch.Manager
+	referenceEvents      chan event.GenericEvent
+	fanOut               *fanout.Tracker
+	startupGate          *priority.Gate
+	imageVerifier        *imageverify.Verifier
+	fileSnapshots        sync.Map
+	fileChanges          sync.Map
+
+	StatusPoller              *polling.StatusPoller
+	PollingOpts               polling.Options
+	ControllerName            string
+	statusManager             string
+	NoCrossNamespaceRefs      bool
+	NoRemoteBases             bool
+	AllowLoadRestrictionsNone bool
+	DisallowedBuildOptions    []string
+	FailFast                  bool
+	ContinuousHealthChecks    bool
+	WatchReferencedObjects    bool
+	ReapplyOnWebhookRecovery  bool
+	CheckResourceQuotas       bool
+	DetectOwnershipConflicts  bool
+	OwnerLookup               bool
+	ReconciliationGates       bool
+	NamespaceReports          bool
+	CacheSecretsAndConfigMaps bool
+	DefaultServiceAccount     string
+	DefaultTimeout            time.Duration
+	DefaultRetryInterval      time.Duration
+	DefaultCommonLabels       map[string]string
+	KubeConfigOpts            runtimeClient.KubeConfigOptions
+	ConcurrentSSA             int
+	DisallowedFieldManagers   []string
+	TenantLockdown            bool
+	TenantExemptNamespaces    []string
+	TenantDenyClusterScoped   bool
+	TenantClusterScopedKinds  []string
+	Throttle                  *throttle.Throttle
+	WebhookBreaker            *webhookbreaker.Breaker
+	WorkDirs                  *workdir.Janitor
+	Diagnostics               *diagnostics.Tracker
+	EffectiveConfig           *effectiveconfig.Options
+	FaultInjector             *faultinjection.Injector
+	SimulationConfig          *rest.Config
+	BuildService              *buildservice.Options
+	ConversionWebhookTimeout  time.Duration
+	StatusSizeLimit           int
+	ApplyChunkSize            int
+	OrderedFanOut             bool
+	ImageScanner              *imagescan.Scanner
+	BuildErrorArtifacts       *buildartifacts.Store
+	ManifestStreams           *manifeststream.Store
+	NamespaceBaseline         types.NamespacedName
+	MutationRules             types.NamespacedName
+	SopsAgeKeyDir             string
+	AzureKeyVaultOptions      intazkv.KeyVaultOptions
+	ConcurrentDecryption      int
+	DryRunCacheTTL            time.Duration
 }
 
 // KustomizationReconcilerOptions contains options for the KustomizationReconciler.
@@ -112,6 +309,9 @@ func (r *KustomizationReconciler) SetupWithManager(ctx context.Context, mgr ctrl
 		ociRepositoryIndexKey string = ".metadata.ociRepository"
 		gitRepositoryIndexKey string = ".metadata.gitRepository"
 		bucketIndexKey        string = ".metadata.bucket"
+		configMapIndexKey     string = ".spec.postBuild.substituteFrom.configMap"
+		secretIndexKey        string = ".spec.postBuild.substituteFrom.secret"
+		decryptionIndexKey    string = ".spec.decryption.secretRef"
 	)
 
 	// Index the Kustomizations by the OCIRepository references they (may) point at.
@@ -132,13 +332,121 @@ func (r *KustomizationReconciler) SetupWithManager(ctx context.Context, mgr ctrl
 		return fmt.Errorf("failed setting index fields: %w", err)
 	}
 
+	// Index the Kustomizations by the ConfigMaps and Secrets they load
+	// post build variables from.
+	if err := mgr.GetCache().IndexField(ctx, &kustomizev1.Kustomization{}, configMapIndexKey,
+		r.indexBySubstituteFrom("ConfigMap")); err != nil {
+		return fmt.Errorf("failed setting index fields: %w", err)
+	}
+	if err := mgr.GetCache().IndexField(ctx, &kustomizev1.Kustomization{}, secretIndexKey,
+		r.indexBySubstituteFrom("Secret")); err != nil {
+		return fmt.Errorf("failed setting index fields: %w", err)
+	}
+
+	// Index the Kustomizations by the Secrets holding their decryption keys.
+	if err := mgr.GetCache().IndexField(ctx, &kustomizev1.Kustomization{}, decryptionIndexKey,
+		r.indexByDecryptionSecret); err != nil {
+		return fmt.Errorf("failed setting index fields: %w", err)
+	}
+
+	// Index the Kustomizations by the Kustomizations they depend on.
+	if err := mgr.GetCache().IndexField(ctx, &kustomizev1.Kustomization{}, dependsOnIndexKey,
+		r.indexByDependsOn); err != nil {
+		return fmt.Errorf("failed setting index fields: %w", err)
+	}
+
+	// Index the Kustomizations by the child Kustomizations they aggregate.
+	if err := mgr.GetCache().IndexField(ctx, &kustomizev1.Kustomization{}, childrenIndexKey,
+		r.indexByChildren); err != nil {
+		return fmt.Errorf("failed setting index fields: %w", err)
+	}
+
+	// Index the Kustomizations by the objects they manage.
+	if err := mgr.GetCache().IndexField(ctx, &kustomizev1.Kustomization{}, owners.IndexKey,
+		r.indexByInventory); err != nil {
+		return fmt.Errorf("failed setting index fields: %w", err)
+	}
+
 	r.requeueDependency = opts.DependencyRequeueInterval
 	r.statusManager = fmt.Sprintf("gotk-%s", r.ControllerName)
 	r.artifactFetchRetries = opts.HTTPRetry
+	r.restConfig = mgr.GetConfig()
+	r.apiReader = mgr.GetAPIReader()
+	r.openAPISchemas = openapi.NewClusterCache(openAPISchemaCacheTTL)
+	r.clusterCapabilities = capabilities.NewDetector(clusterCapabilitiesTTL)
+	r.imageVerifier = imageverify.NewVerifier()
+	if r.ContinuousHealthChecks {
+		r.healthWatches = healthwatch.NewManager(ctx, r.notifyHealthChange)
+	}
+	r.referenceEvents = make(chan event.GenericEvent)
+	if r.WatchReferencedObjects {
+		metadataClient, err := metadata.NewForConfig(mgr.GetConfig())
+		if err != nil {
+			return fmt.Errorf("failed to create the metadata client: %w", err)
+		}
+		r.referenceWatches = refwatch.NewManager(ctx, metadataClient, r.notifyReferenceChange)
+	}
+	if r.ReapplyOnWebhookRecovery {
+		clientset, err := kubernetes.NewForConfig(mgr.GetConfig())
+		if err != nil {
+			return fmt.Errorf("failed to create the kubernetes client: %w", err)
+		}
+		r.webhookWatches = webhookwatch.NewManager(ctx, clientset, r.notifyWebhookRecovery)
+	}
+	if r.OrderedFanOut {
+		r.fanOut = fanout.NewTracker()
+	}
+	if r.EffectiveConfig != nil {
+		r.EffectiveConfig.Collect = r.effectiveConfig
+	}
+	if r.BuildService != nil {
+		r.BuildService.Client = r.Client
+		r.BuildService.Build = r.buildSource
+	}
+	if r.DryRunCacheTTL > 0 {
+		r.dryRunCache = dryruncache.NewCache(r.DryRunCacheTTL)
+	}
+	if r.NamespaceBaseline.Name != "" {
+		// The baseline objects are read without cache, as their kinds
+		// are defined by the templates.
+		baselineConfig, baselineMapper := mgr.GetConfig(), mgr.GetRESTMapper()
+		if r.SimulationConfig != nil {
+			baselineConfig = r.SimulationConfig
+			mapper, err := runtimeClient.NewDynamicRESTMapper(baselineConfig)
+			if err != nil {
+				return fmt.Errorf("failed to create the namespace baseline client: %w", err)
+			}
+			baselineMapper = mapper
+		}
+		baselineClient, err := client.New(baselineConfig, client.Options{
+			Scheme: mgr.GetScheme(),
+			Mapper: baselineMapper,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create the namespace baseline client: %w", err)
+		}
+		r.baselineClient = baselineClient
+	}
+
+	// Enqueue the existing Kustomizations in dependency and priority order
+	// once the cache is synced, instead of the order of the informer.
+	r.startupGate = &priority.Gate{}
+	if err := mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+		if !mgr.GetCache().WaitForCacheSync(ctx) {
+			return nil
+		}
+		if err := r.startupGate.Run(ctx, mgr.GetCache(), priority.Order, r.enqueue); err != nil {
+			ctrl.LoggerFrom(ctx).Error(err, "failed to enqueue the Kustomizations in dependency order")
+		}
+		return nil
+	})); err != nil {
+		return fmt.Errorf("failed to add the startup runnable: %w", err)
+	}
 
-	return ctrl.NewControllerManagedBy(mgr).
+	blder := ctrl.NewControllerManagedBy(mgr).
 		For(&kustomizev1.Kustomization{}, builder.WithPredicates(
 			predicate.Or(predicate.GenerationChangedPredicate{}, predicates.ReconcileRequestedPredicate{}),
+			r.startupGate.Predicate(),
 		)).
 		Watches(
 			&sourcev1b2.OCIRepository{},
@@ -155,8 +463,67 @@ func (r *KustomizationReconciler) SetupWithManager(ctx context.Context, mgr ctrl
 			handler.EnqueueRequestsFromMapFunc(r.requestsForRevisionChangeOf(bucketIndexKey)),
 			builder.WithPredicates(SourceRevisionChangePredicate{}),
 		).
+		Watches(
+			&kustomizev1.Kustomization{},
+			handler.EnqueueRequestsFromMapFunc(r.requestsForReadinessChangeOf),
+			builder.WithPredicates(ReadinessChangePredicate{}),
+		).
+		WatchesRawSource(
+			&source.Channel{Source: r.referenceEvents},
+			&handler.EnqueueRequestForObject{},
+		)
+
+	// Reconcile the Kustomizations consuming a source in dependency order,
+	// when the source has handled a reconciliation request.
+	if r.OrderedFanOut {
+		blder = blder.
+			Watches(
+				&sourcev1b2.OCIRepository{},
+				handler.EnqueueRequestsFromMapFunc(r.requestsForReconcileRequestOf(ociRepositoryIndexKey)),
+				builder.WithPredicates(SourceReconcileRequestPredicate{}),
+			).
+			Watches(
+				&sourcev1.GitRepository{},
+				handler.EnqueueRequestsFromMapFunc(r.requestsForReconcileRequestOf(gitRepositoryIndexKey)),
+				builder.WithPredicates(SourceReconcileRequestPredicate{}),
+			).
+			Watches(
+				&sourcev1b2.Bucket{},
+				handler.EnqueueRequestsFromMapFunc(r.requestsForReconcileRequestOf(bucketIndexKey)),
+				builder.WithPredicates(SourceReconcileRequestPredicate{}),
+			)
+	}
+
+	// Reconcile the paused Kustomizations as soon as the ReconciliationGates
+	// selecting them are opened.
+	if r.ReconciliationGates {
+		blder = blder.Watches(
+			&kustomizev1.ReconciliationGate{},
+			handler.EnqueueRequestsFromMapFunc(r.requestsForGateChangeOf),
+			builder.WithPredicates(GateOpenedPredicate{}),
+		)
+	}
+
+	// Reconcile the Kustomizations as soon as their post build variables
+	// or decryption keys change, when the ConfigMaps and Secrets are cached
+	// anyway.
+	if r.CacheSecretsAndConfigMaps {
+		blder = blder.
+			Watches(
+				&corev1.ConfigMap{},
+				handler.EnqueueRequestsFromMapFunc(r.requestsForConfigChangeOf(configMapIndexKey)),
+				builder.WithPredicates(ConfigDataChangePredicate{}),
+			).
+			Watches(
+				&corev1.Secret{},
+				handler.EnqueueRequestsFromMapFunc(r.requestsForConfigChangeOf(secretIndexKey, decryptionIndexKey)),
+				builder.WithPredicates(ConfigDataChangePredicate{}),
+			)
+	}
+
+	return blder.
 		WithOptions(controller.Options{
-			RateLimiter: opts.RateLimiter,
+			RateLimiter: r.Diagnostics.RateLimiter(opts.RateLimiter),
 		}).
 		Complete(r)
 }
@@ -164,15 +531,42 @@ func (r *KustomizationReconciler) SetupWithManager(ctx context.Context, mgr ctrl
 func (r *KustomizationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, retErr error) {
 	log := ctrl.LoggerFrom(ctx)
 	reconcileStart := time.Now()
+	defer r.Diagnostics.Start(req.NamespacedName)()
+	defer r.enqueueDependents(ctx, req.NamespacedName)
 
 	obj := &kustomizev1.Kustomization{}
 	if err := r.Get(ctx, req.NamespacedName, obj); err != nil {
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
+	// Log the reconciliation at the level set by the object annotation.
+	if level, ok := obj.GetAnnotations()[kustomizev1.LogLevelAnnotation]; ok {
+		log = loglevel.WithLevel(log, level)
+		ctx = ctrl.LoggerInto(ctx, log)
+	}
+
+	// Check the spec for human errors, before the controller defaults are
+	// set, so that the defaults are not reported as violations.
+	violations := r.checkSpec(obj)
+
+	// Set the controller defaults on the unset fields, before initializing
+	// the patcher, so that they are not persisted in the object spec.
+	r.setDefaults(obj)
+
 	// Initialize the runtime patcher with the current version of the object.
 	patcher := patch.NewSerialPatcher(obj, r.Client)
 
+	// Record the timeout and retry interval in effect, after the patcher
+	// initialization, so that the changes of the defaults are patched.
+	obj.Status.EffectiveTimeout = &metav1.Duration{Duration: obj.GetTimeout()}
+	obj.Status.EffectiveRetryInterval = &metav1.Duration{Duration: obj.GetRetryInterval()}
+
+	// Restore the status sections stored in a ConfigMap, after initializing
+	// the patcher, so that they are patched back if the object gets smaller.
+	if err := r.restoreStatusDetails(ctx, obj); err != nil {
+		return ctrl.Result{}, err
+	}
+
 	// Finalise the reconciliation and report the results.
 	defer func() {
 		// Patch finalizers, status and conditions.
@@ -180,6 +574,13 @@ func (r *KustomizationReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 			retErr = kerrors.NewAggregate([]error{retErr, err})
 		}
 
+		// Summarize the Kustomizations of the namespace with the new status.
+		if r.NamespaceReports {
+			if err := r.updateNamespaceReport(ctx, obj); err != nil {
+				log.Error(err, "unable to update the namespace report")
+			}
+		}
+
 		// Record Prometheus metrics.
 		r.Metrics.RecordReadiness(ctx, obj)
 		r.Metrics.RecordDuration(ctx, obj, reconcileStart)
@@ -190,8 +591,11 @@ func (r *KustomizationReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 			msg := fmt.Sprintf("Reconciliation finished in %s, next run in %s",
 				time.Since(reconcileStart).String(),
 				obj.Spec.Interval.Duration.String())
+			if changes, ok := r.fileChanges.LoadAndDelete(req.NamespacedName); ok {
+				msg = fmt.Sprintf("%s\nFiles changed since the last applied revision: %s", msg, changes)
+			}
 			log.Info(msg, "revision", obj.Status.LastAttemptedRevision)
-			r.event(obj, obj.Status.LastAppliedRevision, eventv1.EventSeverityInfo, msg,
+			r.event(ctx, obj, obj.Status.LastAppliedRevision, eventv1.EventSeverityInfo, msg,
 				map[string]string{
 					kustomizev1.GroupVersion.Group + "/" + eventv1.MetaCommitStatusKey: eventv1.MetaCommitStatusUpdateValue,
 				})
@@ -200,6 +604,17 @@ func (r *KustomizationReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 
 	// Prune managed resources if the object is under deletion.
 	if !obj.ObjectMeta.DeletionTimestamp.IsZero() {
+		r.stopHealthWatch(obj)
+		r.stopReferenceWatch(obj)
+		r.webhookWatches.Forget(req.NamespacedName)
+		r.fileSnapshots.Delete(req.NamespacedName)
+		buildusage.Delete(obj.GetName(), obj.GetNamespace())
+		credexpiry.Delete(obj.GetName(), obj.GetNamespace())
+		flapping.Delete(obj.GetName(), obj.GetNamespace())
+		leadtime.Delete(obj.GetName(), obj.GetNamespace())
+		if r.ManifestStreams != nil {
+			r.ManifestStreams.Remove(req.NamespacedName)
+		}
 		return r.finalize(ctx, obj)
 	}
 
@@ -215,8 +630,44 @@ func (r *KustomizationReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 	// Skip reconciliation if the object is suspended.
 	if obj.Spec.Suspend {
 		log.Info("Reconciliation is suspended for this object")
+		r.stopReferenceWatch(obj)
+		r.webhookWatches.Forget(req.NamespacedName)
+		return ctrl.Result{}, nil
+	}
+
+	// Slow down the reconciliation if the API server of the target
+	// cluster responds with high latency.
+	if err := r.waitForThrottle(ctx, obj); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Stall the reconciliation if the object violates the tenancy lockdown,
+	// until its spec is changed.
+	if err := r.checkTenancy(obj); err != nil {
+		conditions.MarkStalled(obj, kustomizev1.TenancyViolationReason, err.Error())
+		conditions.MarkFalse(obj, meta.ReadyCondition, kustomizev1.TenancyViolationReason, err.Error())
+		obj.Status.ObservedGeneration = obj.Generation
+		log.Error(err, "Reconciliation stalled")
+		r.event(ctx, obj, "unknown", eventv1.EventSeverityError, err.Error(), nil)
+		return ctrl.Result{}, nil
+	}
+
+	// Stall the reconciliation if the spec matches a human error pattern,
+	// until it's changed.
+	if len(violations) > 0 {
+		msg := fmt.Sprintf("invalid spec: %s", strings.Join(violations, "; "))
+		conditions.MarkStalled(obj, kustomizev1.InvalidSpecReason, msg)
+		conditions.MarkFalse(obj, meta.ReadyCondition, kustomizev1.InvalidSpecReason, msg)
+		obj.Status.ObservedGeneration = obj.Generation
+		log.Error(errors.New(msg), "Reconciliation stalled")
+		r.event(ctx, obj, "unknown", eventv1.EventSeverityError, msg, nil)
 		return ctrl.Result{}, nil
 	}
+	conditions.Delete(obj, meta.StalledCondition)
+
+	// Watch the Secrets and ConfigMaps referenced by the object, to
+	// reconcile it as soon as they change.
+	r.startReferenceWatch(obj)
 
 	// Resolve the source reference and requeue the reconciliation if the source is not found.
 	artifactSource, err := r.getSource(ctx, obj)
@@ -232,7 +683,7 @@ func (r *KustomizationReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 		if acl.IsAccessDenied(err) {
 			conditions.MarkFalse(obj, meta.ReadyCondition, apiacl.AccessDeniedReason, err.Error())
 			log.Error(err, "Access denied to cross-namespace source")
-			r.event(obj, "unknown", eventv1.EventSeverityError, err.Error(), nil)
+			r.event(ctx, obj, "unknown", eventv1.EventSeverityError, err.Error(), nil)
 			return ctrl.Result{RequeueAfter: obj.GetRetryInterval()}, nil
 		}
 
@@ -248,16 +699,50 @@ func (r *KustomizationReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 		return ctrl.Result{RequeueAfter: r.requeueDependency}, nil
 	}
 
+	// Annotate the events of the revision with the metadata of its commit.
+	ctx = commitinfo.IntoContext(ctx, commitinfo.FromArtifact(artifactSource.GetArtifact()))
+
 	// Check dependencies and requeue the reconciliation if the check fails.
 	if len(obj.Spec.DependsOn) > 0 {
+		// Stall the reconciliation if the object depends on itself, and
+		// re-evaluate the cycle periodically as it can be broken by
+		// changing any of the Kustomizations in it.
+		cycle, err := r.findDependencyCycle(ctx, obj)
+		if err != nil {
+			conditions.MarkFalse(obj, meta.ReadyCondition, kustomizev1.DependencyNotReadyReason, err.Error())
+			return ctrl.Result{}, err
+		}
+		if len(cycle) > 0 {
+			msg := fmt.Sprintf("circular dependency detected: %s", strings.Join(cycle, " -> "))
+			conditions.MarkStalled(obj, kustomizev1.CircularDependencyReason, msg)
+			conditions.MarkFalse(obj, meta.ReadyCondition, kustomizev1.CircularDependencyReason, msg)
+			obj.Status.ObservedGeneration = obj.Generation
+			log.Error(errors.New(msg), "Reconciliation stalled")
+			r.event(ctx, obj, artifactSource.GetArtifact().Revision, eventv1.EventSeverityError, msg, nil)
+			return ctrl.Result{RequeueAfter: r.requeueDependency}, nil
+		}
+
 		if err := r.checkDependencies(ctx, obj, artifactSource); err != nil {
 			conditions.MarkFalse(obj, meta.ReadyCondition, kustomizev1.DependencyNotReadyReason, err.Error())
 			msg := fmt.Sprintf("Dependencies do not meet ready condition, retrying in %s", r.requeueDependency.String())
 			log.Info(msg)
-			r.event(obj, artifactSource.GetArtifact().Revision, eventv1.EventSeverityInfo, msg, nil)
+			r.event(ctx, obj, artifactSource.GetArtifact().Revision, eventv1.EventSeverityInfo, msg, nil)
 			return ctrl.Result{RequeueAfter: r.requeueDependency}, nil
 		}
 		log.Info("All dependencies are ready, proceeding with reconciliation")
+	} else {
+		obj.Status.Dependencies = nil
+	}
+
+	// Delay the apply of a new revision if the revision rate limit is
+	// reached, without reporting the delay as a failure. The revisions
+	// pushed in the meantime are coalesced, as the latest revision of the
+	// source is applied once the window allows it.
+	var limitErr *revisionrate.LimitedError
+	if err := revisionrate.Check(obj, artifactSource.GetArtifact().Revision, time.Now()); errors.As(err, &limitErr) {
+		conditions.MarkFalse(obj, meta.ReadyCondition, kustomizev1.RevisionRateLimitedReason, err.Error())
+		log.Info(err.Error(), "revision", artifactSource.GetArtifact().Revision)
+		return ctrl.Result{RequeueAfter: time.Until(limitErr.Until)}, nil
 	}
 
 	// Reconcile the latest revision.
@@ -271,6 +756,39 @@ func (r *KustomizationReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 		return ctrl.Result{RequeueAfter: r.requeueDependency}, nil
 	}
 
+	// Requeue the reconciliation to apply the next chunk of objects,
+	// letting the other Kustomizations be reconciled in-between.
+	if errors.Is(reconcileErr, errApplyInProgress) {
+		log.Info(conditions.GetMessage(obj, meta.ReadyCondition), "revision", artifactSource.GetArtifact().Revision)
+		return ctrl.Result{RequeueAfter: applyChunkRequeueDelay}, nil
+	}
+
+	// Requeue the reconciliation once the circuit of the flapping webhook
+	// is half-open, without reporting the delay as a failure.
+	var circuitErr *webhookbreaker.OpenError
+	if errors.As(reconcileErr, &circuitErr) {
+		log.Info(circuitErr.Error(), "revision", artifactSource.GetArtifact().Revision)
+		return ctrl.Result{RequeueAfter: time.Until(circuitErr.Until)}, nil
+	}
+
+	// Requeue the reconciliation at the specified interval while the apply
+	// is paused by a closed gate, without reporting the pause as a failure.
+	// The reconciliation is requeued as soon as the gate is opened.
+	var gateErr *reconciliationgate.ClosedError
+	if errors.As(reconcileErr, &gateErr) {
+		log.Info(gateErr.Error(), "revision", artifactSource.GetArtifact().Revision)
+		return ctrl.Result{RequeueAfter: jitter.JitteredIntervalDuration(obj.GetRequeueAfter())}, nil
+	}
+
+	// Report the failures caused by expired credentials with a dedicated reason.
+	if provider := credexpiry.Detect(reconcileErr, obj.IsRemote()); provider != "" {
+		conditions.MarkFalse(obj, meta.ReadyCondition, kustomizev1.CredentialsExpiredReason,
+			"%s", conditions.GetMessage(obj, meta.ReadyCondition))
+		credexpiry.Record(obj.GetName(), obj.GetNamespace(), provider)
+	} else {
+		credexpiry.Delete(obj.GetName(), obj.GetNamespace())
+	}
+
 	// Broadcast the reconciliation failure and requeue at the specified retry interval.
 	if reconcileErr != nil {
 		log.Error(reconcileErr, fmt.Sprintf("Reconciliation failed after %s, next try in %s",
@@ -278,8 +796,14 @@ func (r *KustomizationReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 			obj.GetRetryInterval().String()),
 			"revision",
 			artifactSource.GetArtifact().Revision)
-		r.event(obj, artifactSource.GetArtifact().Revision, eventv1.EventSeverityError,
-			reconcileErr.Error(), nil)
+		// Reference the files of the failed build in the event.
+		var metadata map[string]string
+		var artifactErr *buildArtifactError
+		if errors.As(reconcileErr, &artifactErr) {
+			metadata = map[string]string{kustomizev1.GroupVersion.Group + "/build-artifact": artifactErr.path}
+		}
+		r.event(ctx, obj, artifactSource.GetArtifact().Revision, eventv1.EventSeverityError,
+			reconcileErr.Error(), metadata)
 		return ctrl.Result{RequeueAfter: obj.GetRetryInterval()}, nil
 	}
 
@@ -293,8 +817,24 @@ func (r *KustomizationReconciler) reconcile(
 	src sourcev1.Source,
 	patcher *patch.SerialPatcher) error {
 
-	// Update status with the reconciliation progress.
 	revision := src.GetArtifact().Revision
+
+	// Skip the new revision if it doesn't change the files affecting the
+	// objects, according to the changed paths listed by the source.
+	if isRevisionUnaffected(obj, src.GetArtifact()) {
+		ctrl.LoggerFrom(ctx).Info("skipping the build of the revision, no files changed under the paths of the Kustomization",
+			"previousRevision", obj.Status.LastAppliedRevision)
+		obj.Status.LastAttemptedRevision = revision
+		obj.Status.LastAppliedRevision = revision
+		obj.Status.LastAppliedCommit = commitinfo.FromArtifact(src.GetArtifact()).Status()
+		conditions.MarkTrue(obj,
+			meta.ReadyCondition,
+			kustomizev1.ReconciliationSucceededReason,
+			fmt.Sprintf("Applied revision: %s", revision))
+		return nil
+	}
+
+	// Update status with the reconciliation progress.
 	progressingMsg := fmt.Sprintf("Fetching manifests for revision %s with a timeout of %s", revision, obj.GetTimeout().String())
 	conditions.MarkUnknown(obj, meta.ReadyCondition, meta.ProgressingReason, "Reconciliation in progress")
 	conditions.MarkReconciling(obj, meta.ProgressingReason, progressingMsg)
@@ -309,7 +849,11 @@ func (r *KustomizationReconciler) reconcile(
 	}
 
 	// Create tmp dir.
-	tmpDir, err := MkdirTempAbs("", "kustomization-")
+	if err := r.WorkDirs.CheckQuota(); err != nil {
+		conditions.MarkFalse(obj, meta.ReadyCondition, sourcev1.DirCreationFailedReason, err.Error())
+		return err
+	}
+	tmpDir, err := MkdirTempAbs(r.WorkDirs.Root(), workdir.Prefix)
 	if err != nil {
 		err = fmt.Errorf("tmp dir error: %w", err)
 		conditions.MarkFalse(obj, meta.ReadyCondition, sourcev1.DirCreationFailedReason, err.Error())
@@ -330,19 +874,70 @@ func (r *KustomizationReconciler) reconcile(
 		return err
 	}
 
+	// Verify the extracted files of Bucket artifacts against the published
+	// checksums, to detect partial downloads from the bucket.
+	if checksums, ok := src.GetArtifact().Metadata[kustomizev1.FileChecksumsMetadataKey]; ok &&
+		obj.Spec.SourceRef.Kind == sourcev1b2.BucketKind {
+		if err := integrity.Verify(tmpDir, checksums); err != nil {
+			conditions.MarkFalse(obj, meta.ReadyCondition, kustomizev1.ArtifactIntegrityErrorReason, err.Error())
+			return err
+		}
+	}
+
+	// Extract the artifacts of the overlay sources at their paths.
+	overlayRevisions, err := r.fetchOverlays(ctx, obj, tmpDir)
+	if err != nil {
+		conditions.MarkFalse(obj, meta.ReadyCondition, kustomizev1.ArtifactFailedReason, err.Error())
+		return err
+	}
+	obj.Status.LastAttemptedOverlayRevisions = overlayRevisions
+
+	// Apply the environment presets matching the labels of the cluster
+	// to a copy of the object, from which the manifests are built.
+	buildObj, err := r.applyEnvironment(ctx, obj)
+	if err != nil {
+		conditions.MarkFalse(obj, meta.ReadyCondition, kustomizev1.BuildFailedReason, err.Error())
+		return err
+	}
+	buildObj, err = r.expandPath(ctx, buildObj)
+	if err != nil {
+		conditions.MarkFalse(obj, meta.ReadyCondition, kustomizev1.BuildFailedReason, err.Error())
+		return err
+	}
+
 	// check build path exists
-	dirPath, err := securejoin.SecureJoin(tmpDir, obj.Spec.Path)
+	dirPath, err := securejoin.SecureJoin(tmpDir, buildObj.Spec.Path)
 	if err != nil {
 		conditions.MarkFalse(obj, meta.ReadyCondition, kustomizev1.ArtifactFailedReason, err.Error())
 		return err
 	}
 
+	// Exclude the ignored files from the decryption and the build.
+	if err := r.removeIgnored(ctx, buildObj, tmpDir, dirPath); err != nil {
+		conditions.MarkFalse(obj, meta.ReadyCondition, kustomizev1.ArtifactFailedReason, err.Error())
+		return err
+	}
+
 	if _, err := os.Stat(dirPath); err != nil {
 		err = fmt.Errorf("kustomization path not found: %w", err)
 		conditions.MarkFalse(obj, meta.ReadyCondition, kustomizev1.ArtifactFailedReason, err.Error())
 		return err
 	}
 
+	// Decrypt the encrypted files without building nor applying them.
+	if obj.Spec.Mode == kustomizev1.VerifyDecryptionMode {
+		obj.Status.LastAttemptedRevision = revision
+		return r.verifyDecryption(ctx, obj, buildObj, revision, tmpDir, dirPath)
+	}
+	obj.Status.DecryptionResults = nil
+
+	// Snapshot the files under the path, to summarize the changes since
+	// the last applied revision.
+	files, snapshotErr := filediff.Take(dirPath)
+	if snapshotErr != nil {
+		ctrl.LoggerFrom(ctx).Error(snapshotErr, "unable to snapshot the source files")
+	}
+
 	// Report progress and set last attempted revision in status.
 	obj.Status.LastAttemptedRevision = revision
 	progressingMsg = fmt.Sprintf("Building manifests for revision %s with a timeout of %s", revision, obj.GetTimeout().String())
@@ -351,58 +946,238 @@ func (r *KustomizationReconciler) reconcile(
 		return fmt.Errorf("failed to update status: %w", err)
 	}
 
-	// Configure the Kubernetes client for impersonation.
-	impersonation := runtimeClient.NewImpersonator(
-		r.Client,
-		r.StatusPoller,
-		r.PollingOpts,
-		obj.Spec.KubeConfig,
-		r.KubeConfigOpts,
-		r.DefaultServiceAccount,
-		obj.Spec.ServiceAccountName,
-		obj.GetNamespace(),
-	)
-
 	// Create the Kubernetes client that runs under impersonation.
-	kubeClient, statusPoller, err := impersonation.GetClient(ctx)
+	kubeClient, statusPoller, err := r.getClusterClient(ctx, obj)
 	if err != nil {
-		conditions.MarkFalse(obj, meta.ReadyCondition, kustomizev1.ReconciliationFailedReason, err.Error())
+		reason := kustomizev1.ReconciliationFailedReason
+		var mismatchErr *certpin.MismatchError
+		if errors.As(err, &mismatchErr) {
+			reason = kustomizev1.APIServerPinMismatchReason
+		}
+		conditions.MarkFalse(obj, meta.ReadyCondition, reason, err.Error())
 		return fmt.Errorf("failed to build kube client: %w", err)
 	}
 
 	// Generate kustomization.yaml if needed.
-	k, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	k, err := runtime.DefaultUnstructuredConverter.ToUnstructured(buildObj)
 	if err != nil {
 		conditions.MarkFalse(obj, meta.ReadyCondition, kustomizev1.BuildFailedReason, err.Error())
 		return err
 	}
+	if len(buildObj.Spec.ComponentToggles) > 0 {
+		components, err := r.components(ctx, buildObj)
+		if err == nil {
+			err = unstructured.SetNestedStringSlice(k, components, "spec", "components")
+		}
+		if err != nil {
+			conditions.MarkFalse(obj, meta.ReadyCondition, kustomizev1.BuildFailedReason, err.Error())
+			return err
+		}
+	}
+	// Measure the resources used by the build and decryption.
+	buildUsage := buildusage.Start()
+
 	err = r.generate(unstructured.Unstructured{Object: k}, tmpDir, dirPath)
 	if err != nil {
 		conditions.MarkFalse(obj, meta.ReadyCondition, kustomizev1.BuildFailedReason, err.Error())
-		return err
+		return r.retainBuildArtifacts(ctx, obj, tmpDir, err)
 	}
 
+	// Collect the kustomize warnings, recorded in status along with
+	// the violations of the validation rules.
+	warnings := buildwarnings.Collect(tmpDir, dirPath)
+
 	// Build the Kustomize overlay and decrypt secrets if needed.
-	resources, err := r.build(ctx, obj, unstructured.Unstructured{Object: k}, tmpDir, dirPath)
+	resources, err := r.build(ctx, buildObj, unstructured.Unstructured{Object: k}, tmpDir, dirPath, false)
+	buildusage.Record(obj.GetName(), obj.GetNamespace(), buildUsage.Stop(tmpDir))
 	if err != nil {
+		r.recordBuildWarnings(ctx, obj, revision, warnings)
 		conditions.MarkFalse(obj, meta.ReadyCondition, kustomizev1.BuildFailedReason, err.Error())
-		return err
+		return r.retainBuildArtifacts(ctx, obj, tmpDir, err)
+	}
+	if r.BuildErrorArtifacts != nil {
+		r.BuildErrorArtifacts.Remove(client.ObjectKeyFromObject(obj))
 	}
 
 	// Convert the build result into Kubernetes unstructured objects.
 	objects, err := ssautil.ReadObjects(bytes.NewReader(resources))
+	if err != nil {
+		r.recordBuildWarnings(ctx, obj, revision, warnings)
+		conditions.MarkFalse(obj, meta.ReadyCondition, kustomizev1.BuildFailedReason, err.Error())
+		return err
+	}
+
+	// Apply the mutation rules of the controller to the objects.
+	if err := r.mutateObjects(ctx, obj, objects); err != nil {
+		r.recordBuildWarnings(ctx, obj, revision, warnings)
+		conditions.MarkFalse(obj, meta.ReadyCondition, kustomizev1.BuildFailedReason, err.Error())
+		return err
+	}
+
+	// Evaluate the validation rules, and record the violations along with
+	// the kustomize warnings in status and notify about changes.
+	if len(obj.Spec.ValidationRules) > 0 {
+		validator, err := validationrules.Compile(obj.Spec.ValidationRules)
+		if err != nil {
+			r.recordBuildWarnings(ctx, obj, revision, warnings)
+			conditions.MarkFalse(obj, meta.ReadyCondition, kustomizev1.BuildFailedReason, err.Error())
+			return err
+		}
+		warnings = append(warnings, validator.Validate(objects)...)
+	}
+	r.recordBuildWarnings(ctx, obj, revision, warnings)
+
+	// Mark the ConfigMaps and Secrets as immutable, and annotate the pod
+	// templates with the checksum of their config.
+	if err := transformConfig(obj, objects); err != nil {
+		conditions.MarkFalse(obj, meta.ReadyCondition, kustomizev1.BuildFailedReason, err.Error())
+		return err
+	}
+
+	// Replace the Secrets designated for the external store with
+	// ExternalSecret objects. Their values are written to the store right
+	// before the apply.
+	objects, pendingSecrets, err := r.externalizeSecrets(obj, objects)
 	if err != nil {
 		conditions.MarkFalse(obj, meta.ReadyCondition, kustomizev1.BuildFailedReason, err.Error())
 		return err
 	}
 
+	// Verify the signatures of the container images.
+	if err := r.verifyImages(ctx, obj, objects); err != nil {
+		conditions.MarkFalse(obj, meta.ReadyCondition, kustomizev1.ImageVerificationFailedReason, err.Error())
+		return err
+	}
+
+	// Scan the container images and block the apply on policy violations.
+	if err := r.checkImagePolicy(ctx, obj, revision, objects); err != nil {
+		conditions.MarkFalse(obj, meta.ReadyCondition, kustomizev1.ImagePolicyViolationReason, err.Error())
+		return err
+	}
+
+	// Detect the capabilities of the cluster the apply is adapted to.
+	if caps, err := r.getClusterCapabilities(ctx, obj); err != nil {
+		ctrl.LoggerFrom(ctx).Error(err, "unable to detect the cluster capabilities")
+	} else {
+		obj.Status.ClusterCapabilities = caps
+	}
+
+	// Set how the API server handles the unknown and duplicate fields.
+	if v := obj.Spec.Validation; v != nil && v.FieldValidation != "" {
+		kubeClient = fieldvalidation.NewClient(kubeClient, v.FieldValidation)
+	}
+
+	// Bound the duration of the requests made for each object.
+	if obj.Spec.ApplyTimeout != nil && obj.Spec.ApplyTimeout.Duration > 0 {
+		kubeClient = applytimeout.NewClient(kubeClient, obj.Spec.ApplyTimeout.Duration)
+	}
+
+	// Reuse the server-side dry-run results of the objects unchanged since
+	// the previous reconciliations.
+	var dryRunCacheClient *dryruncache.Client
+	if r.dryRunCache != nil {
+		if scope, err := r.dryRunCacheScope(ctx, obj, kubeClient); err != nil {
+			ctrl.LoggerFrom(ctx).Error(err, "unable to reuse the server-side dry-run results")
+		} else {
+			dryRunCacheClient = r.dryRunCache.Client(kubeClient, scope)
+			kubeClient = dryRunCacheClient
+		}
+	}
+
+	// Downgrade the admission errors of the server-side dry-run to warnings.
+	var dryRunClient *dryrun.Client
+	if obj.Spec.AdmissionDryRunPolicy == kustomizev1.AdmissionDryRunWarn {
+		dryRunClient = dryrun.NewClient(kubeClient)
+		kubeClient = dryRunClient
+	}
+
 	// Create the server-side apply manager.
 	resourceManager := ssa.NewResourceManager(kubeClient, statusPoller, ssa.Owner{
 		Field: r.ControllerName,
 		Group: kustomizev1.GroupVersion.Group,
 	})
+
+	// Set the defaults, namespaces and common metadata of the objects.
+	clusterScoped := obj.Status.ClusterScopedResources
+	if err := r.prepare(ctx, resourceManager, obj, objects); err != nil {
+		conditions.MarkFalse(obj, meta.ReadyCondition, kustomizev1.ReconciliationFailedReason, err.Error())
+		return err
+	}
+
+	// Report the cluster-scoped objects of the tenants, and deny them
+	// when enabled.
+	if err := r.checkClusterScoped(ctx, obj, revision, clusterScoped); err != nil {
+		conditions.MarkFalse(obj, meta.ReadyCondition, kustomizev1.TenancyViolationReason, err.Error())
+		return err
+	}
+
+	// Create the namespaces of the cluster targeted by the objects in the
+	// simulation API server.
+	if r.SimulationConfig != nil {
+		created, err := simulation.MirrorNamespaces(ctx, r.apiReader, kubeClient, objects)
+		if err != nil {
+			conditions.MarkFalse(obj, meta.ReadyCondition, kustomizev1.ReconciliationFailedReason, err.Error())
+			return err
+		}
+		if len(created) > 0 {
+			ctrl.LoggerFrom(ctx).Info("mirrored the namespaces of the cluster in the simulation API server", "namespaces", created)
+		}
+	}
+
+	// Compare the objects with the cluster without modifying them.
+	if obj.Spec.Mode == kustomizev1.ObserveMode {
+		return r.observe(ctx, resourceManager, obj, revision, objects)
+	}
+	obj.Status.Differences = nil
+
+	// Pause the apply while a closed ReconciliationGate selects the object.
+	if err := r.checkReconciliationGates(ctx, obj); err != nil {
+		reason := kustomizev1.ReconciliationFailedReason
+		var gateErr *reconciliationgate.ClosedError
+		if errors.As(err, &gateErr) {
+			reason = kustomizev1.ReconciliationGatedReason
+		}
+		conditions.MarkFalse(obj, meta.ReadyCondition, reason, err.Error())
+		return err
+	}
+
+	// Fail early if the new objects would exceed the resource quotas.
+	if r.CheckResourceQuotas {
+		if err := r.checkResourceQuotas(ctx, kubeClient, obj, objects); err != nil {
+			return err
+		}
+	}
+
+	// Fail early if the objects are managed by other Kustomizations.
+	if r.DetectOwnershipConflicts && !obj.IsRemote() {
+		if err := r.checkOwnershipConflicts(ctx, obj, objects); err != nil {
+			return err
+		}
+	}
+
+	// Keep the in-cluster replicas of the workloads scaled by autoscalers.
+	autoDetect := obj.Spec.ApplyStrategy != nil && obj.Spec.ApplyStrategy.PreserveAutoscaledReplicas
+	preserved, err := replicas.Preserve(ctx, kubeClient, objects, autoDetect)
+	if err != nil {
+		conditions.MarkFalse(obj, meta.ReadyCondition, kustomizev1.ReconciliationFailedReason, err.Error())
+		return err
+	}
+	if len(preserved) > 0 {
+		ctrl.LoggerFrom(ctx).V(1).Info("preserved the in-cluster replicas", "objects", preserved)
+	}
+
 	resourceManager.SetOwnerLabels(objects, obj.GetName(), obj.GetNamespace())
 	resourceManager.SetConcurrency(r.ConcurrentSSA)
+	if obj.Spec.InventoryExport != nil {
+		key := inventoryExportKey(obj)
+		inventory.SetExportOwner(objects, inventory.ApplySetID(key.Name, key.Namespace))
+	}
+
+	// Delay the apply if the objects are intercepted by a flapping webhook.
+	if err := r.checkWebhookCircuits(obj, objects); err != nil {
+		conditions.MarkFalse(obj, meta.ReadyCondition, kustomizev1.WebhookCircuitOpenReason, err.Error())
+		return err
+	}
 
 	// Update status with the reconciliation progress.
 	progressingMsg = fmt.Sprintf("Detecting drift for revision %s with a timeout of %s", revision, obj.GetTimeout().String())
@@ -411,13 +1186,77 @@ func (r *KustomizationReconciler) reconcile(
 		return fmt.Errorf("failed to update status: %w", err)
 	}
 
+	// Select the objects applied in this reconciliation, when the apply
+	// of the huge Kustomizations is spread across reconciliations.
+	chunk, applied := r.nextApplyChunk(obj, revision, resources, objects)
+
+	// Delay the apply when the fault is injected.
+	if err := r.FaultInjector.DelayApply(ctx, obj); err != nil {
+		conditions.MarkFalse(obj, meta.ReadyCondition, kustomizev1.ReconciliationFailedReason, err.Error())
+		return err
+	}
+
+	// Write the Secrets designated for the external store, once all the
+	// checks which can abort the apply have passed.
+	if err := r.materializeSecrets(ctx, obj, pendingSecrets); err != nil {
+		conditions.MarkFalse(obj, meta.ReadyCondition, kustomizev1.ReconciliationFailedReason, err.Error())
+		return err
+	}
+
+	// Compare the flapping objects with their in-cluster state, before
+	// they get updated in-place by the server-side apply.
+	fieldPaths := r.flappingFieldPaths(ctx, kubeClient, obj, chunk)
+
 	// Validate and apply resources in stages.
-	drifted, changeSet, err := r.apply(ctx, resourceManager, obj, revision, objects)
+	drifted, changeSet, digests, err := r.apply(ctx, resourceManager, obj, revision, chunk)
+	if dryRunCacheClient != nil && dryRunCacheClient.Hits() > 0 {
+		ctrl.LoggerFrom(ctx).Info(fmt.Sprintf("reused the server-side dry-run results of %d objects", dryRunCacheClient.Hits()))
+	}
+	if dryRunClient != nil {
+		if warnings := dryRunClient.Warnings(); len(warnings) > 0 {
+			msg := fmt.Sprintf("server-side dry-run denied by admission, applied anyway:\n%s", strings.Join(warnings, "\n"))
+			ctrl.LoggerFrom(ctx).Info(msg, "revision", revision)
+			r.event(ctx, obj, revision, eventv1.EventSeverityInfo, msg, nil)
+		}
+	}
+	r.watchWebhooks(obj, err)
+	r.recordWebhookCalls(ctx, obj, chunk, err)
 	if err != nil {
+		r.recordPendingRecreations(ctx, kubeClient, obj, revision, chunk)
+		conditions.MarkFalse(obj, meta.ReadyCondition, kustomizev1.ReconciliationFailedReason, err.Error())
+		return err
+	}
+
+	// Record the objects changed by consecutive reconciliations.
+	r.recordFlappingResources(ctx, obj, revision, objects, changeSet, digests, fieldPaths)
+
+	// Record the progress and requeue until all the chunks are applied.
+	if applied+len(chunk) < len(objects) {
+		return r.recordApplyProgress(obj, revision, resources, applied+len(chunk), len(objects), changeSet, digests)
+	}
+	obj.Status.PendingRecreations = nil
+	obj.Status.ApplyProgress = nil
+	if applied > 0 {
+		addUnchanged(changeSet, objects[:applied])
+		if digests != nil {
+			for id, digest := range inventory.Digests(obj.Status.Inventory) {
+				if _, ok := digests[id]; !ok {
+					digests[id] = digest
+				}
+			}
+		}
+	}
+
+	// Record the adoption of the objects tracked by Argo CD, and remove
+	// their tracking metadata once the grace period expired.
+	if err := r.migrateArgoCD(ctx, resourceManager, obj, revision, objects); err != nil {
 		conditions.MarkFalse(obj, meta.ReadyCondition, kustomizev1.ReconciliationFailedReason, err.Error())
 		return err
 	}
 
+	// Record the objects with reconciliation disabled in-cluster.
+	r.recordUnmanagedOverrides(ctx, obj, revision, objects, changeSet)
+
 	// Create an inventory from the reconciled resources.
 	newInventory := inventory.New()
 	err = inventory.AddChangeSet(newInventory, changeSet)
@@ -425,10 +1264,17 @@ func (r *KustomizationReconciler) reconcile(
 		conditions.MarkFalse(obj, meta.ReadyCondition, kustomizev1.ReconciliationFailedReason, err.Error())
 		return err
 	}
+	inventory.SetDigests(newInventory, digests)
 
 	// Set last applied inventory in status.
 	obj.Status.Inventory = newInventory
 
+	// Publish the container images of the applied revision.
+	obj.Status.Images = images.List(objects)
+
+	// Retain the manifest stream of the applied revision.
+	r.recordManifestStream(ctx, obj, revision, objects)
+
 	// Detect stale resources which are subject to garbage collection.
 	staleObjects, err := inventory.Diff(oldInventory, newInventory)
 	if err != nil {
@@ -436,16 +1282,38 @@ func (r *KustomizationReconciler) reconcile(
 		return err
 	}
 
+	// Keep the previous generations of the generated ConfigMaps and Secrets
+	// in the inventory, so that they are garbage collected later on.
+	staleObjects, err = r.retainGenerations(ctx, resourceManager, obj, staleObjects)
+	if err != nil {
+		conditions.MarkFalse(obj, meta.ReadyCondition, kustomizev1.PruneFailedReason, err.Error())
+		return err
+	}
+
+	// Keep the stale resources in the inventory when the garbage collection
+	// is dropped by fault injection, so that they are pruned once it stops.
+	if obj.Spec.Prune && len(staleObjects) > 0 && r.FaultInjector.DropPrune(obj) {
+		ctrl.LoggerFrom(ctx).Info("garbage collection dropped by fault injection", "objects", len(staleObjects))
+		inventory.AddObjects(obj.Status.Inventory, staleObjects)
+		staleObjects = nil
+	}
+
 	// Run garbage collection for stale resources that do not have pruning disabled.
 	if _, err := r.prune(ctx, resourceManager, obj, revision, staleObjects); err != nil {
 		conditions.MarkFalse(obj, meta.ReadyCondition, kustomizev1.PruneFailedReason, err.Error())
 		return err
 	}
 
+	// Record the inventory in the ConfigMap of the inventory export.
+	if err := r.exportInventory(ctx, resourceManager, obj); err != nil {
+		conditions.MarkFalse(obj, meta.ReadyCondition, kustomizev1.ReconciliationFailedReason, err.Error())
+		return err
+	}
+
 	// Run the health checks for the last applied resources.
 	isNewRevision := !src.GetArtifact().HasRevision(obj.Status.LastAppliedRevision)
 	if err := r.checkHealth(ctx,
-		resourceManager,
+		statusPoller,
 		patcher,
 		obj,
 		revision,
@@ -456,8 +1324,32 @@ func (r *KustomizationReconciler) reconcile(
 		return err
 	}
 
+	// Run the smoke tests once the resources are healthy.
+	if err := r.runTests(ctx, kubeClient, patcher, obj, revision); err != nil {
+		conditions.MarkFalse(obj, meta.ReadyCondition, kustomizev1.TestFailedReason, err.Error())
+		return err
+	}
+
+	// Roll up the readiness of the child Kustomizations.
+	if err := r.checkChildren(ctx, kubeClient, obj, changeSet.ToObjMetadataSet()); err != nil {
+		conditions.MarkFalse(obj, meta.ReadyCondition, kustomizev1.ChildrenNotReadyReason, err.Error())
+		return err
+	}
+
+	// Record the time from the availability of the new revision to its
+	// readiness.
+	if isNewRevision {
+		if d, ok := leadtime.Since(src.GetArtifact(), obj.GetCreationTimestamp(), time.Now()); ok {
+			leadtime.Record(obj.GetName(), obj.GetNamespace(), d)
+			obj.Status.LastTimeToReady = &metav1.Duration{Duration: d}
+		}
+	}
+
 	// Set last applied revision.
+	r.recordFileChanges(ctx, obj, revision, files)
+	revisionrate.Record(obj, revision, time.Now())
 	obj.Status.LastAppliedRevision = revision
+	obj.Status.LastAppliedCommit = commitinfo.FromArtifact(src.GetArtifact()).Status()
 
 	// Mark the object as ready.
 	conditions.MarkTrue(obj,
@@ -468,9 +1360,137 @@ func (r *KustomizationReconciler) reconcile(
 	return nil
 }
 
+// observe compares the objects with the cluster without modifying them,
+// records the objects which exist in-cluster in the inventory, and the
+// objects which are missing or have drifted in status.
+func (r *KustomizationReconciler) observe(ctx context.Context,
+	manager *ssa.ResourceManager,
+	obj *kustomizev1.Kustomization,
+	revision string,
+	objects []*unstructured.Unstructured) error {
+	opts := ssa.DiffOptions{
+		Exclusions: map[string]string{
+			fmt.Sprintf("%s/reconcile", kustomizev1.GroupVersion.Group): kustomizev1.DisabledValue,
+		},
+	}
+	changeSet := ssa.NewChangeSet()
+	var differences []string
+	for _, u := range objects {
+		entry, _, _, err := manager.Diff(ctx, u, opts)
+		if apierrors.IsNotFound(err) {
+			// The namespace of the object is missing.
+			differences = append(differences, fmt.Sprintf("%s: missing", ssautil.FmtUnstructured(u)))
+			continue
+		}
+		if err != nil {
+			conditions.MarkFalse(obj, meta.ReadyCondition, kustomizev1.ReconciliationFailedReason, err.Error())
+			return err
+		}
+		switch entry.Action {
+		case ssa.CreatedAction:
+			differences = append(differences, fmt.Sprintf("%s: missing", entry.Subject))
+		case ssa.ConfiguredAction:
+			differences = append(differences, fmt.Sprintf("%s: drifted", entry.Subject))
+			changeSet.Add(*entry)
+		case ssa.UnchangedAction:
+			changeSet.Add(*entry)
+		}
+	}
+
+	newInventory := inventory.New()
+	if err := inventory.AddChangeSet(newInventory, changeSet); err != nil {
+		conditions.MarkFalse(obj, meta.ReadyCondition, kustomizev1.ReconciliationFailedReason, err.Error())
+		return err
+	}
+	obj.Status.Inventory = newInventory
+	r.recordDifferences(ctx, obj, revision, differences)
+
+	conditions.MarkTrue(obj,
+		meta.ReadyCondition,
+		kustomizev1.ObservationSucceededReason,
+		fmt.Sprintf("Observed revision: %s, %d of %d objects differ from the cluster",
+			revision, len(differences), len(objects)))
+	return nil
+}
+
+// verifyDecryption decrypts the encrypted files under the path of the
+// Kustomization, and records the outcome for each file in status.
+func (r *KustomizationReconciler) verifyDecryption(ctx context.Context,
+	obj, buildObj *kustomizev1.Kustomization,
+	revision, workDir, dirPath string) error {
+	if buildObj.Spec.Decryption == nil {
+		err := fmt.Errorf("the '%s' mode requires the decryption to be configured", kustomizev1.VerifyDecryptionMode)
+		conditions.MarkFalse(obj, meta.ReadyCondition, kustomizev1.DecryptionFailedReason, err.Error())
+		return err
+	}
+
+	dec, cleanup, err := r.newDecryptor(ctx, buildObj, workDir)
+	if err != nil {
+		conditions.MarkFalse(obj, meta.ReadyCondition, kustomizev1.DecryptionFailedReason, err.Error())
+		return err
+	}
+	defer cleanup()
+
+	files, err := dec.VerifyFiles(dirPath)
+	if err != nil {
+		conditions.MarkFalse(obj, meta.ReadyCondition, kustomizev1.DecryptionFailedReason, err.Error())
+		return err
+	}
+
+	var results, failures []string
+	for _, f := range files {
+		result := fmt.Sprintf("%s: decrypted", f.Path)
+		if f.Err != nil {
+			result = fmt.Sprintf("%s: %s", f.Path, f.Err.Error())
+			failures = append(failures, result)
+		}
+		results = append(results, result)
+	}
+	obj.Status.DecryptionResults = results
+
+	if len(failures) > 0 {
+		err := fmt.Errorf("%d of %d encrypted files couldn't be decrypted:\n%s",
+			len(failures), len(files), strings.Join(failures, "\n"))
+		conditions.MarkFalse(obj, meta.ReadyCondition, kustomizev1.DecryptionFailedReason, err.Error())
+		return err
+	}
+
+	conditions.MarkTrue(obj,
+		meta.ReadyCondition,
+		kustomizev1.DecryptionVerifiedReason,
+		fmt.Sprintf("Verified revision: %s, %d encrypted files decrypted", revision, len(files)))
+	return nil
+}
+
+// recordDifferences sets the differences in status, and emits an event
+// only if they differ from the ones recorded for a previous revision.
+func (r *KustomizationReconciler) recordDifferences(ctx context.Context,
+	obj *kustomizev1.Kustomization,
+	revision string,
+	differences []string) {
+	changed := !slices.Equal(differences, obj.Status.Differences)
+	obj.Status.Differences = differences
+
+	if changed && len(differences) > 0 {
+		msg := fmt.Sprintf("objects differing from the cluster:\n%s", strings.Join(differences, "\n"))
+		ctrl.LoggerFrom(ctx).Info(msg, "revision", revision)
+		r.event(ctx, obj, revision, eventv1.EventSeverityInfo, msg, nil)
+	}
+}
+
+// checkDependencies records the state of the dependencies in status, and
+// returns an error for the first one which is not ready.
 func (r *KustomizationReconciler) checkDependencies(ctx context.Context,
 	obj *kustomizev1.Kustomization,
 	source sourcev1.Source) error {
+	waitingSince := make(map[types.NamespacedName]*metav1.Time)
+	for _, d := range obj.Status.Dependencies {
+		waitingSince[types.NamespacedName{Namespace: d.Namespace, Name: d.Name}] = d.WaitingSince
+	}
+
+	now := metav1.Now()
+	var firstErr error
+	statuses := make([]kustomizev1.DependencyStatus, 0, len(obj.Spec.DependsOn))
 	for _, d := range obj.Spec.DependsOn {
 		if d.Namespace == "" {
 			d.Namespace = obj.GetNamespace()
@@ -479,59 +1499,226 @@ func (r *KustomizationReconciler) checkDependencies(ctx context.Context,
 			Namespace: d.Namespace,
 			Name:      d.Name,
 		}
-		var k kustomizev1.Kustomization
-		err := r.Get(ctx, dName, &k)
-		if err != nil {
-			return fmt.Errorf("dependency '%s' not found: %w", dName, err)
-		}
 
-		if len(k.Status.Conditions) == 0 || k.Generation != k.Status.ObservedGeneration {
-			return fmt.Errorf("dependency '%s' is not ready", dName)
+		status := kustomizev1.DependencyStatus{
+			Name:      d.Name,
+			Namespace: d.Namespace,
+			Ready:     true,
 		}
-
-		if !apimeta.IsStatusConditionTrue(k.Status.Conditions, meta.ReadyCondition) {
-			return fmt.Errorf("dependency '%s' is not ready", dName)
+		if err := r.checkDependency(ctx, obj, source, dName, &status); err != nil {
+			status.Ready = false
+			status.Message = err.Error()
+			status.WaitingSince = waitingSince[dName]
+			if status.WaitingSince == nil {
+				status.WaitingSince = &now
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
 		}
+		statuses = append(statuses, status)
+	}
+	obj.Status.Dependencies = statuses
+
+	return firstErr
+}
+
+// checkDependency returns an error if the dependency is not ready, or if
+// it's not up to date with the revision of the shared source. The last
+// applied revision of the dependency is recorded in the status.
+func (r *KustomizationReconciler) checkDependency(ctx context.Context,
+	obj *kustomizev1.Kustomization,
+	source sourcev1.Source,
+	dName types.NamespacedName,
+	status *kustomizev1.DependencyStatus) error {
+	var k kustomizev1.Kustomization
+	err := r.Get(ctx, dName, &k)
+	if err != nil {
+		return fmt.Errorf("dependency '%s' not found: %w", dName, err)
+	}
+	status.Revision = k.Status.LastAppliedRevision
+
+	if len(k.Status.Conditions) == 0 || k.Generation != k.Status.ObservedGeneration {
+		return fmt.Errorf("dependency '%s' is not ready", dName)
+	}
+
+	if !apimeta.IsStatusConditionTrue(k.Status.Conditions, meta.ReadyCondition) {
+		return fmt.Errorf("dependency '%s' is not ready", dName)
+	}
+
+	srcNamespace := k.Spec.SourceRef.Namespace
+	if srcNamespace == "" {
+		srcNamespace = k.GetNamespace()
+	}
+	dSrcNamespace := obj.Spec.SourceRef.Namespace
+	if dSrcNamespace == "" {
+		dSrcNamespace = obj.GetNamespace()
+	}
+
+	if k.Spec.SourceRef.Name == obj.Spec.SourceRef.Name &&
+		srcNamespace == dSrcNamespace &&
+		k.Spec.SourceRef.Kind == obj.Spec.SourceRef.Kind &&
+		!source.GetArtifact().HasRevision(k.Status.LastAppliedRevision) {
+		return fmt.Errorf("dependency '%s' revision is not up to date", dName)
+	}
+
+	return nil
+}
+
+// checkChildren records the state of the Kustomizations applied by the
+// given one in status, when it aggregates their readiness, and returns an
+// error if some of them are not ready.
+func (r *KustomizationReconciler) checkChildren(ctx context.Context,
+	kubeClient client.Client,
+	obj *kustomizev1.Kustomization,
+	objects object.ObjMetadataSet) error {
+	if !obj.Spec.AggregateChildren {
+		obj.Status.Children = nil
+		conditions.Delete(obj, kustomizev1.ChildrenReadyCondition)
+		return nil
+	}
+
+	// The children of the local cluster are read from the cache, which
+	// is kept up to date by the readiness watch.
+	var reader client.Reader = r.Client
+	if obj.IsRemote() {
+		reader = kubeClient
+	}
 
-		srcNamespace := k.Spec.SourceRef.Namespace
-		if srcNamespace == "" {
-			srcNamespace = k.GetNamespace()
+	var notReady []string
+	var children []kustomizev1.ChildStatus
+	for _, o := range objects {
+		if o.GroupKind != kustomizev1.GroupVersion.WithKind(kustomizev1.KustomizationKind).GroupKind() ||
+			(o.Name == obj.GetName() && o.Namespace == obj.GetNamespace()) {
+			continue
 		}
-		dSrcNamespace := obj.Spec.SourceRef.Namespace
-		if dSrcNamespace == "" {
-			dSrcNamespace = obj.GetNamespace()
+		status := kustomizev1.ChildStatus{
+			Name:      o.Name,
+			Namespace: o.Namespace,
+			Ready:     true,
 		}
-
-		if k.Spec.SourceRef.Name == obj.Spec.SourceRef.Name &&
-			srcNamespace == dSrcNamespace &&
-			k.Spec.SourceRef.Kind == obj.Spec.SourceRef.Kind &&
-			!source.GetArtifact().HasRevision(k.Status.LastAppliedRevision) {
-			return fmt.Errorf("dependency '%s' revision is not up to date", dName)
+		if err := checkChild(ctx, reader, &status); err != nil {
+			status.Ready = false
+			status.Message = err.Error()
+			notReady = append(notReady, fmt.Sprintf("%s/%s", o.Namespace, o.Name))
 		}
+		children = append(children, status)
 	}
+	obj.Status.Children = children
 
+	if len(children) == 0 {
+		conditions.Delete(obj, kustomizev1.ChildrenReadyCondition)
+		return nil
+	}
+	if len(notReady) > 0 {
+		msg := fmt.Sprintf("%d of %d child Kustomizations not ready: %s",
+			len(notReady), len(children), strings.Join(notReady, ", "))
+		conditions.MarkFalse(obj, kustomizev1.ChildrenReadyCondition, kustomizev1.ChildrenNotReadyReason, msg)
+		return errors.New(msg)
+	}
+	conditions.MarkTrue(obj, kustomizev1.ChildrenReadyCondition, meta.SucceededReason,
+		fmt.Sprintf("%d child Kustomizations ready", len(children)))
 	return nil
 }
 
+// checkChild returns an error if the child Kustomization is not ready. The
+// last applied revision of the child is recorded in the status.
+func checkChild(ctx context.Context, reader client.Reader, status *kustomizev1.ChildStatus) error {
+	var k kustomizev1.Kustomization
+	if err := reader.Get(ctx, types.NamespacedName{Namespace: status.Namespace, Name: status.Name}, &k); err != nil {
+		return fmt.Errorf("not found: %w", err)
+	}
+	status.Revision = k.Status.LastAppliedRevision
+
+	if isReady(&k) {
+		return nil
+	}
+	if msg := conditions.GetMessage(&k, meta.ReadyCondition); msg != "" && k.Generation == k.Status.ObservedGeneration {
+		return errors.New(msg)
+	}
+	return errors.New("reconciliation in progress")
+}
+
+// findDependencyCycle returns the namespaced names of the Kustomizations
+// forming a dependency cycle starting and ending with the given one, or
+// nil if it doesn't depend on itself. The dependencies which are not found
+// are ignored.
+func (r *KustomizationReconciler) findDependencyCycle(ctx context.Context,
+	obj *kustomizev1.Kustomization) ([]string, error) {
+	start := client.ObjectKeyFromObject(obj)
+	visited := map[types.NamespacedName]bool{start: true}
+	var path []types.NamespacedName
+
+	var visit func(key types.NamespacedName, dependsOn []meta.NamespacedObjectReference) (bool, error)
+	visit = func(key types.NamespacedName, dependsOn []meta.NamespacedObjectReference) (bool, error) {
+		path = append(path, key)
+		for _, d := range dependsOn {
+			dep := types.NamespacedName{Namespace: d.Namespace, Name: d.Name}
+			if dep.Namespace == "" {
+				dep.Namespace = key.Namespace
+			}
+			if dep == start {
+				path = append(path, dep)
+				return true, nil
+			}
+			if visited[dep] {
+				continue
+			}
+			visited[dep] = true
+
+			var k kustomizev1.Kustomization
+			if err := r.Get(ctx, dep, &k); err != nil {
+				if apierrors.IsNotFound(err) {
+					continue
+				}
+				return false, fmt.Errorf("failed to get dependency '%s': %w", dep, err)
+			}
+			if found, err := visit(dep, k.Spec.DependsOn); found || err != nil {
+				return found, err
+			}
+		}
+		path = path[:len(path)-1]
+		return false, nil
+	}
+
+	found, err := visit(start, obj.Spec.DependsOn)
+	if err != nil || !found {
+		return nil, err
+	}
+	cycle := make([]string, 0, len(path))
+	for _, key := range path {
+		cycle = append(cycle, key.String())
+	}
+	return cycle, nil
+}
+
 func (r *KustomizationReconciler) getSource(ctx context.Context,
 	obj *kustomizev1.Kustomization) (sourcev1.Source, error) {
+	return r.getSourceRef(ctx, obj, obj.Spec.SourceRef)
+}
+
+// getSourceRef returns the source of the reference, which defaults to the
+// namespace of the Kustomization.
+func (r *KustomizationReconciler) getSourceRef(ctx context.Context,
+	obj *kustomizev1.Kustomization,
+	ref kustomizev1.CrossNamespaceSourceReference) (sourcev1.Source, error) {
 	var src sourcev1.Source
 	sourceNamespace := obj.GetNamespace()
-	if obj.Spec.SourceRef.Namespace != "" {
-		sourceNamespace = obj.Spec.SourceRef.Namespace
+	if ref.Namespace != "" {
+		sourceNamespace = ref.Namespace
 	}
 	namespacedName := types.NamespacedName{
 		Namespace: sourceNamespace,
-		Name:      obj.Spec.SourceRef.Name,
+		Name:      ref.Name,
 	}
 
 	if r.NoCrossNamespaceRefs && sourceNamespace != obj.GetNamespace() {
 		return src, acl.AccessDeniedError(
 			fmt.Sprintf("can't access '%s/%s', cross-namespace references have been blocked",
-				obj.Spec.SourceRef.Kind, namespacedName))
+				ref.Kind, namespacedName))
 	}
 
-	switch obj.Spec.SourceRef.Kind {
+	switch ref.Kind {
 	case sourcev1b2.OCIRepositoryKind:
 		var repository sourcev1b2.OCIRepository
 		err := r.Client.Get(ctx, namespacedName, &repository)
@@ -564,393 +1751,3066 @@ func (r *KustomizationReconciler) getSource(ctx context.Context,
 		src = &bucket
 	default:
 		return src, fmt.Errorf("source `%s` kind '%s' not supported",
-			obj.Spec.SourceRef.Name, obj.Spec.SourceRef.Kind)
+			ref.Name, ref.Kind)
 	}
 	return src, nil
 }
 
+// fetchOverlays extracts the artifacts of the overlay sources at their
+// paths in the directory of the source artifact, replacing the files under
+// the paths, and returns the revisions of the artifacts.
+func (r *KustomizationReconciler) fetchOverlays(ctx context.Context,
+	obj *kustomizev1.Kustomization,
+	tmpDir string) ([]kustomizev1.OverlayRevision, error) {
+	var revisions []kustomizev1.OverlayRevision
+	for _, overlay := range obj.Spec.OverlaySources {
+		src, err := r.getSourceRef(ctx, obj, overlay.SourceRef)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				return nil, fmt.Errorf("overlay source '%s' not found", overlay.SourceRef.String())
+			}
+			return nil, err
+		}
+		artifact := src.GetArtifact()
+		if artifact == nil {
+			return nil, fmt.Errorf("overlay source '%s' artifact not found", overlay.SourceRef.String())
+		}
+
+		dir, err := securejoin.SecureJoin(tmpDir, overlay.Path)
+		if err != nil {
+			return nil, err
+		}
+		if dir == tmpDir {
+			return nil, fmt.Errorf("overlay source '%s' path '%s' is the root of the source artifact",
+				overlay.SourceRef.String(), overlay.Path)
+		}
+		if err := os.RemoveAll(dir); err != nil {
+			return nil, fmt.Errorf("unable to remove the files under '%s': %w", overlay.Path, err)
+		}
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return nil, err
+		}
+
+		if err := fetch.NewArchiveFetcherWithLogger(
+			r.artifactFetchRetries,
+			tar.UnlimitedUntarSize,
+			tar.UnlimitedUntarSize,
+			os.Getenv("SOURCE_CONTROLLER_LOCALHOST"),
+			ctrl.LoggerFrom(ctx),
+		).Fetch(artifact.URL, artifact.Digest, dir); err != nil {
+			return nil, fmt.Errorf("overlay source '%s': %w", overlay.SourceRef.String(), err)
+		}
+
+		revisions = append(revisions, kustomizev1.OverlayRevision{
+			Source:   overlay.SourceRef.Kind + "/" + sourceKey(obj, overlay.SourceRef),
+			Revision: artifact.Revision,
+		})
+	}
+	return revisions, nil
+}
+
 func (r *KustomizationReconciler) generate(obj unstructured.Unstructured,
 	workDir string, dirPath string) error {
 	_, err := generator.NewGenerator(workDir, obj).WriteFile(dirPath)
 	return err
 }
 
-func (r *KustomizationReconciler) build(ctx context.Context,
-	obj *kustomizev1.Kustomization, u unstructured.Unstructured,
-	workDir, dirPath string) ([]byte, error) {
-	dec, cleanup, err := decryptor.NewTempDecryptor(workDir, r.Client, obj)
+// recordBuildWarnings sets the build warnings in status, and emits an event
+// only if the warnings differ from the ones recorded for a previous revision.
+func (r *KustomizationReconciler) recordBuildWarnings(ctx context.Context,
+	obj *kustomizev1.Kustomization,
+	revision string,
+	warnings []string) {
+	changed := len(warnings) != len(obj.Status.BuildWarnings)
+	for i := 0; !changed && i < len(warnings); i++ {
+		changed = warnings[i] != obj.Status.BuildWarnings[i]
+	}
+	obj.Status.BuildWarnings = warnings
+
+	if changed && len(warnings) > 0 {
+		msg := fmt.Sprintf("build warnings:\n%s", strings.Join(warnings, "\n"))
+		ctrl.LoggerFrom(ctx).Info(msg, "revision", revision)
+		r.event(ctx, obj, revision, eventv1.EventSeverityInfo, msg, nil)
+	}
+}
+
+// expandPath returns a copy of the Kustomization with the variables in the
+// path replaced with the post build variables, or the Kustomization itself
+// when the path has no variables.
+func (r *KustomizationReconciler) expandPath(ctx context.Context, obj *kustomizev1.Kustomization) (*kustomizev1.Kustomization, error) {
+	if !strings.Contains(obj.Spec.Path, "${") {
+		return obj, nil
+	}
+
+	vars, err := substitution.LoadVariables(ctx, r.Client, obj)
 	if err != nil {
 		return nil, err
 	}
-	defer cleanup()
-
-	// Import decryption keys
-	if err := dec.ImportKeys(ctx); err != nil {
+	path, err := substitution.ExpandPath(obj.Spec.Path, vars)
+	if err != nil {
 		return nil, err
 	}
+	ctrl.LoggerFrom(ctx).V(1).Info("expanded the variables of the path", "path", path)
 
-	// Decrypt Kustomize EnvSources files before build
-	if err = dec.DecryptEnvSources(dirPath); err != nil {
-		return nil, fmt.Errorf("error decrypting env sources: %w", err)
+	buildObj := obj.DeepCopy()
+	buildObj.Spec.Path = path
+	return buildObj, nil
+}
+
+// applyEnvironment returns a copy of the Kustomization with the path,
+// components and post build variables of the environment presets matching
+// the labels of the cluster, or the Kustomization itself when it has no
+// environment.
+func (r *KustomizationReconciler) applyEnvironment(ctx context.Context, obj *kustomizev1.Kustomization) (*kustomizev1.Kustomization, error) {
+	if obj.Spec.Environment == nil {
+		return obj, nil
 	}
 
-	m, err := generator.SecureBuild(workDir, dirPath, !r.NoRemoteBases)
+	clusterLabels, err := r.clusterLabels(ctx, obj)
 	if err != nil {
-		return nil, fmt.Errorf("kustomize build failed: %w", err)
+		return nil, err
+	}
+	result, err := environment.Resolve(obj.Spec.Environment, clusterLabels)
+	if err != nil {
+		return nil, err
 	}
+	ctrl.LoggerFrom(ctx).V(1).Info("resolved the environment presets", "presets", result.Presets)
 
-	for _, res := range m.Resources() {
-		// check if resources conform to the Kubernetes API conventions
-		if res.GetName() == "" || res.GetKind() == "" || res.GetApiVersion() == "" {
-			return nil, fmt.Errorf("failed to decode Kubernetes apiVersion, kind and name from: %v", res.String())
+	buildObj := obj.DeepCopy()
+	if result.Path != "" {
+		buildObj.Spec.Path = result.Path
+	}
+	for _, component := range result.Components {
+		if !slices.Contains(buildObj.Spec.Components, component) {
+			buildObj.Spec.Components = append(buildObj.Spec.Components, component)
+		}
+	}
+	if len(result.Substitute) > 0 {
+		if buildObj.Spec.PostBuild == nil {
+			buildObj.Spec.PostBuild = &kustomizev1.PostBuild{}
+		}
+		for k, v := range buildObj.Spec.PostBuild.Substitute {
+			result.Substitute[k] = v
+		}
+		buildObj.Spec.PostBuild.Substitute = result.Substitute
+	}
+	return buildObj, nil
+}
+
+// clusterLabels returns the labels describing the cluster, read from the
+// Namespace and the ConfigMap referenced in the environment.
+func (r *KustomizationReconciler) clusterLabels(ctx context.Context, obj *kustomizev1.Kustomization) (map[string]string, error) {
+	env := obj.Spec.Environment
+	clusterLabels := make(map[string]string)
+
+	if env.NamespaceName != "" {
+		reader := r.apiReader
+		if reader == nil {
+			reader = r.Client
+		}
+		var ns corev1.Namespace
+		if err := reader.Get(ctx, types.NamespacedName{Name: env.NamespaceName}, &ns); err != nil {
+			return nil, fmt.Errorf("failed to get the environment Namespace '%s': %w", env.NamespaceName, err)
 		}
+		maps.Copy(clusterLabels, ns.GetLabels())
+	}
 
-		// check if resources are encrypted and decrypt them before generating the final YAML
-		if obj.Spec.Decryption != nil {
-			outRes, err := dec.DecryptResource(res)
-			if err != nil {
-				return nil, fmt.Errorf("decryption failed for '%s': %w", res.GetName(), err)
-			}
+	if ref := env.ConfigMapRef; ref != nil {
+		key := types.NamespacedName{Namespace: obj.GetNamespace(), Name: ref.Name}
+		if ref.Namespace != "" {
+			key.Namespace = ref.Namespace
+		}
+		if r.NoCrossNamespaceRefs && key.Namespace != obj.GetNamespace() {
+			return nil, acl.AccessDeniedError(
+				fmt.Sprintf("can't access 'ConfigMap/%s', cross-namespace references have been blocked", key))
+		}
+		var cm corev1.ConfigMap
+		if err := r.Client.Get(ctx, key, &cm); err != nil {
+			return nil, fmt.Errorf("failed to get the environment ConfigMap '%s': %w", key, err)
+		}
+		maps.Copy(clusterLabels, cm.Data)
+	}
 
-			if outRes != nil {
-				_, err = m.Replace(res)
-				if err != nil {
+	return clusterLabels, nil
+}
+
+// components returns the paths of the Components of the Kustomization,
+// followed by the paths of the enabled component toggles.
+func (r *KustomizationReconciler) components(ctx context.Context, obj *kustomizev1.Kustomization) ([]string, error) {
+	components := append([]string{}, obj.Spec.Components...)
+
+	var vars map[string]string
+	for _, toggle := range obj.Spec.ComponentToggles {
+		enabled := toggle.Enabled == nil || *toggle.Enabled
+		if toggle.EnabledBy != "" {
+			if vars == nil {
+				var err error
+				if vars, err = substitution.LoadVariables(ctx, r.Client, obj); err != nil {
 					return nil, err
 				}
 			}
-		}
-
-		// run variable substitutions
-		if obj.Spec.PostBuild != nil {
-			outRes, err := generator.SubstituteVariables(ctx, r.Client, u, res, false)
-			if err != nil {
-				return nil, fmt.Errorf("var substitution failed for '%s': %w", res.GetName(), err)
-			}
-
-			if outRes != nil {
-				_, err = m.Replace(res)
+			if v, ok := vars[toggle.EnabledBy]; ok {
+				b, err := strconv.ParseBool(v)
 				if err != nil {
-					return nil, err
+					return nil, fmt.Errorf("component '%s' toggle variable '%s' is not a boolean: %w",
+						toggle.Path, toggle.EnabledBy, err)
 				}
+				enabled = b
 			}
 		}
+		if enabled && !slices.Contains(components, toggle.Path) {
+			components = append(components, toggle.Path)
+		}
 	}
-
-	resources, err := m.AsYaml()
-	if err != nil {
-		return nil, fmt.Errorf("kustomize build failed: %w", err)
-	}
-
-	return resources, nil
+	return components, nil
 }
 
-func (r *KustomizationReconciler) apply(ctx context.Context,
-	manager *ssa.ResourceManager,
-	obj *kustomizev1.Kustomization,
-	revision string,
-	objects []*unstructured.Unstructured) (bool, *ssa.ChangeSet, error) {
-	log := ctrl.LoggerFrom(ctx)
+// buildOptions returns the kustomize build options for the given
+// Kustomization, after checking them against the controller policy.
+func (r *KustomizationReconciler) buildOptions(ctx context.Context, obj *kustomizev1.Kustomization) (build.Options, error) {
+	opts := build.Options{
+		AllowRemoteBases: !r.NoRemoteBases,
+		LoadRestrictions: kustypes.LoadRestrictionsRootOnly,
+	}
 
-	if err := ssa.SetNativeKindsDefaults(objects); err != nil {
-		return false, nil, err
+	spec := obj.Spec.BuildOptions
+	if spec == nil {
+		return opts, nil
 	}
 
-	if meta := obj.Spec.CommonMetadata; meta != nil {
-		ssautil.SetCommonMetadata(objects, meta.Labels, meta.Annotations)
+	var set []string
+	if spec.LoadRestrictions != "" {
+		set = append(set, build.OptionLoadRestrictions)
+	}
+	if spec.Reorder != "" {
+		set = append(set, build.OptionReorder)
+	}
+	if spec.AddManagedByLabel != nil {
+		set = append(set, build.OptionAddManagedByLabel)
+	}
+	if spec.ManagedByLabelValue != "" {
+		set = append(set, build.OptionManagedByLabelValue)
+	}
+	if spec.OpenAPIPath != "" {
+		set = append(set, build.OptionOpenAPIPath)
+	}
+	if spec.OpenAPISchemaFrom != nil {
+		set = append(set, build.OptionOpenAPISchemaFrom)
+	}
+	for _, name := range set {
+		for _, disallowed := range r.DisallowedBuildOptions {
+			if name == disallowed {
+				return opts, fmt.Errorf("build option '%s' is not allowed by the controller policy", name)
+			}
+		}
 	}
 
-	applyOpts := ssa.DefaultApplyOptions()
-	applyOpts.Force = obj.Spec.Force
-	applyOpts.ExclusionSelector = map[string]string{
-		fmt.Sprintf("%s/reconcile", kustomizev1.GroupVersion.Group): kustomizev1.DisabledValue,
-		fmt.Sprintf("%s/ssa", kustomizev1.GroupVersion.Group):       kustomizev1.IgnoreValue,
+	if obj.GetLoadRestrictions() == kustomizev1.LoadRestrictionsNone {
+		if !r.AllowLoadRestrictionsNone {
+			return opts, fmt.Errorf("load restrictions '%s' is not allowed, the controller must run with --allow-load-restrictions-none",
+				kustomizev1.LoadRestrictionsNone)
+		}
+		opts.LoadRestrictions = kustypes.LoadRestrictionsNone
 	}
-	applyOpts.IfNotPresentSelector = map[string]string{
-		fmt.Sprintf("%s/ssa", kustomizev1.GroupVersion.Group): kustomizev1.IfNotPresentValue,
+	if spec.Reorder != "" {
+		opts.Reorder = krusty.ReorderOption(spec.Reorder)
 	}
-	applyOpts.ForceSelector = map[string]string{
-		fmt.Sprintf("%s/force", kustomizev1.GroupVersion.Group): kustomizev1.EnabledValue,
+	if add := spec.AddManagedByLabel; add != nil {
+		if !*add && spec.ManagedByLabelValue != "" {
+			return opts, fmt.Errorf("managedByLabelValue can't be set when addManagedByLabel is false")
+		}
+		opts.AddManagedByLabel = *add
+		opts.RemoveManagedByLabel = !*add
 	}
+	opts.ManagedByLabelValue = spec.ManagedByLabelValue
+	opts.OpenAPIPath = spec.OpenAPIPath
 
-	fieldManagers := []ssa.FieldManager{
-		{
-			// to undo changes made with 'kubectl apply --server-side --force-conflicts'
-			Name:          "kubectl",
-			OperationType: metav1.ManagedFieldsOperationApply,
-		},
-		{
-			// to undo changes made with 'kubectl apply'
-			Name:          "kubectl",
-			OperationType: metav1.ManagedFieldsOperationUpdate,
-		},
-		{
-			// to undo changes made with 'kubectl apply'
-			Name:          "before-first-apply",
-			OperationType: metav1.ManagedFieldsOperationUpdate,
-		},
-		{
-			// to undo changes made by the controller before SSA
-			Name:          r.ControllerName,
-			OperationType: metav1.ManagedFieldsOperationUpdate,
+	if from := spec.OpenAPISchemaFrom; from != nil {
+		if spec.OpenAPIPath != "" {
+			return opts, fmt.Errorf("openAPIPath and openAPISchemaFrom are mutually exclusive")
+		}
+		schema, err := r.getOpenAPISchema(ctx, obj, from)
+		if err != nil {
+			return opts, err
+		}
+		opts.OpenAPISchema = schema
+	}
+
+	return opts, nil
+}
+
+// restoreStatusDetails sets the status sections stored in the ConfigMap
+// referenced in status. If the ConfigMap is not found, the reference is
+// removed and the sections are rebuilt by the reconciliation, except for
+// the inventory entries which can't be garbage collected anymore.
+func (r *KustomizationReconciler) restoreStatusDetails(ctx context.Context, obj *kustomizev1.Kustomization) error {
+	ref := obj.Status.Details
+	if ref == nil {
+		return nil
+	}
+
+	reader := r.apiReader
+	if reader == nil {
+		reader = r.Client
+	}
+	var cm corev1.ConfigMap
+	if err := reader.Get(ctx, types.NamespacedName{Namespace: obj.GetNamespace(), Name: ref.Name}, &cm); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to get the status details ConfigMap '%s': %w", ref.Name, err)
+		}
+		ctrl.LoggerFrom(ctx).Error(err, "status details ConfigMap not found, the sections it held are lost",
+			"name", ref.Name, "sections", ref.Sections)
+		obj.Status.Details = nil
+		return nil
+	}
+	return statusdetails.Restore(obj, cm.Data)
+}
+
+// trimStatus moves the verbose sections of the status to a ConfigMap owned
+// by the Kustomization, when the size of the object is above the limit, and
+// deletes the ConfigMap when it's no longer needed. The returned function
+// sets the moved sections back on the object.
+func (r *KustomizationReconciler) trimStatus(ctx context.Context, obj *kustomizev1.Kustomization) (func(), error) {
+	noop := func() {}
+	if r.StatusSizeLimit <= 0 && obj.Status.Details == nil {
+		return noop, nil
+	}
+	if !obj.GetDeletionTimestamp().IsZero() {
+		return noop, nil
+	}
+
+	full := obj.Status.DeepCopy()
+	restore := func() {
+		obj.Status.BuildWarnings = full.BuildWarnings
+		obj.Status.UnmanagedOverrides = full.UnmanagedOverrides
+		obj.Status.Images = full.Images
+		obj.Status.Differences = full.Differences
+		obj.Status.Inventory = full.Inventory
+		obj.Status.Conditions = full.Conditions
+	}
+
+	var data map[string]string
+	if r.StatusSizeLimit > 0 {
+		var err error
+		if data, err = statusdetails.Trim(obj, r.StatusSizeLimit); err != nil {
+			restore()
+			return noop, err
+		}
+	}
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      statusdetails.ConfigMapName(obj),
+			Namespace: obj.GetNamespace(),
 		},
 	}
+	if len(data) == 0 {
+		if obj.Status.Details == nil {
+			return restore, nil
+		}
+		if err := r.Delete(ctx, cm); client.IgnoreNotFound(err) != nil {
+			return noop, fmt.Errorf("failed to delete the status details ConfigMap '%s': %w", cm.Name, err)
+		}
+		obj.Status.Details = nil
+		return restore, nil
+	}
+
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, cm, func() error {
+		cm.Data = data
+		return controllerutil.SetControllerReference(obj, cm, r.Client.Scheme())
+	}); err != nil {
+		restore()
+		return noop, fmt.Errorf("failed to write the status details ConfigMap '%s': %w", cm.Name, err)
+	}
+
+	sections := make([]string, 0, len(data))
+	for name := range data {
+		sections = append(sections, name)
+	}
+	sort.Strings(sections)
+	obj.Status.Details = &kustomizev1.StatusDetailsReference{Name: cm.Name, Sections: sections}
+	ctrl.LoggerFrom(ctx).V(1).Info("status sections moved to ConfigMap",
+		"name", cm.Name, "sections", sections)
+	return restore, nil
+}
+
+// getOpenAPISchema returns the OpenAPI schema from the given source.
+func (r *KustomizationReconciler) getOpenAPISchema(ctx context.Context,
+	obj *kustomizev1.Kustomization, from *kustomizev1.OpenAPISchemaSource) ([]byte, error) {
+	switch from.Kind {
+	case kustomizev1.OpenAPISchemaFromConfigMap:
+		if from.Name == "" {
+			return nil, fmt.Errorf("the ConfigMap name of the OpenAPI schema is not specified")
+		}
+		name := types.NamespacedName{Namespace: obj.GetNamespace(), Name: from.Name}
+		return openapi.FromConfigMap(ctx, r.Client, name, from.Key)
+	case kustomizev1.OpenAPISchemaFromCluster:
+		cfg, err := r.getRESTConfig(ctx, obj)
+		if err != nil {
+			return nil, err
+		}
+		return r.openAPISchemas.Get(ctx, cfg)
+	default:
+		return nil, fmt.Errorf("unsupported OpenAPI schema source kind '%s'", from.Kind)
+	}
+}
+
+// getRESTConfig returns the REST config of the cluster the Kustomization
+// is applied to, either the local cluster or the remote one set in the
+// KubeConfig secret or the remote cluster connection. The connections to
+// the remote cluster verify the certificates of its API server against
+// the pins, if any.
+func (r *KustomizationReconciler) getRESTConfig(ctx context.Context, obj *kustomizev1.Kustomization) (*rest.Config, error) {
+	if r.SimulationConfig != nil {
+		return rest.CopyConfig(r.SimulationConfig), nil
+	}
+	if !obj.IsRemote() {
+		return r.restConfig, nil
+	}
+	cfg, err := r.getRemoteRESTConfig(ctx, obj)
+	if err != nil || len(obj.Spec.APIServerPins) == 0 {
+		return cfg, err
+	}
+	pins, err := certpin.Parse(obj.Spec.APIServerPins)
+	if err != nil {
+		return nil, err
+	}
+	return certpin.Configure(cfg, pins)
+}
+
+// getRemoteRESTConfig returns the REST config of the remote cluster, from
+// the KubeConfig secret or the remote cluster connection.
+func (r *KustomizationReconciler) getRemoteRESTConfig(ctx context.Context, obj *kustomizev1.Kustomization) (*rest.Config, error) {
+	if obj.Spec.RemoteCluster != nil {
+		cfg, err := remotecluster.RESTConfig(ctx, r.Client, obj.GetNamespace(), obj.Spec.RemoteCluster)
+		if err != nil {
+			return nil, err
+		}
+		return runtimeClient.KubeConfig(cfg, r.KubeConfigOpts), nil
+	}
+
+	secretName := types.NamespacedName{
+		Namespace: obj.GetNamespace(),
+		Name:      obj.Spec.KubeConfig.SecretRef.Name,
+	}
+	var secret corev1.Secret
+	if err := r.Get(ctx, secretName, &secret); err != nil {
+		return nil, fmt.Errorf("unable to read KubeConfig secret '%s' error: %w", secretName.String(), err)
+	}
+
+	var kubeConfig []byte
+	switch {
+	case obj.Spec.KubeConfig.SecretRef.Key != "":
+		kubeConfig = secret.Data[obj.Spec.KubeConfig.SecretRef.Key]
+	case secret.Data["value"] != nil:
+		kubeConfig = secret.Data["value"]
+	default:
+		kubeConfig = secret.Data["value.yaml"]
+	}
+	if kubeConfig == nil {
+		return nil, fmt.Errorf("KubeConfig secret '%s' does not contain a kubeconfig", secretName)
+	}
+
+	cfg, err := clientcmd.RESTConfigFromKubeConfig(kubeConfig)
+	if err != nil {
+		return nil, err
+	}
+	return runtimeClient.KubeConfig(cfg, r.KubeConfigOpts), nil
+}
+
+// getClusterClient returns the client and the status poller of the cluster
+// the Kustomization is applied to, impersonating its service account.
+func (r *KustomizationReconciler) getClusterClient(ctx context.Context,
+	obj *kustomizev1.Kustomization) (client.Client, *polling.StatusPoller, error) {
+	// Apply to the simulation API server with its admin config, as the
+	// service accounts and the remote clusters don't exist there.
+	if r.SimulationConfig != nil {
+		return r.newClusterClient(rest.CopyConfig(r.SimulationConfig))
+	}
+
+	if !obj.IsRemote() {
+		impersonation := runtimeClient.NewImpersonator(
+			r.Client,
+			r.StatusPoller,
+			r.PollingOpts,
+			nil,
+			r.KubeConfigOpts,
+			r.DefaultServiceAccount,
+			obj.Spec.ServiceAccountName,
+			obj.GetNamespace(),
+		)
+		return impersonation.GetClient(ctx)
+	}
+
+	cfg, err := r.getRESTConfig(ctx, obj)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(obj.Spec.APIServerPins) > 0 {
+		if err := certpin.Check(ctx, cfg); err != nil {
+			return nil, nil, err
+		}
+	}
+	r.impersonateServiceAccount(cfg, obj)
+	return r.newClusterClient(cfg)
+}
+
+// getImpersonatedRESTConfig returns the REST config of the cluster the
+// Kustomization is applied to, impersonating its service account like the
+// client returned by getClusterClient, for the clients which can't be built
+// from the impersonator, e.g. the ones of the health watches.
+func (r *KustomizationReconciler) getImpersonatedRESTConfig(ctx context.Context,
+	obj *kustomizev1.Kustomization) (*rest.Config, error) {
+	cfg, err := r.getRESTConfig(ctx, obj)
+	if err != nil {
+		return nil, err
+	}
+	if r.SimulationConfig != nil {
+		return cfg, nil
+	}
+	cfg = rest.CopyConfig(cfg)
+	r.impersonateServiceAccount(cfg, obj)
+	return cfg, nil
+}
+
+// impersonateServiceAccount sets the REST config to impersonate the service
+// account of the Kustomization, or the default one, if any.
+func (r *KustomizationReconciler) impersonateServiceAccount(cfg *rest.Config, obj *kustomizev1.Kustomization) {
+	serviceAccount := r.DefaultServiceAccount
+	if obj.Spec.ServiceAccountName != "" {
+		serviceAccount = obj.Spec.ServiceAccountName
+	}
+	if serviceAccount != "" {
+		cfg.Impersonate = rest.ImpersonationConfig{
+			UserName: fmt.Sprintf("system:serviceaccount:%s:%s", obj.GetNamespace(), serviceAccount),
+		}
+	}
+}
+
+// newClusterClient returns a client and a status poller for the cluster
+// of the REST config.
+func (r *KustomizationReconciler) newClusterClient(cfg *rest.Config) (client.Client, *polling.StatusPoller, error) {
+	restMapper, err := runtimeClient.NewDynamicRESTMapper(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	kubeClient, err := client.New(cfg, client.Options{
+		Scheme: r.Client.Scheme(),
+		Mapper: restMapper,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return kubeClient, polling.NewStatusPoller(kubeClient, restMapper, r.PollingOpts), nil
+}
+
+// getClusterCapabilities returns the capabilities of the cluster the
+// Kustomization is applied to.
+func (r *KustomizationReconciler) getClusterCapabilities(ctx context.Context,
+	obj *kustomizev1.Kustomization) (*kustomizev1.ClusterCapabilities, error) {
+	cfg, err := r.getRESTConfig(ctx, obj)
+	if err != nil {
+		return nil, err
+	}
+	return r.clusterCapabilities.Get(ctx, cfg)
+}
+
+// dryRunCacheScope returns the scope of the dry-run results of the
+// Kustomization, which changes with its spec, the version of the cluster
+// and the admission configurations.
+func (r *KustomizationReconciler) dryRunCacheScope(ctx context.Context,
+	obj *kustomizev1.Kustomization, kubeClient client.Client) (string, error) {
+	cfg, err := r.getRESTConfig(ctx, obj)
+	if err != nil {
+		return "", err
+	}
+	cluster, err := dryruncache.Scope(ctx, cfg, kubeClient)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s/%s/%d/%s", obj.GetNamespace(), obj.GetName(), obj.GetGeneration(), cluster), nil
+}
+
+// recordUnmanagedOverrides sets in status the objects which were skipped
+// from apply due to the reconcile annotation or label being set to disabled
+// in-cluster, and emits an event when the list changes.
+func (r *KustomizationReconciler) recordUnmanagedOverrides(ctx context.Context,
+	obj *kustomizev1.Kustomization,
+	revision string,
+	objects []*unstructured.Unstructured,
+	changeSet *ssa.ChangeSet) {
+	// Objects skipped due to their metadata in the source are not overrides.
+	selector := map[string]string{
+		fmt.Sprintf("%s/reconcile", kustomizev1.GroupVersion.Group): kustomizev1.DisabledValue,
+		fmt.Sprintf("%s/ssa", kustomizev1.GroupVersion.Group):       kustomizev1.IgnoreValue,
+	}
+	ifNotPresentSelector := map[string]string{
+		fmt.Sprintf("%s/ssa", kustomizev1.GroupVersion.Group): kustomizev1.IfNotPresentValue,
+	}
+	skippedInSource := make(map[string]struct{})
+	for _, u := range objects {
+		if ssautil.AnyInMetadata(u, selector) || ssautil.AnyInMetadata(u, ifNotPresentSelector) {
+			skippedInSource[ssautil.FmtUnstructured(u)] = struct{}{}
+		}
+	}
+
+	var overrides []string
+	if changeSet != nil {
+		for _, entry := range changeSet.Entries {
+			if entry.Action != ssa.SkippedAction {
+				continue
+			}
+			id := ssautil.FmtObjMetadata(entry.ObjMetadata)
+			if _, ok := skippedInSource[id]; !ok {
+				overrides = append(overrides, id)
+			}
+		}
+	}
+	sort.Strings(overrides)
+
+	changed := len(overrides) != len(obj.Status.UnmanagedOverrides)
+	for i := 0; !changed && i < len(overrides); i++ {
+		changed = overrides[i] != obj.Status.UnmanagedOverrides[i]
+	}
+	obj.Status.UnmanagedOverrides = overrides
+
+	if changed && len(overrides) > 0 {
+		msg := fmt.Sprintf("reconciliation disabled in-cluster for:\n%s", strings.Join(overrides, "\n"))
+		ctrl.LoggerFrom(ctx).Info(msg, "revision", revision)
+		r.event(ctx, obj, revision, eventv1.EventSeverityInfo, msg, nil)
+	}
+}
+
+// flappingFieldPaths returns the JSON pointers of the fields which differ
+// in-cluster for the objects reported as flapping, indexed by object.
+func (r *KustomizationReconciler) flappingFieldPaths(ctx context.Context,
+	c client.Client,
+	obj *kustomizev1.Kustomization,
+	objects []*unstructured.Unstructured) map[string][]string {
+	if len(obj.Status.FlappingResources) == 0 {
+		return nil
+	}
+	suspects := make(map[string]struct{}, len(obj.Status.FlappingResources))
+	for _, e := range obj.Status.FlappingResources {
+		suspects[e.Object] = struct{}{}
+	}
+
+	paths := make(map[string][]string)
+	for _, u := range objects {
+		subject := ssautil.FmtUnstructured(u)
+		if _, ok := suspects[subject]; !ok {
+			continue
+		}
+		existing := &unstructured.Unstructured{}
+		existing.SetGroupVersionKind(u.GroupVersionKind())
+		if err := c.Get(ctx, client.ObjectKeyFromObject(u), existing); err != nil {
+			ctrl.LoggerFrom(ctx).V(1).Info("unable to get the flapping object", "object", subject, "error", err.Error())
+			continue
+		}
+		paths[subject] = flapping.FieldPaths(u, existing)
+	}
+	return paths
+}
+
+// recordFlappingResources sets in status the objects changed by the apply
+// while their manifests are unchanged, with the number of consecutive
+// reconciliations which changed them, and the fields which differed
+// in-cluster before the apply. A warning event is emitted when objects
+// start flapping.
+func (r *KustomizationReconciler) recordFlappingResources(ctx context.Context,
+	obj *kustomizev1.Kustomization,
+	revision string,
+	objects []*unstructured.Unstructured,
+	changeSet *ssa.ChangeSet,
+	digests map[string]string,
+	fieldPaths map[string][]string) {
+	entries := make(map[string]kustomizev1.FlappingResource, len(obj.Status.FlappingResources))
+	for _, e := range obj.Status.FlappingResources {
+		entries[e.Object] = e
+	}
+
+	var started []string
+	if changeSet != nil {
+		previous := inventory.Digests(obj.Status.Inventory)
+		for _, entry := range changeSet.Entries {
+			subject := ssautil.FmtObjMetadata(entry.ObjMetadata)
+			id := entry.ObjMetadata.String()
+			unchanged := obj.Status.LastAppliedRevision == revision
+			if digest, ok := previous[id]; ok && digests != nil {
+				unchanged = digests[id] == digest
+			}
+			if entry.Action != ssa.ConfiguredAction || !unchanged {
+				delete(entries, subject)
+				continue
+			}
+
+			e := entries[subject]
+			e.Object = subject
+			e.Reconciliations++
+			if paths := fieldPaths[subject]; len(paths) > 0 {
+				e.FieldPaths = paths
+			}
+			entries[subject] = e
+			if e.Reconciliations == flapping.Threshold {
+				started = append(started, subject)
+			}
+		}
+	}
+
+	// Forget the objects removed from the manifests.
+	built := make(map[string]struct{}, len(objects))
+	for _, u := range objects {
+		built[ssautil.FmtUnstructured(u)] = struct{}{}
+	}
+	var list []kustomizev1.FlappingResource
+	count := 0
+	for subject, e := range entries {
+		if _, ok := built[subject]; !ok {
+			continue
+		}
+		list = append(list, e)
+		if e.Reconciliations >= flapping.Threshold {
+			count++
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Object < list[j].Object
+	})
+	obj.Status.FlappingResources = list
+	obj.Status.SuggestedIgnoreRules = driftignore.Suggest(list, flapping.Threshold)
+	flapping.Record(obj.GetName(), obj.GetNamespace(), count)
+
+	if len(started) > 0 {
+		sort.Strings(started)
+		var lines []string
+		for _, subject := range started {
+			line := subject
+			if paths := entries[subject].FieldPaths; len(paths) > 0 {
+				line = fmt.Sprintf("%s: %s", subject, strings.Join(paths, ", "))
+			}
+			lines = append(lines, line)
+		}
+		msg := fmt.Sprintf("objects changed by every reconciliation while their manifests are unchanged:\n%s",
+			strings.Join(lines, "\n"))
+		if len(obj.Status.SuggestedIgnoreRules) > 0 {
+			if rules, err := yaml.Marshal(map[string]any{"ignore": obj.Status.SuggestedIgnoreRules}); err == nil {
+				msg += fmt.Sprintf("\nsuggested .spec.driftDetection rules:\n%s", strings.TrimSuffix(string(rules), "\n"))
+			}
+		}
+		ctrl.LoggerFrom(ctx).Info(msg, "revision", revision)
+		r.event(ctx, obj, revision, eventv1.EventSeverityError, msg, nil)
+	}
+}
+
+// newDecryptor returns a decryptor for the working directory, with the
+// decryption keys of the Kustomization imported.
+func (r *KustomizationReconciler) newDecryptor(ctx context.Context,
+	obj *kustomizev1.Kustomization, workDir string) (*decryptor.Decryptor, func(), error) {
+	dec, cleanup, err := decryptor.NewTempDecryptor(workDir, r.Client, obj)
+	if err != nil {
+		return nil, nil, err
+	}
+	dec.SetAzureKeyVaultOptions(r.AzureKeyVaultOptions)
+	dec.SetConcurrency(r.ConcurrentDecryption)
+
+	// Import decryption keys
+	if err := dec.ImportKeys(ctx); err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	if err := dec.ImportAgeKeyFiles(r.SopsAgeKeyDir); err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	return dec, cleanup, nil
+}
+
+// build runs the kustomize build, the decryption and the post build
+// substitutions. With localOnly, the references to remote bases and files
+// are denied, whatever the controller settings.
+func (r *KustomizationReconciler) build(ctx context.Context,
+	obj *kustomizev1.Kustomization, u unstructured.Unstructured,
+	workDir, dirPath string, localOnly bool) ([]byte, error) {
+	dec, cleanup, err := r.newDecryptor(ctx, obj, workDir)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	// Decrypt Kustomize EnvSources files before build
+	if err = dec.DecryptEnvSources(dirPath); err != nil {
+		return nil, fmt.Errorf("error decrypting env sources: %w", err)
+	}
+
+	// Validate the post build variables before substitution
+	if obj.Spec.PostBuild != nil && obj.Spec.PostBuild.SubstituteSchema != nil {
+		vars, err := substitution.LoadVariables(ctx, r.Client, obj)
+		if err != nil {
+			return nil, err
+		}
+		if err := substitution.Validate(obj.Spec.PostBuild.SubstituteSchema.Raw, vars); err != nil {
+			return nil, err
+		}
+	}
+
+	buildOpts, err := r.buildOptions(ctx, obj)
+	if err != nil {
+		return nil, err
+	}
+	buildOpts.LocalOnly = localOnly
+
+	m, err := build.SecureBuild(workDir, dirPath, buildOpts)
+	if err != nil {
+		return nil, fmt.Errorf("kustomize build failed: %w", err)
+	}
+
+	for _, res := range m.Resources() {
+		// check if resources conform to the Kubernetes API conventions
+		if res.GetName() == "" || res.GetKind() == "" || res.GetApiVersion() == "" {
+			return nil, fmt.Errorf("failed to decode Kubernetes apiVersion, kind and name from: %v", res.String())
+		}
+	}
+
+	// check if resources are encrypted and decrypt them before generating the final YAML
+	if obj.Spec.Decryption != nil {
+		decrypted, err := dec.DecryptResources(m.Resources())
+		if err != nil {
+			return nil, err
+		}
+		for _, res := range decrypted {
+			if _, err := m.Replace(res); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, res := range m.Resources() {
+		// run variable substitutions
+		if obj.Spec.PostBuild != nil {
+			outRes, err := generator.SubstituteVariables(ctx, r.Client, u, res, false)
+			if err != nil {
+				return nil, fmt.Errorf("var substitution failed for '%s': %w", res.GetName(), err)
+			}
+
+			if outRes != nil {
+				_, err = m.Replace(res)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	resources, err := m.AsYaml()
+	if err != nil {
+		return nil, fmt.Errorf("kustomize build failed: %w", err)
+	}
+
+	return resources, nil
+}
+
+// isArgoCDStripDue returns whether the grace period of the Argo CD
+// migration expired, after which the tracking metadata is removed.
+func isArgoCDStripDue(obj *kustomizev1.Kustomization) bool {
+	migration, status := obj.Spec.ArgoCDMigration, obj.Status.ArgoCDMigration
+	return migration != nil && migration.StripTrackingAfter != nil && status != nil &&
+		time.Since(status.AdoptedAt.Time) >= migration.StripTrackingAfter.Duration
+}
+
+// migrateArgoCD counts the applied objects tracked by the Argo CD
+// Application of the migration, records the time at which they were first
+// adopted, and removes their tracking metadata once the grace period expired.
+func (r *KustomizationReconciler) migrateArgoCD(ctx context.Context,
+	manager *ssa.ResourceManager,
+	obj *kustomizev1.Kustomization,
+	revision string,
+	objects []*unstructured.Unstructured) error {
+	migration := obj.Spec.ArgoCDMigration
+	if migration == nil {
+		obj.Status.ArgoCDMigration = nil
+		return nil
+	}
+
+	strip := isArgoCDStripDue(obj)
+	var tracked, stripped int
+	for _, u := range objects {
+		existing := &metav1.PartialObjectMetadata{}
+		existing.SetGroupVersionKind(u.GroupVersionKind())
+		if err := manager.Client().Get(ctx, client.ObjectKeyFromObject(u), existing); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return fmt.Errorf("failed to get %s: %w", ssautil.FmtUnstructured(u), err)
+		}
+		if !argocd.IsTracked(existing, u, migration.Application) {
+			continue
+		}
+		if !strip {
+			tracked++
+			continue
+		}
+
+		patch, err := argocd.StripPatch(existing, u)
+		if err != nil || patch == nil {
+			continue
+		}
+		if err := manager.Client().Patch(ctx, existing, client.RawPatch(types.MergePatchType, patch)); err != nil {
+			return fmt.Errorf("failed to remove the Argo CD tracking metadata from %s: %w", ssautil.FmtUnstructured(u), err)
+		}
+		stripped++
+	}
+
+	if obj.Status.ArgoCDMigration == nil {
+		if tracked == 0 {
+			return nil
+		}
+		obj.Status.ArgoCDMigration = &kustomizev1.ArgoCDMigrationStatus{AdoptedAt: metav1.Now()}
+		msg := fmt.Sprintf("Adopted %d objects tracked by the Argo CD Application '%s'", tracked, migration.Application)
+		ctrl.LoggerFrom(ctx).Info(msg, "revision", revision)
+		r.event(ctx, obj, revision, eventv1.EventSeverityInfo, msg, nil)
+	}
+	obj.Status.ArgoCDMigration.Tracked = tracked
+	if stripped > 0 {
+		msg := fmt.Sprintf("Removed the Argo CD tracking metadata from %d objects", stripped)
+		ctrl.LoggerFrom(ctx).Info(msg, "revision", revision)
+		r.event(ctx, obj, revision, eventv1.EventSeverityInfo, msg, nil)
+	}
+	return nil
+}
+
+// applyNamespaceBaseline applies the objects of the baseline templates,
+// with the controller permissions, to the target namespace of the
+// Kustomization when its Namespace object is among the given objects.
+// The Kustomizations targeting remote clusters are skipped.
+func (r *KustomizationReconciler) applyNamespaceBaseline(ctx context.Context,
+	obj *kustomizev1.Kustomization,
+	objects []*unstructured.Unstructured) error {
+	namespace := obj.Spec.TargetNamespace
+	if r.NamespaceBaseline.Name == "" || namespace == "" || obj.IsRemote() {
+		return nil
+	}
+	if !slices.ContainsFunc(objects, func(u *unstructured.Unstructured) bool {
+		return u.GetKind() == "Namespace" && u.GroupVersionKind().Group == "" && u.GetName() == namespace
+	}) {
+		return nil
+	}
+
+	templates := &corev1.ConfigMap{}
+	if err := r.apiReader.Get(ctx, r.NamespaceBaseline, templates); err != nil {
+		return fmt.Errorf("failed to get the namespace baseline '%s': %w", r.NamespaceBaseline, err)
+	}
+	baselineObjects, err := baseline.Render(templates.Data, namespace)
+	if err != nil {
+		return err
+	}
+	if len(baselineObjects) == 0 {
+		return nil
+	}
+
+	manager := ssa.NewResourceManager(r.baselineClient, nil, ssa.Owner{
+		Field: r.ControllerName,
+		Group: kustomizev1.GroupVersion.Group,
+	})
+	changeSet, err := manager.ApplyAll(ctx, baselineObjects, ssa.DefaultApplyOptions())
+	if err != nil {
+		return fmt.Errorf("failed to apply the baseline of namespace '%s': %w", namespace, err)
+	}
+	ctrl.LoggerFrom(ctx).Info("server-side apply for namespace baseline completed", "output", changeSet.ToMap())
+	return nil
+}
+
+// mutateObjects applies the mutation rules of the controller, except the
+// ones skipped by the Kustomization, to the objects in-place.
+func (r *KustomizationReconciler) mutateObjects(ctx context.Context,
+	obj *kustomizev1.Kustomization,
+	objects []*unstructured.Unstructured) error {
+	if r.MutationRules.Name == "" {
+		return nil
+	}
+
+	rules := &corev1.ConfigMap{}
+	if err := r.apiReader.Get(ctx, r.MutationRules, rules); err != nil {
+		return fmt.Errorf("failed to get the mutation rules '%s': %w", r.MutationRules, err)
+	}
+	mutator, err := mutationrules.Compile(rules.Data)
+	if err != nil {
+		return err
+	}
+	mutated, err := mutator.Mutate(objects, obj.Spec.SkipMutationRules)
+	if err != nil {
+		return err
+	}
+	if len(mutated) > 0 {
+		ctrl.LoggerFrom(ctx).Info("mutation rules applied", "output", mutated)
+	}
+	return nil
+}
+
+// retainBuildArtifacts archives the files of the failed build, with the
+// secrets redacted, and returns the build error with the path the archive
+// is served at. The build error is returned as is if the archive fails.
+func (r *KustomizationReconciler) retainBuildArtifacts(ctx context.Context,
+	obj *kustomizev1.Kustomization,
+	tmpDir string,
+	buildErr error) error {
+	if r.BuildErrorArtifacts == nil {
+		return buildErr
+	}
+	path, err := r.BuildErrorArtifacts.Add(client.ObjectKeyFromObject(obj), tmpDir)
+	if err != nil {
+		ctrl.LoggerFrom(ctx).Error(err, "unable to retain the files of the failed build")
+		return buildErr
+	}
+	return &buildArtifactError{err: buildErr, path: path}
+}
+
+// removeIgnored removes the files of the artifact excluded by the
+// .kustomizeignore files, and the files under the path excluded by
+// .spec.ignore. It returns an error if the path itself is excluded.
+func (r *KustomizationReconciler) removeIgnored(ctx context.Context,
+	obj *kustomizev1.Kustomization,
+	tmpDir, dirPath string) error {
+	ps, err := buildignore.LoadPatterns(tmpDir)
+	if err != nil {
+		return fmt.Errorf("failed to read the %s files: %w", buildignore.IgnoreFile, err)
+	}
+	path, err := filepath.Rel(tmpDir, dirPath)
+	if err != nil {
+		return err
+	}
+	path = filepath.ToSlash(path)
+	if buildignore.Excluded(ps, path, true) {
+		return fmt.Errorf("kustomization path '%s' is excluded by a %s file", obj.Spec.Path, buildignore.IgnoreFile)
+	}
+	if obj.Spec.Ignore != nil {
+		ps = append(ps, buildignore.ParsePatterns(*obj.Spec.Ignore, path)...)
+	}
+
+	ignored, err := buildignore.Remove(tmpDir, ps)
+	if err != nil {
+		return fmt.Errorf("failed to exclude the ignored files: %w", err)
+	}
+	if len(ignored) > 0 {
+		ctrl.LoggerFrom(ctx).V(1).Info("excluded the ignored files from the build", "files", ignored)
+	}
+	return nil
+}
+
+// recordManifestStream retains the normalized manifest stream of the
+// applied objects and records its digest in status.
+func (r *KustomizationReconciler) recordManifestStream(ctx context.Context,
+	obj *kustomizev1.Kustomization,
+	revision string,
+	objects []*unstructured.Unstructured) {
+	if r.ManifestStreams == nil {
+		obj.Status.ManifestDigest = ""
+		return
+	}
+	data, err := manifeststream.Format(objects)
+	if err != nil {
+		ctrl.LoggerFrom(ctx).Error(err, "unable to render the manifest stream", "revision", revision)
+		obj.Status.ManifestDigest = ""
+		return
+	}
+	obj.Status.ManifestDigest = r.ManifestStreams.Add(client.ObjectKeyFromObject(obj), revision, data)
+}
+
+// checkResourceQuotas returns an error listing the object count quotas
+// which would be exceeded by the objects not yet in the inventory.
+func (r *KustomizationReconciler) checkResourceQuotas(ctx context.Context,
+	kubeClient client.Client,
+	obj *kustomizev1.Kustomization,
+	objects []*unstructured.Unstructured) error {
+	known := make(map[string]bool)
+	if obj.Status.Inventory != nil {
+		for _, entry := range obj.Status.Inventory.Entries {
+			known[entry.ID] = true
+		}
+	}
+	var candidates []*unstructured.Unstructured
+	for _, u := range objects {
+		if !known[object.UnstructuredToObjMetadata(u).String()] {
+			candidates = append(candidates, u)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	exceeded, err := quota.Check(ctx, kubeClient, candidates)
+	if err != nil {
+		conditions.MarkFalse(obj, meta.ReadyCondition, kustomizev1.ReconciliationFailedReason, err.Error())
+		return err
+	}
+	if len(exceeded) > 0 {
+		err := fmt.Errorf("the new objects would exceed the resource quotas:\n%s", strings.Join(exceeded, "\n"))
+		conditions.MarkFalse(obj, meta.ReadyCondition, kustomizev1.QuotaExceededReason, err.Error())
+		return err
+	}
+	return nil
+}
+
+// checkOwnershipConflicts returns an error listing the objects which are
+// in the inventory of other Kustomizations.
+func (r *KustomizationReconciler) checkOwnershipConflicts(ctx context.Context,
+	obj *kustomizev1.Kustomization,
+	objects []*unstructured.Unstructured) error {
+	var conflicts []string
+	for _, u := range objects {
+		var list kustomizev1.KustomizationList
+		if err := r.List(ctx, &list, client.MatchingFields{
+			owners.IndexKey: object.UnstructuredToObjMetadata(u).String(),
+		}); err != nil {
+			err = fmt.Errorf("failed to list the Kustomizations managing %s: %w", ssautil.FmtUnstructured(u), err)
+			conditions.MarkFalse(obj, meta.ReadyCondition, kustomizev1.ReconciliationFailedReason, err.Error())
+			return err
+		}
+		for _, k := range list.Items {
+			if k.GetName() == obj.GetName() && k.GetNamespace() == obj.GetNamespace() {
+				continue
+			}
+			conflicts = append(conflicts, fmt.Sprintf("%s is managed by both Kustomization %s/%s and %s/%s",
+				ssautil.FmtUnstructured(u), obj.GetNamespace(), obj.GetName(), k.GetNamespace(), k.GetName()))
+		}
+	}
+	if len(conflicts) > 0 {
+		sort.Strings(conflicts)
+		err := fmt.Errorf("ownership conflict:\n%s", strings.Join(conflicts, "\n"))
+		conditions.MarkFalse(obj, meta.ReadyCondition, kustomizev1.OwnershipConflictReason, err.Error())
+		return err
+	}
+	return nil
+}
+
+// verifyImages verifies the signatures of the container images of the
+// objects, and returns an error listing the images which couldn't be
+// verified.
+func (r *KustomizationReconciler) verifyImages(ctx context.Context,
+	obj *kustomizev1.Kustomization,
+	objects []*unstructured.Unstructured) error {
+	spec := obj.Spec.ImageVerification
+	if spec == nil {
+		return nil
+	}
+
+	var policy imageverify.Policy
+	if spec.SecretRef != nil {
+		var secret corev1.Secret
+		key := types.NamespacedName{Namespace: obj.GetNamespace(), Name: spec.SecretRef.Name}
+		if err := r.Client.Get(ctx, key, &secret); err != nil {
+			return fmt.Errorf("failed to get the image verification Secret '%s': %w", key, err)
+		}
+		for k, data := range secret.Data {
+			if strings.HasSuffix(k, ".pub") {
+				policy.PublicKeys = append(policy.PublicKeys, data)
+			}
+		}
+		if len(policy.PublicKeys) == 0 {
+			return fmt.Errorf("no public keys found in the image verification Secret '%s'", key)
+		}
+	}
+	for _, match := range spec.MatchOIDCIdentity {
+		policy.Identities = append(policy.Identities, cosign.Identity{
+			IssuerRegExp:  match.Issuer,
+			SubjectRegExp: match.Subject,
+		})
+	}
+
+	failures, err := r.imageVerifier.Verify(ctx, images.List(objects), policy)
+	if err != nil {
+		return fmt.Errorf("image verification failed: %w", err)
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("failed to verify the signatures of the images:\n%s", strings.Join(failures, "\n"))
+	}
+	return nil
+}
+
+// checkImagePolicy scans the container images of the objects, and returns
+// an error if they violate the image policy of the Kustomization and its
+// action is 'Block'. With the 'Warn' action, the violations and the scan
+// failures are reported in an event instead.
+func (r *KustomizationReconciler) checkImagePolicy(ctx context.Context,
+	obj *kustomizev1.Kustomization,
+	revision string,
+	objects []*unstructured.Unstructured) error {
+	policy := obj.Spec.ImagePolicy
+	if policy == nil {
+		return nil
+	}
+	if r.ImageScanner == nil {
+		return errors.New("the image policy requires the controller to run with --image-scanner-address")
+	}
+
+	warn := func(msg string) {
+		ctrl.LoggerFrom(ctx).Info(msg, "revision", revision)
+		r.event(ctx, obj, revision, eventv1.EventSeverityInfo, msg, nil)
+	}
+
+	reports, err := r.ImageScanner.Scan(ctx, images.List(objects))
+	if err != nil {
+		if policy.Action == kustomizev1.ImagePolicyWarn {
+			warn(fmt.Sprintf("image scan failed, applying anyway: %s", err))
+			return nil
+		}
+		return fmt.Errorf("image scan failed: %w", err)
+	}
+
+	violations := imagescan.Violations(reports, *policy)
+	if len(violations) == 0 {
+		return nil
+	}
+	severity := policy.Severity
+	if severity == "" {
+		severity = "CRITICAL"
+	}
+	msg := fmt.Sprintf("images with vulnerabilities of severity %s or higher", severity)
+	if policy.Action == kustomizev1.ImagePolicyWarn {
+		warn(fmt.Sprintf("%s, applied anyway:\n%s", msg, strings.Join(violations, "\n")))
+		return nil
+	}
+	return fmt.Errorf("%s:\n%s", msg, strings.Join(violations, "\n"))
+}
+
+// externalizeSecrets returns the objects with the Secrets annotated for the
+// external store replaced by ExternalSecret objects, and the values of these
+// Secrets, which are written to the store by materializeSecrets.
+func (r *KustomizationReconciler) externalizeSecrets(obj *kustomizev1.Kustomization,
+	objects []*unstructured.Unstructured) ([]*unstructured.Unstructured, []secretstore.Pending, error) {
+	// Never write to the external store in simulation.
+	if r.SimulationConfig != nil {
+		return objects, nil, nil
+	}
+
+	var store *kustomizev1.ExternalSecretStore
+	if obj.Spec.Decryption != nil {
+		store = obj.Spec.Decryption.ExternalStore
+	}
+
+	var pending []secretstore.Pending
+	result := make([]*unstructured.Unstructured, 0, len(objects))
+	for _, u := range objects {
+		if !secretstore.IsDesignated(u) {
+			result = append(result, u)
+			continue
+		}
+
+		// Refuse to apply the plain text Secret to the cluster.
+		if store == nil {
+			return nil, nil, fmt.Errorf("%s is annotated with '%s', configuring an external store is required for this secret to be reconciled",
+				ssautil.FmtUnstructured(u), secretstore.Annotation)
+		}
+
+		_, prefix, err := secretstore.SplitPath(store.Path)
+		if err != nil {
+			return nil, nil, err
+		}
+		es, p, err := secretstore.Externalize(u, prefix, store.StoreRef)
+		if err != nil {
+			return nil, nil, err
+		}
+		result = append(result, es)
+		pending = append(pending, p)
+	}
+
+	return result, pending, nil
+}
+
+// materializeSecrets writes the values of the Secrets replaced by
+// ExternalSecret objects to the secret manager configured in the decryption
+// spec.
+func (r *KustomizationReconciler) materializeSecrets(ctx context.Context,
+	obj *kustomizev1.Kustomization,
+	pending []secretstore.Pending) error {
+	if len(pending) == 0 {
+		return nil
+	}
+
+	writer, _, err := r.getSecretStoreWriter(ctx, obj, obj.Spec.Decryption.ExternalStore)
+	if err != nil {
+		return err
+	}
+	for _, p := range pending {
+		if err := secretstore.Write(ctx, writer, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *KustomizationReconciler) getSecretStoreWriter(ctx context.Context,
+	obj *kustomizev1.Kustomization,
+	store *kustomizev1.ExternalSecretStore) (secretstore.Writer, string, error) {
+	secretName := types.NamespacedName{
+		Namespace: obj.GetNamespace(),
+		Name:      store.SecretRef.Name,
+	}
+	var secret corev1.Secret
+	if err := r.Get(ctx, secretName, &secret); err != nil {
+		return nil, "", fmt.Errorf("failed to read external store secret '%s': %w", secretName, err)
+	}
+	token, ok := secret.Data[secretstore.TokenKey]
+	if !ok {
+		return nil, "", fmt.Errorf("'%s' not found in external store secret '%s'", secretstore.TokenKey, secretName)
+	}
+
+	mount, prefix, err := secretstore.SplitPath(store.Path)
+	if err != nil {
+		return nil, "", err
+	}
+
+	switch store.Provider {
+	case secretstore.ProviderVault:
+		w, err := secretstore.NewVaultWriter(store.Address, string(token), mount)
+		if err != nil {
+			return nil, "", err
+		}
+		return w, prefix, nil
+	default:
+		return nil, "", fmt.Errorf("external store provider '%s' not supported", store.Provider)
+	}
+}
+
+// prepare sets the defaults of the native kinds, the namespace of the
+// objects according to the scope of their kind, and the common metadata.
+func (r *KustomizationReconciler) prepare(ctx context.Context,
+	manager *ssa.ResourceManager,
+	obj *kustomizev1.Kustomization,
+	objects []*unstructured.Unstructured) error {
+	log := ctrl.LoggerFrom(ctx)
+
+	if err := ssa.SetNativeKindsDefaults(objects); err != nil {
+		return err
+	}
+
+	// Check the namespace of the objects against the scope of their kind.
+	var defaultNamespace string
+	if obj.Spec.InheritNamespace {
+		defaultNamespace = obj.GetNamespace()
+	}
+	scopeResult, err := scope.Check(manager.Client().RESTMapper(), objects, defaultNamespace)
+	if err != nil {
+		return err
+	}
+	if len(scopeResult.Defaulted) > 0 {
+		log.Info("set the namespace of the namespaced objects without namespace",
+			"namespace", defaultNamespace, "objects", scopeResult.Defaulted)
+	}
+	if len(scopeResult.Ignored) > 0 {
+		log.Info("the namespace of the cluster-scoped objects is ignored, it should be removed from their manifests",
+			"objects", scopeResult.Ignored)
+	}
+
+	// Record the cluster-scoped objects of the tenants, which escape the
+	// reviews of the namespaced permissions.
+	obj.Status.ClusterScopedResources = nil
+	if r.isTenant(obj) && len(scopeResult.ClusterScoped) > 0 {
+		obj.Status.ClusterScopedResources = slices.Clone(scopeResult.ClusterScoped)
+		sort.Strings(obj.Status.ClusterScopedResources)
+	}
+
+	if meta := obj.Spec.CommonMetadata; meta != nil {
+		ssautil.SetCommonMetadata(objects, meta.Labels, meta.Annotations)
+	}
+	return nil
+}
+
+// nextApplyChunk returns the objects to apply in this reconciliation and
+// the number of objects applied by the previous ones for the same revision,
+// when the number of objects is above the apply chunk size. Otherwise, all
+// the objects are returned.
+func (r *KustomizationReconciler) nextApplyChunk(obj *kustomizev1.Kustomization,
+	revision string,
+	resources []byte,
+	objects []*unstructured.Unstructured) ([]*unstructured.Unstructured, int) {
+	if r.ApplyChunkSize <= 0 || len(objects) <= r.ApplyChunkSize {
+		obj.Status.ApplyProgress = nil
+		return objects, 0
+	}
+
+	// Sort the objects so that the chunks are stable across reconciliations,
+	// and the CRDs and Namespaces are applied first, unless they are applied
+	// in the order of the build.
+	if obj.Spec.ApplyStrategy == nil || !obj.Spec.ApplyStrategy.SingleStage {
+		sort.Sort(ssa.SortableUnstructureds(objects))
+	}
+
+	applied := 0
+	if p := obj.Status.ApplyProgress; p != nil &&
+		p.Revision == revision &&
+		p.Digest == applyDigest(resources) &&
+		p.Total == len(objects) {
+		applied = p.Applied
+	}
+	end := min(applied+r.ApplyChunkSize, len(objects))
+	return objects[applied:end], applied
+}
+
+// recordApplyProgress records the number of objects applied for the
+// revision, adds the applied objects to the inventory so that they are
+// garbage collected on deletion, and returns errApplyInProgress.
+func (r *KustomizationReconciler) recordApplyProgress(obj *kustomizev1.Kustomization,
+	revision string,
+	resources []byte,
+	applied, total int,
+	changeSet *ssa.ChangeSet,
+	digests map[string]string) error {
+	obj.Status.ApplyProgress = &kustomizev1.ApplyProgress{
+		Revision: revision,
+		Digest:   applyDigest(resources),
+		Applied:  applied,
+		Total:    total,
+	}
+
+	inv := inventory.New()
+	if obj.Status.Inventory != nil {
+		obj.Status.Inventory.DeepCopyInto(inv)
+	}
+	known := make(map[string]struct{}, len(inv.Entries))
+	for _, e := range inv.Entries {
+		known[e.ID] = struct{}{}
+	}
+	for _, entry := range changeSet.Entries {
+		if _, ok := known[entry.ObjMetadata.String()]; !ok {
+			inv.Entries = append(inv.Entries, kustomizev1.ResourceRef{
+				ID:      entry.ObjMetadata.String(),
+				Version: entry.GroupVersion,
+			})
+		}
+	}
+	merged := inventory.Digests(inv)
+	maps.Copy(merged, digests)
+	inventory.SetDigests(inv, merged)
+	obj.Status.Inventory = inv
+
+	msg := fmt.Sprintf("Applied %d of %d objects for revision %s, applying the next ones", applied, total, revision)
+	conditions.MarkUnknown(obj, meta.ReadyCondition, meta.ProgressingReason, msg)
+	conditions.MarkReconciling(obj, meta.ProgressingReason, msg)
+	return errApplyInProgress
+}
+
+// addUnchanged adds the objects to the change set as unchanged.
+func addUnchanged(changeSet *ssa.ChangeSet, objects []*unstructured.Unstructured) {
+	for _, u := range objects {
+		changeSet.Add(ssa.ChangeSetEntry{
+			ObjMetadata:  object.UnstructuredToObjMetadata(u),
+			GroupVersion: u.GroupVersionKind().Version,
+			Subject:      ssautil.FmtUnstructured(u),
+			Action:       ssa.UnchangedAction,
+		})
+	}
+}
+
+// applyDigest returns the digest of the rendered manifests, which
+// identifies the objects applied in chunks.
+func applyDigest(resources []byte) string {
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(resources))
+}
+
+// apply validates and applies the objects in stages. When the differential
+// apply is enabled, it returns the digests of the rendered objects indexed
+// by their inventory ID, and skips the objects whose digest is unchanged
+// unless the drift detection is due.
+func (r *KustomizationReconciler) apply(ctx context.Context,
+	manager *ssa.ResourceManager,
+	obj *kustomizev1.Kustomization,
+	revision string,
+	objects []*unstructured.Unstructured) (bool, *ssa.ChangeSet, map[string]string, error) {
+	log := ctrl.LoggerFrom(ctx)
+
+	// collect the subresources before the objects get updated in-place
+	// by the server-side apply
+	declaredSubresources, err := subresources.Collect(objects)
+	if err != nil {
+		return false, nil, nil, err
+	}
+
+	// contains the objects' metadata after apply
+	resultSet := ssa.NewChangeSet()
+
+	// Compute the digests of the rendered objects before they get
+	// updated in-place by the server-side apply.
+	var digests map[string]string
+	fullApply := true
+	if obj.Spec.DifferentialApply != nil {
+		var unchanged []*unstructured.Unstructured
+		var err error
+		fullApply = isDriftDetectionDue(obj)
+		objects, unchanged, digests, err = splitUnchanged(obj, objects, fullApply)
+		if err != nil {
+			return false, nil, nil, err
+		}
+		for _, u := range unchanged {
+			resultSet.Add(ssa.ChangeSetEntry{
+				ObjMetadata:  object.UnstructuredToObjMetadata(u),
+				GroupVersion: u.GroupVersionKind().Version,
+				Subject:      ssautil.FmtUnstructured(u),
+				Action:       ssa.UnchangedAction,
+			})
+		}
+		if len(unchanged) > 0 {
+			log.Info(fmt.Sprintf("skipping server-side apply for %d unchanged objects", len(unchanged)))
+		}
+	}
+
+	applyOpts := ssa.DefaultApplyOptions()
+	applyOpts.Force = obj.Spec.Force
+	applyOpts.ExclusionSelector = map[string]string{
+		fmt.Sprintf("%s/reconcile", kustomizev1.GroupVersion.Group): kustomizev1.DisabledValue,
+		fmt.Sprintf("%s/ssa", kustomizev1.GroupVersion.Group):       kustomizev1.IgnoreValue,
+	}
+	applyOpts.IfNotPresentSelector = map[string]string{
+		fmt.Sprintf("%s/ssa", kustomizev1.GroupVersion.Group): kustomizev1.IfNotPresentValue,
+	}
+	applyOpts.ForceSelector = map[string]string{
+		fmt.Sprintf("%s/force", kustomizev1.GroupVersion.Group): kustomizev1.EnabledValue,
+	}
+
+	fieldManagers := []ssa.FieldManager{
+		{
+			// to undo changes made with 'kubectl apply --server-side --force-conflicts'
+			Name:          "kubectl",
+			OperationType: metav1.ManagedFieldsOperationApply,
+		},
+		{
+			// to undo changes made with 'kubectl apply'
+			Name:          "kubectl",
+			OperationType: metav1.ManagedFieldsOperationUpdate,
+		},
+		{
+			// to undo changes made with 'kubectl apply'
+			Name:          "before-first-apply",
+			OperationType: metav1.ManagedFieldsOperationUpdate,
+		},
+		{
+			// to undo changes made by the controller before SSA
+			Name:          r.ControllerName,
+			OperationType: metav1.ManagedFieldsOperationUpdate,
+		},
+	}
+
+	for _, fieldManager := range r.DisallowedFieldManagers {
+		fieldManagers = append(fieldManagers, ssa.FieldManager{
+			Name:          fieldManager,
+			OperationType: metav1.ManagedFieldsOperationApply,
+		})
+		// to undo changes made by the controller before SSA
+		fieldManagers = append(fieldManagers, ssa.FieldManager{
+			Name:          fieldManager,
+			OperationType: metav1.ManagedFieldsOperationUpdate,
+		})
+	}
+
+	applyOpts.Cleanup = ssa.ApplyCleanupOptions{
+		Annotations: []string{
+			// remove the kubectl annotation
+			corev1.LastAppliedConfigAnnotation,
+			// remove deprecated fluxcd.io annotations
+			"kustomize.toolkit.fluxcd.io/checksum",
+			"fluxcd.io/sync-checksum",
+		},
+		Labels: []string{
+			// remove deprecated fluxcd.io labels
+			"fluxcd.io/sync-gc-mark",
+		},
+		FieldManagers: fieldManagers,
+		Exclusions: map[string]string{
+			fmt.Sprintf("%s/ssa", kustomizev1.GroupVersion.Group): kustomizev1.MergeValue,
+		},
+	}
+
+	// Keep the last applied configuration Argo CD computes its diffs from,
+	// while the objects are migrated.
+	if obj.Spec.ArgoCDMigration != nil && !isArgoCDStripDue(obj) {
+		applyOpts.Cleanup.Annotations = slices.DeleteFunc(applyOpts.Cleanup.Annotations, func(a string) bool {
+			return a == corev1.LastAppliedConfigAnnotation
+		})
+	}
+
+	// create first the objects deleted by a forced apply which could not
+	// be created again by the previous reconciliations
+	var recreated []*unstructured.Unstructured
+	objects, recreated = splitPendingRecreations(obj, objects)
+	recreateLog, err := r.applyInOrder(ctx, manager, obj, revision, recreated, applyOpts, resultSet)
+	if err != nil {
+		return false, nil, nil, fmt.Errorf("failed to recreate the objects deleted by a forced apply: %w", err)
+	}
+	for _, u := range recreated {
+		subject := ssautil.FmtUnstructured(u)
+		obj.Status.PendingRecreations = slices.DeleteFunc(obj.Status.PendingRecreations, func(s string) bool {
+			return s == subject
+		})
+	}
+
+	// apply the objects in the order of the build, without staging
+	if obj.Spec.ApplyStrategy != nil && obj.Spec.ApplyStrategy.SingleStage {
+		changeSetLog, err := r.applyInOrder(ctx, manager, obj, revision, objects, applyOpts, resultSet)
+		changeSetLog = recreateLog + changeSetLog
+		if err == nil {
+			var applied []string
+			applied, err = subresources.ApplyAll(ctx, manager.Client(), r.ControllerName, declaredSubresources, resultSet)
+			for _, subject := range applied {
+				changeSetLog += subject + " subresources applied\n"
+			}
+		}
+		if err != nil {
+			return false, nil, nil, fmt.Errorf("%w\n%s", err, changeSetLog)
+		}
+		return r.finishApply(ctx, obj, revision, changeSetLog, fullApply), resultSet, digests, nil
+	}
+
+	// contains only CRDs and Namespaces
+	var defStage []*unstructured.Unstructured
+
+	// contains only Kubernetes Class types e.g.: RuntimeClass, PriorityClass,
+	// StorageClass, VolumeSnapshotClass, IngressClass, GatewayClass, ClusterClass, etc
+	var classStage []*unstructured.Unstructured
+
+	// contains all objects except for CRDs, Namespaces and Class type objects
+	var resStage []*unstructured.Unstructured
+
+	// contains the objects of the kinds applied with client-side apply
+	var csaStage []*unstructured.Unstructured
+
+	// contains the custom resources of the CRDs converted by a webhook,
+	// applied once the webhook services are ready
+	var convStage []*unstructured.Unstructured
+	var webhooks map[schema.GroupKind]types.NamespacedName
+	if r.ConversionWebhookTimeout > 0 {
+		var err error
+		if webhooks, err = crdconversion.Webhooks(objects); err != nil {
+			return false, nil, nil, err
+		}
+	}
+
+	for _, u := range objects {
+		if decryptor.IsEncryptedSecret(u) {
+			return false, nil, nil,
+				fmt.Errorf("%s is SOPS encrypted, configuring decryption is required for this secret to be reconciled",
+					ssautil.FmtUnstructured(u))
+		}
+
+		switch {
+		case applyStrategy(obj, u) == kustomizev1.ClientSideApply:
+			csaStage = append(csaStage, u)
+		case ssautil.IsClusterDefinition(u):
+			defStage = append(defStage, u)
+		case strings.HasSuffix(u.GetKind(), "Class"):
+			classStage = append(classStage, u)
+		case hasWebhook(webhooks, u):
+			convStage = append(convStage, u)
+		default:
+			resStage = append(resStage, u)
+		}
+
+	}
+
+	var changeSetLog strings.Builder
+	changeSetLog.WriteString(recreateLog)
+
+	// validate, apply and wait for CRDs and Namespaces to register
+	if len(defStage) > 0 {
+		changeSet, err := manager.ApplyAll(ctx, defStage, applyOpts)
+		if err != nil {
+			return false, nil, nil, err
+		}
+
+		if changeSet != nil && len(changeSet.Entries) > 0 {
+			resultSet.Append(changeSet.Entries)
+
+			log.Info("server-side apply for cluster definitions completed", "output", changeSet.ToMap())
+			for _, change := range changeSet.Entries {
+				if HasChanged(change.Action) {
+					changeSetLog.WriteString(change.String() + "\n")
+				}
+			}
+
+			if err := manager.WaitForSet(changeSet.ToObjMetadataSet(), ssa.WaitOptions{
+				Interval: 2 * time.Second,
+				Timeout:  obj.GetTimeout(),
+			}); err != nil {
+				return false, nil, nil, err
+			}
+		}
+
+		// Stamp the baseline objects into the target namespace, before
+		// the objects of the Kustomization are created in it.
+		if err := r.applyNamespaceBaseline(ctx, obj, defStage); err != nil {
+			return false, nil, nil, err
+		}
+	}
+
+	// validate, apply and wait for Class type objects to register
+	if len(classStage) > 0 {
+		changeSet, err := manager.ApplyAll(ctx, classStage, applyOpts)
+		if err != nil {
+			return false, nil, nil, err
+		}
+
+		if changeSet != nil && len(changeSet.Entries) > 0 {
+			resultSet.Append(changeSet.Entries)
+
+			log.Info("server-side apply for cluster class types completed", "output", changeSet.ToMap())
+			for _, change := range changeSet.Entries {
+				if HasChanged(change.Action) {
+					changeSetLog.WriteString(change.String() + "\n")
+				}
+			}
+
+			if err := manager.WaitForSet(changeSet.ToObjMetadataSet(), ssa.WaitOptions{
+				Interval: 2 * time.Second,
+				Timeout:  obj.GetTimeout(),
+			}); err != nil {
+				return false, nil, nil, err
+			}
+		}
+	}
+
+	// sort by kind, validate and apply all the others objects
+	sort.Sort(ssa.SortableUnstructureds(resStage))
+	if len(resStage) > 0 {
+		changeSet, err := manager.ApplyAll(ctx, resStage, applyOpts)
+		if err != nil {
+			return false, nil, nil, fmt.Errorf("%w\n%s", err, changeSetLog.String())
+		}
+
+		if changeSet != nil && len(changeSet.Entries) > 0 {
+			resultSet.Append(changeSet.Entries)
+
+			log.Info("server-side apply completed", "output", changeSet.ToMap(), "revision", revision)
+			for _, change := range changeSet.Entries {
+				if HasChanged(change.Action) {
+					changeSetLog.WriteString(change.String() + "\n")
+				}
+			}
+		}
+	}
+
+	// apply the objects of the kinds overridden to client-side apply
+	sort.Sort(ssa.SortableUnstructureds(csaStage))
+	if len(csaStage) > 0 {
+		changeSet, err := csa.ApplyAll(ctx, manager.Client(), r.ControllerName, csaStage, applyOpts)
+		if err != nil {
+			return false, nil, nil, fmt.Errorf("%w\n%s", err, changeSetLog.String())
+		}
+
+		resultSet.Append(changeSet.Entries)
+		log.Info("client-side apply completed", "output", changeSet.ToMap(), "revision", revision)
+		for _, change := range changeSet.Entries {
+			if HasChanged(change.Action) {
+				changeSetLog.WriteString(change.String() + "\n")
+			}
+		}
+	}
+
+	// wait for the conversion webhooks to be ready and apply their custom resources
+	if len(convStage) > 0 {
+		services := make([]types.NamespacedName, 0, len(webhooks))
+		seen := make(map[types.NamespacedName]bool)
+		for _, u := range convStage {
+			svc := webhooks[u.GroupVersionKind().GroupKind()]
+			if !seen[svc] {
+				seen[svc] = true
+				services = append(services, svc)
+			}
+		}
+		if err := crdconversion.WaitForEndpoints(ctx, manager.Client(), services,
+			2*time.Second, min(r.ConversionWebhookTimeout, obj.GetTimeout())); err != nil {
+			return false, nil, nil, fmt.Errorf("%w\n%s", err, changeSetLog.String())
+		}
+
+		sort.Sort(ssa.SortableUnstructureds(convStage))
+		changeSet, err := manager.ApplyAll(ctx, convStage, applyOpts)
+		if err != nil {
+			return false, nil, nil, fmt.Errorf("%w\n%s", err, changeSetLog.String())
+		}
+
+		if changeSet != nil && len(changeSet.Entries) > 0 {
+			resultSet.Append(changeSet.Entries)
+
+			log.Info("server-side apply for converted custom resources completed", "output", changeSet.ToMap(), "revision", revision)
+			for _, change := range changeSet.Entries {
+				if HasChanged(change.Action) {
+					changeSetLog.WriteString(change.String() + "\n")
+				}
+			}
+		}
+	}
+
+	// apply the subresources declared in the manifests
+	applied, err := subresources.ApplyAll(ctx, manager.Client(), r.ControllerName, declaredSubresources, resultSet)
+	for _, subject := range applied {
+		changeSetLog.WriteString(subject + " subresources applied\n")
+	}
+	if err != nil {
+		return false, nil, nil, fmt.Errorf("%w\n%s", err, changeSetLog.String())
+	}
+
+	return r.finishApply(ctx, obj, revision, changeSetLog.String(), fullApply), resultSet, digests, nil
+}
+
+// finishApply emits the event listing the changes, records the time of the
+// last full apply, and returns true if the apply resulted in changes.
+func (r *KustomizationReconciler) finishApply(ctx context.Context,
+	obj *kustomizev1.Kustomization,
+	revision string,
+	changeSetLog string,
+	fullApply bool) bool {
+	// emit event only if the server-side apply resulted in changes
+	applyLog := strings.TrimSuffix(changeSetLog, "\n")
+	if applyLog != "" {
+		r.event(ctx, obj, revision, eventv1.EventSeverityInfo, applyLog, nil)
+	}
+
+	// record the time of the last full apply for the drift detection
+	switch {
+	case obj.Spec.DifferentialApply == nil:
+		obj.Status.LastFullApplyAt = nil
+	case fullApply:
+		now := metav1.Now()
+		obj.Status.LastFullApplyAt = &now
+	}
+
+	return applyLog != ""
+}
+
+// applyInOrder applies the objects one by one in the given order, with their
+// apply strategy. It waits for the cluster definitions to register before
+// applying the next objects, and stamps the namespace baseline once the
+// Namespace is applied. It returns the changes, appended to the result set.
+func (r *KustomizationReconciler) applyInOrder(ctx context.Context,
+	manager *ssa.ResourceManager,
+	obj *kustomizev1.Kustomization,
+	revision string,
+	objects []*unstructured.Unstructured,
+	applyOpts ssa.ApplyOptions,
+	resultSet *ssa.ChangeSet) (string, error) {
+	var changeSetLog strings.Builder
+	for _, u := range objects {
+		if decryptor.IsEncryptedSecret(u) {
+			return changeSetLog.String(),
+				fmt.Errorf("%s is SOPS encrypted, configuring decryption is required for this secret to be reconciled",
+					ssautil.FmtUnstructured(u))
+		}
+
+		var change *ssa.ChangeSetEntry
+		if applyStrategy(obj, u) == kustomizev1.ClientSideApply {
+			changeSet, err := csa.ApplyAll(ctx, manager.Client(), r.ControllerName, []*unstructured.Unstructured{u}, applyOpts)
+			if err != nil {
+				return changeSetLog.String(), err
+			}
+			if len(changeSet.Entries) > 0 {
+				change = &changeSet.Entries[0]
+			}
+		} else {
+			var err error
+			if change, err = manager.Apply(ctx, u, applyOpts); err != nil {
+				return changeSetLog.String(), err
+			}
+		}
+		if change == nil {
+			continue
+		}
+		resultSet.Add(*change)
+		if HasChanged(change.Action) {
+			changeSetLog.WriteString(change.String() + "\n")
+		}
+
+		if ssautil.IsClusterDefinition(u) {
+			if HasChanged(change.Action) {
+				if err := manager.WaitForSet(object.ObjMetadataSet{change.ObjMetadata}, ssa.WaitOptions{
+					Interval: 2 * time.Second,
+					Timeout:  obj.GetTimeout(),
+				}); err != nil {
+					return changeSetLog.String(), err
+				}
+			}
+			if err := r.applyNamespaceBaseline(ctx, obj, []*unstructured.Unstructured{u}); err != nil {
+				return changeSetLog.String(), err
+			}
+		}
+	}
+	if len(objects) > 0 {
+		ctrl.LoggerFrom(ctx).Info("in-order apply completed", "output", resultSet.ToMap(), "revision", revision)
+	}
+	return changeSetLog.String(), nil
+}
+
+// splitPendingRecreations returns the objects without the ones pending
+// recreation, and the objects pending recreation.
+func splitPendingRecreations(obj *kustomizev1.Kustomization,
+	objects []*unstructured.Unstructured) ([]*unstructured.Unstructured, []*unstructured.Unstructured) {
+	if len(obj.Status.PendingRecreations) == 0 {
+		return objects, nil
+	}
+	var others, pending []*unstructured.Unstructured
+	for _, u := range objects {
+		if slices.Contains(obj.Status.PendingRecreations, ssautil.FmtUnstructured(u)) {
+			pending = append(pending, u)
+		} else {
+			others = append(others, u)
+		}
+	}
+	return others, pending
+}
+
+// recordPendingRecreations sets in status the objects of the failed apply
+// which are not found in the cluster, while they were applied by the previous
+// reconciliations and are subject to a forced apply, or were already pending
+// recreation. These objects have been deleted to replace their immutable
+// fields, and could not be created again. A warning event is emitted when
+// new objects are pending recreation.
+func (r *KustomizationReconciler) recordPendingRecreations(ctx context.Context,
+	c client.Client,
+	obj *kustomizev1.Kustomization,
+	revision string,
+	objects []*unstructured.Unstructured) {
+	log := ctrl.LoggerFrom(ctx)
+
+	var applied object.ObjMetadataSet
+	if obj.Status.Inventory != nil {
+		var err error
+		if applied, err = inventory.ListMetadata(obj.Status.Inventory); err != nil {
+			log.Error(err, "unable to list the inventory")
+			return
+		}
+	}
+	forceSelector := map[string]string{
+		fmt.Sprintf("%s/force", kustomizev1.GroupVersion.Group): kustomizev1.EnabledValue,
+	}
+
+	var pending, added []string
+	for _, u := range objects {
+		subject := ssautil.FmtUnstructured(u)
+		wasPending := slices.Contains(obj.Status.PendingRecreations, subject)
+		forced := obj.Spec.Force || ssautil.AnyInMetadata(u, forceSelector)
+		if !wasPending && !(forced && applied.Contains(object.UnstructuredToObjMetadata(u))) {
+			continue
+		}
+
+		existing := &unstructured.Unstructured{}
+		existing.SetGroupVersionKind(u.GroupVersionKind())
+		err := c.Get(ctx, client.ObjectKeyFromObject(u), existing)
+		switch {
+		case apierrors.IsNotFound(err):
+			pending = append(pending, subject)
+			if !wasPending {
+				added = append(added, subject)
+			}
+		case err != nil:
+			log.Error(err, "unable to check if the object exists", "object", subject)
+			if wasPending {
+				pending = append(pending, subject)
+			}
+		}
+	}
+	sort.Strings(pending)
+	obj.Status.PendingRecreations = pending
+
+	if len(added) > 0 {
+		msg := fmt.Sprintf("objects deleted by a forced apply could not be created again, they are applied first by the next reconciliations:\n%s",
+			strings.Join(added, "\n"))
+		log.Info(msg, "revision", revision)
+		r.event(ctx, obj, revision, eventv1.EventSeverityError, msg, nil)
+	}
+}
+
+// applyStrategy returns the apply strategy of the object, set by the first
+// override matching its kind. The objects are applied with client-side apply
+// on the clusters which don't support server-side apply.
+func applyStrategy(obj *kustomizev1.Kustomization, u *unstructured.Unstructured) string {
+	if obj.Spec.ApplyStrategy != nil {
+		gvk := u.GroupVersionKind()
+		for _, o := range obj.Spec.ApplyStrategy.Overrides {
+			if o.Kind == gvk.Kind && (o.Group == "" || o.Group == gvk.Group) {
+				return o.Strategy
+			}
+		}
+	}
+	if caps := obj.Status.ClusterCapabilities; caps != nil && !caps.ServerSideApply {
+		return kustomizev1.ClientSideApply
+	}
+	return kustomizev1.ServerSideApply
+}
+
+// hasWebhook returns true if the object is a custom resource
+// of a CRD converted by a webhook.
+func hasWebhook(webhooks map[schema.GroupKind]types.NamespacedName, u *unstructured.Unstructured) bool {
+	_, ok := webhooks[u.GroupVersionKind().GroupKind()]
+	return ok
+}
+
+// isDriftDetectionDue returns true if all the objects must be applied, either
+// because the drift detection interval has elapsed since the last full apply,
+// or because a reconciliation has been requested.
+func isDriftDetectionDue(obj *kustomizev1.Kustomization) bool {
+	if obj.Status.LastFullApplyAt == nil {
+		return true
+	}
+	if v, ok := meta.ReconcileAnnotationValue(obj.GetAnnotations()); ok && v != obj.Status.LastHandledReconcileAt {
+		return true
+	}
+	return time.Since(obj.Status.LastFullApplyAt.Time) >= obj.GetDriftDetectionInterval()
+}
+
+// isRevisionUnaffected returns true if the revision diff is enabled, the
+// artifact lists the files changed since the last applied revision, and
+// none of them is under the paths of the Kustomization. The revision is
+// never skipped when the spec changed, the previous reconciliation failed,
+// an apply is in progress, all the objects must be applied or the
+// Kustomization has overlay sources, whose changes aren't listed.
+func isRevisionUnaffected(obj *kustomizev1.Kustomization, artifact *sourcev1.Artifact) bool {
+	if obj.Spec.DifferentialApply == nil || !obj.Spec.DifferentialApply.RevisionDiff {
+		return false
+	}
+	if obj.Spec.Mode != "" && obj.Spec.Mode != kustomizev1.ApplyMode {
+		return false
+	}
+	if len(obj.Spec.OverlaySources) > 0 {
+		return false
+	}
+	if obj.Status.LastAppliedRevision == "" || artifact.HasRevision(obj.Status.LastAppliedRevision) {
+		return false
+	}
+	if obj.Generation != obj.Status.ObservedGeneration || !apimeta.IsStatusConditionTrue(obj.Status.Conditions, meta.ReadyCondition) {
+		return false
+	}
+	if obj.Status.ApplyProgress != nil || isDriftDetectionDue(obj) {
+		return false
+	}
+
+	previous, ok := artifact.Metadata[kustomizev1.PreviousRevisionMetadataKey]
+	if !ok || previous != obj.Status.LastAppliedRevision {
+		return false
+	}
+	changed, ok := artifact.Metadata[kustomizev1.ChangedPathsMetadataKey]
+	if !ok {
+		return false
+	}
+	return !changedPathsAffect(obj, changed)
+}
+
+// splitUnchanged computes the digests of the rendered objects and returns the
+// objects to apply and the objects whose digest matches the one recorded in
+// the inventory. The objects reported as unmanaged overrides, as pending
+// recreation or as flapping are always applied.
+func splitUnchanged(obj *kustomizev1.Kustomization,
+	objects []*unstructured.Unstructured,
+	fullApply bool) ([]*unstructured.Unstructured, []*unstructured.Unstructured, map[string]string, error) {
+	previous := inventory.Digests(obj.Status.Inventory)
+	overrides := make(map[string]struct{}, len(obj.Status.UnmanagedOverrides)+len(obj.Status.PendingRecreations))
+	for _, o := range obj.Status.UnmanagedOverrides {
+		overrides[o] = struct{}{}
+	}
+	for _, o := range obj.Status.PendingRecreations {
+		overrides[o] = struct{}{}
+	}
+	for _, o := range obj.Status.FlappingResources {
+		overrides[o.Object] = struct{}{}
+	}
+
+	var toApply, unchanged []*unstructured.Unstructured
+	digests := make(map[string]string, len(objects))
+	for _, u := range objects {
+		id := object.UnstructuredToObjMetadata(u).String()
+		digest, err := inventory.Digest(u)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to compute the digest of %s: %w", ssautil.FmtUnstructured(u), err)
+		}
+		digests[id] = digest
+
+		_, overridden := overrides[ssautil.FmtUnstructured(u)]
+		if !fullApply && !overridden && previous[id] == digest {
+			unchanged = append(unchanged, u)
+			continue
+		}
+		toApply = append(toApply, u)
+	}
+	return toApply, unchanged, digests, nil
+}
+
+func (r *KustomizationReconciler) checkHealth(ctx context.Context,
+	poller healthprogress.Poller,
+	patcher *patch.SerialPatcher,
+	obj *kustomizev1.Kustomization,
+	revision string,
+	isNewRevision bool,
+	drifted bool,
+	objects object.ObjMetadataSet) error {
+	if len(obj.Spec.HealthChecks) == 0 && !obj.Spec.Wait {
+		conditions.Delete(obj, kustomizev1.HealthyCondition)
+		r.stopHealthWatch(obj)
+		return nil
+	}
+
+	// The workloads never become ready in the simulation API server,
+	// which runs without the controllers of the cluster.
+	if r.SimulationConfig != nil {
+		ctrl.LoggerFrom(ctx).Info("health checks skipped in simulation")
+		conditions.Delete(obj, kustomizev1.HealthyCondition)
+		return nil
+	}
+
+	checkStart := time.Now()
+	var err error
+	if !obj.Spec.Wait {
+		objects, err = inventory.ReferenceToObjMetadataSet(obj.Spec.HealthChecks)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(objects) == 0 {
+		conditions.Delete(obj, kustomizev1.HealthyCondition)
+		r.stopHealthWatch(obj)
+		return nil
+	}
+
+	// Guard against deadlock (waiting on itself).
+	var toCheck []object.ObjMetadata
+	for _, o := range objects {
+		if o.GroupKind.Kind == kustomizev1.KustomizationKind &&
+			o.Name == obj.GetName() &&
+			o.Namespace == obj.GetNamespace() {
+			continue
+		}
+		toCheck = append(toCheck, o)
+	}
+
+	// Find the previous health check result.
+	wasHealthy := apimeta.IsStatusConditionTrue(obj.Status.Conditions, kustomizev1.HealthyCondition)
+
+	// Update status with the reconciliation progress.
+	message := fmt.Sprintf("Running health checks for revision %s with a timeout of %s", revision, obj.GetTimeout().String())
+	conditions.MarkReconciling(obj, meta.ProgressingReason, message)
+	conditions.MarkUnknown(obj, kustomizev1.HealthyCondition, meta.ProgressingReason, message)
+	if err := r.patch(ctx, obj, patcher); err != nil {
+		return fmt.Errorf("unable to update the healthy status to progressing: %w", err)
+	}
+
+	// Report the progress of the health checks while waiting.
+	log := ctrl.LoggerFrom(ctx)
+	reportProgress := func(progress string) {
+		progressMessage := fmt.Sprintf("%s: %s", message, progress)
+		conditions.MarkReconciling(obj, meta.ProgressingReason, progressMessage)
+		conditions.MarkUnknown(obj, kustomizev1.HealthyCondition, meta.ProgressingReason, progressMessage)
+		if err := r.patch(ctx, obj, patcher); err != nil {
+			log.Error(err, "unable to update the health checks progress")
+		}
+	}
 
-	for _, fieldManager := range r.DisallowedFieldManagers {
-		fieldManagers = append(fieldManagers, ssa.FieldManager{
-			Name:          fieldManager,
-			OperationType: metav1.ManagedFieldsOperationApply,
-		})
-		// to undo changes made by the controller before SSA
-		fieldManagers = append(fieldManagers, ssa.FieldManager{
-			Name:          fieldManager,
-			OperationType: metav1.ManagedFieldsOperationUpdate,
-		})
+	// Check the health with a default timeout of 30sec shorter than the reconciliation interval.
+	err = healthprogress.Wait(ctx, poller, toCheck, ssa.WaitOptions{
+		Interval: 5 * time.Second,
+		Timeout:  obj.GetTimeout(),
+		FailFast: r.FailFast,
+	}, healthCheckProgressInterval, reportProgress)
+	if err == nil {
+		err = r.FaultInjector.FailHealthCheck(obj)
+	}
+	if err != nil {
+		conditions.MarkFalse(obj, meta.ReadyCondition, kustomizev1.HealthCheckFailedReason, err.Error())
+		conditions.MarkFalse(obj, kustomizev1.HealthyCondition, kustomizev1.HealthCheckFailedReason, err.Error())
+		r.stopHealthWatch(obj)
+		return fmt.Errorf("health check failed after %s: %w", time.Since(checkStart).String(), err)
 	}
 
-	applyOpts.Cleanup = ssa.ApplyCleanupOptions{
-		Annotations: []string{
-			// remove the kubectl annotation
-			corev1.LastAppliedConfigAnnotation,
-			// remove deprecated fluxcd.io annotations
-			"kustomize.toolkit.fluxcd.io/checksum",
-			"fluxcd.io/sync-checksum",
-		},
-		Labels: []string{
-			// remove deprecated fluxcd.io labels
-			"fluxcd.io/sync-gc-mark",
-		},
-		FieldManagers: fieldManagers,
-		Exclusions: map[string]string{
-			fmt.Sprintf("%s/ssa", kustomizev1.GroupVersion.Group): kustomizev1.MergeValue,
+	// Emit recovery event if the previous health check failed.
+	msg := fmt.Sprintf("Health check passed in %s", time.Since(checkStart).String())
+	if !wasHealthy || (isNewRevision && drifted) {
+		r.event(ctx, obj, revision, eventv1.EventSeverityInfo, msg, nil)
+	}
+
+	conditions.MarkTrue(obj, kustomizev1.HealthyCondition, meta.SucceededReason, msg)
+	if err := r.patch(ctx, obj, patcher); err != nil {
+		return fmt.Errorf("unable to update the healthy status to progressing: %w", err)
+	}
+
+	r.startHealthWatch(ctx, obj, toCheck)
+
+	return nil
+}
+
+// runTests runs the smoke tests of the Kustomization in the target cluster,
+// and records their result in the TestSuccess condition. An event is emitted
+// when the tests pass after having failed.
+func (r *KustomizationReconciler) runTests(ctx context.Context,
+	kubeClient client.Client,
+	patcher *patch.SerialPatcher,
+	obj *kustomizev1.Kustomization,
+	revision string) error {
+	if len(obj.Spec.Tests) == 0 {
+		conditions.Delete(obj, kustomizev1.TestSuccessCondition)
+		return nil
+	}
+
+	// The Jobs never run in the simulation API server, which runs
+	// without the controllers of the cluster.
+	if r.SimulationConfig != nil {
+		ctrl.LoggerFrom(ctx).Info("smoke tests skipped in simulation")
+		conditions.Delete(obj, kustomizev1.TestSuccessCondition)
+		return nil
+	}
+
+	passed := conditions.IsTrue(obj, kustomizev1.TestSuccessCondition)
+	message := fmt.Sprintf("Running smoke tests for revision %s with a timeout of %s", revision, obj.GetTimeout().String())
+	conditions.MarkReconciling(obj, meta.ProgressingReason, message)
+	conditions.MarkUnknown(obj, kustomizev1.TestSuccessCondition, meta.ProgressingReason, message)
+	if err := r.patch(ctx, obj, patcher); err != nil {
+		return fmt.Errorf("unable to update the test status to progressing: %w", err)
+	}
+
+	namespace := obj.GetNamespace()
+	if obj.Spec.TargetNamespace != "" {
+		namespace = obj.Spec.TargetNamespace
+	}
+	testStart := time.Now()
+	err := smoketest.Run(ctx, kubeClient, obj.Spec.Tests, smoketest.Options{
+		Namespace: namespace,
+		Labels: map[string]string{
+			fmt.Sprintf("%s/name", kustomizev1.GroupVersion.Group):      obj.GetName(),
+			fmt.Sprintf("%s/namespace", kustomizev1.GroupVersion.Group): obj.GetNamespace(),
 		},
+		Interval: 2 * time.Second,
+		Timeout:  obj.GetTimeout(),
+	})
+	if err != nil {
+		conditions.MarkFalse(obj, kustomizev1.TestSuccessCondition, kustomizev1.TestFailedReason, err.Error())
+		return err
 	}
 
-	// contains only CRDs and Namespaces
-	var defStage []*unstructured.Unstructured
+	msg := fmt.Sprintf("%d smoke tests passed in %s", len(obj.Spec.Tests), time.Since(testStart).String())
+	if !passed {
+		r.event(ctx, obj, revision, eventv1.EventSeverityInfo, msg, nil)
+	}
+	conditions.MarkTrue(obj, kustomizev1.TestSuccessCondition, meta.SucceededReason, msg)
+	return nil
+}
 
-	// contains only Kubernetes Class types e.g.: RuntimeClass, PriorityClass,
-	// StorageClass, VolumeSnapshotClass, IngressClass, GatewayClass, ClusterClass, etc
-	var classStage []*unstructured.Unstructured
+// startHealthWatch keeps watching the health checked objects, when
+// continuous health checks are enabled.
+func (r *KustomizationReconciler) startHealthWatch(ctx context.Context,
+	obj *kustomizev1.Kustomization, objects object.ObjMetadataSet) {
+	if r.healthWatches == nil {
+		return
+	}
 
-	// contains all objects except for CRDs, Namespaces and Class type objects
-	var resStage []*unstructured.Unstructured
+	cfg, err := r.getImpersonatedRESTConfig(ctx, obj)
+	if err != nil {
+		ctrl.LoggerFrom(ctx).Error(err, "unable to watch the health of the resources")
+		return
+	}
+	dynamicClient, err := dynamic.NewForConfig(cfg)
+	if err != nil {
+		ctrl.LoggerFrom(ctx).Error(err, "unable to watch the health of the resources")
+		return
+	}
+	restMapper, err := runtimeClient.NewDynamicRESTMapper(cfg)
+	if err != nil {
+		ctrl.LoggerFrom(ctx).Error(err, "unable to watch the health of the resources")
+		return
+	}
 
-	// contains the objects' metadata after apply
-	resultSet := ssa.NewChangeSet()
+	r.healthWatches.Start(client.ObjectKeyFromObject(obj),
+		watcher.NewDefaultStatusWatcher(dynamicClient, restMapper), objects)
+}
 
-	for _, u := range objects {
-		if decryptor.IsEncryptedSecret(u) {
-			return false, nil,
-				fmt.Errorf("%s is SOPS encrypted, configuring decryption is required for this secret to be reconciled",
-					ssautil.FmtUnstructured(u))
+// stopHealthWatch stops watching the objects of the Kustomization, if any.
+func (r *KustomizationReconciler) stopHealthWatch(obj *kustomizev1.Kustomization) {
+	if r.healthWatches == nil {
+		return
+	}
+	r.healthWatches.Stop(client.ObjectKeyFromObject(obj))
+}
+
+// startReferenceWatch watches the Secrets and ConfigMaps referenced in the
+// post build substitutions, the decryption, the kubeconfig and the
+// environment, when enabled.
+func (r *KustomizationReconciler) startReferenceWatch(obj *kustomizev1.Kustomization) {
+	if r.referenceWatches == nil {
+		return
+	}
+
+	var refs []refwatch.Ref
+	if obj.Spec.PostBuild != nil {
+		for _, ref := range obj.Spec.PostBuild.SubstituteFrom {
+			refs = append(refs, refwatch.Ref{Kind: ref.Kind, Namespace: obj.GetNamespace(), Name: ref.Name})
 		}
+	}
+	if obj.Spec.Decryption != nil && obj.Spec.Decryption.SecretRef != nil {
+		refs = append(refs, refwatch.Ref{Kind: "Secret", Namespace: obj.GetNamespace(), Name: obj.Spec.Decryption.SecretRef.Name})
+	}
+	if obj.Spec.KubeConfig != nil {
+		refs = append(refs, refwatch.Ref{Kind: "Secret", Namespace: obj.GetNamespace(), Name: obj.Spec.KubeConfig.SecretRef.Name})
+	}
+	if rc := obj.Spec.RemoteCluster; rc != nil {
+		refs = append(refs, refwatch.Ref{Kind: "Secret", Namespace: obj.GetNamespace(), Name: rc.TokenSecretRef.Name})
+		if rc.CAConfigMapRef != nil {
+			refs = append(refs, refwatch.Ref{Kind: "ConfigMap", Namespace: obj.GetNamespace(), Name: rc.CAConfigMapRef.Name})
+		}
+	}
+	if obj.Spec.Environment != nil && obj.Spec.Environment.ConfigMapRef != nil {
+		ref := obj.Spec.Environment.ConfigMapRef
+		namespace := obj.GetNamespace()
+		if ref.Namespace != "" {
+			namespace = ref.Namespace
+		}
+		refs = append(refs, refwatch.Ref{Kind: "ConfigMap", Namespace: namespace, Name: ref.Name})
+	}
 
-		switch {
-		case ssautil.IsClusterDefinition(u):
-			defStage = append(defStage, u)
-		case strings.HasSuffix(u.GetKind(), "Class"):
-			classStage = append(classStage, u)
-		default:
-			resStage = append(resStage, u)
+	r.referenceWatches.Track(client.ObjectKeyFromObject(obj), refs)
+}
+
+// stopReferenceWatch stops watching the objects referenced by the Kustomization, if any.
+func (r *KustomizationReconciler) stopReferenceWatch(obj *kustomizev1.Kustomization) {
+	if r.referenceWatches == nil {
+		return
+	}
+	r.referenceWatches.Forget(client.ObjectKeyFromObject(obj))
+}
+
+// watchWebhooks watches the webhook services which couldn't be called
+// when applying the Kustomization, to apply it again as soon as they are
+// back, when enabled. The services of remote clusters are not watched.
+func (r *KustomizationReconciler) watchWebhooks(obj *kustomizev1.Kustomization, applyErr error) {
+	if r.webhookWatches == nil || obj.IsRemote() {
+		return
+	}
+	r.webhookWatches.Track(client.ObjectKeyFromObject(obj), webhookwatch.Services(applyErr))
+}
+
+// updateNamespaceReport writes the KustomizationReport of the namespace of
+// the object, with the status of the object instead of the cached one,
+// which may not include the last patch. The report is deleted along with
+// the last Kustomization of the namespace.
+func (r *KustomizationReconciler) updateNamespaceReport(ctx context.Context,
+	obj *kustomizev1.Kustomization) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var list kustomizev1.KustomizationList
+		if err := r.List(ctx, &list, client.InNamespace(obj.GetNamespace())); err != nil {
+			return fmt.Errorf("failed to list the Kustomizations: %w", err)
+		}
+		items := []kustomizev1.Kustomization{*obj}
+		for _, item := range list.Items {
+			if item.GetName() != obj.GetName() {
+				items = append(items, item)
+			}
 		}
+		status := nsreport.Summarize(items)
+
+		report := &kustomizev1.KustomizationReport{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      kustomizev1.KustomizationReportName,
+				Namespace: obj.GetNamespace(),
+			},
+		}
+		if status.Total == 0 {
+			return client.IgnoreNotFound(r.Delete(ctx, report))
+		}
+		_, err := controllerutil.CreateOrUpdate(ctx, r.Client, report, func() error {
+			report.Status = status
+			return nil
+		})
+		return err
+	})
+}
+
+// checkReconciliationGates returns a ClosedError if a closed
+// ReconciliationGate selects the object, when the gates are enabled.
+func (r *KustomizationReconciler) checkReconciliationGates(ctx context.Context,
+	obj *kustomizev1.Kustomization) error {
+	if !r.ReconciliationGates {
+		return nil
+	}
 
+	var gates kustomizev1.ReconciliationGateList
+	if err := r.List(ctx, &gates); err != nil {
+		return fmt.Errorf("failed to list the ReconciliationGates: %w", err)
 	}
+	return reconciliationgate.Check(gates.Items, obj.GetLabels())
+}
 
-	var changeSetLog strings.Builder
+// checkWebhookCircuits returns an error if the objects are intercepted by
+// a webhook whose circuit is open, when the circuit breaker is enabled. The
+// webhooks of remote clusters are not tracked.
+func (r *KustomizationReconciler) checkWebhookCircuits(obj *kustomizev1.Kustomization,
+	objects []*unstructured.Unstructured) error {
+	if r.WebhookBreaker == nil || obj.IsRemote() {
+		return nil
+	}
+	return r.WebhookBreaker.Check(r.groupResources(objects))
+}
 
-	// validate, apply and wait for CRDs and Namespaces to register
-	if len(defStage) > 0 {
-		changeSet, err := manager.ApplyAll(ctx, defStage, applyOpts)
+// recordWebhookCalls records the webhooks which couldn't be called when
+// applying the objects, and opens their circuits if they are flapping.
+// A successful apply closes the circuits of the webhooks intercepting
+// the objects.
+func (r *KustomizationReconciler) recordWebhookCalls(ctx context.Context,
+	obj *kustomizev1.Kustomization,
+	objects []*unstructured.Unstructured,
+	applyErr error) {
+	if r.WebhookBreaker == nil || obj.IsRemote() {
+		return
+	}
+
+	if applyErr == nil {
+		r.WebhookBreaker.RecordSuccess(r.groupResources(objects))
+		return
+	}
+
+	log := ctrl.LoggerFrom(ctx)
+	for _, webhook := range webhookbreaker.Webhooks(applyErr) {
+		if !r.WebhookBreaker.RecordFailure(webhook) {
+			continue
+		}
+		configuration, rules, err := r.webhookRules(ctx, webhook)
 		if err != nil {
-			return false, nil, err
+			log.Error(err, "unable to find the rules of the flapping webhook", "webhook", webhook)
+			continue
 		}
+		r.WebhookBreaker.SetRules(webhook, configuration, rules)
+		log.Info("circuit opened for the flapping webhook", "webhook", webhook, "configuration", configuration)
+	}
+}
 
-		if changeSet != nil && len(changeSet.Entries) > 0 {
-			resultSet.Append(changeSet.Entries)
+// webhookRules returns the configuration, in the 'Kind/name' format, and
+// the rules of the admission webhook with the given name.
+func (r *KustomizationReconciler) webhookRules(ctx context.Context,
+	webhook string) (string, []admissionregistrationv1.RuleWithOperations, error) {
+	var validating admissionregistrationv1.ValidatingWebhookConfigurationList
+	if err := r.apiReader.List(ctx, &validating); err != nil {
+		return "", nil, err
+	}
+	for _, c := range validating.Items {
+		for _, w := range c.Webhooks {
+			if w.Name == webhook {
+				return "ValidatingWebhookConfiguration/" + c.Name, w.Rules, nil
+			}
+		}
+	}
 
-			log.Info("server-side apply for cluster definitions completed", "output", changeSet.ToMap())
-			for _, change := range changeSet.Entries {
-				if HasChanged(change.Action) {
-					changeSetLog.WriteString(change.String() + "\n")
-				}
+	var mutating admissionregistrationv1.MutatingWebhookConfigurationList
+	if err := r.apiReader.List(ctx, &mutating); err != nil {
+		return "", nil, err
+	}
+	for _, c := range mutating.Items {
+		for _, w := range c.Webhooks {
+			if w.Name == webhook {
+				return "MutatingWebhookConfiguration/" + c.Name, w.Rules, nil
 			}
+		}
+	}
+
+	return "", nil, fmt.Errorf("webhook '%s' not found", webhook)
+}
+
+// groupResources returns the resources of the objects, the kinds which
+// are not served yet are skipped.
+func (r *KustomizationReconciler) groupResources(objects []*unstructured.Unstructured) []schema.GroupResource {
+	seen := make(map[schema.GroupResource]struct{})
+	var resources []schema.GroupResource
+	for _, u := range objects {
+		gvk := u.GroupVersionKind()
+		mapping, err := r.Client.RESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version)
+		if err != nil {
+			continue
+		}
+		gr := mapping.Resource.GroupResource()
+		if _, ok := seen[gr]; !ok {
+			seen[gr] = struct{}{}
+			resources = append(resources, gr)
+		}
+	}
+	return resources
+}
+
+// notifyWebhookRecovery enqueues the reconciliation of a Kustomization
+// when a webhook service it failed to call has ready endpoints again.
+func (r *KustomizationReconciler) notifyWebhookRecovery(ctx context.Context,
+	key types.NamespacedName, svc types.NamespacedName) {
+	ctrl.LoggerFrom(ctx).Info("webhook service recovered, enqueuing reconciliation",
+		"kustomization", key.String(), "service", svc.String())
+	_ = r.enqueue(ctx, key)
+}
+
+// enqueueDependents enqueues the Kustomizations of an ordered fan-out
+// which were waiting for the given Kustomization to be reconciled.
+func (r *KustomizationReconciler) enqueueDependents(ctx context.Context, key types.NamespacedName) {
+	for _, dependent := range r.fanOut.Done(key) {
+		ctrl.LoggerFrom(ctx).V(1).Info("dependencies reconciled, enqueuing reconciliation",
+			"kustomization", dependent.String())
+		if err := r.enqueue(ctx, dependent); err != nil {
+			return
+		}
+	}
+}
+
+// enqueue adds a reconciliation request for the given Kustomization
+// at the end of the work queue.
+func (r *KustomizationReconciler) enqueue(ctx context.Context, key types.NamespacedName) error {
+	obj := &kustomizev1.Kustomization{}
+	obj.SetName(key.Name)
+	obj.SetNamespace(key.Namespace)
+	select {
+	case r.referenceEvents <- event.GenericEvent{Object: obj}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// notifyReferenceChange enqueues the reconciliation of a Kustomization
+// when one of the objects it references changes.
+func (r *KustomizationReconciler) notifyReferenceChange(ctx context.Context,
+	key types.NamespacedName, ref refwatch.Ref) {
+	ctrl.LoggerFrom(ctx).V(1).Info("referenced object changed, enqueuing reconciliation",
+		"kustomization", key.String(), "kind", ref.Kind, "name", ref.Name)
+
+	obj := &kustomizev1.Kustomization{}
+	if err := r.Get(ctx, key, obj); err != nil {
+		return
+	}
+
+	// The health watch uses the previous credentials of the remote cluster,
+	// the reconciliation starts it again with the rotated ones.
+	if isClusterCredential(obj, ref) {
+		r.stopHealthWatch(obj)
+	}
+
+	select {
+	case r.referenceEvents <- event.GenericEvent{Object: obj}:
+	case <-ctx.Done():
+	}
+}
+
+// isClusterCredential returns true if the referenced object holds the
+// credentials of the remote cluster the Kustomization is applied to.
+func isClusterCredential(obj *kustomizev1.Kustomization, ref refwatch.Ref) bool {
+	switch {
+	case obj.Spec.KubeConfig != nil:
+		return ref.Kind == "Secret" && ref.Name == obj.Spec.KubeConfig.SecretRef.Name
+	case obj.Spec.RemoteCluster != nil:
+		rc := obj.Spec.RemoteCluster
+		return (ref.Kind == "Secret" && ref.Name == rc.TokenSecretRef.Name) ||
+			(ref.Kind == "ConfigMap" && rc.CAConfigMapRef != nil && ref.Name == rc.CAConfigMapRef.Name)
+	default:
+		return false
+	}
+}
+
+// notifyHealthChange updates the Healthy and Ready conditions when the
+// health of the watched objects changes in between reconciliations.
+func (r *KustomizationReconciler) notifyHealthChange(ctx context.Context,
+	key types.NamespacedName, healthy bool, message string) {
+	log := ctrl.LoggerFrom(ctx).WithValues("kustomization", key.String())
+
+	obj := &kustomizev1.Kustomization{}
+	if err := r.Get(ctx, key, obj); err != nil {
+		return
+	}
+
+	// Leave the status to the reconciliation in progress.
+	if obj.Spec.Suspend || conditions.IsReconciling(obj) ||
+		conditions.IsTrue(obj, kustomizev1.HealthyCondition) == healthy {
+		return
+	}
+
+	patcher := patch.NewSerialPatcher(obj, r.Client)
+	revision := obj.Status.LastAppliedRevision
+	if healthy {
+		conditions.MarkTrue(obj, kustomizev1.HealthyCondition, meta.SucceededReason, message)
+		conditions.MarkTrue(obj, meta.ReadyCondition, kustomizev1.ReconciliationSucceededReason,
+			fmt.Sprintf("Applied revision: %s", revision))
+	} else {
+		conditions.MarkFalse(obj, kustomizev1.HealthyCondition, kustomizev1.HealthCheckFailedReason, message)
+		conditions.MarkFalse(obj, meta.ReadyCondition, kustomizev1.HealthCheckFailedReason, message)
+	}
+
+	if err := r.patch(ctx, obj, patcher); err != nil {
+		log.Error(err, "unable to update the healthy status")
+		return
+	}
+	r.Metrics.RecordReadiness(ctx, obj)
+
+	if healthy {
+		log.Info(message, "revision", revision)
+		r.event(ctx, obj, revision, eventv1.EventSeverityInfo, message, nil)
+	} else {
+		log.Error(errors.New(message), "health check failed after the resources became ready", "revision", revision)
+		r.event(ctx, obj, revision, eventv1.EventSeverityError, message, nil)
+	}
+}
+
+func (r *KustomizationReconciler) prune(ctx context.Context,
+	manager *ssa.ResourceManager,
+	obj *kustomizev1.Kustomization,
+	revision string,
+	objects []*unstructured.Unstructured) (bool, error) {
+	if !obj.Spec.Prune {
+		return false, nil
+	}
+
+	log := ctrl.LoggerFrom(ctx)
+
+	opts := ssa.DeleteOptions{
+		PropagationPolicy: metav1.DeletePropagationBackground,
+		Inclusions:        manager.GetOwnerLabels(obj.Name, obj.Namespace),
+		Exclusions: map[string]string{
+			fmt.Sprintf("%s/prune", kustomizev1.GroupVersion.Group):     kustomizev1.DisabledValue,
+			fmt.Sprintf("%s/reconcile", kustomizev1.GroupVersion.Group): kustomizev1.DisabledValue,
+		},
+	}
+
+	changeSet, err := manager.DeleteAll(ctx, objects, opts)
+	if err != nil {
+		return false, err
+	}
+
+	// emit event only if the prune operation resulted in changes
+	if changeSet != nil && len(changeSet.Entries) > 0 {
+		log.Info(fmt.Sprintf("garbage collection completed: %s", changeSet.String()))
+		r.event(ctx, obj, revision, eventv1.EventSeverityInfo, changeSet.String(), nil)
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// waitForThrottle waits for the delay computed from the latency of the API
+// server the Kustomization targets, when adaptive throttling is enabled.
+func (r *KustomizationReconciler) waitForThrottle(ctx context.Context, obj *kustomizev1.Kustomization) error {
+	if r.Throttle == nil {
+		return nil
+	}
+
+	// Errors are reported by the reconciliation when building the client.
+	cfg, err := r.getRESTConfig(ctx, obj)
+	if err != nil {
+		return nil
+	}
+
+	host := throttle.Host(cfg)
+	delay := r.Throttle.Delay(host)
+	throttle.RecordDelay(host, delay)
+	if delay == 0 {
+		return nil
+	}
+
+	ctrl.LoggerFrom(ctx).Info(fmt.Sprintf("API server latency is high, delaying reconciliation by %s", delay.Round(time.Millisecond)),
+		"host", host)
+	select {
+	case <-time.After(delay):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
 
-			if err := manager.WaitForSet(changeSet.ToObjMetadataSet(), ssa.WaitOptions{
-				Interval: 2 * time.Second,
-				Timeout:  obj.GetTimeout(),
-			}); err != nil {
-				return false, nil, err
+// recordFileChanges keeps the snapshot of the files of the applied revision,
+// and records the summary of the file changes since the previous applied
+// revision for the reconciliation event. The snapshots are kept in memory,
+// no summary is recorded for the first revision applied after a restart.
+func (r *KustomizationReconciler) recordFileChanges(ctx context.Context,
+	obj *kustomizev1.Kustomization, revision string, files filediff.Snapshot) {
+	key := client.ObjectKeyFromObject(obj)
+	if files == nil {
+		r.fileSnapshots.Delete(key)
+		return
+	}
+
+	if v, ok := r.fileSnapshots.Load(key); ok {
+		previous := v.(*fileSnapshot)
+		if previous.revision != revision {
+			if changes := filediff.Compare(previous.files, files); !changes.IsEmpty() {
+				ctrl.LoggerFrom(ctx).Info("source files changed",
+					"added", changes.Added, "changed", changes.Changed, "removed", changes.Removed)
+				r.fileChanges.Store(key, fmt.Sprintf("%s under %s", changes, obj.Spec.Path))
 			}
 		}
 	}
+	r.fileSnapshots.Store(key, &fileSnapshot{revision: revision, files: files})
+}
 
-	// validate, apply and wait for Class type objects to register
-	if len(classStage) > 0 {
-		changeSet, err := manager.ApplyAll(ctx, classStage, applyOpts)
+// effectiveConfig returns the controller settings and the effective
+// configuration of the Kustomizations of the namespace, read from the cache.
+func (r *KustomizationReconciler) effectiveConfig(ctx context.Context, namespace, name string) (effectiveconfig.Report, error) {
+	report := effectiveconfig.Report{
+		Controller: effectiveconfig.Controller{
+			DefaultServiceAccount:     r.DefaultServiceAccount,
+			DefaultCommonLabels:       r.DefaultCommonLabels,
+			NoCrossNamespaceRefs:      r.NoCrossNamespaceRefs,
+			NoRemoteBases:             r.NoRemoteBases,
+			AllowLoadRestrictionsNone: r.AllowLoadRestrictionsNone,
+			DisallowedBuildOptions:    r.DisallowedBuildOptions,
+			TenantLockdown:            r.TenantLockdown,
+			TenantExemptNamespaces:    r.TenantExemptNamespaces,
+			TenantDenyClusterScoped:   r.TenantDenyClusterScoped,
+			TenantClusterScopedKinds:  r.TenantClusterScopedKinds,
+			FeatureGates:              make(map[string]bool),
+		},
+		Kustomizations: []effectiveconfig.Kustomization{},
+	}
+	if r.DefaultTimeout > 0 {
+		report.Controller.DefaultTimeout = r.DefaultTimeout.String()
+	}
+	if r.DefaultRetryInterval > 0 {
+		report.Controller.DefaultRetryInterval = r.DefaultRetryInterval.String()
+	}
+	for feature := range features.FeatureGates() {
+		enabled, err := features.Enabled(feature)
 		if err != nil {
-			return false, nil, err
+			return report, err
 		}
+		report.Controller.FeatureGates[feature] = enabled
+	}
 
-		if changeSet != nil && len(changeSet.Entries) > 0 {
-			resultSet.Append(changeSet.Entries)
+	var list kustomizev1.KustomizationList
+	if err := r.List(ctx, &list, client.InNamespace(namespace)); err != nil {
+		return report, err
+	}
+	sort.Slice(list.Items, func(i, j int) bool {
+		if list.Items[i].Namespace != list.Items[j].Namespace {
+			return list.Items[i].Namespace < list.Items[j].Namespace
+		}
+		return list.Items[i].Name < list.Items[j].Name
+	})
 
-			log.Info("server-side apply for cluster class types completed", "output", changeSet.ToMap())
-			for _, change := range changeSet.Entries {
-				if HasChanged(change.Action) {
-					changeSetLog.WriteString(change.String() + "\n")
-				}
-			}
+	for i := range list.Items {
+		if name != "" && list.Items[i].Name != name {
+			continue
+		}
+		obj := list.Items[i].DeepCopy()
+		r.setDefaults(obj)
+		entry := effectiveconfig.Kustomization{
+			Name:      obj.GetName(),
+			Namespace: obj.GetNamespace(),
+		}
+		if err := r.checkTenancy(obj); err != nil {
+			entry.Errors = append(entry.Errors, err.Error())
+		}
+		buildObj, err := r.applyEnvironment(ctx, obj)
+		if err != nil {
+			entry.Errors = append(entry.Errors, err.Error())
+			buildObj = obj
+		}
 
-			if err := manager.WaitForSet(changeSet.ToObjMetadataSet(), ssa.WaitOptions{
-				Interval: 2 * time.Second,
-				Timeout:  obj.GetTimeout(),
-			}); err != nil {
-				return false, nil, err
-			}
+		serviceAccount := r.DefaultServiceAccount
+		if buildObj.Spec.ServiceAccountName != "" {
+			serviceAccount = buildObj.Spec.ServiceAccountName
 		}
+		mode := buildObj.Spec.Mode
+		if mode == "" {
+			mode = kustomizev1.ApplyMode
+		}
+		entry.Spec = buildObj.Spec
+		entry.Effective = effectiveconfig.Settings{
+			Interval:           buildObj.GetRequeueAfter().String(),
+			Timeout:            buildObj.GetTimeout().String(),
+			RetryInterval:      buildObj.GetRetryInterval().String(),
+			ServiceAccountName: serviceAccount,
+			LoadRestrictions:   buildObj.GetLoadRestrictions(),
+			Mode:               mode,
+		}
+		report.Kustomizations = append(report.Kustomizations, entry)
 	}
+	return report, nil
+}
 
-	// sort by kind, validate and apply all the others objects
-	sort.Sort(ssa.SortableUnstructureds(resStage))
-	if len(resStage) > 0 {
-		changeSet, err := manager.ApplyAll(ctx, resStage, applyOpts)
-		if err != nil {
-			return false, nil, fmt.Errorf("%w\n%s", err, changeSetLog.String())
+// transformConfig marks the ConfigMaps and Secrets as immutable and rotates
+// them, then annotates the pod templates with the checksum of their config,
+// and removes the fields ignored by the drift detection, according to the
+// spec of the Kustomization.
+func transformConfig(obj *kustomizev1.Kustomization, objects []*unstructured.Unstructured) error {
+	if obj.Spec.ImmutableConfig != nil {
+		if err := immutableconfig.Apply(objects, obj.GetImmutableConfigRotation()); err != nil {
+			return fmt.Errorf("failed to set immutable config: %w", err)
 		}
+	}
+	if obj.Spec.RolloutOnConfigChange {
+		if err := configchecksum.Set(objects); err != nil {
+			return fmt.Errorf("failed to set config checksums: %w", err)
+		}
+	}
+	if obj.Spec.DriftDetection != nil {
+		if _, err := driftignore.Apply(obj.Spec.DriftDetection.Ignore, objects); err != nil {
+			return fmt.Errorf("failed to ignore the drift detection fields: %w", err)
+		}
+	}
+	return nil
+}
 
-		if changeSet != nil && len(changeSet.Entries) > 0 {
-			resultSet.Append(changeSet.Entries)
-
-			log.Info("server-side apply completed", "output", changeSet.ToMap(), "revision", revision)
-			for _, change := range changeSet.Entries {
-				if HasChanged(change.Action) {
-					changeSetLog.WriteString(change.String() + "\n")
-				}
-			}
+// buildSource builds the source archive of the request with the spec of the
+// Kustomization, as the reconciliation does up to the apply. The Secrets
+// designated for the external store are not materialized, as it writes to
+// the store, and the SOPS encrypted files are not decrypted, as the
+// decryption would use the keys of the controller, e.g. its KMS workload
+// identity and the age keys of '--sops-age-key-dir', which would allow the
+// callers to read the encrypted files of the other tenants. The remote bases
+// and files are denied, so that the callers can't make the controller send
+// requests from within the cluster.
+func (r *KustomizationReconciler) buildSource(ctx context.Context, req buildservice.Request) ([]*unstructured.Unstructured, error) {
+	obj := &kustomizev1.Kustomization{}
+	if err := r.Get(ctx, req.Kustomization, obj); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, buildservice.ErrNotFound
 		}
+		return nil, err
+	}
+	r.setDefaults(obj)
+	if err := r.checkTenancy(obj); err != nil {
+		return nil, err
 	}
 
-	// emit event only if the server-side apply resulted in changes
-	applyLog := strings.TrimSuffix(changeSetLog.String(), "\n")
-	if applyLog != "" {
-		r.event(obj, revision, eventv1.EventSeverityInfo, applyLog, nil)
+	if err := r.WorkDirs.CheckQuota(); err != nil {
+		return nil, err
 	}
+	tmpDir, err := MkdirTempAbs(r.WorkDirs.Root(), workdir.Prefix)
+	if err != nil {
+		return nil, fmt.Errorf("tmp dir error: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
 
-	return applyLog != "", resultSet, nil
-}
+	if err := tar.Untar(req.Archive, tmpDir); err != nil {
+		return nil, fmt.Errorf("failed to extract the source archive: %w", err)
+	}
 
-func (r *KustomizationReconciler) checkHealth(ctx context.Context,
-	manager *ssa.ResourceManager,
-	patcher *patch.SerialPatcher,
-	obj *kustomizev1.Kustomization,
-	revision string,
-	isNewRevision bool,
-	drifted bool,
-	objects object.ObjMetadataSet) error {
-	if len(obj.Spec.HealthChecks) == 0 && !obj.Spec.Wait {
-		conditions.Delete(obj, kustomizev1.HealthyCondition)
-		return nil
+	buildObj, err := r.applyEnvironment(ctx, obj)
+	if err != nil {
+		return nil, err
+	}
+	buildObj = buildObj.DeepCopy()
+	buildObj.Spec.Decryption = nil
+	if req.Path != "" {
+		buildObj.Spec.Path = req.Path
+	} else if buildObj, err = r.expandPath(ctx, buildObj); err != nil {
+		return nil, err
+	}
+	dirPath, err := securejoin.SecureJoin(tmpDir, buildObj.Spec.Path)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.removeIgnored(ctx, buildObj, tmpDir, dirPath); err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(dirPath); err != nil {
+		return nil, fmt.Errorf("kustomization path not found: %w", err)
 	}
 
-	checkStart := time.Now()
-	var err error
-	if !obj.Spec.Wait {
-		objects, err = inventory.ReferenceToObjMetadataSet(obj.Spec.HealthChecks)
+	k, err := runtime.DefaultUnstructuredConverter.ToUnstructured(buildObj)
+	if err != nil {
+		return nil, err
+	}
+	if len(buildObj.Spec.ComponentToggles) > 0 {
+		components, err := r.components(ctx, buildObj)
+		if err == nil {
+			err = unstructured.SetNestedStringSlice(k, components, "spec", "components")
+		}
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
-
-	if len(objects) == 0 {
-		conditions.Delete(obj, kustomizev1.HealthyCondition)
-		return nil
+	if err := r.generate(unstructured.Unstructured{Object: k}, tmpDir, dirPath); err != nil {
+		return nil, err
+	}
+	resources, err := r.build(ctx, buildObj, unstructured.Unstructured{Object: k}, tmpDir, dirPath, true)
+	if err != nil {
+		return nil, err
+	}
+	objects, err := ssautil.ReadObjects(bytes.NewReader(resources))
+	if err != nil {
+		return nil, err
 	}
 
-	// Guard against deadlock (waiting on itself).
-	var toCheck []object.ObjMetadata
-	for _, o := range objects {
-		if o.GroupKind.Kind == kustomizev1.KustomizationKind &&
-			o.Name == obj.GetName() &&
-			o.Namespace == obj.GetNamespace() {
-			continue
-		}
-		toCheck = append(toCheck, o)
+	if err := r.mutateObjects(ctx, obj, objects); err != nil {
+		return nil, err
+	}
+	if err := transformConfig(obj, objects); err != nil {
+		return nil, err
 	}
 
-	// Find the previous health check result.
-	wasHealthy := apimeta.IsStatusConditionTrue(obj.Status.Conditions, kustomizev1.HealthyCondition)
+	resourceManager := ssa.NewResourceManager(r.Client, nil, ssa.Owner{
+		Field: r.ControllerName,
+		Group: kustomizev1.GroupVersion.Group,
+	})
+	if err := r.prepare(ctx, resourceManager, obj, objects); err != nil {
+		return nil, err
+	}
+	resourceManager.SetOwnerLabels(objects, obj.GetName(), obj.GetNamespace())
+	return objects, nil
+}
 
-	// Update status with the reconciliation progress.
-	message := fmt.Sprintf("Running health checks for revision %s with a timeout of %s", revision, obj.GetTimeout().String())
-	conditions.MarkReconciling(obj, meta.ProgressingReason, message)
-	conditions.MarkUnknown(obj, kustomizev1.HealthyCondition, meta.ProgressingReason, message)
-	if err := r.patch(ctx, obj, patcher); err != nil {
-		return fmt.Errorf("unable to update the healthy status to progressing: %w", err)
+// checkTenancy returns an error if the tenancy lockdown is enabled and the
+// Kustomization, in a namespace which is not exempted, does not specify a
+// service account or targets a remote cluster.
+func (r *KustomizationReconciler) checkTenancy(obj *kustomizev1.Kustomization) error {
+	if !r.TenantLockdown || slices.Contains(r.TenantExemptNamespaces, obj.GetNamespace()) {
+		return nil
 	}
 
-	// Check the health with a default timeout of 30sec shorter than the reconciliation interval.
-	if err := manager.WaitForSet(toCheck, ssa.WaitOptions{
-		Interval: 5 * time.Second,
-		Timeout:  obj.GetTimeout(),
-		FailFast: r.FailFast,
-	}); err != nil {
-		conditions.MarkFalse(obj, meta.ReadyCondition, kustomizev1.HealthCheckFailedReason, err.Error())
-		conditions.MarkFalse(obj, kustomizev1.HealthyCondition, kustomizev1.HealthCheckFailedReason, err.Error())
-		return fmt.Errorf("health check failed after %s: %w", time.Since(checkStart).String(), err)
+	if obj.Spec.ServiceAccountName == "" {
+		return fmt.Errorf("tenancy lockdown: '.spec.serviceAccountName' is required in namespace '%s'", obj.GetNamespace())
 	}
 
-	// Emit recovery event if the previous health check failed.
-	msg := fmt.Sprintf("Health check passed in %s", time.Since(checkStart).String())
-	if !wasHealthy || (isNewRevision && drifted) {
-		r.event(obj, revision, eventv1.EventSeverityInfo, msg, nil)
+	if obj.Spec.KubeConfig != nil {
+		return fmt.Errorf("tenancy lockdown: '.spec.kubeConfig' is not allowed in namespace '%s'", obj.GetNamespace())
 	}
 
-	conditions.MarkTrue(obj, kustomizev1.HealthyCondition, meta.SucceededReason, msg)
-	if err := r.patch(ctx, obj, patcher); err != nil {
-		return fmt.Errorf("unable to update the healthy status to progressing: %w", err)
+	if obj.Spec.RemoteCluster != nil {
+		return fmt.Errorf("tenancy lockdown: '.spec.remoteCluster' is not allowed in namespace '%s'", obj.GetNamespace())
 	}
 
 	return nil
 }
 
-func (r *KustomizationReconciler) prune(ctx context.Context,
-	manager *ssa.ResourceManager,
+// isTenant returns true if the Kustomization impersonates a service account
+// outside the namespaces exempted from the tenant lockdown.
+func (r *KustomizationReconciler) isTenant(obj *kustomizev1.Kustomization) bool {
+	if slices.Contains(r.TenantExemptNamespaces, obj.GetNamespace()) {
+		return false
+	}
+	return obj.Spec.ServiceAccountName != "" || r.DefaultServiceAccount != ""
+}
+
+// checkClusterScoped emits an event when the cluster-scoped objects of the
+// tenant change from the previous ones, and returns an error listing the
+// objects of the kinds which are not allowed, when they are denied to the
+// tenants.
+func (r *KustomizationReconciler) checkClusterScoped(ctx context.Context,
 	obj *kustomizev1.Kustomization,
 	revision string,
-	objects []*unstructured.Unstructured) (bool, error) {
-	if !obj.Spec.Prune {
-		return false, nil
+	previous []string) error {
+	current := obj.Status.ClusterScopedResources
+	if len(current) > 0 && !slices.Equal(current, previous) {
+		msg := fmt.Sprintf("cluster-scoped objects applied by the tenant:\n%s", strings.Join(current, "\n"))
+		ctrl.LoggerFrom(ctx).Info(msg, "revision", revision)
+		r.event(ctx, obj, revision, eventv1.EventSeverityInfo, msg, nil)
 	}
 
-	log := ctrl.LoggerFrom(ctx)
+	if !r.TenantDenyClusterScoped {
+		return nil
+	}
+	var denied []string
+	for _, id := range current {
+		kind, _, _ := strings.Cut(id, "/")
+		if !slices.Contains(r.TenantClusterScopedKinds, kind) {
+			denied = append(denied, id)
+		}
+	}
+	if len(denied) > 0 {
+		return fmt.Errorf("tenancy lockdown: cluster-scoped objects are not allowed in namespace '%s': %s",
+			obj.GetNamespace(), strings.Join(denied, ", "))
+	}
+	return nil
+}
 
-	opts := ssa.DeleteOptions{
-		PropagationPolicy: metav1.DeletePropagationBackground,
-		Inclusions:        manager.GetOwnerLabels(obj.Name, obj.Namespace),
-		Exclusions: map[string]string{
-			fmt.Sprintf("%s/prune", kustomizev1.GroupVersion.Group):     kustomizev1.DisabledValue,
-			fmt.Sprintf("%s/reconcile", kustomizev1.GroupVersion.Group): kustomizev1.DisabledValue,
-		},
+// checkSpec returns the human error patterns found in the spec, with the
+// suggestions to fix them. The health checks are checked against the kinds
+// of the local cluster only.
+func (r *KustomizationReconciler) checkSpec(obj *kustomizev1.Kustomization) []string {
+	var mapper apimeta.RESTMapper
+	if !obj.IsRemote() {
+		mapper = r.Client.RESTMapper()
 	}
 
-	changeSet, err := manager.DeleteAll(ctx, objects, opts)
+	var violations []string
+	for _, v := range speccheck.Check(obj, mapper) {
+		violations = append(violations, v.String())
+	}
+	return violations
+}
+
+// setDefaults sets the timeout, the retry interval and the common labels
+// configured at the controller level when the Kustomization does not
+// specify them.
+func (r *KustomizationReconciler) setDefaults(obj *kustomizev1.Kustomization) {
+	if obj.Spec.Timeout == nil && r.DefaultTimeout > 0 {
+		obj.Spec.Timeout = &metav1.Duration{Duration: r.DefaultTimeout}
+	}
+	if obj.Spec.RetryInterval == nil && r.DefaultRetryInterval > 0 {
+		obj.Spec.RetryInterval = &metav1.Duration{Duration: r.DefaultRetryInterval}
+	}
+
+	if len(r.DefaultCommonLabels) > 0 {
+		if obj.Spec.CommonMetadata == nil {
+			obj.Spec.CommonMetadata = &kustomizev1.CommonMetadata{}
+		}
+		if obj.Spec.CommonMetadata.Labels == nil {
+			obj.Spec.CommonMetadata.Labels = make(map[string]string, len(r.DefaultCommonLabels))
+		}
+		for k, v := range r.DefaultCommonLabels {
+			if _, ok := obj.Spec.CommonMetadata.Labels[k]; !ok {
+				obj.Spec.CommonMetadata.Labels[k] = v
+			}
+		}
+	}
+}
+
+// retainGenerations removes from the stale objects the most recent previous
+// generations of the ConfigMaps and Secrets created by kustomize generators
+// with a name suffix hash, and adds them to the inventory.
+func (r *KustomizationReconciler) retainGenerations(ctx context.Context,
+	manager *ssa.ResourceManager,
+	obj *kustomizev1.Kustomization,
+	objects []*unstructured.Unstructured) ([]*unstructured.Unstructured, error) {
+	if !obj.Spec.Prune || obj.Spec.RetainedGenerations <= 0 {
+		return objects, nil
+	}
+
+	generations, err := inventory.PreviousGenerations(objects, obj.Status.Inventory)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
 
-	// emit event only if the prune operation resulted in changes
-	if changeSet != nil && len(changeSet.Entries) > 0 {
-		log.Info(fmt.Sprintf("garbage collection completed: %s", changeSet.String()))
-		r.event(obj, revision, eventv1.EventSeverityInfo, changeSet.String(), nil)
-		return true, nil
+	var existing []*unstructured.Unstructured
+	for _, u := range generations {
+		existingObj := &unstructured.Unstructured{}
+		existingObj.SetGroupVersionKind(u.GroupVersionKind())
+		if err := manager.Client().Get(ctx, client.ObjectKeyFromObject(u), existingObj); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to get %s: %w", ssautil.FmtUnstructured(u), err)
+		}
+		existing = append(existing, existingObj)
 	}
 
-	return false, nil
+	retained := inventory.SelectRetained(existing, obj.Spec.RetainedGenerations)
+	if len(retained) == 0 {
+		return objects, nil
+	}
+	inventory.AddObjects(obj.Status.Inventory, retained)
+
+	ids := make(map[string]struct{}, len(retained))
+	for _, u := range retained {
+		ids[ssautil.FmtUnstructured(u)] = struct{}{}
+	}
+	stale := make([]*unstructured.Unstructured, 0, len(objects))
+	for _, u := range objects {
+		if _, ok := ids[ssautil.FmtUnstructured(u)]; !ok {
+			stale = append(stale, u)
+		}
+	}
+	return stale, nil
 }
 
 func (r *KustomizationReconciler) finalize(ctx context.Context,
 	obj *kustomizev1.Kustomization) (ctrl.Result, error) {
 	log := ctrl.LoggerFrom(ctx)
+
+	// Wait for the dependents to be deleted before pruning the objects.
+	if obj.Spec.DeleteAfterDependents {
+		dependents, err := r.listDependents(ctx, obj)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if len(dependents) > 0 {
+			msg := fmt.Sprintf("Deletion waits for the dependents to be deleted: %s, retrying in %s",
+				strings.Join(dependents, ", "), r.requeueDependency.String())
+			conditions.MarkFalse(obj, meta.ReadyCondition, kustomizev1.DependentsNotDeletedReason, msg)
+			log.Info(msg)
+			return ctrl.Result{RequeueAfter: r.requeueDependency}, nil
+		}
+	}
 	if obj.Spec.Prune &&
 		!obj.Spec.Suspend &&
 		obj.Status.Inventory != nil &&
@@ -968,7 +4828,7 @@ func (r *KustomizationReconciler) finalize(ctx context.Context,
 			obj.GetNamespace(),
 		)
 		if impersonation.CanImpersonate(ctx) {
-			kubeClient, _, err := impersonation.GetClient(ctx)
+			kubeClient, _, err := r.getClusterClient(ctx, obj)
 			if err != nil {
 				return ctrl.Result{}, err
 			}
@@ -989,19 +4849,28 @@ func (r *KustomizationReconciler) finalize(ctx context.Context,
 
 			changeSet, err := resourceManager.DeleteAll(ctx, objects, opts)
 			if err != nil {
-				r.event(obj, obj.Status.LastAppliedRevision, eventv1.EventSeverityError, "pruning for deleted resource failed", nil)
+				r.event(ctx, obj, obj.Status.LastAppliedRevision, eventv1.EventSeverityError, "pruning for deleted resource failed", nil)
 				// Return the error so we retry the failed garbage collection
 				return ctrl.Result{}, err
 			}
 
 			if changeSet != nil && len(changeSet.Entries) > 0 {
-				r.event(obj, obj.Status.LastAppliedRevision, eventv1.EventSeverityInfo, changeSet.String(), nil)
+				r.event(ctx, obj, obj.Status.LastAppliedRevision, eventv1.EventSeverityInfo, changeSet.String(), nil)
+			}
+
+			// Delete the ConfigMap of the inventory export along with the objects.
+			if obj.Spec.InventoryExport != nil {
+				key := inventoryExportKey(obj)
+				cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace}}
+				if err := kubeClient.Delete(ctx, cm); client.IgnoreNotFound(err) != nil {
+					return ctrl.Result{}, fmt.Errorf("failed to delete the inventory ConfigMap '%s': %w", key, err)
+				}
 			}
 		} else {
 			// when the account to impersonate is gone, log the stale objects and continue with the finalization
 			msg := fmt.Sprintf("unable to prune objects: \n%s", ssautil.FmtUnstructuredList(objects))
 			log.Error(fmt.Errorf("skiping pruning, failed to find account to impersonate"), msg)
-			r.event(obj, obj.Status.LastAppliedRevision, eventv1.EventSeverityError, msg, nil)
+			r.event(ctx, obj, obj.Status.LastAppliedRevision, eventv1.EventSeverityError, msg, nil)
 		}
 	}
 
@@ -1011,7 +4880,63 @@ func (r *KustomizationReconciler) finalize(ctx context.Context,
 	return ctrl.Result{}, nil
 }
 
-func (r *KustomizationReconciler) event(obj *kustomizev1.Kustomization,
+// exportInventory records the inventory of the Kustomization into the
+// ConfigMap of its inventory export, if any.
+func (r *KustomizationReconciler) exportInventory(ctx context.Context,
+	manager *ssa.ResourceManager,
+	obj *kustomizev1.Kustomization) error {
+	if obj.Spec.InventoryExport == nil {
+		return nil
+	}
+
+	key := inventoryExportKey(obj)
+	cm, err := inventory.ExportConfigMap(obj.Status.Inventory, key.Name, key.Namespace)
+	if err != nil {
+		return fmt.Errorf("failed to export the inventory: %w", err)
+	}
+	u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(cm)
+	if err != nil {
+		return fmt.Errorf("failed to export the inventory: %w", err)
+	}
+	if _, err := manager.Apply(ctx, &unstructured.Unstructured{Object: u}, ssa.DefaultApplyOptions()); err != nil {
+		return fmt.Errorf("failed to export the inventory to ConfigMap '%s': %w", key, err)
+	}
+	return nil
+}
+
+// inventoryExportKey returns the name and namespace of the ConfigMap
+// of the inventory export.
+func inventoryExportKey(obj *kustomizev1.Kustomization) types.NamespacedName {
+	key := types.NamespacedName{
+		Name:      obj.Spec.InventoryExport.Name,
+		Namespace: obj.Spec.InventoryExport.Namespace,
+	}
+	if key.Namespace == "" {
+		key.Namespace = obj.GetNamespace()
+	}
+	return key
+}
+
+// listDependents returns the namespaced names of the Kustomizations
+// which depend on the given one, sorted alphabetically.
+func (r *KustomizationReconciler) listDependents(ctx context.Context,
+	obj *kustomizev1.Kustomization) ([]string, error) {
+	var list kustomizev1.KustomizationList
+	if err := r.List(ctx, &list, client.MatchingFields{
+		dependsOnIndexKey: client.ObjectKeyFromObject(obj).String(),
+	}); err != nil {
+		return nil, fmt.Errorf("failed to list the dependents: %w", err)
+	}
+	dependents := make([]string, 0, len(list.Items))
+	for i := range list.Items {
+		dependents = append(dependents, client.ObjectKeyFromObject(&list.Items[i]).String())
+	}
+	sort.Strings(dependents)
+	return dependents, nil
+}
+
+func (r *KustomizationReconciler) event(ctx context.Context,
+	obj *kustomizev1.Kustomization,
 	revision, severity, msg string,
 	metadata map[string]string) {
 	if metadata == nil {
@@ -1020,6 +4945,12 @@ func (r *KustomizationReconciler) event(obj *kustomizev1.Kustomization,
 	if revision != "" {
 		metadata[kustomizev1.GroupVersion.Group+"/revision"] = revision
 	}
+	// Correlate the event with the log lines of the reconciliation.
+	if id := controller.ReconcileIDFromContext(ctx); id != "" {
+		metadata[kustomizev1.GroupVersion.Group+"/reconcile-id"] = string(id)
+	}
+	// Describe the commit of the revision.
+	commitinfo.FromContext(ctx).Annotate(metadata, revision)
 
 	reason := severity
 	conditions.GetReason(obj, meta.ReadyCondition)
@@ -1071,6 +5002,8 @@ func (r *KustomizationReconciler) patch(ctx context.Context,
 	patchOpts := []patch.Option{}
 	ownedConditions := []string{
 		kustomizev1.HealthyCondition,
+		kustomizev1.ChildrenReadyCondition,
+		kustomizev1.TestSuccessCondition,
 		meta.ReadyCondition,
 		meta.ReconcilingCondition,
 		meta.StalledCondition,
@@ -1081,6 +5014,14 @@ func (r *KustomizationReconciler) patch(ctx context.Context,
 		patch.WithFieldOwner(r.statusManager),
 	)
 
+	// Move the verbose status sections to a ConfigMap if the object
+	// is too large, and keep them in memory for the next patches.
+	restore, err := r.trimStatus(ctx, obj)
+	if err != nil {
+		return err
+	}
+	defer restore()
+
 	// Patch the object status, conditions and finalizers.
 	if err := patcher.Patch(ctx, obj, patchOpts...); err != nil {
 		if !obj.GetDeletionTimestamp().IsZero() {
@@ -0,0 +1,129 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/fluxcd/pkg/testserver"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+)
+
+func TestKustomizationReconciler_Defaults(t *testing.T) {
+	g := NewWithT(t)
+	id := "defaults-" + randStringRunes(5)
+	revision := "v1.0.0"
+
+	reconciler.DefaultTimeout = 3 * time.Minute
+	reconciler.DefaultRetryInterval = 30 * time.Second
+	reconciler.DefaultCommonLabels = map[string]string{
+		"team":  "platform",
+		"owner": "platform",
+	}
+	defer func() {
+		reconciler.DefaultTimeout = 0
+		reconciler.DefaultRetryInterval = 0
+		reconciler.DefaultCommonLabels = nil
+	}()
+
+	err := createNamespace(id)
+	g.Expect(err).NotTo(HaveOccurred(), "failed to create test namespace")
+
+	artifact, err := testServer.ArtifactFromFiles([]testserver.File{
+		{
+			Name: "config.yaml",
+			Body: fmt.Sprintf(`---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: %[1]s
+data:
+  key: val
+`, id),
+		},
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	repositoryName := types.NamespacedName{
+		Name:      fmt.Sprintf("defaults-%s", randStringRunes(5)),
+		Namespace: id,
+	}
+
+	err = applyGitRepository(repositoryName, artifact, revision)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	kustomization := &kustomizev1.Kustomization{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("defaults-%s", randStringRunes(5)),
+			Namespace: id,
+		},
+		Spec: kustomizev1.KustomizationSpec{
+			Interval: metav1.Duration{Duration: reconciliationInterval},
+			Path:     "./",
+			SourceRef: kustomizev1.CrossNamespaceSourceReference{
+				Name:      repositoryName.Name,
+				Namespace: repositoryName.Namespace,
+				Kind:      sourcev1.GitRepositoryKind,
+			},
+			CommonMetadata: &kustomizev1.CommonMetadata{
+				Labels: map[string]string{
+					"owner": id,
+				},
+			},
+			TargetNamespace: id,
+		},
+	}
+
+	g.Expect(k8sClient.Create(context.Background(), kustomization)).To(Succeed())
+
+	resultK := &kustomizev1.Kustomization{}
+	g.Eventually(func() bool {
+		_ = k8sClient.Get(context.Background(), client.ObjectKeyFromObject(kustomization), resultK)
+		return isReconcileSuccess(resultK)
+	}, timeout, time.Second).Should(BeTrue())
+
+	t.Run("sets the default labels", func(t *testing.T) {
+		g := NewWithT(t)
+		var cm corev1.ConfigMap
+		g.Expect(k8sClient.Get(context.Background(), client.ObjectKey{Name: id, Namespace: id}, &cm)).To(Succeed())
+		g.Expect(cm.GetLabels()).To(HaveKeyWithValue("team", "platform"))
+		g.Expect(cm.GetLabels()).To(HaveKeyWithValue("owner", id))
+	})
+
+	t.Run("does not persist the defaults", func(t *testing.T) {
+		g := NewWithT(t)
+		g.Expect(resultK.Spec.Timeout).To(BeNil())
+		g.Expect(resultK.Spec.RetryInterval).To(BeNil())
+		g.Expect(resultK.Spec.CommonMetadata.Labels).To(Equal(map[string]string{"owner": id}))
+	})
+
+	t.Run("records the effective values", func(t *testing.T) {
+		g := NewWithT(t)
+		g.Expect(resultK.Status.EffectiveTimeout).To(Equal(&metav1.Duration{Duration: 3 * time.Minute}))
+		g.Expect(resultK.Status.EffectiveRetryInterval).To(Equal(&metav1.Duration{Duration: 30 * time.Second}))
+	})
+}
@@ -26,6 +26,7 @@ import (
 	"github.com/fluxcd/pkg/testserver"
 	sourcev1 "github.com/fluxcd/source-controller/api/v1"
 	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	apimeta "k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
@@ -182,5 +183,209 @@ spec:
 			ready := apimeta.FindStatusCondition(resultK.Status.Conditions, meta.ReadyCondition)
 			return ready.Reason == kustomizev1.DependencyNotReadyReason
 		}, timeout, time.Second).Should(BeTrue())
+
+		g.Expect(resultK.Status.Dependencies).To(HaveLen(1))
+		dep := resultK.Status.Dependencies[0]
+		g.Expect(dep.Name).To(Equal("root"))
+		g.Expect(dep.Namespace).To(Equal(id))
+		g.Expect(dep.Ready).To(BeFalse())
+		g.Expect(dep.Message).To(ContainSubstring("not found"))
+		g.Expect(dep.WaitingSince).ToNot(BeNil())
+	})
+}
+
+func TestKustomizationReconciler_DeleteAfterDependents(t *testing.T) {
+	g := NewWithT(t)
+	id := "dep-" + randStringRunes(5)
+	revision := "v1.0.0"
+
+	err := createNamespace(id)
+	g.Expect(err).NotTo(HaveOccurred(), "failed to create test namespace")
+
+	err = createKubeConfigSecret(id)
+	g.Expect(err).NotTo(HaveOccurred(), "failed to create kubeconfig secret")
+
+	artifact, err := testServer.ArtifactFromFiles([]testserver.File{
+		{
+			Name: "config.yaml",
+			Body: `---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+data:
+  key: value
+`,
+		},
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	repositoryName := types.NamespacedName{
+		Name:      fmt.Sprintf("dep-%s", randStringRunes(5)),
+		Namespace: id,
+	}
+	g.Expect(applyGitRepository(repositoryName, artifact, revision)).To(Succeed())
+
+	newKustomization := func(name string, dependsOn ...string) *kustomizev1.Kustomization {
+		k := &kustomizev1.Kustomization{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: id,
+			},
+			Spec: kustomizev1.KustomizationSpec{
+				Interval: metav1.Duration{Duration: reconciliationInterval},
+				Path:     "./",
+				KubeConfig: &meta.KubeConfigReference{
+					SecretRef: meta.SecretKeyReference{
+						Name: "kubeconfig",
+					},
+				},
+				SourceRef: kustomizev1.CrossNamespaceSourceReference{
+					Name:      repositoryName.Name,
+					Namespace: repositoryName.Namespace,
+					Kind:      sourcev1.GitRepositoryKind,
+				},
+				TargetNamespace:       name,
+				Prune:                 true,
+				DeleteAfterDependents: true,
+			},
+		}
+		for _, d := range dependsOn {
+			k.Spec.DependsOn = append(k.Spec.DependsOn, meta.NamespacedObjectReference{Name: d})
+		}
+		return k
+	}
+
+	for _, ns := range []string{"infra-" + id, "apps-" + id} {
+		g.Expect(createNamespace(ns)).To(Succeed())
+	}
+	infra := newKustomization("infra-" + id)
+	apps := newKustomization("apps-"+id, infra.Name)
+	g.Expect(k8sClient.Create(context.Background(), infra)).To(Succeed())
+	g.Expect(k8sClient.Create(context.Background(), apps)).To(Succeed())
+
+	for _, k := range []*kustomizev1.Kustomization{infra, apps} {
+		g.Eventually(func() bool {
+			var obj kustomizev1.Kustomization
+			_ = k8sClient.Get(context.Background(), client.ObjectKeyFromObject(k), &obj)
+			return isReconcileSuccess(&obj)
+		}, timeout, time.Second).Should(BeTrue())
+	}
+
+	t.Run("waits for the dependents to be deleted", func(t *testing.T) {
+		g := NewWithT(t)
+		g.Expect(k8sClient.Delete(context.Background(), infra)).To(Succeed())
+
+		g.Eventually(func() bool {
+			var obj kustomizev1.Kustomization
+			_ = k8sClient.Get(context.Background(), client.ObjectKeyFromObject(infra), &obj)
+			ready := apimeta.FindStatusCondition(obj.Status.Conditions, meta.ReadyCondition)
+			return ready != nil && ready.Reason == kustomizev1.DependentsNotDeletedReason
+		}, timeout, time.Second).Should(BeTrue())
+
+		var obj kustomizev1.Kustomization
+		g.Expect(k8sClient.Get(context.Background(), client.ObjectKeyFromObject(infra), &obj)).To(Succeed())
+		g.Expect(apimeta.FindStatusCondition(obj.Status.Conditions, meta.ReadyCondition).Message).
+			To(ContainSubstring(client.ObjectKeyFromObject(apps).String()))
+	})
+
+	t.Run("finalizes after the dependents are deleted", func(t *testing.T) {
+		g := NewWithT(t)
+
+		g.Expect(k8sClient.Delete(context.Background(), apps)).To(Succeed())
+
+		g.Eventually(func() bool {
+			var obj kustomizev1.Kustomization
+			err := k8sClient.Get(context.Background(), client.ObjectKeyFromObject(infra), &obj)
+			return apierrors.IsNotFound(err)
+		}, timeout, time.Second).Should(BeTrue())
+	})
+}
+
+func TestKustomizationReconciler_CircularDependency(t *testing.T) {
+	g := NewWithT(t)
+	id := "dep-" + randStringRunes(5)
+	revision := "v1.0.0"
+
+	err := createNamespace(id)
+	g.Expect(err).NotTo(HaveOccurred(), "failed to create test namespace")
+
+	artifact, err := testServer.ArtifactFromFiles([]testserver.File{
+		{
+			Name: "config.yaml",
+			Body: `---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+data:
+  key: value
+`,
+		},
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	repositoryName := types.NamespacedName{
+		Name:      fmt.Sprintf("dep-%s", randStringRunes(5)),
+		Namespace: id,
+	}
+	g.Expect(applyGitRepository(repositoryName, artifact, revision)).To(Succeed())
+
+	newKustomization := func(name, dependsOn string) *kustomizev1.Kustomization {
+		return &kustomizev1.Kustomization{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: id,
+			},
+			Spec: kustomizev1.KustomizationSpec{
+				Interval: metav1.Duration{Duration: reconciliationInterval},
+				Path:     "./",
+				SourceRef: kustomizev1.CrossNamespaceSourceReference{
+					Name: repositoryName.Name,
+					Kind: sourcev1.GitRepositoryKind,
+				},
+				TargetNamespace: id,
+				DependsOn: []meta.NamespacedObjectReference{
+					{Name: dependsOn},
+				},
+			},
+		}
+	}
+
+	first := newKustomization("first", "second")
+	second := newKustomization("second", "third")
+	third := newKustomization("third", "first")
+	for _, k := range []*kustomizev1.Kustomization{first, second, third} {
+		g.Expect(k8sClient.Create(context.Background(), k)).To(Succeed())
+	}
+
+	t.Run("reports the cycle", func(t *testing.T) {
+		g := NewWithT(t)
+		g.Eventually(func() string {
+			var obj kustomizev1.Kustomization
+			_ = k8sClient.Get(context.Background(), client.ObjectKeyFromObject(first), &obj)
+			stalled := apimeta.FindStatusCondition(obj.Status.Conditions, meta.StalledCondition)
+			if stalled == nil || stalled.Reason != kustomizev1.CircularDependencyReason {
+				return ""
+			}
+			return stalled.Message
+		}, timeout, time.Second).Should(Equal(fmt.Sprintf(
+			"circular dependency detected: %[1]s/first -> %[1]s/second -> %[1]s/third -> %[1]s/first", id)))
+	})
+
+	t.Run("recovers when the cycle is broken", func(t *testing.T) {
+		g := NewWithT(t)
+
+		var obj kustomizev1.Kustomization
+		g.Expect(k8sClient.Get(context.Background(), client.ObjectKeyFromObject(third), &obj)).To(Succeed())
+		obj.Spec.DependsOn = nil
+		g.Expect(k8sClient.Update(context.Background(), &obj)).To(Succeed())
+
+		g.Eventually(func() bool {
+			var obj kustomizev1.Kustomization
+			_ = k8sClient.Get(context.Background(), client.ObjectKeyFromObject(first), &obj)
+			return isReconcileSuccess(&obj) &&
+				apimeta.FindStatusCondition(obj.Status.Conditions, meta.StalledCondition) == nil
+		}, timeout, time.Second).Should(BeTrue())
 	})
 }
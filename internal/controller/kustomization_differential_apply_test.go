@@ -0,0 +1,268 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/fluxcd/pkg/testserver"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+)
+
+func TestKustomizationReconciler_DifferentialApply(t *testing.T) {
+	g := NewWithT(t)
+	id := "diff-" + randStringRunes(5)
+	revision := "v1.0.0"
+
+	err := createNamespace(id)
+	g.Expect(err).NotTo(HaveOccurred(), "failed to create test namespace")
+
+	manifests := func(data string) []testserver.File {
+		return []testserver.File{
+			{
+				Name: "config.yaml",
+				Body: fmt.Sprintf(`---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: %[1]s
+data:
+  key: %[2]s
+`, id, data),
+			},
+		}
+	}
+
+	artifact, err := testServer.ArtifactFromFiles(manifests("v1"))
+	g.Expect(err).NotTo(HaveOccurred())
+
+	repositoryName := types.NamespacedName{
+		Name:      fmt.Sprintf("diff-%s", randStringRunes(5)),
+		Namespace: id,
+	}
+
+	err = applyGitRepository(repositoryName, artifact, revision)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	kustomization := &kustomizev1.Kustomization{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("diff-%s", randStringRunes(5)),
+			Namespace: id,
+		},
+		Spec: kustomizev1.KustomizationSpec{
+			Interval: metav1.Duration{Duration: reconciliationInterval},
+			Path:     "./",
+			SourceRef: kustomizev1.CrossNamespaceSourceReference{
+				Name:      repositoryName.Name,
+				Namespace: repositoryName.Namespace,
+				Kind:      sourcev1.GitRepositoryKind,
+			},
+			TargetNamespace:   id,
+			DifferentialApply: &kustomizev1.DifferentialApply{},
+		},
+	}
+
+	g.Expect(k8sClient.Create(context.Background(), kustomization)).To(Succeed())
+
+	resultK := &kustomizev1.Kustomization{}
+	configKey := types.NamespacedName{Name: id, Namespace: id}
+	drift := func() {
+		var cm corev1.ConfigMap
+		g.Expect(k8sClient.Get(context.Background(), configKey, &cm)).To(Succeed())
+		cm.Data["key"] = "drifted"
+		g.Expect(k8sClient.Update(context.Background(), &cm)).To(Succeed())
+	}
+	configValue := func() string {
+		var cm corev1.ConfigMap
+		g.Expect(k8sClient.Get(context.Background(), configKey, &cm)).To(Succeed())
+		return cm.Data["key"]
+	}
+
+	g.Eventually(func() bool {
+		_ = k8sClient.Get(context.Background(), client.ObjectKeyFromObject(kustomization), resultK)
+		return isReconcileSuccess(resultK) && resultK.Status.LastAppliedRevision == revision
+	}, timeout, time.Second).Should(BeTrue())
+
+	t.Run("records the digests and the full apply time", func(t *testing.T) {
+		g.Expect(resultK.Status.LastFullApplyAt).ToNot(BeNil())
+		g.Expect(resultK.Status.Inventory.Entries).To(HaveLen(1))
+		g.Expect(resultK.Status.Inventory.Entries[0].Digest).To(HavePrefix("sha256:"))
+	})
+
+	t.Run("skips unchanged objects", func(t *testing.T) {
+		drift()
+		lastFullApplyAt := resultK.Status.LastFullApplyAt
+
+		resultK.Spec.Interval = metav1.Duration{Duration: reconciliationInterval + time.Second}
+		g.Expect(k8sClient.Update(context.Background(), resultK)).To(Succeed())
+
+		g.Eventually(func() bool {
+			_ = k8sClient.Get(context.Background(), client.ObjectKeyFromObject(kustomization), resultK)
+			return isReconcileSuccess(resultK) && resultK.Status.ObservedGeneration == resultK.Generation
+		}, timeout, time.Second).Should(BeTrue())
+
+		g.Expect(configValue()).To(Equal("drifted"))
+		g.Expect(resultK.Status.LastFullApplyAt).To(Equal(lastFullApplyAt))
+	})
+
+	t.Run("applies changed objects", func(t *testing.T) {
+		revision := "v2.0.0"
+		artifact, err := testServer.ArtifactFromFiles(manifests("v2"))
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(applyGitRepository(repositoryName, artifact, revision)).To(Succeed())
+
+		g.Eventually(func() bool {
+			_ = k8sClient.Get(context.Background(), client.ObjectKeyFromObject(kustomization), resultK)
+			return resultK.Status.LastAppliedRevision == revision
+		}, timeout, time.Second).Should(BeTrue())
+
+		g.Expect(configValue()).To(Equal("v2"))
+	})
+
+	t.Run("corrects drift on reconcile request", func(t *testing.T) {
+		drift()
+
+		requestedAt := time.Now().String()
+		resultK.SetAnnotations(map[string]string{
+			meta.ReconcileRequestAnnotation: requestedAt,
+		})
+		g.Expect(k8sClient.Update(context.Background(), resultK)).To(Succeed())
+
+		g.Eventually(func() bool {
+			_ = k8sClient.Get(context.Background(), client.ObjectKeyFromObject(kustomization), resultK)
+			return resultK.Status.LastHandledReconcileAt == requestedAt
+		}, timeout, time.Second).Should(BeTrue())
+
+		g.Expect(configValue()).To(Equal("v2"))
+	})
+}
+
+func TestIsRevisionUnaffected(t *testing.T) {
+	now := metav1.Now()
+	kustomization := func(mutate func(obj *kustomizev1.Kustomization)) *kustomizev1.Kustomization {
+		obj := &kustomizev1.Kustomization{
+			ObjectMeta: metav1.ObjectMeta{Generation: 1},
+			Spec: kustomizev1.KustomizationSpec{
+				Path:              "./apps/frontend",
+				DifferentialApply: &kustomizev1.DifferentialApply{RevisionDiff: true},
+			},
+			Status: kustomizev1.KustomizationStatus{
+				ObservedGeneration:  1,
+				LastAppliedRevision: "main@sha1:1",
+				LastFullApplyAt:     &now,
+				Conditions: []metav1.Condition{
+					{Type: meta.ReadyCondition, Status: metav1.ConditionTrue},
+				},
+			},
+		}
+		if mutate != nil {
+			mutate(obj)
+		}
+		return obj
+	}
+	artifact := func(previous, changed string) *sourcev1.Artifact {
+		return &sourcev1.Artifact{
+			Revision: "main@sha1:2",
+			Metadata: map[string]string{
+				kustomizev1.PreviousRevisionMetadataKey: previous,
+				kustomizev1.ChangedPathsMetadataKey:     changed,
+			},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		obj      *kustomizev1.Kustomization
+		artifact *sourcev1.Artifact
+		want     bool
+	}{
+		{
+			name:     "changes outside the paths",
+			obj:      kustomization(nil),
+			artifact: artifact("main@sha1:1", "apps/backend/deploy.yaml"),
+			want:     true,
+		},
+		{
+			name:     "changes under the path",
+			obj:      kustomization(nil),
+			artifact: artifact("main@sha1:1", "apps/frontend/deploy.yaml"),
+		},
+		{
+			name: "changes under the filter paths",
+			obj: kustomization(func(obj *kustomizev1.Kustomization) {
+				obj.Spec.SourceChangeFilter = &kustomizev1.SourceChangeFilter{Paths: []string{"apps/base"}}
+			}),
+			artifact: artifact("main@sha1:1", "apps/base/deploy.yaml"),
+		},
+		{
+			name:     "changes listed against another revision",
+			obj:      kustomization(nil),
+			artifact: artifact("main@sha1:0", "apps/backend/deploy.yaml"),
+		},
+		{
+			name:     "without metadata",
+			obj:      kustomization(nil),
+			artifact: &sourcev1.Artifact{Revision: "main@sha1:2"},
+		},
+		{
+			name: "revision diff disabled",
+			obj: kustomization(func(obj *kustomizev1.Kustomization) {
+				obj.Spec.DifferentialApply.RevisionDiff = false
+			}),
+			artifact: artifact("main@sha1:1", "apps/backend/deploy.yaml"),
+		},
+		{
+			name: "spec changed",
+			obj: kustomization(func(obj *kustomizev1.Kustomization) {
+				obj.Generation = 2
+			}),
+			artifact: artifact("main@sha1:1", "apps/backend/deploy.yaml"),
+		},
+		{
+			name: "previous reconciliation failed",
+			obj: kustomization(func(obj *kustomizev1.Kustomization) {
+				obj.Status.Conditions[0].Status = metav1.ConditionFalse
+			}),
+			artifact: artifact("main@sha1:1", "apps/backend/deploy.yaml"),
+		},
+		{
+			name: "drift detection due",
+			obj: kustomization(func(obj *kustomizev1.Kustomization) {
+				obj.Status.LastFullApplyAt = &metav1.Time{Time: now.Add(-2 * time.Hour)}
+			}),
+			artifact: artifact("main@sha1:1", "apps/backend/deploy.yaml"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(isRevisionUnaffected(tt.obj, tt.artifact)).To(Equal(tt.want))
+		})
+	}
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+	"github.com/fluxcd/kustomize-controller/internal/effectiveconfig"
+)
+
+func TestKustomizationReconciler_EffectiveConfig(t *testing.T) {
+	g := NewWithT(t)
+	id := "eff-" + randStringRunes(5)
+
+	err := createNamespace(id)
+	g.Expect(err).NotTo(HaveOccurred(), "failed to create test namespace")
+
+	reconciler.DefaultServiceAccount = "default-sa"
+	reconciler.DefaultTimeout = 3 * time.Minute
+	defer func() {
+		reconciler.DefaultServiceAccount = ""
+		reconciler.DefaultTimeout = 0
+	}()
+
+	for _, name := range []string{"b", "a"} {
+		kustomization := &kustomizev1.Kustomization{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: id,
+			},
+			Spec: kustomizev1.KustomizationSpec{
+				Interval: metav1.Duration{Duration: time.Hour},
+				Path:     "./",
+				Suspend:  true,
+				SourceRef: kustomizev1.CrossNamespaceSourceReference{
+					Name: "missing",
+					Kind: sourcev1.GitRepositoryKind,
+				},
+			},
+		}
+		if name == "b" {
+			kustomization.Spec.ServiceAccountName = "tenant"
+			kustomization.Spec.Timeout = &metav1.Duration{Duration: time.Minute}
+		}
+		g.Expect(k8sClient.Create(context.Background(), kustomization)).To(Succeed())
+	}
+
+	var report effectiveconfig.Report
+	g.Eventually(func() int {
+		report, err = reconciler.effectiveConfig(context.Background(), id, "")
+		g.Expect(err).NotTo(HaveOccurred())
+		return len(report.Kustomizations)
+	}, timeout, time.Second).Should(Equal(2))
+
+	g.Expect(report.Controller.DefaultServiceAccount).To(Equal("default-sa"))
+	g.Expect(report.Controller.DefaultTimeout).To(Equal("3m0s"))
+	g.Expect(report.Controller.FeatureGates).NotTo(BeEmpty())
+
+	a, b := report.Kustomizations[0], report.Kustomizations[1]
+	g.Expect(a.Name).To(Equal("a"))
+	g.Expect(a.Effective.ServiceAccountName).To(Equal("default-sa"))
+	g.Expect(a.Effective.Timeout).To(Equal("3m0s"))
+	g.Expect(a.Spec.Timeout.Duration).To(Equal(3 * time.Minute))
+	g.Expect(a.Effective.Mode).To(Equal(kustomizev1.ApplyMode))
+	g.Expect(b.Name).To(Equal("b"))
+	g.Expect(b.Effective.ServiceAccountName).To(Equal("tenant"))
+	g.Expect(b.Effective.Timeout).To(Equal("1m0s"))
+
+	report, err = reconciler.effectiveConfig(context.Background(), id, "b")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(report.Kustomizations).To(HaveLen(1))
+	g.Expect(report.Kustomizations[0].Name).To(Equal("b"))
+}
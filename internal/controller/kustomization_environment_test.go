@@ -0,0 +1,156 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/fluxcd/pkg/testserver"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+)
+
+func TestKustomizationReconciler_EnvironmentPresets(t *testing.T) {
+	g := NewWithT(t)
+	id := "env-" + randStringRunes(5)
+	revision := "v1.0.0"
+	resultK := &kustomizev1.Kustomization{}
+
+	err := createNamespace(id)
+	g.Expect(err).NotTo(HaveOccurred(), "failed to create test namespace")
+
+	err = createKubeConfigSecret(id)
+	g.Expect(err).NotTo(HaveOccurred(), "failed to create kubeconfig secret")
+
+	clusterInfo := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster-info", Namespace: id},
+		Data:       map[string]string{"tier": "production", "region": "eu-west-1"},
+	}
+	g.Expect(k8sClient.Create(context.Background(), clusterInfo)).To(Succeed())
+
+	overlay := func(name string) []testserver.File {
+		return []testserver.File{
+			{
+				Name: fmt.Sprintf("%s/config.yaml", name),
+				Body: fmt.Sprintf(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: %s
+data:
+  region: ${REGION:=none}
+`, name),
+			},
+		}
+	}
+	files := append(overlay("staging"), overlay("production")...)
+
+	artifact, err := testServer.ArtifactFromFiles(files)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	repositoryName := types.NamespacedName{
+		Name:      fmt.Sprintf("env-%s", randStringRunes(5)),
+		Namespace: id,
+	}
+
+	err = applyGitRepository(repositoryName, artifact, revision)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	kustomization := &kustomizev1.Kustomization{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("env-%s", randStringRunes(5)),
+			Namespace: id,
+		},
+		Spec: kustomizev1.KustomizationSpec{
+			Interval:        metav1.Duration{Duration: reconciliationInterval},
+			Path:            "./staging",
+			Prune:           true,
+			TargetNamespace: id,
+			KubeConfig: &meta.KubeConfigReference{
+				SecretRef: meta.SecretKeyReference{
+					Name: "kubeconfig",
+				},
+			},
+			SourceRef: kustomizev1.CrossNamespaceSourceReference{
+				Name:      repositoryName.Name,
+				Namespace: repositoryName.Namespace,
+				Kind:      sourcev1.GitRepositoryKind,
+			},
+			PostBuild: &kustomizev1.PostBuild{
+				Substitute: map[string]string{"TIER": "any"},
+			},
+			Environment: &kustomizev1.Environment{
+				ConfigMapRef: &meta.NamespacedObjectReference{Name: clusterInfo.Name},
+				Presets: []kustomizev1.EnvironmentPreset{
+					{
+						Name:     "production",
+						Selector: metav1.LabelSelector{MatchLabels: map[string]string{"tier": "production"}},
+						Path:     "./production",
+					},
+					{
+						Name:       "europe",
+						Selector:   metav1.LabelSelector{MatchLabels: map[string]string{"region": "eu-west-1"}},
+						Substitute: map[string]string{"REGION": "eu"},
+					},
+				},
+			},
+		},
+	}
+
+	g.Expect(k8sClient.Create(context.Background(), kustomization)).To(Succeed())
+
+	t.Run("builds the path of the matching preset", func(t *testing.T) {
+		g := NewWithT(t)
+		g.Eventually(func() bool {
+			_ = k8sClient.Get(context.Background(), client.ObjectKeyFromObject(kustomization), resultK)
+			return isReconcileSuccess(resultK)
+		}, timeout, time.Second).Should(BeTrue())
+
+		var cm corev1.ConfigMap
+		g.Expect(k8sClient.Get(context.Background(), client.ObjectKey{Name: "production", Namespace: id}, &cm)).To(Succeed())
+		g.Expect(cm.Data).To(HaveKeyWithValue("region", "eu"))
+		g.Expect(k8sClient.Get(context.Background(), client.ObjectKey{Name: "staging", Namespace: id}, &cm)).ToNot(Succeed())
+
+		// The presets are not persisted in the spec.
+		g.Expect(resultK.Spec.Path).To(Equal("./staging"))
+		g.Expect(resultK.Spec.PostBuild.Substitute).ToNot(HaveKey("REGION"))
+	})
+
+	t.Run("falls back to the spec when no preset matches", func(t *testing.T) {
+		g := NewWithT(t)
+		clusterInfo.Data = map[string]string{"tier": "staging"}
+		g.Expect(k8sClient.Update(context.Background(), clusterInfo)).To(Succeed())
+
+		resultK.Spec.Interval = metav1.Duration{Duration: time.Minute}
+		g.Expect(k8sClient.Update(context.Background(), resultK)).To(Succeed())
+
+		var cm corev1.ConfigMap
+		g.Eventually(func() error {
+			return k8sClient.Get(context.Background(), client.ObjectKey{Name: "staging", Namespace: id}, &cm)
+		}, timeout, time.Second).Should(Succeed())
+		g.Expect(cm.Data).To(HaveKeyWithValue("region", "none"))
+	})
+}
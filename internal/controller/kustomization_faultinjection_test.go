@@ -0,0 +1,165 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/fluxcd/pkg/runtime/conditions"
+	"github.com/fluxcd/pkg/testserver"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+	"github.com/fluxcd/kustomize-controller/internal/faultinjection"
+)
+
+func TestKustomizationReconciler_FaultInjection(t *testing.T) {
+	g := NewWithT(t)
+	id := "fi-" + randStringRunes(5)
+	revision := "v1.0.0"
+
+	err := createNamespace(id)
+	g.Expect(err).NotTo(HaveOccurred(), "failed to create test namespace")
+
+	manifests := func(names ...string) []testserver.File {
+		var files []testserver.File
+		for _, name := range names {
+			files = append(files, testserver.File{
+				Name: name + ".yaml",
+				Body: fmt.Sprintf(`---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: %[1]s
+data:
+  key: "%[1]s"
+`, name),
+			})
+		}
+		return files
+	}
+
+	artifact, err := testServer.ArtifactFromFiles(manifests("first", "second"))
+	g.Expect(err).NotTo(HaveOccurred())
+
+	repositoryName := types.NamespacedName{
+		Name:      fmt.Sprintf("fi-%s", randStringRunes(5)),
+		Namespace: id,
+	}
+	err = applyGitRepository(repositoryName, artifact, revision)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	injector, err := faultinjection.New(faultinjection.Options{
+		Selector:                  "chaos=enabled",
+		HealthCheckFailurePercent: 100,
+		DropPrune:                 true,
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	reconciler.FaultInjector = injector
+	defer func() {
+		reconciler.FaultInjector = nil
+	}()
+
+	kustomization := &kustomizev1.Kustomization{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("fi-%s", randStringRunes(5)),
+			Namespace: id,
+			Labels:    map[string]string{"chaos": "enabled"},
+		},
+		Spec: kustomizev1.KustomizationSpec{
+			Interval: metav1.Duration{Duration: reconciliationInterval},
+			Path:     "./",
+			SourceRef: kustomizev1.CrossNamespaceSourceReference{
+				Name:      repositoryName.Name,
+				Namespace: repositoryName.Namespace,
+				Kind:      sourcev1.GitRepositoryKind,
+			},
+			TargetNamespace: id,
+			Prune:           true,
+			Wait:            true,
+			Timeout:         &metav1.Duration{Duration: 10 * time.Second},
+		},
+	}
+	g.Expect(k8sClient.Create(context.Background(), kustomization)).To(Succeed())
+
+	resultK := &kustomizev1.Kustomization{}
+
+	t.Run("fails the health checks", func(t *testing.T) {
+		g.Eventually(func() bool {
+			_ = k8sClient.Get(context.Background(), client.ObjectKeyFromObject(kustomization), resultK)
+			return conditions.IsFalse(resultK, kustomizev1.HealthyCondition)
+		}, timeout, time.Second).Should(BeTrue())
+		logStatus(t, resultK)
+
+		g.Expect(conditions.GetReason(resultK, meta.ReadyCondition)).To(Equal(kustomizev1.HealthCheckFailedReason))
+		g.Expect(conditions.GetMessage(resultK, meta.ReadyCondition)).To(ContainSubstring(faultinjection.ErrHealthCheck.Error()))
+	})
+
+	t.Run("drops the garbage collection", func(t *testing.T) {
+		injector, err := faultinjection.New(faultinjection.Options{
+			Selector:  "chaos=enabled",
+			DropPrune: true,
+		})
+		g.Expect(err).NotTo(HaveOccurred())
+		reconciler.FaultInjector = injector
+
+		artifact, err := testServer.ArtifactFromFiles(manifests("first"))
+		g.Expect(err).NotTo(HaveOccurred())
+		revision = "v2.0.0"
+		err = applyGitRepository(repositoryName, artifact, revision)
+		g.Expect(err).NotTo(HaveOccurred())
+
+		g.Eventually(func() bool {
+			_ = k8sClient.Get(context.Background(), client.ObjectKeyFromObject(kustomization), resultK)
+			return resultK.Status.LastAppliedRevision == revision
+		}, timeout, time.Second).Should(BeTrue())
+		logStatus(t, resultK)
+
+		g.Expect(k8sClient.Get(context.Background(), types.NamespacedName{Name: "second", Namespace: id}, &corev1.ConfigMap{})).To(Succeed())
+		g.Expect(resultK.Status.Inventory.Entries).To(HaveLen(2))
+	})
+
+	t.Run("prunes the stale objects once the fault is removed", func(t *testing.T) {
+		reconciler.FaultInjector = nil
+
+		artifact, err := testServer.ArtifactFromFiles(manifests("first"))
+		g.Expect(err).NotTo(HaveOccurred())
+		revision = "v3.0.0"
+		err = applyGitRepository(repositoryName, artifact, revision)
+		g.Expect(err).NotTo(HaveOccurred())
+
+		g.Eventually(func() bool {
+			_ = k8sClient.Get(context.Background(), client.ObjectKeyFromObject(kustomization), resultK)
+			return resultK.Status.LastAppliedRevision == revision
+		}, timeout, time.Second).Should(BeTrue())
+		logStatus(t, resultK)
+
+		err = k8sClient.Get(context.Background(), types.NamespacedName{Name: "second", Namespace: id}, &corev1.ConfigMap{})
+		g.Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		g.Expect(resultK.Status.Inventory.Entries).To(HaveLen(1))
+	})
+}
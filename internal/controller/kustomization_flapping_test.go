@@ -0,0 +1,108 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fluxcd/cli-utils/pkg/object"
+	"github.com/fluxcd/pkg/apis/kustomize"
+	"github.com/fluxcd/pkg/ssa"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+)
+
+func TestKustomizationReconciler_FlappingResources(t *testing.T) {
+	g := NewWithT(t)
+	id := "flapping-" + randStringRunes(5)
+	revision := "v1.0.0"
+
+	err := createNamespace(id)
+	g.Expect(err).NotTo(HaveOccurred(), "failed to create test namespace")
+
+	// The in-cluster value differs from the manifest, as if a mutating
+	// webhook rewrote it.
+	existing := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: id},
+		Data:       map[string]string{"key": "mutated"},
+	}
+	g.Expect(k8sClient.Create(context.Background(), existing)).To(Succeed())
+
+	desired := &unstructured.Unstructured{}
+	desired.SetAPIVersion("v1")
+	desired.SetKind("ConfigMap")
+	desired.SetNamespace(id)
+	desired.SetName("app")
+	g.Expect(unstructured.SetNestedField(desired.Object, "value", "data", "key")).To(Succeed())
+	objects := []*unstructured.Unstructured{desired}
+
+	changeSet := func(action ssa.Action) *ssa.ChangeSet {
+		set := ssa.NewChangeSet()
+		set.Add(ssa.ChangeSetEntry{
+			ObjMetadata:  object.UnstructuredToObjMetadata(desired),
+			GroupVersion: "v1",
+			Subject:      "ConfigMap/" + id + "/app",
+			Action:       action,
+		})
+		return set
+	}
+
+	obj := &kustomizev1.Kustomization{
+		ObjectMeta: metav1.ObjectMeta{Name: id, Namespace: id},
+		Status:     kustomizev1.KustomizationStatus{LastAppliedRevision: revision},
+	}
+
+	t.Run("ignores the changes of a new revision", func(t *testing.T) {
+		reconciler.recordFlappingResources(context.Background(), obj, "v2.0.0", objects, changeSet(ssa.ConfiguredAction), nil, nil)
+		g.Expect(obj.Status.FlappingResources).To(BeEmpty())
+	})
+
+	t.Run("records the changes of the same revision", func(t *testing.T) {
+		fieldPaths := reconciler.flappingFieldPaths(context.Background(), k8sClient, obj, objects)
+		g.Expect(fieldPaths).To(BeEmpty())
+
+		reconciler.recordFlappingResources(context.Background(), obj, revision, objects, changeSet(ssa.ConfiguredAction), nil, fieldPaths)
+		g.Expect(obj.Status.FlappingResources).To(HaveLen(1))
+		g.Expect(obj.Status.FlappingResources[0].Reconciliations).To(Equal(1))
+		g.Expect(obj.Status.FlappingResources[0].FieldPaths).To(BeEmpty())
+	})
+
+	t.Run("reports the fields of the flapping object", func(t *testing.T) {
+		fieldPaths := reconciler.flappingFieldPaths(context.Background(), k8sClient, obj, objects)
+		reconciler.recordFlappingResources(context.Background(), obj, revision, objects, changeSet(ssa.ConfiguredAction), nil, fieldPaths)
+		g.Expect(obj.Status.FlappingResources).To(Equal([]kustomizev1.FlappingResource{{
+			Object:          "ConfigMap/" + id + "/app",
+			Reconciliations: 2,
+			FieldPaths:      []string{"/data/key"},
+		}}))
+		g.Expect(obj.Status.SuggestedIgnoreRules).To(Equal([]kustomizev1.IgnoreRule{{
+			Paths:  []string{"/data/key"},
+			Target: &kustomize.Selector{Kind: "ConfigMap", Namespace: id, Name: "app"},
+		}}))
+	})
+
+	t.Run("forgets the object applied without changes", func(t *testing.T) {
+		reconciler.recordFlappingResources(context.Background(), obj, revision, objects, changeSet(ssa.UnchangedAction), nil, nil)
+		g.Expect(obj.Status.FlappingResources).To(BeEmpty())
+		g.Expect(obj.Status.SuggestedIgnoreRules).To(BeEmpty())
+	})
+}
@@ -0,0 +1,134 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/fluxcd/pkg/runtime/conditions"
+	"github.com/fluxcd/pkg/testserver"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+)
+
+func TestKustomizationReconciler_ReconciliationGate(t *testing.T) {
+	g := NewWithT(t)
+	id := "gate-" + randStringRunes(5)
+	revision := "v1.0.0"
+
+	reconciler.ReconciliationGates = true
+	defer func() {
+		reconciler.ReconciliationGates = false
+	}()
+
+	err := createNamespace(id)
+	g.Expect(err).NotTo(HaveOccurred(), "failed to create test namespace")
+
+	artifact, err := testServer.ArtifactFromFiles([]testserver.File{
+		{
+			Name: "config.yaml",
+			Body: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+data:
+  key: value
+`,
+		},
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	repositoryName := types.NamespacedName{
+		Name:      fmt.Sprintf("gate-%s", randStringRunes(5)),
+		Namespace: id,
+	}
+
+	err = applyGitRepository(repositoryName, artifact, revision)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	gate := &kustomizev1.ReconciliationGate{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: id,
+		},
+		Spec: kustomizev1.ReconciliationGateSpec{
+			Closed: true,
+			Selector: metav1.LabelSelector{
+				MatchLabels: map[string]string{"gate": id},
+			},
+			Message: "maintenance",
+		},
+	}
+	g.Expect(k8sClient.Create(context.Background(), gate)).To(Succeed())
+	defer k8sClient.Delete(context.Background(), gate)
+
+	kustomization := &kustomizev1.Kustomization{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      id,
+			Namespace: id,
+			Labels:    map[string]string{"gate": id},
+		},
+		Spec: kustomizev1.KustomizationSpec{
+			Interval:        metav1.Duration{Duration: reconciliationInterval},
+			Path:            "./",
+			TargetNamespace: id,
+			Prune:           true,
+			SourceRef: kustomizev1.CrossNamespaceSourceReference{
+				Name:      repositoryName.Name,
+				Namespace: repositoryName.Namespace,
+				Kind:      sourcev1.GitRepositoryKind,
+			},
+		},
+	}
+	g.Expect(k8sClient.Create(context.Background(), kustomization)).To(Succeed())
+
+	resultK := &kustomizev1.Kustomization{}
+	g.Eventually(func() bool {
+		_ = k8sClient.Get(context.Background(), client.ObjectKeyFromObject(kustomization), resultK)
+		return conditions.GetReason(resultK, meta.ReadyCondition) == kustomizev1.ReconciliationGatedReason
+	}, timeout, time.Second).Should(BeTrue())
+
+	g.Expect(conditions.GetMessage(resultK, meta.ReadyCondition)).To(Equal(
+		fmt.Sprintf("apply paused by the closed ReconciliationGate '%s': maintenance", id)))
+	g.Expect(resultK.Status.LastAttemptedRevision).To(Equal(revision))
+	g.Expect(resultK.Status.LastAppliedRevision).To(BeEmpty())
+
+	err = k8sClient.Get(context.Background(), types.NamespacedName{Name: "config", Namespace: id}, &corev1.ConfigMap{})
+	g.Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+	// Opening the gate releases the apply.
+	patch := client.MergeFrom(gate.DeepCopy())
+	gate.Spec.Closed = false
+	g.Expect(k8sClient.Patch(context.Background(), gate, patch)).To(Succeed())
+
+	g.Eventually(func() bool {
+		_ = k8sClient.Get(context.Background(), client.ObjectKeyFromObject(kustomization), resultK)
+		return conditions.IsReady(resultK) && resultK.Status.LastAppliedRevision == revision
+	}, timeout, time.Second).Should(BeTrue())
+
+	g.Expect(k8sClient.Get(context.Background(), types.NamespacedName{Name: "config", Namespace: id}, &corev1.ConfigMap{})).To(Succeed())
+}
@@ -0,0 +1,175 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/fluxcd/pkg/testserver"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	"github.com/google/go-containerregistry/pkg/name"
+	. "github.com/onsi/gomega"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+	"github.com/fluxcd/kustomize-controller/internal/imageverify"
+)
+
+func TestKustomizationReconciler_ImageVerification(t *testing.T) {
+	g := NewWithT(t)
+	id := "iv-" + randStringRunes(5)
+	revision := "v1.0.0"
+	resultK := &kustomizev1.Kustomization{}
+
+	signed := map[string]bool{"ghcr.io/org/web:v1.0.0": true}
+	verifier := reconciler.imageVerifier
+	reconciler.imageVerifier = imageverify.NewVerifierWithFunc(func(_ context.Context, ref name.Reference, _ *cosign.CheckOpts) error {
+		if !signed[ref.String()] {
+			return errors.New("no matching signatures")
+		}
+		return nil
+	})
+	defer func() { reconciler.imageVerifier = verifier }()
+
+	err := createNamespace(id)
+	g.Expect(err).NotTo(HaveOccurred(), "failed to create test namespace")
+
+	err = createKubeConfigSecret(id)
+	g.Expect(err).NotTo(HaveOccurred(), "failed to create kubeconfig secret")
+
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	g.Expect(err).NotTo(HaveOccurred())
+	pub, err := cryptoutils.MarshalPublicKeyToPEM(&priv.PublicKey)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	keys := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "cosign-keys",
+			Namespace: id,
+		},
+		Data: map[string][]byte{"author.pub": pub},
+	}
+	g.Expect(k8sClient.Create(context.Background(), keys)).To(Succeed())
+
+	artifact, err := testServer.ArtifactFromFiles([]testserver.File{
+		{
+			Name: "deployment.yaml",
+			Body: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  selector:
+    matchLabels:
+      app: web
+  template:
+    metadata:
+      labels:
+        app: web
+    spec:
+      initContainers:
+      - name: init
+        image: ghcr.io/org/init:v1.0.0
+      containers:
+      - name: web
+        image: ghcr.io/org/web:v1.0.0
+`,
+		},
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	repositoryName := types.NamespacedName{
+		Name:      fmt.Sprintf("iv-%s", randStringRunes(5)),
+		Namespace: id,
+	}
+
+	err = applyGitRepository(repositoryName, artifact, revision)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	kustomization := &kustomizev1.Kustomization{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("iv-%s", randStringRunes(5)),
+			Namespace: id,
+		},
+		Spec: kustomizev1.KustomizationSpec{
+			Interval:        metav1.Duration{Duration: reconciliationInterval},
+			Path:            "./",
+			TargetNamespace: id,
+			KubeConfig: &meta.KubeConfigReference{
+				SecretRef: meta.SecretKeyReference{
+					Name: "kubeconfig",
+				},
+			},
+			SourceRef: kustomizev1.CrossNamespaceSourceReference{
+				Name:      repositoryName.Name,
+				Namespace: repositoryName.Namespace,
+				Kind:      sourcev1.GitRepositoryKind,
+			},
+			ImageVerification: &kustomizev1.ImageVerification{
+				Provider:  "cosign",
+				SecretRef: &meta.LocalObjectReference{Name: keys.Name},
+			},
+		},
+	}
+
+	g.Expect(k8sClient.Create(context.Background(), kustomization)).To(Succeed())
+
+	t.Run("fails on unsigned images", func(t *testing.T) {
+		g := NewWithT(t)
+		g.Eventually(func() bool {
+			_ = k8sClient.Get(context.Background(), client.ObjectKeyFromObject(kustomization), resultK)
+			ready := apimeta.FindStatusCondition(resultK.Status.Conditions, meta.ReadyCondition)
+			return ready != nil && ready.Reason == kustomizev1.ImageVerificationFailedReason
+		}, timeout, time.Second).Should(BeTrue())
+
+		ready := apimeta.FindStatusCondition(resultK.Status.Conditions, meta.ReadyCondition)
+		g.Expect(ready.Message).To(ContainSubstring("ghcr.io/org/init:v1.0.0: no matching signatures"))
+		g.Expect(ready.Message).ToNot(ContainSubstring("ghcr.io/org/web:v1.0.0"))
+		g.Expect(k8sClient.Get(context.Background(), client.ObjectKey{Name: "web", Namespace: id}, &appsv1.Deployment{})).ToNot(Succeed())
+	})
+
+	t.Run("applies when all images are signed", func(t *testing.T) {
+		g := NewWithT(t)
+		reconciler.imageVerifier = imageverify.NewVerifierWithFunc(func(_ context.Context, _ name.Reference, _ *cosign.CheckOpts) error {
+			return nil
+		})
+
+		resultK.Spec.Interval = metav1.Duration{Duration: reconciliationInterval / 2}
+		g.Expect(k8sClient.Update(context.Background(), resultK)).To(Succeed())
+
+		g.Eventually(func() bool {
+			_ = k8sClient.Get(context.Background(), client.ObjectKeyFromObject(kustomization), resultK)
+			return isReconcileSuccess(resultK) && resultK.Status.ObservedGeneration == resultK.Generation
+		}, timeout, time.Second).Should(BeTrue())
+		g.Expect(k8sClient.Get(context.Background(), client.ObjectKey{Name: "web", Namespace: id}, &appsv1.Deployment{})).To(Succeed())
+	})
+}
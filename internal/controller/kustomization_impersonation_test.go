@@ -19,6 +19,7 @@ package controller
 import (
 	"context"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
@@ -318,3 +319,108 @@ data:
 	})
 
 }
+
+func TestKustomizationReconciler_TenantLockdown(t *testing.T) {
+	g := NewWithT(t)
+	id := "lockdown-" + randStringRunes(5)
+	revision := "v1.0.0"
+
+	reconciler.TenantLockdown = true
+	defer func() {
+		reconciler.TenantLockdown = false
+		reconciler.TenantExemptNamespaces = nil
+	}()
+
+	err := createNamespace(id)
+	g.Expect(err).NotTo(HaveOccurred(), "failed to create test namespace")
+
+	err = createKubeConfigSecret(id)
+	g.Expect(err).NotTo(HaveOccurred(), "failed to create kubeconfig secret")
+
+	artifact, err := testServer.ArtifactFromFiles([]testserver.File{
+		{
+			Name: "config.yaml",
+			Body: fmt.Sprintf(`---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: %[1]s
+data:
+  key: val
+`, id),
+		},
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	repositoryName := types.NamespacedName{
+		Name:      fmt.Sprintf("lockdown-%s", randStringRunes(5)),
+		Namespace: id,
+	}
+
+	err = applyGitRepository(repositoryName, artifact, revision)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	kustomization := &kustomizev1.Kustomization{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("lockdown-%s", randStringRunes(5)),
+			Namespace: id,
+		},
+		Spec: kustomizev1.KustomizationSpec{
+			Interval: metav1.Duration{Duration: reconciliationInterval},
+			Path:     "./",
+			SourceRef: kustomizev1.CrossNamespaceSourceReference{
+				Name:      repositoryName.Name,
+				Namespace: repositoryName.Namespace,
+				Kind:      sourcev1.GitRepositoryKind,
+			},
+			TargetNamespace: id,
+		},
+	}
+
+	g.Expect(k8sClient.Create(context.Background(), kustomization)).To(Succeed())
+
+	resultK := &kustomizev1.Kustomization{}
+	isStalled := func() bool {
+		_ = k8sClient.Get(context.Background(), client.ObjectKeyFromObject(kustomization), resultK)
+		return apimeta.IsStatusConditionTrue(resultK.Status.Conditions, meta.StalledCondition) &&
+			resultK.Status.ObservedGeneration == resultK.Generation
+	}
+
+	t.Run("stalls without service account", func(t *testing.T) {
+		g.Eventually(isStalled, timeout, time.Second).Should(BeTrue())
+
+		ready := apimeta.FindStatusCondition(resultK.Status.Conditions, meta.ReadyCondition)
+		g.Expect(ready.Reason).To(Equal(kustomizev1.TenancyViolationReason))
+		g.Expect(ready.Message).To(ContainSubstring("'.spec.serviceAccountName' is required"))
+	})
+
+	t.Run("stalls with kubeconfig", func(t *testing.T) {
+		resultK.Spec.ServiceAccountName = "default"
+		resultK.Spec.KubeConfig = &meta.KubeConfigReference{
+			SecretRef: meta.SecretKeyReference{
+				Name: "kubeconfig",
+			},
+		}
+		g.Expect(k8sClient.Update(context.Background(), resultK)).To(Succeed())
+
+		g.Eventually(func() bool {
+			if !isStalled() {
+				return false
+			}
+			ready := apimeta.FindStatusCondition(resultK.Status.Conditions, meta.ReadyCondition)
+			return strings.Contains(ready.Message, "'.spec.kubeConfig' is not allowed")
+		}, timeout, time.Second).Should(BeTrue())
+	})
+
+	t.Run("reconciles in exempted namespaces", func(t *testing.T) {
+		reconciler.TenantExemptNamespaces = []string{id}
+		resultK.Spec.ServiceAccountName = ""
+		g.Expect(k8sClient.Update(context.Background(), resultK)).To(Succeed())
+
+		g.Eventually(func() bool {
+			_ = k8sClient.Get(context.Background(), client.ObjectKeyFromObject(kustomization), resultK)
+			return isReconcileSuccess(resultK)
+		}, timeout, time.Second).Should(BeTrue())
+		g.Expect(apimeta.FindStatusCondition(resultK.Status.Conditions, meta.StalledCondition)).To(BeNil())
+	})
+}
@@ -19,8 +19,11 @@ package controller
 import (
 	"context"
 	"fmt"
+	"path"
+	"strings"
 
 	"github.com/fluxcd/pkg/runtime/conditions"
+	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
@@ -30,6 +33,8 @@ import (
 	sourcev1 "github.com/fluxcd/source-controller/api/v1"
 
 	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+	"github.com/fluxcd/kustomize-controller/internal/priority"
+	"github.com/fluxcd/kustomize-controller/internal/reconciliationgate"
 )
 
 func (r *KustomizationReconciler) requestsForRevisionChangeOf(indexKey string) handler.MapFunc {
@@ -57,13 +62,39 @@ func (r *KustomizationReconciler) requestsForRevisionChangeOf(indexKey string) h
 		}
 		var dd []dependency.Dependent
 		for i, d := range list.Items {
+			// If the source is an overlay of the Kustomization, we should only make
+			// a request if the revision of the artifact was not attempted yet
+			if revision, ok := overlayRevision(&list.Items[i], client.ObjectKeyFromObject(obj).String()); ok {
+				if conditions.IsReady(&list.Items[i]) && repo.GetArtifact().HasRevision(revision) {
+					continue
+				}
+				dd = append(dd, d.DeepCopy())
+				continue
+			}
 			// If the Kustomization is ready and the revision of the artifact equals
 			// to the last attempted revision, we should not make a request for this Kustomization
 			if conditions.IsReady(&list.Items[i]) && repo.GetArtifact().HasRevision(d.Status.LastAttemptedRevision) {
 				continue
 			}
+			// If the Kustomization filters the source changes and the artifact lists
+			// the changed files, we should only make a request if its paths changed
+			if !sourceChangeAffects(&list.Items[i], repo.GetArtifact()) {
+				log.V(1).Info("skipping source revision change outside of the Kustomization paths",
+					"kustomization", client.ObjectKeyFromObject(&list.Items[i]).String())
+				continue
+			}
 			dd = append(dd, d.DeepCopy())
 		}
+		// Enqueue the dependents once their dependencies are reconciled.
+		if r.fanOut != nil {
+			items := make([]kustomizev1.Kustomization, 0, len(dd))
+			for _, d := range dd {
+				items = append(items, *d.(*kustomizev1.Kustomization))
+			}
+			reqs := requestsFor(r.fanOut.Start(items))
+			priority.SortRequests(reqs, items)
+			return reqs
+		}
 		sorted, err := dependency.Sort(dd)
 		if err != nil {
 			log.Error(err, "failed to sort dependencies for revision change")
@@ -74,10 +105,263 @@ func (r *KustomizationReconciler) requestsForRevisionChangeOf(indexKey string) h
 			reqs[i].NamespacedName.Name = sorted[i].Name
 			reqs[i].NamespacedName.Namespace = sorted[i].Namespace
 		}
+		priority.SortRequests(reqs, list.Items)
+		return reqs
+	}
+}
+
+// requestsForReconcileRequestOf returns the requests for the Kustomizations
+// consuming the source which has handled a reconciliation request, starting
+// with the ones which don't depend on the others.
+func (r *KustomizationReconciler) requestsForReconcileRequestOf(indexKey string) handler.MapFunc {
+	return func(ctx context.Context, obj client.Object) []reconcile.Request {
+		var list kustomizev1.KustomizationList
+		if err := r.List(ctx, &list, client.MatchingFields{
+			indexKey: client.ObjectKeyFromObject(obj).String(),
+		}); err != nil {
+			ctrl.LoggerFrom(ctx).Error(err, "failed to list objects for reconcile request")
+			return nil
+		}
+		items := make([]kustomizev1.Kustomization, 0, len(list.Items))
+		for _, k := range list.Items {
+			if !k.Spec.Suspend {
+				items = append(items, k)
+			}
+		}
+		reqs := requestsFor(r.fanOut.Start(items))
+		priority.SortRequests(reqs, items)
 		return reqs
 	}
 }
 
+func requestsFor(keys []types.NamespacedName) []reconcile.Request {
+	reqs := make([]reconcile.Request, len(keys))
+	for i := range keys {
+		reqs[i].NamespacedName = keys[i]
+	}
+	return reqs
+}
+
+// sourceChangeAffects returns false if the Kustomization has a source change
+// filter, and the artifact metadata lists changed files none of which are
+// under the Kustomization path or the filter paths.
+func sourceChangeAffects(obj *kustomizev1.Kustomization, artifact *sourcev1.Artifact) bool {
+	if obj.Spec.SourceChangeFilter == nil {
+		return true
+	}
+
+	changed, ok := artifact.Metadata[kustomizev1.ChangedPathsMetadataKey]
+	if !ok {
+		return true
+	}
+	return changedPathsAffect(obj, changed)
+}
+
+// changedPathsAffect returns true if any of the changed paths, separated by
+// commas or new lines, is under the Kustomization path or the source change
+// filter paths. The Kustomization path is truncated to the directory before
+// its first variable, as the variables are expanded at build time.
+func changedPathsAffect(obj *kustomizev1.Kustomization, changed string) bool {
+	dir := obj.Spec.Path
+	if i := strings.Index(dir, "${"); i >= 0 {
+		dir = dir[:strings.LastIndex(dir[:i], "/")+1]
+	}
+	prefixes := []string{dir}
+	if obj.Spec.SourceChangeFilter != nil {
+		prefixes = append(prefixes, obj.Spec.SourceChangeFilter.Paths...)
+	}
+	for i, prefix := range prefixes {
+		prefixes[i] = cleanSourcePath(prefix)
+		if prefixes[i] == "" {
+			return true
+		}
+	}
+
+	for _, p := range strings.FieldsFunc(changed, func(r rune) bool {
+		return r == ',' || r == '\n'
+	}) {
+		p = cleanSourcePath(strings.TrimSpace(p))
+		for _, prefix := range prefixes {
+			if p == prefix || strings.HasPrefix(p, prefix+"/") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// cleanSourcePath returns the path relative to the source root, without
+// leading or trailing slashes, or an empty string for the root itself.
+func cleanSourcePath(p string) string {
+	return strings.Trim(path.Clean("/"+p), "/")
+}
+
+// requestsForConfigChangeOf returns the requests for the Kustomizations
+// referencing the ConfigMap or Secret, listed with the given index keys.
+func (r *KustomizationReconciler) requestsForConfigChangeOf(indexKeys ...string) handler.MapFunc {
+	return func(ctx context.Context, obj client.Object) []reconcile.Request {
+		seen := make(map[client.ObjectKey]bool)
+		var reqs []reconcile.Request
+		for _, indexKey := range indexKeys {
+			var list kustomizev1.KustomizationList
+			if err := r.List(ctx, &list, client.InNamespace(obj.GetNamespace()), client.MatchingFields{
+				indexKey: client.ObjectKeyFromObject(obj).String(),
+			}); err != nil {
+				ctrl.LoggerFrom(ctx).Error(err, "failed to list objects for config change")
+				return nil
+			}
+			for i, k := range list.Items {
+				key := client.ObjectKeyFromObject(&list.Items[i])
+				if k.Spec.Suspend || seen[key] {
+					continue
+				}
+				seen[key] = true
+				reqs = append(reqs, reconcile.Request{NamespacedName: key})
+			}
+		}
+		return reqs
+	}
+}
+
+// indexBySubstituteFrom indexes the Kustomizations by the ConfigMaps or
+// Secrets, depending on the kind, they load post build variables from.
+func (r *KustomizationReconciler) indexBySubstituteFrom(kind string) func(o client.Object) []string {
+	return func(o client.Object) []string {
+		k, ok := o.(*kustomizev1.Kustomization)
+		if !ok {
+			panic(fmt.Sprintf("Expected a Kustomization, got %T", o))
+		}
+
+		if k.Spec.PostBuild == nil {
+			return nil
+		}
+
+		var keys []string
+		for _, ref := range k.Spec.PostBuild.SubstituteFrom {
+			if ref.Kind == kind {
+				keys = append(keys, fmt.Sprintf("%s/%s", k.GetNamespace(), ref.Name))
+			}
+		}
+		return keys
+	}
+}
+
+// indexByDecryptionSecret indexes the Kustomizations by the Secret
+// holding their decryption keys.
+func (r *KustomizationReconciler) indexByDecryptionSecret(o client.Object) []string {
+	k, ok := o.(*kustomizev1.Kustomization)
+	if !ok {
+		panic(fmt.Sprintf("Expected a Kustomization, got %T", o))
+	}
+
+	if k.Spec.Decryption == nil || k.Spec.Decryption.SecretRef == nil {
+		return nil
+	}
+	return []string{fmt.Sprintf("%s/%s", k.GetNamespace(), k.Spec.Decryption.SecretRef.Name)}
+}
+
+// indexByDependsOn indexes the Kustomizations by the Kustomizations
+// they depend on.
+func (r *KustomizationReconciler) indexByDependsOn(o client.Object) []string {
+	k, ok := o.(*kustomizev1.Kustomization)
+	if !ok {
+		panic(fmt.Sprintf("Expected a Kustomization, got %T", o))
+	}
+
+	keys := make([]string, 0, len(k.Spec.DependsOn))
+	for _, d := range k.Spec.DependsOn {
+		namespace := d.Namespace
+		if namespace == "" {
+			namespace = k.GetNamespace()
+		}
+		keys = append(keys, fmt.Sprintf("%s/%s", namespace, d.Name))
+	}
+	return keys
+}
+
+// indexByChildren indexes the Kustomizations by the child Kustomizations
+// whose readiness they aggregate, which are recorded in their status.
+func (r *KustomizationReconciler) indexByChildren(o client.Object) []string {
+	k, ok := o.(*kustomizev1.Kustomization)
+	if !ok {
+		panic(fmt.Sprintf("Expected a Kustomization, got %T", o))
+	}
+
+	// The children of the remote clusters are not watched.
+	if !k.Spec.AggregateChildren || k.IsRemote() {
+		return nil
+	}
+
+	keys := make([]string, 0, len(k.Status.Children))
+	for _, c := range k.Status.Children {
+		keys = append(keys, fmt.Sprintf("%s/%s", c.Namespace, c.Name))
+	}
+	return keys
+}
+
+// indexByInventory indexes the Kustomizations by the objects of their
+// inventory, in the inventory ID format.
+func (r *KustomizationReconciler) indexByInventory(o client.Object) []string {
+	k, ok := o.(*kustomizev1.Kustomization)
+	if !ok {
+		panic(fmt.Sprintf("Expected a Kustomization, got %T", o))
+	}
+
+	// The objects of the remote clusters can't conflict with the local ones.
+	if !(r.DetectOwnershipConflicts || r.OwnerLookup) || k.IsRemote() || k.Status.Inventory == nil {
+		return nil
+	}
+
+	keys := make([]string, 0, len(k.Status.Inventory.Entries))
+	for _, e := range k.Status.Inventory.Entries {
+		keys = append(keys, e.ID)
+	}
+	return keys
+}
+
+// requestsForReadinessChangeOf returns the requests for the Kustomizations
+// aggregating the readiness of the given child Kustomization.
+func (r *KustomizationReconciler) requestsForReadinessChangeOf(ctx context.Context, obj client.Object) []reconcile.Request {
+	var list kustomizev1.KustomizationList
+	if err := r.List(ctx, &list, client.MatchingFields{
+		childrenIndexKey: client.ObjectKeyFromObject(obj).String(),
+	}); err != nil {
+		ctrl.LoggerFrom(ctx).Error(err, "failed to list objects for readiness change")
+		return nil
+	}
+	var reqs []reconcile.Request
+	for i, k := range list.Items {
+		if !k.Spec.Suspend {
+			reqs = append(reqs, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&list.Items[i])})
+		}
+	}
+	return reqs
+}
+
+func (r *KustomizationReconciler) requestsForGateChangeOf(ctx context.Context, obj client.Object) []reconcile.Request {
+	gate, ok := obj.(*kustomizev1.ReconciliationGate)
+	if !ok {
+		return nil
+	}
+
+	var list kustomizev1.KustomizationList
+	if err := r.List(ctx, &list); err != nil {
+		ctrl.LoggerFrom(ctx).Error(err, "failed to list objects for gate change")
+		return nil
+	}
+	var reqs []reconcile.Request
+	for i, k := range list.Items {
+		if k.Spec.Suspend {
+			continue
+		}
+		if ok, err := reconciliationgate.Selects(*gate, k.GetLabels()); err != nil || !ok {
+			continue
+		}
+		reqs = append(reqs, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&list.Items[i])})
+	}
+	return reqs
+}
+
 func (r *KustomizationReconciler) indexBy(kind string) func(o client.Object) []string {
 	return func(o client.Object) []string {
 		k, ok := o.(*kustomizev1.Kustomization)
@@ -85,14 +369,47 @@ func (r *KustomizationReconciler) indexBy(kind string) func(o client.Object) []s
 			panic(fmt.Sprintf("Expected a Kustomization, got %T", o))
 		}
 
+		var keys []string
 		if k.Spec.SourceRef.Kind == kind {
-			namespace := k.GetNamespace()
-			if k.Spec.SourceRef.Namespace != "" {
-				namespace = k.Spec.SourceRef.Namespace
+			keys = append(keys, sourceKey(k, k.Spec.SourceRef))
+		}
+		for _, overlay := range k.Spec.OverlaySources {
+			if overlay.SourceRef.Kind == kind {
+				keys = append(keys, sourceKey(k, overlay.SourceRef))
 			}
-			return []string{fmt.Sprintf("%s/%s", namespace, k.Spec.SourceRef.Name)}
 		}
 
-		return nil
+		return keys
+	}
+}
+
+// sourceKey returns the namespace/name key of the source reference, which
+// defaults to the namespace of the Kustomization.
+func sourceKey(obj *kustomizev1.Kustomization, ref kustomizev1.CrossNamespaceSourceReference) string {
+	namespace := obj.GetNamespace()
+	if ref.Namespace != "" {
+		namespace = ref.Namespace
+	}
+	return fmt.Sprintf("%s/%s", namespace, ref.Name)
+}
+
+// overlayRevision returns the last attempted revision of the overlay source
+// with the namespace/name key, and false if the key is not of an overlay
+// source or is of the source of the Kustomization.
+func overlayRevision(obj *kustomizev1.Kustomization, key string) (string, bool) {
+	if sourceKey(obj, obj.Spec.SourceRef) == key {
+		return "", false
+	}
+	for _, overlay := range obj.Spec.OverlaySources {
+		if sourceKey(obj, overlay.SourceRef) != key {
+			continue
+		}
+		for _, r := range obj.Status.LastAttemptedOverlayRevisions {
+			if r.Source == overlay.SourceRef.Kind+"/"+key {
+				return r.Revision, true
+			}
+		}
+		return "", true
 	}
+	return "", false
 }
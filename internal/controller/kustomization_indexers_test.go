@@ -0,0 +1,238 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+)
+
+func TestSourceChangeAffects(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		filter   *kustomizev1.SourceChangeFilter
+		metadata map[string]string
+		want     bool
+	}{
+		{
+			name:     "without filter",
+			path:     "./apps/frontend",
+			metadata: map[string]string{kustomizev1.ChangedPathsMetadataKey: "apps/backend/deploy.yaml"},
+			want:     true,
+		},
+		{
+			name:   "without changed paths metadata",
+			path:   "./apps/frontend",
+			filter: &kustomizev1.SourceChangeFilter{},
+			want:   true,
+		},
+		{
+			name:     "with changes under the path",
+			path:     "./apps/frontend",
+			filter:   &kustomizev1.SourceChangeFilter{},
+			metadata: map[string]string{kustomizev1.ChangedPathsMetadataKey: "apps/backend/deploy.yaml,apps/frontend/deploy.yaml"},
+			want:     true,
+		},
+		{
+			name:     "with changes outside the path",
+			path:     "./apps/frontend",
+			filter:   &kustomizev1.SourceChangeFilter{},
+			metadata: map[string]string{kustomizev1.ChangedPathsMetadataKey: "apps/backend/deploy.yaml\napps/frontend-v2/deploy.yaml"},
+			want:     false,
+		},
+		{
+			name:     "with changes under the filter paths",
+			path:     "./apps/frontend",
+			filter:   &kustomizev1.SourceChangeFilter{Paths: []string{"./apps/base/"}},
+			metadata: map[string]string{kustomizev1.ChangedPathsMetadataKey: "/apps/base/deploy.yaml"},
+			want:     true,
+		},
+		{
+			name:     "with the source root as path",
+			path:     "./",
+			filter:   &kustomizev1.SourceChangeFilter{},
+			metadata: map[string]string{kustomizev1.ChangedPathsMetadataKey: "README.md"},
+			want:     true,
+		},
+		{
+			name:     "with changes under the directory of the path variables",
+			path:     "./clusters/${CLUSTER_NAME}/apps",
+			filter:   &kustomizev1.SourceChangeFilter{},
+			metadata: map[string]string{kustomizev1.ChangedPathsMetadataKey: "clusters/staging/apps/deploy.yaml"},
+			want:     true,
+		},
+		{
+			name:     "with changes outside the directory of the path variables",
+			path:     "./clusters/${CLUSTER_NAME}/apps",
+			filter:   &kustomizev1.SourceChangeFilter{},
+			metadata: map[string]string{kustomizev1.ChangedPathsMetadataKey: "docs/README.md"},
+			want:     false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			obj := &kustomizev1.Kustomization{
+				Spec: kustomizev1.KustomizationSpec{
+					Path:               tt.path,
+					SourceChangeFilter: tt.filter,
+				},
+			}
+			artifact := &sourcev1.Artifact{Metadata: tt.metadata}
+			g.Expect(sourceChangeAffects(obj, artifact)).To(Equal(tt.want))
+		})
+	}
+}
+
+func TestIndexBySubstituteFrom(t *testing.T) {
+	g := NewWithT(t)
+
+	obj := &kustomizev1.Kustomization{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "apps",
+			Namespace: "flux-system",
+		},
+		Spec: kustomizev1.KustomizationSpec{
+			PostBuild: &kustomizev1.PostBuild{
+				SubstituteFrom: []kustomizev1.SubstituteReference{
+					{Kind: "ConfigMap", Name: "cluster-vars"},
+					{Kind: "Secret", Name: "cluster-secrets", Optional: true},
+					{Kind: "ConfigMap", Name: "tenant-vars"},
+				},
+			},
+		},
+	}
+
+	r := &KustomizationReconciler{}
+	g.Expect(r.indexBySubstituteFrom("ConfigMap")(obj)).To(Equal([]string{
+		"flux-system/cluster-vars",
+		"flux-system/tenant-vars",
+	}))
+	g.Expect(r.indexBySubstituteFrom("Secret")(obj)).To(Equal([]string{
+		"flux-system/cluster-secrets",
+	}))
+
+	obj.Spec.PostBuild = nil
+	g.Expect(r.indexBySubstituteFrom("ConfigMap")(obj)).To(BeEmpty())
+}
+
+func TestIndexByDecryptionSecret(t *testing.T) {
+	g := NewWithT(t)
+
+	obj := &kustomizev1.Kustomization{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "apps",
+			Namespace: "flux-system",
+		},
+		Spec: kustomizev1.KustomizationSpec{
+			Decryption: &kustomizev1.Decryption{
+				Provider: "sops",
+			},
+		},
+	}
+
+	r := &KustomizationReconciler{}
+	g.Expect(r.indexByDecryptionSecret(obj)).To(BeEmpty())
+
+	obj.Spec.Decryption.SecretRef = &meta.LocalObjectReference{Name: "sops-age"}
+	g.Expect(r.indexByDecryptionSecret(obj)).To(Equal([]string{"flux-system/sops-age"}))
+}
+
+func TestIndexByDependsOn(t *testing.T) {
+	g := NewWithT(t)
+
+	obj := &kustomizev1.Kustomization{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "apps",
+			Namespace: "flux-system",
+		},
+	}
+
+	r := &KustomizationReconciler{}
+	g.Expect(r.indexByDependsOn(obj)).To(BeEmpty())
+
+	obj.Spec.DependsOn = []meta.NamespacedObjectReference{
+		{Name: "infra"},
+		{Name: "crds", Namespace: "cluster-system"},
+	}
+	g.Expect(r.indexByDependsOn(obj)).To(Equal([]string{"flux-system/infra", "cluster-system/crds"}))
+}
+
+func TestIndexByChildren(t *testing.T) {
+	g := NewWithT(t)
+
+	obj := &kustomizev1.Kustomization{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "apps",
+			Namespace: "flux-system",
+		},
+		Status: kustomizev1.KustomizationStatus{
+			Children: []kustomizev1.ChildStatus{
+				{Name: "frontend", Namespace: "flux-system"},
+				{Name: "backend", Namespace: "apps"},
+			},
+		},
+	}
+
+	r := &KustomizationReconciler{}
+	g.Expect(r.indexByChildren(obj)).To(BeEmpty())
+
+	obj.Spec.AggregateChildren = true
+	g.Expect(r.indexByChildren(obj)).To(Equal([]string{"flux-system/frontend", "apps/backend"}))
+
+	obj.Spec.KubeConfig = &meta.KubeConfigReference{}
+	g.Expect(r.indexByChildren(obj)).To(BeEmpty())
+}
+
+func TestIndexByInventory(t *testing.T) {
+	g := NewWithT(t)
+
+	obj := &kustomizev1.Kustomization{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "apps",
+			Namespace: "flux-system",
+		},
+		Status: kustomizev1.KustomizationStatus{
+			Inventory: &kustomizev1.ResourceInventory{
+				Entries: []kustomizev1.ResourceRef{
+					{ID: "apps_config__ConfigMap", Version: "v1"},
+					{ID: "apps_web_apps_Deployment", Version: "v1"},
+				},
+			},
+		},
+	}
+
+	r := &KustomizationReconciler{}
+	g.Expect(r.indexByInventory(obj)).To(BeEmpty())
+
+	r.DetectOwnershipConflicts = true
+	g.Expect(r.indexByInventory(obj)).To(Equal([]string{"apps_config__ConfigMap", "apps_web_apps_Deployment"}))
+
+	r.DetectOwnershipConflicts, r.OwnerLookup = false, true
+	g.Expect(r.indexByInventory(obj)).To(HaveLen(2))
+
+	obj.Spec.KubeConfig = &meta.KubeConfigReference{}
+	g.Expect(r.indexByInventory(obj)).To(BeEmpty())
+}
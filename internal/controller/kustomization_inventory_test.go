@@ -37,6 +37,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+	"github.com/fluxcd/kustomize-controller/internal/inventory"
 )
 
 func TestKustomizationReconciler_Inventory(t *testing.T) {
@@ -190,6 +191,8 @@ stringData:
 
 		g.Expect(k8sClient.Get(context.Background(), configMapName, configMap)).To(Succeed())
 		g.Expect(configMap.Data["key"]).To(Equal(testVal))
+
+		g.Expect(resultK.Status.UnmanagedOverrides).To(ConsistOf(fmt.Sprintf("ConfigMap/%s/%s", id, id)))
 	})
 
 	t.Run("corrects drift", func(t *testing.T) {
@@ -213,6 +216,8 @@ stringData:
 
 		g.Expect(k8sClient.Get(context.Background(), configMapName, configMap)).To(Succeed())
 		g.Expect(configMap.Data["key"]).To(Equal(id))
+
+		g.Expect(resultK.Status.UnmanagedOverrides).To(BeEmpty())
 	})
 
 	t.Run("renames resources", func(t *testing.T) {
@@ -271,3 +276,103 @@ stringData:
 		g.Expect(configMap.Data["key"]).To(Equal(id))
 	})
 }
+
+func TestKustomizationReconciler_InventoryExport(t *testing.T) {
+	g := NewWithT(t)
+	id := "inv-" + randStringRunes(5)
+	revision := "v1.0.0"
+
+	err := createNamespace(id)
+	g.Expect(err).NotTo(HaveOccurred(), "failed to create test namespace")
+
+	err = createKubeConfigSecret(id)
+	g.Expect(err).NotTo(HaveOccurred(), "failed to create kubeconfig secret")
+
+	artifact, err := testServer.ArtifactFromFiles([]testserver.File{
+		{
+			Name: "config.yaml",
+			Body: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app
+data:
+  key: value
+`,
+		},
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	repositoryName := types.NamespacedName{
+		Name:      fmt.Sprintf("inv-%s", randStringRunes(5)),
+		Namespace: id,
+	}
+
+	err = applyGitRepository(repositoryName, artifact, revision)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	kustomization := &kustomizev1.Kustomization{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("inv-%s", randStringRunes(5)),
+			Namespace: id,
+		},
+		Spec: kustomizev1.KustomizationSpec{
+			Interval: metav1.Duration{Duration: 2 * time.Minute},
+			Path:     "./",
+			KubeConfig: &meta.KubeConfigReference{
+				SecretRef: meta.SecretKeyReference{
+					Name: "kubeconfig",
+				},
+			},
+			SourceRef: kustomizev1.CrossNamespaceSourceReference{
+				Name:      repositoryName.Name,
+				Namespace: repositoryName.Namespace,
+				Kind:      sourcev1.GitRepositoryKind,
+			},
+			TargetNamespace: id,
+			Prune:           true,
+			InventoryExport: &kustomizev1.InventoryExport{
+				Name: "app-inventory",
+			},
+		},
+	}
+
+	g.Expect(k8sClient.Create(context.Background(), kustomization)).To(Succeed())
+
+	resultK := &kustomizev1.Kustomization{}
+	g.Eventually(func() bool {
+		_ = k8sClient.Get(context.Background(), client.ObjectKeyFromObject(kustomization), resultK)
+		ready := apimeta.IsStatusConditionTrue(resultK.Status.Conditions, meta.ReadyCondition)
+		return ready && resultK.Status.LastAppliedRevision == revision
+	}, timeout, time.Second).Should(BeTrue())
+
+	applySetID := inventory.ApplySetID("app-inventory", id)
+	exportName := types.NamespacedName{Name: "app-inventory", Namespace: id}
+
+	t.Run("records the inventory", func(t *testing.T) {
+		g := NewWithT(t)
+		export := &corev1.ConfigMap{}
+		g.Expect(k8sClient.Get(context.Background(), exportName, export)).To(Succeed())
+		g.Expect(export.GetLabels()).To(HaveKeyWithValue("applyset.kubernetes.io/id", applySetID))
+		g.Expect(export.GetAnnotations()).To(HaveKeyWithValue("applyset.kubernetes.io/contains-group-kinds", "ConfigMap"))
+		g.Expect(export.Data).To(Equal(map[string]string{
+			fmt.Sprintf("%s_app__ConfigMap", id): "",
+		}))
+
+		app := &corev1.ConfigMap{}
+		g.Expect(k8sClient.Get(context.Background(), types.NamespacedName{Name: "app", Namespace: id}, app)).To(Succeed())
+		g.Expect(app.GetLabels()).To(HaveKeyWithValue(inventory.ApplySetPartOfLabel, applySetID))
+		g.Expect(app.GetAnnotations()).To(HaveKeyWithValue("config.k8s.io/owning-inventory", applySetID))
+	})
+
+	t.Run("deletes the export with the objects", func(t *testing.T) {
+		g := NewWithT(t)
+		g.Expect(k8sClient.Delete(context.Background(), kustomization)).To(Succeed())
+		g.Eventually(func() bool {
+			err := k8sClient.Get(context.Background(), client.ObjectKeyFromObject(kustomization), kustomization)
+			return apierrors.IsNotFound(err)
+		}, timeout, time.Second).Should(BeTrue())
+
+		err := k8sClient.Get(context.Background(), exportName, &corev1.ConfigMap{})
+		g.Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+}
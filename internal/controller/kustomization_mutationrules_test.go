@@ -0,0 +1,164 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/fluxcd/pkg/runtime/conditions"
+	"github.com/fluxcd/pkg/testserver"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+)
+
+func TestKustomizationReconciler_MutationRules(t *testing.T) {
+	g := NewWithT(t)
+	id := "mut-" + randStringRunes(5)
+	revision := "v1.0.0"
+
+	err := createNamespace(id)
+	g.Expect(err).NotTo(HaveOccurred(), "failed to create test namespace")
+
+	rules := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "mutation-rules", Namespace: id},
+		Data: map[string]string{
+			"owner-label": `kinds: [ConfigMap]
+selector:
+  matchLabels:
+    mutate: "true"
+patch:
+- op: add
+  path: /metadata/labels/owner
+  valueExpression: "object.metadata.name + '-team'"
+`,
+			"data-key": `kinds: [ConfigMap]
+selector:
+  matchLabels:
+    mutate: "true"
+condition: "!has(object.data.injected)"
+patch:
+- op: add
+  path: /data/injected
+  value: "true"
+`,
+		},
+	}
+	g.Expect(k8sClient.Create(context.Background(), rules)).To(Succeed())
+
+	reconciler.MutationRules = client.ObjectKeyFromObject(rules)
+	defer func() {
+		reconciler.MutationRules = types.NamespacedName{}
+	}()
+
+	artifact, err := testServer.ArtifactFromFiles([]testserver.File{
+		{
+			Name: "config.yaml",
+			Body: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+  labels:
+    mutate: "true"
+data:
+  key: value
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: other
+data:
+  key: value
+`,
+		},
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	repositoryName := types.NamespacedName{
+		Name:      fmt.Sprintf("mut-%s", randStringRunes(5)),
+		Namespace: id,
+	}
+
+	err = applyGitRepository(repositoryName, artifact, revision)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	kustomization := &kustomizev1.Kustomization{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      id,
+			Namespace: id,
+		},
+		Spec: kustomizev1.KustomizationSpec{
+			Interval:        metav1.Duration{Duration: reconciliationInterval},
+			Path:            "./",
+			TargetNamespace: id,
+			Prune:           true,
+			SourceRef: kustomizev1.CrossNamespaceSourceReference{
+				Name:      repositoryName.Name,
+				Namespace: repositoryName.Namespace,
+				Kind:      sourcev1.GitRepositoryKind,
+			},
+			SkipMutationRules: []string{"data-key"},
+		},
+	}
+	g.Expect(k8sClient.Create(context.Background(), kustomization)).To(Succeed())
+
+	resultK := &kustomizev1.Kustomization{}
+	g.Eventually(func() bool {
+		_ = k8sClient.Get(context.Background(), client.ObjectKeyFromObject(kustomization), resultK)
+		return conditions.IsReady(resultK) && resultK.Status.LastAppliedRevision == revision
+	}, timeout, time.Second).Should(BeTrue())
+
+	t.Run("applies the rules to the selected objects", func(t *testing.T) {
+		g := NewWithT(t)
+		config := &corev1.ConfigMap{}
+		g.Expect(k8sClient.Get(context.Background(), types.NamespacedName{Name: "config", Namespace: id}, config)).To(Succeed())
+		g.Expect(config.Labels).To(HaveKeyWithValue("owner", "config-team"))
+		g.Expect(config.Data).NotTo(HaveKey("injected"))
+
+		other := &corev1.ConfigMap{}
+		g.Expect(k8sClient.Get(context.Background(), types.NamespacedName{Name: "other", Namespace: id}, other)).To(Succeed())
+		g.Expect(other.Labels).NotTo(HaveKey("owner"))
+	})
+
+	t.Run("fails the reconciliation on invalid rules", func(t *testing.T) {
+		g := NewWithT(t)
+		rules.Data["invalid"] = `patch: []`
+		g.Expect(k8sClient.Update(context.Background(), rules)).To(Succeed())
+
+		err = applyGitRepository(repositoryName, artifact, "v2.0.0")
+		g.Expect(err).NotTo(HaveOccurred())
+
+		g.Eventually(func() bool {
+			_ = k8sClient.Get(context.Background(), client.ObjectKeyFromObject(kustomization), resultK)
+			return conditions.IsFalse(resultK, meta.ReadyCondition) &&
+				resultK.Status.LastAttemptedRevision == "v2.0.0"
+		}, timeout, time.Second).Should(BeTrue())
+
+		g.Expect(conditions.GetReason(resultK, meta.ReadyCondition)).To(Equal(kustomizev1.BuildFailedReason))
+		g.Expect(conditions.GetMessage(resultK, meta.ReadyCondition)).To(ContainSubstring(
+			"invalid mutation rule 'invalid': the patch is empty"))
+	})
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/fluxcd/pkg/testserver"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+)
+
+func TestKustomizationReconciler_NamespaceReport(t *testing.T) {
+	g := NewWithT(t)
+	id := "nsreport-" + randStringRunes(5)
+	revision := "v1.0.0"
+
+	reconciler.NamespaceReports = true
+	defer func() {
+		reconciler.NamespaceReports = false
+	}()
+
+	err := createNamespace(id)
+	g.Expect(err).NotTo(HaveOccurred(), "failed to create test namespace")
+
+	artifact, err := testServer.ArtifactFromFiles([]testserver.File{
+		{
+			Name: "apps/config.yaml",
+			Body: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+data:
+  key: value
+`,
+		},
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	repositoryName := types.NamespacedName{
+		Name:      fmt.Sprintf("nsreport-%s", randStringRunes(5)),
+		Namespace: id,
+	}
+	err = applyGitRepository(repositoryName, artifact, revision)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	kustomization := func(name, path string) *kustomizev1.Kustomization {
+		return &kustomizev1.Kustomization{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: id,
+			},
+			Spec: kustomizev1.KustomizationSpec{
+				Interval: metav1.Duration{Duration: reconciliationInterval},
+				Path:     path,
+				SourceRef: kustomizev1.CrossNamespaceSourceReference{
+					Name: repositoryName.Name,
+					Kind: sourcev1.GitRepositoryKind,
+				},
+				TargetNamespace: id,
+				Timeout:         &metav1.Duration{Duration: 10 * time.Second},
+			},
+		}
+	}
+	apps := kustomization("apps", "./apps")
+	missing := kustomization("missing", "./missing")
+	g.Expect(k8sClient.Create(context.Background(), apps)).To(Succeed())
+	g.Expect(k8sClient.Create(context.Background(), missing)).To(Succeed())
+
+	reportKey := types.NamespacedName{Name: kustomizev1.KustomizationReportName, Namespace: id}
+	report := &kustomizev1.KustomizationReport{}
+
+	t.Run("summarizes the Kustomizations of the namespace", func(t *testing.T) {
+		g.Eventually(func() bool {
+			if err := k8sClient.Get(context.Background(), reportKey, report); err != nil {
+				return false
+			}
+			return report.Status.Ready == 1 && report.Status.Failing == 1
+		}, timeout, time.Second).Should(BeTrue())
+
+		g.Expect(report.Status.Total).To(Equal(2))
+		g.Expect(report.Status.FailingNames).To(Equal([]string{"missing"}))
+		g.Expect(report.Status.Kustomizations).To(HaveLen(2))
+		g.Expect(report.Status.Kustomizations[0].Name).To(Equal("apps"))
+		g.Expect(report.Status.Kustomizations[0].Ready).To(Equal(metav1.ConditionTrue))
+		g.Expect(report.Status.Kustomizations[0].LastAppliedRevision).To(Equal(revision))
+		g.Expect(report.Status.Kustomizations[1].Reason).To(Equal(kustomizev1.ArtifactFailedReason))
+	})
+
+	t.Run("deletes the report with the last Kustomization", func(t *testing.T) {
+		g.Expect(k8sClient.Delete(context.Background(), missing)).To(Succeed())
+		g.Eventually(func() bool {
+			if err := k8sClient.Get(context.Background(), reportKey, report); err != nil {
+				return false
+			}
+			return report.Status.Total == 1
+		}, timeout, time.Second).Should(BeTrue())
+
+		g.Expect(k8sClient.Delete(context.Background(), apps)).To(Succeed())
+		g.Eventually(func() bool {
+			err := k8sClient.Get(context.Background(), reportKey, report)
+			return apierrors.IsNotFound(err)
+		}, timeout, time.Second).Should(BeTrue())
+	})
+}
@@ -0,0 +1,152 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/fluxcd/pkg/runtime/conditions"
+	"github.com/fluxcd/pkg/testserver"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+)
+
+func TestKustomizationReconciler_ObserveMode(t *testing.T) {
+	g := NewWithT(t)
+	id := "obs-" + randStringRunes(5)
+	revision := "v1.0.0"
+	resultK := &kustomizev1.Kustomization{}
+
+	err := createNamespace(id)
+	g.Expect(err).NotTo(HaveOccurred(), "failed to create test namespace")
+
+	err = createKubeConfigSecret(id)
+	g.Expect(err).NotTo(HaveOccurred(), "failed to create kubeconfig secret")
+
+	for name, value := range map[string]string{"drifted": "old", "matching": "value"} {
+		cm := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: id},
+			Data:       map[string]string{"key": value},
+		}
+		g.Expect(k8sClient.Create(context.Background(), cm)).To(Succeed())
+	}
+
+	manifest := func(name, value string) string {
+		return fmt.Sprintf(`---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: %s
+data:
+  key: %s
+`, name, value)
+	}
+	artifact, err := testServer.ArtifactFromFiles([]testserver.File{
+		{
+			Name: "configmaps.yaml",
+			Body: manifest("drifted", "new") + manifest("matching", "value") + manifest("missing", "value"),
+		},
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	repositoryName := types.NamespacedName{
+		Name:      fmt.Sprintf("obs-%s", randStringRunes(5)),
+		Namespace: id,
+	}
+
+	err = applyGitRepository(repositoryName, artifact, revision)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	kustomization := &kustomizev1.Kustomization{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("obs-%s", randStringRunes(5)),
+			Namespace: id,
+		},
+		Spec: kustomizev1.KustomizationSpec{
+			Interval:        metav1.Duration{Duration: reconciliationInterval},
+			Path:            "./",
+			TargetNamespace: id,
+			Prune:           true,
+			Mode:            kustomizev1.ObserveMode,
+			KubeConfig: &meta.KubeConfigReference{
+				SecretRef: meta.SecretKeyReference{
+					Name: "kubeconfig",
+				},
+			},
+			SourceRef: kustomizev1.CrossNamespaceSourceReference{
+				Name:      repositoryName.Name,
+				Namespace: repositoryName.Namespace,
+				Kind:      sourcev1.GitRepositoryKind,
+			},
+		},
+	}
+
+	g.Expect(k8sClient.Create(context.Background(), kustomization)).To(Succeed())
+
+	t.Run("reports the differences without modifying the cluster", func(t *testing.T) {
+		g := NewWithT(t)
+		g.Eventually(func() bool {
+			_ = k8sClient.Get(context.Background(), client.ObjectKeyFromObject(kustomization), resultK)
+			return conditions.IsTrue(resultK, meta.ReadyCondition) &&
+				conditions.GetReason(resultK, meta.ReadyCondition) == kustomizev1.ObservationSucceededReason
+		}, timeout, time.Second).Should(BeTrue())
+
+		g.Expect(resultK.Status.Differences).To(ConsistOf(
+			fmt.Sprintf("ConfigMap/%s/drifted: drifted", id),
+			fmt.Sprintf("ConfigMap/%s/missing: missing", id),
+		))
+		g.Expect(resultK.Status.Inventory.Entries).To(HaveLen(2))
+		g.Expect(resultK.Status.LastAppliedRevision).To(BeEmpty())
+
+		drifted := &corev1.ConfigMap{}
+		g.Expect(k8sClient.Get(context.Background(), client.ObjectKey{Name: "drifted", Namespace: id}, drifted)).To(Succeed())
+		g.Expect(drifted.Data["key"]).To(Equal("old"))
+		g.Expect(drifted.GetLabels()).To(BeEmpty())
+
+		err := k8sClient.Get(context.Background(), client.ObjectKey{Name: "missing", Namespace: id}, &corev1.ConfigMap{})
+		g.Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	t.Run("applies when switching to the apply mode", func(t *testing.T) {
+		g := NewWithT(t)
+		resultK.Spec.Mode = kustomizev1.ApplyMode
+		g.Expect(k8sClient.Update(context.Background(), resultK)).To(Succeed())
+
+		g.Eventually(func() bool {
+			_ = k8sClient.Get(context.Background(), client.ObjectKeyFromObject(kustomization), resultK)
+			return isReconcileSuccess(resultK) && resultK.Status.LastAppliedRevision == revision
+		}, timeout, time.Second).Should(BeTrue())
+
+		g.Expect(resultK.Status.Differences).To(BeEmpty())
+		g.Expect(resultK.Status.Inventory.Entries).To(HaveLen(3))
+
+		drifted := &corev1.ConfigMap{}
+		g.Expect(k8sClient.Get(context.Background(), client.ObjectKey{Name: "drifted", Namespace: id}, drifted)).To(Succeed())
+		g.Expect(drifted.Data["key"]).To(Equal("new"))
+	})
+}
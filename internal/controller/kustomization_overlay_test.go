@@ -0,0 +1,182 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/fluxcd/pkg/runtime/conditions"
+	"github.com/fluxcd/pkg/testserver"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+)
+
+func TestKustomizationReconciler_OverlaySources(t *testing.T) {
+	g := NewWithT(t)
+	id := "overlay-" + randStringRunes(5)
+	revision := "v1.0.0"
+
+	err := createNamespace(id)
+	g.Expect(err).NotTo(HaveOccurred(), "failed to create test namespace")
+
+	baseManifests := func(tier string) []testserver.File {
+		return []testserver.File{
+			{
+				Name: "kustomization.yaml",
+				Body: `---
+apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+resources:
+- config.yaml
+`,
+			},
+			{
+				Name: "config.yaml",
+				Body: fmt.Sprintf(`---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app
+data:
+  tier: %s
+`, tier),
+			},
+		}
+	}
+
+	// The environment repository only contains the entrypoint, and a
+	// placeholder replaced by the base artifact.
+	envManifests := []testserver.File{
+		{
+			Name: "kustomization.yaml",
+			Body: `---
+apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+resources:
+- base
+namePrefix: staging-
+`,
+		},
+		{
+			Name: "base/README.md",
+			Body: "replaced by the base artifact",
+		},
+	}
+
+	envArtifact, err := testServer.ArtifactFromFiles(envManifests)
+	g.Expect(err).NotTo(HaveOccurred())
+	envName := types.NamespacedName{
+		Name:      fmt.Sprintf("env-%s", randStringRunes(5)),
+		Namespace: id,
+	}
+	g.Expect(applyGitRepository(envName, envArtifact, "main@sha1:env")).To(Succeed())
+
+	baseArtifact, err := testServer.ArtifactFromFiles(baseManifests("free"))
+	g.Expect(err).NotTo(HaveOccurred())
+	baseName := types.NamespacedName{
+		Name:      fmt.Sprintf("base-%s", randStringRunes(5)),
+		Namespace: id,
+	}
+	g.Expect(applyGitRepository(baseName, baseArtifact, revision)).To(Succeed())
+
+	kustomization := &kustomizev1.Kustomization{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("overlay-%s", randStringRunes(5)),
+			Namespace: id,
+		},
+		Spec: kustomizev1.KustomizationSpec{
+			Interval: metav1.Duration{Duration: reconciliationInterval},
+			Path:     "./",
+			SourceRef: kustomizev1.CrossNamespaceSourceReference{
+				Name: envName.Name,
+				Kind: sourcev1.GitRepositoryKind,
+			},
+			OverlaySources: []kustomizev1.OverlaySource{{
+				SourceRef: kustomizev1.CrossNamespaceSourceReference{
+					Name: baseName.Name,
+					Kind: sourcev1.GitRepositoryKind,
+				},
+				Path: "./base",
+			}},
+			TargetNamespace: id,
+			Timeout:         &metav1.Duration{Duration: 10 * time.Second},
+		},
+	}
+	g.Expect(k8sClient.Create(context.Background(), kustomization)).To(Succeed())
+
+	resultK := &kustomizev1.Kustomization{}
+	baseSource := fmt.Sprintf("%s/%s/%s", sourcev1.GitRepositoryKind, id, baseName.Name)
+
+	t.Run("builds the base artifact at the overlay path", func(t *testing.T) {
+		g.Eventually(func() bool {
+			_ = k8sClient.Get(context.Background(), client.ObjectKeyFromObject(kustomization), resultK)
+			return isReconcileSuccess(resultK)
+		}, timeout, time.Second).Should(BeTrue())
+		logStatus(t, resultK)
+
+		g.Expect(resultK.Status.LastAttemptedOverlayRevisions).To(Equal([]kustomizev1.OverlayRevision{
+			{Source: baseSource, Revision: revision},
+		}))
+
+		var cm corev1.ConfigMap
+		g.Expect(k8sClient.Get(context.Background(), types.NamespacedName{Name: "staging-app", Namespace: id}, &cm)).To(Succeed())
+		g.Expect(cm.Data["tier"]).To(Equal("free"))
+	})
+
+	t.Run("reconciles on a new revision of the overlay source", func(t *testing.T) {
+		baseArtifact, err := testServer.ArtifactFromFiles(baseManifests("premium"))
+		g.Expect(err).NotTo(HaveOccurred())
+		revision = "v2.0.0"
+		g.Expect(applyGitRepository(baseName, baseArtifact, revision)).To(Succeed())
+
+		g.Eventually(func() bool {
+			_ = k8sClient.Get(context.Background(), client.ObjectKeyFromObject(kustomization), resultK)
+			return len(resultK.Status.LastAttemptedOverlayRevisions) == 1 &&
+				resultK.Status.LastAttemptedOverlayRevisions[0].Revision == revision &&
+				isReconcileSuccess(resultK)
+		}, timeout, time.Second).Should(BeTrue())
+		logStatus(t, resultK)
+
+		var cm corev1.ConfigMap
+		g.Expect(k8sClient.Get(context.Background(), types.NamespacedName{Name: "staging-app", Namespace: id}, &cm)).To(Succeed())
+		g.Expect(cm.Data["tier"]).To(Equal("premium"))
+	})
+
+	t.Run("fails when the overlay source is missing", func(t *testing.T) {
+		patch := client.MergeFrom(resultK.DeepCopy())
+		resultK.Spec.OverlaySources[0].SourceRef.Name = "missing"
+		g.Expect(k8sClient.Patch(context.Background(), resultK, patch)).To(Succeed())
+
+		g.Eventually(func() bool {
+			_ = k8sClient.Get(context.Background(), client.ObjectKeyFromObject(kustomization), resultK)
+			return conditions.GetReason(resultK, meta.ReadyCondition) == kustomizev1.ArtifactFailedReason
+		}, timeout, time.Second).Should(BeTrue())
+		logStatus(t, resultK)
+
+		g.Expect(conditions.GetMessage(resultK, meta.ReadyCondition)).To(ContainSubstring("overlay source"))
+	})
+}
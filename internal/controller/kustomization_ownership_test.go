@@ -0,0 +1,116 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/fluxcd/pkg/runtime/conditions"
+	"github.com/fluxcd/pkg/testserver"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+)
+
+func TestKustomizationReconciler_OwnershipConflict(t *testing.T) {
+	g := NewWithT(t)
+	id := "ownership-" + randStringRunes(5)
+	revision := "v1.0.0"
+
+	reconciler.DetectOwnershipConflicts = true
+	defer func() {
+		reconciler.DetectOwnershipConflicts = false
+	}()
+
+	err := createNamespace(id)
+	g.Expect(err).NotTo(HaveOccurred(), "failed to create test namespace")
+
+	artifact, err := testServer.ArtifactFromFiles([]testserver.File{
+		{
+			Name: "config.yaml",
+			Body: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+data:
+  key: value
+`,
+		},
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	repositoryName := types.NamespacedName{
+		Name:      fmt.Sprintf("ownership-%s", randStringRunes(5)),
+		Namespace: id,
+	}
+
+	err = applyGitRepository(repositoryName, artifact, revision)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	newKustomization := func(name string) *kustomizev1.Kustomization {
+		return &kustomizev1.Kustomization{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: id,
+			},
+			Spec: kustomizev1.KustomizationSpec{
+				Interval:        metav1.Duration{Duration: reconciliationInterval},
+				Path:            "./",
+				TargetNamespace: id,
+				Prune:           true,
+				SourceRef: kustomizev1.CrossNamespaceSourceReference{
+					Name:      repositoryName.Name,
+					Namespace: repositoryName.Namespace,
+					Kind:      sourcev1.GitRepositoryKind,
+				},
+			},
+		}
+	}
+
+	first := newKustomization("first")
+	g.Expect(k8sClient.Create(context.Background(), first)).To(Succeed())
+
+	resultK := &kustomizev1.Kustomization{}
+	g.Eventually(func() bool {
+		_ = k8sClient.Get(context.Background(), client.ObjectKeyFromObject(first), resultK)
+		return conditions.IsReady(resultK) && resultK.Status.LastAppliedRevision == revision
+	}, timeout, time.Second).Should(BeTrue())
+
+	second := newKustomization("second")
+	g.Expect(k8sClient.Create(context.Background(), second)).To(Succeed())
+
+	g.Eventually(func() bool {
+		_ = k8sClient.Get(context.Background(), client.ObjectKeyFromObject(second), resultK)
+		return conditions.GetReason(resultK, meta.ReadyCondition) == kustomizev1.OwnershipConflictReason
+	}, timeout, time.Second).Should(BeTrue())
+
+	g.Expect(conditions.GetMessage(resultK, meta.ReadyCondition)).To(ContainSubstring(
+		fmt.Sprintf("ConfigMap/%[1]s/config is managed by both Kustomization %[1]s/second and %[1]s/first", id)))
+	g.Expect(resultK.Status.Inventory).To(BeNil())
+
+	// The first Kustomization keeps managing the object.
+	g.Expect(k8sClient.Get(context.Background(), client.ObjectKeyFromObject(first), resultK)).To(Succeed())
+	g.Expect(conditions.IsReady(resultK)).To(BeTrue())
+}
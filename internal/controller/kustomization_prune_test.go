@@ -441,3 +441,142 @@ data:
 	})
 
 }
+
+func TestKustomizationReconciler_PruneRetainedGenerations(t *testing.T) {
+	g := NewWithT(t)
+	id := "gc-" + randStringRunes(5)
+	revision := "v1.0.0"
+
+	err := createNamespace(id)
+	g.Expect(err).NotTo(HaveOccurred(), "failed to create test namespace")
+
+	manifests := func(data string) []testserver.File {
+		return []testserver.File{
+			{
+				Name: "kustomization.yaml",
+				Body: fmt.Sprintf(`---
+apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+configMapGenerator:
+- name: app-config
+  literals:
+  - key=%s
+`, data),
+			},
+		}
+	}
+
+	artifact, err := testServer.ArtifactFromFiles(manifests(revision))
+	g.Expect(err).NotTo(HaveOccurred())
+
+	repositoryName := types.NamespacedName{
+		Name:      fmt.Sprintf("gc-%s", randStringRunes(5)),
+		Namespace: id,
+	}
+
+	err = applyGitRepository(repositoryName, artifact, revision)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	kustomization := &kustomizev1.Kustomization{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("gc-%s", randStringRunes(5)),
+			Namespace: id,
+		},
+		Spec: kustomizev1.KustomizationSpec{
+			Interval: metav1.Duration{Duration: reconciliationInterval},
+			Path:     "./",
+			SourceRef: kustomizev1.CrossNamespaceSourceReference{
+				Name:      repositoryName.Name,
+				Namespace: repositoryName.Namespace,
+				Kind:      sourcev1.GitRepositoryKind,
+			},
+			TargetNamespace:     id,
+			Prune:               true,
+			RetainedGenerations: 1,
+		},
+	}
+
+	g.Expect(k8sClient.Create(context.Background(), kustomization)).To(Succeed())
+
+	resultK := &kustomizev1.Kustomization{}
+	listConfigMaps := func() []string {
+		list := &corev1.ConfigMapList{}
+		g.Expect(k8sClient.List(context.Background(), list, client.InNamespace(id), client.MatchingLabels{
+			"kustomize.toolkit.fluxcd.io/name": kustomization.Name,
+		})).To(Succeed())
+		var names []string
+		for _, cm := range list.Items {
+			names = append(names, cm.Name)
+		}
+		return names
+	}
+	inventoryIDs := func() []string {
+		var ids []string
+		for _, e := range resultK.Status.Inventory.Entries {
+			ids = append(ids, e.ID)
+		}
+		return ids
+	}
+
+	g.Eventually(func() bool {
+		_ = k8sClient.Get(context.Background(), client.ObjectKeyFromObject(kustomization), resultK)
+		return resultK.Status.LastAppliedRevision == revision
+	}, timeout, time.Second).Should(BeTrue())
+
+	firstGeneration := listConfigMaps()
+	g.Expect(firstGeneration).To(HaveLen(1))
+
+	var secondGeneration []string
+	t.Run("retains the previous generation", func(t *testing.T) {
+		// Ensure the generations have distinct creation timestamps.
+		time.Sleep(time.Second)
+
+		revision := "v2.0.0"
+		artifact, err := testServer.ArtifactFromFiles(manifests(revision))
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(applyGitRepository(repositoryName, artifact, revision)).To(Succeed())
+
+		g.Eventually(func() bool {
+			_ = k8sClient.Get(context.Background(), client.ObjectKeyFromObject(kustomization), resultK)
+			return resultK.Status.LastAppliedRevision == revision
+		}, timeout, time.Second).Should(BeTrue())
+
+		names := listConfigMaps()
+		g.Expect(names).To(HaveLen(2))
+		g.Expect(names).To(ContainElement(firstGeneration[0]))
+		g.Expect(inventoryIDs()).To(ContainElement(fmt.Sprintf("%s_%s__ConfigMap", id, firstGeneration[0])))
+		for _, name := range names {
+			if name != firstGeneration[0] {
+				secondGeneration = append(secondGeneration, name)
+			}
+		}
+	})
+
+	t.Run("deletes older generations", func(t *testing.T) {
+		revision := "v3.0.0"
+		artifact, err := testServer.ArtifactFromFiles(manifests(revision))
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(applyGitRepository(repositoryName, artifact, revision)).To(Succeed())
+
+		g.Eventually(func() bool {
+			_ = k8sClient.Get(context.Background(), client.ObjectKeyFromObject(kustomization), resultK)
+			return resultK.Status.LastAppliedRevision == revision
+		}, timeout, time.Second).Should(BeTrue())
+
+		names := listConfigMaps()
+		g.Expect(names).To(HaveLen(2))
+		g.Expect(names).ToNot(ContainElement(firstGeneration[0]))
+		g.Expect(names).To(ContainElement(secondGeneration[0]))
+		g.Expect(inventoryIDs()).ToNot(ContainElement(fmt.Sprintf("%s_%s__ConfigMap", id, firstGeneration[0])))
+	})
+
+	t.Run("deletes all generations on finalization", func(t *testing.T) {
+		g.Expect(k8sClient.Delete(context.Background(), kustomization)).To(Succeed())
+		g.Eventually(func() bool {
+			err = k8sClient.Get(context.Background(), client.ObjectKeyFromObject(kustomization), kustomization)
+			return apierrors.IsNotFound(err)
+		}, timeout, time.Second).Should(BeTrue())
+
+		g.Expect(listConfigMaps()).To(BeEmpty())
+	})
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fluxcd/cli-utils/pkg/object"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+	"github.com/fluxcd/kustomize-controller/internal/inventory"
+)
+
+func TestKustomizationReconciler_PendingRecreations(t *testing.T) {
+	g := NewWithT(t)
+	id := "recreate-" + randStringRunes(5)
+
+	err := createNamespace(id)
+	g.Expect(err).NotTo(HaveOccurred(), "failed to create test namespace")
+
+	existing := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "existing", Namespace: id}}
+	g.Expect(k8sClient.Create(context.Background(), existing)).To(Succeed())
+
+	configMap := func(name string, labels map[string]string) *unstructured.Unstructured {
+		u := &unstructured.Unstructured{}
+		u.SetAPIVersion("v1")
+		u.SetKind("ConfigMap")
+		u.SetNamespace(id)
+		u.SetName(name)
+		u.SetLabels(labels)
+		return u
+	}
+	forced := map[string]string{"kustomize.toolkit.fluxcd.io/force": kustomizev1.EnabledValue}
+	objects := []*unstructured.Unstructured{
+		configMap("existing", forced),
+		configMap("deleted", forced),
+		configMap("not-forced", nil),
+		configMap("new", forced),
+	}
+
+	// The "new" object has never been applied, so it wasn't deleted by
+	// a forced apply.
+	inv := inventory.New()
+	inventory.AddObjects(inv, objects[:3])
+	obj := &kustomizev1.Kustomization{
+		ObjectMeta: metav1.ObjectMeta{Name: id, Namespace: id},
+		Status:     kustomizev1.KustomizationStatus{Inventory: inv},
+	}
+
+	t.Run("records the deleted forced objects", func(t *testing.T) {
+		reconciler.recordPendingRecreations(context.Background(), k8sClient, obj, "v1", objects)
+		g.Expect(obj.Status.PendingRecreations).To(Equal([]string{"ConfigMap/" + id + "/deleted"}))
+	})
+
+	t.Run("keeps the pending objects until created", func(t *testing.T) {
+		obj.Status.Inventory = inventory.New()
+		reconciler.recordPendingRecreations(context.Background(), k8sClient, obj, "v2", objects)
+		g.Expect(obj.Status.PendingRecreations).To(Equal([]string{"ConfigMap/" + id + "/deleted"}))
+	})
+
+	t.Run("applies the pending objects first", func(t *testing.T) {
+		others, pending := splitPendingRecreations(obj, objects)
+		g.Expect(pending).To(HaveLen(1))
+		g.Expect(pending[0].GetName()).To(Equal("deleted"))
+		g.Expect(others).To(HaveLen(3))
+
+		// The digest of the deleted object matches the inventory, yet it
+		// is applied.
+		digest, err := inventory.Digest(pending[0])
+		g.Expect(err).NotTo(HaveOccurred())
+		inventory.AddObjects(obj.Status.Inventory, pending)
+		inventory.SetDigests(obj.Status.Inventory, map[string]string{
+			object.UnstructuredToObjMetadata(pending[0]).String(): digest,
+		})
+		toApply, unchanged, _, err := splitUnchanged(obj, pending, false)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(unchanged).To(BeEmpty())
+		g.Expect(toApply).To(HaveLen(1))
+	})
+}
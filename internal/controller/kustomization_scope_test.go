@@ -0,0 +1,113 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/fluxcd/pkg/testserver"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+)
+
+func TestKustomizationReconciler_NamespaceScope(t *testing.T) {
+	g := NewWithT(t)
+	id := "scope-" + randStringRunes(5)
+	revision := "v1.0.0"
+
+	err := createNamespace(id)
+	g.Expect(err).NotTo(HaveOccurred(), "failed to create test namespace")
+
+	artifact, err := testServer.ArtifactFromFiles([]testserver.File{
+		{
+			Name: "config.yaml",
+			Body: `---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: without-namespace
+data:
+  key: value
+`,
+		},
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	repositoryName := types.NamespacedName{
+		Name:      fmt.Sprintf("scope-%s", randStringRunes(5)),
+		Namespace: id,
+	}
+	g.Expect(applyGitRepository(repositoryName, artifact, revision)).To(Succeed())
+
+	kustomization := &kustomizev1.Kustomization{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("scope-%s", randStringRunes(5)),
+			Namespace: id,
+		},
+		Spec: kustomizev1.KustomizationSpec{
+			Interval: metav1.Duration{Duration: reconciliationInterval},
+			Path:     "./",
+			SourceRef: kustomizev1.CrossNamespaceSourceReference{
+				Name: repositoryName.Name,
+				Kind: sourcev1.GitRepositoryKind,
+			},
+			Prune: true,
+		},
+	}
+	g.Expect(k8sClient.Create(context.Background(), kustomization)).To(Succeed())
+
+	t.Run("fails for objects without namespace", func(t *testing.T) {
+		g := NewWithT(t)
+		g.Eventually(func() string {
+			var obj kustomizev1.Kustomization
+			_ = k8sClient.Get(context.Background(), client.ObjectKeyFromObject(kustomization), &obj)
+			ready := apimeta.FindStatusCondition(obj.Status.Conditions, meta.ReadyCondition)
+			if ready == nil || ready.Status != metav1.ConditionFalse {
+				return ""
+			}
+			return ready.Message
+		}, timeout, time.Second).Should(ContainSubstring("namespaced objects without namespace: ConfigMap/without-namespace"))
+	})
+
+	t.Run("inherits the namespace of the Kustomization", func(t *testing.T) {
+		g := NewWithT(t)
+
+		var obj kustomizev1.Kustomization
+		g.Expect(k8sClient.Get(context.Background(), client.ObjectKeyFromObject(kustomization), &obj)).To(Succeed())
+		obj.Spec.InheritNamespace = true
+		g.Expect(k8sClient.Update(context.Background(), &obj)).To(Succeed())
+
+		g.Eventually(func() bool {
+			_ = k8sClient.Get(context.Background(), client.ObjectKeyFromObject(kustomization), &obj)
+			return isReconcileSuccess(&obj)
+		}, timeout, time.Second).Should(BeTrue())
+
+		var cm corev1.ConfigMap
+		g.Expect(k8sClient.Get(context.Background(), types.NamespacedName{Namespace: id, Name: "without-namespace"}, &cm)).To(Succeed())
+	})
+}
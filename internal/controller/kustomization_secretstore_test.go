@@ -0,0 +1,205 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/fluxcd/pkg/runtime/conditions"
+	"github.com/fluxcd/pkg/testserver"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	"github.com/hashicorp/vault/api"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+)
+
+func TestKustomizationReconciler_ExternalSecretStore(t *testing.T) {
+	g := NewWithT(t)
+	id := "store-" + randStringRunes(5)
+	revision := "v1.0.0"
+
+	err := createNamespace(id)
+	g.Expect(err).NotTo(HaveOccurred(), "failed to create test namespace")
+
+	tokenSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "vault-token",
+			Namespace: id,
+		},
+		StringData: map[string]string{
+			"token": os.Getenv("VAULT_TOKEN"),
+		},
+	}
+	g.Expect(k8sClient.Create(context.Background(), tokenSecret)).To(Succeed())
+
+	manifests := []testserver.File{
+		{
+			Name: "crd.yaml",
+			Body: `---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: externalsecrets.external-secrets.io
+spec:
+  group: external-secrets.io
+  names:
+    kind: ExternalSecret
+    listKind: ExternalSecretList
+    plural: externalsecrets
+    singular: externalsecret
+  scope: Namespaced
+  versions:
+    - name: v1beta1
+      schema:
+        openAPIV3Schema:
+          type: object
+          x-kubernetes-preserve-unknown-fields: true
+      served: true
+      storage: true
+`,
+		},
+		{
+			Name: "secret.yaml",
+			Body: fmt.Sprintf(`---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: %[1]s
+  namespace: %[1]s
+  annotations:
+    kustomize.toolkit.fluxcd.io/external-store: enabled
+stringData:
+  password: "%[2]s"
+`, id, "top-secret"),
+		},
+	}
+
+	artifact, err := testServer.ArtifactFromFiles(manifests)
+	g.Expect(err).NotTo(HaveOccurred(), "failed to create artifact from files")
+
+	repositoryName := types.NamespacedName{
+		Name:      fmt.Sprintf("store-%s", randStringRunes(5)),
+		Namespace: id,
+	}
+
+	err = applyGitRepository(repositoryName, artifact, revision)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	kustomization := &kustomizev1.Kustomization{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("store-%s", randStringRunes(5)),
+			Namespace: id,
+		},
+		Spec: kustomizev1.KustomizationSpec{
+			Interval: metav1.Duration{Duration: reconciliationInterval},
+			Path:     "./",
+			Prune:    true,
+			SourceRef: kustomizev1.CrossNamespaceSourceReference{
+				Name:      repositoryName.Name,
+				Namespace: repositoryName.Namespace,
+				Kind:      sourcev1.GitRepositoryKind,
+			},
+			Decryption: &kustomizev1.Decryption{
+				Provider: "sops",
+				ExternalStore: &kustomizev1.ExternalSecretStore{
+					Provider: "vault",
+					Address:  os.Getenv("VAULT_ADDR"),
+					Path:     "secret/flux",
+					SecretRef: meta.LocalObjectReference{
+						Name: tokenSecret.Name,
+					},
+					StoreRef: kustomizev1.ExternalSecretStoreReference{
+						Name: "vault",
+					},
+				},
+			},
+		},
+	}
+
+	g.Expect(k8sClient.Create(context.Background(), kustomization)).To(Succeed())
+
+	resultK := &kustomizev1.Kustomization{}
+	g.Eventually(func() bool {
+		_ = k8sClient.Get(context.Background(), client.ObjectKeyFromObject(kustomization), resultK)
+		return isReconcileSuccess(resultK) && resultK.Status.LastAppliedRevision == revision
+	}, timeout, time.Second).Should(BeTrue())
+	logStatus(t, resultK)
+
+	t.Run("writes the secret values to the store", func(t *testing.T) {
+		g := NewWithT(t)
+		cli, err := api.NewClient(api.DefaultConfig())
+		g.Expect(err).NotTo(HaveOccurred())
+
+		s, err := cli.KVv2("secret").Get(context.Background(), fmt.Sprintf("flux/%[1]s/%[1]s", id))
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(s.Data).To(HaveKeyWithValue("password", "top-secret"))
+	})
+
+	t.Run("replaces the secret with an external secret", func(t *testing.T) {
+		g := NewWithT(t)
+		es := &unstructured.Unstructured{}
+		es.SetAPIVersion("external-secrets.io/v1beta1")
+		es.SetKind("ExternalSecret")
+		g.Expect(k8sClient.Get(context.Background(), types.NamespacedName{Name: id, Namespace: id}, es)).To(Succeed())
+
+		dataFrom, _, _ := unstructured.NestedSlice(es.Object, "spec", "dataFrom")
+		g.Expect(dataFrom).To(HaveLen(1))
+		g.Expect(dataFrom[0]).To(HaveKeyWithValue("extract",
+			map[string]interface{}{"key": fmt.Sprintf("flux/%[1]s/%[1]s", id)}))
+
+		err := k8sClient.Get(context.Background(), types.NamespacedName{Name: id, Namespace: id}, &corev1.Secret{})
+		g.Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	t.Run("doesn't write to the store in observe mode", func(t *testing.T) {
+		g := NewWithT(t)
+		observed := kustomization.DeepCopy()
+		observed.ObjectMeta = metav1.ObjectMeta{
+			Name:      fmt.Sprintf("store-observe-%s", randStringRunes(5)),
+			Namespace: id,
+		}
+		observed.Spec.Mode = kustomizev1.ObserveMode
+		observed.Spec.Prune = false
+		observed.Spec.Decryption.ExternalStore.Path = "secret/observe"
+		g.Expect(k8sClient.Create(context.Background(), observed)).To(Succeed())
+
+		resultO := &kustomizev1.Kustomization{}
+		g.Eventually(func() bool {
+			_ = k8sClient.Get(context.Background(), client.ObjectKeyFromObject(observed), resultO)
+			return conditions.GetReason(resultO, meta.ReadyCondition) == kustomizev1.ObservationSucceededReason &&
+				resultO.Status.LastAttemptedRevision == revision
+		}, timeout, time.Second).Should(BeTrue())
+		logStatus(t, resultO)
+
+		cli, err := api.NewClient(api.DefaultConfig())
+		g.Expect(err).NotTo(HaveOccurred())
+		_, err = cli.KVv2("secret").Get(context.Background(), fmt.Sprintf("observe/%[1]s/%[1]s", id))
+		g.Expect(err).To(MatchError(api.ErrSecretNotFound))
+	})
+}
@@ -0,0 +1,154 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fluxcd/pkg/testserver"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+	"github.com/fluxcd/kustomize-controller/internal/statusdetails"
+)
+
+func TestKustomizationReconciler_StatusDetails(t *testing.T) {
+	g := NewWithT(t)
+	id := "details-" + randStringRunes(5)
+	revision := "v1.0.0"
+
+	reconciler.StatusSizeLimit = 4 * 1024
+	defer func() {
+		reconciler.StatusSizeLimit = 0
+	}()
+
+	err := createNamespace(id)
+	g.Expect(err).NotTo(HaveOccurred(), "failed to create test namespace")
+
+	manifests := func(from, to int) []testserver.File {
+		var sb strings.Builder
+		for i := from; i < to; i++ {
+			sb.WriteString(fmt.Sprintf(`---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config-with-a-long-name-%d
+data:
+  key: value
+`, i))
+		}
+		return []testserver.File{{Name: "configmaps.yaml", Body: sb.String()}}
+	}
+
+	artifact, err := testServer.ArtifactFromFiles(manifests(0, 100))
+	g.Expect(err).NotTo(HaveOccurred())
+
+	repositoryName := types.NamespacedName{
+		Name:      fmt.Sprintf("details-%s", randStringRunes(5)),
+		Namespace: id,
+	}
+	g.Expect(applyGitRepository(repositoryName, artifact, revision)).To(Succeed())
+
+	kustomization := &kustomizev1.Kustomization{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("details-%s", randStringRunes(5)),
+			Namespace: id,
+		},
+		Spec: kustomizev1.KustomizationSpec{
+			Interval: metav1.Duration{Duration: reconciliationInterval},
+			Path:     "./",
+			SourceRef: kustomizev1.CrossNamespaceSourceReference{
+				Name: repositoryName.Name,
+				Kind: sourcev1.GitRepositoryKind,
+			},
+			TargetNamespace: id,
+			Prune:           true,
+		},
+	}
+	g.Expect(k8sClient.Create(context.Background(), kustomization)).To(Succeed())
+
+	resultK := &kustomizev1.Kustomization{}
+
+	t.Run("moves the inventory to a ConfigMap", func(t *testing.T) {
+		g := NewWithT(t)
+		g.Eventually(func() bool {
+			_ = k8sClient.Get(context.Background(), client.ObjectKeyFromObject(kustomization), resultK)
+			return isReconcileSuccess(resultK) && resultK.Status.LastAppliedRevision == revision
+		}, timeout, time.Second).Should(BeTrue())
+
+		g.Expect(resultK.Status.Inventory).To(BeNil())
+		g.Expect(resultK.Status.Details).ToNot(BeNil())
+		g.Expect(resultK.Status.Details.Name).To(Equal(statusdetails.ConfigMapName(resultK)))
+		g.Expect(resultK.Status.Details.Sections).To(ContainElement(statusdetails.InventorySection))
+
+		var cm corev1.ConfigMap
+		g.Expect(k8sClient.Get(context.Background(), types.NamespacedName{
+			Namespace: id, Name: resultK.Status.Details.Name}, &cm)).To(Succeed())
+		g.Expect(cm.Data[statusdetails.InventorySection]).To(ContainSubstring("config-with-a-long-name-0"))
+		g.Expect(cm.OwnerReferences).To(HaveLen(1))
+		g.Expect(cm.OwnerReferences[0].Name).To(Equal(kustomization.Name))
+	})
+
+	t.Run("prunes the objects of the moved inventory", func(t *testing.T) {
+		g := NewWithT(t)
+
+		artifact, err := testServer.ArtifactFromFiles(manifests(1, 100))
+		g.Expect(err).NotTo(HaveOccurred())
+		revision = "v2.0.0"
+		g.Expect(applyGitRepository(repositoryName, artifact, revision)).To(Succeed())
+
+		g.Eventually(func() bool {
+			_ = k8sClient.Get(context.Background(), client.ObjectKeyFromObject(kustomization), resultK)
+			return isReconcileSuccess(resultK) && resultK.Status.LastAppliedRevision == revision
+		}, timeout, time.Second).Should(BeTrue())
+
+		err = k8sClient.Get(context.Background(), types.NamespacedName{
+			Namespace: id, Name: "config-with-a-long-name-0"}, &corev1.ConfigMap{})
+		g.Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	t.Run("restores the inventory when the status gets smaller", func(t *testing.T) {
+		g := NewWithT(t)
+
+		artifact, err := testServer.ArtifactFromFiles(manifests(1, 3))
+		g.Expect(err).NotTo(HaveOccurred())
+		revision = "v3.0.0"
+		g.Expect(applyGitRepository(repositoryName, artifact, revision)).To(Succeed())
+
+		g.Eventually(func() bool {
+			_ = k8sClient.Get(context.Background(), client.ObjectKeyFromObject(kustomization), resultK)
+			return isReconcileSuccess(resultK) && resultK.Status.LastAppliedRevision == revision
+		}, timeout, time.Second).Should(BeTrue())
+
+		g.Expect(resultK.Status.Details).To(BeNil())
+		g.Expect(resultK.Status.Inventory.Entries).To(HaveLen(2))
+
+		err = k8sClient.Get(context.Background(), types.NamespacedName{
+			Namespace: id, Name: statusdetails.ConfigMapName(resultK)}, &corev1.ConfigMap{})
+		g.Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+}
@@ -30,6 +30,7 @@ import (
 	. "github.com/onsi/gomega"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -490,6 +491,125 @@ metadata:
 		g.Expect(deployment.Spec.Template.Spec.Containers[0].Image).To(ContainSubstring("5.2.0"))
 		g.Expect(deployment.Spec.Template.Spec.Containers[1].Image).To(ContainSubstring("sha256:2832f53c577d44753e97b0ed5f00e7e3a06979c9fab77d0e78bdac4b612b14fb"))
 	})
+
+	t.Run("publishes the images in status", func(t *testing.T) {
+		var obj kustomizev1.Kustomization
+		g.Expect(k8sClient.Get(context.Background(), client.ObjectKeyFromObject(kustomization), &obj)).To(Succeed())
+		g.Expect(obj.Status.Images).To(ContainElements(
+			deployment.Spec.Template.Spec.Containers[0].Image,
+			deployment.Spec.Template.Spec.Containers[1].Image,
+		))
+	})
+}
+
+func TestKustomizationReconciler_DeletePatches(t *testing.T) {
+	g := NewWithT(t)
+	id := "dp-" + randStringRunes(5)
+	revision := "v1.0.0"
+	resultK := &kustomizev1.Kustomization{}
+
+	err := createNamespace(id)
+	g.Expect(err).NotTo(HaveOccurred(), "failed to create test namespace")
+
+	err = createKubeConfigSecret(id)
+	g.Expect(err).NotTo(HaveOccurred(), "failed to create kubeconfig secret")
+
+	artifact, err := testServer.ArtifactFromFiles([]testserver.File{
+		{
+			Name: "config.yaml",
+			Body: fmt.Sprintf(`---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: vendor-defaults
+  namespace: %[1]s
+  annotations:
+    vendor.io/managed: "true"
+data:
+  key: val
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: vendor-settings
+  namespace: %[1]s
+data:
+  key: val
+`, id),
+		},
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	repositoryName := types.NamespacedName{
+		Name:      fmt.Sprintf("dp-%s", randStringRunes(5)),
+		Namespace: id,
+	}
+
+	err = applyGitRepository(repositoryName, artifact, revision)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	kustomization := &kustomizev1.Kustomization{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("dp-%s", randStringRunes(5)),
+			Namespace: id,
+		},
+		Spec: kustomizev1.KustomizationSpec{
+			Interval: metav1.Duration{Duration: reconciliationInterval},
+			Path:     "./",
+			Prune:    true,
+			KubeConfig: &meta.KubeConfigReference{
+				SecretRef: meta.SecretKeyReference{
+					Name: "kubeconfig",
+				},
+			},
+			SourceRef: kustomizev1.CrossNamespaceSourceReference{
+				Name:      repositoryName.Name,
+				Namespace: repositoryName.Namespace,
+				Kind:      sourcev1.GitRepositoryKind,
+			},
+		},
+	}
+
+	g.Expect(k8sClient.Create(context.Background(), kustomization)).To(Succeed())
+
+	g.Eventually(func() bool {
+		_ = k8sClient.Get(context.Background(), client.ObjectKeyFromObject(kustomization), resultK)
+		return isReconcileSuccess(resultK)
+	}, timeout, time.Second).Should(BeTrue())
+
+	t.Run("deletes the resources matching the target", func(t *testing.T) {
+		g := NewWithT(t)
+		resultK.Spec.Patches = []kustomize.Patch{
+			{
+				Patch: `
+$patch: delete
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: not-used
+`,
+				Target: &kustomize.Selector{
+					Kind:               "ConfigMap",
+					Name:               "vendor-.*",
+					Namespace:          "dp-.*",
+					AnnotationSelector: "vendor.io/managed=true",
+				},
+			},
+		}
+		g.Expect(k8sClient.Update(context.Background(), resultK)).To(Succeed())
+
+		g.Eventually(func() bool {
+			_ = k8sClient.Get(context.Background(), client.ObjectKeyFromObject(kustomization), resultK)
+			return isReconcileSuccess(resultK) && resultK.Status.ObservedGeneration == resultK.Generation
+		}, timeout, time.Second).Should(BeTrue())
+
+		var cm corev1.ConfigMap
+		g.Eventually(func() bool {
+			err := k8sClient.Get(context.Background(), client.ObjectKey{Name: "vendor-defaults", Namespace: id}, &cm)
+			return apierrors.IsNotFound(err)
+		}, timeout, time.Second).Should(BeTrue())
+		g.Expect(k8sClient.Get(context.Background(), client.ObjectKey{Name: "vendor-settings", Namespace: id}, &cm)).To(Succeed())
+	})
 }
 
 func checkConfigMap(list *corev1.ConfigMapList, name string) bool {
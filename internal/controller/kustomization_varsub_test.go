@@ -20,18 +20,22 @@ import (
 	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/fluxcd/pkg/apis/meta"
 	"github.com/fluxcd/pkg/testserver"
 	sourcev1 "github.com/fluxcd/source-controller/api/v1"
 	. "github.com/onsi/gomega"
 	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	apimeta "k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/metadata"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+	"github.com/fluxcd/kustomize-controller/internal/refwatch"
 )
 
 func TestKustomizationReconciler_Varsub(t *testing.T) {
@@ -349,3 +353,185 @@ metadata:
 		g.Expect(resultSA.Labels["shape"]).To(Equal("square"))
 	})
 }
+
+func TestKustomizationReconciler_VarsubSchema(t *testing.T) {
+	g := NewWithT(t)
+	id := "vars-" + randStringRunes(5)
+	revision := "v1.0.0/" + randStringRunes(7)
+
+	err := createNamespace(id)
+	g.Expect(err).NotTo(HaveOccurred(), "failed to create test namespace")
+
+	manifests := []testserver.File{
+		{
+			Name: "service.yaml",
+			Body: fmt.Sprintf(`---
+apiVersion: v1
+kind: Service
+metadata:
+  name: %[1]s
+  namespace: %[1]s
+spec:
+  ports:
+  - port: ${port}
+`, id),
+		},
+	}
+
+	artifact, err := testServer.ArtifactFromFiles(manifests)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	repositoryName := types.NamespacedName{
+		Name:      randStringRunes(5),
+		Namespace: id,
+	}
+
+	err = applyGitRepository(repositoryName, artifact, revision)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	inputK := &kustomizev1.Kustomization{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      id,
+			Namespace: id,
+		},
+		Spec: kustomizev1.KustomizationSpec{
+			Interval: metav1.Duration{Duration: reconciliationInterval},
+			Path:     "./",
+			Prune:    true,
+			SourceRef: kustomizev1.CrossNamespaceSourceReference{
+				Kind: sourcev1.GitRepositoryKind,
+				Name: repositoryName.Name,
+			},
+			PostBuild: &kustomizev1.PostBuild{
+				Substitute: map[string]string{"port": "http"},
+				SubstituteSchema: &apiextensionsv1.JSON{
+					Raw: []byte(`{"type":"object","properties":{"port":{"type":"integer"}}}`),
+				},
+			},
+		},
+	}
+	g.Expect(k8sClient.Create(ctx, inputK)).Should(Succeed())
+
+	resultK := &kustomizev1.Kustomization{}
+
+	t.Run("fails with invalid variables", func(t *testing.T) {
+		g.Eventually(func() bool {
+			_ = k8sClient.Get(ctx, client.ObjectKeyFromObject(inputK), resultK)
+			return isReconcileFailure(resultK)
+		}, timeout, interval).Should(BeTrue())
+		logStatus(t, resultK)
+
+		ready := apimeta.FindStatusCondition(resultK.Status.Conditions, meta.ReadyCondition)
+		g.Expect(ready.Reason).To(Equal(kustomizev1.BuildFailedReason))
+		g.Expect(ready.Message).To(ContainSubstring("'port': Invalid type. Expected: integer, given: string"))
+	})
+
+	t.Run("reconciles with valid variables", func(t *testing.T) {
+		g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(inputK), inputK)).Should(Succeed())
+		inputK.Spec.PostBuild.Substitute["port"] = "8080"
+		g.Expect(k8sClient.Update(ctx, inputK)).Should(Succeed())
+
+		g.Eventually(func() bool {
+			_ = k8sClient.Get(ctx, client.ObjectKeyFromObject(inputK), resultK)
+			return isReconcileSuccess(resultK)
+		}, timeout, interval).Should(BeTrue())
+
+		resultSvc := &corev1.Service{}
+		g.Expect(k8sClient.Get(ctx, types.NamespacedName{Name: id, Namespace: id}, resultSvc)).Should(Succeed())
+		g.Expect(resultSvc.Spec.Ports[0].Port).To(BeEquivalentTo(8080))
+	})
+}
+
+func TestKustomizationReconciler_VarsubReferenceWatch(t *testing.T) {
+	g := NewWithT(t)
+	id := "vars-" + randStringRunes(5)
+	revision := "v1.0.0/" + randStringRunes(7)
+
+	reconciler.referenceWatches = refwatch.NewManager(context.Background(),
+		metadata.NewForConfigOrDie(testEnv.GetConfig()), reconciler.notifyReferenceChange)
+	defer func() {
+		reconciler.referenceWatches = nil
+	}()
+
+	err := createNamespace(id)
+	g.Expect(err).NotTo(HaveOccurred(), "failed to create test namespace")
+
+	artifact, err := testServer.ArtifactFromFiles([]testserver.File{
+		{
+			Name: "configmap.yaml",
+			Body: fmt.Sprintf(`---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: %[1]s
+  namespace: %[1]s
+data:
+  zone: ${zone}
+`, id),
+		},
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	repositoryName := types.NamespacedName{
+		Name:      randStringRunes(5),
+		Namespace: id,
+	}
+
+	err = applyGitRepository(repositoryName, artifact, revision)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	vars := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "vars",
+			Namespace: id,
+		},
+		Data: map[string]string{"zone": "az-1a"},
+	}
+	g.Expect(k8sClient.Create(context.Background(), vars)).Should(Succeed())
+
+	inputK := &kustomizev1.Kustomization{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      id,
+			Namespace: id,
+		},
+		Spec: kustomizev1.KustomizationSpec{
+			Interval: metav1.Duration{Duration: time.Hour},
+			Path:     "./",
+			Prune:    true,
+			SourceRef: kustomizev1.CrossNamespaceSourceReference{
+				Kind: sourcev1.GitRepositoryKind,
+				Name: repositoryName.Name,
+			},
+			PostBuild: &kustomizev1.PostBuild{
+				SubstituteFrom: []kustomizev1.SubstituteReference{
+					{
+						Kind: "ConfigMap",
+						Name: vars.Name,
+					},
+				},
+			},
+		},
+	}
+	g.Expect(k8sClient.Create(context.Background(), inputK)).Should(Succeed())
+
+	resultCM := &corev1.ConfigMap{}
+	zone := func() string {
+		_ = k8sClient.Get(context.Background(), types.NamespacedName{Name: id, Namespace: id}, resultCM)
+		return resultCM.Data["zone"]
+	}
+
+	t.Run("substitutes the referenced values", func(t *testing.T) {
+		g.Eventually(zone, timeout, interval).Should(Equal("az-1a"))
+		g.Expect(reconciler.referenceWatches.IsWatching(refwatch.Ref{
+			Kind:      "ConfigMap",
+			Namespace: id,
+			Name:      vars.Name,
+		})).To(BeTrue())
+	})
+
+	t.Run("reconciles when the referenced values change", func(t *testing.T) {
+		vars.Data["zone"] = "az-1b"
+		g.Expect(k8sClient.Update(context.Background(), vars)).Should(Succeed())
+		g.Eventually(zone, timeout, interval).Should(Equal("az-1b"))
+	})
+}
@@ -0,0 +1,160 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/fluxcd/pkg/runtime/conditions"
+	"github.com/fluxcd/pkg/testserver"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+)
+
+func TestKustomizationReconciler_VerifyDecryption(t *testing.T) {
+	g := NewWithT(t)
+	id := "vd-" + randStringRunes(5)
+	revision := "v1.0.0"
+	resultK := &kustomizev1.Kustomization{}
+
+	err := createNamespace(id)
+	g.Expect(err).NotTo(HaveOccurred(), "failed to create test namespace")
+
+	err = createKubeConfigSecret(id)
+	g.Expect(err).NotTo(HaveOccurred(), "failed to create kubeconfig secret")
+
+	ageSecret, err := os.ReadFile("testdata/sops/secret.age.yaml")
+	g.Expect(err).NotTo(HaveOccurred())
+	pgpSecret, err := os.ReadFile("testdata/sops/secret.yaml")
+	g.Expect(err).NotTo(HaveOccurred())
+	ageKey, err := os.ReadFile("testdata/sops/age.txt")
+	g.Expect(err).NotTo(HaveOccurred())
+	pgpKey, err := os.ReadFile("testdata/sops/pgp.asc")
+	g.Expect(err).NotTo(HaveOccurred())
+
+	artifact, err := testServer.ArtifactFromFiles([]testserver.File{
+		{Name: "secret.age.yaml", Body: string(ageSecret)},
+		{Name: "pgp/secret.yaml", Body: string(pgpSecret)},
+		{
+			Name: "config.yaml",
+			Body: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+data:
+  key: value
+`,
+		},
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	repositoryName := types.NamespacedName{
+		Name:      fmt.Sprintf("vd-%s", randStringRunes(5)),
+		Namespace: id,
+	}
+
+	err = applyGitRepository(repositoryName, artifact, revision)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	sopsSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "sops-keys",
+			Namespace: id,
+		},
+		StringData: map[string]string{
+			"age.agekey": string(ageKey),
+		},
+	}
+	g.Expect(k8sClient.Create(context.Background(), sopsSecret)).To(Succeed())
+
+	kustomization := &kustomizev1.Kustomization{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("vd-%s", randStringRunes(5)),
+			Namespace: id,
+		},
+		Spec: kustomizev1.KustomizationSpec{
+			Interval:        metav1.Duration{Duration: reconciliationInterval},
+			Path:            "./",
+			TargetNamespace: id,
+			Prune:           true,
+			Mode:            kustomizev1.VerifyDecryptionMode,
+			KubeConfig: &meta.KubeConfigReference{
+				SecretRef: meta.SecretKeyReference{
+					Name: "kubeconfig",
+				},
+			},
+			SourceRef: kustomizev1.CrossNamespaceSourceReference{
+				Name:      repositoryName.Name,
+				Namespace: repositoryName.Namespace,
+				Kind:      sourcev1.GitRepositoryKind,
+			},
+			Decryption: &kustomizev1.Decryption{
+				Provider: "sops",
+				SecretRef: &meta.LocalObjectReference{
+					Name: sopsSecret.Name,
+				},
+			},
+		},
+	}
+
+	g.Expect(k8sClient.Create(context.Background(), kustomization)).To(Succeed())
+
+	t.Run("reports the files which can't be decrypted", func(t *testing.T) {
+		g := NewWithT(t)
+		g.Eventually(func() bool {
+			_ = k8sClient.Get(context.Background(), client.ObjectKeyFromObject(kustomization), resultK)
+			return conditions.GetReason(resultK, meta.ReadyCondition) == kustomizev1.DecryptionFailedReason &&
+				len(resultK.Status.DecryptionResults) == 2
+		}, timeout, time.Second).Should(BeTrue())
+
+		g.Expect(resultK.Status.DecryptionResults[0]).To(HavePrefix("pgp/secret.yaml: "))
+		g.Expect(resultK.Status.DecryptionResults[0]).ToNot(HaveSuffix(": decrypted"))
+		g.Expect(resultK.Status.DecryptionResults[1]).To(Equal("secret.age.yaml: decrypted"))
+		g.Expect(conditions.GetMessage(resultK, meta.ReadyCondition)).To(ContainSubstring("1 of 2 encrypted files"))
+	})
+
+	t.Run("verifies the decryption without applying", func(t *testing.T) {
+		g := NewWithT(t)
+		g.Expect(k8sClient.Get(context.Background(), client.ObjectKeyFromObject(sopsSecret), sopsSecret)).To(Succeed())
+		sopsSecret.StringData = map[string]string{"pgp.asc": string(pgpKey)}
+		g.Expect(k8sClient.Update(context.Background(), sopsSecret)).To(Succeed())
+
+		g.Eventually(func() bool {
+			_ = k8sClient.Get(context.Background(), client.ObjectKeyFromObject(kustomization), resultK)
+			return conditions.GetReason(resultK, meta.ReadyCondition) == kustomizev1.DecryptionVerifiedReason
+		}, timeout, time.Second).Should(BeTrue())
+
+		g.Expect(resultK.Status.DecryptionResults).To(Equal([]string{
+			"pgp/secret.yaml: decrypted",
+			"secret.age.yaml: decrypted",
+		}))
+		g.Expect(resultK.Status.LastAppliedRevision).To(BeEmpty())
+		g.Expect(k8sClient.Get(context.Background(), client.ObjectKey{Name: "config", Namespace: id},
+			&corev1.ConfigMap{})).ToNot(Succeed())
+	})
+}
@@ -23,6 +23,7 @@ import (
 	"time"
 
 	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
@@ -35,6 +36,7 @@ import (
 	sourcev1 "github.com/fluxcd/source-controller/api/v1"
 
 	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+	"github.com/fluxcd/kustomize-controller/internal/healthwatch"
 )
 
 func TestKustomizationReconciler_WaitConditions(t *testing.T) {
@@ -196,6 +198,7 @@ parameters:
 		g.Expect(len(events) > 0).To(BeTrue())
 		g.Expect(events[len(events)-1].Type).To(BeIdenticalTo("Warning"))
 		g.Expect(events[len(events)-1].Message).To(ContainSubstring("does-not-exists"))
+		g.Expect(events[len(events)-1].Annotations).To(HaveKey(kustomizev1.GroupVersion.Group + "/reconcile-id"))
 	})
 
 	t.Run("recovers and reports healthy status", func(t *testing.T) {
@@ -275,3 +278,138 @@ parameters:
 		}, timeout, time.Second).Should(BeTrue())
 	})
 }
+
+func TestKustomizationReconciler_ContinuousHealthChecks(t *testing.T) {
+	g := NewWithT(t)
+	id := "health-" + randStringRunes(5)
+	revision := "v1.0.0"
+	resultK := &kustomizev1.Kustomization{}
+
+	watchCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	reconciler.healthWatches = healthwatch.NewManager(watchCtx, reconciler.notifyHealthChange)
+	defer func() {
+		reconciler.healthWatches = nil
+	}()
+
+	err := createNamespace(id)
+	g.Expect(err).NotTo(HaveOccurred(), "failed to create test namespace")
+
+	err = createKubeConfigSecret(id)
+	g.Expect(err).NotTo(HaveOccurred(), "failed to create kubeconfig secret")
+
+	artifact, err := testServer.ArtifactFromFiles([]testserver.File{
+		{
+			Name: "config.yaml",
+			Body: fmt.Sprintf(`---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: %[1]s
+data:
+  key: "%[1]s"
+`, id),
+		},
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	repositoryName := types.NamespacedName{
+		Name:      fmt.Sprintf("health-%s", randStringRunes(5)),
+		Namespace: id,
+	}
+
+	err = applyGitRepository(repositoryName, artifact, revision)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	kustomization := &kustomizev1.Kustomization{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("health-%s", randStringRunes(5)),
+			Namespace: id,
+		},
+		Spec: kustomizev1.KustomizationSpec{
+			Interval: metav1.Duration{Duration: time.Hour},
+			Path:     "./",
+			KubeConfig: &meta.KubeConfigReference{
+				SecretRef: meta.SecretKeyReference{
+					Name: "kubeconfig",
+				},
+			},
+			SourceRef: kustomizev1.CrossNamespaceSourceReference{
+				Name:      repositoryName.Name,
+				Namespace: repositoryName.Namespace,
+				Kind:      sourcev1.GitRepositoryKind,
+			},
+			TargetNamespace: id,
+			Prune:           true,
+			Wait:            true,
+		},
+	}
+
+	g.Expect(k8sClient.Create(context.Background(), kustomization)).To(Succeed())
+
+	t.Run("watches the resources after the health checks passed", func(t *testing.T) {
+		g.Eventually(func() bool {
+			_ = k8sClient.Get(context.Background(), client.ObjectKeyFromObject(kustomization), resultK)
+			return isReconcileSuccess(resultK)
+		}, timeout, time.Second).Should(BeTrue())
+
+		g.Expect(conditions.IsTrue(resultK, kustomizev1.HealthyCondition)).To(BeTrue())
+		g.Expect(reconciler.healthWatches.IsWatching(client.ObjectKeyFromObject(kustomization))).To(BeTrue())
+	})
+
+	t.Run("reports unhealthy status when a resource is deleted", func(t *testing.T) {
+		cm := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: id, Namespace: id},
+		}
+		g.Expect(k8sClient.Delete(context.Background(), cm)).To(Succeed())
+
+		g.Eventually(func() bool {
+			_ = k8sClient.Get(context.Background(), client.ObjectKeyFromObject(kustomization), resultK)
+			return conditions.IsFalse(resultK, kustomizev1.HealthyCondition)
+		}, timeout, time.Second).Should(BeTrue())
+		logStatus(t, resultK)
+
+		g.Expect(conditions.GetReason(resultK, kustomizev1.HealthyCondition)).To(Equal(kustomizev1.HealthCheckFailedReason))
+		g.Expect(conditions.GetMessage(resultK, kustomizev1.HealthyCondition)).To(ContainSubstring("NotFound"))
+		g.Expect(conditions.IsFalse(resultK, meta.ReadyCondition)).To(BeTrue())
+
+		events := getEvents(resultK.GetName(), map[string]string{"kustomize.toolkit.fluxcd.io/revision": revision})
+		g.Expect(events).ToNot(BeEmpty())
+	})
+
+	t.Run("stops watching when the Kustomization is deleted", func(t *testing.T) {
+		g.Expect(k8sClient.Delete(context.Background(), kustomization)).To(Succeed())
+
+		g.Eventually(func() bool {
+			err := k8sClient.Get(context.Background(), client.ObjectKeyFromObject(kustomization), resultK)
+			return apierrors.IsNotFound(err)
+		}, timeout, time.Second).Should(BeTrue())
+
+		g.Expect(reconciler.healthWatches.IsWatching(client.ObjectKeyFromObject(kustomization))).To(BeFalse())
+	})
+}
+
+func TestKustomizationReconciler_HealthWatchImpersonation(t *testing.T) {
+	g := NewWithT(t)
+
+	obj := &kustomizev1.Kustomization{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "tenant"},
+	}
+	cfg, err := reconciler.getImpersonatedRESTConfig(context.Background(), obj)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(cfg.Impersonate.UserName).To(BeEmpty())
+
+	obj.Spec.ServiceAccountName = "reconciler"
+	cfg, err = reconciler.getImpersonatedRESTConfig(context.Background(), obj)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(cfg.Impersonate.UserName).To(Equal("system:serviceaccount:tenant:reconciler"))
+	g.Expect(reconciler.restConfig.Impersonate.UserName).To(BeEmpty())
+
+	defaultServiceAccount := reconciler.DefaultServiceAccount
+	reconciler.DefaultServiceAccount = "default"
+	defer func() { reconciler.DefaultServiceAccount = defaultServiceAccount }()
+	obj.Spec.ServiceAccountName = ""
+	cfg, err = reconciler.getImpersonatedRESTConfig(context.Background(), obj)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(cfg.Impersonate.UserName).To(Equal("system:serviceaccount:tenant:default"))
+}
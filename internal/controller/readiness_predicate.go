@@ -0,0 +1,62 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"github.com/fluxcd/pkg/apis/meta"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+)
+
+// ReadinessChangePredicate triggers an update event when the readiness of
+// a Kustomization changes, and a delete event when it's deleted. The
+// create events are filtered, as the Kustomizations are not ready yet.
+type ReadinessChangePredicate struct {
+	predicate.Funcs
+}
+
+func (ReadinessChangePredicate) Create(event.CreateEvent) bool {
+	return false
+}
+
+func (ReadinessChangePredicate) Update(e event.UpdateEvent) bool {
+	if e.ObjectOld == nil || e.ObjectNew == nil {
+		return false
+	}
+
+	oldObj, ok := e.ObjectOld.(*kustomizev1.Kustomization)
+	if !ok {
+		return false
+	}
+	newObj, ok := e.ObjectNew.(*kustomizev1.Kustomization)
+	if !ok {
+		return false
+	}
+
+	return isReady(oldObj) != isReady(newObj) ||
+		oldObj.Status.LastAppliedRevision != newObj.Status.LastAppliedRevision
+}
+
+// isReady returns true if the Kustomization is ready and up to date with
+// its generation.
+func isReady(obj *kustomizev1.Kustomization) bool {
+	return obj.Generation == obj.Status.ObservedGeneration &&
+		apimeta.IsStatusConditionTrue(obj.Status.Conditions, meta.ReadyCondition)
+}
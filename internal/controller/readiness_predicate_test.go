@@ -0,0 +1,89 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+)
+
+func TestReadinessChangePredicate_Update(t *testing.T) {
+	kustomization := func(generation, observedGeneration int64, ready metav1.ConditionStatus, revision string) *kustomizev1.Kustomization {
+		return &kustomizev1.Kustomization{
+			ObjectMeta: metav1.ObjectMeta{Name: "apps", Generation: generation},
+			Status: kustomizev1.KustomizationStatus{
+				ObservedGeneration:  observedGeneration,
+				LastAppliedRevision: revision,
+				Conditions: []metav1.Condition{
+					{Type: meta.ReadyCondition, Status: ready},
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		oldObj *kustomizev1.Kustomization
+		newObj *kustomizev1.Kustomization
+		want   bool
+	}{
+		{
+			name:   "becomes ready",
+			oldObj: kustomization(1, 1, metav1.ConditionUnknown, ""),
+			newObj: kustomization(1, 1, metav1.ConditionTrue, "main@sha1:1"),
+			want:   true,
+		},
+		{
+			name:   "becomes not ready",
+			oldObj: kustomization(1, 1, metav1.ConditionTrue, "main@sha1:1"),
+			newObj: kustomization(1, 1, metav1.ConditionFalse, "main@sha1:1"),
+			want:   true,
+		},
+		{
+			name:   "new generation",
+			oldObj: kustomization(1, 1, metav1.ConditionTrue, "main@sha1:1"),
+			newObj: kustomization(2, 1, metav1.ConditionTrue, "main@sha1:1"),
+			want:   true,
+		},
+		{
+			name:   "new revision",
+			oldObj: kustomization(1, 1, metav1.ConditionTrue, "main@sha1:1"),
+			newObj: kustomization(1, 1, metav1.ConditionTrue, "main@sha1:2"),
+			want:   true,
+		},
+		{
+			name:   "unchanged readiness",
+			oldObj: kustomization(1, 1, metav1.ConditionFalse, "main@sha1:1"),
+			newObj: kustomization(1, 1, metav1.ConditionFalse, "main@sha1:1"),
+			want:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			got := ReadinessChangePredicate{}.Update(event.UpdateEvent{ObjectOld: tt.oldObj, ObjectNew: tt.newObj})
+			g.Expect(got).To(Equal(tt.want))
+		})
+	}
+}
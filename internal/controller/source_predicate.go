@@ -17,10 +17,12 @@ limitations under the License.
 package controller
 
 import (
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 
 	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	sourcev1b2 "github.com/fluxcd/source-controller/api/v1beta2"
 )
 
 type SourceRevisionChangePredicate struct {
@@ -53,3 +55,33 @@ func (SourceRevisionChangePredicate) Update(e event.UpdateEvent) bool {
 
 	return false
 }
+
+// SourceReconcileRequestPredicate triggers an update event when a source
+// has handled a reconciliation request, e.g. 'flux reconcile source git'.
+type SourceReconcileRequestPredicate struct {
+	predicate.Funcs
+}
+
+func (SourceReconcileRequestPredicate) Update(e event.UpdateEvent) bool {
+	if e.ObjectOld == nil || e.ObjectNew == nil {
+		return false
+	}
+
+	handled := lastHandledReconcileRequest(e.ObjectNew)
+	return handled != "" && handled != lastHandledReconcileRequest(e.ObjectOld)
+}
+
+// lastHandledReconcileRequest returns the value of the last reconciliation
+// request handled by the source.
+func lastHandledReconcileRequest(obj client.Object) string {
+	switch o := obj.(type) {
+	case *sourcev1.GitRepository:
+		return o.Status.GetLastHandledReconcileRequest()
+	case *sourcev1b2.OCIRepository:
+		return o.Status.GetLastHandledReconcileRequest()
+	case *sourcev1b2.Bucket:
+		return o.Status.GetLastHandledReconcileRequest()
+	default:
+		return ""
+	}
+}
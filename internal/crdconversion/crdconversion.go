@@ -0,0 +1,115 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package crdconversion finds the CustomResourceDefinitions converted by a
+// webhook, and waits for the webhook services to be ready, so that their
+// custom resources are not applied while the API server can't convert them.
+package crdconversion
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	discoveryv1 "k8s.io/api/discovery/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var crdKind = apiextensionsv1.SchemeGroupVersion.WithKind("CustomResourceDefinition")
+
+// Webhooks returns the services of the conversion webhooks of the given
+// CustomResourceDefinitions, indexed by the group and kind of their custom
+// resources. The other objects, and the CRDs whose webhook is called by URL,
+// are skipped.
+func Webhooks(objects []*unstructured.Unstructured) (map[schema.GroupKind]types.NamespacedName, error) {
+	result := make(map[schema.GroupKind]types.NamespacedName)
+	for _, u := range objects {
+		if u.GroupVersionKind() != crdKind {
+			continue
+		}
+		var crd apiextensionsv1.CustomResourceDefinition
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, &crd); err != nil {
+			return nil, fmt.Errorf("failed to decode CustomResourceDefinition '%s': %w", u.GetName(), err)
+		}
+		conversion := crd.Spec.Conversion
+		if conversion == nil || conversion.Strategy != apiextensionsv1.WebhookConverter ||
+			conversion.Webhook == nil || conversion.Webhook.ClientConfig == nil ||
+			conversion.Webhook.ClientConfig.Service == nil {
+			continue
+		}
+		svc := conversion.Webhook.ClientConfig.Service
+		gk := schema.GroupKind{Group: crd.Spec.Group, Kind: crd.Spec.Names.Kind}
+		result[gk] = types.NamespacedName{Namespace: svc.Namespace, Name: svc.Name}
+	}
+	return result, nil
+}
+
+// WaitForEndpoints waits until each service has at least one ready endpoint,
+// and returns an error listing the services which are still not ready after
+// the timeout.
+func WaitForEndpoints(ctx context.Context, reader client.Reader,
+	services []types.NamespacedName, interval, timeout time.Duration) error {
+	pending := services
+	err := wait.PollUntilContextTimeout(ctx, interval, timeout, true, func(ctx context.Context) (bool, error) {
+		var notReady []types.NamespacedName
+		for _, svc := range pending {
+			ready, err := hasReadyEndpoint(ctx, reader, svc)
+			if err != nil {
+				return false, err
+			}
+			if !ready {
+				notReady = append(notReady, svc)
+			}
+		}
+		pending = notReady
+		return len(pending) == 0, nil
+	})
+	if err != nil && wait.Interrupted(err) {
+		names := make([]string, 0, len(pending))
+		for _, svc := range pending {
+			names = append(names, svc.String())
+		}
+		sort.Strings(names)
+		return fmt.Errorf("conversion webhook services not ready after %s: %s",
+			timeout, strings.Join(names, ", "))
+	}
+	return err
+}
+
+func hasReadyEndpoint(ctx context.Context, reader client.Reader, svc types.NamespacedName) (bool, error) {
+	var slices discoveryv1.EndpointSliceList
+	if err := reader.List(ctx, &slices, client.InNamespace(svc.Namespace),
+		client.MatchingLabels{discoveryv1.LabelServiceName: svc.Name}); err != nil {
+		return false, fmt.Errorf("failed to list the endpoints of the service '%s': %w", svc, err)
+	}
+	for _, slice := range slices.Items {
+		for _, ep := range slice.Endpoints {
+			// A nil ready condition must be interpreted as ready.
+			if ep.Conditions.Ready == nil || *ep.Conditions.Ready {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
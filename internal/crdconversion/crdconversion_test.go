@@ -0,0 +1,142 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crdconversion
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	ssautil "github.com/fluxcd/pkg/ssa/utils"
+	. "github.com/onsi/gomega"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const manifests = `
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.example.com
+spec:
+  group: example.com
+  names:
+    kind: Widget
+    plural: widgets
+  scope: Namespaced
+  conversion:
+    strategy: Webhook
+    webhook:
+      conversionReviewVersions: ["v1"]
+      clientConfig:
+        service:
+          namespace: widgets-system
+          name: widgets-webhook
+---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: gadgets.example.com
+spec:
+  group: example.com
+  names:
+    kind: Gadget
+    plural: gadgets
+  scope: Namespaced
+  conversion:
+    strategy: Webhook
+    webhook:
+      conversionReviewVersions: ["v1"]
+      clientConfig:
+        url: https://gadgets.example.com/convert
+---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: gizmos.example.com
+spec:
+  group: example.com
+  names:
+    kind: Gizmo
+    plural: gizmos
+  scope: Namespaced
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: widgets-system
+`
+
+func TestWebhooks(t *testing.T) {
+	g := NewWithT(t)
+
+	objects, err := ssautil.ReadObjects(strings.NewReader(manifests))
+	g.Expect(err).ToNot(HaveOccurred())
+
+	webhooks, err := Webhooks(objects)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(webhooks).To(Equal(map[schema.GroupKind]types.NamespacedName{
+		{Group: "example.com", Kind: "Widget"}: {Namespace: "widgets-system", Name: "widgets-webhook"},
+	}))
+}
+
+func TestWaitForEndpoints(t *testing.T) {
+	svc := types.NamespacedName{Namespace: "widgets-system", Name: "widgets-webhook"}
+
+	newSlice := func(ready *bool) *discoveryv1.EndpointSlice {
+		return &discoveryv1.EndpointSlice{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: svc.Namespace,
+				Name:      svc.Name + "-abcde",
+				Labels:    map[string]string{discoveryv1.LabelServiceName: svc.Name},
+			},
+			AddressType: discoveryv1.AddressTypeIPv4,
+			Endpoints: []discoveryv1.Endpoint{
+				{Addresses: []string{"10.0.0.1"}, Conditions: discoveryv1.EndpointConditions{Ready: ready}},
+			},
+		}
+	}
+
+	t.Run("returns when an endpoint is ready", func(t *testing.T) {
+		g := NewWithT(t)
+
+		reader := fake.NewClientBuilder().WithObjects(newSlice(ptr.To(true))).Build()
+		err := WaitForEndpoints(context.Background(), reader, []types.NamespacedName{svc}, 10*time.Millisecond, time.Second)
+		g.Expect(err).ToNot(HaveOccurred())
+	})
+
+	t.Run("fails when no endpoint is ready", func(t *testing.T) {
+		g := NewWithT(t)
+
+		reader := fake.NewClientBuilder().WithObjects(newSlice(ptr.To(false))).Build()
+		err := WaitForEndpoints(context.Background(), reader, []types.NamespacedName{svc}, 10*time.Millisecond, 100*time.Millisecond)
+		g.Expect(err).To(MatchError(ContainSubstring("not ready after 100ms: widgets-system/widgets-webhook")))
+	})
+
+	t.Run("fails when the service has no endpoints", func(t *testing.T) {
+		g := NewWithT(t)
+
+		reader := fake.NewClientBuilder().Build()
+		err := WaitForEndpoints(context.Background(), reader, []types.NamespacedName{svc}, 10*time.Millisecond, 100*time.Millisecond)
+		g.Expect(err).To(MatchError(ContainSubstring("widgets-system/widgets-webhook")))
+	})
+}
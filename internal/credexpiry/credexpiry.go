@@ -0,0 +1,121 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package credexpiry recognizes the errors caused by expired credentials,
+// returned by the key management services during the decryption and by the
+// remote clusters, and reports them as a metric.
+//
+// The errors are matched on the messages of the providers, as they reach
+// the controller flattened by SOPS and the Kubernetes client.
+package credexpiry
+
+import (
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// ProviderAWS is the provider of the expired AWS credentials.
+	ProviderAWS = "aws"
+	// ProviderAzure is the provider of the expired Azure credentials.
+	ProviderAzure = "azure"
+	// ProviderGCP is the provider of the expired GCP credentials.
+	ProviderGCP = "gcp"
+	// ProviderVault is the provider of the expired Hashicorp Vault tokens.
+	ProviderVault = "vault"
+	// ProviderKubeConfig is the provider of the expired credentials of the
+	// remote clusters.
+	ProviderKubeConfig = "kubeconfig"
+)
+
+type signature struct {
+	provider string
+	message  string
+}
+
+// signatures are the lower-cased messages of the expiry errors, in the
+// order they are matched.
+var signatures = []signature{
+	{ProviderAWS, "expiredtoken"},
+	{ProviderAWS, "security token included in the request is expired"},
+	// The client secret is expired.
+	{ProviderAzure, "aadsts7000222"},
+	// The client assertion, e.g. the workload identity token, is expired.
+	{ProviderAzure, "aadsts700024"},
+	// The refresh token is expired.
+	{ProviderAzure, "aadsts700082"},
+	{ProviderGCP, "token has been expired or revoked"},
+	{ProviderGCP, "oauth2: token expired"},
+	{ProviderVault, "token is expired"},
+	{ProviderVault, "token has expired"},
+}
+
+// remoteSignatures are the lower-cased messages of the errors returned when
+// the credentials of a kubeconfig are expired.
+var remoteSignatures = []string{
+	"certificate has expired",
+	// The message of the 401 responses, returned for the expired tokens.
+	"the server has asked for the client to provide credentials",
+}
+
+var expired = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "gotk_credentials_expired",
+		Help: "Set to 1 when the last reconciliation of the Kustomization failed because of expired credentials.",
+	},
+	[]string{"name", "namespace", "provider"},
+)
+
+// RegisterMetrics registers the expired credentials metric with the registry.
+func RegisterMetrics(registry prometheus.Registerer) {
+	registry.MustRegister(expired)
+}
+
+// Detect returns the provider of the expired credentials the error is
+// caused by, or an empty string. The errors of the Kubernetes client are
+// only considered when the objects are applied to a remote cluster.
+func Detect(err error, remoteCluster bool) string {
+	if err == nil {
+		return ""
+	}
+	msg := strings.ToLower(err.Error())
+	for _, s := range signatures {
+		if strings.Contains(msg, s.message) {
+			return s.provider
+		}
+	}
+	if remoteCluster {
+		for _, s := range remoteSignatures {
+			if strings.Contains(msg, s) {
+				return ProviderKubeConfig
+			}
+		}
+	}
+	return ""
+}
+
+// Record sets the metric of the Kustomization for the provider of the
+// expired credentials.
+func Record(name, namespace, provider string) {
+	Delete(name, namespace)
+	expired.WithLabelValues(name, namespace, provider).Set(1)
+}
+
+// Delete removes the metric of the Kustomization.
+func Delete(name, namespace string) {
+	expired.DeletePartialMatch(prometheus.Labels{"name": name, "namespace": namespace})
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package credexpiry
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name          string
+		err           error
+		remoteCluster bool
+		want          string
+	}{
+		{
+			name: "no error",
+		},
+		{
+			name: "AWS KMS",
+			err: errors.New("cannot get sops data key: Error getting data key: 0 successful groups required, got 0: " +
+				"failed to decrypt sops data key with AWS KMS: operation error KMS: Decrypt, https response error " +
+				"StatusCode: 400, api error ExpiredTokenException: The security token included in the request is expired"),
+			want: ProviderAWS,
+		},
+		{
+			name: "Azure Key Vault",
+			err: errors.New("failed to decrypt sops data key with Azure Key Vault key: ClientSecretCredential: " +
+				"AADSTS7000222: The provided client secret keys for app '00000000' are expired."),
+			want: ProviderAzure,
+		},
+		{
+			name: "GCP KMS",
+			err:  errors.New(`oauth2: "invalid_grant" "Token has been expired or revoked."`),
+			want: ProviderGCP,
+		},
+		{
+			name: "remote cluster",
+			err: errors.New("failed to build kube client: Get \"https://cluster:6443\": " +
+				"x509: certificate has expired or is not yet valid"),
+			remoteCluster: true,
+			want:          ProviderKubeConfig,
+		},
+		{
+			name: "local cluster",
+			err: errors.New("failed to build kube client: Get \"https://cluster:6443\": " +
+				"x509: certificate has expired or is not yet valid"),
+		},
+		{
+			name: "other error",
+			err:  errors.New("failed to decrypt sops data key with GCP KMS key: permission denied"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(Detect(tt.err, tt.remoteCluster)).To(Equal(tt.want))
+		})
+	}
+}
+
+func TestRecord(t *testing.T) {
+	g := NewWithT(t)
+
+	Record("apps", "default", ProviderAWS)
+	Record("apps", "default", ProviderGCP)
+	Record("infra", "default", ProviderAzure)
+	g.Expect(testutil.CollectAndCount(expired)).To(Equal(2))
+	g.Expect(testutil.ToFloat64(expired.WithLabelValues("apps", "default", ProviderGCP))).To(Equal(float64(1)))
+
+	Delete("apps", "default")
+	Delete("infra", "default")
+	g.Expect(testutil.CollectAndCount(expired)).To(BeZero())
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package csa applies objects with a client-side three-way merge, like
+// 'kubectl apply' without '--server-side', for the API servers which
+// mishandle server-side apply.
+//
+// The patches are computed from the last applied configuration, recorded
+// in the 'kubectl.kubernetes.io/last-applied-configuration' annotation, the
+// desired object and the object in the cluster. They are sent as JSON merge
+// patches, as the strategic merge patches require the Go types of the
+// objects.
+package csa
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/fluxcd/cli-utils/pkg/object"
+	"github.com/fluxcd/pkg/ssa"
+	ssautil "github.com/fluxcd/pkg/ssa/utils"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/jsonmergepatch"
+	"k8s.io/apimachinery/pkg/util/mergepatch"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ApplyAll applies the objects in order, and returns the change set. The
+// objects are skipped according to the exclusion and if-not-present
+// selectors of the options, as for the server-side apply.
+func ApplyAll(ctx context.Context, c client.Client, fieldManager string,
+	objects []*unstructured.Unstructured, opts ssa.ApplyOptions) (*ssa.ChangeSet, error) {
+	changeSet := ssa.NewChangeSet()
+	for _, u := range objects {
+		action, err := apply(ctx, c, fieldManager, u, opts)
+		if err != nil {
+			return nil, fmt.Errorf("%s client-side apply failed: %w", ssautil.FmtUnstructured(u), err)
+		}
+		changeSet.Add(ssa.ChangeSetEntry{
+			ObjMetadata:  object.UnstructuredToObjMetadata(u),
+			GroupVersion: u.GroupVersionKind().Version,
+			Subject:      ssautil.FmtUnstructured(u),
+			Action:       action,
+		})
+	}
+	return changeSet, nil
+}
+
+func apply(ctx context.Context, c client.Client, fieldManager string,
+	desired *unstructured.Unstructured, opts ssa.ApplyOptions) (ssa.Action, error) {
+	existing := &unstructured.Unstructured{}
+	existing.SetGroupVersionKind(desired.GroupVersionKind())
+	err := c.Get(ctx, client.ObjectKeyFromObject(desired), existing)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return "", err
+	}
+	found := err == nil
+
+	if ssautil.AnyInMetadata(desired, opts.ExclusionSelector) ||
+		(found && ssautil.AnyInMetadata(existing, opts.ExclusionSelector)) ||
+		(found && ssautil.AnyInMetadata(desired, opts.IfNotPresentSelector)) {
+		return ssa.SkippedAction, nil
+	}
+
+	modified, err := withLastApplied(desired)
+	if err != nil {
+		return "", err
+	}
+
+	if !found {
+		if err := c.Create(ctx, modified, client.FieldOwner(fieldManager)); err != nil {
+			return "", err
+		}
+		return ssa.CreatedAction, nil
+	}
+
+	modifiedJSON, err := json.Marshal(modified.Object)
+	if err != nil {
+		return "", err
+	}
+	currentJSON, err := json.Marshal(existing.Object)
+	if err != nil {
+		return "", err
+	}
+	var originalJSON []byte
+	if original, ok := existing.GetAnnotations()[corev1.LastAppliedConfigAnnotation]; ok {
+		originalJSON = []byte(original)
+	}
+
+	patch, err := jsonmergepatch.CreateThreeWayJSONMergePatch(originalJSON, modifiedJSON, currentJSON,
+		mergepatch.RequireKeyUnchanged("apiVersion"),
+		mergepatch.RequireKeyUnchanged("kind"),
+		mergepatch.RequireMetadataKeyUnchanged("name"))
+	if err != nil {
+		return "", fmt.Errorf("failed to compute the three-way merge patch: %w", err)
+	}
+	if string(patch) == "{}" {
+		return ssa.UnchangedAction, nil
+	}
+
+	if err := c.Patch(ctx, existing, client.RawPatch(types.MergePatchType, patch), client.FieldOwner(fieldManager)); err != nil {
+		return "", err
+	}
+	return ssa.ConfiguredAction, nil
+}
+
+// withLastApplied returns a copy of the object annotated with its
+// configuration, from which the next patches are computed.
+func withLastApplied(u *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	modified := u.DeepCopy()
+	annotations := modified.GetAnnotations()
+	delete(annotations, corev1.LastAppliedConfigAnnotation)
+	modified.SetAnnotations(annotations)
+
+	data, err := json.Marshal(modified.Object)
+	if err != nil {
+		return nil, err
+	}
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[corev1.LastAppliedConfigAnnotation] = string(data)
+	modified.SetAnnotations(annotations)
+	return modified, nil
+}
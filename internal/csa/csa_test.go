@@ -0,0 +1,97 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package csa
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fluxcd/pkg/ssa"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newConfigMap(data map[string]interface{}) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata": map[string]interface{}{
+			"name":      "config",
+			"namespace": "apps",
+		},
+		"data": data,
+	}}
+	return u
+}
+
+func TestApplyAll(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	c := fake.NewClientBuilder().Build()
+	opts := ssa.DefaultApplyOptions()
+	opts.ExclusionSelector = map[string]string{"kustomize.toolkit.fluxcd.io/reconcile": "disabled"}
+
+	applied := func() *corev1.ConfigMap {
+		cm := &corev1.ConfigMap{}
+		g.Expect(c.Get(ctx, client.ObjectKey{Name: "config", Namespace: "apps"}, cm)).To(Succeed())
+		return cm
+	}
+	action := func(cs *ssa.ChangeSet) ssa.Action {
+		g.Expect(cs.Entries).To(HaveLen(1))
+		return cs.Entries[0].Action
+	}
+
+	// Create the object with its last applied configuration.
+	cs, err := ApplyAll(ctx, c, "flux", []*unstructured.Unstructured{
+		newConfigMap(map[string]interface{}{"a": "1", "b": "2"}),
+	}, opts)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(action(cs)).To(Equal(ssa.CreatedAction))
+	g.Expect(applied().Annotations).To(HaveKey(corev1.LastAppliedConfigAnnotation))
+
+	// Keep the fields set by the other clients.
+	cm := applied()
+	cm.Data["other"] = "3"
+	g.Expect(c.Update(ctx, cm)).To(Succeed())
+	cs, err = ApplyAll(ctx, c, "flux", []*unstructured.Unstructured{
+		newConfigMap(map[string]interface{}{"a": "1", "b": "2"}),
+	}, opts)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(action(cs)).To(Equal(ssa.UnchangedAction))
+
+	// Remove the fields removed from the configuration only.
+	cs, err = ApplyAll(ctx, c, "flux", []*unstructured.Unstructured{
+		newConfigMap(map[string]interface{}{"a": "changed"}),
+	}, opts)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(action(cs)).To(Equal(ssa.ConfiguredAction))
+	g.Expect(applied().Data).To(Equal(map[string]string{"a": "changed", "other": "3"}))
+
+	// Skip the objects excluded in the cluster.
+	cm = applied()
+	cm.Labels = map[string]string{"kustomize.toolkit.fluxcd.io/reconcile": "disabled"}
+	g.Expect(c.Update(ctx, cm)).To(Succeed())
+	cs, err = ApplyAll(ctx, c, "flux", []*unstructured.Unstructured{
+		newConfigMap(map[string]interface{}{"a": "skipped"}),
+	}, opts)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(action(cs)).To(Equal(ssa.SkippedAction))
+	g.Expect(applied().Data).To(HaveKeyWithValue("a", "changed"))
+}
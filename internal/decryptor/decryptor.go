@@ -17,11 +17,13 @@ limitations under the License.
 package decryptor
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/base64"
 	"errors"
 	"fmt"
+	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
@@ -30,6 +32,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	securejoin "github.com/cyphar/filepath-securejoin"
 	"github.com/getsops/sops/v3"
 	"github.com/getsops/sops/v3/aes"
@@ -40,10 +43,12 @@ import (
 	"github.com/getsops/sops/v3/keyservice"
 	awskms "github.com/getsops/sops/v3/kms"
 	"github.com/getsops/sops/v3/pgp"
+	"golang.org/x/sync/errgroup"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/types"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/kustomize/api/konfig"
 	"sigs.k8s.io/kustomize/api/resource"
@@ -143,10 +148,20 @@ type Decryptor struct {
 	// azureToken is the Azure credential token used to authenticate towards
 	// any Azure Key Vault.
 	azureToken *azkv.TokenCredential
+	// azureCredential is the credential wrapped by azureToken, used with the
+	// azureKeyVault options.
+	azureCredential azcore.TokenCredential
+	// azureKeyVault configures the retries and failover of the Azure Key
+	// Vault requests.
+	azureKeyVault intazkv.KeyVaultOptions
 	// gcpCredsJSON is the JSON credential file of the service account used to
 	// authenticate towards any GCP KMS.
 	gcpCredsJSON []byte
 
+	// concurrency is the number of resources and files decrypted in
+	// parallel. Defaults to one when not set.
+	concurrency int
+
 	// keyServices are the SOPS keyservice.KeyServiceClient's available to the
 	// decryptor.
 	keyServices      []keyservice.KeyServiceClient
@@ -176,6 +191,19 @@ func NewTempDecryptor(root string, client client.Client, kustomization *kustomiz
 	return NewDecryptor(root, client, kustomization, maxEncryptedFileSize, gnuPGHome.String()), cleanup, nil
 }
 
+// SetAzureKeyVaultOptions configures the retries and failover of the Azure
+// Key Vault requests. It does not have an effect after the first call to
+// SopsDecryptWithFormat().
+func (d *Decryptor) SetAzureKeyVaultOptions(opts intazkv.KeyVaultOptions) {
+	d.azureKeyVault = opts
+}
+
+// SetConcurrency sets the number of resources and files decrypted in
+// parallel by DecryptResources() and VerifyFiles().
+func (d *Decryptor) SetConcurrency(n int) {
+	d.concurrency = n
+}
+
 // IsEncryptedSecret checks if the given object is a Kubernetes Secret encrypted
 // with Mozilla SOPS.
 func IsEncryptedSecret(object *unstructured.Unstructured) bool {
@@ -254,6 +282,7 @@ func (d *Decryptor) ImportKeys(ctx context.Context) error {
 						return fmt.Errorf("failed to import '%s' data from %s decryption Secret '%s': %w", name, provider, secretName, err)
 					}
 					d.azureToken = azkv.NewTokenCredential(azureToken)
+					d.azureCredential = azureToken
 				}
 			case filepath.Ext(DecryptionGCPCredsFile):
 				if name == DecryptionGCPCredsFile {
@@ -265,6 +294,46 @@ func (d *Decryptor) ImportKeys(ctx context.Context) error {
 	return nil
 }
 
+// ImportAgeKeyFiles imports the age identities of the AgeKeyFile named in
+// the Kustomization's v1.Decryption spec, from the subdirectory of the given
+// directory named after the namespace of the Kustomization, as mounted into
+// the controller by e.g. the Secrets Store CSI driver. The files of the
+// other namespaces are never imported, so that the tenants can't decrypt the
+// data encrypted for the identities of each other.
+// It returns an error if the AgeKeyFile is set without a directory, or if
+// the import fails.
+func (d *Decryptor) ImportAgeKeyFiles(dir string) error {
+	decryption := d.kustomization.Spec.Decryption
+	if decryption == nil || decryption.Provider != DecryptionProviderSOPS || decryption.AgeKeyFile == "" {
+		return nil
+	}
+
+	if dir == "" {
+		return fmt.Errorf("cannot import age key file '%s': no age key directory configured for the controller",
+			decryption.AgeKeyFile)
+	}
+	namespaceDir, err := securejoin.SecureJoin(dir, d.kustomization.GetNamespace())
+	if err != nil {
+		return fmt.Errorf("cannot import age key file '%s': %w", decryption.AgeKeyFile, err)
+	}
+	path, err := securejoin.SecureJoin(namespaceDir, decryption.AgeKeyFile)
+	if err != nil {
+		return fmt.Errorf("cannot import age key file '%s': %w", decryption.AgeKeyFile, err)
+	}
+	return d.importAgeKeyFile(path)
+}
+
+func (d *Decryptor) importAgeKeyFile(path string) error {
+	value, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("cannot read age key file '%s': %w", filepath.Base(path), err)
+	}
+	if err = d.ageIdentities.Import(string(value)); err != nil {
+		return fmt.Errorf("failed to import age key file '%s': %w", filepath.Base(path), err)
+	}
+	return nil
+}
+
 // SopsDecryptWithFormat attempts to load a SOPS encrypted file using the store
 // for the input format, gathers the data key for it from the key service,
 // and then decrypts the file data with the retrieved data key.
@@ -399,6 +468,49 @@ func (d *Decryptor) DecryptResource(res *resource.Resource) (*resource.Resource,
 	return nil, nil
 }
 
+// DecryptResources attempts to decrypt the provided resources with
+// DecryptResource(), up to the concurrency of the Decryptor in parallel, to
+// overlap the round-trips to the key management services.
+// It returns the resources which have been decrypted, in the order of the
+// provided resources, or the error of the first resource failing to
+// decrypt. The remaining resources are not decrypted after a failure.
+func (d *Decryptor) DecryptResources(resources []*resource.Resource) ([]*resource.Resource, error) {
+	decrypted := make([]*resource.Resource, len(resources))
+	errs := make([]error, len(resources))
+	d.forEach(len(resources), func(i int) error {
+		decrypted[i], errs[i] = d.DecryptResource(resources[i])
+		return errs[i]
+	})
+
+	var result []*resource.Resource
+	for i, res := range resources {
+		if errs[i] != nil {
+			return nil, fmt.Errorf("decryption failed for '%s': %w", res.GetName(), errs[i])
+		}
+		if decrypted[i] != nil {
+			result = append(result, decrypted[i])
+		}
+	}
+	return result, nil
+}
+
+// forEach calls fn for the indexes from zero to n, up to the concurrency of
+// the Decryptor in parallel. It stops calling fn after the first error.
+func (d *Decryptor) forEach(n int, fn func(i int) error) {
+	g, ctx := errgroup.WithContext(context.Background())
+	g.SetLimit(max(d.concurrency, 1))
+	for i := 0; i < n; i++ {
+		i := i
+		g.Go(func() error {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fn(i)
+		})
+	}
+	_ = g.Wait()
+}
+
 // DecryptEnvSources attempts to decrypt all types.SecretArgs FileSources and
 // EnvSources a Kustomization file in the directory at the provided path refers
 // to, before walking recursively over all other resources it refers to.
@@ -415,6 +527,95 @@ func (d *Decryptor) DecryptEnvSources(path string) error {
 	return recurseKustomizationFiles(d.root, path, visit, visited)
 }
 
+// FileDecryption is the outcome of the decryption of an encrypted file.
+type FileDecryption struct {
+	// Path of the file, relative to the root of the Decryptor.
+	Path string
+	// Err is the decryption error, nil when the file could be decrypted.
+	Err error
+}
+
+// VerifyFiles attempts to decrypt all the SOPS encrypted files in the
+// directory at the provided path, and its subdirectories, without writing
+// the decrypted data. The documents of the YAML files are decrypted one by
+// one, like the resources of a build.
+// The files are decrypted up to the concurrency of the Decryptor in
+// parallel. It returns the outcome for each of the encrypted files, in the
+// order of the directory walk. Irregular files, and files exceeding the maxFileSize, are
+// ignored.
+func (d *Decryptor) VerifyFiles(path string) ([]FileDecryption, error) {
+	absPath, _, err := securePaths(d.root, path)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []FileDecryption
+	var encrypted [][]byte
+	err = filepath.WalkDir(absPath, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if entry.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		if info, err := entry.Info(); err != nil || (d.maxFileSize > 0 && info.Size() > d.maxFileSize) {
+			return nil
+		}
+
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		if !bytes.Contains(data, sopsFormatToMarkerBytes[formatForPath(p)]) {
+			return nil
+		}
+		results = append(results, FileDecryption{Path: stripRoot(d.root, p)})
+		encrypted = append(encrypted, data)
+		return nil
+	})
+	if err != nil {
+		return nil, securePathErr(d.root, err)
+	}
+
+	d.forEach(len(results), func(i int) error {
+		results[i].Err = d.verifyData(encrypted[i], formatForPath(results[i].Path))
+		return nil
+	})
+	return results, nil
+}
+
+// verifyData attempts to decrypt the data in the given format, document by
+// document for YAML.
+func (d *Decryptor) verifyData(data []byte, format formats.Format) error {
+	if format != formats.Yaml {
+		_, err := d.SopsDecryptWithFormat(data, format, format)
+		return err
+	}
+
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(data)))
+	for i := 1; ; i++ {
+		doc, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !bytes.Contains(doc, sopsFormatToMarkerBytes[formats.Yaml]) {
+			continue
+		}
+		if _, err := d.SopsDecryptWithFormat(doc, formats.Yaml, formats.Yaml); err != nil {
+			return fmt.Errorf("document %d: %w", i, err)
+		}
+	}
+}
+
 // decryptKustomizationEnvSources returns a visitKustomization implementation
 // which attempts to decrypt any EnvSources entry it finds in the Kustomization
 // file with which it is called.
@@ -579,6 +780,12 @@ func (d *Decryptor) loadKeyServiceServer() {
 	if d.azureToken != nil {
 		serverOpts = append(serverOpts, intkeyservice.WithAzureToken{Token: d.azureToken})
 	}
+	if !d.azureKeyVault.IsZero() {
+		serverOpts = append(serverOpts, intkeyservice.WithAzureKeyVault{
+			Credential: d.azureCredential,
+			Options:    d.azureKeyVault,
+		})
+	}
 	serverOpts = append(serverOpts, intkeyservice.WithAWSKeys{CredsProvider: d.awsCredsProvider})
 	server := intkeyservice.NewServer(serverOpts...)
 	d.keyServices = append(make([]keyservice.KeyServiceClient, 0), keyservice.NewCustomLocalClient(server))
@@ -392,6 +392,114 @@ clientSecret: some-client-secret`),
 	}
 }
 
+func TestDecryptor_ImportAgeKeyFiles(t *testing.T) {
+	ageKey, err := os.ReadFile("testdata/age.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	for name, data := range map[string][]byte{
+		filepath.Join("team-a", "team"+DecryptionAgeExt):    ageKey,
+		filepath.Join("team-a", "invalid"+DecryptionAgeExt): []byte("not-a-valid-key"),
+		filepath.Join("team-b", "other"+DecryptionAgeExt):   ageKey,
+		"root" + DecryptionAgeExt:                           ageKey,
+	} {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0o700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		namespace  string
+		decryption *kustomizev1.Decryption
+		dir        string
+		wantErr    string
+		wantKeys   int
+	}{
+		{
+			name:       "no decryption",
+			namespace:  "team-a",
+			decryption: nil,
+			dir:        dir,
+		},
+		{
+			name:       "no age key file",
+			namespace:  "team-a",
+			decryption: &kustomizev1.Decryption{Provider: DecryptionProviderSOPS},
+			dir:        dir,
+		},
+		{
+			name:       "named age key file",
+			namespace:  "team-a",
+			decryption: &kustomizev1.Decryption{Provider: DecryptionProviderSOPS, AgeKeyFile: "team" + DecryptionAgeExt},
+			dir:        dir,
+			wantKeys:   1,
+		},
+		{
+			name:       "age key file import error",
+			namespace:  "team-a",
+			decryption: &kustomizev1.Decryption{Provider: DecryptionProviderSOPS, AgeKeyFile: "invalid" + DecryptionAgeExt},
+			dir:        dir,
+			wantErr:    "failed to import age key file 'invalid.agekey'",
+		},
+		{
+			name:       "age key file of another namespace",
+			namespace:  "team-b",
+			decryption: &kustomizev1.Decryption{Provider: DecryptionProviderSOPS, AgeKeyFile: "team" + DecryptionAgeExt},
+			dir:        dir,
+			wantErr:    "cannot read age key file 'team.agekey'",
+		},
+		{
+			name:       "age key file outside the namespace directory",
+			namespace:  "team-b",
+			decryption: &kustomizev1.Decryption{Provider: DecryptionProviderSOPS, AgeKeyFile: "root" + DecryptionAgeExt},
+			dir:        dir,
+			wantErr:    "cannot read age key file 'root.agekey'",
+		},
+		{
+			name:       "named age key file without directory",
+			namespace:  "team-a",
+			decryption: &kustomizev1.Decryption{Provider: DecryptionProviderSOPS, AgeKeyFile: "team" + DecryptionAgeExt},
+			wantErr:    "no age key directory configured",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			kustomization := kustomizev1.Kustomization{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "age-files",
+					Namespace: tt.namespace,
+				},
+				Spec: kustomizev1.KustomizationSpec{
+					Interval:   metav1.Duration{Duration: 2 * time.Minute},
+					Path:       "./",
+					Decryption: tt.decryption,
+				},
+			}
+
+			d, cleanup, err := NewTempDecryptor("", fake.NewClientBuilder().Build(), &kustomization)
+			g.Expect(err).ToNot(HaveOccurred())
+			t.Cleanup(cleanup)
+
+			err = d.ImportAgeKeyFiles(tt.dir)
+			if tt.wantErr != "" {
+				g.Expect(err).To(HaveOccurred())
+				g.Expect(err.Error()).To(ContainSubstring(tt.wantErr))
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(d.ageIdentities).To(HaveLen(tt.wantKeys))
+		})
+	}
+}
+
 func TestDecryptor_SopsDecryptWithFormat(t *testing.T) {
 	t.Run("decrypt INI to INI", func(t *testing.T) {
 		g := NewWithT(t)
@@ -747,6 +855,77 @@ func TestDecryptor_DecryptResource(t *testing.T) {
 	})
 }
 
+func TestDecryptor_DecryptResources(t *testing.T) {
+	g := NewWithT(t)
+
+	resourceFactory := provider.NewDefaultDepProvider().GetResourceFactory()
+
+	kus := &kustomizev1.Kustomization{
+		Spec: kustomizev1.KustomizationSpec{
+			Decryption: &kustomizev1.Decryption{Provider: DecryptionProviderSOPS},
+		},
+	}
+	d, cleanup, err := NewTempDecryptor("", fake.NewClientBuilder().Build(), kus)
+	g.Expect(err).ToNot(HaveOccurred())
+	t.Cleanup(cleanup)
+	d.SetConcurrency(4)
+
+	id, err := extage.GenerateX25519Identity()
+	g.Expect(err).ToNot(HaveOccurred())
+	otherID, err := extage.GenerateX25519Identity()
+	g.Expect(err).ToNot(HaveOccurred())
+	d.ageIdentities = age.ParsedIdentities{id}
+
+	newResource := func(name string, recipient *extage.X25519Recipient) *resource.Resource {
+		res := resourceFactory.FromMap(map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Secret",
+			"metadata":   map[string]interface{}{"name": name},
+			"stringData": map[string]interface{}{"key": name},
+		})
+		if recipient == nil {
+			res.SetKind("ConfigMap")
+			return res
+		}
+		data, err := res.MarshalJSON()
+		g.Expect(err).ToNot(HaveOccurred())
+		encData, err := d.sopsEncryptWithFormat(sops.Metadata{
+			EncryptedRegex: "^(data|stringData)$",
+			KeyGroups: []sops.KeyGroup{
+				{&age.MasterKey{Recipient: recipient.String()}},
+			},
+		}, data, formats.Json, formats.Json)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(res.UnmarshalJSON(encData)).To(Succeed())
+		return res
+	}
+
+	var resources []*resource.Resource
+	for i := 0; i < 10; i++ {
+		resources = append(resources, newResource(fmt.Sprintf("secret-%d", i), id.Recipient()))
+	}
+	resources = append(resources, newResource("plain", nil))
+
+	decrypted, err := d.DecryptResources(resources)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(decrypted).To(HaveLen(10))
+	for i, res := range decrypted {
+		g.Expect(res.GetName()).To(Equal(fmt.Sprintf("secret-%d", i)))
+		g.Expect(isSOPSEncryptedResource(res)).To(BeFalse())
+		g.Expect(res.GetDataMap()).To(BeEmpty())
+	}
+
+	t.Run("decryption error", func(t *testing.T) {
+		g := NewWithT(t)
+		_, err := d.DecryptResources([]*resource.Resource{
+			newResource("secret", id.Recipient()),
+			newResource("other", otherID.Recipient()),
+		})
+		g.Expect(err).To(HaveOccurred())
+		g.Expect(err.Error()).To(HavePrefix("decryption failed for 'other'"))
+	})
+}
+
 func TestDecryptor_decryptKustomizationEnvSources(t *testing.T) {
 	type file struct {
 		name           string
@@ -1075,6 +1254,65 @@ func TestDecryptor_decryptSopsFile(t *testing.T) {
 	}
 }
 
+func TestDecryptor_VerifyFiles(t *testing.T) {
+	g := NewWithT(t)
+
+	id, err := extage.GenerateX25519Identity()
+	g.Expect(err).ToNot(HaveOccurred())
+	otherID, err := extage.GenerateX25519Identity()
+	g.Expect(err).ToNot(HaveOccurred())
+
+	root := t.TempDir()
+	kus := &kustomizev1.Kustomization{
+		Spec: kustomizev1.KustomizationSpec{
+			Decryption: &kustomizev1.Decryption{Provider: DecryptionProviderSOPS},
+		},
+	}
+	d, cleanup, err := NewTempDecryptor(root, fake.NewClientBuilder().Build(), kus)
+	g.Expect(err).ToNot(HaveOccurred())
+	t.Cleanup(cleanup)
+	d.ageIdentities = age.ParsedIdentities{id}
+
+	encrypt := func(recipient extage.Recipient, data []byte, format formats.Format) []byte {
+		out, err := d.sopsEncryptWithFormat(sops.Metadata{
+			KeyGroups: []sops.KeyGroup{
+				{&age.MasterKey{Recipient: recipient.(*extage.X25519Recipient).String()}},
+			},
+		}, data, format, format)
+		g.Expect(err).ToNot(HaveOccurred())
+		return out
+	}
+
+	files := map[string][]byte{
+		"secrets.yaml": append([]byte("apiVersion: v1\nkind: ConfigMap\n---\n"),
+			encrypt(id.Recipient(), []byte("key: value\n"), formats.Yaml)...),
+		"apps/app.env":        encrypt(id.Recipient(), []byte("key=value\n"), formats.Dotenv),
+		"apps/other.yaml":     encrypt(otherID.Recipient(), []byte("key: value\n"), formats.Yaml),
+		"apps/plain.yaml":     []byte("key: value\n"),
+		".git/encrypted.yaml": encrypt(otherID.Recipient(), []byte("key: value\n"), formats.Yaml),
+	}
+	for name, data := range files {
+		g.Expect(os.MkdirAll(filepath.Join(root, filepath.Dir(name)), 0o700)).To(Succeed())
+		g.Expect(os.WriteFile(filepath.Join(root, name), data, 0o600)).To(Succeed())
+	}
+
+	results, err := d.VerifyFiles(root)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(results).To(HaveLen(3))
+	g.Expect(results[0]).To(Equal(FileDecryption{Path: "apps/app.env"}))
+	g.Expect(results[1].Path).To(Equal("apps/other.yaml"))
+	g.Expect(results[1].Err).To(HaveOccurred())
+	g.Expect(results[1].Err.Error()).To(ContainSubstring("document 1"))
+	g.Expect(results[2]).To(Equal(FileDecryption{Path: "secrets.yaml"}))
+
+	t.Run("path outside of root", func(t *testing.T) {
+		g := NewWithT(t)
+		results, err := d.VerifyFiles("../../apps")
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(results).To(HaveLen(2))
+	})
+}
+
 func TestDecryptor_secureLoadKustomizationFile(t *testing.T) {
 	kusType := kustypes.TypeMeta{
 		APIVersion: kustypes.KustomizationVersion,
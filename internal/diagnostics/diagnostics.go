@@ -0,0 +1,251 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package diagnostics keeps track of the reconciliations in progress and
+// of the ones waiting for a retry, and serves them with the work queue
+// metrics and the cache sizes, to debug stuck reconciliations.
+package diagnostics
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/ratelimiter"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// queueMetrics are the work queue metrics included in the report.
+var queueMetrics = []string{
+	"workqueue_depth",
+	"workqueue_unfinished_work_seconds",
+	"workqueue_longest_running_processor_seconds",
+}
+
+// Tracker records the reconciliations in progress, and the requests
+// waiting for a retry after a failure.
+type Tracker struct {
+	mu       sync.Mutex
+	inFlight map[types.NamespacedName]time.Time
+	retries  map[types.NamespacedName]Retry
+	now      func() time.Time
+}
+
+// Reconcile is a reconciliation in progress.
+type Reconcile struct {
+	Name      string    `json:"name"`
+	Namespace string    `json:"namespace"`
+	StartedAt time.Time `json:"startedAt"`
+}
+
+// Retry is a request waiting in the queue for a retry.
+type Retry struct {
+	Name      string    `json:"name"`
+	Namespace string    `json:"namespace"`
+	Failures  int       `json:"failures"`
+	RetryAt   time.Time `json:"retryAt"`
+}
+
+// NewTracker returns an empty Tracker.
+func NewTracker() *Tracker {
+	return &Tracker{
+		inFlight: make(map[types.NamespacedName]time.Time),
+		retries:  make(map[types.NamespacedName]Retry),
+		now:      time.Now,
+	}
+}
+
+// Start records the start of the reconciliation of the object, and returns
+// the function to call when it ends. It's a noop if the Tracker is nil.
+func (t *Tracker) Start(key types.NamespacedName) func() {
+	if t == nil {
+		return func() {}
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.inFlight[key] = t.now()
+	return func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		delete(t.inFlight, key)
+	}
+}
+
+// RateLimiter returns the rate limiter of the controller, wrapped to
+// record the requests which are retried after a failure.
+func (t *Tracker) RateLimiter(rl ratelimiter.RateLimiter) ratelimiter.RateLimiter {
+	if t == nil {
+		return rl
+	}
+	return &rateLimiter{RateLimiter: rl, tracker: t}
+}
+
+// InFlight returns the reconciliations in progress, the oldest first.
+func (t *Tracker) InFlight() []Reconcile {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	result := make([]Reconcile, 0, len(t.inFlight))
+	for key, start := range t.inFlight {
+		result = append(result, Reconcile{Name: key.Name, Namespace: key.Namespace, StartedAt: start})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].StartedAt.Before(result[j].StartedAt)
+	})
+	return result
+}
+
+// Retries returns the requests waiting for a retry, the next one first.
+func (t *Tracker) Retries() []Retry {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	result := make([]Retry, 0, len(t.retries))
+	for _, r := range t.retries {
+		result = append(result, r)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].RetryAt.Before(result[j].RetryAt)
+	})
+	return result
+}
+
+type rateLimiter struct {
+	ratelimiter.RateLimiter
+	tracker *Tracker
+}
+
+func (r *rateLimiter) When(item interface{}) time.Duration {
+	delay := r.RateLimiter.When(item)
+	if req, ok := item.(reconcile.Request); ok {
+		t := r.tracker
+		t.mu.Lock()
+		t.retries[req.NamespacedName] = Retry{
+			Name:      req.Name,
+			Namespace: req.Namespace,
+			Failures:  r.RateLimiter.NumRequeues(item),
+			RetryAt:   t.now().Add(delay),
+		}
+		t.mu.Unlock()
+	}
+	return delay
+}
+
+func (r *rateLimiter) Forget(item interface{}) {
+	r.RateLimiter.Forget(item)
+	if req, ok := item.(reconcile.Request); ok {
+		r.tracker.mu.Lock()
+		delete(r.tracker.retries, req.NamespacedName)
+		r.tracker.mu.Unlock()
+	}
+}
+
+// Options configures the content of the diagnostics report.
+type Options struct {
+	// Reader reads the objects from the cache of the controller,
+	// the cache sizes are not reported if it's nil.
+	Reader client.Reader
+
+	// CachedKinds returns an empty list for each cached kind
+	// whose objects are counted.
+	CachedKinds map[string]func() client.ObjectList
+
+	// Gatherer returns the work queue metrics.
+	Gatherer prometheus.Gatherer
+
+	// QueueName is the name of the controller work queue.
+	QueueName string
+}
+
+// Report is the diagnostics report.
+type Report struct {
+	InFlight []Reconcile        `json:"inFlight"`
+	Retries  []Retry            `json:"retries"`
+	Queue    map[string]float64 `json:"queue,omitempty"`
+	Cache    map[string]int     `json:"cache,omitempty"`
+	Errors   []string           `json:"errors,omitempty"`
+}
+
+// Collect returns the diagnostics report.
+func (t *Tracker) Collect(ctx context.Context, opts *Options) Report {
+	report := Report{
+		InFlight: t.InFlight(),
+		Retries:  t.Retries(),
+	}
+
+	if opts.Gatherer != nil {
+		families, err := opts.Gatherer.Gather()
+		if err != nil {
+			report.Errors = append(report.Errors, err.Error())
+		}
+		for _, family := range families {
+			for _, name := range queueMetrics {
+				if family.GetName() != name {
+					continue
+				}
+				for _, m := range family.GetMetric() {
+					for _, label := range m.GetLabel() {
+						if label.GetName() == "name" && label.GetValue() == opts.QueueName && m.GetGauge() != nil {
+							if report.Queue == nil {
+								report.Queue = make(map[string]float64)
+							}
+							report.Queue[name] = m.GetGauge().GetValue()
+						}
+					}
+				}
+			}
+		}
+	}
+
+	for kind, newList := range opts.CachedKinds {
+		if opts.Reader == nil {
+			break
+		}
+		list := newList()
+		if err := opts.Reader.List(ctx, list, client.UnsafeDisableDeepCopy); err != nil {
+			report.Errors = append(report.Errors, err.Error())
+			continue
+		}
+		if report.Cache == nil {
+			report.Cache = make(map[string]int)
+		}
+		report.Cache[kind] = meta.LenList(list)
+	}
+
+	return report
+}
+
+// Handler returns an HTTP handler serving the diagnostics report as JSON.
+// The options are read on each request, which allows to set the Reader
+// once the manager serving the handler is created.
+func Handler(t *Tracker, opts *Options) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", "GET")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(t.Collect(r.Context(), opts))
+	})
+}
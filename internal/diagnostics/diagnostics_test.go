@@ -0,0 +1,115 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package diagnostics
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestTracker(t *testing.T) {
+	g := NewWithT(t)
+
+	tracker := NewTracker()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tracker.now = func() time.Time { return now }
+
+	first := types.NamespacedName{Namespace: "apps", Name: "first"}
+	second := types.NamespacedName{Namespace: "apps", Name: "second"}
+
+	doneFirst := tracker.Start(first)
+	now = now.Add(time.Second)
+	doneSecond := tracker.Start(second)
+	g.Expect(tracker.InFlight()).To(Equal([]Reconcile{
+		{Name: "first", Namespace: "apps", StartedAt: now.Add(-time.Second)},
+		{Name: "second", Namespace: "apps", StartedAt: now},
+	}))
+	doneFirst()
+	doneSecond()
+	g.Expect(tracker.InFlight()).To(BeEmpty())
+
+	rl := tracker.RateLimiter(workqueue.NewItemExponentialFailureRateLimiter(time.Second, time.Minute))
+	req := reconcile.Request{NamespacedName: first}
+	rl.When(req)
+	rl.When(req)
+	g.Expect(tracker.Retries()).To(Equal([]Retry{
+		{Name: "first", Namespace: "apps", Failures: 2, RetryAt: now.Add(2 * time.Second)},
+	}))
+	rl.Forget(req)
+	g.Expect(tracker.Retries()).To(BeEmpty())
+
+	var nilTracker *Tracker
+	nilTracker.Start(first)()
+	g.Expect(nilTracker.RateLimiter(rl)).To(BeIdenticalTo(rl))
+}
+
+func TestHandler(t *testing.T) {
+	g := NewWithT(t)
+
+	tracker := NewTracker()
+	defer tracker.Start(types.NamespacedName{Namespace: "apps", Name: "podinfo"})()
+
+	registry := prometheus.NewRegistry()
+	depth := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "workqueue_depth"}, []string{"name"})
+	registry.MustRegister(depth)
+	depth.WithLabelValues("kustomization").Set(3)
+	depth.WithLabelValues("other").Set(7)
+
+	reader := fake.NewClientBuilder().WithObjects(
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "apps", Name: "first"}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "apps", Name: "second"}},
+	).Build()
+
+	handler := Handler(tracker, &Options{
+		Reader: reader,
+		CachedKinds: map[string]func() client.ObjectList{
+			"ConfigMap": func() client.ObjectList { return &corev1.ConfigMapList{} },
+		},
+		Gatherer:  registry,
+		QueueName: "kustomization",
+	})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/diagnostics", nil))
+	g.Expect(rec.Code).To(Equal(http.StatusOK))
+
+	var report Report
+	g.Expect(json.Unmarshal(rec.Body.Bytes(), &report)).To(Succeed())
+	g.Expect(report.InFlight).To(HaveLen(1))
+	g.Expect(report.InFlight[0].Name).To(Equal("podinfo"))
+	g.Expect(report.Retries).To(BeEmpty())
+	g.Expect(report.Queue).To(Equal(map[string]float64{"workqueue_depth": 3}))
+	g.Expect(report.Cache).To(Equal(map[string]int{"ConfigMap": 2}))
+	g.Expect(report.Errors).To(BeEmpty())
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/debug/diagnostics", nil))
+	g.Expect(rec.Code).To(Equal(http.StatusMethodNotAllowed))
+}
@@ -0,0 +1,178 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package driftignore excludes the fields set in-cluster by mutating
+// webhooks, defaulting or other controllers from the apply, so that their
+// drift is not corrected, and suggests the rules excluding the fields of
+// the flapping objects.
+package driftignore
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"github.com/fluxcd/pkg/apis/kustomize"
+	ssautil "github.com/fluxcd/pkg/ssa/utils"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+)
+
+// Apply removes in-place the fields at the paths of the rules from the
+// objects matching their target, and returns the number of objects
+// changed. The paths missing from an object are skipped.
+func Apply(rules []kustomizev1.IgnoreRule, objects []*unstructured.Unstructured) (int, error) {
+	changed := 0
+	for i, rule := range rules {
+		for _, obj := range objects {
+			ok, err := Matches(rule.Target, obj)
+			if err != nil {
+				return changed, fmt.Errorf("ignore rule %d: %w", i, err)
+			}
+			if !ok {
+				continue
+			}
+			removed, err := remove(obj, rule.Paths)
+			if err != nil {
+				return changed, fmt.Errorf("%s: ignore rule %d: %w", ssautil.FmtUnstructured(obj), i, err)
+			}
+			if removed {
+				changed++
+			}
+		}
+	}
+	return changed, nil
+}
+
+// Matches returns true if the object matches the selector, a nil selector
+// matching all objects. The group, version, kind, namespace and name are
+// matched as anchored regular expressions, as in the kustomize patches.
+func Matches(selector *kustomize.Selector, obj *unstructured.Unstructured) (bool, error) {
+	if selector == nil {
+		return true, nil
+	}
+	gvk := obj.GroupVersionKind()
+	for _, m := range []struct{ pattern, value string }{
+		{selector.Group, gvk.Group},
+		{selector.Version, gvk.Version},
+		{selector.Kind, gvk.Kind},
+		{selector.Namespace, obj.GetNamespace()},
+		{selector.Name, obj.GetName()},
+	} {
+		if m.pattern == "" {
+			continue
+		}
+		re, err := regexp.Compile("^(?:" + m.pattern + ")$")
+		if err != nil {
+			return false, fmt.Errorf("invalid target pattern '%s': %w", m.pattern, err)
+		}
+		if !re.MatchString(m.value) {
+			return false, nil
+		}
+	}
+	for _, m := range []struct {
+		expression string
+		set        map[string]string
+	}{
+		{selector.LabelSelector, obj.GetLabels()},
+		{selector.AnnotationSelector, obj.GetAnnotations()},
+	} {
+		if m.expression == "" {
+			continue
+		}
+		sel, err := labels.Parse(m.expression)
+		if err != nil {
+			return false, fmt.Errorf("invalid target selector '%s': %w", m.expression, err)
+		}
+		if !sel.Matches(labels.Set(m.set)) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// Suggest returns the rules ignoring the fields of the objects flapping for
+// at least the threshold of reconciliations, one rule per object targeting
+// it by kind, namespace and name.
+func Suggest(resources []kustomizev1.FlappingResource, threshold int) []kustomizev1.IgnoreRule {
+	var rules []kustomizev1.IgnoreRule
+	for _, r := range resources {
+		if r.Reconciliations < threshold || len(r.FieldPaths) == 0 {
+			continue
+		}
+		// The objects are in the 'Kind/namespace/name' format, without
+		// namespace for the cluster-scoped objects.
+		parts := strings.Split(r.Object, "/")
+		target := &kustomize.Selector{Kind: parts[0]}
+		switch len(parts) {
+		case 2:
+			target.Name = regexp.QuoteMeta(parts[1])
+		case 3:
+			target.Namespace = regexp.QuoteMeta(parts[1])
+			target.Name = regexp.QuoteMeta(parts[2])
+		default:
+			continue
+		}
+		rules = append(rules, kustomizev1.IgnoreRule{
+			Paths:  r.FieldPaths,
+			Target: target,
+		})
+	}
+	return rules
+}
+
+// remove removes the fields at the paths from the object, and returns
+// true if any field was removed.
+func remove(obj *unstructured.Unstructured, paths []string) (bool, error) {
+	ops := make([]map[string]string, 0, len(paths))
+	for _, path := range paths {
+		if !strings.HasPrefix(path, "/") {
+			return false, fmt.Errorf("invalid JSON pointer '%s', must start with '/'", path)
+		}
+		ops = append(ops, map[string]string{"op": "remove", "path": path})
+	}
+	data, err := json.Marshal(ops)
+	if err != nil {
+		return false, err
+	}
+	patch, err := jsonpatch.DecodePatch(data)
+	if err != nil {
+		return false, err
+	}
+	doc, err := json.Marshal(obj.Object)
+	if err != nil {
+		return false, err
+	}
+	opts := jsonpatch.NewApplyOptions()
+	opts.AllowMissingPathOnRemove = true
+	patched, err := patch.ApplyWithOptions(doc, opts)
+	if err != nil {
+		return false, fmt.Errorf("patch failed: %w", err)
+	}
+	if jsonpatch.Equal(doc, patched) {
+		return false, nil
+	}
+	result := map[string]any{}
+	if err := json.Unmarshal(patched, &result); err != nil {
+		return false, err
+	}
+	obj.Object = result
+	return true, nil
+}
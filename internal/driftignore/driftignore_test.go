@@ -0,0 +1,129 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driftignore
+
+import (
+	"testing"
+
+	"github.com/fluxcd/pkg/apis/kustomize"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+)
+
+func deployment(name string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata": map[string]any{
+			"name":      name,
+			"namespace": "apps",
+		},
+		"spec": map[string]any{
+			"replicas": int64(2),
+			"template": map[string]any{
+				"metadata": map[string]any{
+					"annotations": map[string]any{
+						"sidecar.istio.io/status": "injected",
+					},
+				},
+			},
+		},
+	}}
+}
+
+func TestApply(t *testing.T) {
+	g := NewWithT(t)
+
+	objects := []*unstructured.Unstructured{deployment("frontend"), deployment("backend")}
+	rules := []kustomizev1.IgnoreRule{
+		{
+			Paths: []string{"/spec/template/metadata/annotations/sidecar.istio.io~1status"},
+		},
+		{
+			Paths:  []string{"/spec/replicas", "/spec/missing"},
+			Target: &kustomize.Selector{Kind: "Deployment", Name: "back.*"},
+		},
+	}
+
+	changed, err := Apply(rules, objects)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(changed).To(Equal(3))
+
+	_, found, _ := unstructured.NestedFieldNoCopy(objects[0].Object, "spec", "template", "metadata", "annotations", "sidecar.istio.io/status")
+	g.Expect(found).To(BeFalse())
+	_, found, _ = unstructured.NestedFieldNoCopy(objects[0].Object, "spec", "replicas")
+	g.Expect(found).To(BeTrue())
+	_, found, _ = unstructured.NestedFieldNoCopy(objects[1].Object, "spec", "replicas")
+	g.Expect(found).To(BeFalse())
+
+	changed, err = Apply(rules, objects)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(changed).To(BeZero())
+
+	_, err = Apply([]kustomizev1.IgnoreRule{{Paths: []string{"spec"}}}, objects)
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestMatches(t *testing.T) {
+	g := NewWithT(t)
+
+	obj := deployment("frontend")
+	obj.SetLabels(map[string]string{"app": "frontend"})
+
+	for _, tt := range []struct {
+		selector *kustomize.Selector
+		want     bool
+	}{
+		{nil, true},
+		{&kustomize.Selector{Group: "apps", Version: "v1", Kind: "Deployment"}, true},
+		{&kustomize.Selector{Kind: "Deploy"}, false},
+		{&kustomize.Selector{Namespace: "apps", Name: "front.*"}, true},
+		{&kustomize.Selector{LabelSelector: "app=frontend"}, true},
+		{&kustomize.Selector{LabelSelector: "app=backend"}, false},
+		{&kustomize.Selector{AnnotationSelector: "team"}, false},
+	} {
+		got, err := Matches(tt.selector, obj)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(got).To(Equal(tt.want), "selector %+v", tt.selector)
+	}
+
+	_, err := Matches(&kustomize.Selector{Name: "("}, obj)
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestSuggest(t *testing.T) {
+	g := NewWithT(t)
+
+	rules := Suggest([]kustomizev1.FlappingResource{
+		{Object: "Deployment/apps/my.app", Reconciliations: 3, FieldPaths: []string{"/spec/replicas"}},
+		{Object: "ClusterRole/view", Reconciliations: 2, FieldPaths: []string{"/rules"}},
+		{Object: "ConfigMap/apps/new", Reconciliations: 1, FieldPaths: []string{"/data/key"}},
+		{Object: "ConfigMap/apps/unknown", Reconciliations: 2},
+	}, 2)
+	g.Expect(rules).To(Equal([]kustomizev1.IgnoreRule{
+		{
+			Paths:  []string{"/spec/replicas"},
+			Target: &kustomize.Selector{Kind: "Deployment", Namespace: "apps", Name: `my\.app`},
+		},
+		{
+			Paths:  []string{"/rules"},
+			Target: &kustomize.Selector{Kind: "ClusterRole", Name: "view"},
+		},
+	}))
+}
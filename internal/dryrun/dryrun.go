@@ -0,0 +1,108 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package dryrun downgrades the server-side dry-run denials of the admission
+// webhooks and ValidatingAdmissionPolicies to warnings, for the policy
+// engines which behave differently under dry-run than for the actual writes.
+package dryrun
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	ssautil "github.com/fluxcd/pkg/ssa/utils"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Client wraps a client to ignore the admission errors returned for the
+// dry-run patches, and records them as warnings. The other requests, and
+// the other errors, are passed through.
+type Client struct {
+	client.Client
+
+	mu       sync.Mutex
+	warnings []string
+}
+
+// NewClient returns a Client wrapping the given one.
+func NewClient(c client.Client) *Client {
+	return &Client{Client: c}
+}
+
+// Patch patches the object, and returns nil instead of the admission
+// errors if the patch is a dry-run.
+func (c *Client) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	err := c.Client.Patch(ctx, obj, patch, opts...)
+	if err == nil || !isDryRun(opts) || !IsAdmissionError(err) {
+		return err
+	}
+
+	name := fmt.Sprintf("%s/%s", obj.GetNamespace(), obj.GetName())
+	if u, ok := obj.(*unstructured.Unstructured); ok {
+		name = ssautil.FmtUnstructured(u)
+
+		// Report the existing objects as configured rather than created.
+		existing := &unstructured.Unstructured{}
+		existing.SetGroupVersionKind(u.GroupVersionKind())
+		if err := c.Client.Get(ctx, client.ObjectKeyFromObject(u), existing); err == nil {
+			u.SetResourceVersion(existing.GetResourceVersion())
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.warnings = append(c.warnings, fmt.Sprintf("%s dry-run failed: %s", name, err))
+	return nil
+}
+
+// Warnings returns the dry-run admission errors ignored so far, sorted.
+func (c *Client) Warnings() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	warnings := append([]string(nil), c.warnings...)
+	sort.Strings(warnings)
+	return warnings
+}
+
+// IsAdmissionError returns true if the error is returned by the API server
+// when an admission webhook or a ValidatingAdmissionPolicy denies the
+// request, or when a webhook doesn't support dry-run.
+func IsAdmissionError(err error) bool {
+	msg := err.Error()
+	if status, ok := err.(apierrors.APIStatus); ok {
+		msg = status.Status().Message
+	}
+	switch {
+	case strings.Contains(msg, "admission webhook") && strings.Contains(msg, "denied the request"):
+		return true
+	case strings.Contains(msg, "admission webhook") && strings.Contains(msg, "does not support dry run"):
+		return true
+	case strings.Contains(msg, "ValidatingAdmissionPolicy") && strings.Contains(msg, "denied request"):
+		return true
+	default:
+		return false
+	}
+}
+
+func isDryRun(opts []client.PatchOption) bool {
+	po := &client.PatchOptions{}
+	po.ApplyOptions(opts)
+	return len(po.DryRun) > 0
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dryrun
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func newStatusError(code int32, msg string) error {
+	return &apierrors.StatusError{ErrStatus: metav1.Status{
+		Status:  metav1.StatusFailure,
+		Code:    code,
+		Reason:  metav1.StatusReasonForbidden,
+		Message: msg,
+	}}
+}
+
+func TestIsAdmissionError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{
+			name: "webhook denial",
+			err:  newStatusError(http.StatusForbidden, `admission webhook "validate.kyverno.svc" denied the request: policy require-labels failed`),
+			want: true,
+		},
+		{
+			name: "webhook without dry-run support",
+			err:  newStatusError(http.StatusBadRequest, `admission webhook "mutate.example.com" does not support dry run`),
+			want: true,
+		},
+		{
+			name: "validating admission policy denial",
+			err:  newStatusError(http.StatusUnprocessableEntity, `deployments.apps "app" is forbidden: ValidatingAdmissionPolicy 'replicas' with binding 'replicas' denied request: failed expression`),
+			want: true,
+		},
+		{
+			name: "invalid object",
+			err:  newStatusError(http.StatusUnprocessableEntity, `Deployment.apps "app" is invalid: spec.replicas: Invalid value`),
+			want: false,
+		},
+		{
+			name: "other error",
+			err:  errors.New("connection refused"),
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(IsAdmissionError(tt.err)).To(Equal(tt.want))
+		})
+	}
+}
+
+func TestClient_Patch(t *testing.T) {
+	g := NewWithT(t)
+
+	denied := newStatusError(http.StatusForbidden, `admission webhook "validate.example.com" denied the request: nope`)
+	c := NewClient(fake.NewClientBuilder().WithInterceptorFuncs(interceptor.Funcs{
+		Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+			return denied
+		},
+	}).Build())
+
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion("v1")
+	obj.SetKind("ConfigMap")
+	obj.SetNamespace("apps")
+	obj.SetName("config")
+
+	g.Expect(c.Patch(context.Background(), obj, client.Apply, client.DryRunAll)).To(Succeed())
+	g.Expect(c.Patch(context.Background(), obj, client.Apply)).To(MatchError(denied))
+	g.Expect(c.Warnings()).To(Equal([]string{
+		`ConfigMap/apps/config dry-run failed: admission webhook "validate.example.com" denied the request: nope`,
+	}))
+}
@@ -0,0 +1,258 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package dryruncache reuses the results of the server-side apply dry-runs
+// across reconciliations, for the objects which are unchanged both in the
+// source and in the cluster.
+//
+// A dry-run result is reused when the desired object, the resourceVersion of
+// the object in the cluster, the version of the cluster and the revision of
+// its admission configurations are the same as when the result was recorded.
+// The results are kept for a limited time, as the admission webhooks may
+// depend on external state.
+package dryruncache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// admissionKinds are the kinds of the configurations of the admission
+// webhooks and policies, which can change the result of the dry-runs.
+var admissionKinds = []schema.GroupVersionKind{
+	{Group: "admissionregistration.k8s.io", Version: "v1", Kind: "MutatingWebhookConfigurationList"},
+	{Group: "admissionregistration.k8s.io", Version: "v1", Kind: "ValidatingWebhookConfigurationList"},
+	{Group: "admissionregistration.k8s.io", Version: "v1beta1", Kind: "ValidatingAdmissionPolicyList"},
+	{Group: "admissionregistration.k8s.io", Version: "v1beta1", Kind: "ValidatingAdmissionPolicyBindingList"},
+}
+
+// Cache holds the results of the server-side dry-runs for a period of time.
+type Cache struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	object    map[string]interface{}
+	expiresAt time.Time
+}
+
+// NewCache returns a Cache which keeps the dry-run results for the given
+// duration.
+func NewCache(ttl time.Duration) *Cache {
+	return &Cache{
+		ttl:     ttl,
+		entries: make(map[string]cacheEntry),
+	}
+}
+
+// Client returns a client wrapping the given one, which reuses the dry-run
+// results recorded with the same scope. The scope must identify the
+// Kustomization, the cluster and its admission configurations, e.g. with
+// Scope. The expired results are evicted.
+func (c *Cache) Client(kubeClient client.Client, scope string) *Client {
+	c.prune()
+	return &Client{
+		Client:   kubeClient,
+		cache:    c,
+		scope:    scope,
+		versions: make(map[string]string),
+	}
+}
+
+func (c *Cache) get(key string) (map[string]interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok || !time.Now().Before(entry.expiresAt) {
+		return nil, false
+	}
+	return entry.object, true
+}
+
+func (c *Cache) set(key string, object map[string]interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = cacheEntry{object: object, expiresAt: time.Now().Add(c.ttl)}
+}
+
+func (c *Cache) prune() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	for key, entry := range c.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// Client wraps a client to serve the server-side apply dry-runs from the
+// cache. The resourceVersions of the objects read with Get are recorded,
+// the dry-runs of the objects which haven't been read are passed through.
+type Client struct {
+	client.Client
+
+	cache *Cache
+	scope string
+
+	mu       sync.Mutex
+	versions map[string]string
+	hits     int
+}
+
+// Get reads the object, and records its resourceVersion for the dry-runs.
+func (c *Client) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	err := c.Client.Get(ctx, key, obj, opts...)
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return err
+	}
+
+	id := objectID(u.GroupVersionKind(), key)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch {
+	case err == nil:
+		c.versions[id] = u.GetResourceVersion()
+	case apierrors.IsNotFound(err):
+		c.versions[id] = ""
+	default:
+		delete(c.versions, id)
+	}
+	return err
+}
+
+// Patch patches the object. The results of the server-side apply dry-runs
+// are served from the cache when recorded, and recorded otherwise. The
+// errors aren't recorded.
+func (c *Client) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok || patch.Type() != types.ApplyPatchType || !isDryRun(opts) {
+		return c.Client.Patch(ctx, obj, patch, opts...)
+	}
+
+	c.mu.Lock()
+	version, found := c.versions[objectID(u.GroupVersionKind(), client.ObjectKeyFromObject(u))]
+	c.mu.Unlock()
+	if !found {
+		return c.Client.Patch(ctx, obj, patch, opts...)
+	}
+
+	key, err := c.key(u, version, opts)
+	if err != nil {
+		return c.Client.Patch(ctx, obj, patch, opts...)
+	}
+	if object, ok := c.cache.get(key); ok {
+		u.Object = runtime.DeepCopyJSON(object)
+		c.mu.Lock()
+		c.hits++
+		c.mu.Unlock()
+		return nil
+	}
+
+	if err := c.Client.Patch(ctx, obj, patch, opts...); err != nil {
+		return err
+	}
+	c.cache.set(key, runtime.DeepCopyJSON(u.Object))
+	return nil
+}
+
+// Hits returns the number of dry-runs served from the cache.
+func (c *Client) Hits() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hits
+}
+
+// key returns the cache key of the dry-run of the object.
+func (c *Client) key(u *unstructured.Unstructured, version string, opts []client.PatchOption) (string, error) {
+	data, err := json.Marshal(u.Object)
+	if err != nil {
+		return "", err
+	}
+	po := &client.PatchOptions{}
+	po.ApplyOptions(opts)
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%s\x00%s\x00",
+		c.scope, objectID(u.GroupVersionKind(), client.ObjectKeyFromObject(u)), version, po.FieldManager)
+	h.Write(data)
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+// Scope returns the version of the cluster the config points at, and the
+// revision of its admission configurations read with the given client.
+func Scope(ctx context.Context, cfg *rest.Config, reader client.Reader) (string, error) {
+	dc, err := discovery.NewDiscoveryClientForConfig(cfg)
+	if err != nil {
+		return "", err
+	}
+	version, err := dc.ServerVersion()
+	if err != nil {
+		return "", fmt.Errorf("unable to read the cluster version: %w", err)
+	}
+
+	var revisions []string
+	for _, gvk := range admissionKinds {
+		list := &metav1.PartialObjectMetadataList{}
+		list.SetGroupVersionKind(gvk)
+		if err := reader.List(ctx, list); err != nil {
+			// The admission policies are not served by all the clusters.
+			if apimeta.IsNoMatchError(err) || apierrors.IsNotFound(err) {
+				continue
+			}
+			return "", fmt.Errorf("unable to list the %s: %w", strings.TrimSuffix(gvk.Kind, "List"), err)
+		}
+		for _, item := range list.Items {
+			revisions = append(revisions, fmt.Sprintf("%s/%s@%s", gvk.Kind, item.GetName(), item.GetResourceVersion()))
+		}
+	}
+	sort.Strings(revisions)
+
+	h := sha256.New()
+	for _, r := range revisions {
+		fmt.Fprintln(h, r)
+	}
+	return fmt.Sprintf("%s/%x", version.GitVersion, h.Sum(nil)), nil
+}
+
+func objectID(gvk schema.GroupVersionKind, key client.ObjectKey) string {
+	return fmt.Sprintf("%s/%s/%s", gvk.String(), key.Namespace, key.Name)
+}
+
+func isDryRun(opts []client.PatchOption) bool {
+	po := &client.PatchOptions{}
+	po.ApplyOptions(opts)
+	return len(po.DryRun) > 0
+}
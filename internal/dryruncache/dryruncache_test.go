@@ -0,0 +1,138 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dryruncache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestClient_Patch(t *testing.T) {
+	existing := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "config", Namespace: "apps"},
+		Data:       map[string]string{"key": "value"},
+	}
+
+	var dryRuns int
+	kubeClient := fake.NewClientBuilder().WithObjects(existing).WithInterceptorFuncs(interceptor.Funcs{
+		Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+			dryRuns++
+			obj.SetResourceVersion("dry-run")
+			return nil
+		},
+	}).Build()
+
+	desired := func(value string) *unstructured.Unstructured {
+		u := &unstructured.Unstructured{}
+		u.SetAPIVersion("v1")
+		u.SetKind("ConfigMap")
+		u.SetNamespace("apps")
+		u.SetName("config")
+		_ = unstructured.SetNestedField(u.Object, value, "data", "key")
+		return u
+	}
+
+	// dryRun reads the object and dry-runs its apply, as done by the
+	// server-side apply manager.
+	dryRun := func(c *Client, u *unstructured.Unstructured) {
+		t.Helper()
+		existing := &unstructured.Unstructured{}
+		existing.SetGroupVersionKind(u.GroupVersionKind())
+		if err := c.Get(context.Background(), client.ObjectKeyFromObject(u), existing); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.Patch(context.Background(), u, client.Apply, client.DryRunAll, client.FieldOwner("flux")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("reuses the dry-run results of the unchanged objects", func(t *testing.T) {
+		g := NewWithT(t)
+		dryRuns = 0
+		cache := NewCache(time.Minute)
+
+		c := cache.Client(kubeClient, "scope")
+		dryRun(c, desired("value"))
+		g.Expect(c.Hits()).To(Equal(0))
+
+		c = cache.Client(kubeClient, "scope")
+		u := desired("value")
+		dryRun(c, u)
+		g.Expect(c.Hits()).To(Equal(1))
+		g.Expect(u.GetResourceVersion()).To(Equal("dry-run"))
+		g.Expect(dryRuns).To(Equal(1))
+	})
+
+	t.Run("dry-runs the changed objects", func(t *testing.T) {
+		g := NewWithT(t)
+		dryRuns = 0
+		cache := NewCache(time.Minute)
+
+		dryRun(cache.Client(kubeClient, "scope"), desired("value"))
+		dryRun(cache.Client(kubeClient, "scope"), desired("changed"))
+		dryRun(cache.Client(kubeClient, "other-scope"), desired("value"))
+		g.Expect(dryRuns).To(Equal(3))
+	})
+
+	t.Run("dry-runs the objects changed in the cluster", func(t *testing.T) {
+		g := NewWithT(t)
+		dryRuns = 0
+		cache := NewCache(time.Minute)
+
+		dryRun(cache.Client(kubeClient, "scope"), desired("value"))
+		cm := &corev1.ConfigMap{}
+		g.Expect(kubeClient.Get(context.Background(), client.ObjectKeyFromObject(existing), cm)).To(Succeed())
+		cm.Data["key"] = "drifted"
+		g.Expect(kubeClient.Update(context.Background(), cm)).To(Succeed())
+		dryRun(cache.Client(kubeClient, "scope"), desired("value"))
+		g.Expect(dryRuns).To(Equal(2))
+	})
+
+	t.Run("evicts the expired results", func(t *testing.T) {
+		g := NewWithT(t)
+		dryRuns = 0
+		cache := NewCache(time.Nanosecond)
+
+		dryRun(cache.Client(kubeClient, "scope"), desired("value"))
+		time.Sleep(time.Millisecond)
+		dryRun(cache.Client(kubeClient, "scope"), desired("value"))
+		g.Expect(dryRuns).To(Equal(2))
+		g.Expect(cache.entries).To(HaveLen(1))
+	})
+
+	t.Run("passes through the applies", func(t *testing.T) {
+		g := NewWithT(t)
+		dryRuns = 0
+		cache := NewCache(time.Minute)
+
+		c := cache.Client(kubeClient, "scope")
+		dryRun(c, desired("value"))
+		g.Expect(c.Patch(context.Background(), desired("value"), client.Apply, client.FieldOwner("flux"))).To(Succeed())
+		g.Expect(c.Patch(context.Background(), desired("value"), client.Apply, client.FieldOwner("flux"))).To(Succeed())
+		g.Expect(dryRuns).To(Equal(3))
+		g.Expect(c.Hits()).To(Equal(0))
+	})
+}
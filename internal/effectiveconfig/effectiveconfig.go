@@ -0,0 +1,126 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package effectiveconfig serves the specs of the Kustomizations with the
+// defaults of the controller and the environment presets applied, along
+// with the controller settings affecting them, to explain why the behavior
+// of a Kustomization differs between clusters.
+package effectiveconfig
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+)
+
+// Path is the path the report is served at.
+const Path = "/debug/effective-config"
+
+// Controller holds the controller settings affecting the Kustomizations.
+type Controller struct {
+	DefaultServiceAccount     string            `json:"defaultServiceAccount,omitempty"`
+	DefaultTimeout            string            `json:"defaultTimeout,omitempty"`
+	DefaultRetryInterval      string            `json:"defaultRetryInterval,omitempty"`
+	DefaultCommonLabels       map[string]string `json:"defaultCommonLabels,omitempty"`
+	NoCrossNamespaceRefs      bool              `json:"noCrossNamespaceRefs"`
+	NoRemoteBases             bool              `json:"noRemoteBases"`
+	AllowLoadRestrictionsNone bool              `json:"allowLoadRestrictionsNone"`
+	DisallowedBuildOptions    []string          `json:"disallowedBuildOptions,omitempty"`
+	TenantLockdown            bool              `json:"tenantLockdown"`
+	TenantExemptNamespaces    []string          `json:"tenantExemptNamespaces,omitempty"`
+	TenantDenyClusterScoped   bool              `json:"tenantDenyClusterScoped"`
+	TenantClusterScopedKinds  []string          `json:"tenantClusterScopedKinds,omitempty"`
+	FeatureGates              map[string]bool   `json:"featureGates"`
+}
+
+// Settings holds the settings of a Kustomization computed from its spec
+// and the controller settings.
+type Settings struct {
+	Interval           string `json:"interval"`
+	Timeout            string `json:"timeout"`
+	RetryInterval      string `json:"retryInterval"`
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+	LoadRestrictions   string `json:"loadRestrictions"`
+	Mode               string `json:"mode"`
+}
+
+// Kustomization is the effective configuration of a Kustomization.
+type Kustomization struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+
+	// Spec is the spec with the defaults of the controller and
+	// the environment presets applied.
+	Spec kustomizev1.KustomizationSpec `json:"spec"`
+
+	// Effective holds the settings computed for the Kustomization.
+	Effective Settings `json:"effective"`
+
+	// Errors are the errors which prevent the Kustomization from being
+	// reconciled with this configuration, e.g. a tenancy violation or
+	// an environment which can't be resolved.
+	Errors []string `json:"errors,omitempty"`
+}
+
+// Report is the effective configuration report.
+type Report struct {
+	Controller     Controller      `json:"controller"`
+	Kustomizations []Kustomization `json:"kustomizations"`
+}
+
+// Options configures the collection of the report.
+type Options struct {
+	// Collect returns the report for the Kustomizations of the namespace,
+	// all namespaces if empty, restricted to the given name if not empty.
+	Collect func(ctx context.Context, namespace, name string) (Report, error)
+}
+
+// Handler returns an HTTP handler serving the report as JSON, filtered with
+// the optional 'namespace' and 'name' query parameters. The options are read
+// on each request, which allows to set the collector once the controller is
+// set up.
+func Handler(opts *Options) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", "GET")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if opts.Collect == nil {
+			http.Error(w, "the controller is not ready", http.StatusServiceUnavailable)
+			return
+		}
+
+		query := r.URL.Query()
+		if query.Get("name") != "" && query.Get("namespace") == "" {
+			http.Error(w, "the 'namespace' query parameter is required with 'name'", http.StatusBadRequest)
+			return
+		}
+
+		report, err := opts.Collect(r.Context(), query.Get("namespace"), query.Get("name"))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to collect the effective configuration: %s", err), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(report)
+	})
+}
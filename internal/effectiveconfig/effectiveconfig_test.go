@@ -0,0 +1,103 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package effectiveconfig
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestHandler(t *testing.T) {
+	var gotNamespace, gotName string
+	opts := &Options{
+		Collect: func(_ context.Context, namespace, name string) (Report, error) {
+			gotNamespace, gotName = namespace, name
+			if namespace == "broken" {
+				return Report{}, errors.New("cache not synced")
+			}
+			return Report{
+				Controller: Controller{DefaultTimeout: "5m0s", FeatureGates: map[string]bool{"GateA": true}},
+				Kustomizations: []Kustomization{
+					{Name: "apps", Namespace: namespace, Effective: Settings{Timeout: "5m0s"}},
+				},
+			}, nil
+		},
+	}
+
+	tests := []struct {
+		name          string
+		query         string
+		code          int
+		wantNamespace string
+		wantName      string
+	}{
+		{name: "all namespaces", code: http.StatusOK},
+		{name: "namespace", query: "namespace=flux-system", code: http.StatusOK, wantNamespace: "flux-system"},
+		{
+			name:          "name",
+			query:         "namespace=flux-system&name=apps",
+			code:          http.StatusOK,
+			wantNamespace: "flux-system",
+			wantName:      "apps",
+		},
+		{name: "name without namespace", query: "name=apps", code: http.StatusBadRequest},
+		{name: "collect failure", query: "namespace=broken", code: http.StatusInternalServerError},
+	}
+
+	handler := Handler(opts)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			gotNamespace, gotName = "", ""
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, Path+"?"+tt.query, nil))
+			g.Expect(rec.Code).To(Equal(tt.code))
+			if tt.code != http.StatusOK {
+				return
+			}
+			g.Expect(gotNamespace).To(Equal(tt.wantNamespace))
+			g.Expect(gotName).To(Equal(tt.wantName))
+
+			var report Report
+			g.Expect(json.Unmarshal(rec.Body.Bytes(), &report)).To(Succeed())
+			g.Expect(report.Controller.DefaultTimeout).To(Equal("5m0s"))
+			g.Expect(report.Controller.FeatureGates).To(HaveKeyWithValue("GateA", true))
+			g.Expect(report.Kustomizations).To(HaveLen(1))
+			g.Expect(report.Kustomizations[0].Effective.Timeout).To(Equal("5m0s"))
+		})
+	}
+
+	t.Run("method not allowed", func(t *testing.T) {
+		g := NewWithT(t)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, Path, nil))
+		g.Expect(rec.Code).To(Equal(http.StatusMethodNotAllowed))
+	})
+
+	t.Run("controller not ready", func(t *testing.T) {
+		g := NewWithT(t)
+		rec := httptest.NewRecorder()
+		Handler(&Options{}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, Path, nil))
+		g.Expect(rec.Code).To(Equal(http.StatusServiceUnavailable))
+	})
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package environment resolves the presets of a Kustomization which
+// match the labels describing the cluster, e.g. its region or tier.
+package environment
+
+import (
+	"fmt"
+	"slices"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+)
+
+// Result is the merge of the presets matching the cluster labels.
+type Result struct {
+	// Presets are the names of the matching presets, in order.
+	Presets []string
+	// Path is the path of the last matching preset setting one.
+	Path string
+	// Components are the components of the matching presets, in order.
+	Components []string
+	// Substitute holds the variables of the matching presets.
+	Substitute map[string]string
+}
+
+// Resolve returns the merge of the presets of the environment which
+// match the given cluster labels, the later presets taking precedence.
+func Resolve(env *kustomizev1.Environment, clusterLabels map[string]string) (Result, error) {
+	result := Result{Substitute: make(map[string]string)}
+	if env == nil {
+		return result, nil
+	}
+
+	for _, preset := range env.Presets {
+		selector, err := metav1.LabelSelectorAsSelector(&preset.Selector)
+		if err != nil {
+			return Result{}, fmt.Errorf("invalid selector of the environment preset '%s': %w", preset.Name, err)
+		}
+		if !selector.Matches(labels.Set(clusterLabels)) {
+			continue
+		}
+
+		result.Presets = append(result.Presets, preset.Name)
+		if preset.Path != "" {
+			result.Path = preset.Path
+		}
+		for _, component := range preset.Components {
+			if !slices.Contains(result.Components, component) {
+				result.Components = append(result.Components, component)
+			}
+		}
+		for k, v := range preset.Substitute {
+			result.Substitute[k] = v
+		}
+	}
+	return result, nil
+}
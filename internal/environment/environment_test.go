@@ -0,0 +1,93 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package environment
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+)
+
+func TestResolve(t *testing.T) {
+	env := &kustomizev1.Environment{
+		Presets: []kustomizev1.EnvironmentPreset{
+			{
+				Name:       "all",
+				Selector:   metav1.LabelSelector{},
+				Components: []string{"../monitoring"},
+				Substitute: map[string]string{"REPLICAS": "1", "LOG_LEVEL": "info"},
+			},
+			{
+				Name:       "production",
+				Selector:   metav1.LabelSelector{MatchLabels: map[string]string{"tier": "production"}},
+				Path:       "./overlays/production",
+				Components: []string{"../monitoring", "../backup"},
+				Substitute: map[string]string{"REPLICAS": "3"},
+			},
+			{
+				Name: "europe",
+				Selector: metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "region", Operator: metav1.LabelSelectorOpIn, Values: []string{"eu-west-1", "eu-central-1"}},
+				}},
+				Substitute: map[string]string{"DATA_RESIDENCY": "eu"},
+			},
+		},
+	}
+
+	t.Run("merges the matching presets in order", func(t *testing.T) {
+		g := NewWithT(t)
+
+		result, err := Resolve(env, map[string]string{"tier": "production", "region": "eu-west-1"})
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(result.Presets).To(Equal([]string{"all", "production", "europe"}))
+		g.Expect(result.Path).To(Equal("./overlays/production"))
+		g.Expect(result.Components).To(Equal([]string{"../monitoring", "../backup"}))
+		g.Expect(result.Substitute).To(Equal(map[string]string{
+			"REPLICAS":       "3",
+			"LOG_LEVEL":      "info",
+			"DATA_RESIDENCY": "eu",
+		}))
+	})
+
+	t.Run("skips the presets which don't match", func(t *testing.T) {
+		g := NewWithT(t)
+
+		result, err := Resolve(env, map[string]string{"tier": "staging", "region": "us-east-1"})
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(result.Presets).To(Equal([]string{"all"}))
+		g.Expect(result.Path).To(BeEmpty())
+		g.Expect(result.Substitute).To(HaveKeyWithValue("REPLICAS", "1"))
+	})
+
+	t.Run("fails on invalid selectors", func(t *testing.T) {
+		g := NewWithT(t)
+
+		_, err := Resolve(&kustomizev1.Environment{
+			Presets: []kustomizev1.EnvironmentPreset{{
+				Name: "invalid",
+				Selector: metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "tier", Operator: "Like"},
+				}},
+			}},
+		}, nil)
+		g.Expect(err).To(HaveOccurred())
+		g.Expect(err.Error()).To(ContainSubstring("'invalid'"))
+	})
+}
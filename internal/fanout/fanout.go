@@ -0,0 +1,160 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package fanout orders the reconciliations of the Kustomizations consuming
+// a source, so that the dependents are enqueued once their dependencies have
+// been reconciled, instead of waiting for them in the dependsOn retry loop.
+package fanout
+
+import (
+	"sort"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+)
+
+// Tracker keeps track of the Kustomizations waiting for their dependencies
+// to be reconciled. A nil Tracker doesn't order the reconciliations.
+type Tracker struct {
+	mu      sync.Mutex
+	waiting map[types.NamespacedName]map[types.NamespacedName]struct{}
+}
+
+// NewTracker returns an empty Tracker.
+func NewTracker() *Tracker {
+	return &Tracker{
+		waiting: make(map[types.NamespacedName]map[types.NamespacedName]struct{}),
+	}
+}
+
+// Start registers the Kustomizations to reconcile, and returns the ones to
+// enqueue first, which don't depend on any of the others. The dependencies
+// which are not part of the list are ignored. All the Kustomizations are
+// returned, and none is registered, if their dependencies form a cycle.
+func (t *Tracker) Start(objects []kustomizev1.Kustomization) []types.NamespacedName {
+	keys := make(map[types.NamespacedName]struct{}, len(objects))
+	for i := range objects {
+		keys[key(&objects[i])] = struct{}{}
+	}
+	if t == nil {
+		return sorted(keys)
+	}
+
+	waiting := make(map[types.NamespacedName]map[types.NamespacedName]struct{}, len(objects))
+	for i := range objects {
+		k := key(&objects[i])
+		for _, dep := range objects[i].GetDependsOn() {
+			d := types.NamespacedName{Namespace: dep.Namespace, Name: dep.Name}
+			if d.Namespace == "" {
+				d.Namespace = k.Namespace
+			}
+			if _, ok := keys[d]; !ok || d == k {
+				continue
+			}
+			if waiting[k] == nil {
+				waiting[k] = make(map[types.NamespacedName]struct{})
+			}
+			waiting[k][d] = struct{}{}
+		}
+	}
+	if hasCycle(keys, waiting) {
+		return sorted(keys)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	roots := make(map[types.NamespacedName]struct{})
+	for k := range keys {
+		if deps, ok := waiting[k]; ok {
+			t.waiting[k] = deps
+		} else {
+			delete(t.waiting, k)
+			roots[k] = struct{}{}
+		}
+	}
+	return sorted(roots)
+}
+
+// Done marks the reconciliation of the Kustomization as done, and returns
+// the Kustomizations which no longer wait for any dependency.
+func (t *Tracker) Done(k types.NamespacedName) []types.NamespacedName {
+	if t == nil {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	ready := make(map[types.NamespacedName]struct{})
+	for dependent, deps := range t.waiting {
+		if _, ok := deps[k]; !ok {
+			continue
+		}
+		delete(deps, k)
+		if len(deps) == 0 {
+			delete(t.waiting, dependent)
+			ready[dependent] = struct{}{}
+		}
+	}
+	return sorted(ready)
+}
+
+// hasCycle returns true if the dependencies can't be sorted topologically.
+func hasCycle(keys map[types.NamespacedName]struct{},
+	waiting map[types.NamespacedName]map[types.NamespacedName]struct{}) bool {
+	remaining := make(map[types.NamespacedName]int, len(keys))
+	dependents := make(map[types.NamespacedName][]types.NamespacedName)
+	var queue []types.NamespacedName
+	for k := range keys {
+		remaining[k] = len(waiting[k])
+		if remaining[k] == 0 {
+			queue = append(queue, k)
+		}
+		for d := range waiting[k] {
+			dependents[d] = append(dependents[d], k)
+		}
+	}
+
+	visited := 0
+	for len(queue) > 0 {
+		k := queue[0]
+		queue = queue[1:]
+		visited++
+		for _, dependent := range dependents[k] {
+			remaining[dependent]--
+			if remaining[dependent] == 0 {
+				queue = append(queue, dependent)
+			}
+		}
+	}
+	return visited != len(keys)
+}
+
+func key(obj *kustomizev1.Kustomization) types.NamespacedName {
+	return types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()}
+}
+
+func sorted(keys map[types.NamespacedName]struct{}) []types.NamespacedName {
+	result := make([]types.NamespacedName, 0, len(keys))
+	for k := range keys {
+		result = append(result, k)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].String() < result[j].String()
+	})
+	return result
+}
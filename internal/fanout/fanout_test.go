@@ -0,0 +1,92 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fanout
+
+import (
+	"testing"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+)
+
+func newKustomization(name string, dependsOn ...string) kustomizev1.Kustomization {
+	obj := kustomizev1.Kustomization{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "flux-system"},
+	}
+	for _, dep := range dependsOn {
+		obj.Spec.DependsOn = append(obj.Spec.DependsOn, meta.NamespacedObjectReference{Name: dep})
+	}
+	return obj
+}
+
+func keys(names ...string) []types.NamespacedName {
+	result := make([]types.NamespacedName, 0, len(names))
+	for _, name := range names {
+		result = append(result, types.NamespacedName{Namespace: "flux-system", Name: name})
+	}
+	return result
+}
+
+func TestTracker(t *testing.T) {
+	t.Run("enqueues the dependents once their dependencies are done", func(t *testing.T) {
+		g := NewWithT(t)
+
+		tracker := NewTracker()
+		roots := tracker.Start([]kustomizev1.Kustomization{
+			newKustomization("apps", "infra-configs", "crds"),
+			newKustomization("infra-configs", "infra-controllers"),
+			newKustomization("infra-controllers", "not-consuming-the-source"),
+			newKustomization("crds"),
+		})
+		g.Expect(roots).To(Equal(keys("crds", "infra-controllers")))
+
+		g.Expect(tracker.Done(keys("crds")[0])).To(BeEmpty())
+		g.Expect(tracker.Done(keys("infra-controllers")[0])).To(Equal(keys("infra-configs")))
+		g.Expect(tracker.Done(keys("infra-controllers")[0])).To(BeEmpty())
+		g.Expect(tracker.Done(keys("infra-configs")[0])).To(Equal(keys("apps")))
+		g.Expect(tracker.waiting).To(BeEmpty())
+	})
+
+	t.Run("enqueues all the Kustomizations of a cycle", func(t *testing.T) {
+		g := NewWithT(t)
+
+		tracker := NewTracker()
+		roots := tracker.Start([]kustomizev1.Kustomization{
+			newKustomization("a", "b"),
+			newKustomization("b", "a"),
+			newKustomization("c"),
+		})
+		g.Expect(roots).To(Equal(keys("a", "b", "c")))
+		g.Expect(tracker.waiting).To(BeEmpty())
+	})
+
+	t.Run("enqueues all the Kustomizations without tracker", func(t *testing.T) {
+		g := NewWithT(t)
+
+		var tracker *Tracker
+		roots := tracker.Start([]kustomizev1.Kustomization{
+			newKustomization("apps", "infra"),
+			newKustomization("infra"),
+		})
+		g.Expect(roots).To(Equal(keys("apps", "infra")))
+		g.Expect(tracker.Done(keys("infra")[0])).To(BeEmpty())
+	})
+}
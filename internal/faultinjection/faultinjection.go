@@ -0,0 +1,184 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package faultinjection injects faults in the reconciliations of the
+// Kustomizations matching a label selector, to validate the alerting and the
+// runbooks against the failure modes of the controller in test environments.
+package faultinjection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	flag "github.com/spf13/pflag"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	flagSelector                  = "fault-injection-selector"
+	flagApplyDelay                = "fault-injection-apply-delay"
+	flagHealthCheckFailurePercent = "fault-injection-health-check-failure-percent"
+	flagDropPrune                 = "fault-injection-drop-prune"
+)
+
+// The names of the injected faults, as reported by the metric.
+const (
+	ApplyDelayFault         = "apply-delay"
+	HealthCheckFailureFault = "health-check-failure"
+	DropPruneFault          = "drop-prune"
+)
+
+// ErrHealthCheck is returned for the health checks failed by injection.
+var ErrHealthCheck = errors.New("health check failure injected by the controller")
+
+// Options contains the fault injection settings.
+type Options struct {
+	// Selector is the label selector of the Kustomizations in which the
+	// faults are injected. Required to inject any fault.
+	Selector string
+
+	// ApplyDelay delays the applies.
+	ApplyDelay time.Duration
+
+	// HealthCheckFailurePercent is the percentage of the health checks
+	// failed after they passed.
+	HealthCheckFailurePercent int
+
+	// DropPrune skips the garbage collection.
+	DropPrune bool
+}
+
+// BindFlags will parse the given pflag.FlagSet for the fault injection
+// flags and set the Options accordingly.
+func (o *Options) BindFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.Selector, flagSelector, "",
+		"The label selector of the Kustomizations in which the faults are injected. "+
+			"For test environments only, the faults are never injected when empty.")
+	fs.DurationVar(&o.ApplyDelay, flagApplyDelay, 0,
+		"The delay injected before the applies of the selected Kustomizations.")
+	fs.IntVar(&o.HealthCheckFailurePercent, flagHealthCheckFailurePercent, 0,
+		"The percentage of the passed health checks of the selected Kustomizations reported as failed.")
+	fs.BoolVar(&o.DropPrune, flagDropPrune, false,
+		"Skip the garbage collection of the selected Kustomizations, the stale objects are kept in the inventory.")
+}
+
+// Injector injects the faults in the reconciliations of the selected
+// Kustomizations. A nil Injector injects no fault.
+type Injector struct {
+	selector                  labels.Selector
+	applyDelay                time.Duration
+	healthCheckFailurePercent int
+	dropPrune                 bool
+
+	// random returns a number in [0,100).
+	random func() int
+}
+
+// New returns an Injector for the options, or nil if the selector is empty.
+func New(o Options) (*Injector, error) {
+	faults := o.ApplyDelay > 0 || o.HealthCheckFailurePercent > 0 || o.DropPrune
+	if o.Selector == "" {
+		if faults {
+			return nil, fmt.Errorf("--%s is required to inject faults", flagSelector)
+		}
+		return nil, nil
+	}
+	if !faults {
+		return nil, fmt.Errorf("--%s is set but no fault is configured", flagSelector)
+	}
+	if o.ApplyDelay < 0 {
+		return nil, fmt.Errorf("invalid --%s value: must not be negative", flagApplyDelay)
+	}
+	if o.HealthCheckFailurePercent < 0 || o.HealthCheckFailurePercent > 100 {
+		return nil, fmt.Errorf("invalid --%s value: must be between 0 and 100", flagHealthCheckFailurePercent)
+	}
+	selector, err := labels.Parse(o.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --%s value: %w", flagSelector, err)
+	}
+	return &Injector{
+		selector:                  selector,
+		applyDelay:                o.ApplyDelay,
+		healthCheckFailurePercent: o.HealthCheckFailurePercent,
+		dropPrune:                 o.DropPrune,
+		random:                    func() int { return rand.Intn(100) },
+	}, nil
+}
+
+// DelayApply waits for the apply delay if the object is selected, and
+// returns the context error if it is done before.
+func (i *Injector) DelayApply(ctx context.Context, obj client.Object) error {
+	if i == nil || i.applyDelay == 0 || !i.selected(obj) {
+		return nil
+	}
+	record(obj, ApplyDelayFault)
+	timer := time.NewTimer(i.applyDelay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// FailHealthCheck returns ErrHealthCheck for the configured percentage of
+// the health checks of the object, if it is selected.
+func (i *Injector) FailHealthCheck(obj client.Object) error {
+	if i == nil || i.healthCheckFailurePercent == 0 || !i.selected(obj) {
+		return nil
+	}
+	if i.random() >= i.healthCheckFailurePercent {
+		return nil
+	}
+	record(obj, HealthCheckFailureFault)
+	return ErrHealthCheck
+}
+
+// DropPrune returns true if the garbage collection of the object is skipped.
+func (i *Injector) DropPrune(obj client.Object) bool {
+	if i == nil || !i.dropPrune || !i.selected(obj) {
+		return false
+	}
+	record(obj, DropPruneFault)
+	return true
+}
+
+func (i *Injector) selected(obj client.Object) bool {
+	return i.selector.Matches(labels.Set(obj.GetLabels()))
+}
+
+func record(obj client.Object, fault string) {
+	injectedFaults.WithLabelValues(obj.GetName(), obj.GetNamespace(), fault).Inc()
+}
+
+var injectedFaults = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "gotk_injected_faults_total",
+		Help: "The number of faults injected in the reconciliations of a Kustomization.",
+	},
+	[]string{"name", "namespace", "fault"},
+)
+
+// RegisterMetrics registers the fault injection metrics with the registry.
+func RegisterMetrics(registry prometheus.Registerer) {
+	registry.MustRegister(injectedFaults)
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package faultinjection
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    Options
+		wantNil bool
+		wantErr string
+	}{
+		{name: "disabled", wantNil: true},
+		{name: "valid", opts: Options{Selector: "chaos=enabled", DropPrune: true}},
+		{
+			name:    "fault without selector",
+			opts:    Options{ApplyDelay: time.Minute},
+			wantErr: "--fault-injection-selector is required",
+		},
+		{
+			name:    "selector without fault",
+			opts:    Options{Selector: "chaos=enabled"},
+			wantErr: "no fault is configured",
+		},
+		{
+			name:    "invalid percent",
+			opts:    Options{Selector: "chaos=enabled", HealthCheckFailurePercent: 101},
+			wantErr: "must be between 0 and 100",
+		},
+		{
+			name:    "invalid selector",
+			opts:    Options{Selector: "chaos in", DropPrune: true},
+			wantErr: "invalid --fault-injection-selector value",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			injector, err := New(tt.opts)
+			if tt.wantErr != "" {
+				g.Expect(err).To(HaveOccurred())
+				g.Expect(err.Error()).To(ContainSubstring(tt.wantErr))
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(injector == nil).To(Equal(tt.wantNil))
+		})
+	}
+}
+
+func TestInjector(t *testing.T) {
+	selected := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "a", Labels: map[string]string{"chaos": "enabled"}}}
+	other := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "b"}}
+
+	t.Run("nil injector", func(t *testing.T) {
+		g := NewWithT(t)
+		var injector *Injector
+		g.Expect(injector.DelayApply(context.Background(), selected)).To(Succeed())
+		g.Expect(injector.FailHealthCheck(selected)).To(Succeed())
+		g.Expect(injector.DropPrune(selected)).To(BeFalse())
+	})
+
+	t.Run("drop prune", func(t *testing.T) {
+		g := NewWithT(t)
+		injector, err := New(Options{Selector: "chaos=enabled", DropPrune: true})
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(injector.DropPrune(selected)).To(BeTrue())
+		g.Expect(injector.DropPrune(other)).To(BeFalse())
+	})
+
+	t.Run("health check failures", func(t *testing.T) {
+		g := NewWithT(t)
+		injector, err := New(Options{Selector: "chaos=enabled", HealthCheckFailurePercent: 30})
+		g.Expect(err).NotTo(HaveOccurred())
+		n := 0
+		injector.random = func() int {
+			n = (n + 1) % 100
+			return n
+		}
+		failed := 0
+		for i := 0; i < 100; i++ {
+			if injector.FailHealthCheck(selected) != nil {
+				failed++
+			}
+		}
+		g.Expect(failed).To(Equal(30))
+		g.Expect(injector.FailHealthCheck(other)).To(Succeed())
+	})
+
+	t.Run("apply delay", func(t *testing.T) {
+		g := NewWithT(t)
+		injector, err := New(Options{Selector: "chaos=enabled", ApplyDelay: 50 * time.Millisecond})
+		g.Expect(err).NotTo(HaveOccurred())
+
+		start := time.Now()
+		g.Expect(injector.DelayApply(context.Background(), selected)).To(Succeed())
+		g.Expect(time.Since(start)).To(BeNumerically(">=", 50*time.Millisecond))
+
+		start = time.Now()
+		g.Expect(injector.DelayApply(context.Background(), other)).To(Succeed())
+		g.Expect(time.Since(start)).To(BeNumerically("<", 50*time.Millisecond))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		g.Expect(injector.DelayApply(ctx, selected)).To(MatchError(context.Canceled))
+	})
+}
@@ -39,6 +39,63 @@ const (
 	// DisableFailFastBehavior controls whether the fail-fast behavior when
 	// waiting for resources to become ready should be disabled.
 	DisableFailFastBehavior = "DisableFailFastBehavior"
+
+	// ContinuousHealthChecks controls whether the resources of a Kustomization
+	// should be watched after its health checks passed.
+	//
+	// When enabled, the Healthy condition is updated and an event is emitted
+	// as soon as a resource degrades or recovers, instead of at the next
+	// reconciliation. This results in increased memory usage and API server
+	// load, as one watch per resource kind and namespace is kept open.
+	ContinuousHealthChecks = "ContinuousHealthChecks"
+
+	// WatchReferencedObjects controls whether the Secrets and ConfigMaps
+	// referenced in the post build substitutions, the decryption and the
+	// kubeconfig of a Kustomization should be watched.
+	//
+	// When enabled, a change to a referenced object triggers the
+	// reconciliation of the Kustomizations referencing it. Each object is
+	// watched individually with metadata only, which doesn't require the
+	// Secrets and ConfigMaps to be cached.
+	WatchReferencedObjects = "WatchReferencedObjects"
+
+	// ReapplyOnWebhookRecovery controls whether the Kustomizations which
+	// failed to apply because a webhook couldn't be called should be
+	// applied again as soon as the webhook service has ready endpoints.
+	//
+	// When enabled, the EndpointSlices of the failing webhook services are
+	// watched until they recover, instead of waiting for the retry interval.
+	ReapplyOnWebhookRecovery = "ReapplyOnWebhookRecovery"
+
+	// CheckResourceQuotas controls whether the object count quotas of the
+	// target namespaces should be checked before the objects are applied.
+	//
+	// When enabled, the apply fails early with the quotas which would be
+	// exceeded by the new objects, at the cost of one request per new object
+	// in the namespaces with resource quotas.
+	CheckResourceQuotas = "CheckResourceQuotas"
+
+	// DetectOwnershipConflicts controls whether the apply should fail when
+	// objects of a Kustomization are in the inventory of another one.
+	//
+	// When enabled, the Kustomizations are indexed by the objects of their
+	// inventory, which results in increased memory usage.
+	DetectOwnershipConflicts = "DetectOwnershipConflicts"
+
+	// ReconciliationGates controls whether the applies of the Kustomizations
+	// should be paused while a ReconciliationGate selecting them is closed.
+	//
+	// When enabled, the controller watches the ReconciliationGates, which
+	// requires their CRD to be installed.
+	ReconciliationGates = "ReconciliationGates"
+
+	// NamespaceReports controls whether the controller should maintain a
+	// KustomizationReport summarizing the Kustomizations of each namespace.
+	//
+	// When enabled, the controller lists the Kustomizations of the namespace
+	// after each reconciliation, which requires the CRD of the reports to be
+	// installed.
+	NamespaceReports = "NamespaceReports"
 )
 
 var features = map[string]bool{
@@ -51,6 +108,27 @@ var features = map[string]bool{
 	// DisableFailFastBehavior
 	// opt-in from v1.1
 	DisableFailFastBehavior: false,
+	// ContinuousHealthChecks
+	// opt-in from v1.3
+	ContinuousHealthChecks: false,
+	// WatchReferencedObjects
+	// opt-in from v1.3
+	WatchReferencedObjects: false,
+	// ReapplyOnWebhookRecovery
+	// opt-in from v1.3
+	ReapplyOnWebhookRecovery: false,
+	// CheckResourceQuotas
+	// opt-in from v1.3
+	CheckResourceQuotas: false,
+	// DetectOwnershipConflicts
+	// opt-in from v1.3
+	DetectOwnershipConflicts: false,
+	// ReconciliationGates
+	// opt-in from v1.3
+	ReconciliationGates: false,
+	// NamespaceReports
+	// opt-in from v1.3
+	NamespaceReports: false,
 }
 
 // FeatureGates contains a list of all supported feature gates and
@@ -0,0 +1,71 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package fieldvalidation sets the server-side field validation directive
+// of the requests writing the objects, which tells the API server how to
+// handle their unknown and duplicate fields.
+package fieldvalidation
+
+import (
+	"context"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Client wraps a client to set the field validation directive of the
+// create and patch requests, including the dry-runs.
+type Client struct {
+	client.Client
+
+	directive string
+}
+
+// NewClient returns a Client setting the given directive, one of 'Strict',
+// 'Warn' or 'Ignore'.
+func NewClient(c client.Client, directive string) *Client {
+	return &Client{Client: c, directive: directive}
+}
+
+// Create creates the object with the field validation directive.
+func (c *Client) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	return c.Client.Create(ctx, obj, append(opts, option(c.directive))...)
+}
+
+// Patch patches the object with the field validation directive.
+func (c *Client) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	return c.Client.Patch(ctx, obj, patch, append(opts, option(c.directive))...)
+}
+
+// option sets the field validation directive of the raw request options,
+// as the client options don't expose it.
+type option string
+
+// ApplyToCreate implements client.CreateOption.
+func (o option) ApplyToCreate(co *client.CreateOptions) {
+	if co.Raw == nil {
+		co.Raw = &metav1.CreateOptions{}
+	}
+	co.Raw.FieldValidation = string(o)
+}
+
+// ApplyToPatch implements client.PatchOption.
+func (o option) ApplyToPatch(po *client.PatchOptions) {
+	if po.Raw == nil {
+		po.Raw = &metav1.PatchOptions{}
+	}
+	po.Raw.FieldValidation = string(o)
+}
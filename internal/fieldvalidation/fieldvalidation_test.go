@@ -0,0 +1,57 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fieldvalidation
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestClient(t *testing.T) {
+	g := NewWithT(t)
+
+	var createOpts *metav1.CreateOptions
+	var patchOpts *metav1.PatchOptions
+	c := NewClient(fake.NewClientBuilder().WithInterceptorFuncs(interceptor.Funcs{
+		Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+			co := &client.CreateOptions{}
+			createOpts = co.ApplyOptions(opts).AsCreateOptions()
+			return nil
+		},
+		Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+			po := &client.PatchOptions{}
+			patchOpts = po.ApplyOptions(opts).AsPatchOptions()
+			return nil
+		},
+	}).Build(), "Strict")
+
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "config", Namespace: "apps"}}
+	g.Expect(c.Create(context.Background(), cm, client.FieldOwner("flux"))).To(Succeed())
+	g.Expect(createOpts.FieldValidation).To(Equal("Strict"))
+	g.Expect(createOpts.FieldManager).To(Equal("flux"))
+
+	g.Expect(c.Patch(context.Background(), cm, client.Apply, client.DryRunAll, client.FieldOwner("flux"))).To(Succeed())
+	g.Expect(patchOpts.FieldValidation).To(Equal("Strict"))
+	g.Expect(patchOpts.DryRun).To(Equal([]string{metav1.DryRunAll}))
+}
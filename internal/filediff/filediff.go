@@ -0,0 +1,116 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package filediff summarizes the file changes between two revisions
+// of a source artifact.
+package filediff
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Snapshot maps the paths of the files, relative to the snapshot root
+// and slash separated, to their SHA-256 digest.
+type Snapshot map[string]string
+
+// Summary lists the files added, changed and removed between two snapshots.
+type Summary struct {
+	Added   []string
+	Changed []string
+	Removed []string
+}
+
+// Take returns the Snapshot of the regular files in the root directory.
+func Take(root string) (Snapshot, error) {
+	snapshot := make(Snapshot)
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		digest, err := fileDigest(path)
+		if err != nil {
+			return err
+		}
+		snapshot[filepath.ToSlash(rel)] = digest
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// Compare returns the sorted lists of the files added, changed and removed
+// in the current Snapshot compared to the previous one.
+func Compare(previous, current Snapshot) Summary {
+	var s Summary
+	for path, digest := range current {
+		prev, ok := previous[path]
+		switch {
+		case !ok:
+			s.Added = append(s.Added, path)
+		case prev != digest:
+			s.Changed = append(s.Changed, path)
+		}
+	}
+	for path := range previous {
+		if _, ok := current[path]; !ok {
+			s.Removed = append(s.Removed, path)
+		}
+	}
+	sort.Strings(s.Added)
+	sort.Strings(s.Changed)
+	sort.Strings(s.Removed)
+	return s
+}
+
+// IsEmpty returns true if no file changed.
+func (s Summary) IsEmpty() bool {
+	return len(s.Added) == 0 && len(s.Changed) == 0 && len(s.Removed) == 0
+}
+
+// String returns the number of files added, changed and removed.
+func (s Summary) String() string {
+	return fmt.Sprintf("%d files added, %d changed, %d removed", len(s.Added), len(s.Changed), len(s.Removed))
+}
+
+func fileDigest(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filediff
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Join(root, filepath.Dir(path)), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(root, path), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestCompare(t *testing.T) {
+	g := NewWithT(t)
+
+	previous, err := Take(writeFiles(t, map[string]string{
+		"kustomization.yaml": "resources:\n- app.yaml\n",
+		"app.yaml":           "replicas: 1\n",
+		"config/old.yaml":    "old\n",
+		"config/same.yaml":   "same\n",
+	}))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(previous).To(HaveKey("config/old.yaml"))
+
+	current, err := Take(writeFiles(t, map[string]string{
+		"kustomization.yaml": "resources:\n- app.yaml\n",
+		"app.yaml":           "replicas: 2\n",
+		"config/new.yaml":    "new\n",
+		"config/same.yaml":   "same\n",
+	}))
+	g.Expect(err).ToNot(HaveOccurred())
+
+	summary := Compare(previous, current)
+	g.Expect(summary.Added).To(Equal([]string{"config/new.yaml"}))
+	g.Expect(summary.Changed).To(Equal([]string{"app.yaml"}))
+	g.Expect(summary.Removed).To(Equal([]string{"config/old.yaml"}))
+	g.Expect(summary.String()).To(Equal("1 files added, 1 changed, 1 removed"))
+	g.Expect(summary.IsEmpty()).To(BeFalse())
+
+	g.Expect(Compare(current, current).IsEmpty()).To(BeTrue())
+}
@@ -0,0 +1,152 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package flapping identifies the objects changed by every apply while
+// their manifests are unchanged, because of mutating webhooks, defaulting
+// mismatches or other controllers fighting over their fields.
+package flapping
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Threshold is the number of consecutive reconciliations changing an
+// object from which it is reported as flapping.
+const Threshold = 2
+
+var flapping = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "gotk_flapping_resources",
+		Help: "The number of objects of the Kustomization changed by consecutive reconciliations while their manifests are unchanged.",
+	},
+	[]string{"name", "namespace"},
+)
+
+// RegisterMetrics registers the flapping objects metric with the registry.
+func RegisterMetrics(registry prometheus.Registerer) {
+	registry.MustRegister(flapping)
+}
+
+// Record sets the number of flapping objects of the Kustomization.
+func Record(name, namespace string, count int) {
+	flapping.WithLabelValues(name, namespace).Set(float64(count))
+}
+
+// Delete removes the metric of the Kustomization.
+func Delete(name, namespace string) {
+	flapping.DeleteLabelValues(name, namespace)
+}
+
+// FieldPaths returns the JSON pointers of the fields set in the desired
+// object whose value differs in the existing object, sorted. The metadata
+// fields other than the labels and annotations, and the status are ignored.
+func FieldPaths(desired, existing *unstructured.Unstructured) []string {
+	var paths []string
+	for key, value := range desired.Object {
+		switch key {
+		case "apiVersion", "kind", "status":
+			continue
+		case "metadata":
+			metadata, _ := value.(map[string]interface{})
+			for _, field := range []string{"labels", "annotations"} {
+				if v, ok := metadata[field]; ok {
+					paths = diff(paths, "/metadata/"+field, v, lookup(existing.Object, "metadata", field))
+				}
+			}
+			continue
+		}
+		paths = diff(paths, "/"+escape(key), value, existing.Object[key])
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// diff appends to the paths the pointers of the desired values which differ
+// from the existing ones. The lists are compared element by element when
+// their lengths match, and as a whole otherwise.
+func diff(paths []string, path string, desired, existing interface{}) []string {
+	switch d := desired.(type) {
+	case map[string]interface{}:
+		e, ok := existing.(map[string]interface{})
+		if !ok {
+			return append(paths, path)
+		}
+		for key, value := range d {
+			paths = diff(paths, path+"/"+escape(key), value, e[key])
+		}
+		return paths
+	case []interface{}:
+		e, ok := existing.([]interface{})
+		if !ok || len(e) != len(d) {
+			return append(paths, path)
+		}
+		for i := range d {
+			paths = diff(paths, fmt.Sprintf("%s/%d", path, i), d[i], e[i])
+		}
+		return paths
+	default:
+		if !equal(desired, existing) {
+			return append(paths, path)
+		}
+		return paths
+	}
+}
+
+// equal compares the scalar values, regardless of the numeric types the
+// decoders of the manifests and of the API responses produce.
+func equal(a, b interface{}) bool {
+	if fa, ok := number(a); ok {
+		fb, ok := number(b)
+		return ok && fa == fb
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func number(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case float64:
+		return n, true
+	default:
+		return 0, false
+	}
+}
+
+func lookup(obj map[string]interface{}, fields ...string) interface{} {
+	var v interface{} = obj
+	for _, field := range fields {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v = m[field]
+	}
+	return v
+}
+
+// escape escapes a reference token of a JSON pointer, as per RFC 6901.
+func escape(token string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(token)
+}
@@ -0,0 +1,168 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package healthprogress waits for the objects to become ready, like the
+// server-side apply manager, and reports the progress of the wait, i.e. the
+// objects which are ready and the status of the others.
+package healthprogress
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fluxcd/cli-utils/pkg/kstatus/polling"
+	"github.com/fluxcd/cli-utils/pkg/kstatus/polling/aggregator"
+	"github.com/fluxcd/cli-utils/pkg/kstatus/polling/collector"
+	"github.com/fluxcd/cli-utils/pkg/kstatus/polling/event"
+	"github.com/fluxcd/cli-utils/pkg/kstatus/status"
+	"github.com/fluxcd/cli-utils/pkg/object"
+	"github.com/fluxcd/pkg/ssa"
+	ssautil "github.com/fluxcd/pkg/ssa/utils"
+)
+
+// maxPending is the maximum number of objects which are not ready listed
+// in the progress.
+const maxPending = 3
+
+// Poller polls the status of the objects.
+type Poller interface {
+	Poll(ctx context.Context, identifiers object.ObjMetadataSet, options polling.PollOptions) <-chan event.Event
+}
+
+// Wait waits for the objects to be ready, and returns the same errors as
+// ssa.ResourceManager.WaitForSet. While waiting, the progress is passed to
+// report when it changes, at most once per progressInterval.
+func Wait(ctx context.Context, poller Poller, set object.ObjMetadataSet, opts ssa.WaitOptions,
+	progressInterval time.Duration, report func(progress string)) error {
+	statusCollector := collector.NewResourceStatusCollector(set)
+
+	ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	eventsChan := poller.Poll(ctx, set, polling.PollOptions{PollInterval: opts.Interval})
+
+	lastStatus := make(map[object.ObjMetadata]*event.ResourceStatus)
+	var lastReport time.Time
+	var lastProgress string
+
+	done := statusCollector.ListenWithObserver(eventsChan, collector.ObserverFunc(
+		func(statusCollector *collector.ResourceStatusCollector, e event.Event) {
+			var rss []*event.ResourceStatus
+			var countFailed int
+			for _, rs := range statusCollector.ResourceStatuses {
+				if rs == nil {
+					continue
+				}
+				// kstatus reports the deadline for all the objects, even
+				// when only one of them is not ready.
+				if !errors.Is(rs.Error, context.DeadlineExceeded) {
+					lastStatus[rs.Identifier] = rs
+				}
+				if rs.Status == status.FailedStatus {
+					countFailed++
+				}
+				rss = append(rss, rs)
+			}
+
+			desired := status.CurrentStatus
+			aggStatus := aggregator.AggregateStatus(rss, desired)
+			if aggStatus == desired || (opts.FailFast && countFailed > 0) {
+				cancel()
+				return
+			}
+
+			if report != nil && time.Since(lastReport) >= progressInterval {
+				if progress := Progress(set, lastStatus); progress != lastProgress {
+					report(progress)
+					lastProgress = progress
+					lastReport = time.Now()
+				}
+			}
+		}),
+	)
+
+	<-done
+
+	if statusCollector.Error != nil {
+		return statusCollector.Error
+	}
+
+	var errs []string
+	for id, rs := range statusCollector.ResourceStatuses {
+		switch {
+		case rs == nil || lastStatus[id] == nil:
+			errs = append(errs, fmt.Sprintf("can't determine status for %s", ssautil.FmtObjMetadata(id)))
+		case lastStatus[id].Status == status.FailedStatus,
+			errors.Is(ctx.Err(), context.DeadlineExceeded) && lastStatus[id].Status != status.CurrentStatus:
+			var builder strings.Builder
+			builder.WriteString(fmt.Sprintf("%s status: '%s'",
+				ssautil.FmtObjMetadata(rs.Identifier), lastStatus[id].Status))
+			if rs.Error != nil {
+				builder.WriteString(fmt.Sprintf(": %s", rs.Error))
+			}
+			errs = append(errs, builder.String())
+		}
+	}
+
+	if len(errs) > 0 {
+		msg := "failed early due to stalled resources"
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			msg = "timeout waiting for"
+		}
+		return fmt.Errorf("%s: [%s]", msg, strings.Join(errs, ", "))
+	}
+
+	return nil
+}
+
+// Progress returns the number of ready objects of the set, and the status
+// of the first objects which are not ready, e.g.
+// '2/5 resources ready; waiting for Deployment/apps/db: Available: 0/3'.
+func Progress(set object.ObjMetadataSet, statuses map[object.ObjMetadata]*event.ResourceStatus) string {
+	var ready int
+	var pending []string
+	for _, id := range set {
+		rs := statuses[id]
+		if rs != nil && rs.Status == status.CurrentStatus {
+			ready++
+			continue
+		}
+		msg := ssautil.FmtObjMetadata(id)
+		switch {
+		case rs == nil:
+			msg += ": status unknown"
+		case rs.Message != "":
+			msg += ": " + rs.Message
+		default:
+			msg += fmt.Sprintf(": %s", rs.Status)
+		}
+		pending = append(pending, msg)
+	}
+	sort.Strings(pending)
+
+	progress := fmt.Sprintf("%d/%d resources ready", ready, len(set))
+	if len(pending) == 0 {
+		return progress
+	}
+	if len(pending) > maxPending {
+		pending = append(pending[:maxPending], fmt.Sprintf("and %d more", len(pending)-maxPending))
+	}
+	return fmt.Sprintf("%s; waiting for %s", progress, strings.Join(pending, "; "))
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package healthprogress
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fluxcd/cli-utils/pkg/kstatus/polling"
+	"github.com/fluxcd/cli-utils/pkg/kstatus/polling/event"
+	"github.com/fluxcd/cli-utils/pkg/kstatus/status"
+	"github.com/fluxcd/cli-utils/pkg/object"
+	"github.com/fluxcd/pkg/ssa"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// fakePoller sends the status updates, then blocks until the context is
+// cancelled.
+type fakePoller []*event.ResourceStatus
+
+func (p fakePoller) Poll(ctx context.Context, _ object.ObjMetadataSet, _ polling.PollOptions) <-chan event.Event {
+	ch := make(chan event.Event)
+	go func() {
+		defer close(ch)
+		for _, rs := range p {
+			select {
+			case ch <- event.Event{Type: event.ResourceUpdateEvent, Resource: rs}:
+			case <-ctx.Done():
+				return
+			}
+		}
+		<-ctx.Done()
+	}()
+	return ch
+}
+
+func deployment(name string) object.ObjMetadata {
+	return object.ObjMetadata{
+		GroupKind: schema.GroupKind{Group: "apps", Kind: "Deployment"},
+		Namespace: "apps",
+		Name:      name,
+	}
+}
+
+func TestWait(t *testing.T) {
+	db, web := deployment("db"), deployment("web")
+	set := object.ObjMetadataSet{db, web}
+	opts := ssa.WaitOptions{Interval: time.Second, Timeout: time.Second}
+
+	t.Run("reports the progress until ready", func(t *testing.T) {
+		g := NewWithT(t)
+		var progress []string
+		err := Wait(context.Background(), fakePoller{
+			{Identifier: db, Status: status.InProgressStatus, Message: "Available: 0/3"},
+			{Identifier: web, Status: status.CurrentStatus},
+			{Identifier: db, Status: status.InProgressStatus, Message: "Available: 1/3"},
+			{Identifier: db, Status: status.CurrentStatus},
+		}, set, opts, 0, func(p string) { progress = append(progress, p) })
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(progress).To(Equal([]string{
+			"0/2 resources ready; waiting for Deployment/apps/db: Available: 0/3; Deployment/apps/web: Unknown",
+			"1/2 resources ready; waiting for Deployment/apps/db: Available: 0/3",
+			"1/2 resources ready; waiting for Deployment/apps/db: Available: 1/3",
+		}))
+	})
+
+	t.Run("times out", func(t *testing.T) {
+		g := NewWithT(t)
+		err := Wait(context.Background(), fakePoller{
+			{Identifier: db, Status: status.InProgressStatus, Message: "Available: 0/3"},
+			{Identifier: web, Status: status.CurrentStatus},
+		}, set, opts, time.Hour, nil)
+		g.Expect(err).To(MatchError("timeout waiting for: [Deployment/apps/db status: 'InProgress']"))
+	})
+}
+
+func TestProgress(t *testing.T) {
+	g := NewWithT(t)
+
+	set := object.ObjMetadataSet{deployment("a"), deployment("b"), deployment("c"), deployment("d"), deployment("e")}
+	statuses := map[object.ObjMetadata]*event.ResourceStatus{
+		deployment("a"): {Status: status.CurrentStatus},
+		deployment("b"): {Status: status.InProgressStatus},
+	}
+	g.Expect(Progress(set, statuses)).To(Equal("1/5 resources ready; waiting for " +
+		"Deployment/apps/b: InProgress; Deployment/apps/c: status unknown; Deployment/apps/d: status unknown; and 1 more"))
+}
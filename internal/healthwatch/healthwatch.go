@@ -0,0 +1,178 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package healthwatch keeps watching the health of the resources of a
+// Kustomization after its health checks passed, and reports when they
+// degrade or recover, in between reconciliations.
+package healthwatch
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/fluxcd/cli-utils/pkg/kstatus/polling/event"
+	"github.com/fluxcd/cli-utils/pkg/kstatus/status"
+	"github.com/fluxcd/cli-utils/pkg/kstatus/watcher"
+	"github.com/fluxcd/cli-utils/pkg/object"
+	ssautil "github.com/fluxcd/pkg/ssa/utils"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// NotifyFunc is called when the health of the watched resources changes.
+// The message lists the unhealthy resources when healthy is false.
+type NotifyFunc func(ctx context.Context, key types.NamespacedName, healthy bool, message string)
+
+// Manager runs a health watch per Kustomization.
+type Manager struct {
+	ctx    context.Context
+	notify NotifyFunc
+
+	mu      sync.Mutex
+	watches map[types.NamespacedName]*watch
+}
+
+type watch struct {
+	ids    object.ObjMetadataSet
+	cancel context.CancelFunc
+}
+
+// NewManager returns a Manager which runs the watches until the
+// given context is cancelled, and calls notify on health changes.
+func NewManager(ctx context.Context, notify NotifyFunc) *Manager {
+	return &Manager{
+		ctx:     ctx,
+		notify:  notify,
+		watches: make(map[types.NamespacedName]*watch),
+	}
+}
+
+// Start watches the given resources of the Kustomization with the
+// StatusWatcher. The resources are expected to be healthy at this point.
+// An existing watch for the same set of resources is kept running,
+// otherwise it is replaced.
+func (m *Manager) Start(key types.NamespacedName, sw watcher.StatusWatcher, ids object.ObjMetadataSet) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if w, ok := m.watches[key]; ok {
+		if w.ids.Hash() == ids.Hash() {
+			return
+		}
+		w.cancel()
+	}
+
+	ctx, cancel := context.WithCancel(m.ctx)
+	m.watches[key] = &watch{ids: ids, cancel: cancel}
+	go m.run(ctx, key, sw, ids)
+}
+
+// Stop stops the watch of the Kustomization, if any.
+func (m *Manager) Stop(key types.NamespacedName) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if w, ok := m.watches[key]; ok {
+		w.cancel()
+		delete(m.watches, key)
+	}
+}
+
+// IsWatching returns true if the Kustomization resources are watched.
+func (m *Manager) IsWatching(key types.NamespacedName) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, ok := m.watches[key]
+	return ok
+}
+
+func (m *Manager) run(ctx context.Context, key types.NamespacedName, sw watcher.StatusWatcher, ids object.ObjMetadataSet) {
+	statuses := make(map[object.ObjMetadata]*event.ResourceStatus, len(ids))
+	healthy := true
+	synced := false
+
+	for e := range sw.Watch(ctx, ids, watcher.Options{}) {
+		switch e.Type {
+		case event.ErrorEvent:
+			// The watcher gives up on fatal errors, the next
+			// reconciliation will start a new watch.
+			m.forget(ctx, key)
+			return
+		case event.SyncEvent:
+			synced = true
+		case event.ResourceUpdateEvent:
+			statuses[e.Resource.Identifier] = e.Resource
+		}
+
+		if !synced || ctx.Err() != nil {
+			continue
+		}
+
+		if healthy {
+			if unhealthy := listUnhealthy(statuses); len(unhealthy) > 0 {
+				healthy = false
+				m.notify(ctx, key, false, fmt.Sprintf("health check failed: %s", strings.Join(unhealthy, ", ")))
+			}
+		} else if len(statuses) == len(ids) && allCurrent(statuses) {
+			healthy = true
+			m.notify(ctx, key, true, "health check passed")
+		}
+	}
+}
+
+// forget removes the watch of the Kustomization if it's the one
+// bound to the given context.
+func (m *Manager) forget(ctx context.Context, key types.NamespacedName) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if ctx.Err() == nil {
+		if w, ok := m.watches[key]; ok {
+			w.cancel()
+			delete(m.watches, key)
+		}
+	}
+}
+
+// listUnhealthy returns the sorted list of the resources which are
+// failed, terminating or not found, with their status message.
+func listUnhealthy(statuses map[object.ObjMetadata]*event.ResourceStatus) []string {
+	var unhealthy []string
+	for id, rs := range statuses {
+		switch rs.Status {
+		case status.FailedStatus, status.TerminatingStatus, status.NotFoundStatus:
+			msg := fmt.Sprintf("%s status: '%s'", ssautil.FmtObjMetadata(id), rs.Status)
+			if rs.Message != "" {
+				msg = fmt.Sprintf("%s: %s", msg, rs.Message)
+			}
+			unhealthy = append(unhealthy, msg)
+		}
+	}
+	sort.Strings(unhealthy)
+	return unhealthy
+}
+
+func allCurrent(statuses map[object.ObjMetadata]*event.ResourceStatus) bool {
+	for _, rs := range statuses {
+		if rs.Status != status.CurrentStatus {
+			return false
+		}
+	}
+	return true
+}
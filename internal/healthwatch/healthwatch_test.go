@@ -0,0 +1,155 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package healthwatch
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fluxcd/cli-utils/pkg/kstatus/polling/event"
+	"github.com/fluxcd/cli-utils/pkg/kstatus/status"
+	"github.com/fluxcd/cli-utils/pkg/kstatus/watcher"
+	"github.com/fluxcd/cli-utils/pkg/object"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+type fakeWatcher struct {
+	events chan event.Event
+}
+
+func (w *fakeWatcher) Watch(ctx context.Context, _ object.ObjMetadataSet, _ watcher.Options) <-chan event.Event {
+	out := make(chan event.Event)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case e := <-w.events:
+				out <- e
+			}
+		}
+	}()
+	return out
+}
+
+type notification struct {
+	healthy bool
+	message string
+}
+
+type recorder struct {
+	mu            sync.Mutex
+	notifications []notification
+}
+
+func (r *recorder) notify(_ context.Context, _ types.NamespacedName, healthy bool, message string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.notifications = append(r.notifications, notification{healthy: healthy, message: message})
+}
+
+func (r *recorder) get() []notification {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]notification{}, r.notifications...)
+}
+
+func update(id object.ObjMetadata, s status.Status, msg string) event.Event {
+	return event.Event{
+		Type: event.ResourceUpdateEvent,
+		Resource: &event.ResourceStatus{
+			Identifier: id,
+			Status:     s,
+			Message:    msg,
+		},
+	}
+}
+
+func TestManager(t *testing.T) {
+	g := NewWithT(t)
+
+	deploy := object.ObjMetadata{
+		GroupKind: schema.GroupKind{Group: "apps", Kind: "Deployment"},
+		Namespace: "default",
+		Name:      "app",
+	}
+	svc := object.ObjMetadata{
+		GroupKind: schema.GroupKind{Kind: "Service"},
+		Namespace: "default",
+		Name:      "app",
+	}
+	ids := object.ObjMetadataSet{deploy, svc}
+	key := types.NamespacedName{Namespace: "default", Name: "app"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	rec := &recorder{}
+	m := NewManager(ctx, rec.notify)
+	w := &fakeWatcher{events: make(chan event.Event)}
+
+	m.Start(key, w, ids)
+	g.Expect(m.IsWatching(key)).To(BeTrue())
+
+	w.events <- update(deploy, status.CurrentStatus, "")
+	w.events <- update(svc, status.CurrentStatus, "")
+	w.events <- event.Event{Type: event.SyncEvent}
+	g.Consistently(rec.get, 200*time.Millisecond).Should(BeEmpty())
+
+	t.Run("reports degraded resources", func(t *testing.T) {
+		w.events <- update(deploy, status.FailedStatus, "Progress deadline exceeded")
+		g.Eventually(rec.get, time.Second).Should(HaveLen(1))
+		n := rec.get()[0]
+		g.Expect(n.healthy).To(BeFalse())
+		g.Expect(n.message).To(ContainSubstring("Deployment/default/app status: 'Failed': Progress deadline exceeded"))
+	})
+
+	t.Run("waits for all resources to be current", func(t *testing.T) {
+		w.events <- update(deploy, status.InProgressStatus, "")
+		g.Consistently(rec.get, 200*time.Millisecond).Should(HaveLen(1))
+	})
+
+	t.Run("reports recovered resources", func(t *testing.T) {
+		w.events <- update(deploy, status.CurrentStatus, "")
+		g.Eventually(rec.get, time.Second).Should(HaveLen(2))
+		g.Expect(rec.get()[1].healthy).To(BeTrue())
+	})
+
+	t.Run("keeps the watch for the same resources", func(t *testing.T) {
+		m.Start(key, &fakeWatcher{}, ids)
+		w.events <- update(svc, status.NotFoundStatus, "")
+		g.Eventually(rec.get, time.Second).Should(HaveLen(3))
+	})
+
+	t.Run("stops the watch", func(t *testing.T) {
+		m.Stop(key)
+		g.Expect(m.IsWatching(key)).To(BeFalse())
+	})
+
+	t.Run("forgets the watch on error", func(t *testing.T) {
+		w := &fakeWatcher{events: make(chan event.Event)}
+		m.Start(key, w, ids)
+		w.events <- event.Event{Type: event.ErrorEvent, Error: errors.New("forbidden")}
+		g.Eventually(func() bool { return m.IsWatching(key) }, time.Second).Should(BeFalse())
+	})
+}
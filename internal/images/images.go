@@ -0,0 +1,74 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package images lists the container images referenced in the manifests
+// of a Kustomization.
+package images
+
+import (
+	"sort"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// containerFields are the fields holding the containers of a pod spec.
+var containerFields = []string{"containers", "initContainers", "ephemeralContainers"}
+
+// List returns the sorted and deduplicated images of the containers found
+// in the objects. Like the kustomize images transformer, the containers are
+// looked up in any field of the objects, so that the pod specs of custom
+// resources are listed as well as the ones of the workloads.
+func List(objects []*unstructured.Unstructured) []string {
+	seen := make(map[string]struct{})
+	for _, u := range objects {
+		collect(u.Object, seen)
+	}
+
+	result := make([]string, 0, len(seen))
+	for image := range seen {
+		result = append(result, image)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func collect(value interface{}, seen map[string]struct{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, field := range containerFields {
+			containers, ok := v[field].([]interface{})
+			if !ok {
+				continue
+			}
+			for _, c := range containers {
+				container, ok := c.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				if image, ok := container["image"].(string); ok && image != "" {
+					seen[image] = struct{}{}
+				}
+			}
+		}
+		for _, nested := range v {
+			collect(nested, seen)
+		}
+	case []interface{}:
+		for _, nested := range v {
+			collect(nested, seen)
+		}
+	}
+}
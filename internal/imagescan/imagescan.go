@@ -0,0 +1,172 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package imagescan queries a vulnerability scanner for the container images
+// of a Kustomization, and checks the reports against its image policy.
+//
+// The scanner endpoint receives a POST request with a JSON body of the form
+// {"image": "<image>"} and responds with a Report, which allows adapting
+// scanners such as Trivy or Grype with a thin HTTP service.
+package imagescan
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+)
+
+const (
+	// requestTimeout is the timeout of a scan request.
+	requestTimeout = 30 * time.Second
+
+	// reportCacheTTL is the duration for which the report of an image
+	// is reused across reconciliations.
+	reportCacheTTL = 10 * time.Minute
+)
+
+// severities ranks the vulnerability severities.
+var severities = map[string]int{
+	"UNKNOWN":  0,
+	"LOW":      1,
+	"MEDIUM":   2,
+	"HIGH":     3,
+	"CRITICAL": 4,
+}
+
+// Vulnerability is a vulnerability found in an image.
+type Vulnerability struct {
+	ID       string `json:"id"`
+	Severity string `json:"severity"`
+}
+
+// Report is the result of the scan of an image.
+type Report struct {
+	Image           string          `json:"image"`
+	Vulnerabilities []Vulnerability `json:"vulnerabilities"`
+}
+
+type cachedReport struct {
+	report  Report
+	expires time.Time
+}
+
+// Scanner queries the scanner endpoint, and caches the reports per image.
+type Scanner struct {
+	address string
+	client  *http.Client
+
+	mu    sync.Mutex
+	cache map[string]cachedReport
+}
+
+// NewScanner returns a Scanner querying the given endpoint.
+func NewScanner(address string) *Scanner {
+	return &Scanner{
+		address: address,
+		client:  &http.Client{Timeout: requestTimeout},
+		cache:   make(map[string]cachedReport),
+	}
+}
+
+// Scan returns the reports of the given images, in the same order.
+func (s *Scanner) Scan(ctx context.Context, images []string) ([]Report, error) {
+	reports := make([]Report, 0, len(images))
+	for _, image := range images {
+		report, err := s.scan(ctx, image)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan image '%s': %w", image, err)
+		}
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+func (s *Scanner) scan(ctx context.Context, image string) (Report, error) {
+	s.mu.Lock()
+	cached, ok := s.cache[image]
+	s.mu.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.report, nil
+	}
+
+	body, err := json.Marshal(map[string]string{"image": image})
+	if err != nil {
+		return Report{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.address, bytes.NewReader(body))
+	if err != nil {
+		return Report{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return Report{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return Report{}, fmt.Errorf("scanner responded with status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	var report Report
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		return Report{}, fmt.Errorf("failed to decode the scan report: %w", err)
+	}
+	report.Image = image
+
+	s.mu.Lock()
+	s.cache[image] = cachedReport{report: report, expires: time.Now().Add(reportCacheTTL)}
+	s.mu.Unlock()
+	return report, nil
+}
+
+// Violations returns the images with vulnerabilities of the policy
+// severity or higher, with the identifiers and severities of these
+// vulnerabilities, e.g. 'nginx:1.25: CVE-2023-44487 (HIGH)'.
+func Violations(reports []Report, policy kustomizev1.ImagePolicy) []string {
+	threshold := severities["CRITICAL"]
+	if rank, ok := severities[strings.ToUpper(policy.Severity)]; ok {
+		threshold = rank
+	}
+
+	var violations []string
+	for _, report := range reports {
+		var found []string
+		for _, v := range report.Vulnerabilities {
+			severity := strings.ToUpper(v.Severity)
+			if severities[severity] < threshold || slices.Contains(policy.IgnoreVulnerabilities, v.ID) {
+				continue
+			}
+			found = append(found, fmt.Sprintf("%s (%s)", v.ID, severity))
+		}
+		if len(found) > 0 {
+			sort.Strings(found)
+			violations = append(violations, fmt.Sprintf("%s: %s", report.Image, strings.Join(slices.Compact(found), ", ")))
+		}
+	}
+	return violations
+}
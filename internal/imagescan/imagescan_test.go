@@ -0,0 +1,112 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imagescan
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+)
+
+func TestScanner_Scan(t *testing.T) {
+	g := NewWithT(t)
+
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		var body map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if body["image"] == "broken:latest" {
+			http.Error(w, "manifest unknown", http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(Report{Vulnerabilities: []Vulnerability{
+			{ID: "CVE-2023-0001", Severity: "high"},
+		}})
+	}))
+	defer server.Close()
+
+	s := NewScanner(server.URL)
+
+	reports, err := s.Scan(context.Background(), []string{"nginx:1.25", "redis:7"})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(reports).To(HaveLen(2))
+	g.Expect(reports[0].Image).To(Equal("nginx:1.25"))
+	g.Expect(reports[1].Vulnerabilities).To(HaveLen(1))
+	g.Expect(requests.Load()).To(BeEquivalentTo(2))
+
+	// The reports are cached per image.
+	_, err = s.Scan(context.Background(), []string{"nginx:1.25"})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(requests.Load()).To(BeEquivalentTo(2))
+
+	_, err = s.Scan(context.Background(), []string{"broken:latest"})
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(ContainSubstring("status 404: manifest unknown"))
+}
+
+func TestViolations(t *testing.T) {
+	reports := []Report{
+		{Image: "nginx:1.25", Vulnerabilities: []Vulnerability{
+			{ID: "CVE-2023-0003", Severity: "CRITICAL"},
+			{ID: "CVE-2023-0002", Severity: "medium"},
+			{ID: "CVE-2023-0001", Severity: "HIGH"},
+		}},
+		{Image: "redis:7", Vulnerabilities: []Vulnerability{
+			{ID: "CVE-2023-0004", Severity: "LOW"},
+		}},
+		{Image: "busybox:1.36"},
+	}
+
+	tests := []struct {
+		name   string
+		policy kustomizev1.ImagePolicy
+		want   []string
+	}{
+		{
+			name:   "defaults to critical",
+			policy: kustomizev1.ImagePolicy{},
+			want:   []string{"nginx:1.25: CVE-2023-0003 (CRITICAL)"},
+		},
+		{
+			name:   "includes the higher severities",
+			policy: kustomizev1.ImagePolicy{Severity: "MEDIUM"},
+			want:   []string{"nginx:1.25: CVE-2023-0001 (HIGH), CVE-2023-0002 (MEDIUM), CVE-2023-0003 (CRITICAL)"},
+		},
+		{
+			name:   "skips the ignored vulnerabilities",
+			policy: kustomizev1.ImagePolicy{Severity: "LOW", IgnoreVulnerabilities: []string{"CVE-2023-0004", "CVE-2023-0003"}},
+			want:   []string{"nginx:1.25: CVE-2023-0001 (HIGH), CVE-2023-0002 (MEDIUM)"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(Violations(reports, tt.policy)).To(Equal(tt.want))
+		})
+	}
+}
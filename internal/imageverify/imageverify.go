@@ -0,0 +1,217 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package imageverify verifies the cosign signatures of the container
+// images of a Kustomization, with public keys or keyless identities.
+package imageverify
+
+import (
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/sigstore/cosign/v2/cmd/cosign/cli/fulcio"
+	coptions "github.com/sigstore/cosign/v2/cmd/cosign/cli/options"
+	"github.com/sigstore/cosign/v2/cmd/cosign/cli/rekor"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+	"github.com/sigstore/sigstore/pkg/signature"
+)
+
+// verifiedCacheTTL is the duration for which a successful verification
+// is reused across reconciliations.
+const verifiedCacheTTL = 10 * time.Minute
+
+// Policy defines how the signatures of the images are verified.
+type Policy struct {
+	// PublicKeys are the PEM encoded public keys of the trusted authors.
+	// The keyless verification is used when empty.
+	PublicKeys [][]byte
+	// Identities are the identities of the keyless signatures.
+	Identities []cosign.Identity
+}
+
+// fingerprint identifies the policy in the cache of verifications.
+func (p Policy) fingerprint() string {
+	h := sha256.New()
+	for _, key := range p.PublicKeys {
+		fmt.Fprintf(h, "key:%x\n", sha256.Sum256(key))
+	}
+	for _, id := range p.Identities {
+		fmt.Fprintf(h, "identity:%q:%q\n", id.IssuerRegExp, id.SubjectRegExp)
+	}
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+// VerifyFunc verifies the signatures of an image with the given options.
+type VerifyFunc func(ctx context.Context, ref name.Reference, opts *cosign.CheckOpts) error
+
+// Verifier verifies the signatures of images, and caches the successful
+// verifications per image and policy.
+type Verifier struct {
+	verify VerifyFunc
+
+	mu       sync.Mutex
+	verified map[string]time.Time
+	keyless  *cosign.CheckOpts
+}
+
+// NewVerifier returns a Verifier using cosign.
+func NewVerifier() *Verifier {
+	return NewVerifierWithFunc(func(ctx context.Context, ref name.Reference, opts *cosign.CheckOpts) error {
+		_, _, err := cosign.VerifyImageSignatures(ctx, ref, opts)
+		return err
+	})
+}
+
+// NewVerifierWithFunc returns a Verifier using the given function
+// to verify the signatures.
+func NewVerifierWithFunc(verify VerifyFunc) *Verifier {
+	return &Verifier{
+		verify:   verify,
+		verified: make(map[string]time.Time),
+	}
+}
+
+// Verify returns the images whose signatures couldn't be verified with
+// the policy, with the reason, e.g. 'nginx:1.25: no signatures found'.
+// An error is returned if the policy is invalid.
+func (v *Verifier) Verify(ctx context.Context, images []string, policy Policy) ([]string, error) {
+	var opts []*cosign.CheckOpts
+	fingerprint := policy.fingerprint()
+
+	var failures []string
+	for _, image := range images {
+		key := fingerprint + "/" + image
+		if v.isVerified(key) {
+			continue
+		}
+
+		ref, err := name.ParseReference(image)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %s", image, err))
+			continue
+		}
+
+		if opts == nil {
+			if opts, err = v.checkOpts(ctx, policy); err != nil {
+				return nil, err
+			}
+		}
+
+		// The image is verified if the signatures match one of the keys.
+		var verifyErr error
+		for _, o := range opts {
+			if verifyErr = v.verify(ctx, ref, o); verifyErr == nil {
+				break
+			}
+		}
+		if verifyErr != nil {
+			failures = append(failures, fmt.Sprintf("%s: %s", image, verifyErr))
+			continue
+		}
+		v.setVerified(key)
+	}
+	sort.Strings(failures)
+	return failures, nil
+}
+
+func (v *Verifier) isVerified(key string) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	expires, ok := v.verified[key]
+	return ok && time.Now().Before(expires)
+}
+
+func (v *Verifier) setVerified(key string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.verified[key] = time.Now().Add(verifiedCacheTTL)
+}
+
+// checkOpts returns the cosign options of each public key of the policy,
+// or the options of the keyless verification.
+func (v *Verifier) checkOpts(ctx context.Context, policy Policy) ([]*cosign.CheckOpts, error) {
+	registryOpts, err := (&coptions.RegistryOptions{}).ClientOpts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(policy.PublicKeys) == 0 {
+		keyless, err := v.keylessOpts(ctx)
+		if err != nil {
+			return nil, err
+		}
+		o := *keyless
+		o.RegistryClientOpts = registryOpts
+		o.Identities = policy.Identities
+		return []*cosign.CheckOpts{&o}, nil
+	}
+
+	opts := make([]*cosign.CheckOpts, 0, len(policy.PublicKeys))
+	for _, key := range policy.PublicKeys {
+		pubKey, err := cryptoutils.UnmarshalPEMToPublicKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode the public key: %w", err)
+		}
+		verifier, err := signature.LoadVerifier(pubKey, crypto.SHA256)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load the public key: %w", err)
+		}
+		opts = append(opts, &cosign.CheckOpts{
+			RegistryClientOpts: registryOpts,
+			SigVerifier:        verifier,
+			Offline:            true,
+			IgnoreTlog:         true,
+		})
+	}
+	return opts, nil
+}
+
+// keylessOpts returns the Fulcio roots and the Rekor and CT log public
+// keys of the public Sigstore instance, fetched once.
+func (v *Verifier) keylessOpts(ctx context.Context) (*cosign.CheckOpts, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.keyless != nil {
+		return v.keyless, nil
+	}
+
+	o := &cosign.CheckOpts{}
+	var err error
+	if o.RekorClient, err = rekor.NewClient(coptions.DefaultRekorURL); err != nil {
+		return nil, fmt.Errorf("unable to create the Rekor client: %w", err)
+	}
+	if o.RootCerts, err = fulcio.GetRoots(); err != nil {
+		return nil, fmt.Errorf("unable to get the Fulcio root certificates: %w", err)
+	}
+	if o.IntermediateCerts, err = fulcio.GetIntermediates(); err != nil {
+		return nil, fmt.Errorf("unable to get the Fulcio intermediate certificates: %w", err)
+	}
+	if o.RekorPubKeys, err = cosign.GetRekorPubs(ctx); err != nil {
+		return nil, fmt.Errorf("unable to get the Rekor public keys: %w", err)
+	}
+	if o.CTLogPubKeys, err = cosign.GetCTLogPubs(ctx); err != nil {
+		return nil, fmt.Errorf("unable to get the CT log public keys: %w", err)
+	}
+	v.keyless = o
+	return o, nil
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imageverify
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	. "github.com/onsi/gomega"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+)
+
+func newPublicKey(t *testing.T) []byte {
+	t.Helper()
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pem, err := cryptoutils.MarshalPublicKeyToPEM(&priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem
+}
+
+func TestVerifier_Verify(t *testing.T) {
+	g := NewWithT(t)
+
+	var calls []string
+	v := NewVerifierWithFunc(func(_ context.Context, ref name.Reference, opts *cosign.CheckOpts) error {
+		calls = append(calls, ref.String())
+		if !opts.Offline || opts.SigVerifier == nil {
+			return errors.New("expected the public key options")
+		}
+		if ref.Context().RepositoryStr() == "library/unsigned" {
+			return errors.New("no signatures found")
+		}
+		return nil
+	})
+	policy := Policy{PublicKeys: [][]byte{newPublicKey(t)}}
+
+	failures, err := v.Verify(context.Background(), []string{"nginx:1.25", "unsigned:v1", "INVALID:ref"}, policy)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(failures).To(HaveLen(2))
+	g.Expect(failures[0]).To(HavePrefix("INVALID:ref: "))
+	g.Expect(failures[1]).To(Equal("unsigned:v1: no signatures found"))
+	g.Expect(calls).To(HaveLen(2))
+
+	t.Run("caches the successful verifications per policy", func(t *testing.T) {
+		g := NewWithT(t)
+		calls = nil
+
+		_, err := v.Verify(context.Background(), []string{"nginx:1.25"}, policy)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(calls).To(BeEmpty())
+
+		_, err = v.Verify(context.Background(), []string{"nginx:1.25"}, Policy{PublicKeys: [][]byte{newPublicKey(t)}})
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(calls).To(HaveLen(1))
+	})
+
+	t.Run("tries each public key", func(t *testing.T) {
+		g := NewWithT(t)
+		var keys int
+		v := NewVerifierWithFunc(func(_ context.Context, _ name.Reference, _ *cosign.CheckOpts) error {
+			keys++
+			if keys < 2 {
+				return errors.New("invalid signature")
+			}
+			return nil
+		})
+
+		failures, err := v.Verify(context.Background(), []string{"nginx:1.25"},
+			Policy{PublicKeys: [][]byte{newPublicKey(t), newPublicKey(t)}})
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(failures).To(BeEmpty())
+		g.Expect(keys).To(Equal(2))
+	})
+
+	t.Run("fails on invalid public keys", func(t *testing.T) {
+		g := NewWithT(t)
+		_, err := v.Verify(context.Background(), []string{"redis:7"}, Policy{PublicKeys: [][]byte{[]byte("invalid")}})
+		g.Expect(err).To(HaveOccurred())
+	})
+}
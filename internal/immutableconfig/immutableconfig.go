@@ -0,0 +1,208 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package immutableconfig marks the ConfigMaps and Secrets as immutable, and
+// rotates them when their data changes, either by renaming them and their
+// references in the pod templates, or by recreating them.
+package immutableconfig
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+	"github.com/fluxcd/kustomize-controller/internal/configchecksum"
+)
+
+// hashLength is the length of the hash suffixed to the renamed objects.
+const hashLength = 10
+
+// forceAnnotation makes the server-side apply recreate the objects
+// whose immutable fields changed.
+var forceAnnotation = fmt.Sprintf("%s/force", kustomizev1.GroupVersion.Group)
+
+// ref identifies a ConfigMap or Secret referred to by a pod template.
+type ref struct {
+	kind      string
+	namespace string
+	name      string
+}
+
+// refPaths are the paths of the references of the pod spec fields,
+// relative to the items of their list.
+var refPaths = []struct {
+	kind string
+	list string
+	name []string
+}{
+	{"ConfigMap", "volumes", []string{"configMap", "name"}},
+	{"Secret", "volumes", []string{"secret", "secretName"}},
+	{"Secret", "imagePullSecrets", []string{"name"}},
+}
+
+// containerRefPaths are the paths of the references of the container fields,
+// relative to the items of their list.
+var containerRefPaths = []struct {
+	kind string
+	list string
+	name []string
+}{
+	{"ConfigMap", "envFrom", []string{"configMapRef", "name"}},
+	{"Secret", "envFrom", []string{"secretRef", "name"}},
+	{"ConfigMap", "env", []string{"valueFrom", "configMapKeyRef", "name"}},
+	{"Secret", "env", []string{"valueFrom", "secretKeyRef", "name"}},
+}
+
+// Apply marks the ConfigMaps and Secrets of the objects as immutable, except
+// the ones which set the immutable field, and the service account tokens.
+// With the Rename rotation, the objects referred to by the pod templates of
+// the workloads are suffixed with the hash of their data, and the references
+// are updated. The other objects are annotated to be recreated by the apply
+// when their data changes.
+func Apply(objects []*unstructured.Unstructured, rotation string) error {
+	targets := make(map[ref]*unstructured.Unstructured)
+	hashedNames := make(map[ref]string)
+	for _, u := range objects {
+		if !isConfig(u) {
+			continue
+		}
+		if _, ok := u.Object["immutable"]; ok {
+			continue
+		}
+		if u.GetKind() == "Secret" && u.Object["type"] == "kubernetes.io/service-account-token" {
+			continue
+		}
+		r := ref{u.GetKind(), u.GetNamespace(), u.GetName()}
+		if rotation == kustomizev1.RenameRotation {
+			hash, err := dataHash(u)
+			if err != nil {
+				return fmt.Errorf("%s/%s/%s: %w", u.GetKind(), u.GetNamespace(), u.GetName(), err)
+			}
+			hashedNames[r] = fmt.Sprintf("%s-%s", u.GetName(), hash)
+		}
+		u.Object["immutable"] = true
+		targets[r] = u
+	}
+	if len(targets) == 0 {
+		return nil
+	}
+
+	// Rename the references of the pod templates to the hashed names.
+	renamed := make(map[ref]bool)
+	for _, u := range objects {
+		path, ok := configchecksum.TemplatePath(u)
+		if !ok || len(hashedNames) == 0 {
+			continue
+		}
+		specPath := append(append([]string{}, path...), "spec")
+		if err := visitRefs(u, specPath, func(r ref) (string, bool) {
+			name, ok := hashedNames[r]
+			renamed[r] = renamed[r] || ok
+			return name, ok
+		}); err != nil {
+			return fmt.Errorf("%s/%s/%s: %w", u.GetKind(), u.GetNamespace(), u.GetName(), err)
+		}
+	}
+
+	// Rename the objects referred to, and recreate the others on changes.
+	for r, u := range targets {
+		if renamed[r] {
+			u.SetName(hashedNames[r])
+			continue
+		}
+		annotations := u.GetAnnotations()
+		if annotations == nil {
+			annotations = make(map[string]string)
+		}
+		annotations[forceAnnotation] = kustomizev1.EnabledValue
+		u.SetAnnotations(annotations)
+	}
+	return nil
+}
+
+// visitRefs calls rename for the ConfigMaps and Secrets referred to by the
+// pod spec at the given path of the workload, and updates their name when
+// rename returns true.
+func visitRefs(u *unstructured.Unstructured, specPath []string, rename func(ref) (string, bool)) error {
+	spec, found, err := unstructured.NestedMap(u.Object, specPath...)
+	if err != nil || !found {
+		return err
+	}
+
+	update := func(kind string, item map[string]interface{}, namePath []string) {
+		name, ok, _ := unstructured.NestedString(item, namePath...)
+		if !ok || name == "" {
+			return
+		}
+		if newName, ok := rename(ref{kind, u.GetNamespace(), name}); ok {
+			_ = unstructured.SetNestedField(item, newName, namePath...)
+		}
+	}
+	updateList := func(obj map[string]interface{}, field string, fn func(item map[string]interface{})) {
+		items, _ := obj[field].([]interface{})
+		for _, item := range items {
+			if m, ok := item.(map[string]interface{}); ok {
+				fn(m)
+			}
+		}
+	}
+
+	for _, p := range refPaths {
+		updateList(spec, p.list, func(item map[string]interface{}) {
+			update(p.kind, item, p.name)
+		})
+	}
+	updateList(spec, "volumes", func(volume map[string]interface{}) {
+		projected, _ := volume["projected"].(map[string]interface{})
+		updateList(projected, "sources", func(source map[string]interface{}) {
+			update("ConfigMap", source, []string{"configMap", "name"})
+			update("Secret", source, []string{"secret", "name"})
+		})
+	})
+	for _, containers := range []string{"initContainers", "containers"} {
+		updateList(spec, containers, func(container map[string]interface{}) {
+			for _, p := range containerRefPaths {
+				updateList(container, p.list, func(item map[string]interface{}) {
+					update(p.kind, item, p.name)
+				})
+			}
+		})
+	}
+
+	return unstructured.SetNestedMap(u.Object, spec, specPath...)
+}
+
+// dataHash returns the hash of the type and data of a ConfigMap or Secret.
+func dataHash(u *unstructured.Unstructured) (string, error) {
+	data := make(map[string]interface{})
+	for _, field := range []string{"type", "data", "binaryData", "stringData"} {
+		if v, ok := u.Object[field]; ok {
+			data[field] = v
+		}
+	}
+	b, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(b))[:hashLength], nil
+}
+
+func isConfig(u *unstructured.Unstructured) bool {
+	return u.GetAPIVersion() == "v1" && (u.GetKind() == "ConfigMap" || u.GetKind() == "Secret")
+}
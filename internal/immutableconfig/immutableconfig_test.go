@@ -0,0 +1,171 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package immutableconfig
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+)
+
+func readObjects(t *testing.T, docs string) []*unstructured.Unstructured {
+	t.Helper()
+	var objects []*unstructured.Unstructured
+	for _, doc := range strings.Split(docs, "---\n") {
+		obj := &unstructured.Unstructured{}
+		if err := yaml.Unmarshal([]byte(doc), &obj.Object); err != nil {
+			t.Fatal(err)
+		}
+		objects = append(objects, obj)
+	}
+	return objects
+}
+
+const testObjects = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: env
+  namespace: apps
+data:
+  key: value
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: creds
+  namespace: apps
+stringData:
+  token: secret
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: unreferenced
+  namespace: apps
+data:
+  key: value
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: mutable
+  namespace: apps
+immutable: false
+data:
+  key: value
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  namespace: apps
+spec:
+  template:
+    spec:
+      volumes:
+      - name: creds
+        projected:
+          sources:
+          - secret:
+              name: creds
+      containers:
+      - name: app
+        envFrom:
+        - configMapRef:
+            name: env
+        env:
+        - name: TOKEN
+          valueFrom:
+            secretKeyRef:
+              name: creds
+              key: token
+        - name: MUTABLE
+          valueFrom:
+            configMapKeyRef:
+              name: mutable
+              key: key
+`
+
+func TestApply(t *testing.T) {
+	t.Run("renames the referred objects", func(t *testing.T) {
+		g := NewWithT(t)
+		objects := readObjects(t, testObjects)
+		g.Expect(Apply(objects, kustomizev1.RenameRotation)).To(Succeed())
+
+		env, creds, unreferenced, mutable, app := objects[0], objects[1], objects[2], objects[3], objects[4]
+		g.Expect(env.GetName()).To(MatchRegexp(`^env-[0-9a-f]{10}$`))
+		g.Expect(env.Object["immutable"]).To(BeTrue())
+		g.Expect(creds.GetName()).To(MatchRegexp(`^creds-[0-9a-f]{10}$`))
+		g.Expect(creds.Object["immutable"]).To(BeTrue())
+
+		g.Expect(unreferenced.GetName()).To(Equal("unreferenced"))
+		g.Expect(unreferenced.Object["immutable"]).To(BeTrue())
+		g.Expect(unreferenced.GetAnnotations()).To(HaveKeyWithValue(forceAnnotation, kustomizev1.EnabledValue))
+
+		g.Expect(mutable.GetName()).To(Equal("mutable"))
+		g.Expect(mutable.Object["immutable"]).To(BeFalse())
+		g.Expect(mutable.GetAnnotations()).To(BeEmpty())
+
+		containers, _, _ := unstructured.NestedSlice(app.Object, "spec", "template", "spec", "containers")
+		container := containers[0].(map[string]interface{})
+		envFrom, _, _ := unstructured.NestedString(container["envFrom"].([]interface{})[0].(map[string]interface{}),
+			"configMapRef", "name")
+		g.Expect(envFrom).To(Equal(env.GetName()))
+		envs := container["env"].([]interface{})
+		token, _, _ := unstructured.NestedString(envs[0].(map[string]interface{}), "valueFrom", "secretKeyRef", "name")
+		g.Expect(token).To(Equal(creds.GetName()))
+		mutableRef, _, _ := unstructured.NestedString(envs[1].(map[string]interface{}), "valueFrom", "configMapKeyRef", "name")
+		g.Expect(mutableRef).To(Equal("mutable"))
+
+		volumes, _, _ := unstructured.NestedSlice(app.Object, "spec", "template", "spec", "volumes")
+		sources, _, _ := unstructured.NestedSlice(volumes[0].(map[string]interface{}), "projected", "sources")
+		projected, _, _ := unstructured.NestedString(sources[0].(map[string]interface{}), "secret", "name")
+		g.Expect(projected).To(Equal(creds.GetName()))
+	})
+
+	t.Run("renames on data changes only", func(t *testing.T) {
+		g := NewWithT(t)
+		first := readObjects(t, testObjects)
+		g.Expect(Apply(first, kustomizev1.RenameRotation)).To(Succeed())
+		second := readObjects(t, strings.Replace(testObjects, "token: secret", "token: rotated", 1))
+		g.Expect(Apply(second, kustomizev1.RenameRotation)).To(Succeed())
+
+		g.Expect(second[0].GetName()).To(Equal(first[0].GetName()))
+		g.Expect(second[1].GetName()).NotTo(Equal(first[1].GetName()))
+	})
+
+	t.Run("recreates the objects", func(t *testing.T) {
+		g := NewWithT(t)
+		objects := readObjects(t, testObjects)
+		g.Expect(Apply(objects, kustomizev1.RecreateRotation)).To(Succeed())
+
+		for _, u := range objects[:3] {
+			g.Expect(u.Object["immutable"]).To(BeTrue())
+			g.Expect(u.GetAnnotations()).To(HaveKeyWithValue(forceAnnotation, kustomizev1.EnabledValue))
+		}
+		g.Expect(objects[0].GetName()).To(Equal("env"))
+		containers, _, _ := unstructured.NestedSlice(objects[4].Object, "spec", "template", "spec", "containers")
+		envFrom, _, _ := unstructured.NestedString(containers[0].(map[string]interface{})["envFrom"].([]interface{})[0].(map[string]interface{}),
+			"configMapRef", "name")
+		g.Expect(envFrom).To(Equal("env"))
+	})
+}
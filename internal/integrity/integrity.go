@@ -0,0 +1,109 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package integrity verifies the files extracted from a source artifact
+// against the checksums published in the artifact metadata.
+package integrity
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	securejoin "github.com/cyphar/filepath-securejoin"
+)
+
+// maxReported is the maximum number of files listed in the error message.
+const maxReported = 5
+
+// Verify checks the SHA-256 checksums of the files in the root directory.
+// The checksums are expected one per line in the sha256sum format, i.e.
+// '<hex digest>  <path>', with the paths relative to the root directory.
+// It returns an error listing the files which are missing or don't match.
+func Verify(root, checksums string) error {
+	expected, err := parse(checksums)
+	if err != nil {
+		return err
+	}
+
+	var failed []string
+	for path, digest := range expected {
+		actual, err := fileDigest(root, path)
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %s", path, err))
+			continue
+		}
+		if actual != digest {
+			failed = append(failed, fmt.Sprintf("%s: checksum mismatch", path))
+		}
+	}
+
+	if len(failed) == 0 {
+		return nil
+	}
+
+	sort.Strings(failed)
+	msg := strings.Join(failed[:min(len(failed), maxReported)], ", ")
+	if len(failed) > maxReported {
+		msg = fmt.Sprintf("%s and %d more", msg, len(failed)-maxReported)
+	}
+	return fmt.Errorf("integrity check failed for %d out of %d files: %s", len(failed), len(expected), msg)
+}
+
+// parse returns the lowercase hex digests indexed by path.
+func parse(checksums string) (map[string]string, error) {
+	expected := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(checksums))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		digest, path, ok := strings.Cut(line, " ")
+		path = strings.TrimPrefix(strings.TrimSpace(path), "*")
+		if _, err := hex.DecodeString(digest); !ok || err != nil || len(digest) != sha256.Size*2 || path == "" {
+			return nil, fmt.Errorf("invalid checksum entry '%s'", line)
+		}
+		expected[path] = strings.ToLower(digest)
+	}
+	return expected, scanner.Err()
+}
+
+func fileDigest(root, path string) (string, error) {
+	filePath, err := securejoin.SecureJoin(root, path)
+	if err != nil {
+		return "", err
+	}
+	f, err := os.Open(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("file not found")
+		}
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package integrity
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestVerify(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"deploy/app.yaml":           "kind: Deployment\n",
+		"deploy/kustomization.yaml": "resources:\n- app.yaml\n",
+	}
+	var checksums string
+	for path, content := range files {
+		g := NewWithT(t)
+		g.Expect(os.MkdirAll(filepath.Join(root, filepath.Dir(path)), 0o755)).To(Succeed())
+		g.Expect(os.WriteFile(filepath.Join(root, path), []byte(content), 0o644)).To(Succeed())
+		checksums += fmt.Sprintf("%x  %s\n", sha256.Sum256([]byte(content)), path)
+	}
+
+	t.Run("matching files", func(t *testing.T) {
+		g := NewWithT(t)
+		g.Expect(Verify(root, checksums)).To(Succeed())
+	})
+
+	t.Run("truncated file", func(t *testing.T) {
+		g := NewWithT(t)
+		g.Expect(os.WriteFile(filepath.Join(root, "deploy/app.yaml"), []byte("kind: Deploy"), 0o644)).To(Succeed())
+		defer os.WriteFile(filepath.Join(root, "deploy/app.yaml"), []byte(files["deploy/app.yaml"]), 0o644)
+
+		err := Verify(root, checksums)
+		g.Expect(err).To(MatchError("integrity check failed for 1 out of 2 files: deploy/app.yaml: checksum mismatch"))
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		g := NewWithT(t)
+		err := Verify(root, checksums+fmt.Sprintf("%x  deploy/missing.yaml\n", sha256.Sum256(nil)))
+		g.Expect(err).To(MatchError(ContainSubstring("deploy/missing.yaml: file not found")))
+	})
+
+	t.Run("invalid entry", func(t *testing.T) {
+		g := NewWithT(t)
+		err := Verify(root, "not-a-digest  deploy/app.yaml")
+		g.Expect(err).To(MatchError(ContainSubstring("invalid checksum entry")))
+	})
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/fluxcd/cli-utils/pkg/common"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+)
+
+const (
+	// ApplySetPartOfLabel marks the objects as members of an ApplySet.
+	ApplySetPartOfLabel = "applyset.kubernetes.io/part-of"
+
+	// owningInventoryAnnotation marks the objects as members of a cli-utils
+	// inventory, as defined by the cli-utils/pkg/inventory package.
+	owningInventoryAnnotation = "config.k8s.io/owning-inventory"
+
+	applySetIDLabel                 = "applyset.kubernetes.io/id"
+	applySetToolingAnnotation       = "applyset.kubernetes.io/tooling"
+	applySetGroupKindsAnnotation    = "applyset.kubernetes.io/contains-group-kinds"
+	applySetNamespacesAnnotation    = "applyset.kubernetes.io/additional-namespaces"
+	applySetTooling                 = "kustomize-controller/v1"
+	applySetIDFormat                = "applyset-%s-v1"
+	applySetParentKindGroupIdentity = "%s.%s.ConfigMap."
+)
+
+// ApplySetID returns the identifier of the ApplySet whose parent is the
+// ConfigMap with the given name and namespace, as specified by KEP-3659.
+func ApplySetID(name, namespace string) string {
+	hash := sha256.Sum256([]byte(fmt.Sprintf(applySetParentKindGroupIdentity, name, namespace)))
+	return fmt.Sprintf(applySetIDFormat, base64.RawURLEncoding.EncodeToString(hash[:]))
+}
+
+// SetExportOwner labels and annotates the objects as members of the
+// exported inventory with the given identifier, for both kubectl
+// ApplySets and cli-utils.
+func SetExportOwner(objects []*unstructured.Unstructured, id string) {
+	for _, u := range objects {
+		labels := u.GetLabels()
+		if labels == nil {
+			labels = make(map[string]string)
+		}
+		labels[ApplySetPartOfLabel] = id
+		u.SetLabels(labels)
+
+		annotations := u.GetAnnotations()
+		if annotations == nil {
+			annotations = make(map[string]string)
+		}
+		annotations[owningInventoryAnnotation] = id
+		u.SetAnnotations(annotations)
+	}
+}
+
+// ExportConfigMap returns a ConfigMap recording the inventory in the
+// format of the cli-utils inventory objects, which is also the parent of
+// a kubectl ApplySet.
+func ExportConfigMap(inv *kustomizev1.ResourceInventory, name, namespace string) (*corev1.ConfigMap, error) {
+	id := ApplySetID(name, namespace)
+	data := make(map[string]string, len(inv.Entries))
+	groupKinds := make(map[string]struct{})
+	namespaces := make(map[string]struct{})
+
+	objects, err := ListMetadata(inv)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range objects {
+		data[m.String()] = ""
+		groupKinds[m.GroupKind.String()] = struct{}{}
+		if m.Namespace != "" && m.Namespace != namespace {
+			namespaces[m.Namespace] = struct{}{}
+		}
+	}
+
+	return &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "ConfigMap",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels: map[string]string{
+				common.InventoryLabel: id,
+				applySetIDLabel:       id,
+			},
+			Annotations: map[string]string{
+				applySetToolingAnnotation:    applySetTooling,
+				applySetGroupKindsAnnotation: joinKeys(groupKinds),
+				applySetNamespacesAnnotation: joinKeys(namespaces),
+			},
+		},
+		Data: data,
+	}, nil
+}
+
+func joinKeys(m map[string]struct{}) string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ",")
+}
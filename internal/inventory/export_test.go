@@ -0,0 +1,72 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+)
+
+func Test_ExportConfigMap(t *testing.T) {
+	g := NewWithT(t)
+
+	inv := &kustomizev1.ResourceInventory{Entries: []kustomizev1.ResourceRef{
+		{ID: "_apps__Namespace", Version: "v1"},
+		{ID: "apps_web_apps_Deployment", Version: "v1"},
+		{ID: "apps_web__Service", Version: "v1"},
+		{ID: "monitoring_web_monitoring.coreos.com_ServiceMonitor", Version: "v1"},
+	}}
+
+	cm, err := ExportConfigMap(inv, "apps-inventory", "flux-system")
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// The identifier is computed as specified by KEP-3659.
+	id := ApplySetID("apps-inventory", "flux-system")
+	g.Expect(id).To(MatchRegexp(`^applyset-[A-Za-z0-9_-]{43}-v1$`))
+	g.Expect(ApplySetID("apps-inventory", "default")).ToNot(Equal(id))
+
+	g.Expect(cm.GetLabels()).To(Equal(map[string]string{
+		"cli-utils.sigs.k8s.io/inventory-id": id,
+		"applyset.kubernetes.io/id":          id,
+	}))
+	g.Expect(cm.GetAnnotations()).To(Equal(map[string]string{
+		"applyset.kubernetes.io/tooling":               "kustomize-controller/v1",
+		"applyset.kubernetes.io/contains-group-kinds":  "Deployment.apps,Namespace,Service,ServiceMonitor.monitoring.coreos.com",
+		"applyset.kubernetes.io/additional-namespaces": "apps,monitoring",
+	}))
+	g.Expect(cm.Data).To(HaveLen(4))
+	g.Expect(cm.Data).To(HaveKeyWithValue("apps_web_apps_Deployment", ""))
+
+	t.Run("fails on invalid entries", func(t *testing.T) {
+		g := NewWithT(t)
+		_, err := ExportConfigMap(&kustomizev1.ResourceInventory{Entries: []kustomizev1.ResourceRef{{ID: "invalid"}}}, "inv", "default")
+		g.Expect(err).To(HaveOccurred())
+	})
+
+	t.Run("sets the owner of the members", func(t *testing.T) {
+		g := NewWithT(t)
+		u := &unstructured.Unstructured{}
+		u.SetLabels(map[string]string{"app": "web"})
+		SetExportOwner([]*unstructured.Unstructured{u}, id)
+		g.Expect(u.GetLabels()).To(Equal(map[string]string{"app": "web", ApplySetPartOfLabel: id}))
+		g.Expect(u.GetAnnotations()).To(HaveKeyWithValue("config.k8s.io/owning-inventory", id))
+	})
+}
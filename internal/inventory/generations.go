@@ -0,0 +1,116 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"regexp"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/fluxcd/cli-utils/pkg/object"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+)
+
+// hashSuffix matches the names of the objects created by the kustomize
+// generators with a name suffix hash.
+var hashSuffix = regexp.MustCompile(`^(.+)-[a-z0-9]{10}$`)
+
+// generationKey returns the key shared by all the generations of a
+// ConfigMap or Secret created by a kustomize generator, or an empty
+// string if the object name has no hash suffix.
+func generationKey(group, kind, namespace, name string) string {
+	if group != "" || (kind != "ConfigMap" && kind != "Secret") {
+		return ""
+	}
+	m := hashSuffix.FindStringSubmatch(name)
+	if m == nil {
+		return ""
+	}
+	return kind + "/" + namespace + "/" + m[1]
+}
+
+// PreviousGenerations returns the stale objects which are previous
+// generations of the ConfigMaps and Secrets created by kustomize
+// generators with a name suffix hash, i.e. which share their kind,
+// namespace and name prefix with an object of the target inventory.
+func PreviousGenerations(stale []*unstructured.Unstructured, target *kustomizev1.ResourceInventory) ([]*unstructured.Unstructured, error) {
+	current, err := ListMetadata(target)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]struct{})
+	for _, m := range current {
+		if key := generationKey(m.GroupKind.Group, m.GroupKind.Kind, m.Namespace, m.Name); key != "" {
+			keys[key] = struct{}{}
+		}
+	}
+
+	var previous []*unstructured.Unstructured
+	for _, u := range stale {
+		key := generationKey(u.GroupVersionKind().Group, u.GetKind(), u.GetNamespace(), u.GetName())
+		if _, ok := keys[key]; ok && key != "" {
+			previous = append(previous, u)
+		}
+	}
+	return previous, nil
+}
+
+// SelectRetained returns the n most recent generations of each generated
+// object, based on their creation timestamp.
+func SelectRetained(generations []*unstructured.Unstructured, n int) []*unstructured.Unstructured {
+	if n <= 0 {
+		return nil
+	}
+
+	byKey := make(map[string][]*unstructured.Unstructured)
+	var keys []string
+	for _, u := range generations {
+		key := generationKey(u.GroupVersionKind().Group, u.GetKind(), u.GetNamespace(), u.GetName())
+		if _, ok := byKey[key]; !ok {
+			keys = append(keys, key)
+		}
+		byKey[key] = append(byKey[key], u)
+	}
+	sort.Strings(keys)
+
+	var retained []*unstructured.Unstructured
+	for _, key := range keys {
+		list := byKey[key]
+		sort.SliceStable(list, func(i, j int) bool {
+			ti, tj := list[i].GetCreationTimestamp(), list[j].GetCreationTimestamp()
+			return tj.Before(&ti)
+		})
+		if len(list) > n {
+			list = list[:n]
+		}
+		retained = append(retained, list...)
+	}
+	return retained
+}
+
+// AddObjects adds the given objects to the inventory.
+func AddObjects(inv *kustomizev1.ResourceInventory, objects []*unstructured.Unstructured) {
+	for _, u := range objects {
+		inv.Entries = append(inv.Entries, kustomizev1.ResourceRef{
+			ID:      object.UnstructuredToObjMetadata(u).String(),
+			Version: u.GroupVersionKind().Version,
+		})
+	}
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func newObject(apiVersion, kind, name string, created time.Time) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(schema.FromAPIVersionAndKind(apiVersion, kind))
+	u.SetNamespace("default")
+	u.SetName(name)
+	u.SetCreationTimestamp(metav1.NewTime(created))
+	return u
+}
+
+func Test_Generations(t *testing.T) {
+	g := NewWithT(t)
+	now := time.Now()
+
+	target := New()
+	AddObjects(target, []*unstructured.Unstructured{
+		newObject("v1", "ConfigMap", "app-config-9hd7m2k5tb", now),
+		newObject("v1", "Secret", "app-secret-c7f8b9d6h4", now),
+		newObject("apps/v1", "Deployment", "app", now),
+	})
+
+	stale := []*unstructured.Unstructured{
+		newObject("v1", "ConfigMap", "app-config-b6t2g4k8m7", now.Add(-3*time.Hour)),
+		newObject("v1", "ConfigMap", "app-config-f5c8k2h9t6", now.Add(-1*time.Hour)),
+		newObject("v1", "ConfigMap", "app-config-m4h6d8b2c9", now.Add(-2*time.Hour)),
+		newObject("v1", "Secret", "app-secret-t8k4h2m6b5", now.Add(-1*time.Hour)),
+		newObject("v1", "ConfigMap", "other-config-k2m4h6t8b9", now.Add(-1*time.Hour)),
+		newObject("v1", "ConfigMap", "app-config", now.Add(-1*time.Hour)),
+		newObject("apps/v1", "Deployment", "app-config-h6t8b2k4m5", now.Add(-1*time.Hour)),
+	}
+
+	previous, err := PreviousGenerations(stale, target)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	t.Run("lists previous generations", func(t *testing.T) {
+		var names []string
+		for _, u := range previous {
+			names = append(names, u.GetName())
+		}
+		g.Expect(names).To(ConsistOf(
+			"app-config-b6t2g4k8m7",
+			"app-config-f5c8k2h9t6",
+			"app-config-m4h6d8b2c9",
+			"app-secret-t8k4h2m6b5",
+		))
+	})
+
+	t.Run("retains the most recent generations", func(t *testing.T) {
+		var names []string
+		for _, u := range SelectRetained(previous, 2) {
+			names = append(names, u.GetName())
+		}
+		g.Expect(names).To(Equal([]string{
+			"app-config-f5c8k2h9t6",
+			"app-config-m4h6d8b2c9",
+			"app-secret-t8k4h2m6b5",
+		}))
+	})
+
+	t.Run("retains nothing by default", func(t *testing.T) {
+		g.Expect(SelectRetained(previous, 0)).To(BeEmpty())
+	})
+
+	t.Run("adds retained objects to the inventory", func(t *testing.T) {
+		inv := New()
+		AddObjects(inv, SelectRetained(previous, 1))
+		g.Expect(inv.Entries).To(HaveLen(2))
+		g.Expect(inv.Entries[0].ID).To(Equal("default_app-config-f5c8k2h9t6__ConfigMap"))
+		g.Expect(inv.Entries[0].Version).To(Equal("v1"))
+	})
+}
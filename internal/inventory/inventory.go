@@ -17,6 +17,8 @@ limitations under the License.
 package inventory
 
 import (
+	"crypto/sha256"
+	"fmt"
 	"sort"
 
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -51,6 +53,36 @@ func AddChangeSet(inv *kustomizev1.ResourceInventory, set *ssa.ChangeSet) error
 	return nil
 }
 
+// Digest returns the checksum of the object's content.
+func Digest(u *unstructured.Unstructured) (string, error) {
+	b, err := u.MarshalJSON()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(b)), nil
+}
+
+// SetDigests records the given checksums, indexed by entry ID, in the inventory entries.
+func SetDigests(inv *kustomizev1.ResourceInventory, digests map[string]string) {
+	for i, entry := range inv.Entries {
+		inv.Entries[i].Digest = digests[entry.ID]
+	}
+}
+
+// Digests returns the checksums recorded in the inventory, indexed by entry ID.
+func Digests(inv *kustomizev1.ResourceInventory) map[string]string {
+	digests := make(map[string]string)
+	if inv == nil {
+		return digests
+	}
+	for _, entry := range inv.Entries {
+		if entry.Digest != "" {
+			digests[entry.ID] = entry.Digest
+		}
+	}
+	return digests
+}
+
 // List returns the inventory entries as unstructured.Unstructured objects.
 func List(inv *kustomizev1.ResourceInventory) ([]*unstructured.Unstructured, error) {
 	objects := make([]*unstructured.Unstructured, 0)
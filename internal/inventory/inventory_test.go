@@ -65,6 +65,34 @@ func Test_Inventory(t *testing.T) {
 		g.Expect(len(unList)).To(BeIdenticalTo(1))
 		g.Expect(unList[0].GetName()).To(BeIdenticalTo("test2"))
 	})
+
+	t.Run("records objects digests", func(t *testing.T) {
+		data, err := os.ReadFile("testdata/inventory1.yaml")
+		g.Expect(err).ToNot(HaveOccurred())
+		objects, err := ssautil.ReadObjects(strings.NewReader(string(data)))
+		g.Expect(err).ToNot(HaveOccurred())
+
+		expected := make(map[string]string)
+		for _, o := range objects {
+			digest, err := Digest(o)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(digest).To(HavePrefix("sha256:"))
+			expected[object.UnstructuredToObjMetadata(o).String()] = digest
+		}
+
+		inv := inv1.DeepCopy()
+		SetDigests(inv, expected)
+
+		digests := Digests(inv)
+		g.Expect(digests).To(HaveLen(len(inv.Entries)))
+		g.Expect(digests).To(Equal(expected))
+
+		o := objects[0].DeepCopy()
+		o.SetLabels(map[string]string{"changed": "true"})
+		digest, err := Digest(o)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(digests).ToNot(ContainElement(digest))
+	})
 }
 
 func readManifest(manifest string) (*ssa.ChangeSet, error) {
@@ -0,0 +1,71 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package leadtime measures the time from the availability of the source
+// revisions to the readiness of the Kustomizations for them, enabling the
+// deployment lead-time SLOs straight from the controller metrics.
+package leadtime
+
+import (
+	"time"
+
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	"github.com/prometheus/client_golang/prometheus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var timeToReady = prometheus.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Name: "gotk_time_to_ready_seconds",
+		Help: "The duration from the availability of the source revisions to the readiness of the Kustomization for them.",
+		// From 5s to about 2h50m.
+		Buckets: prometheus.ExponentialBuckets(5, 2, 12),
+	},
+	[]string{"name", "namespace"},
+)
+
+// RegisterMetrics registers the time to ready metric with the registry.
+func RegisterMetrics(registry prometheus.Registerer) {
+	registry.MustRegister(timeToReady)
+}
+
+// Record observes the time to ready of the Kustomization.
+func Record(name, namespace string, d time.Duration) {
+	timeToReady.WithLabelValues(name, namespace).Observe(d.Seconds())
+}
+
+// Delete removes the metric of the Kustomization.
+func Delete(name, namespace string) {
+	timeToReady.DeleteLabelValues(name, namespace)
+}
+
+// Since returns the duration from the availability of the artifact
+// revision to now. The revisions published before the Kustomization was
+// created are available from its creation. It returns false if the time
+// of the artifact is not set.
+func Since(artifact *sourcev1.Artifact, created metav1.Time, now time.Time) (time.Duration, bool) {
+	if artifact == nil || artifact.LastUpdateTime.IsZero() {
+		return 0, false
+	}
+	available := artifact.LastUpdateTime.Time
+	if created.After(available) {
+		available = created.Time
+	}
+	if now.Before(available) {
+		return 0, true
+	}
+	return now.Sub(available), true
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package leadtime
+
+import (
+	"testing"
+	"time"
+
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSince(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	artifact := &sourcev1.Artifact{LastUpdateTime: metav1.NewTime(now.Add(-10 * time.Minute))}
+
+	for _, tt := range []struct {
+		name     string
+		artifact *sourcev1.Artifact
+		created  metav1.Time
+		want     time.Duration
+		wantOK   bool
+	}{
+		{"from the artifact update", artifact, metav1.NewTime(now.Add(-time.Hour)), 10 * time.Minute, true},
+		{"from the creation of the Kustomization", artifact, metav1.NewTime(now.Add(-2 * time.Minute)), 2 * time.Minute, true},
+		{"with clock skew", &sourcev1.Artifact{LastUpdateTime: metav1.NewTime(now.Add(time.Second))}, metav1.Time{}, 0, true},
+		{"without time", &sourcev1.Artifact{}, metav1.Time{}, 0, false},
+		{"without artifact", nil, metav1.Time{}, 0, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			got, ok := Since(tt.artifact, tt.created, now)
+			g.Expect(ok).To(Equal(tt.wantOK))
+			g.Expect(got).To(Equal(tt.want))
+		})
+	}
+}
+
+func TestRecord(t *testing.T) {
+	g := NewWithT(t)
+
+	registry := prometheus.NewRegistry()
+	RegisterMetrics(registry)
+
+	Record("apps", "flux-system", 30*time.Second)
+	Record("apps", "flux-system", 90*time.Second)
+	g.Expect(testutil.CollectAndCount(timeToReady)).To(Equal(1))
+
+	Delete("apps", "flux-system")
+	g.Expect(testutil.CollectAndCount(timeToReady)).To(BeZero())
+}
@@ -0,0 +1,254 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package loglevel provides a logger whose verbosity can be changed at
+// runtime, globally through an HTTP endpoint, or for the log lines of a
+// single object.
+package loglevel
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync/atomic"
+
+	"github.com/fluxcd/pkg/runtime/logger"
+	"github.com/go-logr/logr"
+	"go.uber.org/zap/zapcore"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+// levels maps the supported level names to the zap levels,
+// with the same names as the --log-level flag.
+var levels = map[string]zapcore.Level{
+	"trace": zapcore.DebugLevel - 1,
+	"debug": zapcore.DebugLevel,
+	"info":  zapcore.InfoLevel,
+	"error": zapcore.ErrorLevel,
+}
+
+// stackLevels maps the --log-level values to the level from which the
+// stack traces are recorded.
+var stackLevels = map[string]zapcore.Level{
+	"trace": zapcore.ErrorLevel,
+	"debug": zapcore.ErrorLevel,
+	"info":  zapcore.PanicLevel,
+	"error": zapcore.PanicLevel,
+}
+
+// Parse returns the zap level of the given level name.
+func Parse(name string) (zapcore.Level, error) {
+	l, ok := levels[name]
+	if !ok {
+		return 0, fmt.Errorf("unsupported log level '%s', must be one of: %v", name, Names())
+	}
+	return l, nil
+}
+
+// Names returns the supported level names, from the most to the least verbose.
+func Names() []string {
+	names := make([]string, 0, len(levels))
+	for name := range levels {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return levels[names[i]] < levels[names[j]]
+	})
+	return names
+}
+
+// Level is the global log level, safe for concurrent use.
+type Level struct {
+	level atomic.Int32
+}
+
+// NewLevel returns a Level set to the given level name.
+func NewLevel(name string) (*Level, error) {
+	l := &Level{}
+	if err := l.Set(name); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Set changes the level.
+func (l *Level) Set(name string) error {
+	level, err := Parse(name)
+	if err != nil {
+		return err
+	}
+	l.level.Store(int32(level))
+	return nil
+}
+
+// String returns the name of the level.
+func (l *Level) String() string {
+	level := l.get()
+	for name, v := range levels {
+		if v == level {
+			return name
+		}
+	}
+	return level.String()
+}
+
+func (l *Level) get() zapcore.Level {
+	return zapcore.Level(l.level.Load())
+}
+
+// NewLogger returns a logger configured like the runtime logger, whose
+// verbosity is controlled by the given Level instead of being fixed
+// by the --log-level flag.
+func NewLogger(opts logger.Options, level *Level) logr.Logger {
+	zapOpts := zap.Options{
+		EncoderConfigOptions: []zap.EncoderConfigOption{
+			func(config *zapcore.EncoderConfig) {
+				config.EncodeTime = zapcore.ISO8601TimeEncoder
+			},
+		},
+		// The lines are filtered by the levelSink, the core
+		// must let through the most verbose ones.
+		Level: levels["trace"],
+	}
+
+	switch opts.LogEncoding {
+	case "console":
+		zapOpts.EncoderConfigOptions = append(zapOpts.EncoderConfigOptions, func(config *zapcore.EncoderConfig) {
+			config.EncodeLevel = logger.CapitalLevelEncoder
+		})
+		zap.ConsoleEncoder(zapOpts.EncoderConfigOptions...)(&zapOpts)
+	case "json":
+		zapOpts.EncoderConfigOptions = append(zapOpts.EncoderConfigOptions, func(config *zapcore.EncoderConfig) {
+			config.EncodeLevel = logger.LowercaseLevelEncoder
+		})
+		zap.JSONEncoder(zapOpts.EncoderConfigOptions...)(&zapOpts)
+	}
+
+	if l, ok := stackLevels[opts.LogLevel]; ok {
+		zapOpts.StacktraceLevel = l
+	}
+
+	return wrap(zap.New(zap.UseFlagOptions(&zapOpts)), level)
+}
+
+func wrap(base logr.Logger, level *Level) logr.Logger {
+	return logr.New(&levelSink{sink: base.GetSink(), level: level})
+}
+
+// WithLevel returns a logger whose lines are written from the given level
+// name, when it's more verbose than the global level. The logger is returned
+// unchanged if it wasn't created by NewLogger, or if the level is unknown.
+func WithLevel(log logr.Logger, name string) logr.Logger {
+	s, ok := log.GetSink().(*levelSink)
+	if !ok {
+		return log
+	}
+	level, err := Parse(name)
+	if err != nil {
+		return log
+	}
+	c := *s
+	c.override = &level
+	return log.WithSink(&c)
+}
+
+// levelSink drops the info lines less important than the global level, or
+// than the level of the logger if it's more verbose. The error lines are
+// always written.
+type levelSink struct {
+	sink     logr.LogSink
+	level    *Level
+	override *zapcore.Level
+}
+
+var _ logr.CallDepthLogSink = &levelSink{}
+
+func (s *levelSink) Init(logr.RuntimeInfo) {
+	// The underlying sink is already initialized, skip
+	// the extra frame of this sink in the caller info.
+	if cd, ok := s.sink.(logr.CallDepthLogSink); ok {
+		s.sink = cd.WithCallDepth(1)
+	}
+}
+
+func (s *levelSink) Enabled(v int) bool {
+	threshold := s.level.get()
+	if s.override != nil && *s.override < threshold {
+		threshold = *s.override
+	}
+	return zapcore.Level(-v) >= threshold && s.sink.Enabled(v)
+}
+
+func (s *levelSink) Info(v int, msg string, keysAndValues ...any) {
+	s.sink.Info(v, msg, keysAndValues...)
+}
+
+func (s *levelSink) Error(err error, msg string, keysAndValues ...any) {
+	s.sink.Error(err, msg, keysAndValues...)
+}
+
+func (s *levelSink) WithValues(keysAndValues ...any) logr.LogSink {
+	c := *s
+	c.sink = s.sink.WithValues(keysAndValues...)
+	return &c
+}
+
+func (s *levelSink) WithName(name string) logr.LogSink {
+	c := *s
+	c.sink = s.sink.WithName(name)
+	return &c
+}
+
+func (s *levelSink) WithCallDepth(depth int) logr.LogSink {
+	cd, ok := s.sink.(logr.CallDepthLogSink)
+	if !ok {
+		return s
+	}
+	c := *s
+	c.sink = cd.WithCallDepth(depth)
+	return &c
+}
+
+type levelBody struct {
+	Level string `json:"level"`
+}
+
+// Handler returns an HTTP handler which returns the global level on GET,
+// and changes it on PUT with a JSON body like {"level": "debug"}.
+func Handler(level *Level) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut:
+			var body levelBody
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				http.Error(w, fmt.Sprintf("invalid request body: %s", err), http.StatusBadRequest)
+				return
+			}
+			if err := level.Set(body.Level); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		default:
+			w.Header().Set("Allow", "GET, PUT")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(levelBody{Level: level.String()})
+	})
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loglevel
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/gomega"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+func newTestLogger(level *Level) (logr.Logger, *bytes.Buffer) {
+	buf := &bytes.Buffer{}
+	base := zap.New(zap.WriteTo(buf), zap.Level(levels["trace"]))
+	return wrap(base, level), buf
+}
+
+func TestLevel(t *testing.T) {
+	g := NewWithT(t)
+
+	level, err := NewLevel("info")
+	g.Expect(err).ToNot(HaveOccurred())
+	log, buf := newTestLogger(level)
+
+	log.V(1).Info("debug line")
+	log.Info("info line")
+	log.Error(errors.New("failed"), "error line")
+	g.Expect(buf.String()).ToNot(ContainSubstring("debug line"))
+	g.Expect(buf.String()).To(ContainSubstring("info line"))
+	g.Expect(buf.String()).To(ContainSubstring("error line"))
+
+	buf.Reset()
+	g.Expect(level.Set("debug")).To(Succeed())
+	g.Expect(level.String()).To(Equal("debug"))
+	log.WithName("child").V(1).Info("debug line")
+	log.V(2).Info("trace line")
+	g.Expect(buf.String()).To(ContainSubstring("debug line"))
+	g.Expect(buf.String()).ToNot(ContainSubstring("trace line"))
+
+	buf.Reset()
+	g.Expect(level.Set("error")).To(Succeed())
+	log.Info("info line")
+	log.Error(errors.New("failed"), "error line")
+	g.Expect(buf.String()).ToNot(ContainSubstring("info line"))
+	g.Expect(buf.String()).To(ContainSubstring("error line"))
+
+	g.Expect(level.Set("verbose")).To(MatchError(ContainSubstring("unsupported log level")))
+	g.Expect(level.String()).To(Equal("error"))
+}
+
+func TestWithLevel(t *testing.T) {
+	g := NewWithT(t)
+
+	level, err := NewLevel("info")
+	g.Expect(err).ToNot(HaveOccurred())
+	log, buf := newTestLogger(level)
+
+	debugLog := WithLevel(log, "debug").WithValues("name", "test")
+	debugLog.V(1).Info("object debug line")
+	log.V(1).Info("global debug line")
+	g.Expect(buf.String()).To(ContainSubstring("object debug line"))
+	g.Expect(buf.String()).ToNot(ContainSubstring("global debug line"))
+
+	// A less verbose override doesn't hide the lines of the global level.
+	buf.Reset()
+	WithLevel(log, "error").Info("info line")
+	g.Expect(buf.String()).To(ContainSubstring("info line"))
+
+	// Unknown levels and loggers are left unchanged.
+	g.Expect(WithLevel(log, "verbose")).To(Equal(log))
+	g.Expect(WithLevel(logr.Discard(), "debug")).To(Equal(logr.Discard()))
+}
+
+func TestHandler(t *testing.T) {
+	g := NewWithT(t)
+
+	level, err := NewLevel("info")
+	g.Expect(err).ToNot(HaveOccurred())
+	handler := Handler(level)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/log-level", nil))
+	g.Expect(rec.Code).To(Equal(http.StatusOK))
+	g.Expect(rec.Body.String()).To(MatchJSON(`{"level":"info"}`))
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/log-level", strings.NewReader(`{"level":"trace"}`)))
+	g.Expect(rec.Code).To(Equal(http.StatusOK))
+	g.Expect(rec.Body.String()).To(MatchJSON(`{"level":"trace"}`))
+	g.Expect(level.String()).To(Equal("trace"))
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/log-level", strings.NewReader(`{"level":"verbose"}`)))
+	g.Expect(rec.Code).To(Equal(http.StatusBadRequest))
+	g.Expect(level.String()).To(Equal("trace"))
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/log-level", nil))
+	g.Expect(rec.Code).To(Equal(http.StatusMethodNotAllowed))
+}
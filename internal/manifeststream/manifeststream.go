@@ -0,0 +1,265 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package manifeststream renders the objects applied for a revision as a
+// normalized multi-document YAML stream, which is identical byte for byte
+// for the same objects across controller versions, and retains the streams
+// of the last revisions of each Kustomization to serve them to the diffing
+// tools and the supply-chain attestations.
+package manifeststream
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/yaml"
+
+	"github.com/fluxcd/kustomize-controller/internal/metricsauth"
+)
+
+const (
+	// PathPrefix is the path under which the streams are served.
+	PathPrefix = "/manifests/"
+
+	// Version is the version of the stream format, increased on any change
+	// of the rendering.
+	Version = "v2"
+
+	// Redacted replaces the values of the Secrets.
+	Redacted = "<redacted>"
+)
+
+// buildMetadataPrefixes are the prefixes of the annotations set by
+// kustomize, whose values depend on its version.
+var buildMetadataPrefixes = []string{
+	"config.kubernetes.io/",
+	"internal.config.kubernetes.io/",
+	"config.k8s.io/",
+}
+
+// Format renders the objects as a YAML stream in which:
+//   - the objects are sorted by group, kind, namespace and name;
+//   - each document starts with a '---' line, the keys are sorted, and
+//     the stream ends with a newline;
+//   - the status, the creation timestamp, the kustomize build annotations
+//     and the empty labels and annotations are removed;
+//   - the values of the Secrets are replaced with Redacted, as their
+//     digests could be reversed by brute force.
+func Format(objects []*unstructured.Unstructured) ([]byte, error) {
+	normalized := make([]*unstructured.Unstructured, 0, len(objects))
+	for _, u := range objects {
+		normalized = append(normalized, normalize(u))
+	}
+	sort.SliceStable(normalized, func(i, j int) bool {
+		return sortKey(normalized[i]) < sortKey(normalized[j])
+	})
+
+	var buf bytes.Buffer
+	for _, u := range normalized {
+		data, err := yaml.Marshal(u.Object)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render %s/%s/%s: %w", u.GetKind(), u.GetNamespace(), u.GetName(), err)
+		}
+		buf.WriteString("---\n")
+		buf.Write(data)
+	}
+	return buf.Bytes(), nil
+}
+
+// Digest returns the SHA-256 digest of the stream, in the 'sha256:<hex>'
+// format.
+func Digest(data []byte) string {
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(data))
+}
+
+func sortKey(u *unstructured.Unstructured) string {
+	gvk := u.GroupVersionKind()
+	return strings.Join([]string{gvk.Group, gvk.Kind, u.GetNamespace(), u.GetName()}, "\x00")
+}
+
+func normalize(u *unstructured.Unstructured) *unstructured.Unstructured {
+	n := u.DeepCopy()
+	unstructured.RemoveNestedField(n.Object, "status")
+	unstructured.RemoveNestedField(n.Object, "metadata", "creationTimestamp")
+
+	annotations := n.GetAnnotations()
+	for k := range annotations {
+		for _, prefix := range buildMetadataPrefixes {
+			if strings.HasPrefix(k, prefix) {
+				delete(annotations, k)
+			}
+		}
+	}
+	if len(annotations) == 0 {
+		unstructured.RemoveNestedField(n.Object, "metadata", "annotations")
+	} else {
+		n.SetAnnotations(annotations)
+	}
+	if len(n.GetLabels()) == 0 {
+		unstructured.RemoveNestedField(n.Object, "metadata", "labels")
+	}
+
+	if n.GetAPIVersion() == "v1" && n.GetKind() == "Secret" {
+		for _, field := range []string{"data", "stringData"} {
+			values, ok, _ := unstructured.NestedStringMap(n.Object, field)
+			if !ok {
+				continue
+			}
+			for k := range values {
+				values[k] = Redacted
+			}
+			_ = unstructured.SetNestedStringMap(n.Object, values, field)
+		}
+	}
+	return n
+}
+
+// Entry describes a retained stream.
+type Entry struct {
+	// Revision is the source revision the stream was applied for.
+	Revision string `json:"revision"`
+
+	// Digest is the digest of the stream.
+	Digest string `json:"digest"`
+
+	// AppliedAt is the time at which the stream was applied.
+	AppliedAt time.Time `json:"appliedAt"`
+
+	data []byte
+}
+
+// Store retains the streams of the last revisions of each Kustomization.
+type Store struct {
+	revisions int
+	now       func() time.Time
+
+	mu      sync.Mutex
+	streams map[types.NamespacedName][]Entry
+}
+
+// NewStore returns a Store retaining the streams of the given number of
+// revisions per Kustomization.
+func NewStore(revisions int) *Store {
+	return &Store{
+		revisions: revisions,
+		now:       time.Now,
+		streams:   make(map[types.NamespacedName][]Entry),
+	}
+}
+
+// Add retains the stream applied for the revision, replacing the stream of
+// the same revision if any, and evicts the oldest ones beyond the number
+// of revisions. It returns the digest of the stream.
+func (s *Store) Add(key types.NamespacedName, revision string, data []byte) string {
+	entry := Entry{Revision: revision, Digest: Digest(data), AppliedAt: s.now().UTC(), data: data}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries := []Entry{entry}
+	for _, e := range s.streams[key] {
+		if e.Revision != revision && len(entries) < s.revisions {
+			entries = append(entries, e)
+		}
+	}
+	s.streams[key] = entries
+	return entry.Digest
+}
+
+// Remove deletes the streams of the given Kustomization.
+func (s *Store) Remove(key types.NamespacedName) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.streams, key)
+}
+
+// List returns the streams of the given Kustomization, newest first.
+func (s *Store) List(key types.NamespacedName) []Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Entry(nil), s.streams[key]...)
+}
+
+// Handler returns an HTTP handler serving under PathPrefix:
+//   - '<namespace>/<name>' the JSON list of the retained streams;
+//   - '<namespace>/<name>/<digest>' the stream with the given digest.
+//
+// The callers authenticate with a bearer token and must be allowed to get
+// the Secrets of the namespace, as the objects can contain decrypted and
+// substituted values. The options are read on each request, which allows
+// to set the client once the manager is created.
+func (s *Store) Handler(auth *metricsauth.Options) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", "GET")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if auth.Client == nil {
+			http.Error(w, "the controller is not ready", http.StatusServiceUnavailable)
+			return
+		}
+
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, PathPrefix), "/")
+		if len(parts) < 2 || len(parts) > 3 || parts[0] == "" {
+			http.NotFound(w, r)
+			return
+		}
+		code, err := metricsauth.Authorize(r, auth.Client, authorizationv1.SubjectAccessReviewSpec{
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace: parts[0],
+				Verb:      "get",
+				Resource:  "secrets",
+			},
+		})
+		if err != nil {
+			http.Error(w, err.Error(), code)
+			return
+		}
+		entries := s.List(types.NamespacedName{Namespace: parts[0], Name: parts[1]})
+		if len(entries) == 0 {
+			http.NotFound(w, r)
+			return
+		}
+
+		if len(parts) == 2 {
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(entries)
+			return
+		}
+
+		for _, e := range entries {
+			if e.Digest == parts[2] {
+				w.Header().Set("Content-Type", "application/yaml")
+				w.Header().Set("X-Manifest-Stream-Version", Version)
+				w.Header().Set("X-Manifest-Stream-Revision", e.Revision)
+				_, _ = w.Write(e.data)
+				return
+			}
+		}
+		http.NotFound(w, r)
+	})
+}
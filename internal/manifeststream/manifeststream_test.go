@@ -0,0 +1,172 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package manifeststream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	"github.com/fluxcd/kustomize-controller/internal/metricsauth"
+)
+
+// reviewClient returns a client authenticating the 'valid' token as the
+// 'dev' user, allowed to get the secrets of the 'apps' namespace only.
+func reviewClient() client.Client {
+	return fake.NewClientBuilder().WithScheme(scheme.Scheme).WithInterceptorFuncs(interceptor.Funcs{
+		Create: func(_ context.Context, _ client.WithWatch, obj client.Object, _ ...client.CreateOption) error {
+			switch review := obj.(type) {
+			case *authenticationv1.TokenReview:
+				if review.Spec.Token == "valid" {
+					review.Status.Authenticated = true
+					review.Status.User = authenticationv1.UserInfo{Username: "dev"}
+				}
+			case *authorizationv1.SubjectAccessReview:
+				attrs := review.Spec.ResourceAttributes
+				review.Status.Allowed = review.Spec.User == "dev" &&
+					attrs.Namespace == "apps" && attrs.Verb == "get" && attrs.Resource == "secrets"
+			default:
+				return errors.New("unexpected object")
+			}
+			return nil
+		},
+	}).Build()
+}
+
+func object(apiVersion, kind, namespace, name string) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{}
+	u.SetAPIVersion(apiVersion)
+	u.SetKind(kind)
+	u.SetNamespace(namespace)
+	u.SetName(name)
+	return u
+}
+
+func TestFormat(t *testing.T) {
+	g := NewWithT(t)
+
+	deployment := object("apps/v1", "Deployment", "apps", "web")
+	deployment.SetAnnotations(map[string]string{"config.kubernetes.io/index": "0"})
+	deployment.SetLabels(map[string]string{})
+	deployment.Object["status"] = map[string]interface{}{"replicas": int64(1)}
+	_ = unstructured.SetNestedField(deployment.Object, nil, "metadata", "creationTimestamp")
+
+	secret := object("v1", "Secret", "apps", "token")
+	secret.SetAnnotations(map[string]string{"owner": "team"})
+	_ = unstructured.SetNestedStringMap(secret.Object, map[string]string{"token": "c2VjcmV0"}, "data")
+
+	namespace := object("v1", "Namespace", "", "apps")
+
+	objects := []*unstructured.Unstructured{deployment, secret, namespace}
+	data, err := Format(objects)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(string(data)).To(Equal(`---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: apps
+---
+apiVersion: v1
+data:
+  token: <redacted>
+kind: Secret
+metadata:
+  annotations:
+    owner: team
+  name: token
+  namespace: apps
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: apps
+`))
+
+	// The objects are left untouched.
+	g.Expect(deployment.Object).To(HaveKey("status"))
+	g.Expect(secret.Object["data"]).To(HaveKeyWithValue("token", "c2VjcmV0"))
+
+	reordered, err := Format([]*unstructured.Unstructured{namespace, deployment, secret})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(reordered).To(Equal(data))
+}
+
+func TestStore(t *testing.T) {
+	g := NewWithT(t)
+	key := types.NamespacedName{Namespace: "apps", Name: "web"}
+
+	s := NewStore(2)
+	s.Add(key, "v1", []byte("a"))
+	s.Add(key, "v2", []byte("b"))
+	s.Add(key, "v2", []byte("c"))
+	g.Expect(s.List(key)).To(HaveLen(2))
+	g.Expect(s.List(key)[0].Digest).To(Equal(Digest([]byte("c"))))
+
+	digest := s.Add(key, "v3", []byte("d"))
+	entries := s.List(key)
+	g.Expect(entries).To(HaveLen(2))
+	g.Expect(entries[0].Revision).To(Equal("v3"))
+	g.Expect(entries[1].Revision).To(Equal("v2"))
+
+	handler := s.Handler(&metricsauth.Options{Client: reviewClient()})
+	get := func(path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, PathPrefix+path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	g.Expect(get("apps/web", "").Code).To(Equal(http.StatusUnauthorized))
+	g.Expect(get("apps/web", "invalid").Code).To(Equal(http.StatusUnauthorized))
+	g.Expect(get("flux-system/web", "valid").Code).To(Equal(http.StatusForbidden))
+
+	rec := get("apps/web", "valid")
+	g.Expect(rec.Code).To(Equal(http.StatusOK))
+	var list []Entry
+	g.Expect(json.Unmarshal(rec.Body.Bytes(), &list)).To(Succeed())
+	g.Expect(list).To(HaveLen(2))
+	g.Expect(list[0].Digest).To(Equal(digest))
+
+	rec = get("apps/web/"+digest, "valid")
+	g.Expect(rec.Code).To(Equal(http.StatusOK))
+	g.Expect(rec.Body.String()).To(Equal("d"))
+	g.Expect(rec.Header().Get("X-Manifest-Stream-Revision")).To(Equal("v3"))
+
+	rec = get("apps/web/"+Digest([]byte("a")), "valid")
+	g.Expect(rec.Code).To(Equal(http.StatusNotFound))
+
+	s.Remove(key)
+	rec = get("apps/web", "valid")
+	g.Expect(rec.Code).To(Equal(http.StatusNotFound))
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package metricsauth authenticates the callers of the endpoints served on
+// the metrics address with token reviews, and authorizes them with subject
+// access reviews, so that these endpoints are subject to the RBAC of the
+// cluster like the API server.
+package metricsauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Options configures the reviews.
+type Options struct {
+	// Client is used to create the token and subject access reviews.
+	Client client.Client
+}
+
+// Authorize authenticates the bearer token of the request and checks that
+// its user is allowed the access described by the resource or non-resource
+// attributes of the spec, returning the HTTP status code to respond with on
+// failure. The user fields of the spec are set from the token review.
+func Authorize(r *http.Request, c client.Client, access authorizationv1.SubjectAccessReviewSpec) (int, error) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return http.StatusUnauthorized, errors.New("a bearer token is required")
+	}
+	return authorize(r.Context(), c, token, access)
+}
+
+// NonResourceHandler returns an HTTP handler which serves the requests with
+// next once their caller is allowed the lowercase method of the request on
+// its path as a non-resource URL, e.g. 'put' on '/log-level'. The options
+// are read on each request, which allows to set the client once the manager
+// is created.
+func NonResourceHandler(opts *Options, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if opts.Client == nil {
+			http.Error(w, "the controller is not ready", http.StatusServiceUnavailable)
+			return
+		}
+		code, err := Authorize(r, opts.Client, authorizationv1.SubjectAccessReviewSpec{
+			NonResourceAttributes: &authorizationv1.NonResourceAttributes{
+				Path: r.URL.Path,
+				Verb: strings.ToLower(r.Method),
+			},
+		})
+		if err != nil {
+			http.Error(w, err.Error(), code)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func authorize(ctx context.Context, c client.Client, token string, access authorizationv1.SubjectAccessReviewSpec) (int, error) {
+	review := &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{Token: token},
+	}
+	if err := c.Create(ctx, review); err != nil {
+		return http.StatusInternalServerError, fmt.Errorf("failed to review the token: %w", err)
+	}
+	if !review.Status.Authenticated {
+		return http.StatusUnauthorized, errors.New("the token is not valid")
+	}
+
+	user := review.Status.User
+	access.User = user.Username
+	access.UID = user.UID
+	access.Groups = user.Groups
+	access.Extra = make(map[string]authorizationv1.ExtraValue, len(user.Extra))
+	for k, v := range user.Extra {
+		access.Extra[k] = authorizationv1.ExtraValue(v)
+	}
+	sar := &authorizationv1.SubjectAccessReview{Spec: access}
+	if err := c.Create(ctx, sar); err != nil {
+		return http.StatusInternalServerError, fmt.Errorf("failed to review the access: %w", err)
+	}
+	if !sar.Status.Allowed {
+		return http.StatusForbidden, fmt.Errorf("user '%s' is not allowed to %s", user.Username, describe(access))
+	}
+	return 0, nil
+}
+
+// describe returns the access as used in the error messages, e.g. "get the
+// secrets in namespace 'apps'".
+func describe(access authorizationv1.SubjectAccessReviewSpec) string {
+	if attrs := access.NonResourceAttributes; attrs != nil {
+		return fmt.Sprintf("%s '%s'", attrs.Verb, attrs.Path)
+	}
+	attrs := access.ResourceAttributes
+	if attrs == nil {
+		return "access the endpoint"
+	}
+	if attrs.Namespace == "" {
+		return fmt.Sprintf("%s the %s", attrs.Verb, attrs.Resource)
+	}
+	return fmt.Sprintf("%s the %s in namespace '%s'", attrs.Verb, attrs.Resource, attrs.Namespace)
+}
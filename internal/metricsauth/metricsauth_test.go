@@ -0,0 +1,142 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metricsauth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+// reviewClient returns a client authenticating the 'valid' token as the
+// 'dev' user, allowed to get the secrets of the 'apps' namespace and to get
+// '/log-level' only.
+func reviewClient() client.Client {
+	return fake.NewClientBuilder().WithScheme(scheme.Scheme).WithInterceptorFuncs(interceptor.Funcs{
+		Create: func(_ context.Context, _ client.WithWatch, obj client.Object, _ ...client.CreateOption) error {
+			switch review := obj.(type) {
+			case *authenticationv1.TokenReview:
+				if review.Spec.Token == "valid" {
+					review.Status.Authenticated = true
+					review.Status.User = authenticationv1.UserInfo{Username: "dev"}
+				}
+			case *authorizationv1.SubjectAccessReview:
+				if review.Spec.User != "dev" {
+					return nil
+				}
+				if attrs := review.Spec.NonResourceAttributes; attrs != nil {
+					review.Status.Allowed = attrs.Path == "/log-level" && attrs.Verb == "get"
+				}
+				if attrs := review.Spec.ResourceAttributes; attrs != nil {
+					review.Status.Allowed = attrs.Namespace == "apps" && attrs.Verb == "get" && attrs.Resource == "secrets"
+				}
+			default:
+				return errors.New("unexpected object")
+			}
+			return nil
+		},
+	}).Build()
+}
+
+func TestAuthorize(t *testing.T) {
+	tests := []struct {
+		name      string
+		token     string
+		namespace string
+		code      int
+	}{
+		{name: "allowed", token: "valid", namespace: "apps", code: 0},
+		{name: "missing token", namespace: "apps", code: http.StatusUnauthorized},
+		{name: "invalid token", token: "invalid", namespace: "apps", code: http.StatusUnauthorized},
+		{name: "forbidden namespace", token: "valid", namespace: "flux-system", code: http.StatusForbidden},
+	}
+
+	c := reviewClient()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			req := httptest.NewRequest(http.MethodGet, "/build/"+tt.namespace+"/podinfo", nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			code, err := Authorize(req, c, authorizationv1.SubjectAccessReviewSpec{
+				ResourceAttributes: &authorizationv1.ResourceAttributes{
+					Namespace: tt.namespace,
+					Verb:      "get",
+					Resource:  "secrets",
+				},
+			})
+			g.Expect(code).To(Equal(tt.code))
+			if tt.code == 0 {
+				g.Expect(err).ToNot(HaveOccurred())
+				return
+			}
+			g.Expect(err).To(HaveOccurred())
+			if tt.code == http.StatusForbidden {
+				g.Expect(err.Error()).To(Equal("user 'dev' is not allowed to get the secrets in namespace 'flux-system'"))
+			}
+		})
+	}
+}
+
+func TestNonResourceHandler(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name   string
+		method string
+		token  string
+		code   int
+	}{
+		{name: "allowed", method: http.MethodGet, token: "valid", code: http.StatusOK},
+		{name: "forbidden verb", method: http.MethodPut, token: "valid", code: http.StatusForbidden},
+		{name: "missing token", method: http.MethodGet, code: http.StatusUnauthorized},
+	}
+
+	handler := NonResourceHandler(&Options{Client: reviewClient()}, next)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			req := httptest.NewRequest(tt.method, "/log-level", nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			g.Expect(rec.Code).To(Equal(tt.code), rec.Body.String())
+		})
+	}
+
+	t.Run("not ready", func(t *testing.T) {
+		g := NewWithT(t)
+		rec := httptest.NewRecorder()
+		NonResourceHandler(&Options{}, next).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/log-level", nil))
+		g.Expect(rec.Code).To(Equal(http.StatusServiceUnavailable))
+	})
+}
@@ -0,0 +1,286 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package mutationrules applies the mutation rules defined by the cluster
+// operators to the objects of the Kustomizations before they're applied,
+// e.g. to inject the imagePullSecrets or the topologySpreadConstraints of
+// the workloads of all tenants.
+package mutationrules
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	ssautil "github.com/fluxcd/pkg/ssa/utils"
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/ext"
+	"google.golang.org/protobuf/types/known/structpb"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// SkipAll skips all the mutation rules when listed in the
+	// skipped rules of a Kustomization.
+	SkipAll = "*"
+
+	// costLimit bounds the cost of the evaluation of an expression.
+	costLimit = 1000000
+)
+
+// Rule is a mutation rule, defined in YAML under a key of the
+// mutation rules ConfigMap, the key being the name of the rule.
+type Rule struct {
+	// Kinds are the kinds of the objects mutated by the rule,
+	// all kinds if empty.
+	Kinds []string `json:"kinds,omitempty"`
+
+	// Selector selects the objects mutated by the rule by their labels.
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+
+	// Condition is a CEL expression, evaluated with the object as
+	// 'object', which must return true for the object to be mutated.
+	Condition string `json:"condition,omitempty"`
+
+	// Patch are the JSON patch operations applied to the objects.
+	Patch []Operation `json:"patch"`
+}
+
+// Operation is a JSON patch operation, whose value may be computed
+// by a CEL expression.
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+
+	// ValueExpression is a CEL expression, evaluated with the object
+	// as 'object', whose result is the value of the operation.
+	ValueExpression string `json:"valueExpression,omitempty"`
+}
+
+type compiledOperation struct {
+	Operation
+	program cel.Program
+}
+
+type compiledRule struct {
+	name       string
+	kinds      []string
+	selector   labels.Selector
+	condition  cel.Program
+	operations []compiledOperation
+}
+
+// Mutator applies compiled mutation rules.
+type Mutator struct {
+	rules []compiledRule
+}
+
+// Compile returns a Mutator for the rules of the ConfigMap data, in the
+// order of their names, or an error if a rule is invalid.
+func Compile(data map[string]string) (*Mutator, error) {
+	env, err := cel.NewEnv(
+		cel.Variable("object", cel.DynType),
+		cel.OptionalTypes(),
+		ext.Strings(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(data))
+	for name := range data {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	m := &Mutator{}
+	for _, name := range names {
+		var r Rule
+		if err := yaml.UnmarshalStrict([]byte(data[name]), &r); err != nil {
+			return nil, fmt.Errorf("invalid mutation rule '%s': %w", name, err)
+		}
+		cr, err := compileRule(env, name, r)
+		if err != nil {
+			return nil, fmt.Errorf("invalid mutation rule '%s': %w", name, err)
+		}
+		m.rules = append(m.rules, cr)
+	}
+	return m, nil
+}
+
+func compileRule(env *cel.Env, name string, r Rule) (compiledRule, error) {
+	cr := compiledRule{name: name, kinds: r.Kinds, selector: labels.Everything()}
+	if r.Selector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(r.Selector)
+		if err != nil {
+			return cr, err
+		}
+		cr.selector = selector
+	}
+	if r.Condition != "" {
+		prg, err := compileExpression(env, r.Condition, cel.BoolType)
+		if err != nil {
+			return cr, fmt.Errorf("invalid condition: %w", err)
+		}
+		cr.condition = prg
+	}
+	if len(r.Patch) == 0 {
+		return cr, fmt.Errorf("the patch is empty")
+	}
+	for i, op := range r.Patch {
+		co := compiledOperation{Operation: op}
+		switch op.Op {
+		case "add", "replace", "test":
+			if op.ValueExpression == "" && len(op.Value) == 0 {
+				return cr, fmt.Errorf("operation %d '%s' has no value", i, op.Op)
+			}
+		case "move", "copy":
+			if op.From == "" {
+				return cr, fmt.Errorf("operation %d '%s' has no from path", i, op.Op)
+			}
+		case "remove":
+		default:
+			return cr, fmt.Errorf("operation %d has the unsupported op '%s'", i, op.Op)
+		}
+		switch {
+		case op.ValueExpression != "" && len(op.Value) > 0:
+			return cr, fmt.Errorf("operation %d sets both the value and the value expression", i)
+		case op.ValueExpression != "":
+			prg, err := compileExpression(env, op.ValueExpression, nil)
+			if err != nil {
+				return cr, fmt.Errorf("invalid value expression of operation %d: %w", i, err)
+			}
+			co.program = prg
+		}
+		cr.operations = append(cr.operations, co)
+	}
+	return cr, nil
+}
+
+func compileExpression(env *cel.Env, expression string, outputType *cel.Type) (cel.Program, error) {
+	ast, issues := env.Compile(expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, issues.Err()
+	}
+	if outputType != nil && ast.OutputType() != outputType && ast.OutputType() != cel.DynType {
+		return nil, fmt.Errorf("must return a %s, got %s", outputType, ast.OutputType())
+	}
+	return env.Program(ast, cel.CostLimit(costLimit))
+}
+
+// patch returns the JSON patch of the rule for the object, with the value
+// expressions evaluated against it.
+func (r compiledRule) patch(object map[string]any) (jsonpatch.Patch, error) {
+	ops := make([]map[string]any, 0, len(r.operations))
+	for i, op := range r.operations {
+		o := map[string]any{"op": op.Op, "path": op.Path}
+		if op.From != "" {
+			o["from"] = op.From
+		}
+		if len(op.Value) > 0 {
+			o["value"] = op.Value
+		}
+		if op.program != nil {
+			out, _, err := op.program.Eval(map[string]any{"object": object})
+			if err != nil {
+				return nil, fmt.Errorf("value expression of operation %d failed: %w", i, err)
+			}
+			value, err := out.ConvertToNative(reflect.TypeOf(&structpb.Value{}))
+			if err != nil {
+				return nil, fmt.Errorf("value expression of operation %d failed: %w", i, err)
+			}
+			o["value"] = value.(*structpb.Value).AsInterface()
+		}
+		ops = append(ops, o)
+	}
+	data, err := json.Marshal(ops)
+	if err != nil {
+		return nil, err
+	}
+	return jsonpatch.DecodePatch(data)
+}
+
+// Mutate applies the rules, except the skipped ones, to the objects in-place
+// and returns the number of objects mutated by each rule. An error is returned
+// if the evaluation of a rule, or the patch of an object, fails.
+func (m *Mutator) Mutate(objects []*unstructured.Unstructured, skip []string) (map[string]int, error) {
+	mutated := make(map[string]int)
+	if slices.Contains(skip, SkipAll) {
+		return mutated, nil
+	}
+	for _, r := range m.rules {
+		if slices.Contains(skip, r.name) {
+			continue
+		}
+		for _, obj := range objects {
+			if len(r.kinds) > 0 && !slices.Contains(r.kinds, obj.GetKind()) {
+				continue
+			}
+			if !r.selector.Matches(labels.Set(obj.GetLabels())) {
+				continue
+			}
+			if r.condition != nil {
+				out, _, err := r.condition.Eval(map[string]any{"object": obj.Object})
+				if err != nil {
+					return nil, fmt.Errorf("%s: mutation rule '%s': condition failed: %w",
+						ssautil.FmtUnstructured(obj), r.name, err)
+				}
+				if ok, _ := out.Value().(bool); !ok {
+					continue
+				}
+			}
+			if err := r.apply(obj); err != nil {
+				return nil, fmt.Errorf("%s: mutation rule '%s': %w", ssautil.FmtUnstructured(obj), r.name, err)
+			}
+			mutated[r.name]++
+		}
+	}
+	return mutated, nil
+}
+
+// apply patches the object in-place with the rule.
+func (r compiledRule) apply(obj *unstructured.Unstructured) error {
+	patch, err := r.patch(obj.Object)
+	if err != nil {
+		return err
+	}
+	doc, err := json.Marshal(obj.Object)
+	if err != nil {
+		return err
+	}
+	// The missing parents of the added values are created, e.g. to add
+	// an item to a list which may not be set.
+	opts := jsonpatch.NewApplyOptions()
+	opts.EnsurePathExistsOnAdd = true
+	patched, err := patch.ApplyWithOptions(doc, opts)
+	if err != nil {
+		return fmt.Errorf("patch failed: %w", err)
+	}
+	result := map[string]any{}
+	if err := json.Unmarshal(patched, &result); err != nil {
+		return err
+	}
+	obj.Object = result
+	return nil
+}
@@ -0,0 +1,255 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mutationrules
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+func readObjects(t *testing.T, docs string) []*unstructured.Unstructured {
+	t.Helper()
+	var objects []*unstructured.Unstructured
+	for _, doc := range strings.Split(docs, "---\n") {
+		obj := &unstructured.Unstructured{}
+		if err := yaml.Unmarshal([]byte(doc), &obj.Object); err != nil {
+			t.Fatal(err)
+		}
+		objects = append(objects, obj)
+	}
+	return objects
+}
+
+const testObjects = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  namespace: apps
+  labels:
+    tier: frontend
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        image: registry.example.com/app:v1
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: internal
+  namespace: apps
+spec:
+  template:
+    spec:
+      imagePullSecrets:
+      - name: internal
+      containers:
+      - name: internal
+        image: ghcr.io/org/internal:v1
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+  namespace: apps
+`
+
+func TestMutator_Mutate(t *testing.T) {
+	rules := map[string]string{
+		"pull-secrets": `kinds: [Deployment]
+condition: "!has(object.spec.template.spec.imagePullSecrets)"
+patch:
+- op: add
+  path: /spec/template/spec/imagePullSecrets/-
+  value:
+    name: registry
+`,
+		"spread": `kinds: [Deployment]
+selector:
+  matchLabels:
+    tier: frontend
+patch:
+- op: add
+  path: /spec/template/spec/topologySpreadConstraints
+  valueExpression: |
+    [{"maxSkew": 1, "topologyKey": "topology.kubernetes.io/zone", "labelSelector": {"matchLabels": {"app": object.metadata.name}}}]
+`,
+	}
+
+	m, err := Compile(rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		skip        []string
+		wantMutated map[string]int
+		wantSecrets []any
+		wantSpread  bool
+	}{
+		{
+			name:        "applies all the rules",
+			wantMutated: map[string]int{"pull-secrets": 1, "spread": 1},
+			wantSecrets: []any{map[string]any{"name": "registry"}},
+			wantSpread:  true,
+		},
+		{
+			name:        "skips a rule",
+			skip:        []string{"spread"},
+			wantMutated: map[string]int{"pull-secrets": 1},
+			wantSecrets: []any{map[string]any{"name": "registry"}},
+		},
+		{
+			name:        "skips all the rules",
+			skip:        []string{SkipAll},
+			wantMutated: map[string]int{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			objects := readObjects(t, testObjects)
+
+			mutated, err := m.Mutate(objects, tt.skip)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(mutated).To(Equal(tt.wantMutated))
+
+			secrets, _, _ := unstructured.NestedSlice(objects[0].Object, "spec", "template", "spec", "imagePullSecrets")
+			g.Expect(secrets).To(Equal(tt.wantSecrets))
+
+			spread, found, _ := unstructured.NestedSlice(objects[0].Object, "spec", "template", "spec", "topologySpreadConstraints")
+			g.Expect(found).To(Equal(tt.wantSpread))
+			if tt.wantSpread {
+				g.Expect(spread).To(HaveLen(1))
+				g.Expect(spread[0]).To(HaveKeyWithValue("labelSelector",
+					map[string]any{"matchLabels": map[string]any{"app": "app"}}))
+			}
+
+			// The objects not selected by the rules are left unchanged.
+			internal, _, _ := unstructured.NestedSlice(objects[1].Object, "spec", "template", "spec", "imagePullSecrets")
+			g.Expect(internal).To(Equal([]any{map[string]any{"name": "internal"}}))
+			g.Expect(objects[2].Object).NotTo(HaveKey("spec"))
+		})
+	}
+}
+
+func TestMutator_MutateFailure(t *testing.T) {
+	g := NewWithT(t)
+
+	m, err := Compile(map[string]string{
+		"replicas": `kinds: [Deployment]
+patch:
+- op: replace
+  path: /spec/replicas
+  value: 2
+`,
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	_, err = m.Mutate(readObjects(t, testObjects), nil)
+	g.Expect(err).To(MatchError(ContainSubstring("Deployment/apps/app: mutation rule 'replicas': patch failed")))
+}
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    string
+		wantErr string
+	}{
+		{
+			name: "valid",
+			rule: `patch:
+- op: remove
+  path: /metadata/annotations
+`,
+		},
+		{
+			name:    "empty patch",
+			rule:    `kinds: [Deployment]`,
+			wantErr: "the patch is empty",
+		},
+		{
+			name:    "unknown field",
+			rule:    `match: {}`,
+			wantErr: `unknown field "match"`,
+		},
+		{
+			name: "unsupported op",
+			rule: `patch:
+- op: merge
+  path: /spec
+`,
+			wantErr: "unsupported op 'merge'",
+		},
+		{
+			name: "missing value",
+			rule: `patch:
+- op: add
+  path: /spec/replicas
+`,
+			wantErr: "operation 0 'add' has no value",
+		},
+		{
+			name: "value and expression",
+			rule: `patch:
+- op: add
+  path: /spec/replicas
+  value: 1
+  valueExpression: "1"
+`,
+			wantErr: "sets both the value and the value expression",
+		},
+		{
+			name: "condition not bool",
+			rule: `condition: "'true'"
+patch:
+- op: remove
+  path: /spec
+`,
+			wantErr: "invalid condition: must return a bool",
+		},
+		{
+			name: "invalid selector",
+			rule: `selector:
+  matchExpressions:
+  - key: tier
+    operator: Like
+patch:
+- op: remove
+  path: /spec
+`,
+			wantErr: "not a valid label selector operator",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			_, err := Compile(map[string]string{"rule": tt.rule})
+			if tt.wantErr == "" {
+				g.Expect(err).NotTo(HaveOccurred())
+				return
+			}
+			g.Expect(err).To(MatchError(And(ContainSubstring("invalid mutation rule 'rule'"), ContainSubstring(tt.wantErr))))
+		})
+	}
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package nsreport summarizes the status of the Kustomizations of a
+// namespace in a KustomizationReport, for the tenants to watch a single
+// object.
+package nsreport
+
+import (
+	"sort"
+
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/fluxcd/pkg/apis/meta"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+)
+
+// Summarize returns the report status of the Kustomizations, sorted by
+// name. The Kustomizations under deletion are excluded.
+func Summarize(items []kustomizev1.Kustomization) kustomizev1.KustomizationReportStatus {
+	var status kustomizev1.KustomizationReportStatus
+	for _, obj := range items {
+		if !obj.GetDeletionTimestamp().IsZero() {
+			continue
+		}
+
+		summary := kustomizev1.KustomizationSummary{
+			Name:                obj.GetName(),
+			Ready:               metav1.ConditionUnknown,
+			Suspended:           obj.Spec.Suspend,
+			LastAppliedRevision: obj.Status.LastAppliedRevision,
+		}
+		if ready := apimeta.FindStatusCondition(obj.Status.Conditions, meta.ReadyCondition); ready != nil {
+			summary.Ready = ready.Status
+			summary.Reason = ready.Reason
+		}
+		status.Kustomizations = append(status.Kustomizations, summary)
+	}
+	sort.Slice(status.Kustomizations, func(i, j int) bool {
+		return status.Kustomizations[i].Name < status.Kustomizations[j].Name
+	})
+
+	for _, summary := range status.Kustomizations {
+		status.Total++
+		switch summary.Ready {
+		case metav1.ConditionTrue:
+			status.Ready++
+		case metav1.ConditionFalse:
+			status.Failing++
+			status.FailingNames = append(status.FailingNames, summary.Name)
+		}
+		if summary.Suspended {
+			status.Suspended++
+		}
+	}
+	return status
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nsreport
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/fluxcd/pkg/apis/meta"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+)
+
+func kustomization(name string, ready metav1.ConditionStatus, reason string, suspended bool) kustomizev1.Kustomization {
+	obj := kustomizev1.Kustomization{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "apps"},
+		Spec:       kustomizev1.KustomizationSpec{Suspend: suspended},
+		Status:     kustomizev1.KustomizationStatus{LastAppliedRevision: "main@sha1:" + name},
+	}
+	if ready != "" {
+		obj.Status.Conditions = []metav1.Condition{{Type: meta.ReadyCondition, Status: ready, Reason: reason}}
+	}
+	return obj
+}
+
+func TestSummarize(t *testing.T) {
+	g := NewWithT(t)
+
+	deleted := kustomization("deleted", metav1.ConditionFalse, "PruneFailed", false)
+	deleted.DeletionTimestamp = &metav1.Time{Time: metav1.Now().Time}
+
+	status := Summarize([]kustomizev1.Kustomization{
+		kustomization("frontend", metav1.ConditionFalse, "HealthCheckFailed", false),
+		kustomization("backend", metav1.ConditionTrue, "ReconciliationSucceeded", false),
+		kustomization("new", "", "", false),
+		kustomization("legacy", metav1.ConditionFalse, "BuildFailed", true),
+		deleted,
+	})
+
+	g.Expect(status.Total).To(Equal(4))
+	g.Expect(status.Ready).To(Equal(1))
+	g.Expect(status.Failing).To(Equal(2))
+	g.Expect(status.Suspended).To(Equal(1))
+	g.Expect(status.FailingNames).To(Equal([]string{"frontend", "legacy"}))
+	g.Expect(status.Kustomizations).To(Equal([]kustomizev1.KustomizationSummary{
+		{Name: "backend", Ready: metav1.ConditionTrue, Reason: "ReconciliationSucceeded", LastAppliedRevision: "main@sha1:backend"},
+		{Name: "frontend", Ready: metav1.ConditionFalse, Reason: "HealthCheckFailed", LastAppliedRevision: "main@sha1:frontend"},
+		{Name: "legacy", Ready: metav1.ConditionFalse, Reason: "BuildFailed", Suspended: true, LastAppliedRevision: "main@sha1:legacy"},
+		{Name: "new", Ready: metav1.ConditionUnknown, LastAppliedRevision: "main@sha1:new"},
+	}))
+}
+
+func TestSummarize_Empty(t *testing.T) {
+	g := NewWithT(t)
+
+	status := Summarize(nil)
+	g.Expect(status).To(Equal(kustomizev1.KustomizationReportStatus{}))
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package openapi loads the OpenAPI schemas used by kustomize to patch
+// custom resources with strategic merge patches.
+package openapi
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+// DefaultConfigMapKey is the ConfigMap data key holding the schema,
+// when no key is specified.
+const DefaultConfigMapKey = "schema.json"
+
+// FromConfigMap returns the OpenAPI schema stored under the given key of
+// the referenced ConfigMap.
+func FromConfigMap(ctx context.Context, c client.Client, name types.NamespacedName, key string) ([]byte, error) {
+	if key == "" {
+		key = DefaultConfigMapKey
+	}
+
+	var cm corev1.ConfigMap
+	if err := c.Get(ctx, name, &cm); err != nil {
+		return nil, fmt.Errorf("unable to read OpenAPI schema ConfigMap '%s': %w", name, err)
+	}
+
+	var data []byte
+	if v, ok := cm.Data[key]; ok {
+		data = []byte(v)
+	} else if v, ok := cm.BinaryData[key]; ok {
+		data = v
+	} else {
+		return nil, fmt.Errorf("OpenAPI schema ConfigMap '%s' does not contain a '%s' key", name, key)
+	}
+
+	if err := validate(data); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI schema in ConfigMap '%s': %w", name, err)
+	}
+	return data, nil
+}
+
+// ClusterCache fetches the OpenAPI v2 schemas served by Kubernetes clusters,
+// and caches them for a period of time as they are expensive to retrieve.
+type ClusterCache struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	schema    []byte
+	expiresAt time.Time
+}
+
+// NewClusterCache returns a ClusterCache which keeps the schemas
+// for the given duration.
+func NewClusterCache(ttl time.Duration) *ClusterCache {
+	return &ClusterCache{
+		ttl:     ttl,
+		entries: make(map[string]cacheEntry),
+	}
+}
+
+// Get returns the OpenAPI v2 schema of the cluster the config points at,
+// fetching it if not cached or expired.
+func (c *ClusterCache) Get(ctx context.Context, cfg *rest.Config) ([]byte, error) {
+	key := cfg.Host
+
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok && time.Now().Before(entry.expiresAt) {
+		return entry.schema, nil
+	}
+
+	schema, err := fetch(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.entries[key] = cacheEntry{schema: schema, expiresAt: time.Now().Add(c.ttl)}
+	c.mu.Unlock()
+	return schema, nil
+}
+
+// fetch downloads the OpenAPI v2 schema in JSON format from the cluster.
+func fetch(ctx context.Context, cfg *rest.Config) ([]byte, error) {
+	dc, err := discovery.NewDiscoveryClientForConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := dc.RESTClient().Get().
+		AbsPath("/openapi/v2").
+		SetHeader("Accept", "application/json").
+		Do(ctx).
+		Raw()
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch the OpenAPI schema from the cluster: %w", err)
+	}
+
+	if err := validate(data); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI schema served by the cluster: %w", err)
+	}
+	return data, nil
+}
+
+// validate checks that the data is a JSON or YAML document holding
+// OpenAPI definitions, as expected by kustomize.
+func validate(data []byte) error {
+	var doc struct {
+		Definitions map[string]interface{} `json:"definitions"`
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	if len(doc.Definitions) == 0 {
+		return fmt.Errorf("no definitions found")
+	}
+	return nil
+}
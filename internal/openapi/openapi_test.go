@@ -0,0 +1,111 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const testSchema = `{"definitions":{"io.example.v1.App":{"type":"object"}}}`
+
+func Test_FromConfigMap(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "schemas", Namespace: "default"},
+		Data: map[string]string{
+			DefaultConfigMapKey: testSchema,
+			"crds.yaml":         "definitions:\n  io.example.v1.App:\n    type: object\n",
+			"invalid.json":      `{"swagger":"2.0"}`,
+		},
+	}
+	c := fake.NewClientBuilder().WithObjects(cm).Build()
+	name := types.NamespacedName{Name: "schemas", Namespace: "default"}
+
+	tests := []struct {
+		name    string
+		key     string
+		wantErr string
+	}{
+		{name: "default key", key: ""},
+		{name: "yaml schema", key: "crds.yaml"},
+		{name: "missing key", key: "missing.json", wantErr: "does not contain a 'missing.json' key"},
+		{name: "no definitions", key: "invalid.json", wantErr: "no definitions found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			data, err := FromConfigMap(context.TODO(), c, name, tt.key)
+			if tt.wantErr != "" {
+				g.Expect(err).To(HaveOccurred())
+				g.Expect(err.Error()).To(ContainSubstring(tt.wantErr))
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(data).ToNot(BeEmpty())
+		})
+	}
+
+	t.Run("missing ConfigMap", func(t *testing.T) {
+		g := NewWithT(t)
+		_, err := FromConfigMap(context.TODO(), c, types.NamespacedName{Name: "missing", Namespace: "default"}, "")
+		g.Expect(err).To(HaveOccurred())
+	})
+}
+
+func Test_ClusterCache(t *testing.T) {
+	g := NewWithT(t)
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/openapi/v2" {
+			http.NotFound(w, r)
+			return
+		}
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(testSchema))
+	}))
+	defer server.Close()
+
+	cfg := &rest.Config{Host: server.URL}
+
+	cache := NewClusterCache(time.Hour)
+	data, err := cache.Get(context.TODO(), cfg)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(string(data)).To(Equal(testSchema))
+
+	_, err = cache.Get(context.TODO(), cfg)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(requests).To(Equal(1))
+
+	expired := NewClusterCache(0)
+	_, err = expired.Get(context.TODO(), cfg)
+	g.Expect(err).ToNot(HaveOccurred())
+	_, err = expired.Get(context.TODO(), cfg)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(requests).To(Equal(3))
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package owners looks up the Kustomizations managing an object, from the
+// index of the Kustomizations by the objects of their inventory.
+package owners
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/fluxcd/cli-utils/pkg/object"
+	"github.com/fluxcd/pkg/runtime/conditions"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+)
+
+const (
+	// Path is the path the lookups are served at.
+	Path = "/debug/owners"
+
+	// IndexKey is the index of the Kustomizations by the objects of their
+	// inventory, in the inventory ID format.
+	IndexKey = ".status.inventory.entries"
+)
+
+// Owner describes a Kustomization managing the object.
+type Owner struct {
+	Name                string `json:"name"`
+	Namespace           string `json:"namespace"`
+	Ready               bool   `json:"ready"`
+	LastAppliedRevision string `json:"lastAppliedRevision,omitempty"`
+}
+
+// Lookup returns the Kustomizations whose inventory contains the object,
+// sorted by namespace and name.
+func Lookup(ctx context.Context, reader client.Reader, obj object.ObjMetadata) ([]Owner, error) {
+	var list kustomizev1.KustomizationList
+	if err := reader.List(ctx, &list, client.MatchingFields{IndexKey: obj.String()}); err != nil {
+		return nil, err
+	}
+	owners := make([]Owner, 0, len(list.Items))
+	for i := range list.Items {
+		k := &list.Items[i]
+		owners = append(owners, Owner{
+			Name:                k.GetName(),
+			Namespace:           k.GetNamespace(),
+			Ready:               conditions.IsReady(k),
+			LastAppliedRevision: k.Status.LastAppliedRevision,
+		})
+	}
+	sort.Slice(owners, func(i, j int) bool {
+		if owners[i].Namespace != owners[j].Namespace {
+			return owners[i].Namespace < owners[j].Namespace
+		}
+		return owners[i].Name < owners[j].Name
+	})
+	return owners, nil
+}
+
+// Options configures the lookups.
+type Options struct {
+	// Reader reads the Kustomizations from the cache of the controller,
+	// the lookups fail if it's nil.
+	Reader client.Reader
+}
+
+// Handler returns an HTTP handler serving as JSON the Kustomizations
+// managing the object identified by the 'apiVersion', 'kind', 'namespace'
+// and 'name' query parameters. The options are read on each request, which
+// allows to set the Reader once the manager serving the handler is created.
+func Handler(opts *Options) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", "GET")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if opts.Reader == nil {
+			http.Error(w, "the cache is not ready", http.StatusServiceUnavailable)
+			return
+		}
+
+		query := r.URL.Query()
+		gv, err := schema.ParseGroupVersion(query.Get("apiVersion"))
+		if err != nil || query.Get("kind") == "" || query.Get("name") == "" {
+			http.Error(w, "the 'apiVersion', 'kind' and 'name' query parameters are required", http.StatusBadRequest)
+			return
+		}
+		obj := object.ObjMetadata{
+			GroupKind: schema.GroupKind{Group: gv.Group, Kind: query.Get("kind")},
+			Namespace: query.Get("namespace"),
+			Name:      query.Get("name"),
+		}
+
+		owners, err := Lookup(r.Context(), opts.Reader, obj)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to look up the owners: %s", err), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(owners)
+	})
+}
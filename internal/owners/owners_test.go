@@ -0,0 +1,129 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package owners
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+)
+
+func kustomization(namespace, name string, ready bool, ids ...string) *kustomizev1.Kustomization {
+	k := &kustomizev1.Kustomization{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Status: kustomizev1.KustomizationStatus{
+			LastAppliedRevision: "main@sha1:1234",
+			Inventory:           &kustomizev1.ResourceInventory{},
+		},
+	}
+	status := metav1.ConditionFalse
+	if ready {
+		status = metav1.ConditionTrue
+	}
+	k.Status.Conditions = []metav1.Condition{{Type: meta.ReadyCondition, Status: status}}
+	for _, id := range ids {
+		k.Status.Inventory.Entries = append(k.Status.Inventory.Entries, kustomizev1.ResourceRef{ID: id, Version: "v1"})
+	}
+	return k
+}
+
+func TestHandler(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = kustomizev1.AddToScheme(scheme)
+	reader := fake.NewClientBuilder().WithScheme(scheme).
+		WithObjects(
+			kustomization("flux-system", "infra", false, "apps_web_apps_Deployment"),
+			kustomization("flux-system", "apps", true, "apps_web_apps_Deployment", "apps_config__ConfigMap"),
+			kustomization("tenants", "apps", true),
+		).
+		WithIndex(&kustomizev1.Kustomization{}, IndexKey, func(o client.Object) []string {
+			var keys []string
+			for _, e := range o.(*kustomizev1.Kustomization).Status.Inventory.Entries {
+				keys = append(keys, e.ID)
+			}
+			return keys
+		}).Build()
+
+	tests := []struct {
+		name   string
+		query  string
+		code   int
+		owners []Owner
+	}{
+		{
+			name:  "object with two owners",
+			query: "apiVersion=apps/v1&kind=Deployment&namespace=apps&name=web",
+			code:  http.StatusOK,
+			owners: []Owner{
+				{Name: "apps", Namespace: "flux-system", Ready: true, LastAppliedRevision: "main@sha1:1234"},
+				{Name: "infra", Namespace: "flux-system", LastAppliedRevision: "main@sha1:1234"},
+			},
+		},
+		{
+			name:  "core object",
+			query: "apiVersion=v1&kind=ConfigMap&namespace=apps&name=config",
+			code:  http.StatusOK,
+			owners: []Owner{
+				{Name: "apps", Namespace: "flux-system", Ready: true, LastAppliedRevision: "main@sha1:1234"},
+			},
+		},
+		{
+			name:   "unmanaged object",
+			query:  "apiVersion=v1&kind=ConfigMap&namespace=apps&name=other",
+			code:   http.StatusOK,
+			owners: []Owner{},
+		},
+		{
+			name:  "missing name",
+			query: "apiVersion=v1&kind=ConfigMap",
+			code:  http.StatusBadRequest,
+		},
+	}
+
+	handler := Handler(&Options{Reader: reader})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, Path+"?"+tt.query, nil))
+			g.Expect(rec.Code).To(Equal(tt.code))
+			if tt.owners == nil {
+				return
+			}
+			var owners []Owner
+			g.Expect(json.Unmarshal(rec.Body.Bytes(), &owners)).To(Succeed())
+			g.Expect(owners).To(Equal(tt.owners))
+		})
+	}
+
+	t.Run("cache not ready", func(t *testing.T) {
+		g := NewWithT(t)
+		rec := httptest.NewRecorder()
+		Handler(&Options{}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, Path, nil))
+		g.Expect(rec.Code).To(Equal(http.StatusServiceUnavailable))
+	})
+}
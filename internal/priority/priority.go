@@ -0,0 +1,193 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package priority orders the reconciliations of the Kustomizations by
+// dependencies and priority when the controller starts, and by priority when
+// a source change enqueues many of them at once. The work queue is processed in FIFO order, so the
+// Kustomizations enqueued first are reconciled first.
+package priority
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"sort"
+	"sync/atomic"
+
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+)
+
+// Order returns the keys of the Kustomizations in dependency order, the
+// dependencies first, then the highest priority first, then sorted by
+// namespace and name. The dependencies which are not part of the list are
+// ignored, and the Kustomizations of a dependency cycle are ordered last.
+func Order(objects []kustomizev1.Kustomization) []types.NamespacedName {
+	nodes := make(map[types.NamespacedName]*node, len(objects))
+	for i := range objects {
+		nodes[client.ObjectKeyFromObject(&objects[i])] = &node{obj: &objects[i]}
+	}
+	for key, n := range nodes {
+		for _, dep := range n.obj.GetDependsOn() {
+			d := types.NamespacedName{Namespace: dep.Namespace, Name: dep.Name}
+			if d.Namespace == "" {
+				d.Namespace = key.Namespace
+			}
+			if dn, ok := nodes[d]; ok && d != key {
+				n.waiting++
+				dn.dependents = append(dn.dependents, n)
+			}
+		}
+	}
+
+	ready := &nodeHeap{}
+	for _, n := range nodes {
+		if n.waiting == 0 {
+			heap.Push(ready, n)
+		}
+	}
+	keys := make([]types.NamespacedName, 0, len(objects))
+	for ready.Len() > 0 {
+		n := heap.Pop(ready).(*node)
+		n.done = true
+		keys = append(keys, client.ObjectKeyFromObject(n.obj))
+		for _, dependent := range n.dependents {
+			dependent.waiting--
+			if dependent.waiting == 0 {
+				heap.Push(ready, dependent)
+			}
+		}
+	}
+
+	if len(keys) < len(nodes) {
+		cycles := &nodeHeap{}
+		for _, n := range nodes {
+			if !n.done {
+				*cycles = append(*cycles, n)
+			}
+		}
+		sort.Sort(cycles)
+		for _, n := range *cycles {
+			keys = append(keys, client.ObjectKeyFromObject(n.obj))
+		}
+	}
+	return keys
+}
+
+type node struct {
+	obj        *kustomizev1.Kustomization
+	dependents []*node
+	waiting    int
+	done       bool
+}
+
+// nodeHeap orders the nodes by priority, then by namespace and name.
+type nodeHeap []*node
+
+func (h nodeHeap) Len() int { return len(h) }
+
+func (h nodeHeap) Less(i, j int) bool {
+	a, b := h[i].obj, h[j].obj
+	if a.Spec.Priority != b.Spec.Priority {
+		return a.Spec.Priority > b.Spec.Priority
+	}
+	if a.Namespace != b.Namespace {
+		return a.Namespace < b.Namespace
+	}
+	return a.Name < b.Name
+}
+
+func (h nodeHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *nodeHeap) Push(x any) { *h = append(*h, x.(*node)) }
+
+func (h *nodeHeap) Pop() any {
+	old := *h
+	n := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return n
+}
+
+// SortRequests sorts the requests by the priority of the Kustomizations,
+// the highest first, keeping the order of the requests of the same priority.
+// The requests for the Kustomizations not in the list have the default
+// priority.
+func SortRequests(reqs []reconcile.Request, objects []kustomizev1.Kustomization) {
+	priorities := make(map[types.NamespacedName]int32, len(objects))
+	for i := range objects {
+		priorities[client.ObjectKeyFromObject(&objects[i])] = objects[i].Spec.Priority
+	}
+	sort.SliceStable(reqs, func(i, j int) bool {
+		return priorities[reqs[i].NamespacedName] > priorities[reqs[j].NamespacedName]
+	})
+}
+
+// Gate holds back the creation events of the Kustomizations while the
+// existing ones are enqueued in priority order by Run.
+type Gate struct {
+	open atomic.Bool
+}
+
+// Predicate filters out the creation events until the gate is open.
+func (g *Gate) Predicate() predicate.Predicate {
+	return predicate.Funcs{
+		CreateFunc: func(event.CreateEvent) bool {
+			return g.open.Load()
+		},
+	}
+}
+
+// Run lists the Kustomizations from the reader, which must be synced,
+// enqueues them in the given order, and opens the gate. The Kustomizations
+// created while the gate was closed, and not listed, are enqueued last.
+func (g *Gate) Run(ctx context.Context, reader client.Reader,
+	order func([]kustomizev1.Kustomization) []types.NamespacedName,
+	enqueue func(context.Context, types.NamespacedName) error) error {
+	// Never hold back the creation events for good.
+	defer g.open.Store(true)
+
+	var list kustomizev1.KustomizationList
+	if err := reader.List(ctx, &list); err != nil {
+		return fmt.Errorf("failed to list Kustomizations: %w", err)
+	}
+	enqueued := make(map[types.NamespacedName]struct{}, len(list.Items))
+	for _, key := range order(list.Items) {
+		if err := enqueue(ctx, key); err != nil {
+			return err
+		}
+		enqueued[key] = struct{}{}
+	}
+
+	g.open.Store(true)
+
+	if err := reader.List(ctx, &list); err != nil {
+		return fmt.Errorf("failed to list Kustomizations: %w", err)
+	}
+	for _, key := range order(list.Items) {
+		if _, ok := enqueued[key]; ok {
+			continue
+		}
+		if err := enqueue(ctx, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
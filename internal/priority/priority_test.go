@@ -0,0 +1,137 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package priority
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+)
+
+func newKustomization(namespace, name string, priority int32) kustomizev1.Kustomization {
+	obj := kustomizev1.Kustomization{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+	}
+	obj.Spec.Priority = priority
+	return obj
+}
+
+func TestOrder(t *testing.T) {
+	g := NewWithT(t)
+
+	keys := Order([]kustomizev1.Kustomization{
+		newKustomization("apps", "frontend", 0),
+		newKustomization("flux-system", "infra", 100),
+		newKustomization("apps", "backend", 0),
+		newKustomization("tools", "dashboards", -10),
+	})
+	g.Expect(keys).To(Equal([]types.NamespacedName{
+		{Namespace: "flux-system", Name: "infra"},
+		{Namespace: "apps", Name: "backend"},
+		{Namespace: "apps", Name: "frontend"},
+		{Namespace: "tools", Name: "dashboards"},
+	}))
+}
+
+func TestOrder_Dependencies(t *testing.T) {
+	g := NewWithT(t)
+
+	apps := newKustomization("flux-system", "apps", 100)
+	apps.Spec.DependsOn = []meta.NamespacedObjectReference{{Name: "infra-configs"}, {Name: "not-listed"}}
+	configs := newKustomization("flux-system", "infra-configs", 0)
+	configs.Spec.DependsOn = []meta.NamespacedObjectReference{{Name: "infra-controllers"}}
+	cycleA := newKustomization("tenants", "a", 0)
+	cycleA.Spec.DependsOn = []meta.NamespacedObjectReference{{Name: "b"}}
+	cycleB := newKustomization("tenants", "b", 0)
+	cycleB.Spec.DependsOn = []meta.NamespacedObjectReference{{Name: "a"}}
+
+	keys := Order([]kustomizev1.Kustomization{
+		apps,
+		cycleB,
+		configs,
+		newKustomization("flux-system", "infra-controllers", -1),
+		cycleA,
+		newKustomization("apps", "frontend", 0),
+	})
+	g.Expect(keys).To(Equal([]types.NamespacedName{
+		{Namespace: "apps", Name: "frontend"},
+		{Namespace: "flux-system", Name: "infra-controllers"},
+		{Namespace: "flux-system", Name: "infra-configs"},
+		{Namespace: "flux-system", Name: "apps"},
+		{Namespace: "tenants", Name: "a"},
+		{Namespace: "tenants", Name: "b"},
+	}))
+}
+
+func TestSortRequests(t *testing.T) {
+	g := NewWithT(t)
+
+	reqs := []reconcile.Request{
+		{NamespacedName: types.NamespacedName{Namespace: "apps", Name: "frontend"}},
+		{NamespacedName: types.NamespacedName{Namespace: "apps", Name: "backend"}},
+		{NamespacedName: types.NamespacedName{Namespace: "flux-system", Name: "not-listed"}},
+		{NamespacedName: types.NamespacedName{Namespace: "flux-system", Name: "infra"}},
+	}
+	SortRequests(reqs, []kustomizev1.Kustomization{
+		newKustomization("apps", "frontend", 0),
+		newKustomization("apps", "backend", -1),
+		newKustomization("flux-system", "infra", 10),
+	})
+	g.Expect(reqs).To(Equal([]reconcile.Request{
+		{NamespacedName: types.NamespacedName{Namespace: "flux-system", Name: "infra"}},
+		{NamespacedName: types.NamespacedName{Namespace: "apps", Name: "frontend"}},
+		{NamespacedName: types.NamespacedName{Namespace: "flux-system", Name: "not-listed"}},
+		{NamespacedName: types.NamespacedName{Namespace: "apps", Name: "backend"}},
+	}))
+}
+
+func TestGate(t *testing.T) {
+	g := NewWithT(t)
+
+	scheme := runtime.NewScheme()
+	g.Expect(kustomizev1.AddToScheme(scheme)).To(Succeed())
+	low := newKustomization("apps", "low", -1)
+	high := newKustomization("apps", "high", 1)
+	reader := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&low, &high).Build()
+
+	gate := &Gate{}
+	created := event.CreateEvent{Object: &low}
+	g.Expect(gate.Predicate().Create(created)).To(BeFalse())
+	g.Expect(gate.Predicate().Update(event.UpdateEvent{ObjectOld: &low, ObjectNew: &low})).To(BeTrue())
+
+	var enqueued []types.NamespacedName
+	err := gate.Run(context.Background(), reader, Order, func(_ context.Context, key types.NamespacedName) error {
+		enqueued = append(enqueued, key)
+		return nil
+	})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(enqueued).To(Equal([]types.NamespacedName{
+		{Namespace: "apps", Name: "high"},
+		{Namespace: "apps", Name: "low"},
+	}))
+	g.Expect(gate.Predicate().Create(created)).To(BeTrue())
+}
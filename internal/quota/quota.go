@@ -0,0 +1,154 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package quota checks the object count quotas of the target namespaces
+// before the objects are applied, to fail early instead of in the middle
+// of the apply when the API server rejects the creation of an object.
+package quota
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// legacyResources are the core resources whose object count can be
+// limited without the 'count/' prefix.
+var legacyResources = map[string]bool{
+	"configmaps":             true,
+	"persistentvolumeclaims": true,
+	"pods":                   true,
+	"replicationcontrollers": true,
+	"resourcequotas":         true,
+	"secrets":                true,
+	"services":               true,
+}
+
+// Check returns the object count quotas which would be exceeded by the
+// creation of the given objects, e.g.
+// 'apps/objects: count/deployments.apps would be 12, limit is 10'.
+//
+// Only the objects which don't exist in-cluster are counted. The quotas
+// with scopes, which only apply to pods, and the namespaces whose quotas
+// can't be listed with the client permissions are skipped, as are the
+// objects whose kind is unknown to the mapper.
+func Check(ctx context.Context, c client.Client, objects []*unstructured.Unstructured) ([]string, error) {
+	byNamespace := make(map[string][]*unstructured.Unstructured)
+	for _, u := range objects {
+		if ns := u.GetNamespace(); ns != "" {
+			byNamespace[ns] = append(byNamespace[ns], u)
+		}
+	}
+	namespaces := make([]string, 0, len(byNamespace))
+	for ns := range byNamespace {
+		namespaces = append(namespaces, ns)
+	}
+	sort.Strings(namespaces)
+
+	var exceeded []string
+	for _, ns := range namespaces {
+		quotas := &corev1.ResourceQuotaList{}
+		if err := c.List(ctx, quotas, client.InNamespace(ns)); err != nil {
+			if apierrors.IsForbidden(err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to list the resource quotas in namespace '%s': %w", ns, err)
+		}
+		if len(quotas.Items) == 0 {
+			continue
+		}
+
+		counts, err := countNew(ctx, c, byNamespace[ns])
+		if err != nil {
+			return nil, err
+		}
+
+		sort.Slice(quotas.Items, func(i, j int) bool { return quotas.Items[i].Name < quotas.Items[j].Name })
+		for _, q := range quotas.Items {
+			if len(q.Spec.Scopes) > 0 || q.Spec.ScopeSelector != nil {
+				continue
+			}
+			names := make([]string, 0, len(q.Spec.Hard))
+			for name := range q.Spec.Hard {
+				names = append(names, string(name))
+			}
+			sort.Strings(names)
+
+			for _, name := range names {
+				n, ok := counts[name]
+				if !ok {
+					continue
+				}
+				hard := q.Spec.Hard[corev1.ResourceName(name)]
+				used := q.Status.Used[corev1.ResourceName(name)]
+				if total := used.Value() + n; total > hard.Value() {
+					exceeded = append(exceeded, fmt.Sprintf("%s/%s: %s would be %d, limit is %d",
+						ns, q.Name, name, total, hard.Value()))
+				}
+			}
+		}
+	}
+	return exceeded, nil
+}
+
+// countNew returns the number of objects which don't exist in-cluster,
+// indexed by the quota resource names counting them.
+func countNew(ctx context.Context, c client.Client, objects []*unstructured.Unstructured) (map[string]int64, error) {
+	counts := make(map[string]int64)
+	for _, u := range objects {
+		gvk := u.GroupVersionKind()
+		mapping, err := c.RESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version)
+		if err != nil {
+			if meta.IsNoMatchError(err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to get the resource of %s/%s/%s: %w", gvk.Kind, u.GetNamespace(), u.GetName(), err)
+		}
+
+		existing := &metav1.PartialObjectMetadata{}
+		existing.SetGroupVersionKind(gvk)
+		err = c.Get(ctx, client.ObjectKeyFromObject(u), existing)
+		if err == nil {
+			continue
+		}
+		if !apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("failed to get %s/%s/%s: %w", gvk.Kind, u.GetNamespace(), u.GetName(), err)
+		}
+
+		resource := mapping.Resource.Resource
+		if group := mapping.Resource.Group; group != "" {
+			counts["count/"+resource+"."+group]++
+			continue
+		}
+		counts["count/"+resource]++
+		if legacyResources[resource] {
+			counts[resource]++
+		}
+		if resource == "services" {
+			if t, _, _ := unstructured.NestedString(u.Object, "spec", "type"); t == string(corev1.ServiceTypeLoadBalancer) {
+				counts[string(corev1.ResourceServicesLoadBalancers)]++
+			}
+		}
+	}
+	return counts, nil
+}
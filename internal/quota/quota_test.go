@@ -0,0 +1,99 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package quota
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newObject(apiVersion, kind, namespace, name string) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(schema.FromAPIVersionAndKind(apiVersion, kind))
+	u.SetNamespace(namespace)
+	u.SetName(name)
+	return u
+}
+
+func TestCheck(t *testing.T) {
+	g := NewWithT(t)
+
+	objects := &corev1.ResourceQuota{
+		ObjectMeta: metav1.ObjectMeta{Name: "objects", Namespace: "apps"},
+		Spec: corev1.ResourceQuotaSpec{Hard: corev1.ResourceList{
+			"count/deployments.apps": resource.MustParse("2"),
+			"configmaps":             resource.MustParse("3"),
+			"services.loadbalancers": resource.MustParse("0"),
+		}},
+		Status: corev1.ResourceQuotaStatus{Used: corev1.ResourceList{
+			"count/deployments.apps": resource.MustParse("1"),
+			"configmaps":             resource.MustParse("2"),
+		}},
+	}
+	scoped := &corev1.ResourceQuota{
+		ObjectMeta: metav1.ObjectMeta{Name: "scoped", Namespace: "apps"},
+		Spec: corev1.ResourceQuotaSpec{
+			Hard:   corev1.ResourceList{"count/deployments.apps": resource.MustParse("0")},
+			Scopes: []corev1.ResourceQuotaScope{corev1.ResourceQuotaScopeBestEffort},
+		},
+	}
+	existing := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "existing", Namespace: "apps"}}
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}, meta.RESTScopeNamespace)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, meta.RESTScopeNamespace)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "Service"}, meta.RESTScopeNamespace)
+	c := fake.NewClientBuilder().WithRESTMapper(mapper).WithObjects(objects, scoped, existing).Build()
+
+	lb := newObject("v1", "Service", "apps", "lb")
+	g.Expect(unstructured.SetNestedField(lb.Object, "LoadBalancer", "spec", "type")).To(Succeed())
+
+	t.Run("reports the exceeded quotas", func(t *testing.T) {
+		g := NewWithT(t)
+		exceeded, err := Check(context.Background(), c, []*unstructured.Unstructured{
+			newObject("apps/v1", "Deployment", "apps", "web"),
+			newObject("apps/v1", "Deployment", "apps", "api"),
+			newObject("v1", "ConfigMap", "apps", "existing"),
+			newObject("v1", "ConfigMap", "apps", "new"),
+			newObject("v1", "ConfigMap", "other", "new"),
+			lb,
+		})
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(exceeded).To(Equal([]string{
+			"apps/objects: count/deployments.apps would be 3, limit is 2",
+			"apps/objects: services.loadbalancers would be 1, limit is 0",
+		}))
+	})
+
+	t.Run("passes within the limits", func(t *testing.T) {
+		g := NewWithT(t)
+		exceeded, err := Check(context.Background(), c, []*unstructured.Unstructured{
+			newObject("apps/v1", "Deployment", "apps", "web"),
+			newObject("v1", "ConfigMap", "apps", "new"),
+		})
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(exceeded).To(BeEmpty())
+	})
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package reconciliationgate finds the closed ReconciliationGates selecting
+// a Kustomization, whose applies are paused until the gates are opened.
+package reconciliationgate
+
+import (
+	"fmt"
+	"sort"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+)
+
+// ClosedError is returned for the applies paused by a closed gate.
+type ClosedError struct {
+	// Gate is the name of the closed ReconciliationGate.
+	Gate string
+
+	// Message is the message of the gate.
+	Message string
+}
+
+func (e *ClosedError) Error() string {
+	msg := fmt.Sprintf("apply paused by the closed ReconciliationGate '%s'", e.Gate)
+	if e.Message != "" {
+		msg = fmt.Sprintf("%s: %s", msg, e.Message)
+	}
+	return msg
+}
+
+// Selects returns true if the selector of the gate matches the given labels.
+func Selects(gate kustomizev1.ReconciliationGate, objLabels map[string]string) (bool, error) {
+	selector, err := metav1.LabelSelectorAsSelector(&gate.Spec.Selector)
+	if err != nil {
+		return false, fmt.Errorf("invalid selector of the ReconciliationGate '%s': %w", gate.GetName(), err)
+	}
+	return selector.Matches(labels.Set(objLabels)), nil
+}
+
+// Check returns a ClosedError for the first closed gate, by name, which
+// selects the given labels. The gates with an invalid selector are
+// considered closed, so that a typo doesn't release a freeze.
+func Check(gates []kustomizev1.ReconciliationGate, objLabels map[string]string) error {
+	sorted := make([]kustomizev1.ReconciliationGate, 0, len(gates))
+	for _, gate := range gates {
+		if gate.Spec.Closed {
+			sorted = append(sorted, gate)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].GetName() < sorted[j].GetName()
+	})
+
+	for _, gate := range sorted {
+		ok, err := Selects(gate, objLabels)
+		if err != nil {
+			return &ClosedError{Gate: gate.GetName(), Message: err.Error()}
+		}
+		if ok {
+			return &ClosedError{Gate: gate.GetName(), Message: gate.Spec.Message}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciliationgate
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+)
+
+func gate(name string, closed bool, selector metav1.LabelSelector, message string) kustomizev1.ReconciliationGate {
+	return kustomizev1.ReconciliationGate{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: kustomizev1.ReconciliationGateSpec{
+			Closed:   closed,
+			Selector: selector,
+			Message:  message,
+		},
+	}
+}
+
+func TestCheck(t *testing.T) {
+	prod := metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}}
+	invalid := metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+		{Key: "env", Operator: "Unknown"},
+	}}
+
+	tests := []struct {
+		name    string
+		gates   []kustomizev1.ReconciliationGate
+		labels  map[string]string
+		wantErr string
+	}{
+		{
+			name:   "no gates",
+			labels: map[string]string{"env": "prod"},
+		},
+		{
+			name:   "open gate",
+			gates:  []kustomizev1.ReconciliationGate{gate("freeze", false, prod, "")},
+			labels: map[string]string{"env": "prod"},
+		},
+		{
+			name:   "closed gate not selecting the labels",
+			gates:  []kustomizev1.ReconciliationGate{gate("freeze", true, prod, "")},
+			labels: map[string]string{"env": "dev"},
+		},
+		{
+			name:    "closed gate selecting the labels",
+			gates:   []kustomizev1.ReconciliationGate{gate("freeze", true, prod, "INC-42")},
+			labels:  map[string]string{"env": "prod"},
+			wantErr: "apply paused by the closed ReconciliationGate 'freeze': INC-42",
+		},
+		{
+			name:    "closed gate with an empty selector",
+			gates:   []kustomizev1.ReconciliationGate{gate("all", true, metav1.LabelSelector{}, "")},
+			wantErr: "apply paused by the closed ReconciliationGate 'all'",
+		},
+		{
+			name: "first closed gate by name",
+			gates: []kustomizev1.ReconciliationGate{
+				gate("maintenance", true, prod, ""),
+				gate("freeze", true, prod, ""),
+			},
+			labels:  map[string]string{"env": "prod"},
+			wantErr: "apply paused by the closed ReconciliationGate 'freeze'",
+		},
+		{
+			name:    "closed gate with an invalid selector",
+			gates:   []kustomizev1.ReconciliationGate{gate("freeze", true, invalid, "")},
+			labels:  map[string]string{"env": "dev"},
+			wantErr: "invalid selector of the ReconciliationGate 'freeze'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			err := Check(tt.gates, tt.labels)
+			if tt.wantErr == "" {
+				g.Expect(err).ToNot(HaveOccurred())
+				return
+			}
+			g.Expect(err).To(HaveOccurred())
+			g.Expect(err.Error()).To(ContainSubstring(tt.wantErr))
+
+			var closedErr *ClosedError
+			g.Expect(err).To(BeAssignableToTypeOf(closedErr))
+		})
+	}
+}
@@ -0,0 +1,206 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package refwatch watches the Secrets and ConfigMaps referenced by the
+// Kustomizations, and reports which Kustomizations are affected when they
+// change. Each referenced object is watched on its own, with a name field
+// selector and metadata only, so that the controller doesn't have to cache
+// all the Secrets and ConfigMaps of the cluster.
+package refwatch
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/metadata"
+	"k8s.io/client-go/metadata/metadatainformer"
+	"k8s.io/client-go/tools/cache"
+)
+
+// resources maps the kinds which can be watched to their API resource.
+var resources = map[string]schema.GroupVersionResource{
+	"Secret":    {Version: "v1", Resource: "secrets"},
+	"ConfigMap": {Version: "v1", Resource: "configmaps"},
+}
+
+// Ref is a reference to a Secret or ConfigMap.
+type Ref struct {
+	Kind      string
+	Namespace string
+	Name      string
+}
+
+// NotifyFunc is called for each Kustomization referencing an object
+// which was created, updated or deleted.
+type NotifyFunc func(ctx context.Context, key types.NamespacedName, ref Ref)
+
+// Manager runs a watch per referenced object, for as long as it's
+// referenced by at least one Kustomization.
+type Manager struct {
+	ctx    context.Context
+	client metadata.Interface
+	notify NotifyFunc
+
+	mu      sync.Mutex
+	watches map[Ref]*watch
+	refs    map[types.NamespacedName][]Ref
+}
+
+type watch struct {
+	owners map[types.NamespacedName]struct{}
+	cancel context.CancelFunc
+}
+
+// NewManager returns a Manager which runs the watches until the
+// given context is cancelled, and calls notify on changes.
+func NewManager(ctx context.Context, client metadata.Interface, notify NotifyFunc) *Manager {
+	return &Manager{
+		ctx:     ctx,
+		client:  client,
+		notify:  notify,
+		watches: make(map[Ref]*watch),
+		refs:    make(map[types.NamespacedName][]Ref),
+	}
+}
+
+// Track sets the objects referenced by the Kustomization. The watches of
+// the new references are started, and the ones of the objects no longer
+// referenced by any Kustomization are stopped. References to kinds other
+// than Secret and ConfigMap are ignored.
+func (m *Manager) Track(key types.NamespacedName, refs []Ref) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	wanted := make(map[Ref]struct{}, len(refs))
+	for _, ref := range refs {
+		if _, ok := resources[ref.Kind]; ok {
+			wanted[ref] = struct{}{}
+		}
+	}
+
+	for _, ref := range m.refs[key] {
+		if _, ok := wanted[ref]; !ok {
+			m.release(key, ref)
+		}
+	}
+
+	tracked := make([]Ref, 0, len(wanted))
+	for ref := range wanted {
+		w, ok := m.watches[ref]
+		if !ok {
+			w = m.start(ref)
+			m.watches[ref] = w
+		}
+		w.owners[key] = struct{}{}
+		tracked = append(tracked, ref)
+	}
+
+	if len(tracked) == 0 {
+		delete(m.refs, key)
+		return
+	}
+	m.refs[key] = tracked
+}
+
+// Forget stops tracking the references of the Kustomization.
+func (m *Manager) Forget(key types.NamespacedName) {
+	m.Track(key, nil)
+}
+
+// IsWatching returns true if the referenced object is watched.
+func (m *Manager) IsWatching(ref Ref) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, ok := m.watches[ref]
+	return ok
+}
+
+// release removes the Kustomization from the owners of the reference,
+// and stops its watch when it has no owners left.
+func (m *Manager) release(key types.NamespacedName, ref Ref) {
+	w, ok := m.watches[ref]
+	if !ok {
+		return
+	}
+	delete(w.owners, key)
+	if len(w.owners) == 0 {
+		w.cancel()
+		delete(m.watches, ref)
+	}
+}
+
+func (m *Manager) start(ref Ref) *watch {
+	ctx, cancel := context.WithCancel(m.ctx)
+
+	informer := metadatainformer.NewFilteredMetadataInformer(m.client, resources[ref.Kind], ref.Namespace, 0,
+		cache.Indexers{}, func(opts *metav1.ListOptions) {
+			opts.FieldSelector = fields.OneTermEqualSelector("metadata.name", ref.Name).String()
+		}).Informer()
+	_, _ = informer.AddEventHandler(cache.ResourceEventHandlerDetailedFuncs{
+		AddFunc: func(_ interface{}, isInInitialList bool) {
+			if !isInInitialList {
+				m.changed(ctx, ref)
+			}
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			if resourceVersion(oldObj) != resourceVersion(newObj) {
+				m.changed(ctx, ref)
+			}
+		},
+		DeleteFunc: func(_ interface{}) {
+			m.changed(ctx, ref)
+		},
+	})
+	go informer.Run(ctx.Done())
+
+	return &watch{
+		owners: make(map[types.NamespacedName]struct{}),
+		cancel: cancel,
+	}
+}
+
+// changed notifies the owners of the reference, if its watch is still running.
+func (m *Manager) changed(ctx context.Context, ref Ref) {
+	m.mu.Lock()
+	var owners []types.NamespacedName
+	if w, ok := m.watches[ref]; ok && ctx.Err() == nil {
+		for key := range w.owners {
+			owners = append(owners, key)
+		}
+	}
+	m.mu.Unlock()
+
+	sort.Slice(owners, func(i, j int) bool {
+		return owners[i].String() < owners[j].String()
+	})
+	for _, key := range owners {
+		m.notify(ctx, key, ref)
+	}
+}
+
+func resourceVersion(obj interface{}) string {
+	if o, err := meta.Accessor(obj); err == nil {
+		return o.GetResourceVersion()
+	}
+	return ""
+}
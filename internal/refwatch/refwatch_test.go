@@ -0,0 +1,103 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package refwatch
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	metadatafake "k8s.io/client-go/metadata/fake"
+)
+
+type recorder struct {
+	mu   sync.Mutex
+	keys []types.NamespacedName
+}
+
+func (r *recorder) notify(_ context.Context, key types.NamespacedName, _ Ref) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.keys = append(r.keys, key)
+}
+
+func (r *recorder) get() []types.NamespacedName {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]types.NamespacedName(nil), r.keys...)
+}
+
+func newSecret(namespace, name, resourceVersion string) *metav1.PartialObjectMetadata {
+	return &metav1.PartialObjectMetadata{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:       namespace,
+			Name:            name,
+			ResourceVersion: resourceVersion,
+		},
+	}
+}
+
+func TestManager_Track(t *testing.T) {
+	g := NewWithT(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	scheme := metadatafake.NewTestScheme()
+	scheme.AddKnownTypeWithName(schema.GroupVersionKind{Version: "v1", Kind: "Secret"}, &metav1.PartialObjectMetadata{})
+	scheme.AddKnownTypeWithName(schema.GroupVersionKind{Version: "v1", Kind: "SecretList"}, &metav1.PartialObjectMetadataList{})
+	client := metadatafake.NewSimpleMetadataClient(scheme, newSecret("apps", "vars", "1"))
+	rec := &recorder{}
+	m := NewManager(ctx, client, rec.notify)
+
+	ref := Ref{Kind: "Secret", Namespace: "apps", Name: "vars"}
+	first := types.NamespacedName{Namespace: "apps", Name: "first"}
+	second := types.NamespacedName{Namespace: "apps", Name: "second"}
+
+	t.Run("ignores unsupported kinds", func(t *testing.T) {
+		other := Ref{Kind: "ServiceAccount", Namespace: "apps", Name: "default"}
+		m.Track(first, []Ref{other})
+		g.Expect(m.IsWatching(other)).To(BeFalse())
+	})
+
+	t.Run("notifies the owners on update", func(t *testing.T) {
+		m.Track(first, []Ref{ref})
+		m.Track(second, []Ref{ref, ref})
+		g.Expect(m.IsWatching(ref)).To(BeTrue())
+
+		// The initial list doesn't notify the owners.
+		g.Consistently(rec.get, 500*time.Millisecond).Should(BeEmpty())
+
+		gvr := resources["Secret"]
+		g.Expect(client.Tracker().Update(gvr, newSecret("apps", "vars", "2"), "apps")).To(Succeed())
+		g.Eventually(rec.get, 5*time.Second).Should(Equal([]types.NamespacedName{first, second}))
+	})
+
+	t.Run("keeps watching while referenced", func(t *testing.T) {
+		m.Track(first, nil)
+		g.Expect(m.IsWatching(ref)).To(BeTrue())
+
+		m.Forget(second)
+		g.Expect(m.IsWatching(ref)).To(BeFalse())
+	})
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package remotecluster assembles the REST config of a remote cluster from
+// the URL of its API server, a CA bundle held inline or in a ConfigMap, and
+// a bearer token held in a Secret.
+package remotecluster
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+)
+
+const (
+	// DefaultCAKey is the key of the CA bundle in the ConfigMap.
+	DefaultCAKey = "ca.crt"
+
+	// DefaultTokenKey is the key of the bearer token in the Secret.
+	DefaultTokenKey = "token"
+)
+
+// RESTConfig returns the REST config of the remote cluster, reading the
+// referenced ConfigMap and Secret from the given namespace.
+func RESTConfig(ctx context.Context, reader client.Reader, namespace string, ref *kustomizev1.RemoteClusterReference) (*rest.Config, error) {
+	cfg := &rest.Config{
+		Host: ref.Server,
+		TLSClientConfig: rest.TLSClientConfig{
+			ServerName: ref.TLSServerName,
+			CAData:     []byte(ref.CA),
+		},
+	}
+
+	if ref.CAConfigMapRef != nil {
+		if ref.CA != "" {
+			return nil, fmt.Errorf("the CA bundle can't be set both inline and in a ConfigMap")
+		}
+		key := ref.CAConfigMapRef.Key
+		if key == "" {
+			key = DefaultCAKey
+		}
+		name := types.NamespacedName{Namespace: namespace, Name: ref.CAConfigMapRef.Name}
+		var cm corev1.ConfigMap
+		if err := reader.Get(ctx, name, &cm); err != nil {
+			return nil, fmt.Errorf("unable to read the CA ConfigMap '%s': %w", name, err)
+		}
+		ca, ok := cm.Data[key]
+		if !ok || ca == "" {
+			return nil, fmt.Errorf("CA ConfigMap '%s' does not contain a '%s' key", name, key)
+		}
+		cfg.TLSClientConfig.CAData = []byte(ca)
+	}
+	if len(cfg.TLSClientConfig.CAData) == 0 {
+		cfg.TLSClientConfig.CAData = nil
+	}
+
+	key := ref.TokenSecretRef.Key
+	if key == "" {
+		key = DefaultTokenKey
+	}
+	name := types.NamespacedName{Namespace: namespace, Name: ref.TokenSecretRef.Name}
+	var secret corev1.Secret
+	if err := reader.Get(ctx, name, &secret); err != nil {
+		return nil, fmt.Errorf("unable to read the token Secret '%s': %w", name, err)
+	}
+	token := secret.Data[key]
+	if len(token) == 0 {
+		return nil, fmt.Errorf("token Secret '%s' does not contain a '%s' key", name, key)
+	}
+	cfg.BearerToken = string(token)
+
+	return cfg, nil
+}
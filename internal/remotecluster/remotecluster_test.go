@@ -0,0 +1,120 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package remotecluster
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+)
+
+func TestRESTConfig(t *testing.T) {
+	reader := fake.NewClientBuilder().WithObjects(
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "ca", Namespace: "flux-system"},
+			Data:       map[string]string{DefaultCAKey: "configmap-ca", "custom": "custom-ca"},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "token", Namespace: "flux-system"},
+			Data:       map[string][]byte{DefaultTokenKey: []byte("secret-token"), "custom": []byte("custom-token")},
+		},
+	).Build()
+
+	tests := []struct {
+		name    string
+		ref     kustomizev1.RemoteClusterReference
+		ca      string
+		token   string
+		wantErr string
+	}{
+		{
+			name: "inline CA",
+			ref: kustomizev1.RemoteClusterReference{
+				CA:             "inline-ca",
+				TokenSecretRef: meta.SecretKeyReference{Name: "token"},
+			},
+			ca:    "inline-ca",
+			token: "secret-token",
+		},
+		{
+			name: "CA from ConfigMap with custom keys",
+			ref: kustomizev1.RemoteClusterReference{
+				CAConfigMapRef: &kustomizev1.ConfigMapKeyReference{Name: "ca", Key: "custom"},
+				TokenSecretRef: meta.SecretKeyReference{Name: "token", Key: "custom"},
+			},
+			ca:    "custom-ca",
+			token: "custom-token",
+		},
+		{
+			name: "system CA",
+			ref: kustomizev1.RemoteClusterReference{
+				TokenSecretRef: meta.SecretKeyReference{Name: "token"},
+			},
+			token: "secret-token",
+		},
+		{
+			name: "both CAs",
+			ref: kustomizev1.RemoteClusterReference{
+				CA:             "inline-ca",
+				CAConfigMapRef: &kustomizev1.ConfigMapKeyReference{Name: "ca"},
+				TokenSecretRef: meta.SecretKeyReference{Name: "token"},
+			},
+			wantErr: "the CA bundle can't be set both inline and in a ConfigMap",
+		},
+		{
+			name: "missing CA key",
+			ref: kustomizev1.RemoteClusterReference{
+				CAConfigMapRef: &kustomizev1.ConfigMapKeyReference{Name: "ca", Key: "missing"},
+				TokenSecretRef: meta.SecretKeyReference{Name: "token"},
+			},
+			wantErr: "CA ConfigMap 'flux-system/ca' does not contain a 'missing' key",
+		},
+		{
+			name: "missing token Secret",
+			ref: kustomizev1.RemoteClusterReference{
+				TokenSecretRef: meta.SecretKeyReference{Name: "missing"},
+			},
+			wantErr: "unable to read the token Secret 'flux-system/missing'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			tt.ref.Server = "https://cluster.example.com:6443"
+			tt.ref.TLSServerName = "kubernetes.default"
+
+			cfg, err := RESTConfig(context.Background(), reader, "flux-system", &tt.ref)
+			if tt.wantErr != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tt.wantErr)))
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(cfg.Host).To(Equal("https://cluster.example.com:6443"))
+			g.Expect(cfg.TLSClientConfig.ServerName).To(Equal("kubernetes.default"))
+			g.Expect(string(cfg.TLSClientConfig.CAData)).To(Equal(tt.ca))
+			g.Expect(cfg.BearerToken).To(Equal(tt.token))
+		})
+	}
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package replicas preserves the in-cluster replicas of the workloads scaled
+// by a HorizontalPodAutoscaler, so that the apply doesn't revert the scaling
+// decisions of the autoscaler.
+package replicas
+
+import (
+	"context"
+	"fmt"
+
+	ssautil "github.com/fluxcd/pkg/ssa/utils"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+)
+
+// target identifies the object scaled by a HorizontalPodAutoscaler.
+type target struct {
+	group     string
+	kind      string
+	namespace string
+	name      string
+}
+
+func targetOf(u *unstructured.Unstructured) target {
+	return target{
+		group:     u.GroupVersionKind().Group,
+		kind:      u.GetKind(),
+		namespace: u.GetNamespace(),
+		name:      u.GetName(),
+	}
+}
+
+// isAutoscaler returns true if the object is a HorizontalPodAutoscaler.
+func isAutoscaler(u *unstructured.Unstructured) bool {
+	return u.GetKind() == "HorizontalPodAutoscaler" && u.GroupVersionKind().Group == "autoscaling"
+}
+
+// scaleTarget returns the target of a HorizontalPodAutoscaler.
+func scaleTarget(hpa *unstructured.Unstructured) (target, bool) {
+	ref, ok, _ := unstructured.NestedStringMap(hpa.Object, "spec", "scaleTargetRef")
+	if !ok || ref["kind"] == "" || ref["name"] == "" {
+		return target{}, false
+	}
+	gv, err := schema.ParseGroupVersion(ref["apiVersion"])
+	if err != nil {
+		return target{}, false
+	}
+	return target{group: gv.Group, kind: ref["kind"], namespace: hpa.GetNamespace(), name: ref["name"]}, true
+}
+
+// Preserve sets the replicas of the workloads to their in-cluster value, for
+// the workloads annotated with 'kustomize.toolkit.fluxcd.io/replicas: Preserve'
+// and, when autoDetect is true, for the workloads scaled by the autoscalers of
+// the objects or of the cluster. The replicas of the manifests are kept for the
+// workloads not yet created. It returns the subjects of the workloads whose
+// replicas are preserved.
+func Preserve(ctx context.Context,
+	c client.Client,
+	objects []*unstructured.Unstructured,
+	autoDetect bool) ([]string, error) {
+	var workloads []*unstructured.Unstructured
+	namespaces := make(map[string]bool)
+	for _, u := range objects {
+		if _, ok, _ := unstructured.NestedFieldNoCopy(u.Object, "spec", "replicas"); !ok {
+			continue
+		}
+		workloads = append(workloads, u)
+		namespaces[u.GetNamespace()] = true
+	}
+	if len(workloads) == 0 {
+		return nil, nil
+	}
+
+	scaled := make(map[target]bool)
+	if autoDetect {
+		for _, u := range objects {
+			if !isAutoscaler(u) {
+				continue
+			}
+			if t, ok := scaleTarget(u); ok {
+				scaled[t] = true
+			}
+		}
+		for namespace := range namespaces {
+			if namespace == "" {
+				continue
+			}
+			hpas := &unstructured.UnstructuredList{}
+			hpas.SetAPIVersion("autoscaling/v2")
+			hpas.SetKind("HorizontalPodAutoscalerList")
+			if err := c.List(ctx, hpas, client.InNamespace(namespace)); err != nil {
+				return nil, fmt.Errorf("failed to list the HorizontalPodAutoscalers in namespace '%s': %w", namespace, err)
+			}
+			for i := range hpas.Items {
+				if t, ok := scaleTarget(&hpas.Items[i]); ok {
+					scaled[t] = true
+				}
+			}
+		}
+	}
+
+	var preserved []string
+	for _, u := range workloads {
+		if u.GetAnnotations()[kustomizev1.ReplicasAnnotation] != kustomizev1.PreserveValue && !scaled[targetOf(u)] {
+			continue
+		}
+
+		existing := &unstructured.Unstructured{}
+		existing.SetGroupVersionKind(u.GroupVersionKind())
+		if err := c.Get(ctx, client.ObjectKeyFromObject(u), existing); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to get the replicas of %s: %w", ssautil.FmtUnstructured(u), err)
+		}
+		replicas, ok, _ := unstructured.NestedFieldCopy(existing.Object, "spec", "replicas")
+		if !ok {
+			continue
+		}
+		if err := unstructured.SetNestedField(u.Object, replicas, "spec", "replicas"); err != nil {
+			return nil, err
+		}
+		preserved = append(preserved, ssautil.FmtUnstructured(u))
+	}
+	return preserved, nil
+}
@@ -0,0 +1,161 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package replicas
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/yaml"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+)
+
+func deployment(t *testing.T, name string, replicas int64, annotations map[string]string) *unstructured.Unstructured {
+	t.Helper()
+	u := &unstructured.Unstructured{}
+	if err := yaml.Unmarshal([]byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  namespace: apps
+spec:
+  selector:
+    matchLabels:
+      app: app
+`), &u.Object); err != nil {
+		t.Fatal(err)
+	}
+	u.SetName(name)
+	u.SetAnnotations(annotations)
+	if err := unstructured.SetNestedField(u.Object, replicas, "spec", "replicas"); err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+func autoscaler(name, target string) *autoscalingv2.HorizontalPodAutoscaler {
+	return &autoscalingv2.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "apps"},
+		Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{
+				APIVersion: "apps/v1",
+				Kind:       "Deployment",
+				Name:       target,
+			},
+			MaxReplicas: 10,
+		},
+	}
+}
+
+func TestPreserve(t *testing.T) {
+	existing := []client.Object{
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "annotated", Namespace: "apps"},
+			Spec:       appsv1.DeploymentSpec{Replicas: ptr.To[int32](5)},
+		},
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "cluster-hpa", Namespace: "apps"},
+			Spec:       appsv1.DeploymentSpec{Replicas: ptr.To[int32](6)},
+		},
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "manifest-hpa", Namespace: "apps"},
+			Spec:       appsv1.DeploymentSpec{Replicas: ptr.To[int32](7)},
+		},
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "unscaled", Namespace: "apps"},
+			Spec:       appsv1.DeploymentSpec{Replicas: ptr.To[int32](8)},
+		},
+		autoscaler("cluster-hpa", "cluster-hpa"),
+	}
+
+	objects := func(t *testing.T) []*unstructured.Unstructured {
+		hpa, err := toUnstructured(autoscaler("manifest-hpa", "manifest-hpa"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return []*unstructured.Unstructured{
+			deployment(t, "annotated", 1, map[string]string{kustomizev1.ReplicasAnnotation: kustomizev1.PreserveValue}),
+			deployment(t, "cluster-hpa", 1, nil),
+			deployment(t, "manifest-hpa", 1, nil),
+			deployment(t, "unscaled", 1, nil),
+			deployment(t, "new", 2, map[string]string{kustomizev1.ReplicasAnnotation: kustomizev1.PreserveValue}),
+			hpa,
+		}
+	}
+
+	tests := []struct {
+		name          string
+		autoDetect    bool
+		wantReplicas  map[string]int64
+		wantPreserved []string
+	}{
+		{
+			name:          "annotated workloads",
+			wantReplicas:  map[string]int64{"annotated": 5, "cluster-hpa": 1, "manifest-hpa": 1, "unscaled": 1, "new": 2},
+			wantPreserved: []string{"Deployment/apps/annotated"},
+		},
+		{
+			name:         "autoscaled workloads",
+			autoDetect:   true,
+			wantReplicas: map[string]int64{"annotated": 5, "cluster-hpa": 6, "manifest-hpa": 7, "unscaled": 1, "new": 2},
+			wantPreserved: []string{
+				"Deployment/apps/annotated",
+				"Deployment/apps/cluster-hpa",
+				"Deployment/apps/manifest-hpa",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			c := fake.NewClientBuilder().WithObjects(existing...).Build()
+			objs := objects(t)
+
+			preserved, err := Preserve(context.Background(), c, objs, tt.autoDetect)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(preserved).To(Equal(tt.wantPreserved))
+
+			for _, u := range objs {
+				if u.GetKind() != "Deployment" {
+					continue
+				}
+				replicas, _, _ := unstructured.NestedInt64(u.Object, "spec", "replicas")
+				g.Expect(replicas).To(Equal(tt.wantReplicas[u.GetName()]), u.GetName())
+			}
+		})
+	}
+}
+
+func toUnstructured(obj client.Object) (*unstructured.Unstructured, error) {
+	data, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+	u := &unstructured.Unstructured{Object: data}
+	u.SetAPIVersion("autoscaling/v2")
+	u.SetKind("HorizontalPodAutoscaler")
+	return u, nil
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package revisionrate limits the number of distinct revisions applied by
+// a Kustomization in a sliding time window, so that the revisions pushed in
+// a burst are coalesced to the latest one.
+package revisionrate
+
+import (
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+)
+
+// LimitedError is returned for the revisions delayed by the rate limit.
+type LimitedError struct {
+	// Revision is the delayed revision.
+	Revision string
+
+	// MaxRevisions is the maximum number of revisions in the window.
+	MaxRevisions int
+
+	// Window is the duration of the sliding window.
+	Window time.Duration
+
+	// Until is the time at which the latest revision can be applied.
+	Until time.Time
+}
+
+func (e *LimitedError) Error() string {
+	return fmt.Sprintf("revision rate limit of %d per %s reached, apply of revision %s delayed until %s",
+		e.MaxRevisions, e.Window, e.Revision, e.Until.UTC().Format(time.RFC3339))
+}
+
+// Prune returns the revisions applied in the window ending at now.
+func Prune(applied []kustomizev1.AppliedRevision, window time.Duration, now time.Time) []kustomizev1.AppliedRevision {
+	var recent []kustomizev1.AppliedRevision
+	for _, r := range applied {
+		if now.Sub(r.AppliedAt.Time) < window {
+			recent = append(recent, r)
+		}
+	}
+	return recent
+}
+
+// Check returns a LimitedError if the revision differs from the last
+// applied one, and the maximum number of revisions have been applied in
+// the window ending at now. Reapplying the last applied revision, e.g. to
+// correct drift or after a spec change, is never limited.
+func Check(obj *kustomizev1.Kustomization, revision string, now time.Time) error {
+	limit := obj.Spec.RevisionRateLimit
+	if limit == nil || revision == obj.Status.LastAppliedRevision {
+		return nil
+	}
+
+	window := obj.GetRevisionRateLimitWindow()
+	recent := Prune(obj.Status.AppliedRevisions, window, now)
+	if len(recent) < limit.MaxRevisions {
+		return nil
+	}
+	return &LimitedError{
+		Revision:     revision,
+		MaxRevisions: limit.MaxRevisions,
+		Window:       window,
+		Until:        recent[len(recent)-limit.MaxRevisions].AppliedAt.Add(window),
+	}
+}
+
+// Record records the applied revision in the status of the object, if it
+// differs from the last applied one, and prunes the revisions out of the
+// window. The records are removed when the rate limit is not set.
+func Record(obj *kustomizev1.Kustomization, revision string, now time.Time) {
+	if obj.Spec.RevisionRateLimit == nil {
+		obj.Status.AppliedRevisions = nil
+		return
+	}
+
+	recent := Prune(obj.Status.AppliedRevisions, obj.GetRevisionRateLimitWindow(), now)
+	if revision != obj.Status.LastAppliedRevision {
+		recent = append(recent, kustomizev1.AppliedRevision{
+			Revision:  revision,
+			AppliedAt: metav1.NewTime(now),
+		})
+	}
+	obj.Status.AppliedRevisions = recent
+}
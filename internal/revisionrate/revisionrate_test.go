@@ -0,0 +1,136 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package revisionrate
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+)
+
+func TestCheck(t *testing.T) {
+	now := time.Date(2024, 4, 1, 12, 0, 0, 0, time.UTC)
+	applied := func(revision string, ago time.Duration) kustomizev1.AppliedRevision {
+		return kustomizev1.AppliedRevision{Revision: revision, AppliedAt: metav1.NewTime(now.Add(-ago))}
+	}
+	kustomization := func(limit *kustomizev1.RevisionRateLimit, history ...kustomizev1.AppliedRevision) *kustomizev1.Kustomization {
+		obj := &kustomizev1.Kustomization{}
+		obj.Spec.RevisionRateLimit = limit
+		obj.Status.AppliedRevisions = history
+		if len(history) > 0 {
+			obj.Status.LastAppliedRevision = history[len(history)-1].Revision
+		}
+		return obj
+	}
+	two := &kustomizev1.RevisionRateLimit{MaxRevisions: 2}
+
+	tests := []struct {
+		name      string
+		obj       *kustomizev1.Kustomization
+		revision  string
+		wantUntil time.Time
+	}{
+		{
+			name:     "no limit",
+			obj:      kustomization(nil, applied("v1", time.Minute), applied("v2", time.Minute)),
+			revision: "v3",
+		},
+		{
+			name:     "below the limit",
+			obj:      kustomization(two, applied("v1", time.Minute)),
+			revision: "v2",
+		},
+		{
+			name:      "limit reached",
+			obj:       kustomization(two, applied("v1", 50*time.Minute), applied("v2", 10*time.Minute)),
+			revision:  "v3",
+			wantUntil: now.Add(10 * time.Minute),
+		},
+		{
+			name:     "last applied revision",
+			obj:      kustomization(two, applied("v1", 50*time.Minute), applied("v2", 10*time.Minute)),
+			revision: "v2",
+		},
+		{
+			name:     "revisions out of the window",
+			obj:      kustomization(two, applied("v1", 70*time.Minute), applied("v2", 10*time.Minute)),
+			revision: "v3",
+		},
+		{
+			name: "custom window",
+			obj: kustomization(&kustomizev1.RevisionRateLimit{MaxRevisions: 2, Window: &metav1.Duration{Duration: 5 * time.Minute}},
+				applied("v1", 50*time.Minute), applied("v2", 10*time.Minute)),
+			revision: "v3",
+		},
+		{
+			name:      "limit lowered",
+			obj:       kustomization(&kustomizev1.RevisionRateLimit{MaxRevisions: 1}, applied("v1", 50*time.Minute), applied("v2", 10*time.Minute)),
+			revision:  "v3",
+			wantUntil: now.Add(50 * time.Minute),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			err := Check(tt.obj, tt.revision, now)
+			if tt.wantUntil.IsZero() {
+				g.Expect(err).ToNot(HaveOccurred())
+				return
+			}
+			g.Expect(err).To(HaveOccurred())
+			limitErr, ok := err.(*LimitedError)
+			g.Expect(ok).To(BeTrue())
+			g.Expect(limitErr.Until).To(Equal(tt.wantUntil))
+			g.Expect(limitErr.Revision).To(Equal(tt.revision))
+		})
+	}
+}
+
+func TestRecord(t *testing.T) {
+	g := NewWithT(t)
+
+	now := time.Date(2024, 4, 1, 12, 0, 0, 0, time.UTC)
+	obj := &kustomizev1.Kustomization{}
+	obj.Spec.RevisionRateLimit = &kustomizev1.RevisionRateLimit{MaxRevisions: 2}
+	obj.Status.LastAppliedRevision = "v1"
+	obj.Status.AppliedRevisions = []kustomizev1.AppliedRevision{
+		{Revision: "v1", AppliedAt: metav1.NewTime(now.Add(-2 * time.Hour))},
+	}
+
+	// Reapplying the last applied revision is not recorded.
+	Record(obj, "v1", now)
+	g.Expect(obj.Status.AppliedRevisions).To(BeEmpty())
+
+	Record(obj, "v2", now)
+	obj.Status.LastAppliedRevision = "v2"
+	g.Expect(obj.Status.AppliedRevisions).To(HaveLen(1))
+	g.Expect(obj.Status.AppliedRevisions[0].Revision).To(Equal("v2"))
+
+	Record(obj, "v3", now.Add(time.Minute))
+	g.Expect(obj.Status.AppliedRevisions).To(HaveLen(2))
+
+	// The records are removed with the limit.
+	obj.Spec.RevisionRateLimit = nil
+	Record(obj, "v4", now)
+	g.Expect(obj.Status.AppliedRevisions).To(BeNil())
+}
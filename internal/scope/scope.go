@@ -0,0 +1,109 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package scope checks the namespace of the objects against the scope of
+// their kind before they are applied, to replace the errors returned by the
+// API server for the mismatches with actionable ones.
+package scope
+
+import (
+	"fmt"
+	"strings"
+
+	ssautil "github.com/fluxcd/pkg/ssa/utils"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Result lists the objects whose namespace doesn't match their scope.
+type Result struct {
+	// Defaulted contains the namespaced objects without namespace which
+	// were set to the default namespace.
+	Defaulted []string
+
+	// Ignored contains the cluster-scoped objects with a namespace, which
+	// is ignored by the API server. The namespace is kept, as it's part of
+	// the inventory ID of the objects applied by the previous versions.
+	Ignored []string
+
+	// ClusterScoped contains all the cluster-scoped objects, in the
+	// 'Kind/name' format, including the custom resources of the CRDs
+	// defined along with them.
+	ClusterScoped []string
+}
+
+// Check sets the default namespace, if not empty, on the namespaced objects
+// without namespace, and reports the cluster-scoped objects with a namespace.
+// It returns an error listing the namespaced objects without namespace when
+// there is no default. The objects whose kind is unknown to the mapper,
+// e.g. the custom resources of CRDs not yet applied, are skipped, except
+// for being reported as cluster-scoped when their CRD is one of the objects.
+func Check(mapper meta.RESTMapper, objects []*unstructured.Unstructured, defaultNamespace string) (Result, error) {
+	var result Result
+	var missing []string
+	crdScopes := definedScopes(objects)
+	for _, u := range objects {
+		gvk := u.GroupVersionKind()
+		mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		if err != nil {
+			if meta.IsNoMatchError(err) {
+				if crdScopes[gvk.GroupKind()] == "Cluster" {
+					result.ClusterScoped = append(result.ClusterScoped, gvk.Kind+"/"+u.GetName())
+				}
+				continue
+			}
+			return result, fmt.Errorf("failed to get the scope of %s: %w", ssautil.FmtUnstructured(u), err)
+		}
+
+		namespaced := mapping.Scope.Name() == meta.RESTScopeNameNamespace
+		if !namespaced {
+			result.ClusterScoped = append(result.ClusterScoped, gvk.Kind+"/"+u.GetName())
+		}
+		switch {
+		case namespaced && u.GetNamespace() == "" && defaultNamespace != "":
+			u.SetNamespace(defaultNamespace)
+			result.Defaulted = append(result.Defaulted, ssautil.FmtUnstructured(u))
+		case namespaced && u.GetNamespace() == "":
+			missing = append(missing, ssautil.FmtUnstructured(u))
+		case !namespaced && u.GetNamespace() != "":
+			result.Ignored = append(result.Ignored, ssautil.FmtUnstructured(u))
+		}
+	}
+
+	if len(missing) > 0 {
+		return result, fmt.Errorf("namespaced objects without namespace: %s; set the namespace in the manifests, "+
+			"or set '.spec.targetNamespace' or '.spec.inheritNamespace' in the Kustomization",
+			strings.Join(missing, ", "))
+	}
+	return result, nil
+}
+
+// definedScopes returns the scopes of the kinds defined by the CRDs of the
+// objects, indexed by group and kind.
+func definedScopes(objects []*unstructured.Unstructured) map[schema.GroupKind]string {
+	scopes := make(map[schema.GroupKind]string)
+	for _, u := range objects {
+		if u.GetKind() != "CustomResourceDefinition" || u.GroupVersionKind().Group != "apiextensions.k8s.io" {
+			continue
+		}
+		group, _, _ := unstructured.NestedString(u.Object, "spec", "group")
+		kind, _, _ := unstructured.NestedString(u.Object, "spec", "names", "kind")
+		scope, _, _ := unstructured.NestedString(u.Object, "spec", "scope")
+		scopes[schema.GroupKind{Group: group, Kind: kind}] = scope
+	}
+	return scopes
+}
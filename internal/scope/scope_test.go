@@ -0,0 +1,119 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scope
+
+import (
+	"strings"
+	"testing"
+
+	ssautil "github.com/fluxcd/pkg/ssa/utils"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const manifests = `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: with-namespace
+  namespace: apps
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: without-namespace
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: reader
+  namespace: apps
+---
+apiVersion: example.com/v1
+kind: Widget
+metadata:
+  name: unknown
+`
+
+func newMapper() meta.RESTMapper {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, meta.RESTScopeNamespace)
+	mapper.Add(schema.GroupVersionKind{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "ClusterRole"}, meta.RESTScopeRoot)
+	return mapper
+}
+
+func readObjects(t *testing.T) []*unstructured.Unstructured {
+	objects, err := ssautil.ReadObjects(strings.NewReader(manifests))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return objects
+}
+
+func TestCheck(t *testing.T) {
+	t.Run("fails for namespaced objects without namespace", func(t *testing.T) {
+		g := NewWithT(t)
+
+		objects := readObjects(t)
+		result, err := Check(newMapper(), objects, "")
+		g.Expect(err).To(MatchError(ContainSubstring("ConfigMap/without-namespace")))
+		g.Expect(err).ToNot(MatchError(ContainSubstring("ConfigMap/apps/with-namespace")))
+		g.Expect(err).To(MatchError(ContainSubstring(".spec.targetNamespace")))
+		g.Expect(result.Ignored).To(Equal([]string{"ClusterRole/apps/reader"}))
+		g.Expect(objects[2].GetNamespace()).To(Equal("apps"))
+	})
+
+	t.Run("sets the default namespace", func(t *testing.T) {
+		g := NewWithT(t)
+
+		objects := readObjects(t)
+		result, err := Check(newMapper(), objects, "default")
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(result.Defaulted).To(Equal([]string{"ConfigMap/default/without-namespace"}))
+		g.Expect(objects[0].GetNamespace()).To(Equal("apps"))
+		g.Expect(objects[1].GetNamespace()).To(Equal("default"))
+		g.Expect(objects[3].GetNamespace()).To(BeEmpty())
+	})
+}
+
+func TestCheck_ClusterScoped(t *testing.T) {
+	g := NewWithT(t)
+
+	objects := readObjects(t)
+	crd, err := ssautil.ReadObjects(strings.NewReader(`
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.example.com
+spec:
+  group: example.com
+  names:
+    kind: Widget
+  scope: Cluster
+`))
+	g.Expect(err).ToNot(HaveOccurred())
+
+	result, err := Check(newMapper(), objects, "default")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(result.ClusterScoped).To(Equal([]string{"ClusterRole/reader"}))
+
+	result, err = Check(newMapper(), append(objects, crd...), "default")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(result.ClusterScoped).To(Equal([]string{"ClusterRole/reader", "Widget/unknown"}))
+}
@@ -0,0 +1,226 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package secretstore materializes decrypted Kubernetes Secrets in an
+// external secret manager, and replaces them with External Secrets Operator
+// objects referencing the stored values.
+package secretstore
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"path"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+)
+
+const (
+	// ProviderVault is the Hashicorp Vault provider name.
+	ProviderVault = "vault"
+
+	// TokenKey is the key of the Secret data entry holding the token used to
+	// authenticate with the external secret manager.
+	TokenKey = "token"
+
+	// ExternalSecretAPIVersion is the External Secrets Operator API version
+	// of the generated objects.
+	ExternalSecretAPIVersion = "external-secrets.io/v1beta1"
+
+	// ExternalSecretKind is the External Secrets Operator kind of the
+	// generated objects.
+	ExternalSecretKind = "ExternalSecret"
+)
+
+// Annotation marks a Secret for materialization in the external store.
+var Annotation = fmt.Sprintf("%s/external-store", kustomizev1.GroupVersion.Group)
+
+// Writer writes the plain text values of a Secret to an external store.
+type Writer interface {
+	Write(ctx context.Context, key string, data map[string]string) error
+}
+
+// IsDesignated returns true if the given object is a Secret annotated for
+// materialization in the external store.
+func IsDesignated(object *unstructured.Unstructured) bool {
+	return object.GetKind() == "Secret" && object.GetAPIVersion() == "v1" &&
+		object.GetAnnotations()[Annotation] == kustomizev1.EnabledValue
+}
+
+// SplitPath splits the store path in the format '<mount>[/<prefix>]'
+// into the mount and the prefix the Secret values are written under.
+func SplitPath(storePath string) (string, string, error) {
+	mount, prefix, _ := strings.Cut(strings.Trim(storePath, "/"), "/")
+	if mount == "" {
+		return "", "", fmt.Errorf("invalid store path '%s': mount is required", storePath)
+	}
+	return mount, prefix, nil
+}
+
+// Key returns the location of the Secret values relative to the
+// store mount, in the format '<prefix>/<namespace>/<name>'.
+func Key(prefix string, object *unstructured.Unstructured) string {
+	return path.Join(prefix, object.GetNamespace(), object.GetName())
+}
+
+// Pending is the data of a Secret replaced by an ExternalSecret, which is
+// written to the store right before the ExternalSecret is applied.
+type Pending struct {
+	// Key of the values in the store.
+	Key string
+
+	// Secret is the '<namespace>/<name>' of the replaced Secret.
+	Secret string
+
+	// Data are the plain text values of the Secret.
+	Data map[string]string
+}
+
+// Externalize returns an ExternalSecret which takes the place of the given
+// Secret in the applied set of objects, and the data to write to the store,
+// without writing it.
+func Externalize(object *unstructured.Unstructured, prefix string,
+	storeRef kustomizev1.ExternalSecretStoreReference) (*unstructured.Unstructured, Pending, error) {
+	data, err := SecretData(object)
+	if err != nil {
+		return nil, Pending{}, err
+	}
+	key := Key(prefix, object)
+	es, err := ExternalSecretFor(object, key, storeRef)
+	if err != nil {
+		return nil, Pending{}, err
+	}
+	return es, Pending{
+		Key:    key,
+		Secret: fmt.Sprintf("%s/%s", object.GetNamespace(), object.GetName()),
+		Data:   data,
+	}, nil
+}
+
+// Write writes the pending data to the store.
+func Write(ctx context.Context, w Writer, p Pending) error {
+	if err := w.Write(ctx, p.Key, p.Data); err != nil {
+		return fmt.Errorf("failed to write '%s' Secret to external store: %w", p.Secret, err)
+	}
+	return nil
+}
+
+// Materialize writes the data of the given Secret to the store, and returns
+// an ExternalSecret which takes its place in the applied set of objects.
+func Materialize(ctx context.Context, w Writer, object *unstructured.Unstructured,
+	prefix string, storeRef kustomizev1.ExternalSecretStoreReference) (*unstructured.Unstructured, error) {
+	es, p, err := Externalize(object, prefix, storeRef)
+	if err != nil {
+		return nil, err
+	}
+	if err := Write(ctx, w, p); err != nil {
+		return nil, err
+	}
+	return es, nil
+}
+
+// SecretData returns the plain text values of the Secret, merging the
+// 'stringData' entries over the base64 decoded 'data' entries.
+func SecretData(object *unstructured.Unstructured) (map[string]string, error) {
+	result := make(map[string]string)
+
+	data, _, err := unstructured.NestedStringMap(object.Object, "data")
+	if err != nil {
+		return nil, fmt.Errorf("invalid '%s/%s' Secret data: %w", object.GetNamespace(), object.GetName(), err)
+	}
+	for k, v := range data {
+		b, err := base64.StdEncoding.DecodeString(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode '%s/%s' Secret field '%s': %w",
+				object.GetNamespace(), object.GetName(), k, err)
+		}
+		result[k] = string(b)
+	}
+
+	stringData, _, err := unstructured.NestedStringMap(object.Object, "stringData")
+	if err != nil {
+		return nil, fmt.Errorf("invalid '%s/%s' Secret stringData: %w", object.GetNamespace(), object.GetName(), err)
+	}
+	for k, v := range stringData {
+		result[k] = v
+	}
+
+	return result, nil
+}
+
+// ExternalSecretFor returns an ExternalSecret which instructs the External
+// Secrets Operator to create the given Secret from the values stored at key.
+// The labels, annotations and type of the Secret are preserved.
+func ExternalSecretFor(object *unstructured.Unstructured, key string,
+	storeRef kustomizev1.ExternalSecretStoreReference) (*unstructured.Unstructured, error) {
+	kind := storeRef.Kind
+	if kind == "" {
+		kind = "SecretStore"
+	}
+
+	secretType, _, _ := unstructured.NestedString(object.Object, "type")
+	if secretType == "" {
+		secretType = string(corev1.SecretTypeOpaque)
+	}
+
+	es := &unstructured.Unstructured{}
+	es.SetAPIVersion(ExternalSecretAPIVersion)
+	es.SetKind(ExternalSecretKind)
+	es.SetName(object.GetName())
+	es.SetNamespace(object.GetNamespace())
+	es.SetLabels(object.GetLabels())
+
+	annotations := make(map[string]string)
+	for k, v := range object.GetAnnotations() {
+		if k == Annotation {
+			continue
+		}
+		annotations[k] = v
+	}
+	if len(annotations) > 0 {
+		es.SetAnnotations(annotations)
+	}
+
+	spec := map[string]interface{}{
+		"secretStoreRef": map[string]interface{}{
+			"kind": kind,
+			"name": storeRef.Name,
+		},
+		"target": map[string]interface{}{
+			"name":           object.GetName(),
+			"creationPolicy": "Owner",
+			"template": map[string]interface{}{
+				"type": secretType,
+			},
+		},
+		"dataFrom": []interface{}{
+			map[string]interface{}{
+				"extract": map[string]interface{}{
+					"key": key,
+				},
+			},
+		},
+	}
+	if err := unstructured.SetNestedMap(es.Object, spec, "spec"); err != nil {
+		return nil, err
+	}
+
+	return es, nil
+}
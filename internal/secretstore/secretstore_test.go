@@ -0,0 +1,136 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secretstore
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	ssautil "github.com/fluxcd/pkg/ssa/utils"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+)
+
+type fakeWriter struct {
+	key  string
+	data map[string]string
+}
+
+func (w *fakeWriter) Write(_ context.Context, key string, data map[string]string) error {
+	w.key = key
+	w.data = data
+	return nil
+}
+
+func Test_Materialize(t *testing.T) {
+	g := NewWithT(t)
+
+	objects, err := ssautil.ReadObjects(strings.NewReader(`---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: db
+  namespace: apps
+  labels:
+    app: db
+  annotations:
+    kustomize.toolkit.fluxcd.io/external-store: enabled
+type: kubernetes.io/basic-auth
+data:
+  username: YWRtaW4=
+stringData:
+  password: secret
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: plain
+  namespace: apps
+stringData:
+  key: value
+`))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(objects).To(HaveLen(2))
+
+	t.Run("selects annotated secrets", func(t *testing.T) {
+		g := NewWithT(t)
+		g.Expect(IsDesignated(objects[0])).To(BeTrue())
+		g.Expect(IsDesignated(objects[1])).To(BeFalse())
+	})
+
+	t.Run("writes values and returns external secret", func(t *testing.T) {
+		g := NewWithT(t)
+		w := &fakeWriter{}
+		es, err := Materialize(context.TODO(), w, objects[0], "flux", kustomizev1.ExternalSecretStoreReference{
+			Name: "vault",
+		})
+		g.Expect(err).ToNot(HaveOccurred())
+
+		g.Expect(w.key).To(Equal("flux/apps/db"))
+		g.Expect(w.data).To(Equal(map[string]string{
+			"username": "admin",
+			"password": "secret",
+		}))
+
+		g.Expect(es.GetKind()).To(Equal(ExternalSecretKind))
+		g.Expect(es.GetName()).To(Equal("db"))
+		g.Expect(es.GetNamespace()).To(Equal("apps"))
+		g.Expect(es.GetLabels()).To(HaveKeyWithValue("app", "db"))
+		g.Expect(es.GetAnnotations()).ToNot(HaveKey(Annotation))
+
+		kind, _, _ := unstructured.NestedString(es.Object, "spec", "secretStoreRef", "kind")
+		g.Expect(kind).To(Equal("SecretStore"))
+		secretType, _, _ := unstructured.NestedString(es.Object, "spec", "target", "template", "type")
+		g.Expect(secretType).To(Equal("kubernetes.io/basic-auth"))
+		dataFrom, _, _ := unstructured.NestedSlice(es.Object, "spec", "dataFrom")
+		g.Expect(dataFrom).To(HaveLen(1))
+		g.Expect(dataFrom[0]).To(HaveKeyWithValue("extract", map[string]interface{}{"key": "flux/apps/db"}))
+	})
+
+	t.Run("externalizes without writing", func(t *testing.T) {
+		g := NewWithT(t)
+		es, pending, err := Externalize(objects[0], "flux", kustomizev1.ExternalSecretStoreReference{
+			Name: "vault",
+		})
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(es.GetKind()).To(Equal(ExternalSecretKind))
+		g.Expect(pending).To(Equal(Pending{
+			Key:    "flux/apps/db",
+			Secret: "apps/db",
+			Data:   map[string]string{"username": "admin", "password": "secret"},
+		}))
+
+		w := &fakeWriter{}
+		g.Expect(Write(context.TODO(), w, pending)).To(Succeed())
+		g.Expect(w.key).To(Equal("flux/apps/db"))
+		g.Expect(w.data).To(Equal(pending.Data))
+	})
+
+	t.Run("splits store path", func(t *testing.T) {
+		g := NewWithT(t)
+		mount, prefix, err := SplitPath("/secret/flux/prod/")
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(mount).To(Equal("secret"))
+		g.Expect(prefix).To(Equal("flux/prod"))
+
+		_, _, err = SplitPath("/")
+		g.Expect(err).To(HaveOccurred())
+	})
+}
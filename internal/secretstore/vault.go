@@ -0,0 +1,54 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secretstore
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// VaultWriter writes Secret values to a Hashicorp Vault KV version 2
+// secrets engine.
+type VaultWriter struct {
+	kv *api.KVv2
+}
+
+// NewVaultWriter returns a VaultWriter for the KV version 2 secrets engine
+// at the given mount of the server at address.
+func NewVaultWriter(address, token, mount string) (*VaultWriter, error) {
+	cfg := api.DefaultConfig()
+	cfg.Address = address
+	client, err := api.NewClient(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Vault client: %w", err)
+	}
+	client.SetToken(token)
+
+	return &VaultWriter{kv: client.KVv2(mount)}, nil
+}
+
+// Write stores the data at the given key.
+func (w *VaultWriter) Write(ctx context.Context, key string, data map[string]string) error {
+	values := make(map[string]interface{}, len(data))
+	for k, v := range data {
+		values[k] = v
+	}
+	_, err := w.kv.Put(ctx, key, values)
+	return err
+}
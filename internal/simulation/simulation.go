@@ -0,0 +1,123 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package simulation runs an ephemeral API server embedded in the
+// controller, to which the Kustomizations are applied instead of the
+// cluster, so that the results of the reconciliations of entire
+// repositories can be validated in CI without changing any cluster.
+package simulation
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+)
+
+// Server is an ephemeral API server, backed by an etcd instance, without
+// the controllers of the cluster.
+type Server struct {
+	env *envtest.Environment
+	cfg *rest.Config
+}
+
+// NewServer starts an API server with the etcd and kube-apiserver binaries
+// of the assets directory, or of the KUBEBUILDER_ASSETS directory if empty.
+func NewServer(assetsDir string) (*Server, error) {
+	env := &envtest.Environment{
+		BinaryAssetsDirectory: assetsDir,
+	}
+	cfg, err := env.Start()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start the simulation API server: %w", err)
+	}
+	return &Server{env: env, cfg: cfg}, nil
+}
+
+// Config returns a copy of the admin config of the API server.
+func (s *Server) Config() *rest.Config {
+	return rest.CopyConfig(s.cfg)
+}
+
+// Start waits for the context to be done and stops the API server. It
+// allows to add the server to the manager, to stop it on shutdown.
+func (s *Server) Start(ctx context.Context) error {
+	<-ctx.Done()
+	return s.Stop()
+}
+
+// NeedLeaderElection returns false, as the server is started before the
+// manager and must be stopped on shutdown by all the replicas.
+func (s *Server) NeedLeaderElection() bool {
+	return false
+}
+
+// Stop stops the API server and removes its data.
+func (s *Server) Stop() error {
+	return s.env.Stop()
+}
+
+// MirrorNamespaces creates in the simulation API server the namespaces of
+// the objects which exist in the cluster, so that the objects targeting the
+// namespaces not defined by the Kustomizations are applied as they would be
+// to the cluster. It returns the names of the created namespaces.
+func MirrorNamespaces(ctx context.Context, cluster client.Reader, sim client.Client,
+	objects []*unstructured.Unstructured) ([]string, error) {
+	var created []string
+	seen := make(map[string]bool)
+	for _, u := range objects {
+		name := u.GetNamespace()
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		err := sim.Get(ctx, types.NamespacedName{Name: name}, &corev1.Namespace{})
+		if err == nil {
+			continue
+		}
+		if !apierrors.IsNotFound(err) {
+			return created, fmt.Errorf("failed to get namespace '%s' from the simulation API server: %w", name, err)
+		}
+
+		ns := &corev1.Namespace{}
+		if err := cluster.Get(ctx, types.NamespacedName{Name: name}, ns); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return created, fmt.Errorf("failed to get namespace '%s': %w", name, err)
+		}
+		mirror := &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Labels:      ns.GetLabels(),
+				Annotations: ns.GetAnnotations(),
+			},
+		}
+		if err := sim.Create(ctx, mirror); err != nil && !apierrors.IsAlreadyExists(err) {
+			return created, fmt.Errorf("failed to create namespace '%s' in the simulation API server: %w", name, err)
+		}
+		created = append(created, name)
+	}
+	return created, nil
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package simulation
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestMirrorNamespaces(t *testing.T) {
+	g := NewWithT(t)
+
+	cluster := fake.NewClientBuilder().WithObjects(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "apps", Labels: map[string]string{"team": "a"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "existing"}},
+	).Build()
+	sim := fake.NewClientBuilder().WithObjects(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "existing"}},
+	).Build()
+
+	object := func(kind, namespace, name string) *unstructured.Unstructured {
+		u := &unstructured.Unstructured{}
+		u.SetAPIVersion("v1")
+		u.SetKind(kind)
+		u.SetNamespace(namespace)
+		u.SetName(name)
+		return u
+	}
+	objects := []*unstructured.Unstructured{
+		object("ConfigMap", "apps", "a"),
+		object("ConfigMap", "apps", "b"),
+		object("ConfigMap", "existing", "c"),
+		object("ConfigMap", "missing", "d"),
+		object("Namespace", "", "new"),
+	}
+
+	created, err := MirrorNamespaces(context.Background(), cluster, sim, objects)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(created).To(ConsistOf("apps"))
+
+	ns := &corev1.Namespace{}
+	g.Expect(sim.Get(context.Background(), types.NamespacedName{Name: "apps"}, ns)).To(Succeed())
+	g.Expect(ns.GetLabels()).To(HaveKeyWithValue("team", "a"))
+	g.Expect(sim.Get(context.Background(), types.NamespacedName{Name: "missing"}, ns)).NotTo(Succeed())
+
+	created, err = MirrorNamespaces(context.Background(), cluster, sim, objects)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(created).To(BeEmpty())
+}
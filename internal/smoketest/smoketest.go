@@ -0,0 +1,176 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package smoketest runs the smoke tests of the Kustomizations in-cluster
+// once their objects are applied and healthy, e.g. to check the value of a
+// ConfigMap or to request a Service URL from a Job.
+package smoketest
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+)
+
+// jobTTL is the time after which the finished Jobs are garbage collected
+// by the cluster, when they couldn't be deleted by the controller.
+const jobTTL = 10 * time.Minute
+
+// Options configures the run of the tests.
+type Options struct {
+	// Namespace is the default namespace of the ConfigMaps, and the
+	// namespace of the Jobs.
+	Namespace string
+
+	// Labels are set on the Jobs.
+	Labels map[string]string
+
+	// Interval at which the Jobs are polled.
+	Interval time.Duration
+
+	// Timeout of each test.
+	Timeout time.Duration
+}
+
+// Run runs the tests in order and returns the error of the first failed
+// test, naming it.
+func Run(ctx context.Context, c client.Client, tests []kustomizev1.Test, opts Options) error {
+	for _, test := range tests {
+		var err error
+		switch {
+		case test.ConfigMap != nil && test.HTTP != nil:
+			err = fmt.Errorf("only one of 'configMap' and 'http' must be set")
+		case test.ConfigMap != nil:
+			err = checkConfigMap(ctx, c, test.ConfigMap, opts)
+		case test.HTTP != nil:
+			err = runHTTP(ctx, c, test.Name, test.HTTP, opts)
+		default:
+			err = fmt.Errorf("one of 'configMap' and 'http' must be set")
+		}
+		if err != nil {
+			return fmt.Errorf("test '%s' failed: %w", test.Name, err)
+		}
+	}
+	return nil
+}
+
+// checkConfigMap checks that the key of the ConfigMap is set, to the
+// expected value if any.
+func checkConfigMap(ctx context.Context, c client.Client, test *kustomizev1.ConfigMapTest, opts Options) error {
+	key := types.NamespacedName{Name: test.Name, Namespace: test.Namespace}
+	if key.Namespace == "" {
+		key.Namespace = opts.Namespace
+	}
+	cm := &corev1.ConfigMap{}
+	if err := c.Get(ctx, key, cm); err != nil {
+		return fmt.Errorf("failed to get ConfigMap '%s': %w", key, err)
+	}
+	value, ok := cm.Data[test.Key]
+	if !ok {
+		return fmt.Errorf("key '%s' not found in ConfigMap '%s'", test.Key, key)
+	}
+	if test.Value != "" && value != test.Value {
+		return fmt.Errorf("key '%s' of ConfigMap '%s' is '%s', expected '%s'", test.Key, key, value, test.Value)
+	}
+	return nil
+}
+
+// runHTTP runs a Job requesting the URL with curl, and waits for it to
+// complete. The Job is deleted once finished.
+func runHTTP(ctx context.Context, c client.Client, name string, test *kustomizev1.HTTPTest, opts Options) error {
+	job := newJob(name, test, opts)
+	if err := c.Create(ctx, job); err != nil {
+		return fmt.Errorf("failed to create Job: %w", err)
+	}
+	defer func() {
+		_ = c.Delete(context.Background(), job, client.PropagationPolicy(metav1.DeletePropagationBackground))
+	}()
+
+	var failed bool
+	err := wait.PollUntilContextTimeout(ctx, opts.Interval, opts.Timeout, true, func(ctx context.Context) (bool, error) {
+		if err := c.Get(ctx, client.ObjectKeyFromObject(job), job); err != nil {
+			return false, err
+		}
+		for _, cond := range job.Status.Conditions {
+			if cond.Status != corev1.ConditionTrue {
+				continue
+			}
+			switch cond.Type {
+			case batchv1.JobComplete:
+				return true, nil
+			case batchv1.JobFailed:
+				failed = true
+				return true, nil
+			}
+		}
+		return false, nil
+	})
+	switch {
+	case err != nil && wait.Interrupted(err):
+		return fmt.Errorf("Job '%s/%s' not completed after %s", job.Namespace, job.Name, opts.Timeout)
+	case err != nil:
+		return fmt.Errorf("failed to get Job '%s/%s': %w", job.Namespace, job.Name, err)
+	case failed:
+		return fmt.Errorf("request to '%s' failed, see the logs of Job '%s/%s'", test.URL, job.Namespace, job.Name)
+	}
+	return nil
+}
+
+// newJob returns the Job requesting the URL of the test, without retries.
+func newJob(name string, test *kustomizev1.HTTPTest, opts Options) *batchv1.Job {
+	image := test.Image
+	if image == "" {
+		image = "curlimages/curl:8.7.1"
+	}
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: fmt.Sprintf("test-%s-", name),
+			Namespace:    opts.Namespace,
+			Labels:       opts.Labels,
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:            ptr.To[int32](0),
+			TTLSecondsAfterFinished: ptr.To(int32(jobTTL.Seconds())),
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: opts.Labels,
+				},
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					Containers: []corev1.Container{{
+						Name:  "test",
+						Image: image,
+						Command: []string{
+							"curl", "--fail", "--silent", "--show-error",
+							"--max-time", fmt.Sprintf("%d", int(opts.Timeout.Seconds())),
+							test.URL,
+						},
+					}},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,131 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package smoketest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+)
+
+var opts = Options{
+	Namespace: "apps",
+	Labels:    map[string]string{"app": "test"},
+	Interval:  10 * time.Millisecond,
+	Timeout:   time.Second,
+}
+
+func TestRun_ConfigMap(t *testing.T) {
+	c := fake.NewClientBuilder().WithObjects(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "status", Namespace: "apps"},
+		Data:       map[string]string{"phase": "ready"},
+	}).Build()
+
+	for _, tt := range []struct {
+		name    string
+		test    kustomizev1.ConfigMapTest
+		wantErr string
+	}{
+		{"key set", kustomizev1.ConfigMapTest{Name: "status", Key: "phase"}, ""},
+		{"expected value", kustomizev1.ConfigMapTest{Name: "status", Namespace: "apps", Key: "phase", Value: "ready"}, ""},
+		{"unexpected value", kustomizev1.ConfigMapTest{Name: "status", Key: "phase", Value: "done"}, "is 'ready', expected 'done'"},
+		{"missing key", kustomizev1.ConfigMapTest{Name: "status", Key: "other"}, "key 'other' not found"},
+		{"missing ConfigMap", kustomizev1.ConfigMapTest{Name: "missing", Key: "phase"}, "failed to get ConfigMap"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			err := Run(context.Background(), c, []kustomizev1.Test{{Name: "config", ConfigMap: &tt.test}}, opts)
+			if tt.wantErr == "" {
+				g.Expect(err).NotTo(HaveOccurred())
+				return
+			}
+			g.Expect(err).To(MatchError(ContainSubstring("test 'config' failed")))
+			g.Expect(err).To(MatchError(ContainSubstring(tt.wantErr)))
+		})
+	}
+
+	g := NewWithT(t)
+	err := Run(context.Background(), c, []kustomizev1.Test{{Name: "empty"}}, opts)
+	g.Expect(err).To(MatchError(ContainSubstring("one of 'configMap' and 'http' must be set")))
+}
+
+func TestRun_HTTP(t *testing.T) {
+	// The fake client completes the Jobs with the given condition.
+	newClient := func(condition batchv1.JobConditionType) client.Client {
+		return fake.NewClientBuilder().WithInterceptorFuncs(interceptor.Funcs{
+			Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+				if err := c.Get(ctx, key, obj, opts...); err != nil {
+					return err
+				}
+				if job, ok := obj.(*batchv1.Job); ok && condition != "" {
+					job.Status.Conditions = []batchv1.JobCondition{{Type: condition, Status: corev1.ConditionTrue}}
+				}
+				return nil
+			},
+		}).Build()
+	}
+	tests := []kustomizev1.Test{{
+		Name: "frontend",
+		HTTP: &kustomizev1.HTTPTest{URL: "http://frontend.apps"},
+	}}
+
+	t.Run("succeeds when the Job completes", func(t *testing.T) {
+		g := NewWithT(t)
+		c := newClient(batchv1.JobComplete)
+		g.Expect(Run(context.Background(), c, tests, opts)).To(Succeed())
+
+		jobs := &batchv1.JobList{}
+		g.Expect(c.List(context.Background(), jobs)).To(Succeed())
+		g.Expect(jobs.Items).To(BeEmpty())
+	})
+
+	t.Run("fails when the Job fails", func(t *testing.T) {
+		g := NewWithT(t)
+		err := Run(context.Background(), newClient(batchv1.JobFailed), tests, opts)
+		g.Expect(err).To(MatchError(ContainSubstring("request to 'http://frontend.apps' failed")))
+	})
+
+	t.Run("fails when the Job doesn't complete", func(t *testing.T) {
+		g := NewWithT(t)
+		err := Run(context.Background(), newClient(""), tests, opts)
+		g.Expect(err).To(MatchError(ContainSubstring("not completed after 1s")))
+	})
+}
+
+func TestNewJob(t *testing.T) {
+	g := NewWithT(t)
+
+	job := newJob("frontend", &kustomizev1.HTTPTest{URL: "http://frontend.apps"}, opts)
+	g.Expect(job.GenerateName).To(Equal("test-frontend-"))
+	g.Expect(job.Namespace).To(Equal("apps"))
+	g.Expect(*job.Spec.BackoffLimit).To(BeZero())
+	container := job.Spec.Template.Spec.Containers[0]
+	g.Expect(container.Image).To(Equal("curlimages/curl:8.7.1"))
+	g.Expect(container.Command).To(Equal([]string{
+		"curl", "--fail", "--silent", "--show-error", "--max-time", "1", "http://frontend.apps",
+	}))
+}
@@ -0,0 +1,171 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azkv
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/security/keyvault/azkeys"
+	"github.com/getsops/sops/v3/azkv"
+)
+
+// KeyVaultOptions configures the requests made to Azure Key Vault, as opposed
+// to the defaults of the SOPS azkv keysource.
+type KeyVaultOptions struct {
+	// MaxRetries is the number of retries of the requests throttled (429)
+	// or failed with a transient error. Defaults to 3 when zero, a negative
+	// value disables the retries.
+	MaxRetries int32
+	// RetryDelay is the initial delay between the retries, when the response
+	// has no Retry-After header. Defaults to 4s when zero.
+	RetryDelay time.Duration
+	// TryTimeout is the timeout of each try of a request. Disabled when zero.
+	TryTimeout time.Duration
+	// FailoverVaults maps the URL of a vault to the URL of its replica, used
+	// when the requests to the vault keep failing with a transient error
+	// after the retries. The replica must hold the same key material, e.g.
+	// restored from a backup of the vault.
+	FailoverVaults map[string]string
+}
+
+// IsZero returns true when the options don't change the defaults.
+func (o KeyVaultOptions) IsZero() bool {
+	return o.MaxRetries == 0 && o.RetryDelay == 0 && o.TryTimeout == 0 && len(o.FailoverVaults) == 0
+}
+
+// ParseFailoverVaults parses the '<vault URL>=<replica URL>' pairs into
+// KeyVaultOptions.FailoverVaults.
+func ParseFailoverVaults(pairs []string) (map[string]string, error) {
+	vaults := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		primary, secondary, ok := strings.Cut(pair, "=")
+		if !ok || primary == "" || secondary == "" {
+			return nil, fmt.Errorf("invalid failover vault '%s': expected '<vault URL>=<replica URL>'", pair)
+		}
+		for _, u := range []string{primary, secondary} {
+			if parsed, err := url.Parse(u); err != nil || parsed.Scheme != "https" || parsed.Host == "" {
+				return nil, fmt.Errorf("invalid failover vault '%s': '%s' is not an https URL", pair, u)
+			}
+		}
+		vaults[normalizeVaultURL(primary)] = secondary
+	}
+	return vaults, nil
+}
+
+// Encrypt encrypts the data key with the Azure Key Vault key, and sets the
+// result as the EncryptedKey of the key.
+func Encrypt(credential azcore.TokenCredential, opts KeyVaultOptions, key *azkv.MasterKey, dataKey []byte) error {
+	result, err := do(credential, opts, key, func(c *azkeys.Client) ([]byte, error) {
+		resp, err := c.Encrypt(context.Background(), key.Name, key.Version, azkeys.KeyOperationParameters{
+			Algorithm: to.Ptr(azkeys.EncryptionAlgorithmRSAOAEP256),
+			Value:     dataKey,
+		}, nil)
+		if err != nil {
+			return nil, err
+		}
+		return resp.KeyOperationResult.Result, nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encrypt sops data key with Azure Key Vault key '%s': %w", key.ToString(), err)
+	}
+	key.SetEncryptedDataKey([]byte(base64.RawURLEncoding.EncodeToString(result)))
+	return nil
+}
+
+// Decrypt decrypts the EncryptedKey of the Azure Key Vault key, and returns
+// the data key.
+func Decrypt(credential azcore.TokenCredential, opts KeyVaultOptions, key *azkv.MasterKey) ([]byte, error) {
+	rawEncryptedKey, err := base64.RawURLEncoding.DecodeString(key.EncryptedKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to base64 decode Azure Key Vault encrypted key: %w", err)
+	}
+	result, err := do(credential, opts, key, func(c *azkeys.Client) ([]byte, error) {
+		resp, err := c.Decrypt(context.Background(), key.Name, key.Version, azkeys.KeyOperationParameters{
+			Algorithm: to.Ptr(azkeys.EncryptionAlgorithmRSAOAEP256),
+			Value:     rawEncryptedKey,
+		}, nil)
+		if err != nil {
+			return nil, err
+		}
+		return resp.KeyOperationResult.Result, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt sops data key with Azure Key Vault key '%s': %w", key.ToString(), err)
+	}
+	return result, nil
+}
+
+// do runs the operation against the vault of the key, and against its
+// replica when the vault keeps failing with a transient error.
+func do(credential azcore.TokenCredential, opts KeyVaultOptions, key *azkv.MasterKey,
+	op func(c *azkeys.Client) ([]byte, error)) ([]byte, error) {
+	vaults := []string{key.VaultURL}
+	if replica, ok := opts.FailoverVaults[normalizeVaultURL(key.VaultURL)]; ok {
+		vaults = append(vaults, replica)
+	}
+
+	var errs []error
+	for _, vault := range vaults {
+		c, err := azkeys.NewClient(vault, credential, &azkeys.ClientOptions{
+			ClientOptions: azcore.ClientOptions{
+				Retry: policy.RetryOptions{
+					MaxRetries: opts.MaxRetries,
+					RetryDelay: opts.RetryDelay,
+					TryTimeout: opts.TryTimeout,
+				},
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to construct Azure Key Vault client for '%s': %w", vault, err)
+		}
+		result, err := op(c)
+		if err == nil {
+			return result, nil
+		}
+		errs = append(errs, fmt.Errorf("vault '%s': %w", vault, err))
+		if !isTransient(err) {
+			break
+		}
+	}
+	return nil, errors.Join(errs...)
+}
+
+// isTransient returns true for the errors which may not occur against a
+// replica of the vault: throttling, server errors and network failures.
+func isTransient(err error) bool {
+	var respErr *azcore.ResponseError
+	if errors.As(err, &respErr) {
+		return respErr.StatusCode == http.StatusTooManyRequests ||
+			respErr.StatusCode == http.StatusRequestTimeout ||
+			respErr.StatusCode >= http.StatusInternalServerError
+	}
+	return true
+}
+
+func normalizeVaultURL(u string) string {
+	return strings.ToLower(strings.TrimSuffix(u, "/"))
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azkv
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/security/keyvault/azkeys"
+	"github.com/getsops/sops/v3/azkv"
+	. "github.com/onsi/gomega"
+)
+
+type fakeCredential struct{}
+
+func (fakeCredential) GetToken(context.Context, policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	return azcore.AccessToken{Token: "token", ExpiresOn: time.Now().Add(time.Hour)}, nil
+}
+
+func TestParseFailoverVaults(t *testing.T) {
+	g := NewWithT(t)
+
+	vaults, err := ParseFailoverVaults([]string{
+		"https://Primary.vault.azure.net/=https://secondary.vault.azure.net/",
+	})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(vaults).To(Equal(map[string]string{
+		"https://primary.vault.azure.net": "https://secondary.vault.azure.net/",
+	}))
+
+	_, err = ParseFailoverVaults([]string{"https://primary.vault.azure.net/"})
+	g.Expect(err).To(HaveOccurred())
+	_, err = ParseFailoverVaults([]string{"https://primary.vault.azure.net/=secondary"})
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestKeyVaultOptions_IsZero(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(KeyVaultOptions{}.IsZero()).To(BeTrue())
+	g.Expect(KeyVaultOptions{FailoverVaults: map[string]string{}}.IsZero()).To(BeTrue())
+	g.Expect(KeyVaultOptions{MaxRetries: -1}.IsZero()).To(BeFalse())
+	g.Expect(KeyVaultOptions{TryTimeout: time.Second}.IsZero()).To(BeFalse())
+}
+
+func Test_do(t *testing.T) {
+	opts := KeyVaultOptions{
+		FailoverVaults: map[string]string{
+			"https://primary.vault.azure.net": "https://secondary.vault.azure.net/",
+		},
+	}
+
+	tests := []struct {
+		name      string
+		vaultURL  string
+		err       error
+		wantCalls int
+		wantErr   bool
+	}{
+		{
+			name:      "no error",
+			vaultURL:  "https://primary.vault.azure.net/",
+			wantCalls: 1,
+		},
+		{
+			name:      "throttled",
+			vaultURL:  "https://primary.vault.azure.net/",
+			err:       &azcore.ResponseError{StatusCode: http.StatusTooManyRequests},
+			wantCalls: 2,
+		},
+		{
+			name:      "unavailable",
+			vaultURL:  "https://primary.vault.azure.net/",
+			err:       &azcore.ResponseError{StatusCode: http.StatusServiceUnavailable},
+			wantCalls: 2,
+		},
+		{
+			name:      "network error",
+			vaultURL:  "https://primary.vault.azure.net/",
+			err:       errors.New("dial tcp: i/o timeout"),
+			wantCalls: 2,
+		},
+		{
+			name:      "forbidden",
+			vaultURL:  "https://primary.vault.azure.net/",
+			err:       &azcore.ResponseError{StatusCode: http.StatusForbidden},
+			wantCalls: 1,
+			wantErr:   true,
+		},
+		{
+			name:      "no replica",
+			vaultURL:  "https://other.vault.azure.net/",
+			err:       &azcore.ResponseError{StatusCode: http.StatusServiceUnavailable},
+			wantCalls: 1,
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			var calls int
+			result, err := do(fakeCredential{}, opts, &azkv.MasterKey{VaultURL: tt.vaultURL, Name: "key"},
+				func(c *azkeys.Client) ([]byte, error) {
+					calls++
+					if calls == 1 && tt.err != nil {
+						return nil, tt.err
+					}
+					return []byte("data"), nil
+				})
+			g.Expect(calls).To(Equal(tt.wantCalls))
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				g.Expect(err.Error()).To(ContainSubstring(tt.vaultURL))
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(result).To(Equal([]byte("data")))
+		})
+	}
+}
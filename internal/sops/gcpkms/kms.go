@@ -0,0 +1,163 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gcpkms
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+
+	kms "cloud.google.com/go/kms/apiv1"
+	"cloud.google.com/go/kms/apiv1/kmspb"
+	"github.com/getsops/sops/v3/gcpkms"
+	"google.golang.org/api/option"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var resourceIDRegexp = regexp.MustCompile(`^(projects/[^/]+/locations/[^/]+/keyRings/[^/]+/cryptoKeys/[^/]+)(/cryptoKeyVersions/([^/]+))?$`)
+
+// ParseResourceID returns the CryptoKey of the given resource ID, and the
+// version it is pinned to, if any. Next to the CryptoKey resource IDs
+// supported by SOPS, the CryptoKeyVersion resource IDs in the format
+// 'projects/<p>/locations/<l>/keyRings/<r>/cryptoKeys/<k>/cryptoKeyVersions/<v>'
+// are accepted.
+func ParseResourceID(resourceID string) (cryptoKey, version string, err error) {
+	matches := resourceIDRegexp.FindStringSubmatch(resourceID)
+	if matches == nil {
+		return "", "", fmt.Errorf("no valid resource ID found in %q", resourceID)
+	}
+	return matches[1], matches[3], nil
+}
+
+// Encrypt encrypts the data key with the GCP KMS key, or with the version
+// the resource ID is pinned to, and returns the encrypted key in the format
+// used by SOPS. When credentialsJSON is nil, the credentials of the
+// environment are used.
+func Encrypt(credentialsJSON []byte, resourceID string, dataKey []byte) (string, error) {
+	if _, _, err := ParseResourceID(resourceID); err != nil {
+		return "", err
+	}
+	client, err := newClient(credentialsJSON)
+	if err != nil {
+		return "", fmt.Errorf("cannot create GCP KMS service: %w", err)
+	}
+	defer client.Close()
+	return encrypt(context.Background(), client, resourceID, dataKey)
+}
+
+// Decrypt decrypts the encrypted key with the GCP KMS key. When credentialsJSON
+// is nil, the credentials of the environment are used.
+func Decrypt(credentialsJSON []byte, resourceID, encryptedKey string) ([]byte, error) {
+	if _, _, err := ParseResourceID(resourceID); err != nil {
+		return nil, err
+	}
+	client, err := newClient(credentialsJSON)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create GCP KMS service: %w", err)
+	}
+	defer client.Close()
+	return decrypt(context.Background(), client, resourceID, encryptedKey)
+}
+
+func encrypt(ctx context.Context, client *kms.KeyManagementClient, resourceID string, dataKey []byte) (string, error) {
+	resp, err := client.Encrypt(ctx, &kmspb.EncryptRequest{
+		Name:      resourceID,
+		Plaintext: dataKey,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt sops data key with GCP KMS key '%s': %w", resourceID,
+			describeError(err, "cloudkms.cryptoKeyVersions.useToEncrypt", "roles/cloudkms.cryptoKeyEncrypter"))
+	}
+	// NB: base64 encoding is for compatibility with SOPS <=3.8.x.
+	return base64.StdEncoding.EncodeToString(resp.Ciphertext), nil
+}
+
+func decrypt(ctx context.Context, client *kms.KeyManagementClient, resourceID, encryptedKey string) ([]byte, error) {
+	// The data is decrypted with the CryptoKey, as the API selects the
+	// version the data key was encrypted with, and rejects version names.
+	cryptoKey, _, err := ParseResourceID(resourceID)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(encryptedKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to base64 decode GCP KMS encrypted key: %w", err)
+	}
+	resp, err := client.Decrypt(ctx, &kmspb.DecryptRequest{
+		Name:       cryptoKey,
+		Ciphertext: ciphertext,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt sops data key with GCP KMS key '%s': %w", resourceID,
+			describeError(err, "cloudkms.cryptoKeyVersions.useToDecrypt", "roles/cloudkms.cryptoKeyDecrypter"))
+	}
+	return resp.Plaintext, nil
+}
+
+// describeError returns an error with an actionable message for the status
+// codes of the permission, key state and external key manager (EKM) errors.
+func describeError(err error, permission, role string) error {
+	s, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	msg := s.Message()
+	for _, d := range s.Details() {
+		if info, ok := d.(*errdetails.ErrorInfo); ok && info.GetReason() != "" {
+			msg = fmt.Sprintf("%s (reason: %s)", msg, info.GetReason())
+		}
+	}
+	switch s.Code() {
+	case codes.PermissionDenied:
+		return fmt.Errorf("permission denied: %s: the credentials need the '%s' permission on the key, e.g. with the '%s' role",
+			msg, permission, role)
+	case codes.Unauthenticated:
+		return fmt.Errorf("the credentials were rejected: %s", msg)
+	case codes.NotFound:
+		return fmt.Errorf("key not found: %s", msg)
+	case codes.FailedPrecondition:
+		return fmt.Errorf("key can't be used: %s: the key version may be disabled or destroyed, "+
+			"or its external key manager unreachable", msg)
+	default:
+		return errors.New(msg)
+	}
+}
+
+func newClient(credentialsJSON []byte) (*kms.KeyManagementClient, error) {
+	var opts []option.ClientOption
+	switch {
+	case credentialsJSON != nil:
+		opts = append(opts, option.WithCredentialsJSON(credentialsJSON))
+	default:
+		if credentials, ok := os.LookupEnv(gcpkms.SopsGoogleCredentialsEnv); ok && len(credentials) > 0 {
+			if _, err := os.Stat(credentials); err == nil {
+				b, err := os.ReadFile(credentials)
+				if err != nil {
+					return nil, err
+				}
+				credentials = string(b)
+			}
+			opts = append(opts, option.WithCredentialsJSON([]byte(credentials)))
+		}
+	}
+	return kms.NewKeyManagementClient(context.Background(), opts...)
+}
@@ -0,0 +1,167 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gcpkms
+
+import (
+	"context"
+	"encoding/base64"
+	"net"
+	"testing"
+
+	kms "cloud.google.com/go/kms/apiv1"
+	"cloud.google.com/go/kms/apiv1/kmspb"
+	. "github.com/onsi/gomega"
+	"google.golang.org/api/option"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const (
+	cryptoKey  = "projects/flux/locations/global/keyRings/flux/cryptoKeys/sops"
+	keyVersion = cryptoKey + "/cryptoKeyVersions/3"
+)
+
+type fakeKMS struct {
+	kmspb.UnimplementedKeyManagementServiceServer
+	names []string
+	err   error
+}
+
+func (s *fakeKMS) Encrypt(_ context.Context, req *kmspb.EncryptRequest) (*kmspb.EncryptResponse, error) {
+	s.names = append(s.names, req.Name)
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &kmspb.EncryptResponse{Name: req.Name, Ciphertext: append([]byte("enc:"), req.Plaintext...)}, nil
+}
+
+func (s *fakeKMS) Decrypt(_ context.Context, req *kmspb.DecryptRequest) (*kmspb.DecryptResponse, error) {
+	s.names = append(s.names, req.Name)
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &kmspb.DecryptResponse{Plaintext: req.Ciphertext[len("enc:"):]}, nil
+}
+
+func newFakeClient(t *testing.T, server *fakeKMS) *kms.KeyManagementClient {
+	t.Helper()
+
+	lis := bufconn.Listen(1024 * 1024)
+	s := grpc.NewServer()
+	kmspb.RegisterKeyManagementServiceServer(s, server)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := kms.NewKeyManagementClient(context.Background(), option.WithGRPCConn(conn))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+	return client
+}
+
+func TestParseResourceID(t *testing.T) {
+	g := NewWithT(t)
+
+	key, version, err := ParseResourceID(cryptoKey)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(key).To(Equal(cryptoKey))
+	g.Expect(version).To(BeEmpty())
+
+	key, version, err = ParseResourceID(keyVersion)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(key).To(Equal(cryptoKey))
+	g.Expect(version).To(Equal("3"))
+
+	_, _, err = ParseResourceID("projects/flux/locations/global/keyRings/flux")
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestEncryptDecrypt_keyVersion(t *testing.T) {
+	g := NewWithT(t)
+
+	server := &fakeKMS{}
+	client := newFakeClient(t, server)
+
+	encryptedKey, err := encrypt(context.Background(), client, keyVersion, []byte("data-key"))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(encryptedKey).To(Equal(base64.StdEncoding.EncodeToString([]byte("enc:data-key"))))
+
+	dataKey, err := decrypt(context.Background(), client, keyVersion, encryptedKey)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(dataKey).To(Equal([]byte("data-key")))
+
+	// The encryption is pinned to the version, the decryption is made
+	// with the CryptoKey.
+	g.Expect(server.names).To(Equal([]string{keyVersion, cryptoKey}))
+}
+
+func TestDecrypt_errors(t *testing.T) {
+	withReason := func(s *status.Status, reason string) error {
+		s, err := s.WithDetails(&errdetails.ErrorInfo{Reason: reason})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s.Err()
+	}
+
+	tests := []struct {
+		name    string
+		err     error
+		wantErr []string
+	}{
+		{
+			name: "permission denied",
+			err: status.Error(codes.PermissionDenied,
+				"Permission 'cloudkms.cryptoKeyVersions.useToDecrypt' denied on resource '"+cryptoKey+"' (or it may not exist)."),
+			wantErr: []string{
+				"failed to decrypt sops data key with GCP KMS key '" + keyVersion + "'",
+				"permission denied: Permission 'cloudkms.cryptoKeyVersions.useToDecrypt' denied on resource",
+				"'roles/cloudkms.cryptoKeyDecrypter' role",
+			},
+		},
+		{
+			name: "external key manager unavailable",
+			err:  withReason(status.New(codes.FailedPrecondition, "The external key manager returned an error."), "EXTERNAL_KEY_MANAGER_ERROR"),
+			wantErr: []string{
+				"key can't be used: The external key manager returned an error. (reason: EXTERNAL_KEY_MANAGER_ERROR)",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			client := newFakeClient(t, &fakeKMS{err: tt.err})
+			_, err := decrypt(context.Background(), client, keyVersion, base64.StdEncoding.EncodeToString([]byte("enc:")))
+			g.Expect(err).To(HaveOccurred())
+			for _, want := range tt.wantErr {
+				g.Expect(err.Error()).To(ContainSubstring(want))
+			}
+		})
+	}
+}
@@ -8,6 +8,7 @@ package keyservice
 
 import (
 	extage "filippo.io/age"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/getsops/sops/v3/age"
 	"github.com/getsops/sops/v3/azkv"
 	"github.com/getsops/sops/v3/gcpkms"
@@ -15,6 +16,8 @@ import (
 	"github.com/getsops/sops/v3/keyservice"
 	awskms "github.com/getsops/sops/v3/kms"
 	"github.com/getsops/sops/v3/pgp"
+
+	intazkv "github.com/fluxcd/kustomize-controller/internal/sops/azkv"
 )
 
 // ServerOption is some configuration that modifies the Server.
@@ -76,6 +79,19 @@ func (o WithAzureToken) ApplyToServer(s *Server) {
 	s.azureToken = o.Token
 }
 
+// WithAzureKeyVault configures the retries and failover of the Azure Key
+// Vault requests on the Server, and the credential used for them.
+type WithAzureKeyVault struct {
+	Credential azcore.TokenCredential
+	Options    intazkv.KeyVaultOptions
+}
+
+// ApplyToServer applies this configuration to the given Server.
+func (o WithAzureKeyVault) ApplyToServer(s *Server) {
+	s.azureCredential = o.Credential
+	s.azureKeyVault = o.Options
+}
+
 // WithDefaultServer configures the fallback default server on the Server.
 type WithDefaultServer struct {
 	Server keyservice.KeyServiceServer
@@ -9,6 +9,7 @@ package keyservice
 import (
 	"fmt"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/getsops/sops/v3/age"
 	"github.com/getsops/sops/v3/azkv"
 	"github.com/getsops/sops/v3/gcpkms"
@@ -20,6 +21,7 @@ import (
 	"golang.org/x/net/context"
 
 	intazkv "github.com/fluxcd/kustomize-controller/internal/sops/azkv"
+	intgcpkms "github.com/fluxcd/kustomize-controller/internal/sops/gcpkms"
 )
 
 // Server is a key service server that uses SOPS MasterKeys to fulfill
@@ -49,6 +51,14 @@ type Server struct {
 	// When nil, the request will be handled by defaultServer.
 	azureToken *azkv.TokenCredential
 
+	// azureKeyVault configures the retries and failover of the Azure Key
+	// Vault requests. When zero, the requests are made with the defaults of
+	// the SOPS azkv keysource.
+	azureKeyVault intazkv.KeyVaultOptions
+	// azureCredential is the credential used with azureKeyVault. When nil,
+	// intazkv.DefaultTokenCredential is used.
+	azureCredential azcore.TokenCredential
+
 	// awsCredsProvider is the Credentials object used for Encrypt and Decrypt
 	// operations of AWS KMS requests.
 	// When nil, the request will be handled by defaultServer.
@@ -310,6 +320,16 @@ func (ks *Server) encryptWithAzureKeyVault(key *keyservice.AzureKeyVaultKey, pla
 		Name:     key.Name,
 		Version:  key.Version,
 	}
+	if !ks.azureKeyVault.IsZero() {
+		credential, err := ks.azureKeyVaultCredential()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get Azure token credential to encrypt data: %w", err)
+		}
+		if err := intazkv.Encrypt(credential, ks.azureKeyVault, &azureKey, plaintext); err != nil {
+			return nil, err
+		}
+		return []byte(azureKey.EncryptedKey), nil
+	}
 	if ks.azureToken == nil {
 		// Ensure we use the default token credential if none is provided
 		// _without_ shelling out to `az`.
@@ -333,6 +353,14 @@ func (ks *Server) decryptWithAzureKeyVault(key *keyservice.AzureKeyVaultKey, cip
 		Name:     key.Name,
 		Version:  key.Version,
 	}
+	if !ks.azureKeyVault.IsZero() {
+		credential, err := ks.azureKeyVaultCredential()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get Azure token credential to decrypt data: %w", err)
+		}
+		azureKey.EncryptedKey = string(ciphertext)
+		return intazkv.Decrypt(credential, ks.azureKeyVault, &azureKey)
+	}
 	if ks.azureToken == nil {
 		// Ensure we use the default token credential if none is provided
 		// _without_ shelling out to `az`.
@@ -349,25 +377,27 @@ func (ks *Server) decryptWithAzureKeyVault(key *keyservice.AzureKeyVaultKey, cip
 	return plaintext, err
 }
 
-func (ks *Server) encryptWithGCPKMS(key *keyservice.GcpKmsKey, plaintext []byte) ([]byte, error) {
-	gcpKey := gcpkms.MasterKey{
-		ResourceID: key.ResourceId,
+// azureKeyVaultCredential returns the credential used with the
+// azureKeyVault options.
+func (ks *Server) azureKeyVaultCredential() (azcore.TokenCredential, error) {
+	if ks.azureCredential != nil {
+		return ks.azureCredential, nil
 	}
-	ks.gcpCredsJSON.ApplyToMasterKey(&gcpKey)
-	if err := gcpKey.Encrypt(plaintext); err != nil {
+	// Ensure we use the default token credential if none is provided
+	// _without_ shelling out to `az`.
+	return intazkv.DefaultTokenCredential()
+}
+
+func (ks *Server) encryptWithGCPKMS(key *keyservice.GcpKmsKey, plaintext []byte) ([]byte, error) {
+	encryptedKey, err := intgcpkms.Encrypt(ks.gcpCredsJSON, key.ResourceId, plaintext)
+	if err != nil {
 		return nil, err
 	}
-	return gcpKey.EncryptedDataKey(), nil
+	return []byte(encryptedKey), nil
 }
 
 func (ks *Server) decryptWithGCPKMS(key *keyservice.GcpKmsKey, ciphertext []byte) ([]byte, error) {
-	gcpKey := gcpkms.MasterKey{
-		ResourceID: key.ResourceId,
-	}
-	ks.gcpCredsJSON.ApplyToMasterKey(&gcpKey)
-	gcpKey.EncryptedKey = string(ciphertext)
-	plaintext, err := gcpKey.Decrypt()
-	return plaintext, err
+	return intgcpkms.Decrypt(ks.gcpCredsJSON, key.ResourceId, string(ciphertext))
 }
 
 func kmsKeyToMasterKey(key *keyservice.KmsKey) awskms.MasterKey {
@@ -0,0 +1,181 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package speccheck detects the human error patterns in the spec of the
+// Kustomizations, which are valid for the API server but lead to subtle
+// misbehavior at runtime, and suggests how to fix them.
+package speccheck
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+	"github.com/fluxcd/kustomize-controller/internal/certpin"
+)
+
+// Violation is a human error pattern found in a field of the spec.
+type Violation struct {
+	// Field is the path of the field, e.g. '.spec.timeout'.
+	Field string
+
+	// Message explains the issue.
+	Message string
+
+	// Fix suggests how to fix the issue.
+	Fix string
+}
+
+// String returns the violation in the '<field>: <message>, <fix>' format.
+func (v Violation) String() string {
+	return fmt.Sprintf("%s: %s, %s", v.Field, v.Message, v.Fix)
+}
+
+// Check returns the violations of the spec, as written by the user before
+// the controller defaults are set. The mapper is used to find the health
+// checks of namespaced kinds without namespace, it can be nil to skip them,
+// e.g. when the kinds are served by a remote cluster.
+func Check(obj *kustomizev1.Kustomization, mapper apimeta.RESTMapper) []Violation {
+	var violations []Violation
+
+	if obj.Spec.Timeout != nil && obj.Spec.Timeout.Duration > obj.Spec.Interval.Duration {
+		violations = append(violations, Violation{
+			Field: ".spec.timeout",
+			Message: fmt.Sprintf("the timeout %s is longer than the interval %s, which delays the next reconciliations",
+				obj.Spec.Timeout.Duration, obj.Spec.Interval.Duration),
+			Fix: "set a timeout shorter than the interval, or increase the interval",
+		})
+	}
+
+	if obj.Spec.ApplyTimeout != nil && obj.Spec.ApplyTimeout.Duration >= obj.GetTimeout() {
+		violations = append(violations, Violation{
+			Field: ".spec.applyTimeout",
+			Message: fmt.Sprintf("the apply timeout %s is not shorter than the timeout %s, which lets a single object consume the whole reconciliation",
+				obj.Spec.ApplyTimeout.Duration, obj.GetTimeout()),
+			Fix: "set an apply timeout shorter than the timeout, e.g. 30s",
+		})
+	}
+
+	if obj.Spec.Prune && strings.ContainsAny(obj.Spec.Path, "*?[") {
+		violations = append(violations, Violation{
+			Field: ".spec.path",
+			Message: fmt.Sprintf("the path '%s' contains a wildcard, which is not expanded and stops the garbage collection of the objects",
+				obj.Spec.Path),
+			Fix: "set the path to a single directory, with a kustomization.yaml listing the directories to include",
+		})
+	}
+
+	if obj.Spec.KubeConfig != nil && obj.Spec.RemoteCluster != nil {
+		violations = append(violations, Violation{
+			Field:   ".spec.remoteCluster",
+			Message: "both the kubeconfig and the remote cluster connection are set, which makes the target cluster ambiguous",
+			Fix:     "remove either .spec.kubeConfig or .spec.remoteCluster",
+		})
+	}
+
+	if len(obj.Spec.APIServerPins) > 0 && !obj.IsRemote() {
+		violations = append(violations, Violation{
+			Field:   ".spec.apiServerPins",
+			Message: "the API server pins are set but the Kustomization is applied to the local cluster, which ignores them",
+			Fix:     "remove the pins, or set .spec.kubeConfig or .spec.remoteCluster",
+		})
+	} else if _, err := certpin.Parse(obj.Spec.APIServerPins); err != nil {
+		violations = append(violations, Violation{
+			Field:   ".spec.apiServerPins",
+			Message: err.Error(),
+			Fix:     "set the base64-encoded SHA-256 digest of the public key, e.g. 'sha256/<base64>', or the hex-encoded SHA-256 fingerprint of the certificate, e.g. 'sha256:<hex>'",
+		})
+	}
+
+	if rc := obj.Spec.RemoteCluster; rc != nil && rc.CA != "" && rc.CAConfigMapRef != nil {
+		violations = append(violations, Violation{
+			Field:   ".spec.remoteCluster.ca",
+			Message: "the CA bundle is set both inline and in a ConfigMap",
+			Fix:     "remove either .spec.remoteCluster.ca or .spec.remoteCluster.caConfigMapRef",
+		})
+	}
+
+	mounts := make([]string, 0, len(obj.Spec.OverlaySources))
+	for i, overlay := range obj.Spec.OverlaySources {
+		mount := path.Clean("/" + overlay.Path)
+		if mount == "/" {
+			violations = append(violations, Violation{
+				Field:   fmt.Sprintf(".spec.overlaySources[%d].path", i),
+				Message: "the path is the root of the source artifact, which replaces all its files",
+				Fix:     "set the path to a directory of the source artifact, e.g. './base'",
+			})
+			continue
+		}
+		for _, other := range mounts {
+			if mount == other || strings.HasPrefix(mount, other+"/") || strings.HasPrefix(other, mount+"/") {
+				violations = append(violations, Violation{
+					Field:   fmt.Sprintf(".spec.overlaySources[%d].path", i),
+					Message: fmt.Sprintf("the path '%s' overlaps with the path of another overlay source, which replaces its files", overlay.Path),
+					Fix:     "set distinct paths which are not nested in one another",
+				})
+				break
+			}
+		}
+		mounts = append(mounts, mount)
+	}
+
+	for i, d := range obj.Spec.DependsOn {
+		namespace := d.Namespace
+		if namespace == "" {
+			namespace = obj.GetNamespace()
+		}
+		if d.Name == obj.GetName() && namespace == obj.GetNamespace() {
+			violations = append(violations, Violation{
+				Field:   fmt.Sprintf(".spec.dependsOn[%d]", i),
+				Message: "the Kustomization depends on itself, which blocks it forever",
+				Fix:     "remove the entry",
+			})
+		}
+	}
+
+	if mapper != nil {
+		for i, hc := range obj.Spec.HealthChecks {
+			if hc.Namespace != "" {
+				continue
+			}
+			gv, err := schema.ParseGroupVersion(hc.APIVersion)
+			if err != nil {
+				continue
+			}
+			// The kinds which are not served yet, e.g. with their CRD in
+			// the manifests, are assumed to be cluster-scoped.
+			mapping, err := mapper.RESTMapping(schema.GroupKind{Group: gv.Group, Kind: hc.Kind}, gv.Version)
+			if err != nil || mapping.Scope.Name() != apimeta.RESTScopeNameNamespace {
+				continue
+			}
+			fix := "set the namespace of the object"
+			if obj.Spec.TargetNamespace != "" {
+				fix = fmt.Sprintf("set the namespace of the object, e.g. the target namespace '%s'", obj.Spec.TargetNamespace)
+			}
+			violations = append(violations, Violation{
+				Field:   fmt.Sprintf(".spec.healthChecks[%d].namespace", i),
+				Message: fmt.Sprintf("the %s '%s' is namespaced but the namespace is missing, which makes the health check time out", hc.Kind, hc.Name),
+				Fix:     fix,
+			})
+		}
+	}
+
+	return violations
+}
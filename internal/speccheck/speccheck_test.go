@@ -0,0 +1,209 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package speccheck
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	. "github.com/onsi/gomega"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+)
+
+func TestCheck(t *testing.T) {
+	mapper := apimeta.NewDefaultRESTMapper(nil)
+	mapper.Add(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}, apimeta.RESTScopeNamespace)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "Namespace"}, apimeta.RESTScopeRoot)
+
+	kustomization := func(mutate func(obj *kustomizev1.Kustomization)) *kustomizev1.Kustomization {
+		obj := &kustomizev1.Kustomization{
+			ObjectMeta: metav1.ObjectMeta{Name: "apps", Namespace: "flux-system"},
+			Spec: kustomizev1.KustomizationSpec{
+				Interval: metav1.Duration{Duration: 10 * time.Minute},
+				Path:     "./apps",
+				Prune:    true,
+			},
+		}
+		mutate(obj)
+		return obj
+	}
+
+	tests := []struct {
+		name   string
+		obj    *kustomizev1.Kustomization
+		mapper apimeta.RESTMapper
+		want   []string
+	}{
+		{
+			name: "valid spec",
+			obj: kustomization(func(obj *kustomizev1.Kustomization) {
+				obj.Spec.Timeout = &metav1.Duration{Duration: 5 * time.Minute}
+				obj.Spec.ApplyTimeout = &metav1.Duration{Duration: 30 * time.Second}
+				obj.Spec.DependsOn = []meta.NamespacedObjectReference{{Name: "apps", Namespace: "infra"}}
+				obj.Spec.HealthChecks = []meta.NamespacedObjectKindReference{
+					{APIVersion: "apps/v1", Kind: "Deployment", Name: "web", Namespace: "apps"},
+					{APIVersion: "v1", Kind: "Namespace", Name: "apps"},
+					{APIVersion: "example.com/v1", Kind: "Unknown", Name: "apps"},
+				}
+			}),
+			mapper: mapper,
+		},
+		{
+			name: "timeout longer than the interval",
+			obj: kustomization(func(obj *kustomizev1.Kustomization) {
+				obj.Spec.Timeout = &metav1.Duration{Duration: 15 * time.Minute}
+			}),
+			want: []string{".spec.timeout"},
+		},
+		{
+			name: "kubeconfig and remote cluster with both CAs",
+			obj: kustomization(func(obj *kustomizev1.Kustomization) {
+				obj.Spec.KubeConfig = &meta.KubeConfigReference{SecretRef: meta.SecretKeyReference{Name: "kubeconfig"}}
+				obj.Spec.RemoteCluster = &kustomizev1.RemoteClusterReference{
+					Server:         "https://cluster.example.com",
+					CA:             "ca",
+					CAConfigMapRef: &kustomizev1.ConfigMapKeyReference{Name: "ca"},
+					TokenSecretRef: meta.SecretKeyReference{Name: "token"},
+				}
+			}),
+			want: []string{".spec.remoteCluster", ".spec.remoteCluster.ca"},
+		},
+		{
+			name: "API server pins of the local cluster",
+			obj: kustomization(func(obj *kustomizev1.Kustomization) {
+				obj.Spec.APIServerPins = []string{"sha256/47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU="}
+			}),
+			want: []string{".spec.apiServerPins"},
+		},
+		{
+			name: "invalid API server pins",
+			obj: kustomization(func(obj *kustomizev1.Kustomization) {
+				obj.Spec.KubeConfig = &meta.KubeConfigReference{SecretRef: meta.SecretKeyReference{Name: "kubeconfig"}}
+				obj.Spec.APIServerPins = []string{"sha256/invalid"}
+			}),
+			want: []string{".spec.apiServerPins"},
+		},
+		{
+			name: "apply timeout longer than the timeout",
+			obj: kustomization(func(obj *kustomizev1.Kustomization) {
+				obj.Spec.Timeout = &metav1.Duration{Duration: 2 * time.Minute}
+				obj.Spec.ApplyTimeout = &metav1.Duration{Duration: 5 * time.Minute}
+			}),
+			want: []string{".spec.applyTimeout"},
+		},
+		{
+			name: "prune with wildcard path",
+			obj: kustomization(func(obj *kustomizev1.Kustomization) {
+				obj.Spec.Path = "./apps/*"
+			}),
+			want: []string{".spec.path"},
+		},
+		{
+			name: "wildcard path without prune",
+			obj: kustomization(func(obj *kustomizev1.Kustomization) {
+				obj.Spec.Path = "./apps/*"
+				obj.Spec.Prune = false
+			}),
+		},
+		{
+			name: "overlay sources at distinct paths",
+			obj: kustomization(func(obj *kustomizev1.Kustomization) {
+				obj.Spec.OverlaySources = []kustomizev1.OverlaySource{
+					{SourceRef: kustomizev1.CrossNamespaceSourceReference{Kind: "GitRepository", Name: "base"}, Path: "./base"},
+					{SourceRef: kustomizev1.CrossNamespaceSourceReference{Kind: "GitRepository", Name: "common"}, Path: "./base-common"},
+				}
+			}),
+		},
+		{
+			name: "overlay sources at the root and overlapping paths",
+			obj: kustomization(func(obj *kustomizev1.Kustomization) {
+				obj.Spec.OverlaySources = []kustomizev1.OverlaySource{
+					{SourceRef: kustomizev1.CrossNamespaceSourceReference{Kind: "GitRepository", Name: "root"}, Path: "./"},
+					{SourceRef: kustomizev1.CrossNamespaceSourceReference{Kind: "GitRepository", Name: "base"}, Path: "./base"},
+					{SourceRef: kustomizev1.CrossNamespaceSourceReference{Kind: "GitRepository", Name: "common"}, Path: "base/common"},
+					{SourceRef: kustomizev1.CrossNamespaceSourceReference{Kind: "GitRepository", Name: "other"}, Path: "./base/"},
+				}
+			}),
+			want: []string{".spec.overlaySources[0].path", ".spec.overlaySources[2].path", ".spec.overlaySources[3].path"},
+		},
+		{
+			name: "dependsOn self-reference",
+			obj: kustomization(func(obj *kustomizev1.Kustomization) {
+				obj.Spec.DependsOn = []meta.NamespacedObjectReference{{Name: "infra"}, {Name: "apps"}}
+			}),
+			want: []string{".spec.dependsOn[1]"},
+		},
+		{
+			name: "health check namespace missing",
+			obj: kustomization(func(obj *kustomizev1.Kustomization) {
+				obj.Spec.HealthChecks = []meta.NamespacedObjectKindReference{
+					{APIVersion: "apps/v1", Kind: "Deployment", Name: "web"},
+				}
+			}),
+			mapper: mapper,
+			want:   []string{".spec.healthChecks[0].namespace"},
+		},
+		{
+			name: "health check namespace missing without mapper",
+			obj: kustomization(func(obj *kustomizev1.Kustomization) {
+				obj.Spec.HealthChecks = []meta.NamespacedObjectKindReference{
+					{APIVersion: "apps/v1", Kind: "Deployment", Name: "web"},
+				}
+			}),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			var fields []string
+			for _, v := range Check(tt.obj, tt.mapper) {
+				g.Expect(v.Message).ToNot(BeEmpty())
+				g.Expect(v.Fix).ToNot(BeEmpty())
+				fields = append(fields, v.Field)
+			}
+			g.Expect(fields).To(Equal(tt.want))
+		})
+	}
+}
+
+func TestViolation_String(t *testing.T) {
+	g := NewWithT(t)
+
+	obj := &kustomizev1.Kustomization{
+		ObjectMeta: metav1.ObjectMeta{Name: "apps", Namespace: "flux-system"},
+		Spec: kustomizev1.KustomizationSpec{
+			TargetNamespace: "apps",
+			HealthChecks: []meta.NamespacedObjectKindReference{
+				{APIVersion: "apps/v1", Kind: "Deployment", Name: "web"},
+			},
+		},
+	}
+	mapper := apimeta.NewDefaultRESTMapper(nil)
+	mapper.Add(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}, apimeta.RESTScopeNamespace)
+
+	violations := Check(obj, mapper)
+	g.Expect(violations).To(HaveLen(1))
+	g.Expect(violations[0].String()).To(Equal(".spec.healthChecks[0].namespace: the Deployment 'web' is namespaced " +
+		"but the namespace is missing, which makes the health check time out, " +
+		"set the namespace of the object, e.g. the target namespace 'apps'"))
+}
@@ -0,0 +1,180 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package statusdetails moves the verbose sections of the status of a
+// Kustomization out of the object when it gets too large to be stored in
+// etcd, and restores them from the ConfigMap they are stored in.
+package statusdetails
+
+import (
+	"encoding/json"
+	"fmt"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+)
+
+// The names of the status sections, in the order in which they are moved.
+const (
+	BuildWarningsSection      = "buildWarnings"
+	UnmanagedOverridesSection = "unmanagedOverrides"
+	ImagesSection             = "images"
+	DifferencesSection        = "differences"
+	InventorySection          = "inventory"
+	ConditionsSection         = "conditions"
+)
+
+// maxConditionMessage is the length above which the condition messages
+// are truncated when the conditions section is moved.
+const maxConditionMessage = 1024
+
+type section struct {
+	name    string
+	move    func(status *kustomizev1.KustomizationStatus) (any, bool)
+	restore func(status *kustomizev1.KustomizationStatus, data []byte) error
+}
+
+var sections = []section{
+	{
+		name: BuildWarningsSection,
+		move: func(status *kustomizev1.KustomizationStatus) (any, bool) {
+			v := status.BuildWarnings
+			status.BuildWarnings = nil
+			return v, len(v) > 0
+		},
+		restore: func(status *kustomizev1.KustomizationStatus, data []byte) error {
+			return json.Unmarshal(data, &status.BuildWarnings)
+		},
+	},
+	{
+		name: UnmanagedOverridesSection,
+		move: func(status *kustomizev1.KustomizationStatus) (any, bool) {
+			v := status.UnmanagedOverrides
+			status.UnmanagedOverrides = nil
+			return v, len(v) > 0
+		},
+		restore: func(status *kustomizev1.KustomizationStatus, data []byte) error {
+			return json.Unmarshal(data, &status.UnmanagedOverrides)
+		},
+	},
+	{
+		name: ImagesSection,
+		move: func(status *kustomizev1.KustomizationStatus) (any, bool) {
+			v := status.Images
+			status.Images = nil
+			return v, len(v) > 0
+		},
+		restore: func(status *kustomizev1.KustomizationStatus, data []byte) error {
+			return json.Unmarshal(data, &status.Images)
+		},
+	},
+	{
+		name: DifferencesSection,
+		move: func(status *kustomizev1.KustomizationStatus) (any, bool) {
+			v := status.Differences
+			status.Differences = nil
+			return v, len(v) > 0
+		},
+		restore: func(status *kustomizev1.KustomizationStatus, data []byte) error {
+			return json.Unmarshal(data, &status.Differences)
+		},
+	},
+	{
+		name: InventorySection,
+		move: func(status *kustomizev1.KustomizationStatus) (any, bool) {
+			v := status.Inventory
+			status.Inventory = nil
+			return v, v != nil
+		},
+		restore: func(status *kustomizev1.KustomizationStatus, data []byte) error {
+			return json.Unmarshal(data, &status.Inventory)
+		},
+	},
+	{
+		// The conditions stay in the status, only their messages are
+		// truncated. They are not restored, as the controller sets them
+		// at each reconciliation.
+		name: ConditionsSection,
+		move: func(status *kustomizev1.KustomizationStatus) (any, bool) {
+			full := make([]any, 0, len(status.Conditions))
+			truncated := false
+			for i := range status.Conditions {
+				full = append(full, status.Conditions[i].DeepCopy())
+				if msg := status.Conditions[i].Message; len(msg) > maxConditionMessage {
+					status.Conditions[i].Message = msg[:maxConditionMessage] + "... (truncated)"
+					truncated = true
+				}
+			}
+			return full, truncated
+		},
+	},
+}
+
+// Size returns the size of the JSON encoding of the Kustomization.
+func Size(obj *kustomizev1.Kustomization) (int, error) {
+	b, err := json.Marshal(obj)
+	if err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// Trim moves the sections of the status out of the Kustomization, until
+// the size of the object is below the limit, and returns their JSON
+// encoding indexed by section name. The sections are not moved when the
+// object is already below the limit.
+func Trim(obj *kustomizev1.Kustomization, limit int) (map[string]string, error) {
+	data := make(map[string]string)
+	for _, s := range sections {
+		size, err := Size(obj)
+		if err != nil {
+			return nil, err
+		}
+		if size <= limit {
+			break
+		}
+		v, ok := s.move(&obj.Status)
+		if !ok {
+			continue
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode the status section '%s': %w", s.name, err)
+		}
+		data[s.name] = string(b)
+	}
+	return data, nil
+}
+
+// Restore sets the sections of the status from their JSON encoding
+// indexed by section name, as returned by Trim.
+func Restore(obj *kustomizev1.Kustomization, data map[string]string) error {
+	for _, s := range sections {
+		v, ok := data[s.name]
+		if !ok || s.restore == nil {
+			continue
+		}
+		if err := s.restore(&obj.Status, []byte(v)); err != nil {
+			return fmt.Errorf("failed to decode the status section '%s': %w", s.name, err)
+		}
+	}
+	return nil
+}
+
+// ConfigMapName returns the name of the ConfigMap holding the status
+// sections moved out of the given Kustomization.
+func ConfigMapName(obj *kustomizev1.Kustomization) string {
+	return fmt.Sprintf("%s-kustomization-status", obj.GetName())
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package statusdetails
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+)
+
+func newKustomization(entries int) *kustomizev1.Kustomization {
+	obj := &kustomizev1.Kustomization{
+		ObjectMeta: metav1.ObjectMeta{Name: "apps", Namespace: "flux-system"},
+	}
+	obj.Status.Inventory = &kustomizev1.ResourceInventory{}
+	for i := 0; i < entries; i++ {
+		obj.Status.Inventory.Entries = append(obj.Status.Inventory.Entries, kustomizev1.ResourceRef{
+			ID:      fmt.Sprintf("apps_configmap-%d__ConfigMap", i),
+			Version: "v1",
+		})
+	}
+	obj.Status.BuildWarnings = []string{"deprecated field"}
+	obj.Status.Conditions = []metav1.Condition{
+		{Type: meta.ReadyCondition, Status: metav1.ConditionFalse, Message: strings.Repeat("x", 2*maxConditionMessage)},
+	}
+	return obj
+}
+
+func TestTrim(t *testing.T) {
+	t.Run("keeps the status below the limit", func(t *testing.T) {
+		g := NewWithT(t)
+
+		obj := newKustomization(10)
+		data, err := Trim(obj, 1<<20)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(data).To(BeEmpty())
+		g.Expect(obj.Status.Inventory.Entries).To(HaveLen(10))
+	})
+
+	t.Run("moves the sections until the object is below the limit", func(t *testing.T) {
+		g := NewWithT(t)
+
+		obj := newKustomization(1000)
+		full := obj.Status.DeepCopy()
+		data, err := Trim(obj, 8*1024)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(data).To(HaveKey(BuildWarningsSection))
+		g.Expect(data).To(HaveKey(InventorySection))
+		g.Expect(data).ToNot(HaveKey(UnmanagedOverridesSection))
+		g.Expect(data).ToNot(HaveKey(ConditionsSection))
+		g.Expect(obj.Status.Inventory).To(BeNil())
+		g.Expect(obj.Status.BuildWarnings).To(BeNil())
+		g.Expect(obj.Status.Conditions[0].Message).To(HaveLen(2 * maxConditionMessage))
+
+		size, err := Size(obj)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(size).To(BeNumerically("<=", 8*1024))
+
+		g.Expect(Restore(obj, data)).To(Succeed())
+		g.Expect(obj.Status.Inventory).To(Equal(full.Inventory))
+		g.Expect(obj.Status.BuildWarnings).To(Equal(full.BuildWarnings))
+	})
+
+	t.Run("truncates the condition messages", func(t *testing.T) {
+		g := NewWithT(t)
+
+		obj := newKustomization(0)
+		data, err := Trim(obj, 1024)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(data).To(HaveKey(ConditionsSection))
+		g.Expect(obj.Status.Conditions[0].Message).To(HaveSuffix("... (truncated)"))
+		g.Expect(data[ConditionsSection]).To(ContainSubstring(strings.Repeat("x", 2*maxConditionMessage)))
+	})
+}
@@ -0,0 +1,190 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package subresources applies the content of the subresources declared in
+// the manifests, e.g. the initial status of the custom resources expected
+// by some operators at bootstrap.
+package subresources
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/fluxcd/cli-utils/pkg/object"
+	"github.com/fluxcd/pkg/ssa"
+	ssautil "github.com/fluxcd/pkg/ssa/utils"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+)
+
+const (
+	// Status is the status subresource.
+	Status = "status"
+
+	// Scale is the scale subresource.
+	Scale = "scale"
+)
+
+// Spec lists the subresources applied for an object.
+type Spec struct {
+	// Status applies the status of the manifest.
+	Status bool
+
+	// Replicas sets the replicas through the scale subresource, if not nil.
+	Replicas *int32
+}
+
+// Parse parses the value of the subresources annotation.
+func Parse(value string) (Spec, error) {
+	var spec Spec
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		name, arg, hasArg := strings.Cut(entry, "=")
+		switch {
+		case entry == "":
+		case name == Status && !hasArg:
+			spec.Status = true
+		case name == Scale && hasArg:
+			replicas, err := strconv.ParseInt(arg, 10, 32)
+			if err != nil || replicas < 0 {
+				return Spec{}, fmt.Errorf("invalid replicas '%s' of the scale subresource", arg)
+			}
+			n := int32(replicas)
+			spec.Replicas = &n
+		default:
+			return Spec{}, fmt.Errorf("unsupported subresource '%s', must be 'status' or 'scale=<replicas>'", entry)
+		}
+	}
+	return spec, nil
+}
+
+// Object holds the subresources declared by an object.
+type Object struct {
+	// Spec lists the subresources to apply.
+	Spec Spec
+
+	// Declared holds the identity and the status of the manifest, copied
+	// before the server-side apply updates the manifest in-place.
+	Declared *unstructured.Unstructured
+}
+
+// Collect returns the subresources declared by the objects, or an error if
+// an object has an invalid subresources annotation or declares the status
+// subresource without a status.
+func Collect(objects []*unstructured.Unstructured) ([]Object, error) {
+	var res []Object
+	for _, u := range objects {
+		value, ok := u.GetAnnotations()[kustomizev1.SubresourcesAnnotation]
+		if !ok {
+			continue
+		}
+		spec, err := Parse(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", ssautil.FmtUnstructured(u), err)
+		}
+
+		declared := &unstructured.Unstructured{Object: map[string]interface{}{}}
+		declared.SetGroupVersionKind(u.GroupVersionKind())
+		declared.SetName(u.GetName())
+		declared.SetNamespace(u.GetNamespace())
+		if spec.Status {
+			status, ok := u.Object[Status].(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%s: the status subresource is declared but the manifest has no status",
+					ssautil.FmtUnstructured(u))
+			}
+			declared.Object[Status] = runtime.DeepCopyJSONValue(status)
+		}
+		res = append(res, Object{Spec: spec, Declared: declared})
+	}
+	return res, nil
+}
+
+// ApplyAll applies the subresources declared by the objects. The subresources
+// of the objects created in the change set are applied, and the status of
+// the existing objects is applied as long as it's empty in-cluster, so that
+// the controllers of the objects take them over once initialized. It returns
+// the subjects of the objects whose subresources were applied.
+func ApplyAll(ctx context.Context,
+	c client.Client,
+	owner string,
+	objects []Object,
+	changeSet *ssa.ChangeSet) ([]string, error) {
+	created := make(map[object.ObjMetadata]bool)
+	for _, entry := range changeSet.Entries {
+		if entry.Action == ssa.CreatedAction {
+			created[entry.ObjMetadata] = true
+		}
+	}
+
+	var applied []string
+	for _, o := range objects {
+		u := o.Declared
+		isNew := created[object.UnstructuredToObjMetadata(u)]
+		changed := false
+		if o.Spec.Status && (isNew || !hasStatus(ctx, c, u)) {
+			if err := c.Status().Patch(ctx, u.DeepCopy(), client.Apply,
+				client.FieldOwner(owner), client.ForceOwnership); err != nil {
+				return applied, fmt.Errorf("%s status apply failed: %w", ssautil.FmtUnstructured(u), err)
+			}
+			changed = true
+		}
+		if isNew && o.Spec.Replicas != nil {
+			if err := applyScale(ctx, c, u, *o.Spec.Replicas); err != nil {
+				return applied, fmt.Errorf("%s scale failed: %w", ssautil.FmtUnstructured(u), err)
+			}
+			changed = true
+		}
+		if changed {
+			applied = append(applied, ssautil.FmtUnstructured(u))
+		}
+	}
+	return applied, nil
+}
+
+// hasStatus returns true if the object has a non-empty status in-cluster,
+// or if it can't be read.
+func hasStatus(ctx context.Context, c client.Client, u *unstructured.Unstructured) bool {
+	existing := &unstructured.Unstructured{}
+	existing.SetGroupVersionKind(u.GroupVersionKind())
+	if err := c.Get(ctx, client.ObjectKeyFromObject(u), existing); err != nil {
+		return !apierrors.IsNotFound(err)
+	}
+	status, _ := existing.Object[Status].(map[string]interface{})
+	return len(status) > 0
+}
+
+// applyScale sets the replicas of the object through the scale subresource.
+func applyScale(ctx context.Context, c client.Client, u *unstructured.Unstructured, replicas int32) error {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(u.GroupVersionKind())
+	obj.SetName(u.GetName())
+	obj.SetNamespace(u.GetNamespace())
+
+	scale := &unstructured.Unstructured{}
+	scale.SetAPIVersion("autoscaling/v1")
+	scale.SetKind("Scale")
+
+	patch := client.RawPatch(types.MergePatchType, []byte(fmt.Sprintf(`{"spec":{"replicas":%d}}`, replicas)))
+	return c.SubResource(Scale).Patch(ctx, obj, patch, &client.SubResourcePatchOptions{SubResourceBody: scale})
+}
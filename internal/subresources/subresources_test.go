@@ -0,0 +1,107 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subresources
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/utils/ptr"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    Spec
+		wantErr string
+	}{
+		{name: "status", value: "status", want: Spec{Status: true}},
+		{name: "scale", value: "scale=2", want: Spec{Replicas: ptr.To[int32](2)}},
+		{name: "both", value: " status , scale=0 ", want: Spec{Status: true, Replicas: ptr.To[int32](0)}},
+		{name: "empty", value: "", want: Spec{}},
+		{name: "scale without replicas", value: "scale", wantErr: "unsupported subresource 'scale'"},
+		{name: "negative replicas", value: "scale=-1", wantErr: "invalid replicas '-1'"},
+		{name: "status with argument", value: "status=true", wantErr: "unsupported subresource 'status=true'"},
+		{name: "unsupported", value: "spec", wantErr: "unsupported subresource 'spec'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			got, err := Parse(tt.value)
+			if tt.wantErr != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tt.wantErr)))
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(got).To(Equal(tt.want))
+		})
+	}
+}
+
+func TestCollect(t *testing.T) {
+	object := func(value string, status map[string]interface{}) *unstructured.Unstructured {
+		u := &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "example.com/v1",
+			"kind":       "Widget",
+			"metadata": map[string]interface{}{
+				"name":      "widget",
+				"namespace": "default",
+			},
+			"spec": map[string]interface{}{"size": int64(1)},
+		}}
+		if value != "" {
+			u.SetAnnotations(map[string]string{kustomizev1.SubresourcesAnnotation: value})
+		}
+		if status != nil {
+			u.Object["status"] = status
+		}
+		return u
+	}
+
+	t.Run("copies the declared status", func(t *testing.T) {
+		g := NewWithT(t)
+		u := object("status", map[string]interface{}{"phase": "Ready"})
+		got, err := Collect([]*unstructured.Unstructured{u, object("", nil)})
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(got).To(HaveLen(1))
+		g.Expect(got[0].Spec).To(Equal(Spec{Status: true}))
+		g.Expect(got[0].Declared.GetName()).To(Equal("widget"))
+		g.Expect(got[0].Declared.GetNamespace()).To(Equal("default"))
+		g.Expect(got[0].Declared.GetKind()).To(Equal("Widget"))
+		g.Expect(got[0].Declared.Object).NotTo(HaveKey("spec"))
+
+		// the in-place update of the manifest doesn't change the declared status
+		delete(u.Object, "status")
+		g.Expect(got[0].Declared.Object["status"]).To(Equal(map[string]interface{}{"phase": "Ready"}))
+	})
+
+	t.Run("rejects the status subresource without status", func(t *testing.T) {
+		g := NewWithT(t)
+		_, err := Collect([]*unstructured.Unstructured{object("status", nil)})
+		g.Expect(err).To(MatchError(ContainSubstring("the manifest has no status")))
+	})
+
+	t.Run("rejects the invalid annotations", func(t *testing.T) {
+		g := NewWithT(t)
+		_, err := Collect([]*unstructured.Unstructured{object("scale=x", nil)})
+		g.Expect(err).To(MatchError(ContainSubstring("Widget/default/widget: invalid replicas 'x'")))
+	})
+}
@@ -0,0 +1,192 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package substitution loads and validates the post build variables
+// of a v1.Kustomization.
+package substitution
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/drone/envsubst"
+	"github.com/drone/envsubst/parse"
+	"github.com/xeipuuv/gojsonschema"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+)
+
+// LoadVariables returns the variables of the Kustomization post build spec,
+// read from the referenced ConfigMaps and Secrets, and overridden by the
+// in-line values. It matches the semantics of the variable substitution.
+func LoadVariables(ctx context.Context, kubeClient client.Client, obj *kustomizev1.Kustomization) (map[string]string, error) {
+	vars := make(map[string]string)
+	if obj.Spec.PostBuild == nil {
+		return vars, nil
+	}
+
+	for _, reference := range obj.Spec.PostBuild.SubstituteFrom {
+		namespacedName := types.NamespacedName{Namespace: obj.GetNamespace(), Name: reference.Name}
+		switch reference.Kind {
+		case "ConfigMap":
+			resource := &corev1.ConfigMap{}
+			if err := kubeClient.Get(ctx, namespacedName, resource); err != nil {
+				if reference.Optional && apierrors.IsNotFound(err) {
+					continue
+				}
+				return nil, fmt.Errorf("substitute from 'ConfigMap/%s' error: %w", reference.Name, err)
+			}
+			for k, v := range resource.Data {
+				vars[k] = strings.ReplaceAll(v, "\n", "")
+			}
+		case "Secret":
+			resource := &corev1.Secret{}
+			if err := kubeClient.Get(ctx, namespacedName, resource); err != nil {
+				if reference.Optional && apierrors.IsNotFound(err) {
+					continue
+				}
+				return nil, fmt.Errorf("substitute from 'Secret/%s' error: %w", reference.Name, err)
+			}
+			for k, v := range resource.Data {
+				vars[k] = strings.ReplaceAll(string(v), "\n", "")
+			}
+		}
+	}
+
+	for k, v := range obj.Spec.PostBuild.Substitute {
+		vars[k] = strings.ReplaceAll(v, "\n", "")
+	}
+
+	return vars, nil
+}
+
+// ExpandPath replaces the variables in the path with their values, e.g.
+// './clusters/${CLUSTER_NAME}/apps', using the syntax of the variable
+// substitution. Unlike the substitution of the manifests, a variable which
+// is not set is an error, unless a default value is given with
+// '${var:=default}', so that an incomplete path is never built.
+func ExpandPath(path string, vars map[string]string) (string, error) {
+	tree, err := parse.Parse(path)
+	if err != nil {
+		return "", fmt.Errorf("invalid path '%s': %w", path, err)
+	}
+	var missing []string
+	unset(tree.Root, vars, &missing)
+	if len(missing) > 0 {
+		return "", fmt.Errorf("path '%s' variables not set: %s", path, strings.Join(missing, ", "))
+	}
+
+	expanded, err := envsubst.Eval(path, func(s string) string {
+		return vars[s]
+	})
+	if err != nil {
+		return "", fmt.Errorf("invalid path '%s': %w", path, err)
+	}
+	if strings.TrimSpace(expanded) == "" {
+		return "", fmt.Errorf("path '%s' expands to an empty path", path)
+	}
+	return expanded, nil
+}
+
+// unset appends to missing the variables of the node which are not set and
+// have no default value.
+func unset(node parse.Node, vars map[string]string, missing *[]string) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		for _, child := range n.Nodes {
+			unset(child, vars, missing)
+		}
+	case *parse.FuncNode:
+		for _, arg := range n.Args {
+			unset(arg, vars, missing)
+		}
+		switch n.Name {
+		case "=", ":=", "-", ":-":
+			return
+		}
+		if _, ok := vars[n.Param]; !ok && !slices.Contains(*missing, n.Param) {
+			*missing = append(*missing, n.Param)
+		}
+	}
+}
+
+// Validate validates the variables against the given JSON Schema.
+// The values of the variables are converted to the type declared by the
+// matching property schema before validation, and all the validation
+// errors are returned in a single error.
+func Validate(schema []byte, vars map[string]string) error {
+	var s struct {
+		Properties map[string]struct {
+			Type string `json:"type"`
+		} `json:"properties"`
+	}
+	if err := json.Unmarshal(schema, &s); err != nil {
+		return fmt.Errorf("invalid variables schema: %w", err)
+	}
+
+	doc := make(map[string]interface{}, len(vars))
+	for k, v := range vars {
+		doc[k] = convert(v, s.Properties[k].Type)
+	}
+
+	result, err := gojsonschema.Validate(gojsonschema.NewBytesLoader(schema), gojsonschema.NewGoLoader(doc))
+	if err != nil {
+		return fmt.Errorf("invalid variables schema: %w", err)
+	}
+	if result.Valid() {
+		return nil
+	}
+
+	var errs []string
+	for _, e := range result.Errors() {
+		field := e.Field()
+		if field == gojsonschema.STRING_CONTEXT_ROOT {
+			errs = append(errs, e.Description())
+			continue
+		}
+		errs = append(errs, fmt.Sprintf("'%s': %s", field, e.Description()))
+	}
+	return fmt.Errorf("variables validation failed: %s", strings.Join(errs, "; "))
+}
+
+// convert returns the value as the given JSON Schema type, or as string if
+// the value can't be parsed, so that the type mismatch is reported by the
+// validation.
+func convert(value, schemaType string) interface{} {
+	switch schemaType {
+	case "integer":
+		if v, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return v
+		}
+	case "number":
+		if v, err := strconv.ParseFloat(value, 64); err == nil {
+			return v
+		}
+	case "boolean":
+		if v, err := strconv.ParseBool(value); err == nil {
+			return v
+		}
+	}
+	return value
+}
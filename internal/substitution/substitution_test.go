@@ -0,0 +1,169 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package substitution
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+)
+
+func Test_LoadVariables(t *testing.T) {
+	g := NewWithT(t)
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "vars", Namespace: "default"},
+		Data:       map[string]string{"port": "8080", "domain": "example.com"},
+	}
+	kubeClient := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(cm).Build()
+
+	obj := &kustomizev1.Kustomization{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+		Spec: kustomizev1.KustomizationSpec{
+			PostBuild: &kustomizev1.PostBuild{
+				Substitute: map[string]string{"domain": "example.org"},
+				SubstituteFrom: []kustomizev1.SubstituteReference{
+					{Kind: "ConfigMap", Name: "vars"},
+					{Kind: "Secret", Name: "missing", Optional: true},
+				},
+			},
+		},
+	}
+
+	vars, err := LoadVariables(context.TODO(), kubeClient, obj)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(vars).To(Equal(map[string]string{"port": "8080", "domain": "example.org"}))
+
+	obj.Spec.PostBuild.SubstituteFrom[1].Optional = false
+	_, err = LoadVariables(context.TODO(), kubeClient, obj)
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(ContainSubstring("substitute from 'Secret/missing' error"))
+}
+
+func Test_Validate(t *testing.T) {
+	schema := []byte(`{
+  "type": "object",
+  "required": ["domain"],
+  "properties": {
+    "port": {"type": "integer", "minimum": 1, "maximum": 65535},
+    "debug": {"type": "boolean"},
+    "domain": {"type": "string", "pattern": "^[a-z0-9.-]+$"}
+  }
+}`)
+
+	tests := []struct {
+		name    string
+		vars    map[string]string
+		wantErr string
+	}{
+		{
+			name: "valid variables",
+			vars: map[string]string{"port": "8080", "debug": "true", "domain": "example.com"},
+		},
+		{
+			name:    "port not a number",
+			vars:    map[string]string{"port": "http", "domain": "example.com"},
+			wantErr: "'port': Invalid type. Expected: integer, given: string",
+		},
+		{
+			name:    "port out of range",
+			vars:    map[string]string{"port": "80000", "domain": "example.com"},
+			wantErr: "'port': Must be less than or equal to 65535",
+		},
+		{
+			name:    "missing domain",
+			vars:    map[string]string{"port": "8080"},
+			wantErr: "domain is required",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			err := Validate(schema, tt.vars)
+			if tt.wantErr == "" {
+				g.Expect(err).ToNot(HaveOccurred())
+				return
+			}
+			g.Expect(err).To(HaveOccurred())
+			g.Expect(err.Error()).To(ContainSubstring(tt.wantErr))
+		})
+	}
+}
+
+func Test_ExpandPath(t *testing.T) {
+	vars := map[string]string{"CLUSTER_NAME": "staging", "REGION": "eu-west-1"}
+
+	tests := []struct {
+		name    string
+		path    string
+		want    string
+		wantErr string
+	}{
+		{
+			name: "without variables",
+			path: "./clusters/production/apps",
+			want: "./clusters/production/apps",
+		},
+		{
+			name: "with variables",
+			path: "./clusters/${CLUSTER_NAME}/${REGION}/apps",
+			want: "./clusters/staging/eu-west-1/apps",
+		},
+		{
+			name: "with default value",
+			path: "./clusters/${CLUSTER_NAME}/${TIER:=standard}",
+			want: "./clusters/staging/standard",
+		},
+		{
+			name:    "with unset variables",
+			path:    "./clusters/${CLUSTER_NAME}/${ZONE}/${TIER}/${ZONE,,}",
+			wantErr: "path './clusters/${CLUSTER_NAME}/${ZONE}/${TIER}/${ZONE,,}' variables not set: ZONE, TIER",
+		},
+		{
+			name:    "empty expansion",
+			path:    "${EMPTY:=}",
+			wantErr: "expands to an empty path",
+		},
+		{
+			name:    "invalid syntax",
+			path:    "./clusters/${CLUSTER_NAME",
+			wantErr: "invalid path",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			got, err := ExpandPath(tt.path, vars)
+			if tt.wantErr != "" {
+				g.Expect(err).To(HaveOccurred())
+				g.Expect(err.Error()).To(ContainSubstring(tt.wantErr))
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(got).To(Equal(tt.want))
+		})
+	}
+}
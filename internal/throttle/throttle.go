@@ -0,0 +1,153 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package throttle records the latency of the Kubernetes API requests per
+// target cluster, and computes the delay by which the reconciliations are
+// slowed down when an API server responds with high latency.
+package throttle
+
+import (
+	"context"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/client-go/rest"
+	clientmetrics "k8s.io/client-go/tools/metrics"
+)
+
+// smoothing is the weight of the last observed latency in the moving average.
+const smoothing = 0.2
+
+// Throttle keeps a moving average of the API requests latency per host.
+type Throttle struct {
+	threshold time.Duration
+	maxDelay  time.Duration
+
+	mu        sync.Mutex
+	latencies map[string]float64
+}
+
+// New returns a Throttle which delays the reconciliations when the average
+// latency of a host exceeds the threshold. The delay grows linearly with the
+// excess latency, up to maxDelay when the average latency reaches twice
+// the threshold.
+func New(threshold, maxDelay time.Duration) *Throttle {
+	return &Throttle{
+		threshold: threshold,
+		maxDelay:  maxDelay,
+		latencies: make(map[string]float64),
+	}
+}
+
+// Observe records the latency of a request to the host.
+func (t *Throttle) Observe(host string, latency time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	avg, ok := t.latencies[host]
+	if !ok {
+		t.latencies[host] = latency.Seconds()
+		return
+	}
+	t.latencies[host] = avg + smoothing*(latency.Seconds()-avg)
+}
+
+// Delay returns the duration by which the reconciliations targeting
+// the host should be delayed.
+func (t *Throttle) Delay(host string) time.Duration {
+	if t == nil || t.threshold <= 0 {
+		return 0
+	}
+
+	t.mu.Lock()
+	avg := t.latencies[host]
+	t.mu.Unlock()
+
+	excess := avg/t.threshold.Seconds() - 1
+	switch {
+	case excess <= 0:
+		return 0
+	case excess >= 1:
+		return t.maxDelay
+	default:
+		return time.Duration(excess * float64(t.maxDelay))
+	}
+}
+
+// Host returns the host of the API server, as reported in the metrics.
+func Host(cfg *rest.Config) string {
+	if u, err := url.Parse(cfg.Host); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return cfg.Host
+}
+
+var (
+	rateLimiterLatency = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "rest_client_rate_limiter_duration_seconds",
+			Help:    "Client side rate limiter latency in seconds, partitioned by verb and host.",
+			Buckets: []float64{0.005, 0.025, 0.1, 0.25, 0.5, 1.0, 2.0, 4.0, 8.0, 15.0, 30.0, 60.0},
+		},
+		[]string{"verb", "host"},
+	)
+
+	requestLatency = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "rest_client_request_duration_seconds",
+			Help:    "Request latency in seconds, partitioned by verb and host.",
+			Buckets: []float64{0.005, 0.025, 0.1, 0.25, 0.5, 1.0, 2.0, 4.0, 8.0, 15.0, 30.0, 60.0},
+		},
+		[]string{"verb", "host"},
+	)
+
+	throttleDelay = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "gotk_reconcile_throttle_delay_seconds",
+			Help: "Delay applied to the reconciliations due to the API server latency, partitioned by host.",
+		},
+		[]string{"host"},
+	)
+)
+
+// RegisterMetrics registers the client-go rate limiter and request latency
+// metrics with the registry, and feeds the request latency to the Throttle,
+// which can be nil.
+func RegisterMetrics(registry prometheus.Registerer, t *Throttle) {
+	registry.MustRegister(rateLimiterLatency, requestLatency, throttleDelay)
+
+	clientmetrics.RateLimiterLatency = &latencyAdapter{metric: rateLimiterLatency}
+	clientmetrics.RequestLatency = &latencyAdapter{metric: requestLatency, throttle: t}
+}
+
+// RecordDelay records the delay applied to the reconciliations targeting the host.
+func RecordDelay(host string, delay time.Duration) {
+	throttleDelay.WithLabelValues(host).Set(delay.Seconds())
+}
+
+type latencyAdapter struct {
+	metric   *prometheus.HistogramVec
+	throttle *Throttle
+}
+
+func (l *latencyAdapter) Observe(_ context.Context, verb string, u url.URL, latency time.Duration) {
+	l.metric.WithLabelValues(verb, u.Host).Observe(latency.Seconds())
+	if l.throttle != nil {
+		l.throttle.Observe(u.Host, latency)
+	}
+}
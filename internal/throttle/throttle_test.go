@@ -0,0 +1,95 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package throttle
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"k8s.io/client-go/rest"
+	clientmetrics "k8s.io/client-go/tools/metrics"
+)
+
+func TestThrottle_Delay(t *testing.T) {
+	g := NewWithT(t)
+
+	th := New(time.Second, 10*time.Second)
+
+	t.Run("does not delay unknown hosts", func(t *testing.T) {
+		g.Expect(th.Delay("unknown:443")).To(BeZero())
+	})
+
+	t.Run("does not delay below the threshold", func(t *testing.T) {
+		th.Observe("fast:443", 100*time.Millisecond)
+		g.Expect(th.Delay("fast:443")).To(BeZero())
+	})
+
+	t.Run("delays proportionally to the excess latency", func(t *testing.T) {
+		th.Observe("slow:443", 1500*time.Millisecond)
+		g.Expect(th.Delay("slow:443")).To(Equal(5 * time.Second))
+	})
+
+	t.Run("caps the delay", func(t *testing.T) {
+		th.Observe("down:443", 30*time.Second)
+		g.Expect(th.Delay("down:443")).To(Equal(10 * time.Second))
+	})
+
+	t.Run("recovers with the moving average", func(t *testing.T) {
+		for i := 0; i < 50; i++ {
+			th.Observe("down:443", 100*time.Millisecond)
+		}
+		g.Expect(th.Delay("down:443")).To(BeZero())
+	})
+
+	t.Run("is disabled without threshold", func(t *testing.T) {
+		disabled := New(0, 10*time.Second)
+		disabled.Observe("slow:443", time.Minute)
+		g.Expect(disabled.Delay("slow:443")).To(BeZero())
+
+		var nilThrottle *Throttle
+		g.Expect(nilThrottle.Delay("slow:443")).To(BeZero())
+	})
+}
+
+func TestHost(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(Host(&rest.Config{Host: "https://10.0.0.1:6443"})).To(Equal("10.0.0.1:6443"))
+	g.Expect(Host(&rest.Config{Host: "10.0.0.1:6443"})).To(Equal("10.0.0.1:6443"))
+}
+
+func TestRegisterMetrics(t *testing.T) {
+	g := NewWithT(t)
+
+	th := New(time.Second, 10*time.Second)
+	registry := prometheus.NewRegistry()
+	RegisterMetrics(registry, th)
+
+	u := url.URL{Scheme: "https", Host: "cluster:443", Path: "/api/v1/namespaces"}
+	clientmetrics.RequestLatency.Observe(context.Background(), "GET", u, 3*time.Second)
+	clientmetrics.RateLimiterLatency.Observe(context.Background(), "GET", u, time.Second)
+	RecordDelay("cluster:443", th.Delay("cluster:443"))
+
+	g.Expect(testutil.CollectAndCount(requestLatency)).To(Equal(1))
+	g.Expect(testutil.CollectAndCount(rateLimiterLatency)).To(Equal(1))
+	g.Expect(testutil.ToFloat64(throttleDelay.WithLabelValues("cluster:443"))).To(Equal(10.0))
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package validationrules evaluates the CEL validation rules of a
+// Kustomization against the objects of its manifests, e.g. to detect
+// workloads without the node selectors or tolerations required to be
+// scheduled on Windows or ARM nodes.
+package validationrules
+
+import (
+	"fmt"
+	"slices"
+
+	ssautil "github.com/fluxcd/pkg/ssa/utils"
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/ext"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+)
+
+const (
+	// MaxWarnings is the maximum number of warnings returned by Validate.
+	MaxWarnings = 50
+
+	// costLimit bounds the cost of the evaluation of an expression.
+	costLimit = 1000000
+)
+
+type rule struct {
+	kustomizev1.ValidationRule
+	program cel.Program
+}
+
+// Validator evaluates compiled validation rules.
+type Validator struct {
+	rules []rule
+}
+
+// Compile returns a Validator for the given rules, or an error if
+// an expression is invalid or doesn't return a bool.
+func Compile(rules []kustomizev1.ValidationRule) (*Validator, error) {
+	env, err := cel.NewEnv(
+		cel.Variable("object", cel.DynType),
+		cel.OptionalTypes(),
+		ext.Strings(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	v := &Validator{}
+	for _, r := range rules {
+		ast, issues := env.Compile(r.Expression)
+		if issues != nil && issues.Err() != nil {
+			return nil, fmt.Errorf("invalid expression of the validation rule '%s': %w", r.Name, issues.Err())
+		}
+		if ast.OutputType() != cel.BoolType && ast.OutputType() != cel.DynType {
+			return nil, fmt.Errorf("invalid expression of the validation rule '%s': must return a bool, got %s",
+				r.Name, ast.OutputType())
+		}
+		prg, err := env.Program(ast, cel.CostLimit(costLimit))
+		if err != nil {
+			return nil, fmt.Errorf("invalid expression of the validation rule '%s': %w", r.Name, err)
+		}
+		v.rules = append(v.rules, rule{ValidationRule: r, program: prg})
+	}
+	return v, nil
+}
+
+// Validate evaluates the rules against the objects, and returns the
+// violations in the 'Kind/namespace/name: rule: message' format.
+// Evaluation errors, e.g. a missing field, are reported as violations.
+func (v *Validator) Validate(objects []*unstructured.Unstructured) []string {
+	var warnings []string
+	for _, obj := range objects {
+		for _, r := range v.rules {
+			if len(r.Kinds) > 0 && !slices.Contains(r.Kinds, obj.GetKind()) {
+				continue
+			}
+			if len(warnings) >= MaxWarnings {
+				return warnings
+			}
+
+			out, _, err := r.program.Eval(map[string]any{"object": obj.Object})
+			if err != nil {
+				warnings = append(warnings, fmt.Sprintf("%s: %s: evaluation failed: %s",
+					ssautil.FmtUnstructured(obj), r.Name, err))
+				continue
+			}
+			if valid, ok := out.Value().(bool); !ok || !valid {
+				msg := r.Message
+				if msg == "" {
+					msg = r.Expression
+				}
+				warnings = append(warnings, fmt.Sprintf("%s: %s: %s", ssautil.FmtUnstructured(obj), r.Name, msg))
+			}
+		}
+	}
+	return warnings
+}
@@ -0,0 +1,138 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validationrules
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+)
+
+func readObjects(t *testing.T, docs string) []*unstructured.Unstructured {
+	t.Helper()
+	var objects []*unstructured.Unstructured
+	for _, doc := range strings.Split(docs, "---\n") {
+		obj := &unstructured.Unstructured{}
+		if err := yaml.Unmarshal([]byte(doc), &obj.Object); err != nil {
+			t.Fatal(err)
+		}
+		objects = append(objects, obj)
+	}
+	return objects
+}
+
+func TestValidator_Validate(t *testing.T) {
+	g := NewWithT(t)
+
+	objects := readObjects(t, `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: linux
+  namespace: apps
+spec:
+  template:
+    spec:
+      nodeSelector:
+        kubernetes.io/os: linux
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: any
+  namespace: apps
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        image: ghcr.io/org/app:v1-arm64
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+  namespace: apps
+`)
+
+	v, err := Compile([]kustomizev1.ValidationRule{
+		{
+			Name:       "os-selector",
+			Kinds:      []string{"Deployment"},
+			Expression: `has(object.spec.template.spec.nodeSelector) && 'kubernetes.io/os' in object.spec.template.spec.nodeSelector`,
+			Message:    "workloads must select the OS of the nodes",
+		},
+		{
+			Name:       "arm-images",
+			Kinds:      []string{"Deployment"},
+			Expression: `object.spec.template.spec.containers.all(c, !c.image.endsWith('-arm64'))`,
+		},
+	})
+	g.Expect(err).ToNot(HaveOccurred())
+
+	g.Expect(v.Validate(objects)).To(Equal([]string{
+		"Deployment/apps/linux: arm-images: evaluation failed: no such key: containers",
+		"Deployment/apps/any: os-selector: workloads must select the OS of the nodes",
+		"Deployment/apps/any: arm-images: object.spec.template.spec.containers.all(c, !c.image.endsWith('-arm64'))",
+	}))
+}
+
+func TestCompile(t *testing.T) {
+	g := NewWithT(t)
+
+	_, err := Compile([]kustomizev1.ValidationRule{{Name: "invalid", Expression: "object.kind =="}})
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(ContainSubstring("validation rule 'invalid'"))
+
+	_, err = Compile([]kustomizev1.ValidationRule{{Name: "string", Expression: "'kind'"}})
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(ContainSubstring("must return a bool"))
+}
+
+func TestValidator_OptionalTypes(t *testing.T) {
+	g := NewWithT(t)
+
+	objects := readObjects(t, `apiVersion: apps/v1
+kind: DaemonSet
+metadata:
+  name: agent
+  namespace: apps
+spec:
+  template:
+    spec:
+      nodeSelector:
+        kubernetes.io/os: windows
+      tolerations:
+      - key: os
+        value: windows
+`)
+
+	v, err := Compile([]kustomizev1.ValidationRule{{
+		Name: "windows-os-selector",
+		Expression: `object.spec.template.spec.?nodeSelector[?'kubernetes.io/os'].orValue('') == 'windows' &&
+			object.spec.template.spec.?tolerations.orValue([]).exists(t, t.key == 'os')`,
+	}})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(v.Validate(objects)).To(BeEmpty())
+
+	delete(objects[0].Object["spec"].(map[string]any)["template"].(map[string]any)["spec"].(map[string]any), "tolerations")
+	g.Expect(v.Validate(objects)).To(HaveLen(1))
+}
@@ -0,0 +1,235 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package webhookbreaker tracks the failures of the API server to call the
+// admission webhooks, and opens a circuit for the webhooks which fail too
+// often, so that the applies of the kinds they intercept are delayed
+// instead of retried in a storm while the webhook is flapping.
+package webhookbreaker
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// failedCall matches the name of an admission webhook which the API server
+// failed to call, e.g. 'failed calling webhook "validate.kyverno.svc":
+// failed to call webhook: Post ...'. The requests denied by a webhook are
+// not failures.
+var failedCall = regexp.MustCompile(`failed calling webhook "([^"]+)"`)
+
+// Webhooks returns the names of the admission webhooks the API server
+// failed to call, as reported in the given error.
+func Webhooks(err error) []string {
+	if err == nil {
+		return nil
+	}
+
+	var webhooks []string
+	for _, match := range failedCall.FindAllStringSubmatch(err.Error(), -1) {
+		if !slices.Contains(webhooks, match[1]) {
+			webhooks = append(webhooks, match[1])
+		}
+	}
+	sort.Strings(webhooks)
+	return webhooks
+}
+
+// OpenError is returned for the applies delayed by an open circuit.
+type OpenError struct {
+	// Webhook is the name of the flapping webhook.
+	Webhook string
+
+	// Configuration is the webhook configuration, in the 'Kind/name'
+	// format.
+	Configuration string
+
+	// Until is the time at which the circuit is half-open, and the
+	// apply is attempted again.
+	Until time.Time
+}
+
+func (e *OpenError) Error() string {
+	return fmt.Sprintf("circuit open for the flapping webhook '%s' of %s, apply delayed until %s",
+		e.Webhook, e.Configuration, e.Until.UTC().Format(time.RFC3339))
+}
+
+// Breaker keeps a circuit per webhook.
+type Breaker struct {
+	threshold int
+	window    time.Duration
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu       sync.Mutex
+	circuits map[string]*circuit
+}
+
+type circuit struct {
+	// failures are the times of the failures within the window.
+	failures []time.Time
+	// openUntil is the end of the cooldown, zero if the circuit is closed.
+	openUntil time.Time
+	// configuration is the webhook configuration in the 'Kind/name' format.
+	configuration string
+	// rules are the rules of the webhook, which select the resources
+	// whose applies are delayed while the circuit is open.
+	rules []admissionregistrationv1.RuleWithOperations
+}
+
+// New returns a Breaker which opens the circuit of a webhook when its
+// failures within the window reach the threshold. The circuit stays open
+// for the cooldown, then the applies are attempted again: the circuit is
+// closed by a successful apply, and opened again by the next failure if
+// the threshold is still reached.
+func New(threshold int, window, cooldown time.Duration) *Breaker {
+	return &Breaker{
+		threshold: threshold,
+		window:    window,
+		cooldown:  cooldown,
+		now:       time.Now,
+		circuits:  make(map[string]*circuit),
+	}
+}
+
+// RecordFailure records a failed call of the webhook, and returns true if
+// its circuit is opened by this failure. The rules of the webhook must be
+// set with SetRules once the circuit is opened.
+func (b *Breaker) RecordFailure(webhook string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	c, ok := b.circuits[webhook]
+	if !ok {
+		c = &circuit{}
+		b.circuits[webhook] = c
+	}
+	c.failures = append(slices.DeleteFunc(c.failures, func(t time.Time) bool {
+		return now.Sub(t) > b.window
+	}), now)
+
+	if len(c.failures) < b.threshold || now.Before(c.openUntil) {
+		return false
+	}
+	c.openUntil = now.Add(b.cooldown)
+	circuitOpen.WithLabelValues(webhook).Set(1)
+	return true
+}
+
+// SetRules sets the webhook configuration and the rules of the webhook,
+// which select the resources affected by its circuit.
+func (b *Breaker) SetRules(webhook, configuration string, rules []admissionregistrationv1.RuleWithOperations) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if c, ok := b.circuits[webhook]; ok {
+		c.configuration = configuration
+		c.rules = rules
+	}
+}
+
+// Check returns an OpenError if the applies of any of the resources are
+// intercepted by a webhook with an open circuit.
+func (b *Breaker) Check(resources []schema.GroupResource) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	webhooks := make([]string, 0, len(b.circuits))
+	for webhook := range b.circuits {
+		webhooks = append(webhooks, webhook)
+	}
+	sort.Strings(webhooks)
+
+	for _, webhook := range webhooks {
+		c := b.circuits[webhook]
+		if c.openUntil.IsZero() {
+			continue
+		}
+		if !now.Before(c.openUntil) {
+			circuitOpen.WithLabelValues(webhook).Set(0)
+			continue
+		}
+		for _, gr := range resources {
+			if matches(c.rules, gr) {
+				return &OpenError{Webhook: webhook, Configuration: c.configuration, Until: c.openUntil}
+			}
+		}
+	}
+	return nil
+}
+
+// RecordSuccess closes the circuits of the webhooks intercepting the
+// applies of the resources, which succeeded.
+func (b *Breaker) RecordSuccess(resources []schema.GroupResource) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for webhook, c := range b.circuits {
+		for _, gr := range resources {
+			if matches(c.rules, gr) {
+				delete(b.circuits, webhook)
+				circuitOpen.DeleteLabelValues(webhook)
+				break
+			}
+		}
+	}
+}
+
+// matches returns true if the rules intercept the create or update of
+// the resource.
+func matches(rules []admissionregistrationv1.RuleWithOperations, gr schema.GroupResource) bool {
+	for _, rule := range rules {
+		if !slices.ContainsFunc(rule.Operations, func(op admissionregistrationv1.OperationType) bool {
+			return op == admissionregistrationv1.OperationAll ||
+				op == admissionregistrationv1.Create ||
+				op == admissionregistrationv1.Update
+		}) {
+			continue
+		}
+		if !slices.Contains(rule.APIGroups, "*") && !slices.Contains(rule.APIGroups, gr.Group) {
+			continue
+		}
+		if slices.ContainsFunc(rule.Resources, func(r string) bool {
+			return r == "*" || r == "*/*" || r == gr.Resource
+		}) {
+			return true
+		}
+	}
+	return false
+}
+
+var circuitOpen = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "gotk_webhook_circuit_open",
+		Help: "Whether the circuit of an admission webhook is open, delaying the applies of the kinds it intercepts.",
+	},
+	[]string{"webhook"},
+)
+
+// RegisterMetrics registers the circuit metrics with the registry.
+func RegisterMetrics(registry prometheus.Registerer) {
+	registry.MustRegister(circuitOpen)
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhookbreaker
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestWebhooks(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(Webhooks(nil)).To(BeEmpty())
+	g.Expect(Webhooks(errors.New(`admission webhook "validate.kyverno.svc" denied the request`))).To(BeEmpty())
+
+	err := errors.New(`Deployment/apps/web dry-run failed: Internal error occurred: failed calling webhook "validate.kyverno.svc": ` +
+		`failed to call webhook: Post "https://kyverno-svc.kyverno.svc:443/validate?timeout=10s": context deadline exceeded
+ConfigMap/apps/config dry-run failed: Internal error occurred: failed calling webhook "mutate.kyverno.svc": failed to call webhook
+Service/apps/web dry-run failed: Internal error occurred: failed calling webhook "validate.kyverno.svc": failed to call webhook`)
+	g.Expect(Webhooks(err)).To(Equal([]string{"mutate.kyverno.svc", "validate.kyverno.svc"}))
+}
+
+func TestBreaker(t *testing.T) {
+	g := NewWithT(t)
+
+	deployments := schema.GroupResource{Group: "apps", Resource: "deployments"}
+	configMaps := schema.GroupResource{Resource: "configmaps"}
+
+	now := time.Date(2024, 4, 1, 12, 0, 0, 0, time.UTC)
+	b := New(3, 5*time.Minute, 2*time.Minute)
+	b.now = func() time.Time { return now }
+
+	// The failures outside the window are not counted.
+	g.Expect(b.RecordFailure("validate.kyverno.svc")).To(BeFalse())
+	now = now.Add(6 * time.Minute)
+	g.Expect(b.RecordFailure("validate.kyverno.svc")).To(BeFalse())
+	g.Expect(b.RecordFailure("validate.kyverno.svc")).To(BeFalse())
+	g.Expect(b.RecordFailure("validate.kyverno.svc")).To(BeTrue())
+	g.Expect(b.RecordFailure("validate.kyverno.svc")).To(BeFalse())
+
+	b.SetRules("validate.kyverno.svc", "ValidatingWebhookConfiguration/kyverno", []admissionregistrationv1.RuleWithOperations{
+		{
+			Operations: []admissionregistrationv1.OperationType{admissionregistrationv1.Create, admissionregistrationv1.Update},
+			Rule:       admissionregistrationv1.Rule{APIGroups: []string{"apps"}, Resources: []string{"deployments"}},
+		},
+		{
+			Operations: []admissionregistrationv1.OperationType{admissionregistrationv1.Delete},
+			Rule:       admissionregistrationv1.Rule{APIGroups: []string{"*"}, Resources: []string{"*"}},
+		},
+	})
+
+	g.Expect(b.Check([]schema.GroupResource{configMaps})).To(Succeed())
+	err := b.Check([]schema.GroupResource{configMaps, deployments})
+	g.Expect(err).To(MatchError("circuit open for the flapping webhook 'validate.kyverno.svc' of " +
+		"ValidatingWebhookConfiguration/kyverno, apply delayed until 2024-04-01T12:08:00Z"))
+
+	// The applies are attempted again after the cooldown.
+	now = now.Add(2 * time.Minute)
+	g.Expect(b.Check([]schema.GroupResource{deployments})).To(Succeed())
+
+	// A failure reopens the circuit while the threshold is reached.
+	g.Expect(b.RecordFailure("validate.kyverno.svc")).To(BeTrue())
+	g.Expect(b.Check([]schema.GroupResource{deployments})).ToNot(Succeed())
+
+	// A successful apply closes the circuit.
+	now = now.Add(2 * time.Minute)
+	b.RecordSuccess([]schema.GroupResource{deployments})
+	g.Expect(b.RecordFailure("validate.kyverno.svc")).To(BeFalse())
+}
+
+func TestMatches(t *testing.T) {
+	rule := func(groups, resources []string, ops ...admissionregistrationv1.OperationType) admissionregistrationv1.RuleWithOperations {
+		return admissionregistrationv1.RuleWithOperations{
+			Operations: ops,
+			Rule:       admissionregistrationv1.Rule{APIGroups: groups, Resources: resources},
+		}
+	}
+	deployments := schema.GroupResource{Group: "apps", Resource: "deployments"}
+
+	tests := []struct {
+		name string
+		rule admissionregistrationv1.RuleWithOperations
+		want bool
+	}{
+		{"exact", rule([]string{"apps"}, []string{"deployments"}, admissionregistrationv1.Create), true},
+		{"wildcards", rule([]string{"*"}, []string{"*"}, admissionregistrationv1.OperationAll), true},
+		{"subresources wildcard", rule([]string{"*"}, []string{"*/*"}, admissionregistrationv1.Update), true},
+		{"subresources only", rule([]string{"apps"}, []string{"deployments/scale"}, admissionregistrationv1.Update), false},
+		{"other group", rule([]string{""}, []string{"deployments"}, admissionregistrationv1.Create), false},
+		{"delete only", rule([]string{"apps"}, []string{"deployments"}, admissionregistrationv1.Delete), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(matches([]admissionregistrationv1.RuleWithOperations{tt.rule}, deployments)).To(Equal(tt.want))
+		})
+	}
+}
@@ -0,0 +1,269 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package webhookwatch watches the endpoints of the webhook services which
+// couldn't be called when applying the Kustomizations, and reports which
+// Kustomizations can be applied again once the services are back. The
+// EndpointSlices of each service are watched on their own, with a label
+// selector, for as long as at least one Kustomization waits for it.
+package webhookwatch
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"sync"
+
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	discoveryinformers "k8s.io/client-go/informers/discovery/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// webhookURL matches the in-cluster URL of a webhook which the API server
+// failed to call, in the errors returned for admission and conversion
+// webhooks, e.g. 'failed calling webhook "x": failed to call webhook:
+// Post "https://svc.ns.svc:443/validate?timeout=10s": ...'.
+var webhookURL = regexp.MustCompile(`webhook[^\n]*?"https://([a-z0-9]([-a-z0-9]*[a-z0-9])?)\.([a-z0-9]([-a-z0-9]*[a-z0-9])?)\.svc[.:/"]`)
+
+// Services returns the webhook services the API server failed to call,
+// as reported in the given error.
+func Services(err error) []types.NamespacedName {
+	if err == nil {
+		return nil
+	}
+
+	seen := make(map[types.NamespacedName]struct{})
+	var services []types.NamespacedName
+	for _, match := range webhookURL.FindAllStringSubmatch(err.Error(), -1) {
+		svc := types.NamespacedName{Namespace: match[3], Name: match[1]}
+		if _, ok := seen[svc]; ok {
+			continue
+		}
+		seen[svc] = struct{}{}
+		services = append(services, svc)
+	}
+	sort.Slice(services, func(i, j int) bool {
+		return services[i].String() < services[j].String()
+	})
+	return services
+}
+
+// NotifyFunc is called for each Kustomization waiting for a webhook
+// service which has ready endpoints again.
+type NotifyFunc func(ctx context.Context, key types.NamespacedName, svc types.NamespacedName)
+
+// Manager runs a watch per webhook service, until the service has ready
+// endpoints again or no Kustomization waits for it anymore.
+type Manager struct {
+	ctx    context.Context
+	client kubernetes.Interface
+	notify NotifyFunc
+
+	mu       sync.Mutex
+	watches  map[types.NamespacedName]*watch
+	services map[types.NamespacedName][]types.NamespacedName
+}
+
+type watch struct {
+	owners map[types.NamespacedName]struct{}
+	cancel context.CancelFunc
+	// ready is the readiness of the service when last observed. The owners
+	// are notified when it goes from not ready to ready, so that a webhook
+	// failing with ready endpoints doesn't cause a reconciliation loop.
+	ready bool
+	// synced is set once the readiness after the initial list is observed.
+	synced bool
+}
+
+// NewManager returns a Manager which runs the watches until the
+// given context is cancelled, and calls notify on recovery.
+func NewManager(ctx context.Context, client kubernetes.Interface, notify NotifyFunc) *Manager {
+	return &Manager{
+		ctx:      ctx,
+		client:   client,
+		notify:   notify,
+		watches:  make(map[types.NamespacedName]*watch),
+		services: make(map[types.NamespacedName][]types.NamespacedName),
+	}
+}
+
+// Track sets the webhook services the Kustomization waits for. The
+// watches of the new services are started, and the ones of the services
+// no Kustomization waits for anymore are stopped.
+func (m *Manager) Track(key types.NamespacedName, services []types.NamespacedName) {
+	if m == nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	wanted := make(map[types.NamespacedName]struct{}, len(services))
+	for _, svc := range services {
+		wanted[svc] = struct{}{}
+	}
+
+	for _, svc := range m.services[key] {
+		if _, ok := wanted[svc]; !ok {
+			m.release(key, svc)
+		}
+	}
+
+	tracked := make([]types.NamespacedName, 0, len(wanted))
+	for svc := range wanted {
+		w, ok := m.watches[svc]
+		if !ok {
+			w = m.start(svc)
+			m.watches[svc] = w
+		}
+		w.owners[key] = struct{}{}
+		tracked = append(tracked, svc)
+	}
+
+	if len(tracked) == 0 {
+		delete(m.services, key)
+		return
+	}
+	m.services[key] = tracked
+}
+
+// Forget stops tracking the webhook services of the Kustomization.
+func (m *Manager) Forget(key types.NamespacedName) {
+	m.Track(key, nil)
+}
+
+// IsWatching returns true if the webhook service is watched.
+func (m *Manager) IsWatching(svc types.NamespacedName) bool {
+	if m == nil {
+		return false
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, ok := m.watches[svc]
+	return ok
+}
+
+// release removes the Kustomization from the owners of the service,
+// and stops its watch when it has no owners left.
+func (m *Manager) release(key types.NamespacedName, svc types.NamespacedName) {
+	w, ok := m.watches[svc]
+	if !ok {
+		return
+	}
+	delete(w.owners, key)
+	if len(w.owners) == 0 {
+		w.cancel()
+		delete(m.watches, svc)
+	}
+}
+
+func (m *Manager) start(svc types.NamespacedName) *watch {
+	ctx, cancel := context.WithCancel(m.ctx)
+	w := &watch{
+		owners: make(map[types.NamespacedName]struct{}),
+		cancel: cancel,
+	}
+
+	informer := discoveryinformers.NewFilteredEndpointSliceInformer(m.client, svc.Namespace, 0,
+		cache.Indexers{}, func(opts *metav1.ListOptions) {
+			opts.LabelSelector = labels.SelectorFromSet(labels.Set{discoveryv1.LabelServiceName: svc.Name}).String()
+		})
+	observe := func(initial bool) {
+		m.observe(ctx, svc, w, hasReadyEndpoint(informer.GetStore().List()), initial)
+	}
+	registration, _ := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(_ interface{}) { observe(false) },
+		UpdateFunc: func(_, _ interface{}) { observe(false) },
+		DeleteFunc: func(_ interface{}) { observe(false) },
+	})
+	go informer.Run(ctx.Done())
+	go func() {
+		// The readiness after the initial list is the baseline, the
+		// events received before are ignored.
+		if registration != nil && cache.WaitForCacheSync(ctx.Done(), registration.HasSynced) {
+			observe(true)
+		}
+	}()
+
+	return w
+}
+
+// observe records the readiness of the service and, when it becomes
+// ready, notifies the owners and stops the watch.
+func (m *Manager) observe(ctx context.Context, svc types.NamespacedName, w *watch, ready, initial bool) {
+	m.mu.Lock()
+	if m.watches[svc] != w || ctx.Err() != nil || (!initial && !w.synced) {
+		m.mu.Unlock()
+		return
+	}
+	recovered := ready && !w.ready && !initial
+	w.ready = ready
+	w.synced = true
+	var owners []types.NamespacedName
+	if recovered {
+		for key := range w.owners {
+			owners = append(owners, key)
+			m.release(key, svc)
+			m.untrack(key, svc)
+		}
+	}
+	m.mu.Unlock()
+
+	sort.Slice(owners, func(i, j int) bool {
+		return owners[i].String() < owners[j].String()
+	})
+	// The watch context is cancelled when the owners are released.
+	for _, key := range owners {
+		m.notify(m.ctx, key, svc)
+	}
+}
+
+// untrack removes the service from the ones the Kustomization waits for.
+func (m *Manager) untrack(key types.NamespacedName, svc types.NamespacedName) {
+	var services []types.NamespacedName
+	for _, s := range m.services[key] {
+		if s != svc {
+			services = append(services, s)
+		}
+	}
+	if len(services) == 0 {
+		delete(m.services, key)
+		return
+	}
+	m.services[key] = services
+}
+
+func hasReadyEndpoint(objects []interface{}) bool {
+	for _, obj := range objects {
+		slice, ok := obj.(*discoveryv1.EndpointSlice)
+		if !ok {
+			continue
+		}
+		for _, ep := range slice.Endpoints {
+			// A nil ready condition must be interpreted as ready.
+			if ep.Conditions.Ready == nil || *ep.Conditions.Ready {
+				return true
+			}
+		}
+	}
+	return false
+}
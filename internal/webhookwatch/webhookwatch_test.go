@@ -0,0 +1,124 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhookwatch
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+type recorder struct {
+	mu   sync.Mutex
+	keys []types.NamespacedName
+}
+
+func (r *recorder) notify(_ context.Context, key types.NamespacedName, _ types.NamespacedName) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.keys = append(r.keys, key)
+}
+
+func (r *recorder) get() []types.NamespacedName {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]types.NamespacedName(nil), r.keys...)
+}
+
+func newEndpointSlice(svc types.NamespacedName, ready bool) *discoveryv1.EndpointSlice {
+	return &discoveryv1.EndpointSlice{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: svc.Namespace,
+			Name:      svc.Name + "-abcde",
+			Labels:    map[string]string{discoveryv1.LabelServiceName: svc.Name},
+		},
+		AddressType: discoveryv1.AddressTypeIPv4,
+		Endpoints: []discoveryv1.Endpoint{{
+			Addresses:  []string{"10.0.0.1"},
+			Conditions: discoveryv1.EndpointConditions{Ready: &ready},
+		}},
+	}
+}
+
+func TestServices(t *testing.T) {
+	g := NewWithT(t)
+
+	err := errors.New(`ConfigMap/apps/settings dry-run failed: Internal error occurred: failed calling webhook "validate.kyverno.svc-fail": ` +
+		`failed to call webhook: Post "https://kyverno-svc.kyverno.svc:443/validate/fail?timeout=10s": dial tcp 10.96.0.10:443: connect: connection refused
+Certificate/apps/tls dry-run failed: Internal error occurred: failed calling webhook "webhook.cert-manager.io": ` +
+		`failed to call webhook: Post "https://cert-manager-webhook.cert-manager.svc:443/validate?timeout=30s": no endpoints available for service "cert-manager-webhook"
+Deployment/apps/web dry-run failed: Internal error occurred: failed calling webhook "validate.kyverno.svc-fail": ` +
+		`failed to call webhook: Post "https://kyverno-svc.kyverno.svc:443/validate/fail?timeout=10s": context deadline exceeded`)
+
+	g.Expect(Services(err)).To(Equal([]types.NamespacedName{
+		{Namespace: "cert-manager", Name: "cert-manager-webhook"},
+		{Namespace: "kyverno", Name: "kyverno-svc"},
+	}))
+	g.Expect(Services(errors.New(`admission webhook "validate.example.com" denied the request`))).To(BeEmpty())
+	g.Expect(Services(errors.New(`failed calling webhook "x": Post "https://hooks.example.com/validate": EOF`))).To(BeEmpty())
+	g.Expect(Services(nil)).To(BeEmpty())
+}
+
+func TestManager_Track(t *testing.T) {
+	g := NewWithT(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	svc := types.NamespacedName{Namespace: "cert-manager", Name: "cert-manager-webhook"}
+	client := fake.NewSimpleClientset(newEndpointSlice(svc, false))
+	rec := &recorder{}
+	m := NewManager(ctx, client, rec.notify)
+
+	first := types.NamespacedName{Namespace: "apps", Name: "first"}
+	second := types.NamespacedName{Namespace: "apps", Name: "second"}
+
+	t.Run("stops watching when no longer waited for", func(t *testing.T) {
+		m.Track(first, []types.NamespacedName{svc})
+		g.Expect(m.IsWatching(svc)).To(BeTrue())
+
+		m.Forget(first)
+		g.Expect(m.IsWatching(svc)).To(BeFalse())
+	})
+
+	t.Run("notifies the owners when the service recovers", func(t *testing.T) {
+		m.Track(first, []types.NamespacedName{svc})
+		m.Track(second, []types.NamespacedName{svc, svc})
+
+		// The initial list doesn't notify the owners.
+		g.Consistently(rec.get, 500*time.Millisecond).Should(BeEmpty())
+
+		_, err := client.DiscoveryV1().EndpointSlices(svc.Namespace).Update(ctx, newEndpointSlice(svc, true), metav1.UpdateOptions{})
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Eventually(rec.get, 5*time.Second).Should(Equal([]types.NamespacedName{first, second}))
+		g.Expect(m.IsWatching(svc)).To(BeFalse())
+	})
+
+	t.Run("doesn't notify when the service is already ready", func(t *testing.T) {
+		m.Track(first, []types.NamespacedName{svc})
+		g.Consistently(rec.get, 500*time.Millisecond).Should(HaveLen(2))
+		g.Expect(m.IsWatching(svc)).To(BeTrue())
+	})
+}
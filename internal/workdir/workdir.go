@@ -0,0 +1,169 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package workdir keeps track of the disk space used by the working
+// directories in which the source artifacts are extracted and built.
+package workdir
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Prefix is the name prefix of the working directories.
+const Prefix = "kustomization-"
+
+var usageBytes = prometheus.NewGauge(
+	prometheus.GaugeOpts{
+		Name: "gotk_workdir_usage_bytes",
+		Help: "Disk space used by the working directories of the reconciliations.",
+	},
+)
+
+// RegisterMetrics registers the disk usage metric with the registry.
+func RegisterMetrics(registry prometheus.Registerer) {
+	registry.MustRegister(usageBytes)
+}
+
+// Janitor enforces a disk quota on the working directories, and removes
+// the ones leaked by a previous run of the controller.
+type Janitor struct {
+	root  string
+	quota int64
+}
+
+// New returns a Janitor for the working directories created in root,
+// which defaults to the OS temporary directory. A quota of zero disables
+// the quota enforcement.
+func New(root string, quota int64) *Janitor {
+	if root == "" {
+		root = os.TempDir()
+	}
+	return &Janitor{root: root, quota: quota}
+}
+
+// Root returns the directory in which the working directories are created,
+// or an empty string for the OS temporary directory if the Janitor is nil.
+func (j *Janitor) Root() string {
+	if j == nil {
+		return ""
+	}
+	return j.root
+}
+
+// CheckQuota returns an error if the working directories use more disk
+// space than the quota. The quota is soft, as the concurrent
+// reconciliations may check it before using any space.
+func (j *Janitor) CheckQuota() error {
+	if j == nil || j.quota <= 0 {
+		return nil
+	}
+	usage, err := j.Usage()
+	if err != nil {
+		return err
+	}
+	if usage >= j.quota {
+		return fmt.Errorf("working directories quota exceeded: %d bytes in use, quota is %d bytes", usage, j.quota)
+	}
+	return nil
+}
+
+// Usage returns the disk space used by the working directories, and
+// records it in the metrics.
+func (j *Janitor) Usage() (int64, error) {
+	dirs, err := j.list()
+	if err != nil {
+		return 0, err
+	}
+
+	var usage int64
+	for _, dir := range dirs {
+		usage += Size(dir)
+	}
+
+	usageBytes.Set(float64(usage))
+	return usage, nil
+}
+
+// Size returns the size of the regular files in the directory tree.
+func Size(dir string) int64 {
+	var size int64
+	_ = filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// The directory may be removed by the reconciliation
+			// while walking it.
+			return nil
+		}
+		if info, err := d.Info(); err == nil && info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
+
+// Clean removes all the working directories. It must be called before
+// any reconciliation is started, to remove the directories leaked by
+// a previous run which crashed.
+func (j *Janitor) Clean() (int, error) {
+	dirs, err := j.list()
+	if err != nil {
+		return 0, err
+	}
+	for _, dir := range dirs {
+		if err := os.RemoveAll(dir); err != nil {
+			return 0, err
+		}
+	}
+	return len(dirs), nil
+}
+
+// Run records the disk usage at every interval, until the context
+// is cancelled.
+func (j *Janitor) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		_, _ = j.Usage()
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (j *Janitor) list() ([]string, error) {
+	entries, err := os.ReadDir(j.root)
+	if err != nil {
+		return nil, err
+	}
+	var dirs []string
+	for _, e := range entries {
+		if e.IsDir() && strings.HasPrefix(e.Name(), Prefix) {
+			dirs = append(dirs, filepath.Join(j.root, e.Name()))
+		}
+	}
+	return dirs, nil
+}
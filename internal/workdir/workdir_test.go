@@ -0,0 +1,61 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workdir
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestJanitor(t *testing.T) {
+	g := NewWithT(t)
+
+	root := t.TempDir()
+	leaked := filepath.Join(root, Prefix+"123")
+	g.Expect(os.MkdirAll(filepath.Join(leaked, "deploy"), 0o755)).To(Succeed())
+	g.Expect(os.WriteFile(filepath.Join(leaked, "deploy", "app.yaml"), make([]byte, 600), 0o644)).To(Succeed())
+
+	other := filepath.Join(root, "other")
+	g.Expect(os.MkdirAll(other, 0o755)).To(Succeed())
+	g.Expect(os.WriteFile(filepath.Join(other, "data"), make([]byte, 1000), 0o644)).To(Succeed())
+
+	t.Run("reports the working directories usage", func(t *testing.T) {
+		j := New(root, 0)
+		g.Expect(j.Usage()).To(Equal(int64(600)))
+		g.Expect(testutil.ToFloat64(usageBytes)).To(Equal(600.0))
+	})
+
+	t.Run("enforces the quota", func(t *testing.T) {
+		g.Expect(New(root, 1000).CheckQuota()).To(Succeed())
+		g.Expect(New(root, 0).CheckQuota()).To(Succeed())
+		g.Expect(New(root, 500).CheckQuota()).To(MatchError(ContainSubstring("quota exceeded")))
+
+		var nilJanitor *Janitor
+		g.Expect(nilJanitor.CheckQuota()).To(Succeed())
+		g.Expect(nilJanitor.Root()).To(BeEmpty())
+	})
+
+	t.Run("cleans the leaked directories", func(t *testing.T) {
+		g.Expect(New(root, 0).Clean()).To(Equal(1))
+		g.Expect(leaked).ToNot(BeADirectory())
+		g.Expect(other).To(BeADirectory())
+	})
+}
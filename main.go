@@ -17,21 +17,31 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"fmt"
+	"net/http"
 	"os"
+	"slices"
+	"strings"
 	"time"
 
 	flag "github.com/spf13/pflag"
 	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/azure"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
+	"k8s.io/client-go/rest"
+	kuberecorder "k8s.io/client-go/tools/record"
 	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
 	ctrlcache "sigs.k8s.io/controller-runtime/pkg/cache"
 	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
 	ctrlcfg "sigs.k8s.io/controller-runtime/pkg/config"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 
 	"github.com/fluxcd/cli-utils/pkg/kstatus/polling"
@@ -52,9 +62,32 @@ import (
 	sourcev1b2 "github.com/fluxcd/source-controller/api/v1beta2"
 
 	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
+	"github.com/fluxcd/kustomize-controller/internal/build"
+	"github.com/fluxcd/kustomize-controller/internal/buildartifacts"
+	"github.com/fluxcd/kustomize-controller/internal/buildservice"
+	"github.com/fluxcd/kustomize-controller/internal/buildusage"
+	"github.com/fluxcd/kustomize-controller/internal/cacheconfig"
+	"github.com/fluxcd/kustomize-controller/internal/capabilities"
+	"github.com/fluxcd/kustomize-controller/internal/cloudevents"
 	"github.com/fluxcd/kustomize-controller/internal/controller"
+	"github.com/fluxcd/kustomize-controller/internal/credexpiry"
+	"github.com/fluxcd/kustomize-controller/internal/diagnostics"
+	"github.com/fluxcd/kustomize-controller/internal/effectiveconfig"
+	"github.com/fluxcd/kustomize-controller/internal/faultinjection"
 	"github.com/fluxcd/kustomize-controller/internal/features"
+	"github.com/fluxcd/kustomize-controller/internal/flapping"
+	"github.com/fluxcd/kustomize-controller/internal/imagescan"
+	"github.com/fluxcd/kustomize-controller/internal/leadtime"
+	"github.com/fluxcd/kustomize-controller/internal/loglevel"
+	"github.com/fluxcd/kustomize-controller/internal/manifeststream"
+	"github.com/fluxcd/kustomize-controller/internal/metricsauth"
+	"github.com/fluxcd/kustomize-controller/internal/owners"
+	"github.com/fluxcd/kustomize-controller/internal/simulation"
+	intazkv "github.com/fluxcd/kustomize-controller/internal/sops/azkv"
 	"github.com/fluxcd/kustomize-controller/internal/statusreaders"
+	"github.com/fluxcd/kustomize-controller/internal/throttle"
+	"github.com/fluxcd/kustomize-controller/internal/webhookbreaker"
+	"github.com/fluxcd/kustomize-controller/internal/workdir"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -76,37 +109,157 @@ func init() {
 
 func main() {
 	var (
-		metricsAddr             string
-		eventsAddr              string
-		healthAddr              string
-		concurrent              int
-		concurrentSSA           int
-		requeueDependency       time.Duration
-		clientOptions           runtimeClient.Options
-		kubeConfigOpts          runtimeClient.KubeConfigOptions
-		logOptions              logger.Options
-		leaderElectionOptions   leaderelection.Options
-		rateLimiterOptions      runtimeCtrl.RateLimiterOptions
-		watchOptions            runtimeCtrl.WatchOptions
-		intervalJitterOptions   jitter.IntervalOptions
-		aclOptions              acl.Options
-		noRemoteBases           bool
-		httpRetry               int
-		defaultServiceAccount   string
-		featureGates            feathelper.FeatureGates
-		disallowedFieldManagers []string
+		metricsAddr               string
+		eventsAddr                string
+		cloudEventsAddr           string
+		cloudEventsSecret         string
+		healthAddr                string
+		concurrent                int
+		concurrentSSA             int
+		concurrentDecryption      int
+		dryRunCacheTTL            time.Duration
+		requeueDependency         time.Duration
+		clientOptions             runtimeClient.Options
+		kubeConfigOpts            runtimeClient.KubeConfigOptions
+		logOptions                logger.Options
+		leaderElectionOptions     leaderelection.Options
+		rateLimiterOptions        runtimeCtrl.RateLimiterOptions
+		watchOptions              runtimeCtrl.WatchOptions
+		intervalJitterOptions     jitter.IntervalOptions
+		cacheOptions              cacheconfig.Options
+		faultInjectionOptions     faultinjection.Options
+		aclOptions                acl.Options
+		noRemoteBases             bool
+		allowLoadRestrictionsNone bool
+		httpRetry                 int
+		defaultServiceAccount     string
+		defaultTimeout            time.Duration
+		defaultRetryInterval      time.Duration
+		defaultCommonLabels       map[string]string
+		featureGates              feathelper.FeatureGates
+		disallowedFieldManagers   []string
+		disallowedBuildOptions    []string
+		tenantLockdown            bool
+		tenantExemptNamespaces    []string
+		tenantDenyClusterScoped   bool
+		tenantClusterScopedKinds  []string
+		throttleLatency           time.Duration
+		throttleMaxDelay          time.Duration
+		webhookFailureThreshold   int
+		webhookFailureWindow      time.Duration
+		webhookCircuitCooldown    time.Duration
+		workDirQuota              string
+		enablePprof               bool
+		enableDiagnostics         bool
+		enableEffectiveConfig     bool
+		enableBuildService        bool
+		simulate                  bool
+		simulationAssets          string
+		enableOwnerLookup         bool
+		conversionWebhookTimeout  time.Duration
+		statusSizeLimit           string
+		orderedFanOut             bool
+		imageScannerAddr          string
+		applyChunkSize            int
+		buildErrorArtifactsTTL    time.Duration
+		manifestStreamRevisions   int
+		namespaceBaseline         string
+		mutationRules             string
+		sopsAgeKeyDir             string
+		azureKVMaxRetries         int
+		azureKVRetryDelay         time.Duration
+		azureKVTryTimeout         time.Duration
+		azureKVFailoverVaults     []string
 	)
 
 	flag.StringVar(&metricsAddr, "metrics-addr", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&eventsAddr, "events-addr", "", "The address of the events receiver.")
+	flag.StringVar(&cloudEventsAddr, "cloudevents-addr", "", "The address of the CloudEvents sink the events are published to.")
+	flag.StringVar(&cloudEventsSecret, "cloudevents-secret", "",
+		"The name of the Secret in the controller namespace holding the 'address' of the CloudEvents sink and an optional bearer 'token'.")
 	flag.StringVar(&healthAddr, "health-addr", ":9440", "The address the health endpoint binds to.")
 	flag.IntVar(&concurrent, "concurrent", 4, "The number of concurrent kustomize reconciles.")
 	flag.IntVar(&concurrentSSA, "concurrent-ssa", 4, "The number of concurrent server-side apply operations.")
+	flag.IntVar(&concurrentDecryption, "concurrent-decryption", 4, "The number of resources decrypted concurrently by each reconcile.")
 	flag.DurationVar(&requeueDependency, "requeue-dependency", 30*time.Second, "The interval at which failing dependencies are reevaluated.")
 	flag.BoolVar(&noRemoteBases, "no-remote-bases", false,
 		"Disallow remote bases usage in Kustomize overlays. When this flag is enabled, all resources must refer to local files included in the source artifact.")
+	flag.BoolVar(&allowLoadRestrictionsNone, "allow-load-restrictions-none", false,
+		"Allow Kustomizations to set '.spec.buildOptions.loadRestrictions' to 'None', permitting Kustomize overlays to load files from outside their root directory.")
+	flag.StringSliceVar(&disallowedBuildOptions, "disallowed-build-options", []string{},
+		fmt.Sprintf("Build options Kustomizations are not allowed to set in '.spec.buildOptions', one of: %s.", strings.Join(build.SupportedOptions, ", ")))
 	flag.IntVar(&httpRetry, "http-retry", 9, "The maximum number of retries when failing to fetch artifacts over HTTP.")
 	flag.StringVar(&defaultServiceAccount, "default-service-account", "", "Default service account used for impersonation.")
+	flag.DurationVar(&defaultTimeout, "default-timeout", 0,
+		"Default timeout for the apply and health checking operations of the Kustomizations which don't set '.spec.timeout'. Defaults to the Kustomization interval.")
+	flag.DurationVar(&defaultRetryInterval, "default-retry-interval", 0,
+		"Default interval at which the failed reconciliations are retried for the Kustomizations which don't set '.spec.retryInterval'. Defaults to the Kustomization interval.")
+	flag.StringToStringVar(&defaultCommonLabels, "default-common-labels", map[string]string{},
+		"Default labels set on the resources reconciled by the Kustomizations, unless '.spec.commonMetadata.labels' sets the same keys.")
+	flag.BoolVar(&tenantLockdown, "tenant-lockdown", false,
+		"Stall the Kustomizations which don't specify '.spec.serviceAccountName' or which specify '.spec.kubeConfig', in all namespaces except the exempted ones and the controller namespace.")
+	flag.StringSliceVar(&tenantExemptNamespaces, "tenant-exempt-namespaces", []string{},
+		"Namespaces in which the Kustomizations are not subject to the tenant lockdown.")
+	flag.BoolVar(&tenantDenyClusterScoped, "tenant-deny-cluster-scoped", false,
+		"Fail the reconciliations of the Kustomizations which impersonate a service account outside the exempted namespaces, when they apply cluster-scoped objects.")
+	flag.StringSliceVar(&tenantClusterScopedKinds, "tenant-cluster-scoped-kinds", []string{},
+		"Kinds of the cluster-scoped objects allowed to the tenants when '--tenant-deny-cluster-scoped' is set, e.g. 'Namespace'.")
+	flag.DurationVar(&throttleLatency, "throttle-latency-threshold", 0,
+		"The average API server latency above which the reconciliations targeting that cluster are delayed. Disabled when zero.")
+	flag.DurationVar(&throttleMaxDelay, "throttle-max-delay", 30*time.Second,
+		"The maximum delay applied to the reconciliations, when the average API server latency reaches twice the threshold.")
+	flag.IntVar(&webhookFailureThreshold, "webhook-failure-threshold", 0,
+		"The number of failed calls of an admission webhook within the failure window above which the applies of the kinds it intercepts are delayed. Disabled when zero.")
+	flag.DurationVar(&webhookFailureWindow, "webhook-failure-window", 5*time.Minute,
+		"The window in which the failed calls of an admission webhook are counted.")
+	flag.DurationVar(&webhookCircuitCooldown, "webhook-circuit-cooldown", 2*time.Minute,
+		"The delay after which the applies delayed by a flapping admission webhook are attempted again.")
+	flag.StringVar(&workDirQuota, "workdir-quota", "",
+		"The disk space, e.g. '2Gi', the working directories of the reconciliations can use before new reconciliations fail. Unlimited when empty.")
+	flag.BoolVar(&enablePprof, "enable-pprof", false,
+		"Serve the pprof and trace endpoints under /debug/pprof on the metrics address.")
+	flag.BoolVar(&enableDiagnostics, "enable-diagnostics", false,
+		"Serve a report of the queued and in-progress reconciliations and of the cache sizes under /debug/diagnostics on the metrics address.")
+	flag.BoolVar(&enableEffectiveConfig, "enable-effective-config", false,
+		"Serve the specs of the Kustomizations with the controller defaults applied, and the controller settings affecting them, under /debug/effective-config on the metrics address.")
+	flag.BoolVar(&enableBuildService, "enable-build-service", false,
+		"Serve the builds of the source archives posted under /build/<namespace>/<name> on the metrics address, rendered with the pipeline and the settings of the controller for the Kustomization.")
+	flag.BoolVar(&simulate, "simulation", false,
+		"Apply the Kustomizations to an ephemeral API server embedded in the controller instead of the cluster, to validate their reconciliation without changing the cluster.")
+	flag.StringVar(&simulationAssets, "simulation-assets", "",
+		"The directory of the etcd and kube-apiserver binaries of the simulation API server. Defaults to the KUBEBUILDER_ASSETS environment variable.")
+	flag.BoolVar(&enableOwnerLookup, "enable-owner-lookup", false,
+		"Index the Kustomizations by the objects of their inventory, and serve the lookups of the Kustomizations managing an object under /debug/owners on the metrics address.")
+	flag.DurationVar(&conversionWebhookTimeout, "conversion-webhook-timeout", 2*time.Minute,
+		"The maximum time to wait for the conversion webhooks of the applied CRDs to be ready before applying their custom resources. Disabled when zero.")
+	flag.StringVar(&statusSizeLimit, "status-size-limit", "1Mi",
+		"The size, e.g. '1Mi', of a Kustomization above which the verbose sections of its status are moved to a ConfigMap. Disabled when empty.")
+	flag.BoolVar(&orderedFanOut, "ordered-fanout", false,
+		"Reconcile the Kustomizations consuming a source in dependency order, when the source handles a reconciliation request or its revision changes.")
+	flag.StringVar(&imageScannerAddr, "image-scanner-address", "",
+		"The URL of the vulnerability scanner endpoint queried for the images of the Kustomizations which set '.spec.imagePolicy'.")
+	flag.IntVar(&applyChunkSize, "apply-chunk-size", 0,
+		"The number of objects above which the objects of a Kustomization are applied in chunks of this size, one per reconciliation. Disabled when zero.")
+	flag.DurationVar(&buildErrorArtifactsTTL, "build-error-artifacts-ttl", 0,
+		"The duration for which the files of the failed builds are served for download on the metrics address, with the secrets redacted. Disabled when zero.")
+	flag.IntVar(&manifestStreamRevisions, "manifest-stream-revisions", 0,
+		"The number of revisions per Kustomization whose normalized manifest stream is served on the metrics address. Disabled when zero.")
+	flag.StringVar(&namespaceBaseline, "namespace-baseline", "",
+		"The name of the ConfigMap in the controller namespace holding the templates of the objects applied to the target namespaces created by the Kustomizations.")
+	flag.StringVar(&mutationRules, "mutation-rules", "",
+		"The name of the ConfigMap in the controller namespace holding the mutation rules applied to the objects of the Kustomizations.")
+	flag.StringVar(&sopsAgeKeyDir, "sops-age-key-dir", "",
+		"The directory holding the SOPS age identities mounted into the controller, e.g. by the Secrets Store CSI driver, in one subdirectory per namespace.")
+	flag.IntVar(&azureKVMaxRetries, "azure-kv-max-retries", 0,
+		"The number of retries of the Azure Key Vault requests throttled or failed with a transient error. Defaults to 3 when zero, a negative value disables the retries.")
+	flag.DurationVar(&azureKVRetryDelay, "azure-kv-retry-delay", 0,
+		"The initial delay between the retries of the Azure Key Vault requests, when not set by the response. Defaults to 4s when zero.")
+	flag.DurationVar(&azureKVTryTimeout, "azure-kv-try-timeout", 0,
+		"The timeout of each try of the Azure Key Vault requests. Disabled when zero.")
+	flag.StringArrayVar(&azureKVFailoverVaults, "azure-kv-failover-vault", nil,
+		"The replica of an Azure Key Vault used when the vault keeps failing with a transient error, in the format '<vault URL>=<replica URL>'.")
+	flag.DurationVar(&dryRunCacheTTL, "dry-run-cache-ttl", 0,
+		"The duration for which the server-side dry-run results of the unchanged objects are reused, disabled when zero.")
 	flag.StringArrayVar(&disallowedFieldManagers, "override-manager", []string{}, "Field manager disallowed to perform changes on managed resources.")
 
 	clientOptions.BindFlags(flag.CommandLine)
@@ -118,10 +271,18 @@ func main() {
 	featureGates.BindFlags(flag.CommandLine)
 	watchOptions.BindFlags(flag.CommandLine)
 	intervalJitterOptions.BindFlags(flag.CommandLine)
+	cacheOptions.BindFlags(flag.CommandLine)
+	faultInjectionOptions.BindFlags(flag.CommandLine)
 
 	flag.Parse()
 
-	logger.SetLogger(logger.NewLogger(logOptions))
+	logLevel, err := loglevel.NewLevel(logOptions.LogLevel)
+	if err != nil {
+		logger.SetLogger(logger.NewLogger(logOptions))
+		setupLog.Error(err, "invalid --log-level value")
+		os.Exit(1)
+	}
+	logger.SetLogger(loglevel.NewLogger(logOptions, logLevel))
 
 	ctx := ctrl.SetupSignalHandler()
 
@@ -130,11 +291,22 @@ func main() {
 		os.Exit(1)
 	}
 
+	for _, name := range disallowedBuildOptions {
+		if !slices.Contains(build.SupportedOptions, name) {
+			setupLog.Error(fmt.Errorf("unknown build option '%s'", name), "invalid --disallowed-build-options value")
+			os.Exit(1)
+		}
+	}
+
 	if err := intervalJitterOptions.SetGlobalJitter(nil); err != nil {
 		setupLog.Error(err, "unable to set global jitter")
 		os.Exit(1)
 	}
 
+	if ns := os.Getenv("RUNTIME_NAMESPACE"); ns != "" {
+		tenantExemptNamespaces = append(tenantExemptNamespaces, ns)
+	}
+
 	watchNamespace := ""
 	if !watchOptions.AllNamespaces {
 		watchNamespace = os.Getenv("RUNTIME_NAMESPACE")
@@ -146,7 +318,8 @@ func main() {
 		os.Exit(1)
 	}
 
-	var disableCacheFor []ctrlclient.Object
+	// The EndpointSlices of the conversion webhooks are read on demand.
+	disableCacheFor := []ctrlclient.Object{&discoveryv1.EndpointSlice{}}
 	shouldCache, err := features.Enabled(features.CacheSecretsAndConfigMaps)
 	if err != nil {
 		setupLog.Error(err, "unable to check feature gate "+features.CacheSecretsAndConfigMaps)
@@ -161,6 +334,63 @@ func main() {
 		leaderElectionId = leaderelection.GenerateID(leaderElectionId, watchOptions.LabelSelector)
 	}
 
+	// The endpoints other than the metrics are authorized with the RBAC
+	// of the cluster, as the metrics address is not authenticated.
+	metricsAuthOpts := &metricsauth.Options{}
+	metricsHandlers := map[string]http.Handler{
+		"/log-level": metricsauth.NonResourceHandler(metricsAuthOpts, loglevel.Handler(logLevel)),
+	}
+	if enablePprof {
+		for path, handler := range pprof.GetHandlers() {
+			metricsHandlers[path] = metricsauth.NonResourceHandler(metricsAuthOpts, handler)
+		}
+	}
+	var diagnosticsTracker *diagnostics.Tracker
+	diagnosticsOpts := &diagnostics.Options{
+		CachedKinds: map[string]func() ctrlclient.ObjectList{
+			kustomizev1.KustomizationKind: func() ctrlclient.ObjectList { return &kustomizev1.KustomizationList{} },
+			sourcev1.GitRepositoryKind:    func() ctrlclient.ObjectList { return &sourcev1.GitRepositoryList{} },
+			sourcev1b2.OCIRepositoryKind:  func() ctrlclient.ObjectList { return &sourcev1b2.OCIRepositoryList{} },
+			sourcev1b2.BucketKind:         func() ctrlclient.ObjectList { return &sourcev1b2.BucketList{} },
+		},
+		Gatherer:  ctrlmetrics.Registry,
+		QueueName: strings.ToLower(kustomizev1.KustomizationKind),
+	}
+	if shouldCache {
+		diagnosticsOpts.CachedKinds["Secret"] = func() ctrlclient.ObjectList { return &corev1.SecretList{} }
+		diagnosticsOpts.CachedKinds["ConfigMap"] = func() ctrlclient.ObjectList { return &corev1.ConfigMapList{} }
+	}
+	if enableDiagnostics {
+		diagnosticsTracker = diagnostics.NewTracker()
+		metricsHandlers["/debug/diagnostics"] = metricsauth.NonResourceHandler(metricsAuthOpts,
+			diagnostics.Handler(diagnosticsTracker, diagnosticsOpts))
+	}
+	var effectiveConfigOpts *effectiveconfig.Options
+	if enableEffectiveConfig {
+		effectiveConfigOpts = &effectiveconfig.Options{}
+		metricsHandlers[effectiveconfig.Path] = metricsauth.NonResourceHandler(metricsAuthOpts,
+			effectiveconfig.Handler(effectiveConfigOpts))
+	}
+	var buildServiceOpts *buildservice.Options
+	if enableBuildService {
+		buildServiceOpts = &buildservice.Options{}
+		metricsHandlers[buildservice.PathPrefix] = buildservice.Handler(buildServiceOpts)
+	}
+	ownersOpts := &owners.Options{}
+	if enableOwnerLookup {
+		metricsHandlers[owners.Path] = metricsauth.NonResourceHandler(metricsAuthOpts, owners.Handler(ownersOpts))
+	}
+	var buildErrorArtifacts *buildartifacts.Store
+	if buildErrorArtifactsTTL > 0 {
+		buildErrorArtifacts = buildartifacts.NewStore(buildErrorArtifactsTTL)
+		metricsHandlers[buildartifacts.PathPrefix] = buildErrorArtifacts.Handler(metricsAuthOpts)
+	}
+	var manifestStreams *manifeststream.Store
+	if manifestStreamRevisions > 0 {
+		manifestStreams = manifeststream.NewStore(manifestStreamRevisions)
+		metricsHandlers[manifeststream.PathPrefix] = manifestStreams.Handler(metricsAuthOpts)
+	}
+
 	restConfig := runtimeClient.GetConfigOrDie(clientOptions)
 	mgrConfig := ctrl.Options{
 		Scheme:                        scheme,
@@ -184,7 +414,7 @@ func main() {
 		},
 		Metrics: metricsserver.Options{
 			BindAddress:   metricsAddr,
-			ExtraHandlers: pprof.GetHandlers(),
+			ExtraHandlers: metricsHandlers,
 		},
 		Controller: ctrlcfg.Controller{
 			MaxConcurrentReconciles: concurrent,
@@ -198,6 +428,11 @@ func main() {
 		}
 	}
 
+	if err := cacheOptions.Apply(&mgrConfig.Cache, shouldCache); err != nil {
+		setupLog.Error(err, "unable to configure the cache")
+		os.Exit(1)
+	}
+
 	mgr, err := ctrl.NewManager(restConfig, mgrConfig)
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
@@ -206,12 +441,100 @@ func main() {
 
 	probes.SetupChecks(mgr, setupLog)
 
+	// The diagnostics report counts the objects in the manager cache.
+	diagnosticsOpts.Reader = mgr.GetCache()
+	ownersOpts.Reader = mgr.GetCache()
+	metricsAuthOpts.Client = mgr.GetClient()
+
 	var eventRecorder *events.Recorder
 	if eventRecorder, err = events.NewRecorder(mgr, ctrl.Log, eventsAddr, controllerName); err != nil {
 		setupLog.Error(err, "unable to create event recorder")
 		os.Exit(1)
 	}
 
+	var kubeEventRecorder kuberecorder.EventRecorder = eventRecorder
+	if cloudEventsAddr != "" || cloudEventsSecret != "" {
+		var cloudEventsToken string
+		if cloudEventsSecret != "" {
+			key := ctrlclient.ObjectKey{Namespace: os.Getenv("RUNTIME_NAMESPACE"), Name: cloudEventsSecret}
+			if cloudEventsAddr, cloudEventsToken, err = cloudevents.SinkFromSecret(ctx, mgr.GetAPIReader(), key); err != nil {
+				setupLog.Error(err, "unable to configure CloudEvents sink")
+				os.Exit(1)
+			}
+		}
+		if kubeEventRecorder, err = cloudevents.NewRecorder(eventRecorder, mgr.GetScheme(), ctrl.Log,
+			cloudEventsAddr, cloudEventsToken, controllerName); err != nil {
+			setupLog.Error(err, "unable to create CloudEvents recorder")
+			os.Exit(1)
+		}
+	}
+
+	var reconcileThrottle *throttle.Throttle
+	if throttleLatency > 0 {
+		reconcileThrottle = throttle.New(throttleLatency, throttleMaxDelay)
+	}
+	throttle.RegisterMetrics(ctrlmetrics.Registry, reconcileThrottle)
+	buildusage.RegisterMetrics(ctrlmetrics.Registry)
+	credexpiry.RegisterMetrics(ctrlmetrics.Registry)
+	capabilities.RegisterMetrics(ctrlmetrics.Registry)
+
+	var webhookBreaker *webhookbreaker.Breaker
+	if webhookFailureThreshold > 0 {
+		webhookBreaker = webhookbreaker.New(webhookFailureThreshold, webhookFailureWindow, webhookCircuitCooldown)
+	}
+	webhookbreaker.RegisterMetrics(ctrlmetrics.Registry)
+
+	faultInjector, err := faultinjection.New(faultInjectionOptions)
+	if err != nil {
+		setupLog.Error(err, "unable to configure the fault injection")
+		os.Exit(1)
+	}
+	if faultInjector != nil {
+		setupLog.Info("fault injection enabled, the reconciliations of the selected Kustomizations will fail on purpose",
+			"selector", faultInjectionOptions.Selector)
+	}
+	faultinjection.RegisterMetrics(ctrlmetrics.Registry)
+	flapping.RegisterMetrics(ctrlmetrics.Registry)
+	leadtime.RegisterMetrics(ctrlmetrics.Registry)
+
+	var statusSizeLimitBytes int
+	if statusSizeLimit != "" {
+		quantity, err := resource.ParseQuantity(statusSizeLimit)
+		if err != nil {
+			setupLog.Error(err, "invalid --status-size-limit value")
+			os.Exit(1)
+		}
+		statusSizeLimitBytes = int(quantity.Value())
+	}
+
+	var imageScanner *imagescan.Scanner
+	if imageScannerAddr != "" {
+		imageScanner = imagescan.NewScanner(imageScannerAddr)
+	}
+
+	var workDirQuotaBytes int64
+	if workDirQuota != "" {
+		quantity, err := resource.ParseQuantity(workDirQuota)
+		if err != nil {
+			setupLog.Error(err, "invalid --workdir-quota value")
+			os.Exit(1)
+		}
+		workDirQuotaBytes = quantity.Value()
+	}
+	workDirs := workdir.New("", workDirQuotaBytes)
+	if n, err := workDirs.Clean(); err != nil {
+		setupLog.Error(err, "unable to clean the working directories")
+	} else if n > 0 {
+		setupLog.Info("removed leaked working directories", "count", n)
+	}
+	workdir.RegisterMetrics(ctrlmetrics.Registry)
+	if err := mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+		return workDirs.Run(ctx, time.Minute)
+	})); err != nil {
+		setupLog.Error(err, "unable to add the working directories janitor")
+		os.Exit(1)
+	}
+
 	metricsH := runtimeCtrl.NewMetrics(mgr, metrics.MustMakeRecorder(), kustomizev1.KustomizationFinalizer)
 
 	jobStatusReader := statusreaders.NewCustomJobStatusReader(mgr.GetRESTMapper())
@@ -228,20 +551,138 @@ func main() {
 		failFast = false
 	}
 
+	continuousHealthChecks, err := features.Enabled(features.ContinuousHealthChecks)
+	if err != nil {
+		setupLog.Error(err, "unable to check feature gate "+features.ContinuousHealthChecks)
+		os.Exit(1)
+	}
+
+	watchReferencedObjects, err := features.Enabled(features.WatchReferencedObjects)
+	if err != nil {
+		setupLog.Error(err, "unable to check feature gate "+features.WatchReferencedObjects)
+		os.Exit(1)
+	}
+
+	reapplyOnWebhookRecovery, err := features.Enabled(features.ReapplyOnWebhookRecovery)
+	if err != nil {
+		setupLog.Error(err, "unable to check feature gate "+features.ReapplyOnWebhookRecovery)
+		os.Exit(1)
+	}
+
+	checkResourceQuotas, err := features.Enabled(features.CheckResourceQuotas)
+	if err != nil {
+		setupLog.Error(err, "unable to check feature gate "+features.CheckResourceQuotas)
+		os.Exit(1)
+	}
+
+	detectOwnershipConflicts, err := features.Enabled(features.DetectOwnershipConflicts)
+	if err != nil {
+		setupLog.Error(err, "unable to check feature gate "+features.DetectOwnershipConflicts)
+		os.Exit(1)
+	}
+
+	reconciliationGates, err := features.Enabled(features.ReconciliationGates)
+	if err != nil {
+		setupLog.Error(err, "unable to check feature gate "+features.ReconciliationGates)
+		os.Exit(1)
+	}
+
+	namespaceReports, err := features.Enabled(features.NamespaceReports)
+	if err != nil {
+		setupLog.Error(err, "unable to check feature gate "+features.NamespaceReports)
+		os.Exit(1)
+	}
+
+	azureKVFailover, err := intazkv.ParseFailoverVaults(azureKVFailoverVaults)
+	if err != nil {
+		setupLog.Error(err, "invalid Azure Key Vault failover")
+		os.Exit(1)
+	}
+
+	var namespaceBaselineKey ctrlclient.ObjectKey
+	if namespaceBaseline != "" {
+		namespaceBaselineKey = ctrlclient.ObjectKey{Namespace: os.Getenv("RUNTIME_NAMESPACE"), Name: namespaceBaseline}
+	}
+
+	var mutationRulesKey ctrlclient.ObjectKey
+	if mutationRules != "" {
+		mutationRulesKey = ctrlclient.ObjectKey{Namespace: os.Getenv("RUNTIME_NAMESPACE"), Name: mutationRules}
+	}
+
+	var simulationConfig *rest.Config
+	if simulate {
+		simServer, err := simulation.NewServer(simulationAssets)
+		if err != nil {
+			setupLog.Error(err, "unable to start the simulation")
+			os.Exit(1)
+		}
+		if err := mgr.Add(simServer); err != nil {
+			_ = simServer.Stop()
+			setupLog.Error(err, "unable to add the simulation API server")
+			os.Exit(1)
+		}
+		simulationConfig = simServer.Config()
+		setupLog.Info("simulation enabled, the Kustomizations are applied to the embedded API server", "host", simulationConfig.Host)
+	}
+
 	if err = (&controller.KustomizationReconciler{
-		ControllerName:          controllerName,
-		DefaultServiceAccount:   defaultServiceAccount,
-		Client:                  mgr.GetClient(),
-		Metrics:                 metricsH,
-		EventRecorder:           eventRecorder,
-		NoCrossNamespaceRefs:    aclOptions.NoCrossNamespaceRefs,
-		NoRemoteBases:           noRemoteBases,
-		FailFast:                failFast,
-		ConcurrentSSA:           concurrentSSA,
-		KubeConfigOpts:          kubeConfigOpts,
-		PollingOpts:             pollingOpts,
-		StatusPoller:            polling.NewStatusPoller(mgr.GetClient(), mgr.GetRESTMapper(), pollingOpts),
-		DisallowedFieldManagers: disallowedFieldManagers,
+		ControllerName:            controllerName,
+		DefaultServiceAccount:     defaultServiceAccount,
+		DefaultTimeout:            defaultTimeout,
+		DefaultRetryInterval:      defaultRetryInterval,
+		DefaultCommonLabels:       defaultCommonLabels,
+		Client:                    mgr.GetClient(),
+		Metrics:                   metricsH,
+		EventRecorder:             kubeEventRecorder,
+		NoCrossNamespaceRefs:      aclOptions.NoCrossNamespaceRefs,
+		NoRemoteBases:             noRemoteBases,
+		AllowLoadRestrictionsNone: allowLoadRestrictionsNone,
+		DisallowedBuildOptions:    disallowedBuildOptions,
+		FailFast:                  failFast,
+		ContinuousHealthChecks:    continuousHealthChecks,
+		WatchReferencedObjects:    watchReferencedObjects,
+		ReapplyOnWebhookRecovery:  reapplyOnWebhookRecovery,
+		CheckResourceQuotas:       checkResourceQuotas,
+		DetectOwnershipConflicts:  detectOwnershipConflicts,
+		OwnerLookup:               enableOwnerLookup,
+		ReconciliationGates:       reconciliationGates,
+		NamespaceReports:          namespaceReports,
+		CacheSecretsAndConfigMaps: shouldCache,
+		ConcurrentSSA:             concurrentSSA,
+		ConcurrentDecryption:      concurrentDecryption,
+		DryRunCacheTTL:            dryRunCacheTTL,
+		KubeConfigOpts:            kubeConfigOpts,
+		PollingOpts:               pollingOpts,
+		StatusPoller:              polling.NewStatusPoller(mgr.GetClient(), mgr.GetRESTMapper(), pollingOpts),
+		DisallowedFieldManagers:   disallowedFieldManagers,
+		TenantLockdown:            tenantLockdown,
+		TenantExemptNamespaces:    tenantExemptNamespaces,
+		TenantDenyClusterScoped:   tenantDenyClusterScoped,
+		TenantClusterScopedKinds:  tenantClusterScopedKinds,
+		Throttle:                  reconcileThrottle,
+		WebhookBreaker:            webhookBreaker,
+		WorkDirs:                  workDirs,
+		Diagnostics:               diagnosticsTracker,
+		EffectiveConfig:           effectiveConfigOpts,
+		BuildService:              buildServiceOpts,
+		FaultInjector:             faultInjector,
+		SimulationConfig:          simulationConfig,
+		ConversionWebhookTimeout:  conversionWebhookTimeout,
+		StatusSizeLimit:           statusSizeLimitBytes,
+		ApplyChunkSize:            applyChunkSize,
+		OrderedFanOut:             orderedFanOut,
+		ImageScanner:              imageScanner,
+		BuildErrorArtifacts:       buildErrorArtifacts,
+		ManifestStreams:           manifestStreams,
+		NamespaceBaseline:         namespaceBaselineKey,
+		MutationRules:             mutationRulesKey,
+		SopsAgeKeyDir:             sopsAgeKeyDir,
+		AzureKeyVaultOptions: intazkv.KeyVaultOptions{
+			MaxRetries:     int32(azureKVMaxRetries),
+			RetryDelay:     azureKVRetryDelay,
+			TryTimeout:     azureKVTryTimeout,
+			FailoverVaults: azureKVFailover,
+		},
 	}).SetupWithManager(ctx, mgr, controller.KustomizationReconcilerOptions{
 		DependencyRequeueInterval: requeueDependency,
 		HTTPRetry:                 httpRetry,